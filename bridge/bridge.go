@@ -0,0 +1,445 @@
+// Package bridge 把聊天平台的群组接入对局：一个群组对应一个房间，玩家在群里用命令加入、开局和投票，
+// 白天的发言在群里进行，身份和夜晚行动通过机器人私信完成。对局仍由services中的引擎驱动，
+// 平台上的玩家和网页、WebSocket客户端的玩家可以在同一个房间中对局
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/services"
+)
+
+// defaultRoomSize 群组中创建房间时默认的人数上限
+const defaultRoomSize = 9
+
+// Platform 聊天平台，负责把消息发到群组或用户的私信
+type Platform interface {
+	// Name 平台名，用作平台玩家ID的前缀和审计日志中的动作来源
+	Name() string
+	// SendGroup 向群组发送消息
+	SendGroup(groupID string, msg Message) error
+	// SendDirect 通过私信向用户发送消息
+	SendDirect(userID string, msg Message) error
+}
+
+// Message 发到平台的一条消息。Choices不为空时平台可以渲染为按钮，
+// 用户选择后以Command作为输入：群组消息的选项交给HandleGroupCommand，私信的选项交给HandleDirect
+type Message struct {
+	Text    string
+	Choices []Choice
+}
+
+// Choice 消息附带的一个选项
+type Choice struct {
+	Label   string
+	Command string
+}
+
+// User 平台上的用户
+type User struct {
+	ID   string
+	Name string
+}
+
+// Bridge 聊天平台与对局之间的桥接
+type Bridge struct {
+	platform Platform
+	rooms    *services.RoomManager
+	sockets  *services.WebSocketManager
+	prefix   string // 群组命令的前缀，用于帮助信息，例如 "!ww "、"/"
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex   sync.Mutex
+	groups  map[string]*session // 群组ID -> 会话
+	players map[string]*session // 平台用户ID -> 所在的会话，私信据此找到房间
+}
+
+// session 群组和房间的对应关系
+type session struct {
+	groupID string
+	roomID  string
+	members map[string]bool // 已加入的平台用户ID
+}
+
+// New 创建桥接，prefix为群组命令的前缀
+func New(platform Platform, rooms *services.RoomManager, sockets *services.WebSocketManager, prefix string) *Bridge {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Bridge{
+		platform: platform,
+		rooms:    rooms,
+		sockets:  sockets,
+		prefix:   prefix,
+		ctx:      ctx,
+		cancel:   cancel,
+		groups:   make(map[string]*session),
+		players:  make(map[string]*session),
+	}
+}
+
+// Close 停止推送房间事件，已创建的房间和对局不受影响
+func (b *Bridge) Close() {
+	b.cancel()
+}
+
+// playerID 平台用户在对局中的玩家ID
+func (b *Bridge) playerID(userID string) string {
+	return b.platform.Name() + ":" + userID
+}
+
+// userID 平台玩家ID对应的平台用户ID，不是本平台的玩家时返回false
+func (b *Bridge) userID(playerID string) (string, bool) {
+	return strings.CutPrefix(playerID, b.platform.Name()+":")
+}
+
+// replyGroup 回复群组，发送失败只记录在平台的日志中
+func (b *Bridge) replyGroup(groupID, format string, args ...any) {
+	b.platform.SendGroup(groupID, Message{Text: fmt.Sprintf(format, args...)})
+}
+
+// replyDirect 私信回复用户
+func (b *Bridge) replyDirect(userID, format string, args ...any) {
+	b.platform.SendDirect(userID, Message{Text: fmt.Sprintf(format, args...)})
+}
+
+// groupSession 获取群组的会话，群组没有房间或房间已被清理时返回nil
+func (b *Bridge) groupSession(groupID string) *session {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	s := b.groups[groupID]
+	if s == nil {
+		return nil
+	}
+	if _, err := b.rooms.GetRoom(s.roomID); err != nil {
+		b.dropLocked(s)
+		return nil
+	}
+	return s
+}
+
+// userSession 获取用户所在的会话
+func (b *Bridge) userSession(userID string) *session {
+	b.mutex.Lock()
+	s := b.players[userID]
+	b.mutex.Unlock()
+
+	if s == nil {
+		return nil
+	}
+	return b.groupSession(s.groupID)
+}
+
+// isMember 平台用户是否已加入会话的房间
+func (b *Bridge) isMember(s *session, userID string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return s.members[userID]
+}
+
+// dropLocked 移除房间已不存在的会话，调用方需持有b.mutex
+func (b *Bridge) dropLocked(s *session) {
+	delete(b.groups, s.groupID)
+	for userID := range s.members {
+		if b.players[userID] == s {
+			delete(b.players, userID)
+		}
+	}
+}
+
+// HandleGroupCommand 处理群组中的命令，args为去掉命令前缀后按空白拆分的参数
+func (b *Bridge) HandleGroupCommand(groupID string, user User, args []string) {
+	if len(args) == 0 {
+		b.help(groupID)
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "create", "创建":
+		size := defaultRoomSize
+		if len(args) > 1 {
+			if n, err := strconv.Atoi(args[1]); err == nil {
+				size = n
+			}
+		}
+		b.create(groupID, user, size)
+	case "join", "加入":
+		b.join(groupID, user)
+	case "start", "开始":
+		b.start(groupID, user)
+	case "vote", "投票":
+		b.vote(groupID, user, args[1:])
+	case "status", "状态":
+		b.status(groupID)
+	default:
+		b.help(groupID)
+	}
+}
+
+// HandleGroupText 处理群组中的普通发言：房间成员的发言转为房间频道的聊天，
+// 对局白天存活玩家的发言同时作为发言动作提交，AI玩家据此分析身份声明
+func (b *Bridge) HandleGroupText(groupID string, user User, text string) {
+	s := b.groupSession(groupID)
+	if s == nil || !b.isMember(s, user.ID) {
+		return
+	}
+
+	playerID := b.playerID(user.ID)
+	if game, exists := b.rooms.GetGameController(s.roomID); exists && game.IsRunning() {
+		if phase, _ := game.CurrentPhase(); phase == services.PhaseDay {
+			b.act(game, s.roomID, playerID, "discuss", "", text)
+		}
+	}
+	b.sockets.PostChat(s.roomID, playerID, services.ChatRequest{Channel: services.ChannelRoom, Message: text})
+}
+
+// HandleDirect 处理用户发给机器人的私信：夜晚行动、狼人频道发言和查看自己的身份
+func (b *Bridge) HandleDirect(user User, text string) {
+	s := b.userSession(user.ID)
+	if s == nil {
+		b.replyDirect(user.ID, "你还没有加入任何对局，请先在群里使用 %sjoin 加入房间", b.prefix)
+		return
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return
+	}
+
+	playerID := b.playerID(user.ID)
+	command := strings.ToLower(fields[0])
+	switch command {
+	case "wolf", "狼":
+		message := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), fields[0]))
+		if err := b.sockets.PostChat(s.roomID, playerID, services.ChatRequest{Channel: services.ChannelWolf, Message: message}); err != nil {
+			b.replyDirect(user.ID, "发送失败：%s", err)
+		}
+		return
+	case "status", "状态", "me", "身份":
+		b.privateStatus(s, user.ID)
+		return
+	}
+
+	game, exists := b.rooms.GetGameController(s.roomID)
+	if !exists || !game.IsRunning() {
+		b.replyDirect(user.ID, "对局尚未开始")
+		return
+	}
+	if _, known := actionNames[command]; !known {
+		b.replyDirect(user.ID, "未知的指令。夜晚行动格式为“动作 座位号”，例如 check 3；狼人可以用 wolf 消息 与同伴交流")
+		return
+	}
+
+	targetID := ""
+	if len(fields) > 1 {
+		var err error
+		if targetID, err = b.seatPlayer(game, fields[1]); err != nil {
+			b.replyDirect(user.ID, "%s", err)
+			return
+		}
+	}
+	if err := b.act(game, s.roomID, playerID, command, targetID, ""); err != nil {
+		b.replyDirect(user.ID, "行动失败：%s", err)
+		return
+	}
+	if command == "check" {
+		b.checkResult(game, user.ID)
+		return
+	}
+	b.replyDirect(user.ID, "已%s", actionNames[command])
+}
+
+// act 以平台玩家的身份提交动作
+func (b *Bridge) act(game *services.GameController, roomID, playerID, actionType, targetID, content string) error {
+	action := models.GameAction{Type: actionType, PlayerID: playerID, RoomID: roomID, TargetID: targetID, Content: content}
+	return game.ProcessAction(b.ctx, action, b.platform.Name(), "")
+}
+
+// create 为群组创建房间，创建者自动加入并成为房主
+func (b *Bridge) create(groupID string, user User, size int) {
+	if s := b.groupSession(groupID); s != nil {
+		b.replyGroup(groupID, "本群已有房间 %s，使用 %sjoin 加入", s.roomID, b.prefix)
+		return
+	}
+
+	aiFill := min(services.DefaultAIFill, size)
+	room, err := b.rooms.CreateRoom(b.platform.Name()+"群组对局", models.StandardMode, size, false, false, "", "", nil, aiFill, nil, false, "")
+	if err != nil {
+		b.replyGroup(groupID, "创建房间失败：%s", err)
+		return
+	}
+
+	s := &session{groupID: groupID, roomID: room.ID, members: make(map[string]bool)}
+	b.mutex.Lock()
+	b.groups[groupID] = s
+	b.mutex.Unlock()
+	go b.watchGroup(s)
+
+	b.replyGroup(groupID, "已创建房间 %s，最多%d人，人数不足时由AI补位。使用 %sjoin 加入，房主使用 %sstart 开始", room.ID, size, b.prefix, b.prefix)
+	b.join(groupID, user)
+}
+
+// join 平台用户加入群组的房间，之后的私有消息通过私信推送
+func (b *Bridge) join(groupID string, user User) {
+	s := b.groupSession(groupID)
+	if s == nil {
+		b.replyGroup(groupID, "本群还没有房间，使用 %screate 创建", b.prefix)
+		return
+	}
+
+	b.mutex.Lock()
+	current := b.players[user.ID]
+	b.mutex.Unlock()
+	if current == s && b.isMember(s, user.ID) {
+		b.replyGroup(groupID, "%s 已在房间中", user.Name)
+		return
+	}
+	if current != nil && current != s {
+		b.replyGroup(groupID, "%s 已在其他群的对局中", user.Name)
+		return
+	}
+
+	playerID := b.playerID(user.ID)
+	err := b.rooms.JoinRoom(s.roomID, models.Player{ID: playerID, Name: user.Name, Type: models.HumanPlayer})
+	if err != nil {
+		b.replyGroup(groupID, "%s 加入失败：%s", user.Name, err)
+		return
+	}
+	b.sockets.JoinRoom(s.roomID, playerID)
+
+	b.mutex.Lock()
+	s.members[user.ID] = true
+	b.players[user.ID] = s
+	b.mutex.Unlock()
+	go b.watchPlayer(s, user.ID)
+
+	count := 0
+	if room, err := b.rooms.GetRoom(s.roomID); err == nil {
+		count = len(room.Players)
+	}
+	b.replyGroup(groupID, "%s 加入了房间，当前%d人。身份和夜晚行动会通过私信发送，请确认能收到机器人的私信", user.Name, count)
+}
+
+// start 房主开始对局
+func (b *Bridge) start(groupID string, user User) {
+	s := b.groupSession(groupID)
+	if s == nil {
+		b.replyGroup(groupID, "本群还没有房间，使用 %screate 创建", b.prefix)
+		return
+	}
+	game, exists := b.rooms.GetGameController(s.roomID)
+	if !exists {
+		b.replyGroup(groupID, "%s", services.ErrGameNotFound)
+		return
+	}
+	if err := game.StartGame(b.playerID(user.ID)); err != nil {
+		b.replyGroup(groupID, "开始失败：%s", err)
+	}
+}
+
+// vote 在群里投票，args[0]为座位号
+func (b *Bridge) vote(groupID string, user User, args []string) {
+	s := b.groupSession(groupID)
+	if s == nil || !b.isMember(s, user.ID) {
+		b.replyGroup(groupID, "%s 不在本群的房间中", user.Name)
+		return
+	}
+	game, exists := b.rooms.GetGameController(s.roomID)
+	if !exists || !game.IsRunning() {
+		b.replyGroup(groupID, "对局尚未开始")
+		return
+	}
+	if len(args) == 0 {
+		b.replyGroup(groupID, "请指定座位号，例如 %svote 3", b.prefix)
+		return
+	}
+
+	targetID, err := b.seatPlayer(game, args[0])
+	if err != nil {
+		b.replyGroup(groupID, "%s", err)
+		return
+	}
+	if err := b.act(game, s.roomID, b.playerID(user.ID), "vote", targetID, ""); err != nil {
+		b.replyGroup(groupID, "%s 投票失败：%s", user.Name, err)
+		return
+	}
+	b.replyGroup(groupID, "%s 已投票", user.Name)
+}
+
+// seatPlayer 按座位号找到玩家ID，座位号从1开始
+func (b *Bridge) seatPlayer(game *services.GameController, seat string) (string, error) {
+	view, err := game.BuildStatus("")
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(seat, "号"))
+	if err != nil || n < 1 || n > len(view.Players) {
+		return "", fmt.Errorf("无效的座位号：%s", seat)
+	}
+	return view.Players[n-1].ID, nil
+}
+
+// status 在群里公布房间和对局的公开信息
+func (b *Bridge) status(groupID string) {
+	s := b.groupSession(groupID)
+	if s == nil {
+		b.replyGroup(groupID, "本群还没有房间，使用 %screate 创建", b.prefix)
+		return
+	}
+	game, exists := b.rooms.GetGameController(s.roomID)
+	if !exists {
+		b.replyGroup(groupID, "%s", services.ErrGameNotFound)
+		return
+	}
+	view, err := game.BuildStatus("")
+	if err != nil {
+		b.replyGroup(groupID, "%s", err)
+		return
+	}
+	b.platform.SendGroup(groupID, Message{Text: publicStatus(s.roomID, view)})
+}
+
+// privateStatus 私信告知玩家自己的身份、狼人同伴和查验记录
+func (b *Bridge) privateStatus(s *session, userID string) {
+	game, exists := b.rooms.GetGameController(s.roomID)
+	if !exists {
+		b.replyDirect(userID, "%s", services.ErrGameNotFound)
+		return
+	}
+	view, err := game.BuildStatus(b.playerID(userID))
+	if err != nil {
+		b.replyDirect(userID, "%s", err)
+		return
+	}
+	b.platform.SendDirect(userID, Message{Text: privateStatus(view)})
+}
+
+// checkResult 预言家查验后私信告知结果
+func (b *Bridge) checkResult(game *services.GameController, userID string) {
+	view, err := game.BuildStatus(b.playerID(userID))
+	if err != nil || len(view.Checks) == 0 {
+		return
+	}
+	check := view.Checks[len(view.Checks)-1]
+	b.replyDirect(userID, "查验结果：%s 是%s", seatName(view.Players, check.TargetID), camp(check.Role))
+}
+
+// help 群组命令的帮助
+func (b *Bridge) help(groupID string) {
+	p := b.prefix
+	b.replyGroup(groupID, strings.Join([]string{
+		"狼人杀命令：",
+		p + "create [人数]  创建房间",
+		p + "join  加入房间",
+		p + "start  房主开始对局",
+		p + "vote 座位号  投票",
+		p + "status  查看对局状态",
+		"白天直接在群里发言；身份和夜晚行动通过机器人私信进行，私信发送“动作 座位号”，例如 check 3",
+	}, "\n"))
+}
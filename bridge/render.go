@@ -0,0 +1,202 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/services"
+)
+
+// roleNames 角色的中文名称
+var roleNames = map[models.Role]string{
+	models.Werewolf:  "狼人",
+	models.Seer:      "预言家",
+	models.Witch:     "女巫",
+	models.Villager:  "村民",
+	models.Hunter:    "猎人",
+	models.Guard:     "守卫",
+	models.Cupid:     "丘比特",
+	models.Thief:     "盗贼",
+	models.WhiteWolf: "白狼王",
+}
+
+// actionNames 私信中可以执行的动作及其中文名称
+var actionNames = map[string]string{
+	"kill":    "击杀",
+	"check":   "查验",
+	"save":    "使用解药",
+	"poison":  "使用毒药",
+	"pass":    "不用药",
+	"protect": "守护",
+	"link":    "连线",
+	"shoot":   "开枪",
+	"explode": "自爆",
+}
+
+// channelNames 转发到私信的聊天频道名称
+var channelNames = map[string]string{
+	services.ChannelWolf:    "狼人频道",
+	services.ChannelDead:    "死亡玩家频道",
+	services.ChannelWhisper: "私聊",
+}
+
+// resultNames 对局结果
+var resultNames = map[string]string{
+	services.WerewolfWin:  "狼人阵营获胜",
+	services.VillagerWin:  "好人阵营获胜",
+	services.LoversWin:    "情侣获胜",
+	services.WhiteWolfWin: "白狼王获胜",
+}
+
+// roleName 角色的中文名称，未知角色原样返回
+func roleName(role models.Role) string {
+	if name, ok := roleNames[role]; ok {
+		return name
+	}
+	return string(role)
+}
+
+// camp 预言家查验看到的阵营
+func camp(role models.Role) string {
+	if role == models.Werewolf || role == models.WhiteWolf {
+		return "狼人"
+	}
+	return "好人"
+}
+
+// seatOf 第i个座位的座位号，从1开始
+func seatOf(i int) string {
+	return strconv.Itoa(i + 1)
+}
+
+// seatLabel 座位号和玩家名，例如 "3号 Alice"
+func seatLabel(i int, player models.Player) string {
+	return seatOf(i) + "号 " + player.Name
+}
+
+// seatNumber 玩家的座位号，玩家不在列表中时返回空
+func seatNumber(players []models.Player, playerID string) string {
+	for i, player := range players {
+		if player.ID == playerID {
+			return seatOf(i)
+		}
+	}
+	return ""
+}
+
+// seatName 玩家的座位号和名字，玩家不在座位上时返回玩家ID
+func seatName(players []models.Player, playerID string) string {
+	for i, player := range players {
+		if player.ID == playerID {
+			return seatLabel(i, player)
+		}
+	}
+	return playerID
+}
+
+// publicStatus 房间和对局的公开信息
+func publicStatus(roomID string, view *models.PlayerGameView) string {
+	var b strings.Builder
+	if !view.IsStarted {
+		fmt.Fprintf(&b, "房间 %s 等待开始，当前%d人：", roomID, len(view.Players))
+	} else {
+		fmt.Fprintf(&b, "房间 %s 第%d轮 %s，剩余%d秒：", roomID, view.Round, phaseNames[view.Phase], view.TimeLeft)
+	}
+	for i, player := range view.Players {
+		b.WriteString("\n" + seatLabel(i, player))
+		if view.IsStarted && !player.Alive {
+			b.WriteString(" 已出局")
+			if player.Role != "" {
+				b.WriteString("（" + roleName(player.Role) + "）")
+			}
+		}
+	}
+	return b.String()
+}
+
+// phaseNames 阶段的中文名称
+var phaseNames = map[string]string{
+	services.PhaseNight: "夜晚",
+	services.PhaseDay:   "白天",
+	services.PhaseVote:  "投票",
+}
+
+// privateStatus 玩家自己的身份、狼人同伴和查验记录
+func privateStatus(view *models.PlayerGameView) string {
+	if view.Role == "" {
+		return "对局尚未开始"
+	}
+
+	lines := []string{"你的身份是：" + roleName(view.Role)}
+	if len(view.Teammates) > 0 {
+		names := make([]string, 0, len(view.Teammates))
+		for _, id := range view.Teammates {
+			names = append(names, seatName(view.Players, id))
+		}
+		lines = append(lines, "狼人同伴："+strings.Join(names, "、")+"，私信发送 wolf 消息 与同伴交流")
+	}
+	for _, check := range view.Checks {
+		lines = append(lines, fmt.Sprintf("第%d夜查验：%s 是%s", check.Round, seatName(view.Players, check.TargetID), camp(check.Role)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// voteResult 一轮投票的计票结果
+func voteResult(players []models.Player, tally *services.VoteTally) string {
+	lines := []string{fmt.Sprintf("第%d轮投票结果：", tally.Round)}
+	for _, player := range players {
+		if target, voted := tally.Votes[player.ID]; voted {
+			lines = append(lines, seatName(players, player.ID)+" → "+seatName(players, target))
+		}
+	}
+	if tally.Eliminated == "" {
+		lines = append(lines, "平票，无人出局")
+	} else {
+		lines = append(lines, fmt.Sprintf("%s 以%g票被放逐", seatName(players, tally.Eliminated), tally.Totals[tally.Eliminated]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// gameResult 对局结果和所有玩家的身份
+func gameResult(result string, players []models.Player) string {
+	text, ok := resultNames[result]
+	if !ok {
+		text = "对局结束"
+	}
+	lines := []string{text + "，身份公布："}
+	for i, player := range players {
+		line := seatLabel(i, player) + "：" + roleName(player.Role)
+		if !player.Alive {
+			line += "（出局）"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SplitText 按行把文本拆成不超过limit个字符的片段，用于有单条消息长度上限的平台
+func SplitText(text string, limit int) []string {
+	var chunks []string
+	var current []rune
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		if len(current) > 0 && len(current)+1+len(runes) > limit {
+			chunks = append(chunks, string(current))
+			current = nil
+		}
+		for len(runes) > limit {
+			chunks = append(chunks, string(runes[:limit]))
+			runes = runes[limit:]
+		}
+		if len(current) > 0 {
+			current = append(current, '\n')
+		}
+		current = append(current, runes...)
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, string(current))
+	}
+	return chunks
+}
@@ -0,0 +1,169 @@
+package bridge
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/reporting"
+	"github.com/qianlnk/werewolf/services"
+)
+
+// pollTimeout 等待房间新事件的最长时间，超时后检查房间是否仍然存在
+const pollTimeout = 30 * time.Second
+
+// event 房间事件中桥接关心的字段
+type event struct {
+	Type     string                `json:"type"`
+	Step     string                `json:"step"` // 旁白步骤
+	Text     string                `json:"text"` // 旁白台词
+	Message  string                `json:"message"`
+	Channel  string                `json:"channel"`
+	PlayerID string                `json:"player_id"`
+	Result   string                `json:"result"`
+	Players  []models.Player       `json:"players"`
+	Tally    *services.VoteTally   `json:"tally"`
+	Role     models.Role           `json:"role"`
+	Round    int                   `json:"round"`
+	Phase    string                `json:"phase"`
+	Actions  []models.ActionOption `json:"actions"`
+	Content  json.RawMessage       `json:"content"` // 私有消息的内容
+}
+
+// watch 依次读取玩家有权收到的房间事件，playerID为空时读取旁观者可见的公开事件，
+// 房间已被清理或桥接关闭时返回。处理单个事件时的panic被捕获，不影响之后的事件
+func (b *Bridge) watch(s *session, playerID string, handle func(event)) {
+	var since int64
+	for {
+		if _, err := b.rooms.GetRoom(s.roomID); err != nil {
+			return
+		}
+		events, err := b.sockets.WaitRoomEvents(b.ctx, s.roomID, playerID, since, pollTimeout)
+		if err != nil {
+			return
+		}
+		for _, e := range events.Events {
+			var ev event
+			if json.Unmarshal(e.Data, &ev) != nil {
+				continue
+			}
+			reporting.Call("bridge."+b.platform.Name(), func() { handle(ev) })
+		}
+		since = events.LastSeq
+	}
+}
+
+// watchGroup 把房间的公开事件推送到群组：法官旁白、投票结果、对局结果和其他前端玩家的发言
+func (b *Bridge) watchGroup(s *session) {
+	b.watch(s, "", func(e event) {
+		switch e.Type {
+		case "narration":
+			msg := Message{Text: e.Text}
+			if e.Step == services.NarrationVoteStart {
+				msg.Text += "\n使用 " + b.prefix + "vote 座位号 投票"
+				msg.Choices = b.voteChoices(s.roomID)
+			}
+			b.platform.SendGroup(s.groupID, msg)
+		case "game_started":
+			b.replyGroup(s.groupID, "游戏开始，身份已通过私信发送")
+		case "vote_result":
+			if e.Tally != nil {
+				b.replyGroup(s.groupID, "%s", voteResult(b.publicPlayers(s.roomID), e.Tally))
+			}
+		case "game_end":
+			b.replyGroup(s.groupID, "%s", gameResult(e.Result, e.Players))
+		case "chat":
+			// 本平台玩家的发言已经在群里，只转发其他前端玩家在房间频道的发言
+			if _, own := b.userID(e.PlayerID); own || e.Channel != services.ChannelRoom {
+				return
+			}
+			b.replyGroup(s.groupID, "%s：%s", seatName(b.publicPlayers(s.roomID), e.PlayerID), e.Message)
+		}
+	})
+}
+
+// watchPlayer 把玩家的私有消息和非公开频道的聊天通过私信推送给平台用户
+func (b *Bridge) watchPlayer(s *session, userID string) {
+	playerID := b.playerID(userID)
+	b.watch(s, playerID, func(e event) {
+		switch e.Type {
+		case "private":
+			var private event
+			if json.Unmarshal(e.Content, &private) == nil {
+				b.deliverPrivate(s, userID, private)
+			}
+		case "chat":
+			// 房间频道的发言在群里可以看到，私信只转发狼人频道、死亡玩家频道和私聊
+			if e.Channel == services.ChannelRoom || e.PlayerID == playerID {
+				return
+			}
+			b.replyDirect(userID, "【%s】%s：%s", channelNames[e.Channel], seatName(b.publicPlayers(s.roomID), e.PlayerID), e.Message)
+		}
+	})
+}
+
+// deliverPrivate 私信推送发给玩家的私有消息
+func (b *Bridge) deliverPrivate(s *session, userID string, e event) {
+	switch e.Type {
+	case "role_assigned":
+		b.privateStatus(s, userID)
+	case "available_actions":
+		// 白天的发言和投票在群里进行，私信只提示夜晚行动和猎人开枪之类的技能
+		if msg, ok := b.actionPrompt(s.roomID, e); ok {
+			b.platform.SendDirect(userID, msg)
+		}
+	case "achievement_unlocked":
+		b.replyDirect(userID, "%s", e.Message)
+	}
+}
+
+// actionPrompt 把可执行的动作渲染为带选项的私信，没有需要在私信中执行的动作时返回false
+func (b *Bridge) actionPrompt(roomID string, e event) (Message, bool) {
+	players := b.publicPlayers(roomID)
+	msg := Message{Text: "轮到你行动，回复“动作 座位号”："}
+	for _, option := range e.Actions {
+		if option.Type == "discuss" || option.Type == "vote" {
+			continue
+		}
+		name := actionNames[option.Type]
+		if len(option.Targets) == 0 {
+			msg.Choices = append(msg.Choices, Choice{Label: name, Command: option.Type})
+			continue
+		}
+		for _, target := range option.Targets {
+			seat := seatNumber(players, target)
+			if seat == "" {
+				continue
+			}
+			msg.Choices = append(msg.Choices, Choice{
+				Label:   name + " " + seatName(players, target),
+				Command: option.Type + " " + seat,
+			})
+		}
+	}
+	return msg, len(msg.Choices) > 0
+}
+
+// voteChoices 投票阶段所有存活玩家的投票选项
+func (b *Bridge) voteChoices(roomID string) []Choice {
+	var choices []Choice
+	for i, player := range b.publicPlayers(roomID) {
+		if player.Alive {
+			choices = append(choices, Choice{Label: seatLabel(i, player), Command: "vote " + seatOf(i)})
+		}
+	}
+	return choices
+}
+
+// publicPlayers 按座位顺序排列的玩家，只包含公开信息
+func (b *Bridge) publicPlayers(roomID string) []models.Player {
+	game, exists := b.rooms.GetGameController(roomID)
+	if !exists {
+		return nil
+	}
+	view, err := game.BuildStatus("")
+	if err != nil {
+		return nil
+	}
+	return view.Players
+}
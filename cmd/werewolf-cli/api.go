@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiClient 调用服务端 /api/v1 接口的HTTP客户端
+type apiClient struct {
+	server string // 服务端地址，如 http://localhost:8080
+	token  string // 登录令牌或API密钥
+	http   *http.Client
+}
+
+func newAPIClient(server, token string) *apiClient {
+	return &apiClient{
+		server: strings.TrimRight(server, "/"),
+		token:  token,
+		http:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// apiError 服务端返回的错误响应
+type apiError struct {
+	Status  int
+	Code    string `json:"code"`
+	Message string `json:"error"`
+}
+
+func (e *apiError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("请求失败（HTTP %d）", e.Status)
+	}
+	return fmt.Sprintf("%s（%s）", e.Message, e.Code)
+}
+
+// do 发送请求，body不为nil时作为JSON请求体，响应解析到out中
+func (c *apiClient) do(method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := c.server + "/api/v1" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &apiError{Status: resp.StatusCode}
+		json.Unmarshal(data, apiErr)
+		return apiErr
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// wsTicket 获取房间的WebSocket连接凭证，只有spectate权限的API密钥获得只读凭证
+func (c *apiClient) wsTicket(roomID string) (string, error) {
+	var resp struct {
+		Ticket string `json:"ticket"`
+	}
+	if err := c.do(http.MethodPost, "/rooms/"+url.PathEscape(roomID)+"/ws-ticket", nil, nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Ticket, nil
+}
+
+// joinRoom 加入房间并返回WebSocket连接凭证
+func (c *apiClient) joinRoom(roomID, name string) (string, error) {
+	var resp struct {
+		Ticket string `json:"ws_ticket"`
+	}
+	body := map[string]string{"name": name}
+	if err := c.do(http.MethodPost, "/rooms/"+url.PathEscape(roomID)+"/join", nil, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.Ticket, nil
+}
+
+// wsURL 根据服务端地址构建WebSocket连接地址
+func (c *apiClient) wsURL(ticket, connectionID string) (string, error) {
+	u, err := url.Parse(c.server)
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	u.Path = "/ws"
+	u.RawQuery = url.Values{
+		"ticket":        {ticket},
+		"connection_id": {connectionID},
+		"protocol":      {"2"},
+	}.Encode()
+	return u.String(), nil
+}
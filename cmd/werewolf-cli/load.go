@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// loadConfig 压测参数
+type loadConfig struct {
+	Rooms        int           // 同时进行的房间数
+	Players      int           // 每个房间的机器人数
+	Fill         int           // 开局时用内置AI补足到的人数，0为只有机器人
+	Mode         string        // 游戏模式
+	Duration     time.Duration // 压测时长，到时断开所有连接并输出统计
+	ChatInterval time.Duration // 白天每个机器人的发言间隔，0为不发言
+	PingInterval time.Duration // 测量往返延迟的心跳间隔
+	Report       time.Duration // 输出中间统计的间隔
+	Rematch      bool          // 对局结束后由房主开始下一局
+}
+
+// loadStats 压测过程中的累计统计，各机器人并发更新
+type loadStats struct {
+	connected    atomic.Int64
+	failed       atomic.Int64
+	messages     atomic.Int64
+	bytes        atomic.Int64
+	actions      atomic.Int64
+	chats        atomic.Int64
+	errors       atomic.Int64
+	gamesStarted atomic.Int64
+	gamesEnded   atomic.Int64
+
+	mutex     sync.Mutex
+	latencies []time.Duration // 心跳往返延迟
+}
+
+// addLatency 记录一次心跳往返延迟
+func (s *loadStats) addLatency(d time.Duration) {
+	s.mutex.Lock()
+	s.latencies = append(s.latencies, d)
+	s.mutex.Unlock()
+}
+
+// print 输出统计，elapsed为压测已进行的时间
+func (s *loadStats) print(out io.Writer, elapsed time.Duration) {
+	s.mutex.Lock()
+	latencies := append([]time.Duration(nil), s.latencies...)
+	s.mutex.Unlock()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		seconds = 1
+	}
+	fmt.Fprintf(out, "[%s] 连接 %d（失败 %d） 收到消息 %d（%.0f/s，%.1f MB） 动作 %d 聊天 %d 错误 %d 开局 %d 结束 %d 心跳延迟 p50=%v p99=%v max=%v\n",
+		elapsed.Truncate(time.Second), s.connected.Load(), s.failed.Load(),
+		s.messages.Load(), float64(s.messages.Load())/seconds, float64(s.bytes.Load())/(1<<20),
+		s.actions.Load(), s.chats.Load(), s.errors.Load(), s.gamesStarted.Load(), s.gamesEnded.Load(),
+		percentile(latencies, 0.50), percentile(latencies, 0.99), percentile(latencies, 1))
+}
+
+// percentile 已排序延迟的分位数，没有数据时为0
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i].Truncate(time.Microsecond)
+}
+
+// runLoad 启动多个房间的机器人对局，到达压测时长或收到中断信号时停止并输出统计
+func runLoad(server string, cfg loadConfig) error {
+	if cfg.Rooms <= 0 || cfg.Players <= 0 {
+		return errUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Duration)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	stats := &loadStats{}
+	started := time.Now()
+	fmt.Fprintf(os.Stdout, "压测开始：%d 个房间，每个房间 %d 个机器人，时长 %v\n", cfg.Rooms, cfg.Players, cfg.Duration)
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Rooms; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			if err := runLoadRoom(ctx, server, cfg, index, stats); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "房间 %d 启动失败: %v\n", index, err)
+			}
+		}(i)
+	}
+
+	// 定期输出中间统计
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(cfg.Report)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			stats.print(os.Stdout, time.Since(started))
+		case <-done:
+			fmt.Fprintln(os.Stdout, "压测结束")
+			stats.print(os.Stdout, time.Since(started))
+			return nil
+		}
+	}
+}
+
+// runLoadRoom 创建一个房间，所有机器人加入后由房主开始游戏，直到压测结束
+func runLoadRoom(ctx context.Context, server string, cfg loadConfig, index int, stats *loadStats) error {
+	bots := make([]*loadBot, 0, cfg.Players)
+	defer func() {
+		for _, bot := range bots {
+			bot.close()
+		}
+	}()
+
+	// 每个机器人使用独立的游客身份
+	for i := 0; i < cfg.Players; i++ {
+		api := newAPIClient(server, "")
+		var resp struct {
+			Token string `json:"token"`
+		}
+		name := fmt.Sprintf("压测%d-%d", index, i)
+		if err := api.do(http.MethodPost, "/users/guest", nil, map[string]string{"display_name": name}, &resp); err != nil {
+			return fmt.Errorf("创建游客失败: %w", err)
+		}
+		api.token = resp.Token
+		bots = append(bots, &loadBot{api: api, name: name, host: i == 0, cfg: cfg, stats: stats})
+	}
+
+	var room struct {
+		ID string `json:"id"`
+	}
+	err := bots[0].api.do(http.MethodPost, "/rooms", nil, map[string]interface{}{
+		"name":        fmt.Sprintf("压测房间%d", index),
+		"mode":        cfg.Mode,
+		"max_players": max(cfg.Players, cfg.Fill),
+		"ai_fill":     cfg.Fill,
+	}, &room)
+	if err != nil {
+		return fmt.Errorf("创建房间失败: %w", err)
+	}
+
+	// 按顺序加入，第一个加入的机器人是房主
+	for _, bot := range bots {
+		if err := bot.join(room.ID); err != nil {
+			stats.failed.Add(1)
+			return err
+		}
+		stats.connected.Add(1)
+	}
+
+	var wg sync.WaitGroup
+	for _, bot := range bots {
+		wg.Add(1)
+		go func(bot *loadBot) {
+			defer wg.Done()
+			bot.run(ctx)
+		}(bot)
+	}
+	bots[0].send("game_action", map[string]string{"type": "start_game"})
+
+	<-ctx.Done()
+	for _, bot := range bots {
+		bot.close()
+	}
+	wg.Wait()
+	return nil
+}
+
+// loadBot 压测中的一个机器人玩家，按收到的可执行动作随机行动
+type loadBot struct {
+	api    *apiClient
+	name   string
+	host   bool // 房主负责开始游戏，并统计开局和结束的对局数
+	cfg    loadConfig
+	stats  *loadStats
+	roomID string
+	conn   *websocket.Conn
+	rng    *rand.Rand
+
+	writeMu  sync.Mutex
+	closed   bool
+	pingSent atomic.Int64 // 最近一次心跳的发送时间，纳秒
+	phase    atomic.Value // 当前阶段，白天时定期发言
+}
+
+// join 加入房间并建立WebSocket连接
+func (b *loadBot) join(roomID string) error {
+	ticket, err := b.api.joinRoom(roomID, b.name)
+	if err != nil {
+		return fmt.Errorf("加入房间失败: %w", err)
+	}
+	target, err := b.api.wsURL(ticket, newConnectionID())
+	if err != nil {
+		return err
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(target, nil)
+	if err != nil {
+		return fmt.Errorf("连接服务器失败: %w", err)
+	}
+	b.roomID, b.conn = roomID, conn
+	b.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	b.phase.Store("")
+	return nil
+}
+
+// run 读取消息并定期发送心跳和聊天，直到连接断开或压测结束
+func (b *loadBot) run(ctx context.Context) {
+	go b.tick(ctx)
+
+	for {
+		_, data, err := b.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		b.stats.messages.Add(1)
+		b.stats.bytes.Add(int64(len(data)))
+		b.handle(data)
+	}
+}
+
+// tick 定期发送心跳，白天按间隔发言
+func (b *loadBot) tick(ctx context.Context) {
+	ping := time.NewTicker(b.cfg.PingInterval)
+	defer ping.Stop()
+	var chat <-chan time.Time
+	if b.cfg.ChatInterval > 0 {
+		ticker := time.NewTicker(b.cfg.ChatInterval + time.Duration(b.rng.Int63n(int64(b.cfg.ChatInterval))))
+		defer ticker.Stop()
+		chat = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			b.pingSent.Store(time.Now().UnixNano())
+			b.send("ping", nil)
+		case <-chat:
+			if b.phase.Load() == "day" {
+				b.send("chat", map[string]string{"message": fmt.Sprintf("%s 的压测发言", b.name)})
+				b.stats.chats.Add(1)
+			}
+		}
+	}
+}
+
+// handle 处理一条服务端消息
+func (b *loadBot) handle(data []byte) {
+	var msg struct {
+		Type    string          `json:"type"`
+		Phase   string          `json:"phase"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+	if msg.Type == "private" {
+		b.handlePrivate(msg.Content)
+		return
+	}
+
+	switch msg.Type {
+	case "countdown":
+		b.phase.Store(msg.Phase)
+	case "game_started":
+		if b.host {
+			b.stats.gamesStarted.Add(1)
+		}
+	case "game_end":
+		if b.host {
+			b.stats.gamesEnded.Add(1)
+			if b.cfg.Rematch {
+				b.send("game_action", map[string]string{"type": "start_game"})
+			}
+		}
+	}
+}
+
+// handlePrivate 处理私有消息：可执行动作、心跳响应和错误
+func (b *loadBot) handlePrivate(content json.RawMessage) {
+	var msg struct {
+		Type         string `json:"type"`
+		Phase        string `json:"phase"`
+		StateVersion int64  `json:"state_version"`
+		Actions      []struct {
+			Type     string   `json:"type"`
+			Targets  []string `json:"targets"`
+			Optional bool     `json:"optional"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal(content, &msg); err != nil {
+		return
+	}
+
+	switch msg.Type {
+	case "pong":
+		if sent := b.pingSent.Swap(0); sent > 0 {
+			b.stats.addLatency(time.Since(time.Unix(0, sent)))
+		}
+	case "error":
+		b.stats.errors.Add(1)
+	case "available_actions":
+		b.phase.Store(msg.Phase)
+		// 只执行必要动作，女巫等可选动作随机选择是否执行
+		for _, option := range msg.Actions {
+			if len(option.Targets) == 0 || (option.Optional && b.rng.Intn(2) == 0) {
+				continue
+			}
+			b.send("game_action", map[string]interface{}{
+				"type":          option.Type,
+				"target":        option.Targets[b.rng.Intn(len(option.Targets))],
+				"state_version": msg.StateVersion,
+			})
+			b.stats.actions.Add(1)
+			break
+		}
+	}
+}
+
+// send 发送消息，连接已关闭时忽略
+func (b *loadBot) send(msgType string, content interface{}) {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	if b.conn == nil || b.closed {
+		return
+	}
+	b.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	b.conn.WriteJSON(map[string]interface{}{
+		"type":    msgType,
+		"room_id": b.roomID,
+		"content": content,
+	})
+}
+
+// close 关闭连接，可重复调用
+func (b *loadBot) close() {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	if b.conn == nil || b.closed {
+		return
+	}
+	b.closed = true
+	b.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	b.conn.Close()
+}
@@ -0,0 +1,327 @@
+// werewolf-cli 狼人杀命令行客户端，用于在没有前端的情况下测试服务端：
+// 创建和加入房间、通过WebSocket交互式对局、旁观房间以及执行管理命令
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usage = `用法: werewolf-cli [-server 地址] [-token 令牌] <命令> [参数]
+
+服务端地址和令牌也可以通过 WEREWOLF_SERVER、WEREWOLF_TOKEN 环境变量设置，
+令牌可以是登录令牌或API密钥。
+
+账号：
+  guest [-name 名称]                  创建游客会话并打印令牌
+  login -u 用户名 -p 密码             登录并打印令牌
+
+房间和对局：
+  rooms [-queue ranked|casual]        列出房间
+  create -name 名称 [-mode classic] [-max 8] [-ranked] [-whispers] [-ai aggressive=1,cautious=2]
+                                      创建房间，-ai 为AI补位时的性格分布
+  join <房间ID> [-name 名称]          加入房间并交互式对局
+  play <房间ID>                       重新连接已加入的房间
+  watch <房间ID>                      旁观房间，只接收消息
+  status <房间ID>                     查询当前视角的游戏状态
+  history [-limit 20]                 列出最近的对局记录
+
+压测：
+  load [-rooms 10] [-players 6] [-fill 0] [-duration 5m] [-chat 10s] [-rematch]
+                                      每个房间由一组游客机器人加入并随机行动，
+                                      定期输出消息吞吐、动作数和心跳往返延迟
+
+管理（需要管理员账号）：
+  admin bans                          列出封禁
+  admin ban <account|ip> <目标> [-reason 原因] [-hours 0]
+  admin unban <封禁ID>
+  admin reports [-status open]        列出举报
+  admin review <举报ID> <dismiss|ban> [-note 备注] [-hours 0]
+  admin audit [-room 房间ID] [-player 玩家ID]
+  admin retention [run]               查看或立即执行数据清理`
+
+func main() {
+	flag.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	server := flag.String("server", envOr("WEREWOLF_SERVER", "http://localhost:8080"), "服务端地址")
+	token := flag.String("token", os.Getenv("WEREWOLF_TOKEN"), "登录令牌或API密钥")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	api := newAPIClient(*server, *token)
+	if err := run(api, flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "错误:", err)
+		os.Exit(1)
+	}
+}
+
+// errUsage 参数错误，打印用法
+var errUsage = errors.New("参数错误，使用 -h 查看用法")
+
+// run 执行命令
+func run(api *apiClient, command string, args []string) error {
+	switch command {
+	case "guest":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		name := fs.String("name", "", "显示名称")
+		fs.Parse(args)
+		return printToken(api, "/users/guest", map[string]string{"display_name": *name})
+
+	case "login":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		username := fs.String("u", "", "用户名")
+		password := fs.String("p", "", "密码")
+		fs.Parse(args)
+		return printToken(api, "/users/login", map[string]string{"username": *username, "password": *password})
+
+	case "rooms":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		queue := fs.String("queue", "", "按队列筛选：ranked、casual")
+		fs.Parse(args)
+		return printResult(api, http.MethodGet, "/rooms", url.Values{"queue": {*queue}}, nil)
+
+	case "create":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		name := fs.String("name", "", "房间名称")
+		mode := fs.String("mode", "classic", "游戏模式：classic、standard、extended")
+		maxPlayers := fs.Int("max", 8, "最大玩家数")
+		ranked := fs.Bool("ranked", false, "排位房间")
+		whispers := fs.Bool("whispers", false, "允许私聊")
+		personalities := fs.String("ai", "", "AI性格分布，如 aggressive=1,cautious=2")
+		fill := fs.Int("fill", 6, "开局时用AI补足到的人数，0为只允许真人对局")
+		fs.Parse(args)
+		if *name == "" {
+			return errUsage
+		}
+		weights, err := parseWeights(*personalities)
+		if err != nil {
+			return err
+		}
+		return printResult(api, http.MethodPost, "/rooms", nil, map[string]interface{}{
+			"name":             *name,
+			"mode":             *mode,
+			"max_players":      *maxPlayers,
+			"ranked":           *ranked,
+			"allow_whispers":   *whispers,
+			"ai_personalities": weights,
+			"ai_fill":          *fill,
+		})
+
+	case "join":
+		roomID, fs, err := roomArgs(command, args)
+		if err != nil {
+			return err
+		}
+		name := fs.String("name", "", "玩家名称")
+		fs.Parse(args[1:])
+		ticket, err := api.joinRoom(roomID, *name)
+		if err != nil {
+			return err
+		}
+		return runSession(api, roomID, ticket, false)
+
+	case "play", "watch":
+		roomID, _, err := roomArgs(command, args)
+		if err != nil {
+			return err
+		}
+		ticket, err := api.wsTicket(roomID)
+		if err != nil {
+			return err
+		}
+		return runSession(api, roomID, ticket, command == "watch")
+
+	case "status":
+		roomID, _, err := roomArgs(command, args)
+		if err != nil {
+			return err
+		}
+		return printResult(api, http.MethodGet, "/game/status", url.Values{"room_id": {roomID}}, nil)
+
+	case "history":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		limit := fs.Int("limit", 20, "条数")
+		fs.Parse(args)
+		return printResult(api, http.MethodGet, "/games/history", url.Values{"limit": {strconv.Itoa(*limit)}}, nil)
+
+	case "load":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		cfg := loadConfig{}
+		fs.IntVar(&cfg.Rooms, "rooms", 10, "同时进行的房间数")
+		fs.IntVar(&cfg.Players, "players", 6, "每个房间的机器人数")
+		fs.IntVar(&cfg.Fill, "fill", 0, "开局时用内置AI补足到的人数，0为只有机器人")
+		fs.StringVar(&cfg.Mode, "mode", "classic", "游戏模式：classic、standard、extended")
+		fs.DurationVar(&cfg.Duration, "duration", 5*time.Minute, "压测时长")
+		fs.DurationVar(&cfg.ChatInterval, "chat", 10*time.Second, "白天每个机器人的发言间隔，0为不发言")
+		fs.DurationVar(&cfg.PingInterval, "ping", 5*time.Second, "测量往返延迟的心跳间隔")
+		fs.DurationVar(&cfg.Report, "report", 10*time.Second, "输出中间统计的间隔")
+		fs.BoolVar(&cfg.Rematch, "rematch", true, "对局结束后由房主开始下一局")
+		fs.Parse(args)
+		return runLoad(api.server, cfg)
+
+	case "admin":
+		if len(args) == 0 {
+			return errUsage
+		}
+		return runAdmin(api, args[0], args[1:])
+	}
+	return fmt.Errorf("未知命令 %s，使用 -h 查看用法", command)
+}
+
+// runAdmin 执行管理命令
+func runAdmin(api *apiClient, command string, args []string) error {
+	fs := flag.NewFlagSet("admin "+command, flag.ExitOnError)
+	switch command {
+	case "bans":
+		return printResult(api, http.MethodGet, "/admin/bans", nil, nil)
+
+	case "ban":
+		reason := fs.String("reason", "", "封禁原因")
+		hours := fs.Int("hours", 0, "封禁时长，0表示永久")
+		if len(args) < 2 {
+			return errUsage
+		}
+		fs.Parse(args[2:])
+		return printResult(api, http.MethodPost, "/admin/bans", nil, map[string]interface{}{
+			"type":           args[0],
+			"target":         args[1],
+			"reason":         *reason,
+			"duration_hours": *hours,
+		})
+
+	case "unban":
+		if len(args) < 1 {
+			return errUsage
+		}
+		return printResult(api, http.MethodDelete, "/admin/bans/"+url.PathEscape(args[0]), nil, nil)
+
+	case "reports":
+		status := fs.String("status", "open", "举报状态，为空时不限制")
+		fs.Parse(args)
+		return printResult(api, http.MethodGet, "/admin/reports", url.Values{"status": {*status}}, nil)
+
+	case "review":
+		note := fs.String("note", "", "处理备注")
+		hours := fs.Int("hours", 0, "封禁时长，0表示永久")
+		if len(args) < 2 {
+			return errUsage
+		}
+		fs.Parse(args[2:])
+		return printResult(api, http.MethodPost, "/admin/reports/"+url.PathEscape(args[0])+"/review", nil,
+			map[string]interface{}{"decision": args[1], "note": *note, "duration_hours": *hours})
+
+	case "audit":
+		room := fs.String("room", "", "房间ID")
+		player := fs.String("player", "", "玩家ID")
+		fs.Parse(args)
+		return printResult(api, http.MethodGet, "/admin/audit", url.Values{"room": {*room}, "player": {*player}}, nil)
+
+	case "retention":
+		if len(args) > 0 && args[0] == "run" {
+			return printResult(api, http.MethodPost, "/admin/retention/run", nil, nil)
+		}
+		return printResult(api, http.MethodGet, "/admin/retention", nil, nil)
+	}
+	return fmt.Errorf("未知管理命令 %s，使用 -h 查看用法", command)
+}
+
+// roomArgs 解析以房间ID开头的参数，返回供后续参数使用的FlagSet
+func roomArgs(command string, args []string) (string, *flag.FlagSet, error) {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		return "", nil, errUsage
+	}
+	return args[0], flag.NewFlagSet(command, flag.ExitOnError), nil
+}
+
+// runSession 建立房间的WebSocket连接并运行交互会话
+func runSession(api *apiClient, roomID, ticket string, readOnly bool) error {
+	s, err := dialRoom(api, roomID, ticket, readOnly)
+	if err != nil {
+		return err
+	}
+	return s.run(os.Stdin)
+}
+
+// printToken 登录或创建游客会话并打印令牌，可以直接设置为WEREWOLF_TOKEN
+func printToken(api *apiClient, path string, body interface{}) error {
+	var resp struct {
+		Token string `json:"token"`
+		User  struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"display_name"`
+		} `json:"user"`
+	}
+	if err := api.do(http.MethodPost, path, nil, body, &resp); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "玩家 %s（%s）\n", resp.User.DisplayName, resp.User.ID)
+	fmt.Println(resp.Token)
+	return nil
+}
+
+// printResult 调用接口并格式化打印响应
+func printResult(api *apiClient, method, path string, query url.Values, body interface{}) error {
+	for key, values := range query {
+		if len(values) == 0 || values[0] == "" {
+			query.Del(key)
+		}
+	}
+
+	var result json.RawMessage
+	if err := api.do(method, path, query, body, &result); err != nil {
+		return err
+	}
+	printJSON(os.Stdout, result)
+	return nil
+}
+
+// printJSON 缩进打印JSON
+func printJSON(w io.Writer, data json.RawMessage) {
+	out, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		out = data
+	}
+	fmt.Fprintln(w, string(out))
+}
+
+// parseWeights 解析 name=weight 形式的逗号分隔列表，省略权重时为1
+func parseWeights(s string) (map[string]int, error) {
+	weights := make(map[string]int)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, found := strings.Cut(item, "=")
+		weight := 1
+		if found {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("无效的权重 %q", item)
+			}
+			weight = n
+		}
+		weights[name] = weight
+	}
+	return weights, nil
+}
+
+// envOr 读取环境变量，未设置时使用默认值
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// session 一个房间的WebSocket会话，打印收到的消息并把输入转换为动作和聊天
+type session struct {
+	api      *apiClient
+	roomID   string
+	conn     *websocket.Conn
+	readOnly bool
+	out      io.Writer
+	writeMu  sync.Mutex
+}
+
+// dialRoom 使用连接凭证建立房间的WebSocket连接
+func dialRoom(api *apiClient, roomID, ticket string, readOnly bool) (*session, error) {
+	target, err := api.wsURL(ticket, newConnectionID())
+	if err != nil {
+		return nil, err
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("连接服务器失败: %w", err)
+	}
+	return &session{api: api, roomID: roomID, conn: conn, readOnly: readOnly, out: os.Stdout}, nil
+}
+
+// run 运行会话，直到连接断开或输入结束
+func (s *session) run(in io.Reader) error {
+	defer s.conn.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- s.readLoop() }()
+
+	if s.readOnly {
+		fmt.Fprintf(s.out, "正在旁观房间 %s，按 Ctrl+C 退出\n", s.roomID)
+		return <-done
+	}
+
+	fmt.Fprintf(s.out, "已进入房间 %s，输入 /help 查看命令\n", s.roomID)
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case line, ok := <-lines:
+			if !ok {
+				s.close()
+				return nil
+			}
+			if quit := s.handleInput(strings.TrimSpace(line)); quit {
+				s.close()
+				return nil
+			}
+		}
+	}
+}
+
+const sessionHelp = `命令：
+  /start                      开始游戏
+  /act <动作> <目标> [内容]    提交游戏动作，如 /act vote player_2
+  /c <频道> <消息>             在指定频道发言：room, wolf, dead, spectator
+  /w <玩家> <消息>             私聊
+  /status                     查询当前视角的游戏状态
+  /quit                       退出
+其他输入作为房间聊天发送`
+
+// handleInput 处理一行输入，返回true表示退出会话
+func (s *session) handleInput(line string) bool {
+	if line == "" {
+		return false
+	}
+	if !strings.HasPrefix(line, "/") {
+		s.send("chat", map[string]string{"message": line})
+		return false
+	}
+
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "/quit", "/exit":
+		return true
+	case "/help":
+		fmt.Fprintln(s.out, sessionHelp)
+	case "/start":
+		s.send("game_action", map[string]string{"type": "start_game"})
+	case "/act":
+		if len(fields) < 3 {
+			fmt.Fprintln(s.out, "用法: /act <动作> <目标> [内容]")
+			break
+		}
+		s.send("game_action", map[string]string{
+			"type":    fields[1],
+			"target":  fields[2],
+			"content": strings.Join(fields[3:], " "),
+		})
+	case "/c":
+		if len(fields) < 3 {
+			fmt.Fprintln(s.out, "用法: /c <频道> <消息>")
+			break
+		}
+		s.send("chat", map[string]string{"channel": fields[1], "message": strings.Join(fields[2:], " ")})
+	case "/w":
+		if len(fields) < 3 {
+			fmt.Fprintln(s.out, "用法: /w <玩家> <消息>")
+			break
+		}
+		s.send("chat", map[string]string{"channel": "whisper", "to": fields[1], "message": strings.Join(fields[2:], " ")})
+	case "/status":
+		var status json.RawMessage
+		if err := s.api.do(http.MethodGet, "/game/status", url.Values{"room_id": {s.roomID}}, nil, &status); err != nil {
+			fmt.Fprintln(s.out, "查询失败:", err)
+			break
+		}
+		printJSON(s.out, status)
+	default:
+		fmt.Fprintln(s.out, "未知命令，输入 /help 查看命令")
+	}
+	return false
+}
+
+// send 发送消息
+func (s *session) send(msgType string, content interface{}) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	err := s.conn.WriteJSON(map[string]interface{}{
+		"type":    msgType,
+		"room_id": s.roomID,
+		"content": content,
+	})
+	if err != nil {
+		fmt.Fprintln(s.out, "发送失败:", err)
+	}
+}
+
+// close 正常关闭连接
+func (s *session) close() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
+
+// readLoop 读取并打印服务端消息，读取时自动响应服务端的心跳
+func (s *session) readLoop() error {
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return nil
+			}
+			return fmt.Errorf("连接已断开: %w", err)
+		}
+		s.print(data)
+	}
+}
+
+// print 按消息类型格式化输出
+func (s *session) print(data []byte) {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		fmt.Fprintln(s.out, string(data))
+		return
+	}
+
+	// 私有消息的实际内容在content中
+	if msg["type"] == "private" {
+		if content, ok := msg["content"].(map[string]interface{}); ok {
+			msg = content
+		}
+	}
+
+	switch msg["type"] {
+	case "chat":
+		target := ""
+		if to, ok := msg["to"].(string); ok && to != "" {
+			target = " -> " + to
+		}
+		fmt.Fprintf(s.out, "[%v] %v%s: %v\n", msg["channel"], msg["player_id"], target, msg["message"])
+	case "error":
+		fmt.Fprintf(s.out, "错误 %v: %v\n", msg["code"], msg["message"])
+	case "pong", "hello":
+	default:
+		delete(msg, "seq")
+		compact, _ := json.Marshal(msg)
+		fmt.Fprintf(s.out, "<%v> %s\n", msg["type"], compact)
+	}
+}
+
+// newConnectionID 生成随机连接ID
+func newConnectionID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return "cli_" + hex.EncodeToString(b)
+}
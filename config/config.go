@@ -0,0 +1,286 @@
+package config
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Config 服务配置
+type Config struct {
+	Server     ServerConfig     `mapstructure:"server"`
+	WebSocket  WebSocketConfig  `mapstructure:"websocket"`
+	Storage    StorageConfig    `mapstructure:"storage"`
+	Auth       AuthConfig       `mapstructure:"auth"`
+	Season     SeasonConfig     `mapstructure:"season"`
+	Retention  RetentionConfig  `mapstructure:"retention"`
+	Log        LogConfig        `mapstructure:"log"`
+	Bot        BotConfig        `mapstructure:"bot"`
+	Game       GameConfig       `mapstructure:"game"`
+	AI         AIConfig         `mapstructure:"ai"`
+	Narration  NarrationConfig  `mapstructure:"narration"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Reporting  ReportingConfig  `mapstructure:"reporting"`
+	Discord    DiscordConfig    `mapstructure:"discord"`
+	Telegram   TelegramConfig   `mapstructure:"telegram"`
+	WeChat     WeChatConfig     `mapstructure:"wechat"`
+	OAuth      OAuthConfig      `mapstructure:"oauth"`
+	ChatFilter ChatFilterConfig `mapstructure:"chat_filter"`
+	Stream     StreamConfig     `mapstructure:"stream"`
+}
+
+// ServerConfig HTTP服务配置
+type ServerConfig struct {
+	Addr            string        `mapstructure:"addr"`             // 监听地址
+	GRPCAddr        string        `mapstructure:"grpc_addr"`        // gRPC服务的监听地址，为空时不启动gRPC服务
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"` // 收到退出信号后等待连接断开和请求完成的最长时间
+	FrontendDir     string        `mapstructure:"frontend_dir"`     // 前端文件目录，为空时使用编译时嵌入的文件，开发时设置为 ./frontend
+	MaxConnections  int           `mapstructure:"max_connections"`  // WebSocket连接数上限，0表示不限制
+	MaxRooms        int           `mapstructure:"max_rooms"`        // 房间数上限，0表示不限制
+	MaxGames        int           `mapstructure:"max_games"`        // 同时进行的对局数上限，0表示不限制
+}
+
+// WebSocketConfig WebSocket连接配置
+type WebSocketConfig struct {
+	SendBuffer     int    `mapstructure:"send_buffer"`     // 每个连接的发送缓冲区大小
+	OverflowPolicy string `mapstructure:"overflow_policy"` // 缓冲区已满时的处理策略：disconnect、drop_oldest、coalesce
+}
+
+// StorageConfig 持久化存储配置
+type StorageConfig struct {
+	Driver string `mapstructure:"driver"` // memory, sqlite, postgres
+	DSN    string `mapstructure:"dsn"`    // 数据库连接串
+
+	SnapshotInterval time.Duration `mapstructure:"snapshot_interval"` // 进行中对局的快照间隔
+}
+
+// AuthConfig 认证配置
+type AuthConfig struct {
+	JWTSecret string        `mapstructure:"jwt_secret"` // JWT签名密钥，为空时随机生成
+	TokenTTL  time.Duration `mapstructure:"token_ttl"`  // 令牌有效期
+	Admins    []string      `mapstructure:"admins"`     // 管理员玩家ID列表
+}
+
+// SeasonConfig 排位赛季配置
+type SeasonConfig struct {
+	Length time.Duration `mapstructure:"length"` // 赛季时长，到期后自动开启新赛季
+}
+
+// BotConfig 外部机器人配置
+type BotConfig struct {
+	ActionTimeout time.Duration `mapstructure:"action_timeout"` // 每个阶段的行动时限，超时由内置AI代为行动
+}
+
+// GameConfig 对局配置
+type GameConfig struct {
+	Seed            int64         `mapstructure:"seed"`             // 随机数种子，非0时每局都使用该种子，用于复现对局和模拟AI
+	DisconnectGrace time.Duration `mapstructure:"disconnect_grace"` // 断线玩家的宽限期，超时由内置AI代为完成本阶段的动作，0表示不代为行动
+	// TimeoutDefaults 阶段到时仍未完成必要动作时的默认处理
+	TimeoutDefaults TimeoutDefaultsConfig `mapstructure:"timeout_defaults"`
+	AFKThreshold    int                   `mapstructure:"afk_threshold"` // 连续几次到时未完成必要动作后判定挂机，0表示不判定
+	AFKTakeover     bool                  `mapstructure:"afk_takeover"`  // 挂机的玩家由AI接管座位，否则轮到其行动时直接放弃
+	// FinishedRetention 对局结束后完整状态在内存中保留的时长，之后只保留存储中的对局记录，0表示立即释放
+	FinishedRetention time.Duration `mapstructure:"finished_retention"`
+}
+
+// TimeoutDefaultsConfig 各类必要动作到时未完成时的默认处理：skip 放弃，random 随机选择目标
+type TimeoutDefaultsConfig struct {
+	Kill    string `mapstructure:"kill"`    // 狼人击杀，random时跟随同伴已选的目标
+	Check   string `mapstructure:"check"`   // 预言家查验
+	Protect string `mapstructure:"protect"` // 守卫守护
+	Vote    string `mapstructure:"vote"`    // 放逐投票，skip即弃票
+}
+
+// AIConfig 内置AI配置
+type AIConfig struct {
+	TuningFile       string        `mapstructure:"tuning_file"`       // AI调参文件，修改后自动重新加载，为空时使用内置的默认参数
+	LearningInterval time.Duration `mapstructure:"learning_interval"` // 从历史对局汇总AI策略权重的间隔，为0时不汇总
+}
+
+// NarrationConfig 法官旁白配置
+type NarrationConfig struct {
+	TTSURL string `mapstructure:"tts_url"` // 语音合成地址模板，{text}替换为URL编码后的台词，为空时旁白只有文字
+}
+
+// RetentionConfig 数据保留配置，时长为0表示不清理该类数据
+type RetentionConfig struct {
+	Interval   time.Duration `mapstructure:"interval"`    // 清理任务执行间隔，为0时不执行
+	Games      time.Duration `mapstructure:"games"`       // 对局记录保留时长
+	Chat       time.Duration `mapstructure:"chat"`        // 对局聊天记录保留时长
+	Audit      time.Duration `mapstructure:"audit"`       // 审计日志保留时长
+	ArchiveDir string        `mapstructure:"archive_dir"` // 删除对局前的归档目录，为空时直接删除
+}
+
+// TracingConfig 调用链配置
+type TracingConfig struct {
+	Endpoint      string        `mapstructure:"endpoint"`       // OTLP/HTTP采集地址，例如 http://localhost:4318，为空时不导出
+	ServiceName   string        `mapstructure:"service_name"`   // 上报的服务名
+	SampleRatio   float64       `mapstructure:"sample_ratio"`   // 新调用链的采样比例，0到1
+	SlowThreshold time.Duration `mapstructure:"slow_threshold"` // 超过该耗时的片段记录警告日志，0表示不记录
+}
+
+// ReportingConfig 错误上报配置
+type ReportingConfig struct {
+	SentryDSN   string `mapstructure:"sentry_dsn"`  // Sentry项目的DSN，为空时panic只记录日志
+	Environment string `mapstructure:"environment"` // 上报的环境名
+	Release     string `mapstructure:"release"`     // 上报的版本号
+}
+
+// DiscordConfig Discord机器人配置
+type DiscordConfig struct {
+	Token  string `mapstructure:"token"`  // 机器人令牌，为空时不启动Discord机器人
+	Prefix string `mapstructure:"prefix"` // 频道命令的前缀
+}
+
+// TelegramConfig Telegram机器人配置
+type TelegramConfig struct {
+	Token  string `mapstructure:"token"`   // 机器人令牌，为空时不启动Telegram机器人
+	APIURL string `mapstructure:"api_url"` // Bot API地址，为空时使用官方地址
+}
+
+// WeChatConfig 微信小程序配置
+type WeChatConfig struct {
+	AppID           string        `mapstructure:"app_id"`           // 小程序的AppID，与app_secret都配置后开放微信登录
+	AppSecret       string        `mapstructure:"app_secret"`       // 小程序的AppSecret
+	MaxFrameSize    int           `mapstructure:"max_frame_size"`   // 小程序连接单个下行帧的字节数上限，超过时拆分发送
+	ReconnectWindow time.Duration `mapstructure:"reconnect_window"` // 小程序断线后保留座位、等待静默重连的时间
+}
+
+// OAuthConfig 第三方登录配置，平台配置了客户端ID和密钥后开放该平台的登录
+type OAuthConfig struct {
+	BaseURL     string            `mapstructure:"base_url"`     // 服务对外的地址，用于拼接回调地址，为空时使用请求的Host
+	RedirectURL string            `mapstructure:"redirect_url"` // 登录完成后跳转的前端页面，结果放在URL片段中
+	GitHub      OAuthClientConfig `mapstructure:"github"`
+	Google      OAuthClientConfig `mapstructure:"google"`
+	WeChat      OAuthClientConfig `mapstructure:"wechat"` // 微信开放平台的网站应用，与小程序的AppID不同
+}
+
+// OAuthClientConfig 在第三方平台注册的应用
+type OAuthClientConfig struct {
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+}
+
+// ChatFilterConfig 聊天和AI发言的内容过滤配置
+type ChatFilterConfig struct {
+	DefaultMode   string        `mapstructure:"default_mode"`  // 房间未指定时的过滤模式：off、mask、block
+	Words         []string      `mapstructure:"words"`         // 敏感词
+	WordlistFile  string        `mapstructure:"wordlist_file"` // 敏感词表文件，每行一个词，与words合并
+	APIURL        string        `mapstructure:"api_url"`       // 外部内容审核接口，为空时只使用敏感词表
+	APIKey        string        `mapstructure:"api_key"`
+	APITimeout    time.Duration `mapstructure:"api_timeout"`
+	MuteThreshold int           `mapstructure:"mute_threshold"` // 窗口内违规几次后自动禁言，0表示不自动禁言
+	MuteWindow    time.Duration `mapstructure:"mute_window"`
+	MuteDuration  time.Duration `mapstructure:"mute_duration"`
+}
+
+// StreamConfig 直播叠加层和旁观事件流配置
+type StreamConfig struct {
+	Delay time.Duration `mapstructure:"delay"` // 相对实际对局的延迟，0表示不延迟
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level  string `mapstructure:"level"`  // debug、info、warn、error
+	Format string `mapstructure:"format"` // text 或 json
+}
+
+// Load 加载配置，优先级：环境变量 > 配置文件 > 默认值
+// 配置文件为当前目录或 ./config 目录下的 config.yaml，环境变量以 WEREWOLF_ 为前缀，
+// 例如 WEREWOLF_STORAGE_DRIVER=sqlite
+func Load() (*Config, error) {
+	v := viper.New()
+
+	// 默认值
+	v.SetDefault("server.addr", ":8080")
+	v.SetDefault("server.grpc_addr", "")
+	v.SetDefault("server.shutdown_timeout", "10s")
+	v.SetDefault("server.frontend_dir", "")
+	v.SetDefault("server.max_connections", 0)
+	v.SetDefault("server.max_rooms", 0)
+	v.SetDefault("server.max_games", 0)
+	v.SetDefault("websocket.send_buffer", 256)
+	v.SetDefault("websocket.overflow_policy", "disconnect")
+	v.SetDefault("storage.driver", "memory")
+	v.SetDefault("storage.dsn", "")
+	v.SetDefault("storage.snapshot_interval", "10s")
+	v.SetDefault("auth.jwt_secret", "")
+	v.SetDefault("auth.token_ttl", "24h")
+	v.SetDefault("auth.admins", []string{})
+	v.SetDefault("season.length", "720h")
+	v.SetDefault("retention.interval", "24h")
+	v.SetDefault("retention.games", "0")
+	v.SetDefault("retention.chat", "0")
+	v.SetDefault("retention.audit", "0")
+	v.SetDefault("retention.archive_dir", "")
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "text")
+	v.SetDefault("bot.action_timeout", "30s")
+	v.SetDefault("game.seed", 0)
+	v.SetDefault("game.disconnect_grace", "10s")
+	v.SetDefault("game.timeout_defaults.kill", "skip")
+	v.SetDefault("game.timeout_defaults.check", "skip")
+	v.SetDefault("game.timeout_defaults.protect", "skip")
+	v.SetDefault("game.timeout_defaults.vote", "skip")
+	v.SetDefault("game.afk_threshold", 2)
+	v.SetDefault("game.afk_takeover", false)
+	v.SetDefault("game.finished_retention", "10m")
+	v.SetDefault("ai.tuning_file", "")
+	v.SetDefault("ai.learning_interval", "1h")
+	v.SetDefault("narration.tts_url", "")
+	v.SetDefault("tracing.endpoint", "")
+	v.SetDefault("tracing.service_name", "werewolf")
+	v.SetDefault("tracing.sample_ratio", 1.0)
+	v.SetDefault("tracing.slow_threshold", "500ms")
+	v.SetDefault("reporting.sentry_dsn", "")
+	v.SetDefault("reporting.environment", "production")
+	v.SetDefault("reporting.release", "")
+	v.SetDefault("discord.token", "")
+	v.SetDefault("discord.prefix", "!ww")
+	v.SetDefault("telegram.token", "")
+	v.SetDefault("telegram.api_url", "")
+	v.SetDefault("wechat.app_id", "")
+	v.SetDefault("wechat.app_secret", "")
+	v.SetDefault("wechat.max_frame_size", 32768)
+	v.SetDefault("wechat.reconnect_window", "2m")
+	v.SetDefault("oauth.base_url", "")
+	v.SetDefault("oauth.redirect_url", "/")
+	for _, provider := range []string{"github", "google", "wechat"} {
+		v.SetDefault("oauth."+provider+".client_id", "")
+		v.SetDefault("oauth."+provider+".client_secret", "")
+	}
+	v.SetDefault("chat_filter.default_mode", "mask")
+	v.SetDefault("chat_filter.words", []string{})
+	v.SetDefault("chat_filter.wordlist_file", "")
+	v.SetDefault("chat_filter.api_url", "")
+	v.SetDefault("chat_filter.api_key", "")
+	v.SetDefault("chat_filter.api_timeout", "3s")
+	v.SetDefault("chat_filter.mute_threshold", 3)
+	v.SetDefault("chat_filter.mute_window", "10m")
+	v.SetDefault("chat_filter.mute_duration", "10m")
+	v.SetDefault("stream.delay", "30s")
+
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+	v.AddConfigPath(".")
+	v.AddConfigPath("./config")
+
+	v.SetEnvPrefix("WEREWOLF")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, err
+		}
+		slog.Info("未找到配置文件，使用默认配置")
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
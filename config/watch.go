@@ -0,0 +1,34 @@
+package config
+
+import (
+	"log/slog"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// WatchFile 读取YAML文件并在文件修改后重新读取，每次读取都调用apply。
+// apply通过decode把文件内容解析到结构体，结构体中已有的值作为文件未设置字段的默认值；
+// 首次读取失败时返回错误，之后重新读取失败只记录日志，由apply决定是否保留原有配置
+func WatchFile(path string, apply func(decode func(target interface{}) error) error) error {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+
+	if err := v.ReadInConfig(); err != nil {
+		return err
+	}
+	if err := apply(func(target interface{}) error { return v.Unmarshal(target) }); err != nil {
+		return err
+	}
+
+	v.OnConfigChange(func(event fsnotify.Event) {
+		if err := apply(func(target interface{}) error { return v.Unmarshal(target) }); err != nil {
+			slog.Error("重新加载配置文件失败，保留原有配置", "file", path, "error", err)
+			return
+		}
+		slog.Info("配置文件已重新加载", "file", path)
+	})
+	v.WatchConfig()
+	return nil
+}
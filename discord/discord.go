@@ -0,0 +1,191 @@
+// Package discord 通过Discord机器人进行对局：一个频道对应一个房间，玩家在频道中用命令加入、开局和投票，
+// 白天在频道中发言，身份和夜晚行动通过机器人私信完成，规则和对局流程由bridge包和引擎处理。
+// 机器人需要在开发者后台开启Message Content Intent
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qianlnk/werewolf/bridge"
+	"github.com/qianlnk/werewolf/services"
+)
+
+// Discord接口参数
+const (
+	apiURL         = "https://discord.com/api/v10"
+	gatewayURL     = "wss://gateway.discord.gg/?v=10&encoding=json"
+	requestTimeout = 10 * time.Second
+	maxMessageLen  = 2000 // 单条消息的字符数上限
+	maxRetries     = 3    // 遇到限流时的最大重试次数
+	inboxSize      = 256  // 等待处理的消息数上限，已满时丢弃新消息
+)
+
+// Bot Discord机器人，通过Gateway接收消息，通过REST接口发送消息
+type Bot struct {
+	token  string
+	prefix string // 频道命令的前缀，例如 !ww
+	bridge *bridge.Bridge
+	client *http.Client
+
+	mutex      sync.Mutex
+	selfID     string            // 机器人自己的用户ID，READY事件中获得
+	dmChannels map[string]string // 用户ID -> 私信频道ID
+
+	// inbox 收到的消息由一个goroutine按顺序处理，发送回复时的限流等待不会阻塞Gateway的心跳
+	inbox  chan messageCreate
+	ctx    context.Context // 关闭机器人时取消
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New 创建Discord机器人，调用Start后开始连接
+func New(token, prefix string, rooms *services.RoomManager, sockets *services.WebSocketManager) *Bot {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Bot{
+		token:      token,
+		prefix:     prefix,
+		client:     &http.Client{Timeout: requestTimeout},
+		dmChannels: make(map[string]string),
+		inbox:      make(chan messageCreate, inboxSize),
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	b.bridge = bridge.New(b, rooms, sockets, prefix+" ")
+	return b
+}
+
+// Start 在后台连接Gateway，断线后自动重连
+func (b *Bot) Start() {
+	go b.run()
+	go b.process()
+}
+
+// Close 断开Gateway并停止推送房间事件
+func (b *Bot) Close() {
+	b.cancel()
+	b.bridge.Close()
+	<-b.done
+}
+
+// Name 平台名
+func (b *Bot) Name() string {
+	return "discord"
+}
+
+// SendGroup 向频道发送消息，选项渲染为可以直接输入的命令
+func (b *Bot) SendGroup(channelID string, msg bridge.Message) error {
+	return b.sendMessage(channelID, render(msg, b.prefix+" "))
+}
+
+// SendDirect 通过私信向用户发送消息
+func (b *Bot) SendDirect(userID string, msg bridge.Message) error {
+	channelID, err := b.dmChannel(userID)
+	if err != nil {
+		slog.Warn("创建Discord私信频道失败", "user_id", userID, "error", err)
+		return err
+	}
+	return b.sendMessage(channelID, render(msg, ""))
+}
+
+// render 把消息和选项渲染为文本，prefix为选项命令的前缀
+func render(msg bridge.Message, prefix string) string {
+	lines := []string{msg.Text}
+	for _, choice := range msg.Choices {
+		lines = append(lines, fmt.Sprintf("`%s%s`  %s", prefix, choice.Command, choice.Label))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dmChannel 获取与用户的私信频道，首次使用时创建
+func (b *Bot) dmChannel(userID string) (string, error) {
+	b.mutex.Lock()
+	channelID, exists := b.dmChannels[userID]
+	b.mutex.Unlock()
+	if exists {
+		return channelID, nil
+	}
+
+	var channel struct {
+		ID string `json:"id"`
+	}
+	if err := b.request(http.MethodPost, "/users/@me/channels", map[string]string{"recipient_id": userID}, &channel); err != nil {
+		return "", err
+	}
+
+	b.mutex.Lock()
+	b.dmChannels[userID] = channel.ID
+	b.mutex.Unlock()
+	return channel.ID, nil
+}
+
+// sendMessage 向频道发送文本，超过长度上限时按行拆成多条
+func (b *Bot) sendMessage(channelID, text string) error {
+	for _, chunk := range bridge.SplitText(text, maxMessageLen) {
+		body := map[string]any{
+			"content": chunk,
+			// 不解析消息中的@，避免玩家名触发提及
+			"allowed_mentions": map[string]any{"parse": []string{}},
+		}
+		if err := b.request(http.MethodPost, "/channels/"+channelID+"/messages", body, nil); err != nil {
+			slog.Warn("发送Discord消息失败", "channel_id", channelID, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// request 调用Discord的REST接口，遇到限流时按返回的等待时间重试
+func (b *Bot) request(method, path string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(b.ctx, method, apiURL+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bot "+b.token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return err
+		}
+		payload, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRetries {
+			var limited struct {
+				RetryAfter float64 `json:"retry_after"`
+			}
+			json.Unmarshal(payload, &limited)
+			select {
+			case <-time.After(time.Duration(limited.RetryAfter*float64(time.Second)) + 50*time.Millisecond):
+			case <-b.ctx.Done():
+				return b.ctx.Err()
+			}
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("Discord返回 %s: %s", resp.Status, bytes.TrimSpace(payload))
+		}
+		if result != nil {
+			return json.Unmarshal(payload, result)
+		}
+		return nil
+	}
+}
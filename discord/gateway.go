@@ -0,0 +1,256 @@
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/qianlnk/werewolf/bridge"
+	"github.com/qianlnk/werewolf/reporting"
+)
+
+// Gateway的操作码
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatAck   = 11
+)
+
+// intents 订阅的事件：服务器频道消息、私信和消息内容
+const intents = 1<<9 | 1<<12 | 1<<15
+
+// maxBackoff 重连的最长等待时间
+const maxBackoff = time.Minute
+
+// gatewayPayload Gateway收发的消息
+type gatewayPayload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d,omitempty"`
+	S  *int64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// messageCreate MESSAGE_CREATE事件中用到的字段
+type messageCreate struct {
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"` // 私信时为空
+	Content   string `json:"content"`
+	Author    struct {
+		ID         string `json:"id"`
+		Username   string `json:"username"`
+		GlobalName string `json:"global_name"`
+		Bot        bool   `json:"bot"`
+	} `json:"author"`
+	Member *struct {
+		Nick string `json:"nick"`
+	} `json:"member"`
+}
+
+// run 保持与Gateway的连接，断开后按指数退避重连
+func (b *Bot) run() {
+	defer close(b.done)
+
+	backoff := time.Second
+	for {
+		ready, err := b.connect()
+		select {
+		case <-b.ctx.Done():
+			return
+		default:
+		}
+		if ready {
+			backoff = time.Second
+		}
+		slog.Warn("Discord连接断开，稍后重连", "error", err, "retry_in", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-b.ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// connect 建立一次Gateway连接并处理事件，直到连接断开。ready表示连接曾经认证成功
+func (b *Bot) connect() (ready bool, err error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(b.ctx, gatewayURL, nil)
+	if err != nil {
+		return false, err
+	}
+	closed := make(chan struct{})
+	defer close(closed)
+	go func() {
+		select {
+		case <-b.ctx.Done():
+		case <-closed:
+		}
+		conn.Close()
+	}()
+
+	var writeMutex sync.Mutex
+	send := func(op int, data any) error {
+		d, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		return conn.WriteJSON(gatewayPayload{Op: op, D: d})
+	}
+
+	// 连接后服务端先发送Hello，给出心跳间隔
+	var hello struct {
+		HeartbeatInterval int64 `json:"heartbeat_interval"`
+	}
+	var payload gatewayPayload
+	if err := conn.ReadJSON(&payload); err != nil {
+		return false, err
+	}
+	if payload.Op != opHello || json.Unmarshal(payload.D, &hello) != nil || hello.HeartbeatInterval <= 0 {
+		return false, fmt.Errorf("意外的Gateway消息：op=%d", payload.Op)
+	}
+
+	if err := send(opIdentify, map[string]any{
+		"token":      b.token,
+		"intents":    intents,
+		"properties": map[string]string{"os": "linux", "browser": "werewolf", "device": "werewolf"},
+	}); err != nil {
+		return false, err
+	}
+
+	var (
+		seqMutex sync.Mutex
+		seq      *int64
+		acked    = true
+	)
+	heartbeat := func() error {
+		seqMutex.Lock()
+		last := seq
+		seqMutex.Unlock()
+		return send(opHeartbeat, last)
+	}
+
+	// 心跳：上一次心跳没有收到确认时视为连接已失效，断开后重连
+	go func() {
+		ticker := time.NewTicker(time.Duration(hello.HeartbeatInterval) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				seqMutex.Lock()
+				missed := !acked
+				acked = false
+				seqMutex.Unlock()
+				if missed || heartbeat() != nil {
+					conn.Close()
+					return
+				}
+			case <-closed:
+				return
+			}
+		}
+	}()
+
+	for {
+		var payload gatewayPayload
+		if err := conn.ReadJSON(&payload); err != nil {
+			return ready, err
+		}
+		if payload.S != nil {
+			seqMutex.Lock()
+			seq = payload.S
+			seqMutex.Unlock()
+		}
+
+		switch payload.Op {
+		case opHeartbeat:
+			if err := heartbeat(); err != nil {
+				return ready, err
+			}
+		case opHeartbeatAck:
+			seqMutex.Lock()
+			acked = true
+			seqMutex.Unlock()
+		case opReconnect:
+			return ready, errors.New("服务端要求重连")
+		case opInvalidSession:
+			return ready, errors.New("会话已失效")
+		case opDispatch:
+			switch payload.T {
+			case "READY":
+				var data struct {
+					User struct {
+						ID string `json:"id"`
+					} `json:"user"`
+				}
+				json.Unmarshal(payload.D, &data)
+				b.mutex.Lock()
+				b.selfID = data.User.ID
+				b.mutex.Unlock()
+				ready = true
+				slog.Info("Discord机器人已连接", "user_id", data.User.ID)
+			case "MESSAGE_CREATE":
+				var msg messageCreate
+				if json.Unmarshal(payload.D, &msg) != nil {
+					break
+				}
+				select {
+				case b.inbox <- msg:
+				default:
+					slog.Warn("Discord消息处理不及，丢弃消息", "channel_id", msg.ChannelID)
+				}
+			}
+		}
+	}
+}
+
+// process 按收到的顺序处理消息，直到机器人关闭
+func (b *Bot) process() {
+	for {
+		select {
+		case msg := <-b.inbox:
+			reporting.Call("discord.message", func() { b.handleMessage(msg) })
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
+
+// handleMessage 把频道和私信中的消息交给bridge处理，忽略机器人发出的消息
+func (b *Bot) handleMessage(msg messageCreate) {
+	b.mutex.Lock()
+	self := b.selfID
+	b.mutex.Unlock()
+	if msg.Author.Bot || msg.Author.ID == self {
+		return
+	}
+
+	user := bridge.User{ID: msg.Author.ID, Name: msg.Author.Username}
+	if msg.Author.GlobalName != "" {
+		user.Name = msg.Author.GlobalName
+	}
+	if msg.Member != nil && msg.Member.Nick != "" {
+		user.Name = msg.Member.Nick
+	}
+
+	content := strings.TrimSpace(msg.Content)
+	if msg.GuildID == "" {
+		b.bridge.HandleDirect(user, content)
+		return
+	}
+	if rest, ok := strings.CutPrefix(content, b.prefix); ok && (rest == "" || rest[0] == ' ') {
+		b.bridge.HandleGroupCommand(msg.ChannelID, user, strings.Fields(rest))
+		return
+	}
+	b.bridge.HandleGroupText(msg.ChannelID, user, content)
+}
@@ -0,0 +1,29 @@
+// Package frontend 前端页面和静态资源，编译时嵌入二进制，部署时不再需要 ./frontend 目录
+package frontend
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"os"
+)
+
+//go:embed *.html css js
+var embedded embed.FS
+
+// FS 获取前端文件，dir不为空时使用磁盘上的目录，开发时修改前端无需重新编译
+func FS(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	return embedded
+}
+
+// Dir 获取前端文件中的子目录，用于静态文件服务
+func Dir(fsys fs.FS, name string) http.FileSystem {
+	sub, err := fs.Sub(fsys, name)
+	if err != nil {
+		panic(err)
+	}
+	return http.FS(sub)
+}
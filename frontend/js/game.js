@@ -5,6 +5,9 @@ let currentPlayer = null;
 let isConnecting = false;
 let reconnectAttempts = 0;
 let connectionId = null;
+let playerPresence = {}; // 玩家ID -> 在线状态
+let countdownTimer = null;
+let serverRestartDelay = 0; // 服务器重启通知中建议的重连等待时间（毫秒）
 const maxReconnectAttempts = 5;
 const reconnectDelay = 3000;
 
@@ -87,7 +90,24 @@ function initGameWebSocket() {
         connectionId = generateConnectionId();
     }
     
-    const wsUrl = `ws://${window.location.host}/ws?room=${currentRoom}&player=${currentPlayer.id}&connection_id=${connectionId}`;
+    // 先获取短期有效的连接凭证，凭证中包含房间和玩家身份
+    authFetch(`/api/v1/rooms/${currentRoom}/ws-ticket`, { method: 'POST' })
+        .then(response => {
+            if (!response.ok) {
+                throw new Error('获取连接凭证失败: ' + response.status);
+            }
+            return response.json();
+        })
+        .then(data => openGameWebSocket(data.ticket))
+        .catch(error => {
+            console.error('初始化WebSocket失败:', error);
+            isConnecting = false;
+        });
+}
+
+// 使用连接凭证建立游戏WebSocket连接
+function openGameWebSocket(ticket) {
+    const wsUrl = `ws://${window.location.host}/ws?ticket=${encodeURIComponent(ticket)}&connection_id=${connectionId}`;
     
     try {
         gameWs = new WebSocket(wsUrl);
@@ -124,6 +144,15 @@ function initGameWebSocket() {
             isConnecting = false;
             gameWs = null;
 
+            // 服务器重启不计入重连次数，按服务端建议的时间后重连
+            if (event.code === 1012 || serverRestartDelay > 0) {
+                const delay = serverRestartDelay || reconnectDelay;
+                serverRestartDelay = 0;
+                console.log(`服务器正在重启, 等待${delay/1000}秒后重新连接...`);
+                setTimeout(initGameWebSocket, delay);
+                return;
+            }
+
             if (document.visibilityState === 'visible' && reconnectAttempts < maxReconnectAttempts) {
                 reconnectAttempts++;
                 const delay = Math.min(reconnectDelay * Math.pow(2, reconnectAttempts - 1), 30000);
@@ -193,8 +222,19 @@ function handleGameMessage(message) {
                     updatePlayerList(message.players);
                 }
                 break;
+            case 'countdown':
+                // 服务端定期推送的剩余时间，用于校正本地倒计时
+                updateCountdown(message.time_left);
+                break;
+            case 'presence':
+                // 玩家上线、断线重连中或离线
+                updatePlayerPresence(message.player_id, message.status);
+                break;
             case 'room_update':
                 // 直接处理房间更新消息
+                if (message.presence) {
+                    Object.assign(playerPresence, message.presence);
+                }
                 if (message.content && message.content.players) {
                     updatePlayerList(message.content.players);
                 } else if (message.players) {
@@ -237,12 +277,34 @@ function handlePrivateMessage(content) {
         case 'role_assign':
             updatePlayerRole(content);
             break;
+        case 'resync':
+            // 断线重连后根据服务端快照恢复界面
+            if (content.is_started) {
+                $('#startGameBtn').hide();
+                $('.role-info').show();
+                $('#roleName').text(content.role);
+                $('#playerStatus').text(content.self && content.self.alive ? '存活' : '已死亡');
+            }
+            updateGameState(content);
+            if (content.players) {
+                updatePlayerList(content.players);
+            }
+            break;
+        case 'chat_history':
+            // 加入或重连房间时补发的最近聊天记录
+            $('#chatBox').empty();
+            (content.messages || []).forEach(appendChatMessage);
+            break;
+        case 'server_shutdown':
+            // 服务器即将重启，连接关闭后等待指定时间再重连
+            serverRestartDelay = (content.reconnect_after || 0) * 1000;
+            break;
     }
 }
 
 // 更新房间信息
 function updateRoomInfo() {
-    fetch(`/api/rooms/${currentRoom}`)
+    authFetch(`/api/v1/rooms/${currentRoom}`)
         .then(response => response.json())
         .then(room => {
             $('#roomName').text(room.name);
@@ -271,10 +333,13 @@ function updatePlayerList(players) {
         }
         
         const isCurrentPlayer = player.id === currentPlayer.id;
+        const presence = playerPresence[player.id];
         const playerCard = $('<div>')
             .addClass('player-card')
             .addClass(player.alive === false ? 'dead' : 'alive')
             .addClass(isCurrentPlayer ? 'current-player' : '')
+            .addClass(presence && presence !== 'online' ? 'offline' : '')
+            .attr('data-player-id', player.id)
             .html(`
                 <div class="player-name">${player.name || '未知玩家'}${isCurrentPlayer ? ' (你)' : ''}</div>
                 ${player.role && (isCurrentPlayer || !player.alive) ? `<div class="player-role">角色: ${player.role}</div>` : ''}
@@ -284,6 +349,32 @@ function updatePlayerList(players) {
     });
 }
 
+// 更新玩家在线状态，不在线的玩家置灰显示
+function updatePlayerPresence(playerId, status) {
+    playerPresence[playerId] = status;
+    $(`.player-card[data-player-id="${playerId}"]`).toggleClass('offline', status !== 'online');
+}
+
+// 按服务端给出的剩余秒数在本地逐秒刷新倒计时，不依赖客户端与服务端时钟一致
+function updateCountdown(timeLeft) {
+    if (countdownTimer) {
+        clearInterval(countdownTimer);
+        countdownTimer = null;
+    }
+
+    const deadline = Date.now() + timeLeft * 1000;
+    const render = () => {
+        const left = Math.max(0, Math.round((deadline - Date.now()) / 1000));
+        $('#gameTimer').text(`剩余时间: ${left}秒`);
+        if (left === 0 && countdownTimer) {
+            clearInterval(countdownTimer);
+            countdownTimer = null;
+        }
+    };
+    render();
+    countdownTimer = setInterval(render, 1000);
+}
+
 // 更新游戏状态
 function updateGameState(state) {
     let statusText = state.status || '等待开始';
@@ -294,8 +385,8 @@ function updateGameState(state) {
         }
     }
     $('#gameStatus').text(statusText);
-    if (state.time_left) {
-        $('#gameTimer').text(`剩余时间: ${state.time_left}秒`);
+    if (state.time_left !== undefined) {
+        updateCountdown(state.time_left);
     }
     if (state.players) {
         updatePlayerList(state.players);
@@ -310,12 +401,23 @@ function updateGameState(state) {
     }
 }
 
+// 聊天频道名称
+const chatChannelNames = {
+    wolf: '狼人',
+    dead: '死亡',
+    spectator: '旁观',
+    whisper: '私聊'
+};
+
 // 添加聊天消息
 function appendChatMessage(chat) {
     const chatBox = $('#chatBox');
+    const channelName = chatChannelNames[chat.channel];
+    const channelLabel = channelName ? `<span class="chat-channel">[${channelName}]</span> ` : '';
     const messageDiv = $('<div>')
         .addClass('chat-message')
-        .html(`<span class="chat-player">${chat.player_id === currentPlayer.id ? '你' : chat.player_id}:</span> ${chat.message}`);
+        .addClass(chat.channel ? 'chat-' + chat.channel : '')
+        .html(`${channelLabel}<span class="chat-player">${chat.player_id === currentPlayer.id ? '你' : chat.player_id}:</span> ${chat.message}`);
     chatBox.append(messageDiv);
     chatBox.scrollTop(chatBox[0].scrollHeight);
 }
@@ -333,13 +435,18 @@ function sendMessage() {
     const message = input.val().trim();
     
     if (message && gameWs && gameWs.readyState === WebSocket.OPEN) {
+        const channel = $('#chatChannel').val() || 'room';
         const chatMessage = {
             type: 'chat',
             room_id: currentRoom,
             content: {
+                channel: channel,
                 message: message
             }
         };
+        if (channel === 'whisper') {
+            chatMessage.content.to = $('#chatTarget').val().trim();
+        }
         
         gameWs.send(JSON.stringify(chatMessage));
         input.val('');
@@ -415,4 +522,13 @@ function sendGameAction(actionType, targetId) {
     };
 
     gameWs.send(JSON.stringify(gameAction));
-}
\ No newline at end of file
+}
+// 携带登录令牌发起请求
+function authFetch(url, options = {}) {
+    const token = localStorage.getItem('token');
+    const headers = Object.assign({}, options.headers);
+    if (token) {
+        headers['Authorization'] = 'Bearer ' + token;
+    }
+    return fetch(url, Object.assign({}, options, { headers: headers }));
+}
@@ -0,0 +1,149 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/qianlnk/werewolf/models"
+	werewolfpb "github.com/qianlnk/werewolf/proto"
+	"github.com/qianlnk/werewolf/reporting"
+	"github.com/qianlnk/werewolf/services"
+)
+
+// playerKey 上下文中已认证的玩家ID
+type playerKey struct{}
+
+// playerID 获取请求已认证的玩家ID
+func playerID(ctx context.Context) string {
+	id, _ := ctx.Value(playerKey{}).(string)
+	return id
+}
+
+// apiKeyKey 上下文中请求使用的API密钥
+type apiKeyKey struct{}
+
+// apiKey 获取请求使用的API密钥，使用登录令牌时为nil
+func apiKey(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(apiKeyKey{}).(*models.APIKey)
+	return key
+}
+
+// methodScopes 使用API密钥调用各方法需要的权限，与对应的HTTP接口相同
+var methodScopes = map[string]string{
+	werewolfpb.GameService_CreateRoom_FullMethodName:    models.ScopeBot,
+	werewolfpb.GameService_ListRooms_FullMethodName:     models.ScopeSpectate,
+	werewolfpb.GameService_JoinRoom_FullMethodName:      models.ScopeBot,
+	werewolfpb.GameService_SubmitAction_FullMethodName:  models.ScopeBot,
+	werewolfpb.GameService_GetGameStatus_FullMethodName: models.ScopeSpectate,
+	werewolfpb.GameService_StreamEvents_FullMethodName:  models.ScopeSpectate,
+}
+
+// authenticate 校验authorization元数据中的令牌或API密钥，返回带有玩家ID的上下文。
+// API密钥需要具有方法对应的权限，所属账号或客户端IP被封禁时拒绝
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	token := requestToken(ctx)
+	if services.IsAPIKey(token) {
+		key, err := s.auth.ParseAPIKey(token)
+		if err != nil {
+			return nil, statusError(ctx, err)
+		}
+		scope, exists := methodScopes[method]
+		if !exists || !key.HasScope(scope) {
+			return nil, statusError(ctx, services.ErrInsufficientScope)
+		}
+		// 所属账号被封禁后密钥随之失效
+		if err := s.moderation.CheckBanned(key.OwnerID, peerIP(ctx)); err != nil {
+			return nil, statusError(ctx, err)
+		}
+		ctx = context.WithValue(ctx, apiKeyKey{}, key)
+		return context.WithValue(ctx, playerKey{}, key.OwnerID), nil
+	}
+
+	claims, err := s.auth.ParseToken(token)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	if err := s.moderation.CheckBanned(claims.Subject, peerIP(ctx)); err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return context.WithValue(ctx, playerKey{}, claims.Subject), nil
+}
+
+// peerIP 客户端的IP地址，无法获取时为空
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// requestToken 从authorization元数据读取令牌或API密钥，格式与HTTP的Authorization请求头相同
+func requestToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if token := strings.TrimPrefix(value, "Bearer "); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// authenticateUnary 认证普通请求
+func (s *Server) authenticateUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authenticateStream 认证流式请求
+func (s *Server) authenticateStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream 带有已认证玩家ID的流
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// recoverUnary 捕获处理中的panic并上报，客户端收到内部错误，不影响其他请求
+func recoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			reporting.Report(reporting.PanicEvent(value, "grpc", "method", info.FullMethod))
+			err = statusError(ctx, services.NewError(services.CodeInternal, "服务器内部错误"))
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// recoverStream 捕获流式请求处理中的panic并上报
+func recoverStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			reporting.Report(reporting.PanicEvent(value, "grpc", "method", info.FullMethod))
+			err = statusError(stream.Context(), services.NewError(services.CodeInternal, "服务器内部错误"))
+		}
+	}()
+	return handler(srv, stream)
+}
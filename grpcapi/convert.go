@@ -0,0 +1,154 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/qianlnk/werewolf/models"
+	werewolfpb "github.com/qianlnk/werewolf/proto"
+	"github.com/qianlnk/werewolf/services"
+)
+
+// errorDomain 错误详情ErrorInfo的domain
+const errorDomain = "werewolf"
+
+// grpcCodes 错误码对应的gRPC状态码，未列出的错误码视为请求参数错误
+var grpcCodes = map[services.ErrorCode]codes.Code{
+	services.CodeUnauthorized:      codes.Unauthenticated,
+	services.CodeInvalidToken:      codes.Unauthenticated,
+	services.CodeForbidden:         codes.PermissionDenied,
+	services.CodeInsufficientScope: codes.PermissionDenied,
+	services.CodeBanned:            codes.PermissionDenied,
+	services.CodeNotInRoom:         codes.PermissionDenied,
+	services.CodeNotHost:           codes.PermissionDenied,
+	services.CodeNotJudge:          codes.PermissionDenied,
+	services.CodeNotFound:          codes.NotFound,
+	services.CodeRoomNotFound:      codes.NotFound,
+	services.CodePlayerNotFound:    codes.NotFound,
+	services.CodeGameNotFound:      codes.NotFound,
+	services.CodeConflict:          codes.AlreadyExists,
+	services.CodeShuttingDown:      codes.Unavailable,
+	services.CodeServerBusy:        codes.ResourceExhausted,
+	services.CodeInternal:          codes.Internal,
+	services.CodeRoomFull:          codes.FailedPrecondition,
+	services.CodeGameNotStarted:    codes.FailedPrecondition,
+	services.CodeGameInProgress:    codes.FailedPrecondition,
+	services.CodeNotEnoughPlayers:  codes.FailedPrecondition,
+	services.CodeNotYourTurn:       codes.FailedPrecondition,
+	services.CodePhaseIncomplete:   codes.FailedPrecondition,
+	services.CodePlayerDead:        codes.FailedPrecondition,
+	services.CodeStateChanged:      codes.Aborted,
+}
+
+// statusError 将服务错误转换为gRPC状态，消息按客户端的accept-language元数据本地化，
+// 错误码放在ErrorInfo详情的reason中，与HTTP接口响应中的code相同
+func statusError(ctx context.Context, err error) error {
+	code := services.ErrorCodeOf(err)
+	grpcCode, exists := grpcCodes[code]
+	if !exists {
+		grpcCode = codes.InvalidArgument
+	}
+
+	st := status.New(grpcCode, services.LocalizedError(err, requestLocale(ctx)))
+	if detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{Reason: string(code), Domain: errorDomain}); detailErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// requestLocale 从accept-language元数据获取客户端的语言，不支持时为空，即默认语言
+func requestLocale(ctx context.Context) services.Locale {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return services.ParseLocale(strings.Join(md.Get("accept-language"), ","))
+}
+
+// playerMessage 转换玩家信息
+func playerMessage(player models.Player) *werewolfpb.Player {
+	return &werewolfpb.Player{
+		Id:          player.ID,
+		Name:        player.Name,
+		Type:        string(player.Type),
+		Role:        string(player.Role),
+		Alive:       player.Alive,
+		Personality: string(player.Personality),
+	}
+}
+
+// playerMessages 转换玩家列表
+func playerMessages(players []models.Player) []*werewolfpb.Player {
+	messages := make([]*werewolfpb.Player, 0, len(players))
+	for _, player := range players {
+		messages = append(messages, playerMessage(player))
+	}
+	return messages
+}
+
+// roomMessage 转换房间信息，room应为RoomInfo或ListRooms返回的公开信息，玩家列表不含角色和情侣身份
+func roomMessage(room *models.Room) *werewolfpb.Room {
+	message := &werewolfpb.Room{
+		Id:            room.ID,
+		Name:          room.Name,
+		Mode:          string(room.Mode),
+		Players:       playerMessages(room.Players),
+		MaxPlayers:    int32(room.MaxPlayers),
+		GameStarted:   room.GameStarted,
+		Ranked:        room.Ranked,
+		AllowWhispers: room.AllowWhispers,
+		AiFill:        int32(room.AIFill),
+		Status:        string(room.Status),
+		HostId:        room.HostID,
+		Sheriff:       room.Sheriff,
+	}
+	if len(room.AIPersonalities) > 0 {
+		message.AiPersonalities = make(map[string]int32, len(room.AIPersonalities))
+		for personality, weight := range room.AIPersonalities {
+			message.AiPersonalities[string(personality)] = int32(weight)
+		}
+	}
+	if len(room.Roles) > 0 {
+		message.Roles = make(map[string]int32, len(room.Roles))
+		for role, count := range room.Roles {
+			message.Roles[string(role)] = int32(count)
+		}
+	}
+	return message
+}
+
+// statusMessage 转换玩家视角的游戏状态
+func statusMessage(view *models.PlayerGameView) *werewolfpb.GameStatus {
+	message := &werewolfpb.GameStatus{
+		IsStarted:    view.IsStarted,
+		Phase:        view.Phase,
+		Round:        int32(view.Round),
+		Role:         string(view.Role),
+		Players:      playerMessages(view.Players),
+		AlivePlayers: view.AlivePlayers,
+		Actions:      view.Actions,
+		TimeLeft:     int32(view.TimeLeft),
+		PhaseEndsAt:  view.PhaseEndsAt,
+		NightStep:    view.NightStep,
+		Teammates:    view.Teammates,
+		Sheriff:      view.Sheriff,
+		Candidates:   view.Candidates,
+		StateVersion: view.StateVersion,
+	}
+	for _, option := range view.AvailableActions {
+		message.AvailableActions = append(message.AvailableActions, &werewolfpb.ActionOption{
+			Type:     option.Type,
+			Targets:  option.Targets,
+			Optional: option.Optional,
+		})
+	}
+	if len(view.KnownRoles) > 0 {
+		message.KnownRoles = make(map[string]string, len(view.KnownRoles))
+		for playerID, role := range view.KnownRoles {
+			message.KnownRoles[playerID] = string(role)
+		}
+	}
+	return message
+}
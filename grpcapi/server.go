@@ -0,0 +1,275 @@
+// Package grpcapi 以gRPC提供房间和对局接口，供机器人、原生移动端等非浏览器客户端使用。
+// 接口定义见 proto/game_service.proto，房间、对局和事件流与HTTP和WebSocket接口共用 services 包的实现
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/qianlnk/werewolf/models"
+	werewolfpb "github.com/qianlnk/werewolf/proto"
+	"github.com/qianlnk/werewolf/services"
+)
+
+// streamPollTimeout 事件流每次等待新事件的最长时间，到时后检查连接和服务器是否仍然可用
+const streamPollTimeout = 25 * time.Second
+
+// Server GameService的实现
+type Server struct {
+	werewolfpb.UnimplementedGameServiceServer
+
+	rooms      *services.RoomManager
+	sockets    *services.WebSocketManager
+	auth       *services.AuthManager
+	moderation *services.ModerationManager
+	grpc       *grpc.Server
+
+	// ctx 服务器关闭时取消，让进行中的事件流尽快返回
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New 创建gRPC服务，调用Serve后开始接受连接
+func New(rooms *services.RoomManager, sockets *services.WebSocketManager, auth *services.AuthManager, moderation *services.ModerationManager) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		rooms:      rooms,
+		sockets:    sockets,
+		auth:       auth,
+		moderation: moderation,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	s.grpc = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(recoverUnary, s.authenticateUnary),
+		grpc.ChainStreamInterceptor(recoverStream, s.authenticateStream),
+	)
+	werewolfpb.RegisterGameServiceServer(s.grpc, s)
+	return s
+}
+
+// Serve 在lis上接受连接，直到Stop被调用
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// Stop 结束进行中的事件流并等待正在处理的请求完成，ctx到期后直接断开剩余的连接
+func (s *Server) Stop(ctx context.Context) {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpc.Stop()
+	}
+}
+
+// CreateRoom 创建房间
+func (s *Server) CreateRoom(ctx context.Context, req *werewolfpb.CreateRoomRequest) (*werewolfpb.Room, error) {
+	if req.Name == "" || req.Mode == "" || req.MaxPlayers <= 0 {
+		return nil, statusError(ctx, services.NewError(services.CodeInvalidRequest, "缺少房间名称、模式或人数上限"))
+	}
+	aiFill := min(services.DefaultAIFill, int(req.MaxPlayers))
+	if req.AiFill != nil {
+		aiFill = int(*req.AiFill)
+	}
+
+	personalities := make(map[models.AIPersonality]int, len(req.AiPersonalities))
+	for personality, weight := range req.AiPersonalities {
+		personalities[models.AIPersonality(personality)] = int(weight)
+	}
+	var roles map[models.Role]int
+	if len(req.Roles) > 0 {
+		roles = make(map[models.Role]int, len(req.Roles))
+		for role, count := range req.Roles {
+			roles[models.Role(role)] = int(count)
+		}
+	}
+
+	room, err := s.rooms.CreateRoom(req.Name, models.GameMode(req.Mode), int(req.MaxPlayers), req.Ranked, req.AllowWhispers,
+		req.ChatFilter, services.Locale(req.Locale), personalities, aiFill, roles, req.Sheriff, "")
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	if room, err = s.rooms.RoomInfo(room.ID); err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return roomMessage(room), nil
+}
+
+// ListRooms 查询房间列表
+func (s *Server) ListRooms(ctx context.Context, req *werewolfpb.ListRoomsRequest) (*werewolfpb.ListRoomsResponse, error) {
+	rooms := s.rooms.ListRooms(req.Queue, models.RoomStatus(req.Status))
+	resp := &werewolfpb.ListRoomsResponse{Rooms: make([]*werewolfpb.Room, 0, len(rooms))}
+	for _, room := range rooms {
+		resp.Rooms = append(resp.Rooms, roomMessage(room))
+	}
+	return resp, nil
+}
+
+// JoinRoom 以当前玩家的身份加入房间，之后通过StreamEvents接收对局事件。
+// 使用API密钥加入的是外部机器人，未在bot.action_timeout内行动时由内置AI代为行动
+func (s *Server) JoinRoom(ctx context.Context, req *werewolfpb.JoinRoomRequest) (*werewolfpb.JoinRoomResponse, error) {
+	player := models.Player{ID: playerID(ctx), Name: req.Name, Type: models.HumanPlayer}
+	if apiKey(ctx) != nil {
+		player.Type = models.BotPlayer
+	}
+
+	// 对局进行中，以旁观者身份进入房间，与HTTP接口相同
+	spectator := false
+	if err := s.rooms.JoinRoom(req.RoomId, player); err == services.ErrGameInProgress {
+		spectator = true
+	} else if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	room, err := s.rooms.RoomInfo(req.RoomId)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	ticket, expiresAt, err := s.auth.IssueWSTicket(player.ID, req.RoomId, spectator)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return &werewolfpb.JoinRoomResponse{
+		Room:            roomMessage(room),
+		Spectator:       spectator,
+		WsTicket:        ticket,
+		WsTicketExpires: expiresAt,
+	}, nil
+}
+
+// SubmitAction 提交游戏动作，校验与WebSocket的game_action相同
+func (s *Server) SubmitAction(ctx context.Context, req *werewolfpb.SubmitActionRequest) (*werewolfpb.SubmitActionResponse, error) {
+	action := models.GameAction{
+		Type:         req.Type,
+		PlayerID:     playerID(ctx),
+		TargetID:     req.Target,
+		RoomID:       req.RoomId,
+		Content:      req.Content,
+		StateVersion: req.StateVersion,
+	}
+	if _, err := s.rooms.GetPlayer(req.RoomId, action.PlayerID); err != nil {
+		if err != services.ErrRoomNotFound {
+			err = services.ErrNotInRoom
+		}
+		s.rooms.AuditRejectedAction(action, services.AuditSourceGRPC, "", err)
+		return nil, statusError(ctx, err)
+	}
+
+	game, exists := s.rooms.GetGameController(req.RoomId)
+	if !exists {
+		s.rooms.AuditRejectedAction(action, services.AuditSourceGRPC, "", services.ErrGameNotFound)
+		return nil, statusError(ctx, services.ErrGameNotFound)
+	}
+	var err error
+	if req.Type == "start_game" {
+		err = game.StartGame(action.PlayerID)
+	} else {
+		err = game.ProcessAction(ctx, action, services.AuditSourceGRPC, "")
+	}
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return &werewolfpb.SubmitActionResponse{}, nil
+}
+
+// GetGameStatus 查询当前玩家视角的游戏状态
+func (s *Server) GetGameStatus(ctx context.Context, req *werewolfpb.GetGameStatusRequest) (*werewolfpb.GameStatus, error) {
+	playerID := playerID(ctx)
+	if err := s.requireMember(req.RoomId, playerID); err != nil {
+		return nil, statusError(ctx, err)
+	}
+	game, exists := s.rooms.GetGameController(req.RoomId)
+	if !exists {
+		return nil, statusError(ctx, services.ErrGameNotFound)
+	}
+	view, err := game.BuildStatus(playerID)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return statusMessage(view), nil
+}
+
+// StreamEvents 推送房间中序号大于since的事件：房间成员收到与WebSocket连接相同的个人视角事件，
+// 不在房间中时收到延迟stream.delay的旁观者事件，与 GET /rooms/:id/stream 相同
+func (s *Server) StreamEvents(req *werewolfpb.StreamEventsRequest, stream werewolfpb.GameService_StreamEventsServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	stop := context.AfterFunc(s.ctx, cancel)
+	defer stop()
+
+	playerID := playerID(ctx)
+	wait := func(since int64) (*services.RoomEvents, error) {
+		return s.sockets.WaitRoomEvents(ctx, req.RoomId, playerID, since, streamPollTimeout)
+	}
+	if err := s.requireMember(req.RoomId, playerID); err == services.ErrRoomNotFound {
+		return statusError(ctx, err)
+	} else if err != nil {
+		delay := s.rooms.StreamDelay()
+		wait = func(since int64) (*services.RoomEvents, error) {
+			return s.sockets.WaitSpectatorEvents(ctx, req.RoomId, since, delay, streamPollTimeout)
+		}
+	}
+
+	since := req.Since
+	for {
+		events, err := wait(since)
+		if err != nil {
+			// 客户端已断开或服务器正在关闭
+			return nil
+		}
+		if err := sendEvents(stream, events); err != nil {
+			return err
+		}
+		since = events.LastSeq
+	}
+}
+
+// requireMember 玩家不在房间中时返回错误
+func (s *Server) requireMember(roomID, playerID string) error {
+	if _, err := s.rooms.GetPlayer(roomID, playerID); err != nil {
+		if err == services.ErrRoomNotFound {
+			return err
+		}
+		return services.ErrNotInRoom
+	}
+	return nil
+}
+
+// sendEvents 推送一批房间事件，部分事件已过期时先推送resync
+func sendEvents(stream werewolfpb.GameService_StreamEventsServer, events *services.RoomEvents) error {
+	if !events.Complete {
+		if err := stream.Send(&werewolfpb.Envelope{Seq: events.LastSeq, Type: "resync"}); err != nil {
+			return err
+		}
+	}
+	for _, event := range events.Events {
+		envelope, err := eventEnvelope(event)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(envelope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eventEnvelope 将房间事件转换为Envelope，seq、type、room_id之外的字段放入payload，与WebSocket的protobuf编码一致
+func eventEnvelope(event services.RoomEvent) (*werewolfpb.Envelope, error) {
+	envelope, err := services.JSONEnvelope(event.Data)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Seq = event.Seq
+	return envelope, nil
+}
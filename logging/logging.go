@@ -0,0 +1,63 @@
+// Package logging 配置全局的结构化日志
+// 各模块通过 log/slog 输出日志，并以属性的形式附带请求ID、房间ID、玩家ID等关联信息，
+// 例如 slog.Info("玩家加入房间", "room_id", roomID, "player_id", playerID)
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// 日志格式
+const (
+	FormatText = "text" // key=value 文本格式，便于本地阅读
+	FormatJSON = "json" // 每行一个JSON对象，便于日志系统采集
+)
+
+// ParseLevel 解析日志级别：debug、info、warn、error，为空时为info
+func ParseLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(value) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("不支持的日志级别 %s", value)
+}
+
+// Setup 按配置创建日志处理器并设置为默认日志，标准库log的输出也会转到该处理器
+func Setup(level, format string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl, AddSource: lvl == slog.LevelDebug}
+	var handler slog.Handler
+	switch format {
+	case "", FormatText:
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("不支持的日志格式 %s", format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// NewRequestID 生成请求ID
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,13 +1,36 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/qianlnk/werewolf/config"
+	"github.com/qianlnk/werewolf/discord"
+	"github.com/qianlnk/werewolf/frontend"
+	"github.com/qianlnk/werewolf/grpcapi"
+	"github.com/qianlnk/werewolf/logging"
 	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/reporting"
 	"github.com/qianlnk/werewolf/services"
+	"github.com/qianlnk/werewolf/storage"
+	"github.com/qianlnk/werewolf/telegram"
+	"github.com/qianlnk/werewolf/tracing"
 )
 
 var (
@@ -19,23 +42,153 @@ var (
 
 	roomManager  *services.RoomManager
 	webSocketMgr *services.WebSocketManager
-	gameManager  = services.NewGameManager()
+	gameStore    storage.Store
+	accountMgr   *services.AccountManager
+	authMgr      *services.AuthManager
+	wechatAuth   *services.WeChatAuth
+	oauthMgr     *services.OAuthManager
+	oauthCfg     config.OAuthConfig
+	miniProgram  services.ClientProfile // 微信小程序连接的参数
+	friendMgr    *services.FriendManager
+	tournaments  *services.TournamentManager
+	moderation   *services.ModerationManager
+	retention    *services.RetentionManager
+	aiLearning   *services.AILearningManager
+	discordBot   *discord.Bot
+	telegramBot  *telegram.Bot
+	grpcServer   *grpcapi.Server
 )
 
 func init() {
-	// 设置日志格式，包含文件名和行号
-	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-
 	webSocketMgr = services.NewWebSocketManager(nil)
 	roomManager = services.NewRoomManager(webSocketMgr)
 	webSocketMgr.SetRoomManager(roomManager)
-
-	// 添加日志记录
-	log.Printf("初始化完成: WebSocket管理器和房间管理器已配置")
 }
 
 func main() {
-	r := gin.Default()
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("加载配置失败", err)
+	}
+	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format); err != nil {
+		fatal("加载配置失败", err)
+	}
+	flushTraces := tracing.Setup(tracing.Config{
+		Endpoint:      cfg.Tracing.Endpoint,
+		ServiceName:   cfg.Tracing.ServiceName,
+		SampleRatio:   cfg.Tracing.SampleRatio,
+		SlowThreshold: cfg.Tracing.SlowThreshold,
+	})
+	flushReports, err := reporting.Setup(reporting.Config{
+		SentryDSN:   cfg.Reporting.SentryDSN,
+		Environment: cfg.Reporting.Environment,
+		Release:     cfg.Reporting.Release,
+	})
+	if err != nil {
+		fatal("加载配置失败", err)
+	}
+
+	overflowPolicy, err := services.ParseOverflowPolicy(cfg.WebSocket.OverflowPolicy)
+	if err != nil {
+		fatal("加载配置失败", err)
+	}
+	webSocketMgr.SetSendQueue(cfg.WebSocket.SendBuffer, overflowPolicy)
+	webSocketMgr.SetMaxConnections(cfg.Server.MaxConnections)
+	roomManager.SetCapacity(cfg.Server.MaxRooms, cfg.Server.MaxGames)
+	roomManager.SetBotTimeout(cfg.Bot.ActionTimeout)
+	roomManager.SetGameSeed(cfg.Game.Seed)
+	roomManager.SetDisconnectGrace(cfg.Game.DisconnectGrace)
+	timeoutDefaults, err := parseTimeoutDefaults(cfg.Game.TimeoutDefaults)
+	if err != nil {
+		fatal("加载配置失败", err)
+	}
+	roomManager.SetTimeoutDefaults(timeoutDefaults)
+	roomManager.SetAFKPolicy(cfg.Game.AFKThreshold, cfg.Game.AFKTakeover)
+	roomManager.SetFinishedGameRetention(cfg.Game.FinishedRetention)
+	roomManager.SetNarrationVoice(cfg.Narration.TTSURL)
+	chatModerator, err := newChatModerator(cfg.ChatFilter)
+	if err != nil {
+		fatal("加载聊天过滤配置失败", err)
+	}
+	roomManager.SetChatModerator(chatModerator)
+	roomManager.SetStreamDelay(cfg.Stream.Delay)
+	if cfg.AI.TuningFile != "" {
+		err := config.WatchFile(cfg.AI.TuningFile, func(decode func(interface{}) error) error {
+			tuning := services.DefaultAITuning()
+			if err := decode(&tuning); err != nil {
+				return err
+			}
+			return services.SetAITuning(tuning)
+		})
+		if err != nil {
+			fatal("加载AI调参失败", err)
+		}
+	}
+
+	// 初始化持久化存储并恢复房间
+	gameStore, err = storage.New(cfg.Storage.Driver, cfg.Storage.DSN)
+	if err != nil {
+		fatal("初始化存储失败", err)
+	}
+	defer gameStore.Close()
+
+	roomManager.SetStore(gameStore)
+	accountMgr = services.NewAccountManager(gameStore)
+	friendMgr = services.NewFriendManager(gameStore, webSocketMgr)
+	moderation = services.NewModerationManager(gameStore)
+	authMgr = services.NewAuthManager(gameStore, cfg.Auth.JWTSecret, cfg.Auth.TokenTTL, cfg.Auth.Admins)
+	wechatAuth = services.NewWeChatAuth(cfg.WeChat.AppID, cfg.WeChat.AppSecret)
+	miniProgram = services.MiniProgramProfile(cfg.WeChat.MaxFrameSize, cfg.WeChat.ReconnectWindow)
+
+	// 配置了客户端ID和密钥的平台开放第三方登录
+	oauthCfg = cfg.OAuth
+	oauthMgr = services.NewOAuthManager()
+	if client := cfg.OAuth.GitHub; client.ClientID != "" && client.ClientSecret != "" {
+		oauthMgr.Register(services.ProviderGitHub, services.NewGitHubProvider(client.ClientID, client.ClientSecret))
+	}
+	if client := cfg.OAuth.Google; client.ClientID != "" && client.ClientSecret != "" {
+		oauthMgr.Register(services.ProviderGoogle, services.NewGoogleProvider(client.ClientID, client.ClientSecret))
+	}
+	if client := cfg.OAuth.WeChat; client.ClientID != "" && client.ClientSecret != "" {
+		oauthMgr.Register(services.ProviderWeChat, services.NewWeChatProvider(client.ClientID, client.ClientSecret))
+	}
+	if err := roomManager.LoadRooms(); err != nil {
+		slog.Error("恢复房间失败", "error", err)
+	}
+	roomManager.StartSnapshotLoop(cfg.Storage.SnapshotInterval)
+	tournaments = services.NewTournamentManager(gameStore, roomManager, webSocketMgr)
+	if err := tournaments.Load(); err != nil {
+		slog.Error("恢复赛事失败", "error", err)
+	}
+	roomManager.SetTournaments(tournaments)
+	roomManager.Seasons().Start(cfg.Season.Length)
+
+	// 定期清理过期数据
+	retention = services.NewRetentionManager(gameStore, services.RetentionPolicy{
+		Games:      cfg.Retention.Games,
+		Chat:       cfg.Retention.Chat,
+		Audit:      cfg.Retention.Audit,
+		ArchiveDir: cfg.Retention.ArchiveDir,
+	})
+	retention.Start(cfg.Retention.Interval)
+
+	// 定期从历史对局中汇总AI的策略权重
+	aiLearning = services.NewAILearningManager(gameStore)
+	aiLearning.Start(cfg.AI.LearningInterval)
+
+	// 配置了机器人令牌时可以在Discord频道中对局
+	if cfg.Discord.Token != "" {
+		discordBot = discord.New(cfg.Discord.Token, cfg.Discord.Prefix, roomManager, webSocketMgr)
+		discordBot.Start()
+	}
+	// 配置了机器人令牌时可以在Telegram群组中对局
+	if cfg.Telegram.Token != "" {
+		telegramBot = telegram.New(cfg.Telegram.Token, cfg.Telegram.APIURL, roomManager, webSocketMgr)
+		telegramBot.Start()
+	}
+
+	r := gin.New()
+	r.Use(requestLogging(), recovery())
 
 	// 设置跨域中间件
 	r.Use(func(c *gin.Context) {
@@ -52,13 +205,17 @@ func main() {
 		c.Next()
 	})
 
-	// 静态文件服务
-	r.Static("/css", "./frontend/css")
-	r.Static("/js", "./frontend/js")
-	r.Static("/static", "./frontend/static")
+	// 静态文件服务，默认使用编译时嵌入的前端文件，配置了前端目录时直接读取磁盘
+	assets := frontend.FS(cfg.Server.FrontendDir)
+	r.StaticFS("/css", frontend.Dir(assets, "css"))
+	r.StaticFS("/js", frontend.Dir(assets, "js"))
 
-	// 加载HTML模板
-	r.LoadHTMLGlob("frontend/*.html")
+	// 加载HTML模板，使用磁盘目录时在调试模式下每次请求重新加载
+	if cfg.Server.FrontendDir != "" {
+		r.LoadHTMLGlob(filepath.Join(cfg.Server.FrontendDir, "*.html"))
+	} else {
+		r.SetHTMLTemplate(template.Must(template.ParseFS(assets, "*.html")))
+	}
 
 	// 前端页面路由
 	r.GET("/", func(c *gin.Context) {
@@ -69,71 +226,683 @@ func main() {
 		c.HTML(http.StatusOK, "game.html", nil)
 	})
 
-	// WebSocket连接处理
+	// 健康检查，附带当前的容量使用量，服务器正在关闭时返回503
+	r.GET("/healthz", healthz)
+
+	// WebSocket连接处理，玩家身份来自令牌而不是查询参数
+	// 使用加入房间或ws-ticket接口签发的短期凭证建立连接，房间和玩家身份都来自凭证
 	r.GET("/ws", func(c *gin.Context) {
-		ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		claims, err := authMgr.ParseWSTicket(c.Query("ticket"))
 		if err != nil {
-			log.Printf("升级WebSocket连接失败: %v", err)
+			respondError(c, http.StatusUnauthorized, err)
 			return
 		}
 
 		// 获取房间ID、玩家ID和连接ID
-		roomID := c.Query("room")
-		playerID := c.Query("player")
+		roomID := claims.RoomID
+		playerID := claims.Subject
 		connectionID := c.Query("connection_id")
 
-		if roomID == "" || playerID == "" || connectionID == "" {
-			log.Printf("缺少必要的连接参数")
-			ws.Close()
+		if connectionID == "" {
+			respondError(c, http.StatusBadRequest, errors.New("缺少必要的连接参数"))
+			return
+		}
+
+		// 协商协议版本，不支持的版本在升级连接前拒绝
+		protocol, err := services.ParseProtocolVersion(c.Query("protocol"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":        err.Error(),
+				"min_protocol": services.MinProtocolVersion,
+				"max_protocol": services.CurrentProtocolVersion,
+			})
+			return
+		}
+
+		encoding, err := services.ParseEncoding(c.Query("encoding"))
+		if err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		// 被封禁的账号或IP不能建立连接
+		if rejectBanned(c, playerID) {
+			return
+		}
+
+		// 连接数已达上限时在升级前拒绝，客户端稍后重试
+		if err := webSocketMgr.CheckConnectionCapacity(); err != nil {
+			respondError(c, http.StatusServiceUnavailable, err)
+			return
+		}
+
+		ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			requestLogger(c).Warn("升级WebSocket连接失败", "error", err)
 			return
 		}
 
-		// 注册WebSocket连接，传入连接ID
-		webSocketMgr.RegisterConnection(playerID, ws, connectionID)
+		// 注册WebSocket连接，传入连接ID，错误信息使用客户端的语言
+		profile := services.WebProfile
+		profile.Locale = requestLocale(c)
+		webSocketMgr.RegisterConnection(playerID, ws, connectionID, protocol, encoding, claims.ReadOnly, profile)
 		webSocketMgr.JoinRoom(roomID, playerID)
 	})
 
-	// API路由组
-	api := r.Group("/api")
+	// 微信小程序的WebSocket连接，直接使用登录令牌认证，省去申请连接凭证的请求；
+	// 固定使用JSON和最新协议，超过帧大小上限的消息拆成chunk消息发送。
+	// 小程序切到后台断线后，在窗口期内重连不会向房间广播掉线，携带last_seq时从该序号续传事件
+	r.GET("/ws/mp", func(c *gin.Context) {
+		claims, err := authMgr.ParseToken(requestToken(c))
+		if err != nil {
+			respondError(c, http.StatusUnauthorized, err)
+			return
+		}
+		playerID := claims.Subject
+
+		roomID := c.Query("room_id")
+		if roomID == "" {
+			respondError(c, http.StatusBadRequest, errors.New("缺少必要的连接参数"))
+			return
+		}
+		if !requireRoomMember(c, roomID, playerID) || rejectBanned(c, playerID) {
+			return
+		}
+
+		// 小程序每次重连使用同一个连接ID，新连接替换尚未超时的旧连接
+		connectionID := c.DefaultQuery("connection_id", "miniprogram")
+		lastSeq := int64(-1)
+		if value := c.Query("last_seq"); value != "" {
+			if lastSeq, err = strconv.ParseInt(value, 10, 64); err != nil || lastSeq < 0 {
+				respondError(c, http.StatusBadRequest, errors.New("无效的事件序号"))
+				return
+			}
+		}
+
+		if err := webSocketMgr.CheckConnectionCapacity(); err != nil {
+			respondError(c, http.StatusServiceUnavailable, err)
+			return
+		}
+
+		ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			requestLogger(c).Warn("升级WebSocket连接失败", "error", err)
+			return
+		}
+
+		profile := miniProgram
+		profile.Locale = requestLocale(c)
+		webSocketMgr.RegisterConnection(playerID, ws, connectionID, services.CurrentProtocolVersion, services.EncodingJSON, false, profile)
+		if lastSeq >= 0 {
+			webSocketMgr.ResumeRoom(roomID, playerID, lastSeq)
+		} else {
+			webSocketMgr.JoinRoom(roomID, playerID)
+		}
+	})
+
+	// 对局回放，按原始（或加速）时间间隔推送事件
+	r.GET("/ws/replay", authRequired(models.ScopeSpectate), func(c *gin.Context) {
+		record, err := gameStore.GetGameRecord(c.Query("game"))
+		if err != nil {
+			statusCode := http.StatusInternalServerError
+			if err == storage.ErrNotFound {
+				statusCode = http.StatusNotFound
+			}
+			respondError(c, statusCode, err)
+			return
+		}
+
+		speed, err := strconv.ParseFloat(c.DefaultQuery("speed", "1"), 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, errors.New("无效的回放倍速"))
+			return
+		}
+
+		ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			requestLogger(c).Warn("升级WebSocket连接失败", "error", err)
+			return
+		}
+		defer ws.Close()
+
+		if err := services.StreamReplay(ws, record, speed); err != nil && err != services.ErrReplayAborted {
+			requestLogger(c).Info("推送对局回放失败", "game_id", record.ID, "error", err)
+		}
+	})
+
+	// 当前版本的接口，之后不兼容的改动在新版本（如/api/v2）中发布
+	registerAPIRoutes(r.Group("/api/v1"))
+
+	// 兼容旧客户端：/api 下的接口与 /api/v1 相同，响应带有弃用提示
+	registerAPIRoutes(r.Group("/api", legacyAPI()))
+
+	// 长轮询和SSE请求使用的上下文，服务器关闭时取消，让这些请求尽快返回
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	srv := &http.Server{
+		Addr:        cfg.Server.Addr,
+		Handler:     r,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	srv.RegisterOnShutdown(cancelRequests)
+
+	// 启动服务器
+	go func() {
+		slog.Info("服务器启动", "addr", cfg.Server.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("服务器启动失败", err)
+		}
+	}()
+
+	// 配置了监听地址时同时提供gRPC接口
+	if cfg.Server.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+		if err != nil {
+			fatal("gRPC服务启动失败", err)
+		}
+		grpcServer = grpcapi.New(roomManager, webSocketMgr, authMgr, moderation)
+		go func() {
+			slog.Info("gRPC服务启动", "addr", cfg.Server.GRPCAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				fatal("gRPC服务启动失败", err)
+			}
+		}()
+	}
+
+	// 等待退出信号
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+
+	shutdown(srv, cfg.Server.ShutdownTimeout, flushTraces, flushReports)
+}
+
+// registerAPIRoutes 在指定的路由组下注册一个版本的API
+func registerAPIRoutes(v *gin.RouterGroup) {
+	// 注册、登录和游客会话无需令牌
+	v.POST("/users/register", registerUser)
+	v.POST("/users/login", loginUser)
+	v.POST("/users/guest", createGuest)
+	v.POST("/users/wechat", wechatLogin)
+
+	// 第三方登录：浏览器跳转到授权页面，授权后回到callback，再带着结果跳转到前端页面
+	v.GET("/oauth/providers", listOAuthProviders)
+	v.GET("/oauth/:provider/login", oauthLogin)
+	v.GET("/oauth/:provider/callback", oauthCallback)
+
+	// 仅限登录令牌，API密钥不能访问账号、好友和举报相关的接口
+	user := v.Group("", authRequired(""))
 	{
+		// 账号相关
+		user.POST("/users/upgrade", upgradeGuest)
+		user.GET("/users/identities", listIdentities)
+		user.POST("/users/identities/:provider", linkIdentity)
+		user.DELETE("/users/identities/:provider", unlinkIdentity)
+
+		// API密钥管理
+		user.GET("/api-keys", listAPIKeys)
+		user.POST("/api-keys", createAPIKey)
+		user.DELETE("/api-keys/:id", revokeAPIKey)
+
+		// 好友相关
+		user.GET("/friends", listFriends)
+		user.POST("/friends/:id", addFriend)
+		user.DELETE("/friends/:id", removeFriend)
+		user.POST("/rooms/:id/invite", inviteFriend)
+
+		// 举报
+		user.POST("/reports", createReport)
+	}
+
+	// 只读接口，API密钥需要spectate权限
+	read := v.Group("", authRequired(models.ScopeSpectate))
+	{
+		read.GET("/users/:id", getUserInfo)
+		read.GET("/players/:id/stats", getPlayerStats)
+		read.GET("/players/:id/achievements", getPlayerAchievements)
+		read.GET("/achievements", listAchievements)
+
+		// 排位赛季相关
+		read.GET("/seasons", listSeasons)
+		read.GET("/seasons/current", getCurrentSeason)
+		read.GET("/seasons/:id/standings", getSeasonStandings)
+
+		// 赛事相关
+		read.GET("/tournaments", listTournaments)
+		read.GET("/tournaments/:id", getTournament)
+		read.GET("/tournaments/:id/standings", getTournamentStandings)
+		read.GET("/tournaments/:id/matches", listTournamentMatches)
+
 		// 游戏房间相关
-		api.POST("/rooms", createRoom)
-		api.GET("/rooms", listRooms)
-		api.GET("/rooms/:id", getRoomInfo)
-		api.POST("/rooms/:id/join", joinRoom)
-		api.GET("/rooms/:id/players/:playerId", getPlayerInfo)
+		read.GET("/rooms", listRooms)
+		read.GET("/rooms/:id", getRoomInfo)
+		read.POST("/rooms/:id/ws-ticket", issueWSTicket)
+		read.GET("/rooms/:id/events", getRoomEvents)
+		read.GET("/rooms/:id/chat", getRoomChat)
+		read.GET("/rooms/:id/overlay", getRoomOverlay)
+		read.GET("/rooms/:id/stream", getRoomStream)
+		read.GET("/rooms/:id/players/:playerId", getPlayerInfo)
+		read.GET("/game/status", getGameStatus)
 
-		// 游戏操作相关
-		api.POST("/game/action", gameAction)
-		api.GET("/game/status", getGameStatus)
+		// 对局记录相关
+		read.GET("/games/history", listGameHistory)
+		read.GET("/games/:id", getGameRecord)
+		read.GET("/games/:id/events", getGameEvents)
+		read.GET("/games/:id/state", getGameState)
+		read.GET("/games/:id/chat", getGameChat)
+		read.GET("/games/:id/export", exportGame)
+		read.GET("/games/:id/story", getGameStory)
 	}
 
-	// 启动服务器
-	log.Println("服务器启动在 :8080 端口")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatal("服务器启动失败:", err)
+	// 参与对局，API密钥需要bot权限
+	play := v.Group("", authRequired(models.ScopeBot))
+	{
+		play.POST("/rooms", createRoom)
+		play.POST("/rooms/:id/join", joinRoom)
+		play.POST("/game/action", gameAction)
+
+		// 法官（上帝模式）
+		play.POST("/rooms/:id/judge", claimJudge)
+		play.DELETE("/rooms/:id/judge", releaseJudge)
+		play.GET("/rooms/:id/judge/state", getJudgeState)
+		play.POST("/rooms/:id/judge/start", judgeStartGame)
+		play.POST("/rooms/:id/judge/advance", judgeAdvance)
+		play.POST("/rooms/:id/judge/kill", judgeKill)
+		play.POST("/rooms/:id/judge/revive", judgeRevive)
+		play.POST("/rooms/:id/judge/notes", judgeNote)
+
+		// 赛事
+		play.POST("/tournaments", createTournament)
+		play.POST("/tournaments/:id/register", registerTournament)
+		play.DELETE("/tournaments/:id/register", withdrawTournament)
+		play.POST("/tournaments/:id/start", startTournament)
+		play.POST("/tournaments/:id/matches/:matchId/start", startTournamentMatch)
+	}
+
+	// 管理接口，仅限配置的管理员账号，API密钥需要admin权限
+	admin := v.Group("/admin", authRequired(models.ScopeAdmin), adminRequired())
+	{
+		admin.GET("/audit", listAuditLog)
+		admin.GET("/rooms/:id/ai/:playerId", getAIDebugInfo)
+		admin.POST("/rooms/:id/rollback", rollbackPhase)
+		admin.GET("/ai/tuning", getAITuning)
+		admin.GET("/ai/learning", getAILearning)
+		admin.POST("/ai/learning/run", runAILearning)
+		admin.GET("/reports", listReports)
+		admin.POST("/reports/:id/review", reviewReport)
+		admin.GET("/bans", listBans)
+		admin.POST("/bans", createBan)
+		admin.DELETE("/bans/:id", deleteBan)
+		admin.GET("/mutes", listMutes)
+		admin.DELETE("/mutes/:playerId", deleteMute)
+		admin.GET("/retention", getRetentionMetrics)
+		admin.GET("/games", getGameMetrics)
+		admin.GET("/capacity", getCapacity)
+		admin.POST("/retention/run", runRetention)
 	}
 }
 
+// legacyAPI 标记未带版本号的旧路径已弃用，并通过Link头指向对应的 /api/v1 路径
+func legacyAPI() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		successor := "/api/v1" + strings.TrimPrefix(c.Request.URL.Path, "/api")
+		c.Header("Deprecation", "true")
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+		c.Next()
+	}
+}
+
+// shutdown 优雅关闭服务器：停止创建房间，通知并断开WebSocket连接，
+// 保存进行中对局的快照并停止各对局，最后等待HTTP请求完成，重启后对局从快照恢复
+func shutdown(srv *http.Server, timeout time.Duration, flushTraces, flushReports func(context.Context) error) {
+	slog.Info("收到退出信号，开始关闭服务器")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	roomManager.StopAccepting()
+	if discordBot != nil {
+		discordBot.Close()
+	}
+	if telegramBot != nil {
+		telegramBot.Close()
+	}
+	if grpcServer != nil {
+		grpcServer.Stop(ctx)
+	}
+
+	if err := webSocketMgr.Shutdown(ctx); err != nil {
+		slog.Warn("等待WebSocket连接断开超时", "error", err)
+	}
+
+	roomManager.SaveSnapshots()
+	roomManager.Close()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("关闭HTTP服务失败", "error", err)
+	}
+	if err := flushTraces(ctx); err != nil {
+		slog.Warn("导出剩余的调用链超时", "error", err)
+	}
+	if err := flushReports(ctx); err != nil {
+		slog.Warn("上报剩余的错误超时", "error", err)
+	}
+	slog.Info("服务器已关闭")
+}
+
+// parseTimeoutDefaults 解析各类必要动作的超时默认处理
+func parseTimeoutDefaults(cfg config.TimeoutDefaultsConfig) (services.TimeoutDefaults, error) {
+	var defaults services.TimeoutDefaults
+	for _, field := range []struct {
+		value  string
+		policy *services.TimeoutPolicy
+	}{
+		{cfg.Kill, &defaults.Kill},
+		{cfg.Check, &defaults.Check},
+		{cfg.Protect, &defaults.Protect},
+		{cfg.Vote, &defaults.Vote},
+	} {
+		policy, err := services.ParseTimeoutPolicy(field.value)
+		if err != nil {
+			return defaults, err
+		}
+		*field.policy = policy
+	}
+	return defaults, nil
+}
+
+// newChatModerator 按配置创建聊天内容审核，没有敏感词且未配置外部审核接口时返回nil，不做过滤
+func newChatModerator(cfg config.ChatFilterConfig) (*services.ChatModerator, error) {
+	words := cfg.Words
+	if cfg.WordlistFile != "" {
+		loaded, err := services.LoadWordlist(cfg.WordlistFile)
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, loaded...)
+	}
+
+	var filters []services.ContentFilter
+	if wordlist := services.NewWordlistFilter(words); wordlist.Len() > 0 {
+		filters = append(filters, wordlist)
+	}
+	if cfg.APIURL != "" {
+		filters = append(filters, services.NewModerationAPIFilter(cfg.APIURL, cfg.APIKey, cfg.APITimeout))
+	}
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	return services.NewChatModerator(cfg.DefaultMode, services.MutePolicy{
+		Threshold: cfg.MuteThreshold,
+		Window:    cfg.MuteWindow,
+		Duration:  cfg.MuteDuration,
+	}, filters...)
+}
+
+// fatal 记录错误并退出进程
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// recovery 捕获处理函数中的panic并上报，返回500，不影响其他请求和连接
+// 放在requestLogging之后，请求日志和调用链中能看到这次失败
+func recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			tags := []string{"method", c.Request.Method, "path", c.Request.URL.Path, "request_id", c.Writer.Header().Get(requestIDHeader)}
+			if playerID := c.GetString("player_id"); playerID != "" {
+				tags = append(tags, "player_id", playerID)
+			}
+			reporting.Report(reporting.PanicEvent(value, "http", tags...))
+			// 已经开始写响应（例如WebSocket握手之后）时无法再返回错误
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			respondError(c, http.StatusInternalServerError, errors.New("服务器内部错误"))
+		}()
+		c.Next()
+	}
+}
+
+// requestIDHeader 请求ID的请求头和响应头，客户端未提供时由服务端生成
+const requestIDHeader = "X-Request-ID"
+
+// traceparentHeader W3C Trace Context的请求头和响应头，客户端传入时请求的片段延续客户端的调用链
+const traceparentHeader = "traceparent"
+
+// requestLogging 为每个请求分配请求ID和调用链片段，并在请求结束后记录访问日志
+func requestLogging() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" {
+			requestID = logging.NewRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := tracing.StartRemote(c.Request.Context(), c.Request.Method+" "+route, c.GetHeader(traceparentHeader))
+		defer span.Finish()
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(traceparentHeader, span.Traceparent())
+		c.Set("logger", slog.With(append([]any{"request_id", requestID}, span.LogAttrs()...)...))
+
+		start := time.Now()
+		c.Next()
+
+		span.SetAttr("http.method", c.Request.Method)
+		span.SetAttr("http.route", route)
+		span.SetAttr("http.status_code", c.Writer.Status())
+		span.SetAttr("request_id", requestID)
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			span.SetError(errors.New(http.StatusText(c.Writer.Status())))
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+		}
+		if playerID := c.GetString("player_id"); playerID != "" {
+			attrs = append(attrs, "player_id", playerID)
+		}
+		if roomID := c.Param("id"); roomID != "" && strings.Contains(c.FullPath(), "/rooms/:id") {
+			attrs = append(attrs, "room_id", roomID)
+		}
+		requestLogger(c).Info("HTTP请求", attrs...)
+	}
+}
+
+// requestLogger 获取附带请求ID的日志
+func requestLogger(c *gin.Context) *slog.Logger {
+	if logger, ok := c.Get("logger"); ok {
+		return logger.(*slog.Logger)
+	}
+	return slog.Default()
+}
+
+// authRequired 校验请求携带的JWT或API密钥，并记录当前玩家ID
+// 令牌从 Authorization: Bearer 头读取，WebSocket连接无法设置请求头时可使用 token 查询参数
+// API密钥以所属账号的身份访问，需要具有scope权限，scope为空表示接口不接受API密钥
+func authRequired(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := requestToken(c)
+		if services.IsAPIKey(token) {
+			key, err := authMgr.ParseAPIKey(token)
+			if err != nil {
+				respondError(c, http.StatusUnauthorized, err)
+				return
+			}
+			if scope == "" || !key.HasScope(scope) {
+				respondError(c, http.StatusForbidden, services.ErrInsufficientScope)
+				return
+			}
+			// 所属账号被封禁后密钥随之失效
+			if rejectBanned(c, key.OwnerID) {
+				return
+			}
+
+			c.Set("player_id", key.OwnerID)
+			c.Set("api_key", key)
+			c.Next()
+			return
+		}
+
+		claims, err := authMgr.ParseToken(token)
+		if err != nil {
+			respondError(c, http.StatusUnauthorized, err)
+			return
+		}
+		// 令牌签发后客户端IP才被封禁时同样拒绝
+		if rejectBanned(c, claims.Subject) {
+			return
+		}
+
+		c.Set("player_id", claims.Subject)
+		c.Next()
+	}
+}
+
+// requestToken 请求携带的令牌或API密钥，优先使用Authorization请求头，其次是token查询参数
+func requestToken(c *gin.Context) string {
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); token != "" {
+		return token
+	}
+	return c.Query("token")
+}
+
+// adminRequired 限制仅管理员可访问，需在authRequired之后使用
+func adminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authMgr.IsAdmin(currentPlayerID(c)) {
+			respondError(c, http.StatusForbidden, errors.New("需要管理员权限"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// errorCodesByStatus 没有错误码的错误按HTTP状态码归类
+var errorCodesByStatus = map[int]services.ErrorCode{
+	http.StatusBadRequest:   services.CodeInvalidRequest,
+	http.StatusUnauthorized: services.CodeUnauthorized,
+	http.StatusForbidden:    services.CodeForbidden,
+	http.StatusNotFound:     services.CodeNotFound,
+	http.StatusConflict:     services.CodeConflict,
+}
+
+// respondError 返回错误响应并中止后续处理，error为本地化的错误信息，code为机器可读的错误码
+func respondError(c *gin.Context, status int, err error) {
+	code := services.ErrorCodeOf(err)
+	if fallback, exists := errorCodesByStatus[status]; exists && code == services.CodeInternal {
+		code = fallback
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": services.LocalizedError(err, requestLocale(c)), "code": code})
+}
+
+// requestLocale 获取客户端的语言：优先使用lang参数，其次是Accept-Language头，都不支持时为空，即默认语言
+func requestLocale(c *gin.Context) services.Locale {
+	if locale := services.ParseLocale(c.Query("lang")); locale != "" {
+		return locale
+	}
+	return services.ParseLocale(c.GetHeader("Accept-Language"))
+}
+
+// rejectBanned 账号或客户端IP被封禁时返回403，返回true表示请求已被拒绝
+func rejectBanned(c *gin.Context, playerID string) bool {
+	err := moderation.CheckBanned(playerID, c.ClientIP())
+	if err == nil {
+		return false
+	}
+
+	if _, banned := err.(*services.BannedError); banned {
+		respondError(c, http.StatusForbidden, err)
+	} else {
+		respondError(c, http.StatusInternalServerError, err)
+	}
+	return true
+}
+
+// currentPlayerID 获取当前请求已认证的玩家ID
+func currentPlayerID(c *gin.Context) string {
+	return c.GetString("player_id")
+}
+
+// currentAPIKey 获取当前请求使用的API密钥，使用登录令牌时返回nil
+func currentAPIKey(c *gin.Context) *models.APIKey {
+	if key, ok := c.Get("api_key"); ok {
+		return key.(*models.APIKey)
+	}
+	return nil
+}
+
 // API处理函数
 func createRoom(c *gin.Context) {
 	var req struct {
 		Name       string          `json:"name" binding:"required"`
 		Mode       models.GameMode `json:"mode" binding:"required"`
 		MaxPlayers int             `json:"max_players" binding:"required"`
+		Ranked     bool            `json:"ranked"`
+		Whispers   bool            `json:"allow_whispers"`
+		// 聊天过滤模式：off 不过滤，mask 屏蔽敏感词，block 拒绝违规发言，不指定时使用服务器的默认模式
+		ChatFilter string `json:"chat_filter"`
+		// 旁白、系统通知和AI发言的语言：zh、en，不指定时使用默认语言
+		Locale services.Locale `json:"locale"`
+		// AI补位时的性格分布，如 {"aggressive": 1, "cautious": 2}
+		AIPersonalities map[models.AIPersonality]int `json:"ai_personalities"`
+		// 开局时用AI补足到的人数，0表示只允许真人对局，不指定时使用默认值
+		AIFill *int `json:"ai_fill"`
+		// 自定义板子各角色的数量，如 {"werewolf": 3, "seer": 1, "guard": 1}，其余座位为村民
+		Roles map[models.Role]int `json:"roles"`
+		// 第一个白天之前竞选警长，警长投票时有1.5票
+		Sheriff bool `json:"sheriff"`
+		// 创建者担任法官（上帝），不入座，排位房间不能设置法官
+		Judge bool `json:"judge"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
+	aiFill := min(services.DefaultAIFill, req.MaxPlayers)
+	if req.AIFill != nil {
+		aiFill = *req.AIFill
+	}
+
+	// 法官席位在创建房间时一并设置，不会留下没有法官的房间
+	judgeID := ""
+	if req.Judge {
+		judgeID = currentPlayerID(c)
+	}
 
-	room := roomManager.CreateRoom(req.Name, req.Mode, req.MaxPlayers)
+	room, err := roomManager.CreateRoom(req.Name, req.Mode, req.MaxPlayers, req.Ranked, req.Whispers, req.ChatFilter, req.Locale, req.AIPersonalities, aiFill, req.Roles, req.Sheriff, judgeID)
+	if errors.Is(err, services.ErrInvalidPersonality) || errors.Is(err, services.ErrInvalidAIFill) ||
+		errors.Is(err, services.ErrInvalidMode) || errors.Is(err, services.ErrInvalidRoomSize) ||
+		errors.Is(err, services.ErrInvalidBoard) || errors.Is(err, services.ErrInvalidChatFilter) ||
+		errors.Is(err, services.ErrInvalidLocale) || errors.Is(err, services.ErrJudgeUnavailable) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusServiceUnavailable, err)
+		return
+	}
 	c.JSON(http.StatusOK, room)
 }
 
 func listRooms(c *gin.Context) {
-	rooms := roomManager.ListRooms()
+	// 可按队列筛选：ranked 排位，casual 休闲；按状态筛选：waiting、playing、finished
+	rooms := roomManager.ListRooms(c.Query("queue"), models.RoomStatus(c.Query("status")))
 	c.JSON(http.StatusOK, gin.H{"rooms": rooms})
 }
 
@@ -144,7 +913,7 @@ func getPlayerInfo(c *gin.Context) {
 
 	player, err := roomManager.GetPlayer(roomID, playerID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
@@ -154,9 +923,9 @@ func getPlayerInfo(c *gin.Context) {
 func getRoomInfo(c *gin.Context) {
 	roomID := c.Param("id")
 
-	room, err := roomManager.GetRoom(roomID)
+	room, err := roomManager.RoomInfo(roomID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
@@ -167,49 +936,1530 @@ func joinRoom(c *gin.Context) {
 	roomID := c.Param("id")
 	var player models.Player
 	if err := c.ShouldBindJSON(&player); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	if err := roomManager.JoinRoom(roomID, player); err != nil {
+	// 只能以令牌中的玩家身份加入房间，使用API密钥加入的是外部机器人
+	player.ID = currentPlayerID(c)
+	player.Type = models.HumanPlayer
+	if currentAPIKey(c) != nil {
+		player.Type = models.BotPlayer
+	}
+
+	err := roomManager.JoinRoom(roomID, player)
+	if err == services.ErrGameInProgress {
+		// 对局进行中，以旁观者身份进入房间，下一局开始前可以再次加入入座
+		ticket, expiresAt, err := authMgr.IssueWSTicket(player.ID, roomID, true)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":           "对局进行中，已作为旁观者进入房间",
+			"spectator":         true,
+			"ws_ticket":         ticket,
+			"ws_ticket_expires": expiresAt,
+		})
+		return
+	}
+	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err == services.ErrRoomNotFound {
 			statusCode = http.StatusNotFound
 		} else if err == services.ErrRoomFull {
 			statusCode = http.StatusBadRequest
 		}
-		c.JSON(statusCode, gin.H{"error": err.Error()})
+		respondError(c, statusCode, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "加入房间成功"})
-}
-
-func gameAction(c *gin.Context) {
-	var action models.GameAction
-	if err := c.ShouldBindJSON(&action); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	ticket, expiresAt, err := authMgr.IssueWSTicket(player.ID, roomID, false)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	// 获取房间ID
-	roomID := action.RoomID
-	game, exists := roomManager.GetGameController(roomID)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "游戏未找到"})
-		return
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "加入房间成功",
+		"ws_ticket":         ticket,
+		"ws_ticket_expires": expiresAt,
+	})
+}
+
+// issueWSTicket 为房间中的玩家签发WebSocket连接凭证，用于重连
+// 只有spectate权限的API密钥无需加入房间，获得只读凭证旁观房间；房间的法官也获得只读凭证
+func issueWSTicket(c *gin.Context) {
+	roomID := c.Param("id")
+	playerID := currentPlayerID(c)
+	readOnly := false
+	if key := currentAPIKey(c); key != nil && !key.HasScope(models.ScopeBot) {
+		readOnly = true
+	}
+
+	// 法官不入座，与旁观者一样使用只读凭证
+	if room, err := roomManager.GetRoom(roomID); err == nil && room.JudgeID != "" && room.JudgeID == playerID {
+		readOnly = true
+	}
+
+	if readOnly {
+		if _, err := roomManager.GetRoom(roomID); err != nil {
+			respondError(c, http.StatusNotFound, err)
+			return
+		}
+	} else if !requireRoomMember(c, roomID, playerID) {
+		return
+	}
+
+	ticket, expiresAt, err := authMgr.IssueWSTicket(playerID, roomID, readOnly)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ticket": ticket, "expires_at": expiresAt})
+}
+
+// requireRoomMember 玩家不在房间中时返回错误，返回false表示请求已被拒绝
+func requireRoomMember(c *gin.Context, roomID, playerID string) bool {
+	if _, err := roomManager.GetPlayer(roomID, playerID); err != nil {
+		statusCode := http.StatusForbidden
+		if err == services.ErrRoomNotFound {
+			statusCode = http.StatusNotFound
+		} else {
+			err = services.ErrNotInRoom
+		}
+		respondError(c, statusCode, err)
+		return false
+	}
+	return true
+}
+
+// getRoomChat 分页获取房间最近的聊天消息，只包含当前玩家能看到的频道
+// before为上一页最早一条消息的ID，省略时从最新的消息开始
+func getRoomChat(c *gin.Context) {
+	roomID := c.Param("id")
+	playerID := currentPlayerID(c)
+	if !requireRoomMember(c, roomID, playerID) {
+		return
+	}
+
+	before, err := strconv.ParseInt(c.DefaultQuery("before", "0"), 10, 64)
+	if err != nil || before < 0 {
+		respondError(c, http.StatusBadRequest, errors.New("无效的消息ID"))
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	c.JSON(http.StatusOK, webSocketMgr.ChatHistory(roomID, playerID, before, limit))
+}
+
+// roomEventsPollTimeout 长轮询没有新事件时的最长等待时间
+const roomEventsPollTimeout = 25 * time.Second
+
+// getRoomEvents 获取房间事件，与WebSocket连接收到的事件相同
+// 默认为长轮询，请求头Accept为text/event-stream时以SSE持续推送
+func getRoomEvents(c *gin.Context) {
+	roomID := c.Param("id")
+	playerID := currentPlayerID(c)
+	if !requireRoomMember(c, roomID, playerID) {
+		return
+	}
+
+	since, err := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil || since < 0 {
+		respondError(c, http.StatusBadRequest, errors.New("无效的序号"))
+		return
+	}
+
+	if c.GetHeader("Accept") == "text/event-stream" {
+		streamRoomEvents(c, roomID, playerID, since)
+		return
+	}
+
+	events, err := webSocketMgr.WaitRoomEvents(c.Request.Context(), roomID, playerID, since, roomEventsPollTimeout)
+	if err != nil {
+		// 客户端已断开
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// streamRoomEvents 以SSE推送房间事件，事件ID为序号，浏览器重连时通过Last-Event-ID继续
+func streamRoomEvents(c *gin.Context, roomID, playerID string, since int64) {
+	if lastID, err := strconv.ParseInt(c.GetHeader("Last-Event-ID"), 10, 64); err == nil && lastID >= 0 {
+		since = lastID
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		events, err := webSocketMgr.WaitRoomEvents(c.Request.Context(), roomID, playerID, since, roomEventsPollTimeout)
+		if err != nil {
+			return false
+		}
+
+		// 部分事件已过期，通知客户端重新获取完整状态
+		if !events.Complete {
+			fmt.Fprintf(w, "event: resync\ndata: {\"last_seq\":%d}\n\n", events.LastSeq)
+		}
+		for _, event := range events.Events {
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.Seq, event.Data)
+		}
+		if len(events.Events) == 0 {
+			// 保持连接，避免被代理断开
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+
+		since = events.LastSeq
+		return true
+	})
+}
+
+// getRoomOverlay 直播画面叠加层使用的对局概况，按直播延迟返回，无需加入房间
+func getRoomOverlay(c *gin.Context) {
+	overlay, err := roomManager.Overlay(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.JSON(http.StatusOK, overlay)
+}
+
+// getRoomStream 延迟的旁观事件流，只包含旁观者可以收到的事件，无需加入房间；
+// 默认长轮询，请求头Accept为text/event-stream时以SSE推送
+func getRoomStream(c *gin.Context) {
+	roomID := c.Param("id")
+	if _, err := roomManager.GetRoom(roomID); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	since, err := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil || since < 0 {
+		respondError(c, http.StatusBadRequest, errors.New("无效的序号"))
+		return
+	}
+	delay := roomManager.StreamDelay()
+
+	if c.GetHeader("Accept") != "text/event-stream" {
+		events, err := webSocketMgr.WaitSpectatorEvents(c.Request.Context(), roomID, since, delay, roomEventsPollTimeout)
+		if err != nil {
+			return
+		}
+		c.JSON(http.StatusOK, events)
+		return
+	}
+
+	if lastID, err := strconv.ParseInt(c.GetHeader("Last-Event-ID"), 10, 64); err == nil && lastID >= 0 {
+		since = lastID
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		events, err := webSocketMgr.WaitSpectatorEvents(c.Request.Context(), roomID, since, delay, roomEventsPollTimeout)
+		if err != nil {
+			return false
+		}
+
+		if !events.Complete {
+			fmt.Fprintf(w, "event: resync\ndata: {\"last_seq\":%d}\n\n", events.LastSeq)
+		}
+		for _, event := range events.Events {
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", event.Seq, event.Data)
+		}
+		if len(events.Events) == 0 {
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+
+		since = events.LastSeq
+		return true
+	})
+}
+
+func gameAction(c *gin.Context) {
+	var action models.GameAction
+	if err := c.ShouldBindJSON(&action); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	action.PlayerID = currentPlayerID(c)
+
+	// 获取房间ID
+	roomID := action.RoomID
+	game, exists := roomManager.GetGameController(roomID)
+	if !exists {
+		roomManager.AuditRejectedAction(action, services.AuditSourceHTTP, "", services.ErrGameNotFound)
+		respondError(c, http.StatusNotFound, services.ErrGameNotFound)
+		return
+	}
 
 	// 处理游戏动作
-	if err := game.ProcessAction(action); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := game.ProcessAction(c.Request.Context(), action, services.AuditSourceHTTP, ""); err != nil {
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "动作执行成功"})
 }
 
+// claimJudge 担任房间的法官，返回只读的WebSocket连接凭证，法官通过它收到房间广播和judge_state推送
+func claimJudge(c *gin.Context) {
+	roomID := c.Param("id")
+	playerID := currentPlayerID(c)
+	if err := roomManager.ClaimJudge(roomID, playerID); err != nil {
+		statusCode := http.StatusConflict
+		if err == services.ErrRoomNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	ticket, expiresAt, err := authMgr.IssueWSTicket(playerID, roomID, true)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "已担任法官",
+		"ws_ticket":         ticket,
+		"ws_ticket_expires": expiresAt,
+	})
+}
+
+// releaseJudge 离开法官席位
+func releaseJudge(c *gin.Context) {
+	if err := roomManager.ReleaseJudge(c.Param("id"), currentPlayerID(c)); err != nil {
+		statusCode := http.StatusForbidden
+		if err == services.ErrRoomNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已离开法官席位"})
+}
+
+// judgeGame 获取法官所在房间的对局控制器，返回false表示请求已被拒绝
+func judgeGame(c *gin.Context) (*services.GameController, bool) {
+	game, exists := roomManager.GetGameController(c.Param("id"))
+	if !exists {
+		respondError(c, http.StatusNotFound, services.ErrGameNotFound)
+		return nil, false
+	}
+	return game, true
+}
+
+// respondJudgeError 按错误类型返回法官操作失败的状态码
+func respondJudgeError(c *gin.Context, err error) {
+	statusCode := http.StatusBadRequest
+	if err == services.ErrNotJudge || err == services.ErrNotHost {
+		statusCode = http.StatusForbidden
+	} else if err == services.ErrGameNotStarted || err == services.ErrGameInProgress {
+		statusCode = http.StatusConflict
+	}
+	respondError(c, statusCode, err)
+}
+
+// getJudgeState 法官视角的完整对局状态，包括所有角色、夜晚目标和未公开的出局原因
+func getJudgeState(c *gin.Context) {
+	game, ok := judgeGame(c)
+	if !ok {
+		return
+	}
+	view, err := game.JudgeState(currentPlayerID(c))
+	if err != nil {
+		respondJudgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// judgeStartGame 法官开始游戏，法官使用只读连接，不能通过WebSocket开始
+func judgeStartGame(c *gin.Context) {
+	game, ok := judgeGame(c)
+	if !ok {
+		return
+	}
+	if err := game.StartGame(currentPlayerID(c)); err != nil {
+		respondJudgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "游戏已开始"})
+}
+
+// judgeAdvance 法官立即结束当前阶段或夜晚步骤
+func judgeAdvance(c *gin.Context) {
+	game, ok := judgeGame(c)
+	if !ok {
+		return
+	}
+	if err := game.JudgeAdvance(currentPlayerID(c)); err != nil {
+		respondJudgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已结束当前阶段"})
+}
+
+// judgeTargetRequest 法官判定出局或复活的请求
+type judgeTargetRequest struct {
+	PlayerID string `json:"player_id" binding:"required"`
+	Note     string `json:"note"` // 裁决理由，只记入法官的备注
+}
+
+// judgeKill 法官判定玩家出局
+func judgeKill(c *gin.Context) {
+	var req judgeTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	game, ok := judgeGame(c)
+	if !ok {
+		return
+	}
+	if err := game.JudgeKill(currentPlayerID(c), req.PlayerID, req.Note); err != nil {
+		respondJudgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已判定玩家出局"})
+}
+
+// judgeRevive 法官让出局的玩家重新回到对局
+func judgeRevive(c *gin.Context) {
+	var req judgeTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	game, ok := judgeGame(c)
+	if !ok {
+		return
+	}
+	if err := game.JudgeRevive(currentPlayerID(c), req.PlayerID, req.Note); err != nil {
+		respondJudgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "玩家已复活"})
+}
+
+// judgeNote 法官在对局日志中记录备注，public为true时作为裁定向房间公布
+func judgeNote(c *gin.Context) {
+	var req struct {
+		Text   string `json:"text" binding:"required"`
+		Public bool   `json:"public"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	game, ok := judgeGame(c)
+	if !ok {
+		return
+	}
+	if err := game.JudgeNote(currentPlayerID(c), req.Text, req.Public); err != nil {
+		respondJudgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "备注已记录"})
+}
+
+// getGameStatus 获取当前玩家视角的游戏状态，其他玩家的角色按与WebSocket推送相同的规则过滤
+// player_id可省略，指定时必须是当前登录的玩家
 func getGameStatus(c *gin.Context) {
-	// TODO: 实现获取游戏状态逻辑
-	c.JSON(http.StatusOK, gin.H{"status": "game status"})
+	roomID := c.Query("room_id")
+	if roomID == "" {
+		respondError(c, http.StatusBadRequest, errors.New("缺少房间ID"))
+		return
+	}
+	playerID := currentPlayerID(c)
+	if requested := c.Query("player_id"); requested != "" && requested != playerID {
+		respondError(c, http.StatusForbidden, errors.New("只能查询自己视角的游戏状态"))
+		return
+	}
+	if !requireRoomMember(c, roomID, playerID) {
+		return
+	}
+
+	game, exists := roomManager.GetGameController(roomID)
+	if !exists {
+		respondError(c, http.StatusNotFound, services.ErrGameNotFound)
+		return
+	}
+
+	status, err := game.BuildStatus(playerID)
+	if err != nil {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func registerUser(c *gin.Context) {
+	var req struct {
+		Username    string `json:"username" binding:"required"`
+		Password    string `json:"password" binding:"required"`
+		DisplayName string `json:"display_name"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if rejectBanned(c, "") {
+		return
+	}
+
+	user, err := accountMgr.Register(req.Username, req.Password, req.DisplayName)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err {
+		case services.ErrUserExists:
+			statusCode = http.StatusConflict
+		case services.ErrInvalidUsername, services.ErrWeakPassword:
+			statusCode = http.StatusBadRequest
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	respondWithToken(c, user)
+}
+
+func loginUser(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := accountMgr.Login(req.Username, req.Password)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == services.ErrInvalidCredentials {
+			statusCode = http.StatusUnauthorized
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	if rejectBanned(c, user.ID) {
+		return
+	}
+
+	respondWithToken(c, user)
+}
+
+// wechatLogin 微信小程序登录，用wx.login获得的code换取微信身份，首次登录时创建账号
+func wechatLogin(c *gin.Context) {
+	var req struct {
+		Code        string `json:"code" binding:"required"`
+		DisplayName string `json:"display_name"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	identity, err := wechatAuth.Code2Session(c.Request.Context(), req.Code)
+	if err != nil {
+		statusCode := http.StatusBadGateway
+		switch err {
+		case services.ErrWeChatDisabled:
+			statusCode = http.StatusNotFound
+		case services.ErrInvalidWeChatCode:
+			statusCode = http.StatusUnauthorized
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	identity.Name = req.DisplayName
+	user, err := accountMgr.LoginExternal(identity)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if rejectBanned(c, user.ID) {
+		return
+	}
+
+	respondWithToken(c, user)
+}
+
+// oauthStateCookie 保存授权请求随机值的Cookie，回调时与state中的值比对
+const oauthStateCookie = "oauth_state"
+
+// listOAuthProviders 列出已配置的第三方登录平台，供登录页面显示按钮
+func listOAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": oauthMgr.Providers()})
+}
+
+// oauthLogin 跳转到第三方平台的授权页面
+func oauthLogin(c *gin.Context) {
+	authURL, ok := beginOAuth(c, "")
+	if !ok {
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// linkIdentity 为当前账号绑定第三方身份，返回授权页面的地址，由前端跳转
+func linkIdentity(c *gin.Context) {
+	authURL, ok := beginOAuth(c, currentPlayerID(c))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": authURL})
+}
+
+// beginOAuth 签发授权请求的state并把随机值写入Cookie，返回授权页面的地址
+func beginOAuth(c *gin.Context, linkUserID string) (string, bool) {
+	name := c.Param("provider")
+	provider, err := oauthMgr.Provider(name)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return "", false
+	}
+
+	state, nonce, err := authMgr.IssueOAuthState(name, linkUserID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return "", false
+	}
+
+	// 平台回调是跨站的顶层跳转，Lax的Cookie会随之发送
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, nonce, int(services.OAuthStateTTL.Seconds()), "/", "", c.Request.TLS != nil, true)
+	return provider.AuthURL(state, oauthCallbackURL(c, name)), true
+}
+
+// oauthCallback 第三方平台授权后的回调，登录或绑定后跳转到前端页面：
+// 登录成功时URL片段为 token=...，绑定成功时为 linked=平台，失败时为 error=错误码
+func oauthCallback(c *gin.Context) {
+	name := c.Param("provider")
+	nonce, _ := c.Cookie(oauthStateCookie)
+	c.SetCookie(oauthStateCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+
+	claims, err := authMgr.ParseOAuthState(c.Query("state"), name, nonce)
+	if err != nil {
+		redirectOAuthResult(c, "error", string(services.ErrorCodeOf(err)))
+		return
+	}
+	provider, err := oauthMgr.Provider(name)
+	if err != nil {
+		redirectOAuthResult(c, "error", string(services.ErrorCodeOf(err)))
+		return
+	}
+
+	// 用户在授权页面拒绝授权时没有code
+	code := c.Query("code")
+	if code == "" {
+		redirectOAuthResult(c, "error", string(services.CodeOAuthFailed))
+		return
+	}
+	identity, err := provider.Exchange(c.Request.Context(), code, oauthCallbackURL(c, name))
+	if err != nil {
+		requestLogger(c).Warn("第三方登录失败", "provider", name, "error", err)
+		redirectOAuthResult(c, "error", string(services.CodeOAuthFailed))
+		return
+	}
+
+	if claims.LinkUserID != "" {
+		if err := accountMgr.LinkIdentity(claims.LinkUserID, identity); err != nil {
+			redirectOAuthResult(c, "error", string(services.ErrorCodeOf(err)))
+			return
+		}
+		redirectOAuthResult(c, "linked", name)
+		return
+	}
+
+	user, err := accountMgr.LoginExternal(identity)
+	if err == nil {
+		err = moderation.CheckBanned(user.ID, c.ClientIP())
+	}
+	var token string
+	if err == nil {
+		token, err = authMgr.IssueToken(user)
+	}
+	if err != nil {
+		redirectOAuthResult(c, "error", string(services.ErrorCodeOf(err)))
+		return
+	}
+	redirectOAuthResult(c, "token", token)
+}
+
+// oauthCallbackURL 在第三方平台登记的回调地址，未配置服务地址时使用请求的Host
+func oauthCallbackURL(c *gin.Context, provider string) string {
+	base := strings.TrimSuffix(oauthCfg.BaseURL, "/")
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + c.Request.Host
+	}
+	return base + "/api/v1/oauth/" + provider + "/callback"
+}
+
+// redirectOAuthResult 带着第三方登录的结果跳转到前端页面，结果放在URL片段中，不会发送给服务器或记入访问日志
+func redirectOAuthResult(c *gin.Context, key, value string) {
+	c.Redirect(http.StatusFound, oauthCfg.RedirectURL+"#"+url.Values{key: {value}}.Encode())
+}
+
+// listIdentities 列出当前账号绑定的第三方身份
+func listIdentities(c *gin.Context) {
+	identities, err := accountMgr.ListIdentities(currentPlayerID(c))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// unlinkIdentity 解除当前账号在指定平台绑定的身份
+func unlinkIdentity(c *gin.Context) {
+	err := accountMgr.UnlinkIdentity(currentPlayerID(c), c.Param("provider"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err {
+		case services.ErrIdentityNotFound:
+			statusCode = http.StatusNotFound
+		case services.ErrLastLoginMethod:
+			statusCode = http.StatusConflict
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已解除绑定"})
+}
+
+func createGuest(c *gin.Context) {
+	var req struct {
+		DisplayName string `json:"display_name"`
+	}
+
+	// 请求体可以为空
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if rejectBanned(c, "") {
+		return
+	}
+
+	user, err := accountMgr.CreateGuest(req.DisplayName)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithToken(c, user)
+}
+
+func upgradeGuest(c *gin.Context) {
+	var req struct {
+		Username    string `json:"username" binding:"required"`
+		Password    string `json:"password" binding:"required"`
+		DisplayName string `json:"display_name"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := accountMgr.UpgradeGuest(currentPlayerID(c), req.Username, req.Password, req.DisplayName)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err {
+		case services.ErrUserExists:
+			statusCode = http.StatusConflict
+		case services.ErrInvalidUsername, services.ErrWeakPassword, services.ErrNotGuest:
+			statusCode = http.StatusBadRequest
+		case services.ErrUserNotFound:
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	// 游客令牌作废，签发正式账号的令牌
+	if err := authMgr.RevokeTokens(user.ID); err != nil {
+		requestLogger(c).Error("撤销游客令牌失败", "player_id", user.ID, "error", err)
+	}
+	respondWithToken(c, user)
+}
+
+// respondWithToken 签发令牌并返回账号信息
+func respondWithToken(c *gin.Context, user *models.User) {
+	token, err := authMgr.IssueToken(user)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": token, "user": user})
+}
+
+func listAPIKeys(c *gin.Context) {
+	keys, err := authMgr.ListAPIKeys(currentPlayerID(c))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// createAPIKey 为当前账号创建API密钥，完整密钥只在响应中返回一次
+func createAPIKey(c *gin.Context) {
+	var req struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"` // spectate, bot, admin
+		ExpiresInDays int      `json:"expires_in_days"`           // 有效天数，0表示永不过期
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	secret, key, err := authMgr.CreateAPIKey(currentPlayerID(c), req.Name, req.Scopes,
+		time.Duration(req.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == services.ErrInvalidScope {
+			statusCode = http.StatusBadRequest
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"key": secret, "api_key": key})
+}
+
+func revokeAPIKey(c *gin.Context) {
+	if err := authMgr.RevokeAPIKey(currentPlayerID(c), c.Param("id")); err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == services.ErrAPIKeyNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已撤销API密钥"})
+}
+
+func getUserInfo(c *gin.Context) {
+	user, err := accountMgr.GetUser(c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == services.ErrUserNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func getPlayerStats(c *gin.Context) {
+	stats, err := roomManager.Stats().GetStats(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func getPlayerAchievements(c *gin.Context) {
+	achievements, err := roomManager.Achievements().GetAchievements(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"achievements": achievements})
+}
+
+func listAchievements(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"achievements": roomManager.Achievements().Catalog()})
+}
+
+func listFriends(c *gin.Context) {
+	friends, err := friendMgr.ListFriends(currentPlayerID(c))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"friends": friends})
+}
+
+func addFriend(c *gin.Context) {
+	friendship, err := friendMgr.AddFriend(currentPlayerID(c), c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err {
+		case services.ErrUserNotFound:
+			statusCode = http.StatusNotFound
+		case services.ErrFriendExists:
+			statusCode = http.StatusConflict
+		case services.ErrFriendSelf, services.ErrGuestNoFriends:
+			statusCode = http.StatusBadRequest
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, friendship)
+}
+
+func removeFriend(c *gin.Context) {
+	if err := friendMgr.RemoveFriend(currentPlayerID(c), c.Param("id")); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已删除好友"})
+}
+
+func inviteFriend(c *gin.Context) {
+	var req struct {
+		FriendID string `json:"friend_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	room, err := roomManager.GetRoom(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	if err := friendMgr.InviteToRoom(currentPlayerID(c), req.FriendID, room); err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err {
+		case services.ErrNotFriends, services.ErrNotInRoom:
+			statusCode = http.StatusForbidden
+		case services.ErrFriendOffline:
+			statusCode = http.StatusConflict
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "邀请已发送"})
+}
+
+func listSeasons(c *gin.Context) {
+	seasons, err := roomManager.Seasons().List()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"seasons": seasons})
+}
+
+func getCurrentSeason(c *gin.Context) {
+	season, err := roomManager.Seasons().Current()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, season)
+}
+
+func getSeasonStandings(c *gin.Context) {
+	seasonID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, errors.New("无效的赛季ID"))
+		return
+	}
+
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	standings, err := roomManager.Seasons().Standings(seasonID, offset, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"season_id": seasonID, "standings": standings})
+}
+
+// createTournament 创建赛事，创建者为组织者
+func createTournament(c *gin.Context) {
+	var req struct {
+		Name      string                  `json:"name" binding:"required"`
+		Format    models.TournamentFormat `json:"format" binding:"required"` // round_robin 循环赛，bracket 淘汰赛
+		Mode      models.GameMode         `json:"mode" binding:"required"`
+		TableSize int                     `json:"table_size" binding:"required"` // 每桌人数，选手不足时用AI补足
+		Rounds    int                     `json:"rounds"`                        // 循环赛的轮数，不指定时使用默认值
+		Locale    services.Locale         `json:"locale"`                        // 比赛房间的语言
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	tournament, err := tournaments.Create(currentPlayerID(c), req.Name, req.Format, req.Mode, req.TableSize, req.Rounds, req.Locale)
+	if errors.Is(err, services.ErrInvalidTournament) || errors.Is(err, services.ErrInvalidMode) ||
+		errors.Is(err, services.ErrInvalidLocale) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, tournament)
+}
+
+func listTournaments(c *gin.Context) {
+	// 可按状态筛选：registering、running、finished
+	list, err := tournaments.List(c.Query("status"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tournaments": list})
+}
+
+func getTournament(c *gin.Context) {
+	tournament, err := tournaments.Get(c.Param("id"))
+	if err != nil {
+		respondTournamentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tournament)
+}
+
+func getTournamentStandings(c *gin.Context) {
+	standings, err := tournaments.Standings(c.Param("id"))
+	if err != nil {
+		respondTournamentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tournament_id": c.Param("id"), "standings": standings})
+}
+
+// listTournamentMatches 赛程：各轮的分桌、比赛房间和结果，可以用round只查询某一轮
+func listTournamentMatches(c *gin.Context) {
+	tournament, err := tournaments.Get(c.Param("id"))
+	if err != nil {
+		respondTournamentError(c, err)
+		return
+	}
+	round, _ := strconv.Atoi(c.DefaultQuery("round", "0"))
+
+	matches := make([]models.TournamentMatch, 0, len(tournament.Matches))
+	for _, match := range tournament.Matches {
+		if round == 0 || match.Round == round {
+			matches = append(matches, match)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"tournament_id": tournament.ID, "round": tournament.Round, "matches": matches})
+}
+
+func registerTournament(c *gin.Context) {
+	if err := tournaments.Register(c.Param("id"), currentPlayerID(c)); err != nil {
+		respondTournamentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "报名成功"})
+}
+
+func withdrawTournament(c *gin.Context) {
+	if err := tournaments.Withdraw(c.Param("id"), currentPlayerID(c)); err != nil {
+		respondTournamentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已退出报名"})
+}
+
+// startTournament 组织者截止报名并开始第一轮
+func startTournament(c *gin.Context) {
+	if err := tournaments.Start(c.Param("id"), currentPlayerID(c)); err != nil {
+		respondTournamentError(c, err)
+		return
+	}
+	tournament, err := tournaments.Get(c.Param("id"))
+	if err != nil {
+		respondTournamentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tournament)
+}
+
+// startTournamentMatch 组织者代房主开始一场比赛
+func startTournamentMatch(c *gin.Context) {
+	if err := tournaments.StartMatch(c.Param("id"), c.Param("matchId"), currentPlayerID(c)); err != nil {
+		respondTournamentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "比赛已开始"})
+}
+
+// respondTournamentError 按错误类型返回赛事操作失败的状态码
+func respondTournamentError(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrTournamentNotFound), errors.Is(err, services.ErrMatchNotFound),
+		errors.Is(err, services.ErrGameNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, services.ErrNotOrganizer):
+		statusCode = http.StatusForbidden
+	case errors.Is(err, services.ErrTournamentClosed), errors.Is(err, services.ErrTournamentNotRunning),
+		errors.Is(err, services.ErrGameInProgress):
+		statusCode = http.StatusConflict
+	case services.ErrorCodeOf(err) != services.CodeInternal:
+		statusCode = http.StatusBadRequest
+	}
+	respondError(c, statusCode, err)
+}
+
+func listGameHistory(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	records, err := gameStore.ListGameRecords(offset, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"games": records})
+}
+
+func getGameRecord(c *gin.Context) {
+	record, err := gameStore.GetGameRecord(c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == storage.ErrNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+func getGameEvents(c *gin.Context) {
+	record, err := gameStore.GetGameRecord(c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == storage.ErrNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	// 支持从指定序号之后继续获取
+	after, _ := strconv.Atoi(c.DefaultQuery("after", "0"))
+	events := make([]models.GameEvent, 0, len(record.Events))
+	for _, event := range record.Events {
+		if event.Seq > after {
+			events = append(events, event)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"game_id":    record.ID,
+		"started_at": record.StartedAt,
+		"events":     events,
+	})
+}
+
+// getGameState 重放事件日志，获取对局在指定序号的事件之后的状态，未指定时为终局状态
+func getGameState(c *gin.Context) {
+	record, err := gameStore.GetGameRecord(c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == storage.ErrNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	seq, _ := strconv.Atoi(c.DefaultQuery("seq", "0"))
+	state := services.RebuildGameState(record, seq)
+	if len(state.Events) > 0 {
+		seq = state.Events[len(state.Events)-1].Seq
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"game_id":     record.ID,
+		"seq":         seq,
+		"round":       state.Round,
+		"phase":       state.Phase,
+		"players":     state.Players,
+		"actions":     state.Actions,
+		"skills":      state.Skills,
+		"known_roles": state.KnownRoles,
+		"claims":      state.Claims,
+	})
+}
+
+// getGameChat 分页获取已结束对局的聊天记录，before为上一页最早一条消息的序号
+func getGameChat(c *gin.Context) {
+	record, err := gameStore.GetGameRecord(c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == storage.ErrNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	before, _ := strconv.Atoi(c.DefaultQuery("before", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	c.JSON(http.StatusOK, services.GameChatHistory(record, before, limit))
+}
+
+func exportGame(c *gin.Context) {
+	record, err := gameStore.GetGameRecord(c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == storage.ErrNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	// 作为附件下载
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="werewolf_game_%s.json"`, record.ID))
+	c.IndentedJSON(http.StatusOK, services.BuildGameExport(record))
+}
+
+// getGameStory 对局的文字回顾，按请求的语言生成纯文本，便于直接转发到群聊
+func getGameStory(c *gin.Context) {
+	record, err := gameStore.GetGameRecord(c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == storage.ErrNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	c.String(http.StatusOK, services.BuildGameStory(record, requestLocale(c)))
+}
+
+func listAuditLog(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	since, _ := strconv.ParseInt(c.Query("since"), 10, 64)
+	until, _ := strconv.ParseInt(c.Query("until"), 10, 64)
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	entries, err := roomManager.Audit().Query(models.AuditQuery{
+		RoomID:   c.Query("room"),
+		PlayerID: c.Query("player"),
+		Since:    since,
+		Until:    until,
+		Offset:   offset,
+		Limit:    limit,
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// getAIDebugInfo 查看对局中AI玩家的怀疑概率、已知身份和最近一次决策的推理过程
+func getAIDebugInfo(c *gin.Context) {
+	game, exists := roomManager.GetGameController(c.Param("id"))
+	if !exists {
+		respondError(c, http.StatusNotFound, services.ErrGameNotFound)
+		return
+	}
+
+	info, err := game.AIDebugInfo(c.Param("playerId"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// rollbackPhase 将对局回滚到上一个阶段开始时的状态，用于恢复因结算错误而卡住的对局
+func rollbackPhase(c *gin.Context) {
+	game, exists := roomManager.GetGameController(c.Param("id"))
+	if !exists {
+		respondError(c, http.StatusNotFound, services.ErrGameNotFound)
+		return
+	}
+
+	if err := game.RollbackPhase(); err != nil {
+		respondError(c, http.StatusConflict, err)
+		return
+	}
+	status, err := game.BuildStatus("")
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// getAITuning 查看当前生效的AI调参，用于确认调参文件是否已重新加载
+func getAITuning(c *gin.Context) {
+	c.JSON(http.StatusOK, services.CurrentAITuning())
+}
+
+// getAILearning 查看最近一次从历史对局汇总的策略胜率和玩家习惯
+func getAILearning(c *gin.Context) {
+	c.JSON(http.StatusOK, services.CurrentAILearning())
+}
+
+func runAILearning(c *gin.Context) {
+	learning, err := aiLearning.Run()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, learning)
+}
+
+func createReport(c *gin.Context) {
+	var req struct {
+		PlayerID string `json:"player_id" binding:"required"`
+		RoomID   string `json:"room_id"`
+		Reason   string `json:"reason" binding:"required"`
+		Details  string `json:"details"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := moderation.Report(currentPlayerID(c), req.PlayerID, req.RoomID, req.Reason, req.Details)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err {
+		case services.ErrReportSelf, services.ErrReportReason:
+			statusCode = http.StatusBadRequest
+		case services.ErrUserNotFound:
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func listReports(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	// 默认只列出待处理的举报
+	reports, err := moderation.ListReports(c.DefaultQuery("status", models.ReportOpen), offset, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+func reviewReport(c *gin.Context) {
+	var req struct {
+		Decision      string `json:"decision" binding:"required"` // dismiss, ban
+		Note          string `json:"note"`
+		DurationHours int    `json:"duration_hours"` // 封禁时长，0表示永久
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	report, ban, err := moderation.ReviewReport(c.Param("id"), currentPlayerID(c), req.Decision, req.Note,
+		time.Duration(req.DurationHours)*time.Hour)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch err {
+		case services.ErrReportNotFound:
+			statusCode = http.StatusNotFound
+		case services.ErrReportReviewed:
+			statusCode = http.StatusConflict
+		case services.ErrInvalidDecision, services.ErrInvalidBan:
+			statusCode = http.StatusBadRequest
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	if ban != nil {
+		enforceBan(ban)
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report, "ban": ban})
+}
+
+func listBans(c *gin.Context) {
+	bans, err := moderation.ListBans()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bans": bans})
+}
+
+func createBan(c *gin.Context) {
+	var req struct {
+		Type          string `json:"type" binding:"required"` // account, ip
+		Target        string `json:"target" binding:"required"`
+		Reason        string `json:"reason"`
+		DurationHours int    `json:"duration_hours"` // 封禁时长，0表示永久
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	ban, err := moderation.Ban(req.Type, req.Target, req.Reason, currentPlayerID(c),
+		time.Duration(req.DurationHours)*time.Hour)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == services.ErrInvalidBan {
+			statusCode = http.StatusBadRequest
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	enforceBan(ban)
+	c.JSON(http.StatusOK, ban)
+}
+
+func deleteBan(c *gin.Context) {
+	if err := moderation.Unban(c.Param("id")); err != nil {
+		statusCode := http.StatusInternalServerError
+		if err == services.ErrBanNotFound {
+			statusCode = http.StatusNotFound
+		}
+		respondError(c, statusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "已解除封禁"})
+}
+
+// listMutes 列出因多次违规发言被自动禁言的玩家
+func listMutes(c *gin.Context) {
+	mutes := []services.Mute{}
+	if cm := roomManager.ChatModerator(); cm != nil {
+		mutes = cm.Mutes()
+	}
+	c.JSON(http.StatusOK, gin.H{"mutes": mutes})
+}
+
+// deleteMute 解除玩家的禁言
+func deleteMute(c *gin.Context) {
+	cm := roomManager.ChatModerator()
+	if cm == nil || !cm.Unmute(c.Param("playerId")) {
+		respondError(c, http.StatusNotFound, services.ErrMuteNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已解除禁言"})
+}
+
+// enforceBan 账号封禁立即生效：撤销已签发的令牌并断开其连接
+func enforceBan(ban *models.Ban) {
+	if ban.Type != models.BanAccount {
+		return
+	}
+	if err := authMgr.RevokeTokens(ban.Target); err != nil {
+		slog.Error("撤销被封禁玩家的令牌失败", "player_id", ban.Target, "error", err)
+	}
+	webSocketMgr.RemoveConnection(ban.Target)
+}
+
+func getRetentionMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, retention.Metrics())
+}
+
+func runRetention(c *gin.Context) {
+	c.JSON(http.StatusOK, retention.Run())
+}
+
+func getGameMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, roomManager.GameMetrics())
+}
+
+func getCapacity(c *gin.Context) {
+	c.JSON(http.StatusOK, roomManager.Capacity())
+}
+
+func healthz(c *gin.Context) {
+	status, statusCode := "ok", http.StatusOK
+	if roomManager.ShuttingDown() {
+		status, statusCode = "shutting_down", http.StatusServiceUnavailable
+	}
+	c.JSON(statusCode, gin.H{"status": status, "capacity": roomManager.Capacity()})
 }
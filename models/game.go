@@ -29,23 +29,42 @@ const (
 	WhiteWolf Role = "whitewolf" // 白狼王
 )
 
+// DeathCause 玩家出局的原因
+type DeathCause string
+
+const (
+	DeathWolfKill     DeathCause = "wolf_kill"     // 夜晚被狼人击杀
+	DeathPoison       DeathCause = "poison"        // 被女巫毒死
+	DeathVote         DeathCause = "vote"          // 被投票放逐
+	DeathHunterShot   DeathCause = "hunter_shot"   // 被猎人开枪带走
+	DeathLover        DeathCause = "lover"         // 情侣一方出局后殉情
+	DeathSelfDestruct DeathCause = "self_destruct" // 白狼王自爆
+	DeathExploded     DeathCause = "exploded"      // 被自爆的白狼王带走
+	DeathJudge        DeathCause = "judge"         // 被法官判定出局
+)
+
 // PlayerType 玩家类型
 type PlayerType string
 
 const (
 	HumanPlayer PlayerType = "human" // 真人玩家
 	AIPlayer    PlayerType = "ai"    // AI玩家
+	BotPlayer   PlayerType = "bot"   // 通过API密钥接入的外部机器人，超时未行动时由内置AI代为行动
 )
 
-// AIPersonality AI性格特征
+// AIPersonality AI性格特征，决定AI的行动策略和发言风格
 type AIPersonality string
 
 const (
 	Aggressive AIPersonality = "aggressive" // 激进型
-	Analytical AIPersonality = "analytical" // 分析型
-	Deceptive  AIPersonality = "deceptive"  // 伪装型
+	Cautious   AIPersonality = "cautious"   // 谨慎型
+	Strategic  AIPersonality = "strategic"  // 策略型
+	Random     AIPersonality = "random"     // 随机型
 )
 
+// AIPersonalities 所有AI性格
+var AIPersonalities = []AIPersonality{Aggressive, Cautious, Strategic, Random}
+
 // Player 玩家信息
 type Player struct {
 	ID          string        `json:"id"`
@@ -54,19 +73,47 @@ type Player struct {
 	Role        Role          `json:"role"`
 	Personality AIPersonality `json:"personality,omitempty"`
 	Alive       bool          `json:"alive"`
-	IsLover     bool          `json:"is_lover"` // 是否是情侣
+	IsLover     bool          `json:"is_lover"`         // 是否是情侣
+	Rating      int           `json:"rating,omitempty"` // 玩家积分，仅真人玩家
 }
 
+// RoomStatus 房间状态
+type RoomStatus string
+
+const (
+	RoomWaiting  RoomStatus = "waiting"  // 等待开始
+	RoomPlaying  RoomStatus = "playing"  // 对局进行中，新加入的玩家只能旁观
+	RoomFinished RoomStatus = "finished" // 上一局已结束，可以开始下一局
+)
+
 // Room 游戏房间
 type Room struct {
-	ID          string   `json:"id"`
-	Name        string   `json:"name"`
-	Mode        GameMode `json:"mode"`
-	Players     []Player `json:"players"`
-	MaxPlayers  int      `json:"max_players"`
-	MinPlayers  int      `json:"min_players"`
-	GameStarted bool     `json:"game_started"`
-	CreatedAt   int64    `json:"created_at"`
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Mode          GameMode   `json:"mode"`
+	Players       []Player   `json:"players"`
+	MaxPlayers    int        `json:"max_players"`
+	MinPlayers    int        `json:"min_players"`
+	GameStarted   bool       `json:"game_started"`
+	Status        RoomStatus `json:"status"`
+	HostID        string     `json:"host_id"`        // 房主，即最早加入的真人玩家或外部机器人，只有房主可以开始游戏
+	Ranked        bool       `json:"ranked"`         // 是否为排位房间，排位对局结束后更新积分
+	AllowWhispers bool       `json:"allow_whispers"` // 是否允许玩家之间私聊
+	// ChatFilter 聊天和发言的过滤模式：off、mask、block，为空时使用服务器的默认模式
+	ChatFilter string `json:"chat_filter,omitempty"`
+	// Locale 旁白、系统通知和AI发言使用的语言，为空时使用服务器的默认语言
+	Locale string `json:"locale,omitempty"`
+	// JudgeID 法官（上帝）的玩家ID，法官不入座，可以看到完整的对局状态并主持对局，为空表示没有法官
+	JudgeID string `json:"judge_id,omitempty"`
+	// AIPersonalities AI补位时的性格分布，值为权重，为空时随机分配
+	AIPersonalities map[AIPersonality]int `json:"ai_personalities,omitempty"`
+	// AIFill 开局时用AI补足到的人数，0表示只允许真人对局
+	AIFill int `json:"ai_fill"`
+	// Roles 自定义板子各角色的数量，其余座位由村民补足，为空时使用Mode对应的板子
+	Roles map[Role]int `json:"roles,omitempty"`
+	// Sheriff 第一个白天之前竞选警长，警长投票时有1.5票，出局时可以移交警徽
+	Sheriff   bool  `json:"sheriff"`
+	CreatedAt int64 `json:"created_at"`
 }
 
 // GameAction 游戏动作
@@ -77,13 +124,367 @@ type GameAction struct {
 	Timestamp int64  `json:"timestamp"`
 	RoomID    string `json:"room_id"`           // 房间ID
 	Content   string `json:"content,omitempty"` // 动作内容
+	// StateVersion 客户端提交动作时看到的对局状态版本，与当前版本不一致时拒绝，为0时不校验
+	StateVersion int64 `json:"state_version,omitempty"`
+}
+
+// PlayerGameView 单个玩家视角下的对局信息，只包含该玩家可以知道的内容：自己的角色、狼人同伴、
+// 自己的查验记录和公开的出局信息。REST状态查询、game_state推送和重连快照都由同一个投影生成
+type PlayerGameView struct {
+	PlayerID         string          `json:"player_id,omitempty"` // 视角所属的玩家，旁观者为空
+	IsStarted        bool            `json:"is_started"`
+	Phase            string          `json:"phase"`                 // night, campaign, sheriff_vote, day, vote
+	NightStep        string          `json:"night_step,omitempty"`  // 夜晚当前进行的步骤：cupid、wolves、seer、witch、guard
+	Round            int             `json:"round"`                 // 游戏轮次
+	Role             Role            `json:"role,omitempty"`        // 当前玩家的角色
+	Players          []Player        `json:"players"`               // 玩家列表，其他存活玩家的角色仅在已知时返回
+	AlivePlayers     []string        `json:"alive_players"`         // 存活玩家ID
+	KnownRoles       map[string]Role `json:"known_roles,omitempty"` // 当前玩家已知的玩家角色，包括自己
+	Teammates        []string        `json:"teammates,omitempty"`   // 狼人的同伴，包括已出局的
+	Checks           []SeerCheck     `json:"checks,omitempty"`      // 预言家自己的查验记录
+	Deaths           []PlayerDeath   `json:"deaths,omitempty"`      // 已公开的出局记录
+	Actions          []string        `json:"actions"`               // 当前玩家在本阶段尚未完成的必要动作
+	AvailableActions []ActionOption  `json:"available_actions"`     // 当前玩家在本阶段可以执行的动作及可选目标
+	TimeLeft         int             `json:"time_left"`             // 剩余时间
+	PhaseEndsAt      int64           `json:"phase_ends_at"`         // 当前阶段截止时间的毫秒时间戳
+	Claims           []RoleClaim     `json:"claims,omitempty"`      // 玩家公开声明的身份
+	Sheriff          string          `json:"sheriff,omitempty"`     // 当前的警长，没有警长时为空
+	Candidates       []string        `json:"candidates,omitempty"`  // 竞选警长时上警的玩家，按座位顺序排列
+	StateVersion     int64           `json:"state_version"`         // 对局状态版本，提交动作时带上
+}
+
+// SeerCheck 预言家的一次查验结果
+type SeerCheck struct {
+	Round    int    `json:"round"` // 查验的夜晚
+	TargetID string `json:"target_id"`
+	Role     Role   `json:"role"`
+}
+
+// PlayerDeath 一次公开的出局记录
+type PlayerDeath struct {
+	PlayerID string `json:"player_id"`
+	Round    int    `json:"round"`
+	Phase    string `json:"phase"` // 出局时所在的阶段
+	// Cause 出局原因，只在公开可知时返回：夜晚出局的原因（击杀、毒杀、殉情）不公开
+	Cause DeathCause `json:"cause,omitempty"`
+}
+
+// ActionOption 玩家在当前阶段可以执行的一种动作
+type ActionOption struct {
+	Type     string   `json:"type"`
+	Targets  []string `json:"targets,omitempty"`  // 可选的目标玩家ID，发言等不需要目标的动作为空
+	Optional bool     `json:"optional,omitempty"` // 可以不执行，例如女巫可以不用药
+}
+
+// RoleClaim 玩家在公开发言中声明的身份，声明预言家时附带其公布的查验结果
+// 声明不一定真实，狼人可以冒充预言家并编造查验结果
+type RoleClaim struct {
+	PlayerID string         `json:"player_id"`
+	Role     Role           `json:"role"`
+	Round    int            `json:"round"` // 首次声明的回合
+	Checks   []ClaimedCheck `json:"checks,omitempty"`
+}
+
+// ClaimedCheck 声明中公布的一次查验结果
+type ClaimedCheck struct {
+	Round    int    `json:"round"` // 查验的夜晚
+	TargetID string `json:"target_id"`
+	Wolf     bool   `json:"wolf"` // true为查杀，false为金水
+}
+
+// GameEvent 对局事件
+type GameEvent struct {
+	Seq       int        `json:"seq"`  // 事件序号，从1开始
+	Type      string     `json:"type"` // game_start, action, chat, death, revive, sheriff, judge_note, phase_change, game_end
+	Round     int        `json:"round"`
+	Phase     string     `json:"phase"`
+	Action    string     `json:"action,omitempty"` // 动作类型，仅action事件
+	PlayerID  string     `json:"player_id,omitempty"`
+	TargetID  string     `json:"target_id,omitempty"`
+	Channel   string     `json:"channel,omitempty"` // 聊天频道，仅chat事件
+	Cause     DeathCause `json:"cause,omitempty"`   // 出局原因，仅death事件
+	System    bool       `json:"system,omitempty"`  // 动作由系统代为生成，例如阶段到时的默认动作
+	Content   string     `json:"content,omitempty"`
+	Timestamp int64      `json:"timestamp"` // 毫秒时间戳
+}
+
+// GameRecord 对局记录
+type GameRecord struct {
+	ID        string      `json:"id"`
+	RoomID    string      `json:"room_id"`
+	Mode      GameMode    `json:"mode"`
+	Players   []Player    `json:"players"`          // 参与玩家及其角色
+	Result    string      `json:"result"`           // 对局结果
+	Ranked    bool        `json:"ranked"`           // 是否为排位对局
+	Events    []GameEvent `json:"events,omitempty"` // 完整事件日志
+	StartedAt int64       `json:"started_at"`       // 开始时间
+	EndedAt   int64       `json:"ended_at"`         // 结束时间
+	Duration  int64       `json:"duration"`         // 对局时长（秒）
+}
+
+// User 玩家账号
+type User struct {
+	ID           string `json:"id"`           // 持久的玩家ID
+	Username     string `json:"username"`     // 登录名
+	DisplayName  string `json:"display_name"` // 显示名称
+	PasswordHash string `json:"-"`            // bcrypt密码哈希
+	Guest        bool   `json:"guest"`        // 是否为游客账号
+	Rating       int    `json:"rating"`       // ELO积分
+	RatedGames   int    `json:"rated_games"`  // 已计分的对局数
+	CreatedAt    int64  `json:"created_at"`
+}
+
+// Session 登录会话，对应一个已签发的令牌
+type Session struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// Identity 账号绑定的第三方登录身份，同一身份只能绑定一个账号，一个账号在每个平台只能绑定一个身份
+type Identity struct {
+	Provider  string `json:"provider"` // github、google、wechat
+	Subject   string `json:"subject"`  // 第三方平台的用户ID，微信优先使用unionid
+	UserID    string `json:"user_id"`
+	Name      string `json:"name"` // 绑定时第三方平台的昵称
+	CreatedAt int64  `json:"created_at"`
+}
+
+// RoleStats 单个角色的统计
+type RoleStats struct {
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	WinRate float64 `json:"win_rate"`
+}
+
+// PlayerStats 玩家生涯统计
+type PlayerStats struct {
+	PlayerID          string              `json:"player_id"`
+	Games             int                 `json:"games"`
+	Wins              int                 `json:"wins"`
+	WinRate           float64             `json:"win_rate"`
+	Roles             map[Role]*RoleStats `json:"roles"`               // 各角色的对局数和胜率
+	SurvivedRounds    int                 `json:"survived_rounds"`     // 累计存活回合数
+	SurvivedGames     int                 `json:"survived_games"`      // 存活到对局结束的次数
+	AvgSurvivalRounds float64             `json:"avg_survival_rounds"` // 平均存活回合数
+	SeerChecks        int                 `json:"seer_checks"`         // 预言家查验次数
+	SeerWolvesFound   int                 `json:"seer_wolves_found"`   // 查验出狼人的次数
+	SeerAccuracy      float64             `json:"seer_accuracy"`       // 查验命中率
+	WolfKills         int                 `json:"wolf_kills"`          // 狼人刀人次数
+	WolfKillSuccess   int                 `json:"wolf_kill_success"`   // 刀人成功次数
+	WolfKillRate      float64             `json:"wolf_kill_rate"`      // 刀人成功率
+	UpdatedAt         int64               `json:"updated_at"`
+}
+
+// Achievement 成就定义
+type Achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PlayerAchievement 玩家已解锁的成就
+type PlayerAchievement struct {
+	PlayerID      string `json:"player_id"`
+	AchievementID string `json:"achievement_id"`
+	GameID        string `json:"game_id"` // 解锁成就的对局
+	UnlockedAt    int64  `json:"unlocked_at"`
+}
+
+// AuditEntry 审计日志条目，记录每一次提交的游戏动作及其校验结果
+type AuditEntry struct {
+	ID           int64  `json:"id"`
+	RoomID       string `json:"room_id"`
+	PlayerID     string `json:"player_id"`
+	ConnectionID string `json:"connection_id,omitempty"` // WebSocket连接ID，HTTP和AI动作为空
+	Source       string `json:"source"`                  // ws, http, ai
+	Action       string `json:"action"`
+	TargetID     string `json:"target_id,omitempty"`
+	Phase        string `json:"phase,omitempty"`
+	Round        int    `json:"round,omitempty"`
+	Accepted     bool   `json:"accepted"`         // 是否通过校验
+	Reason       string `json:"reason,omitempty"` // 被拒绝的原因
+	Timestamp    int64  `json:"timestamp"`        // 毫秒时间戳
+}
+
+// AuditQuery 审计日志查询条件，空字段表示不限制
+type AuditQuery struct {
+	RoomID   string
+	PlayerID string
+	Since    int64 // 毫秒时间戳，包含
+	Until    int64 // 毫秒时间戳，不包含
+	Offset   int
+	Limit    int
+}
+
+// Season 排位赛季
+type Season struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	StartedAt int64  `json:"started_at"`
+	EndsAt    int64  `json:"ends_at"`  // 计划结束时间
+	EndedAt   int64  `json:"ended_at"` // 实际结束时间，进行中的赛季为0
+}
+
+// SeasonStanding 玩家在某个赛季的排位成绩
+type SeasonStanding struct {
+	SeasonID  int    `json:"season_id"`
+	PlayerID  string `json:"player_id"`
+	Rating    int    `json:"rating"` // 赛季积分，每个赛季从默认积分开始
+	Games     int    `json:"games"`
+	Wins      int    `json:"wins"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// 好友关系状态
+const (
+	FriendPending  = "pending"  // 等待对方确认
+	FriendAccepted = "accepted" // 已成为好友
+)
+
+// Friendship 好友关系，RequesterID为发起好友请求的一方
+type Friendship struct {
+	RequesterID string `json:"requester_id"`
+	AddresseeID string `json:"addressee_id"`
+	Status      string `json:"status"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
+// 举报处理状态
+const (
+	ReportOpen      = "open"      // 待处理
+	ReportDismissed = "dismissed" // 已驳回
+	ReportActioned  = "actioned"  // 已处罚
+)
+
+// Report 玩家举报
+type Report struct {
+	ID         string `json:"id"`
+	ReporterID string `json:"reporter_id"`
+	ReportedID string `json:"reported_id"`
+	RoomID     string `json:"room_id,omitempty"`
+	Reason     string `json:"reason"`
+	Details    string `json:"details,omitempty"`
+	Status     string `json:"status"`
+	ReviewedBy string `json:"reviewed_by,omitempty"`
+	ReviewNote string `json:"review_note,omitempty"`
+	CreatedAt  int64  `json:"created_at"`
+	ReviewedAt int64  `json:"reviewed_at,omitempty"`
+}
+
+// 封禁类型
+const (
+	BanAccount = "account" // 封禁账号
+	BanIP      = "ip"      // 封禁IP
+)
+
+// Ban 全局封禁
+type Ban struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Target    string `json:"target"` // 账号封禁为玩家ID，IP封禁为IP地址
+	Reason    string `json:"reason"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at"` // 0表示永久封禁
+}
+
+// API密钥的权限范围
+const (
+	ScopeSpectate = "spectate" // 只读：查询房间、对局记录，以只读连接旁观房间
+	ScopeBot      = "bot"      // 以密钥所属账号的身份加入房间并参与对局，包含spectate
+	ScopeAdmin    = "admin"    // 调用管理接口，仅限管理员账号，包含spectate
+)
+
+// APIKey 供机器人和工具使用的API密钥，以所属账号的身份访问接口，权限受Scopes限制
+type APIKey struct {
+	ID         string   `json:"id"`
+	OwnerID    string   `json:"owner_id"` // 所属账号的玩家ID
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	SecretHash string   `json:"-"` // 密钥的SHA-256哈希
+	CreatedAt  int64    `json:"created_at"`
+	ExpiresAt  int64    `json:"expires_at"` // 0表示永不过期
+}
+
+// HasScope 判断密钥是否具有指定权限，bot和admin权限都包含spectate
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || (scope == ScopeSpectate && (s == ScopeBot || s == ScopeAdmin)) {
+			return true
+		}
+	}
+	return false
+}
+
+// TournamentFormat 赛事赛制
+type TournamentFormat string
+
+const (
+	TournamentRoundRobin TournamentFormat = "round_robin" // 循环赛：每轮重新分桌，所有选手打满设定的轮数
+	TournamentBracket    TournamentFormat = "bracket"     // 淘汰赛：每轮按累计积分淘汰后一半选手，剩下一桌时进行决赛
+)
+
+// 赛事状态
+const (
+	TournamentRegistering = "registering" // 报名中
+	TournamentRunning     = "running"     // 进行中
+	TournamentFinished    = "finished"    // 已结束
+)
+
+// 比赛场次状态
+const (
+	MatchScheduled = "scheduled" // 已分桌，等待开始或对局进行中
+	MatchFinished  = "finished"  // 对局已结束并计分
+)
+
+// Tournament 赛事，由组织者创建，服务器为每场比赛自动创建房间并累计积分
+type Tournament struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	OrganizerID string             `json:"organizer_id"`
+	Format      TournamentFormat   `json:"format"`
+	Mode        GameMode           `json:"mode"`
+	TableSize   int                `json:"table_size"`       // 每桌人数，选手不足时用AI补足
+	Rounds      int                `json:"rounds,omitempty"` // 循环赛的轮数，淘汰赛的轮数由报名人数决定
+	Locale      string             `json:"locale,omitempty"` // 比赛房间的语言
+	Status      string             `json:"status"`
+	Round       int                `json:"round"` // 当前轮次，报名中为0
+	Players     []TournamentPlayer `json:"players"`
+	Matches     []TournamentMatch  `json:"matches"`
+	ChampionID  string             `json:"champion_id,omitempty"`
+	CreatedAt   int64              `json:"created_at"`
+	StartedAt   int64              `json:"started_at,omitempty"`
+	EndedAt     int64              `json:"ended_at,omitempty"`
+}
+
+// TournamentPlayer 报名的选手及其累计成绩
+type TournamentPlayer struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Points int    `json:"points"`
+	Games  int    `json:"games"`
+	Wins   int    `json:"wins"`
+	// EliminatedRound 淘汰赛中被淘汰的轮次，0表示仍在比赛中
+	EliminatedRound int `json:"eliminated_round,omitempty"`
+}
+
+// TournamentMatch 一场比赛，即某一轮中的一桌
+type TournamentMatch struct {
+	ID        string         `json:"id"`
+	Round     int            `json:"round"`
+	Table     int            `json:"table"`
+	PlayerIDs []string       `json:"player_ids"`
+	RoomID    string         `json:"room_id,omitempty"` // 比赛房间，创建失败时为空，组织者开始比赛时重新创建
+	Status    string         `json:"status"`
+	GameID    string         `json:"game_id,omitempty"`
+	Result    string         `json:"result,omitempty"`
+	Points    map[string]int `json:"points,omitempty"` // 本场各选手获得的积分
 }
 
-// GameStatus 游戏状态
-type GameStatus struct {
-	Phase    string   `json:"phase"`     // day, night
-	Round    int      `json:"round"`     // 游戏轮次
-	Players  []Player `json:"players"`   // 玩家列表
-	Actions  []string `json:"actions"`   // 可执行的动作
-	TimeLeft int      `json:"time_left"` // 剩余时间
+// TournamentStanding 赛事排名中的一项
+type TournamentStanding struct {
+	Rank int `json:"rank"`
+	TournamentPlayer
 }
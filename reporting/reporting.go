@@ -0,0 +1,115 @@
+// Package reporting 捕获panic并上报错误
+// HTTP处理函数、WebSocket消息处理、对局命令和后台goroutine中的panic都由Recover捕获，
+// 记录日志并交给配置的Reporter（例如Sentry）上报，其余玩家和对局不受影响
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+)
+
+// Event 一次需要上报的错误
+type Event struct {
+	Message string            // 错误信息，panic时为panic的值
+	Where   string            // 出错的位置，例如 http、ws.message、game.command
+	Stack   []byte            // 调用栈
+	Tags    map[string]string // 关联信息，例如房间ID、玩家ID和请求ID
+	Panic   bool              // 是否由panic引起
+}
+
+// Reporter 错误上报的目标，实现需要并发安全并且不能阻塞调用方
+type Reporter interface {
+	Report(event Event)
+}
+
+var (
+	reporter Reporter
+	mutex    sync.RWMutex
+)
+
+// SetReporter 设置错误上报的目标，nil表示只记录日志
+func SetReporter(r Reporter) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	reporter = r
+}
+
+// Report 记录日志并上报错误，tags为成对的键值
+func Report(event Event) {
+	attrs := []any{"where", event.Where, "error", event.Message}
+	for key, value := range event.Tags {
+		attrs = append(attrs, key, value)
+	}
+	if event.Panic {
+		slog.Error("捕获到panic", append(attrs, "stack", string(event.Stack))...)
+	} else {
+		slog.Error("上报错误", attrs...)
+	}
+
+	mutex.RLock()
+	r := reporter
+	mutex.RUnlock()
+	if r != nil {
+		r.Report(event)
+	}
+}
+
+// Recover 捕获当前goroutine的panic并上报，需要直接用defer调用：
+//
+//	defer reporting.Recover("ws.message", "player_id", playerID)
+//
+// tags为成对的键值；捕获到panic时返回前不再向上传播
+func Recover(where string, tags ...string) {
+	if value := recover(); value != nil {
+		Report(PanicEvent(value, where, tags...))
+	}
+}
+
+// PanicEvent 为已捕获的panic构建上报事件，用于需要在捕获后自行清理的调用方
+func PanicEvent(value any, where string, tags ...string) Event {
+	event := Event{Message: fmt.Sprint(value), Where: where, Stack: debug.Stack(), Panic: true, Tags: make(map[string]string)}
+	for i := 0; i+1 < len(tags); i += 2 {
+		event.Tags[tags[i]] = tags[i+1]
+	}
+	return event
+}
+
+// Call 在当前goroutine中执行fn，fn中的panic被捕获并上报后正常返回，用于后台循环的每一轮
+func Call(where string, fn func()) {
+	defer Recover(where)
+	fn()
+}
+
+// Go 在新的goroutine中执行fn，fn中的panic被捕获并上报，不会导致进程退出
+func Go(where string, fn func()) {
+	go Call(where, fn)
+}
+
+// Config 错误上报配置
+type Config struct {
+	SentryDSN   string // Sentry项目的DSN，为空时只记录日志
+	Environment string // 上报的环境名，例如 production、staging
+	Release     string // 上报的版本号
+}
+
+// Setup 按配置启用错误上报，返回的函数在服务关闭时调用，发送尚未上报的事件
+func Setup(config Config) (func(context.Context) error, error) {
+	if config.SentryDSN == "" {
+		SetReporter(nil)
+		return func(context.Context) error { return nil }, nil
+	}
+	sentry, err := NewSentryReporter(config.SentryDSN, config.Environment, config.Release)
+	if err != nil {
+		return nil, err
+	}
+	SetReporter(sentry)
+	slog.Info("Sentry错误上报已启用", "environment", config.Environment, "release", config.Release)
+	return func(ctx context.Context) error {
+		SetReporter(nil)
+		return sentry.Close(ctx)
+	}, nil
+}
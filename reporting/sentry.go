@@ -0,0 +1,188 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 上报队列参数：队列满时丢弃新事件，不阻塞出错的请求或对局
+const (
+	sentryQueueSize = 256
+	sentryTimeout   = 10 * time.Second
+	sentryClient    = "werewolf/1.0"
+)
+
+// SentryReporter 通过Sentry的envelope接口上报错误
+type SentryReporter struct {
+	endpoint    string
+	auth        string
+	dsn         string
+	environment string
+	release     string
+	serverName  string
+	client      *http.Client
+	events      chan Event
+	done        chan struct{}
+}
+
+// NewSentryReporter 按DSN创建Sentry上报，DSN格式为 https://<key>@<host>/<project_id>
+func NewSentryReporter(dsn, environment, release string) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, fmt.Errorf("无效的Sentry DSN")
+	}
+	path := strings.Trim(u.Path, "/")
+	index := strings.LastIndex(path, "/")
+	projectID, prefix := path[index+1:], ""
+	if index >= 0 {
+		prefix = "/" + path[:index]
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("无效的Sentry DSN：缺少项目ID")
+	}
+
+	hostname, _ := os.Hostname()
+	r := &SentryReporter{
+		endpoint:    fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, projectID),
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, u.User.Username()),
+		dsn:         dsn,
+		environment: environment,
+		release:     release,
+		serverName:  hostname,
+		client:      &http.Client{Timeout: sentryTimeout},
+		events:      make(chan Event, sentryQueueSize),
+		done:        make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+// Report 放入上报队列，队列已满时丢弃
+func (r *SentryReporter) Report(event Event) {
+	select {
+	case r.events <- event:
+	default:
+	}
+}
+
+// Close 发送队列中剩余的事件，ctx结束时不再等待
+func (r *SentryReporter) Close(ctx context.Context) error {
+	close(r.events)
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 逐个发送事件
+func (r *SentryReporter) run() {
+	defer close(r.done)
+
+	for event := range r.events {
+		if err := r.send(event); err != nil {
+			slog.Warn("上报错误到Sentry失败", "error", err)
+		}
+	}
+}
+
+// send 以envelope格式发送一个事件
+func (r *SentryReporter) send(event Event) error {
+	id := make([]byte, 16)
+	rand.Read(id)
+	eventID := hex.EncodeToString(id)
+
+	level := "error"
+	if event.Panic {
+		level = "fatal"
+	}
+	payload := map[string]any{
+		"event_id":    eventID,
+		"timestamp":   time.Now().UTC().Format(time.RFC3339Nano),
+		"platform":    "go",
+		"level":       level,
+		"logger":      event.Where,
+		"server_name": r.serverName,
+		"tags":        event.Tags,
+		"exception": map[string]any{"values": []map[string]any{{
+			"type":       event.Where,
+			"value":      event.Message,
+			"stacktrace": map[string]any{"frames": stackFrames(event.Stack)},
+		}}},
+	}
+	if r.environment != "" {
+		payload["environment"] = r.environment
+	}
+	if r.release != "" {
+		payload["release"] = r.release
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.Encode(map[string]string{"event_id": eventID, "dsn": r.dsn, "sent_at": time.Now().UTC().Format(time.RFC3339)})
+	encoder.Encode(map[string]string{"type": "event"})
+	encoder.Encode(payload)
+
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.auth)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Sentry返回 %s", resp.Status)
+	}
+	return nil
+}
+
+// stackFrames 将debug.Stack的输出转换为Sentry的调用栈帧，按从外到内的顺序排列
+func stackFrames(stack []byte) []map[string]any {
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+	frames := make([]map[string]any, 0, len(lines)/2)
+	// 第一行为goroutine信息，之后每两行为一帧：函数名和 "\t文件:行号 +偏移"
+	for i := 1; i+1 < len(lines); i += 2 {
+		function := lines[i]
+		if created, found := strings.CutPrefix(function, "created by "); found {
+			// 最后一帧为创建goroutine的位置，形如 "created by pkg.fn in goroutine 7"
+			function, _, _ = strings.Cut(created, " in goroutine ")
+		} else if paren := strings.LastIndex(function, "("); paren > 0 {
+			function = function[:paren]
+		}
+		location := strings.TrimSpace(lines[i+1])
+		if space := strings.LastIndex(location, " +"); space > 0 {
+			location = location[:space]
+		}
+		file, line := location, 0
+		if colon := strings.LastIndex(location, ":"); colon > 0 {
+			file = location[:colon]
+			line, _ = strconv.Atoi(location[colon+1:])
+		}
+		frames = append(frames, map[string]any{
+			"function": function,
+			"filename": file,
+			"lineno":   line,
+			"in_app":   strings.Contains(function, "qianlnk/werewolf") && !strings.Contains(function, "werewolf/reporting."),
+		})
+	}
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return frames
+}
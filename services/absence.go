@@ -0,0 +1,132 @@
+package services
+
+import (
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// defaultDisconnectGrace 断线玩家的默认宽限期
+const defaultDisconnectGrace = 10 * time.Second
+
+// SetDisconnectGrace 设置断线玩家的宽限期，超过宽限期仍未重连的玩家本阶段的必要动作由内置AI代为完成，
+// 0表示不代为行动，阶段等到截止时间结束
+func (rm *RoomManager) SetDisconnectGrace(grace time.Duration) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.disconnectGrace = grace
+}
+
+// disconnectGrace 获取断线玩家的宽限期
+func (gc *GameController) disconnectGrace() time.Duration {
+	if gc.game.roomManager == nil {
+		return defaultDisconnectGrace
+	}
+	gc.game.roomManager.mutex.RLock()
+	defer gc.game.roomManager.mutex.RUnlock()
+
+	return gc.game.roomManager.disconnectGrace
+}
+
+// CheckAbsentPlayers 有玩家断线后重新设置宽限期计时
+func (gc *GameController) CheckAbsentPlayers() {
+	gc.post(gc.armAbsenceTimer)
+}
+
+// armAbsenceTimer 为尚未行动的断线玩家设置宽限期计时，在对局goroutine中执行
+func (gc *GameController) armAbsenceTimer() {
+	gc.stopAbsenceTimer()
+
+	grace := gc.disconnectGrace()
+	if grace <= 0 || !gc.game.IsStarted {
+		return
+	}
+	due, wait := gc.absentPlayers(grace)
+	if len(due) > 0 {
+		wait = 0
+	} else if wait <= 0 {
+		return
+	}
+
+	phase, round, step := gc.game.Phase, gc.game.Round, gc.game.NightStep
+	gc.absenceTimer = deadlines.AfterFunc(wait, func() {
+		gc.postTimer(func() {
+			gc.handleAbsenceTimeout(phase, round, step)
+		})
+	})
+}
+
+// stopAbsenceTimer 停止宽限期计时，调用方需持有gc.mutex
+func (gc *GameController) stopAbsenceTimer() {
+	if gc.absenceTimer != nil {
+		gc.absenceTimer.Stop()
+		gc.absenceTimer = nil
+	}
+}
+
+// absentPlayers 获取当前阶段还有必要动作未完成的存活断线真人玩家：due为断线已超过宽限期的玩家，
+// wait为其余断线玩家中最早到期的剩余时间。白天的发言持续到截止时间，女巫可以不用药，都不需要代为行动
+func (gc *GameController) absentPlayers(grace time.Duration) (due []models.Player, wait time.Duration) {
+	source, ok := gc.sink.(connectionSource)
+	if !ok || gc.game.Phase == PhaseDay {
+		return nil, 0
+	}
+
+	now := time.Now()
+	for _, player := range gc.game.Players {
+		if player.Type != models.HumanPlayer || player.Role == models.Witch || len(gc.game.pendingActions(player.ID)) == 0 {
+			continue
+		}
+		since, disconnected := source.disconnectedSince(player.ID)
+		if !disconnected {
+			continue
+		}
+		if left := grace - now.Sub(since); left > 0 {
+			if wait == 0 || left < wait {
+				wait = left
+			}
+			continue
+		}
+		due = append(due, player)
+	}
+	return due, wait
+}
+
+// handleAbsenceTimeout 宽限期到期后由内置AI代替仍未重连的玩家完成本阶段的必要动作，
+// 座位仍归玩家所有，重连后可以继续自己行动；超过重连窗口期仍未重连的才由AI接管，见TakeOverPlayer
+func (gc *GameController) handleAbsenceTimeout(phase string, round int, step string) {
+	// 阶段或夜晚步骤已经切换，计时器已过期
+	if !gc.game.IsStarted || gc.game.Phase != phase || gc.game.Round != round || gc.game.NightStep != step {
+		return
+	}
+	gc.absenceTimer = nil
+
+	due, _ := gc.absentPlayers(gc.disconnectGrace())
+	for _, player := range due {
+		pending := gc.game.pendingActions(player.ID)
+		gc.logger().Info("玩家断线超过宽限期，由内置AI代为行动", "player_id", player.ID, "phase", phase, "pending", pending)
+		gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(map[string]interface{}{
+			"type":      "player_absent",
+			"player_id": player.ID,
+			"phase":     phase,
+			"round":     round,
+			"actions":   pending,
+		}, gc.locale(), "message", "system.player_absent", player.Name))
+		if err := gc.applyAIAction(player); err != nil {
+			gc.logger().Warn("内置AI代替断线玩家行动失败", "player_id", player.ID, "error", err)
+		}
+		// 白狼王自爆会直接结束白天
+		if !gc.game.IsStarted || gc.game.Phase != phase || gc.game.Round != round || gc.game.NightStep != step {
+			return
+		}
+	}
+	if len(due) > 0 {
+		gc.checkPhaseProgress()
+	}
+
+	// 阶段没有结束时继续等待其余断线的玩家
+	if gc.game.IsStarted && gc.game.Phase == phase && gc.game.Round == round && gc.game.NightStep == step {
+		gc.armAbsenceTimer()
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUserExists         = NewError(CodeUserExists, "用户名已被注册")
+	ErrUserNotFound       = NewError(CodeUserNotFound, "用户不存在")
+	ErrInvalidCredentials = NewError(CodeInvalidCredentials, "用户名或密码错误")
+	ErrInvalidUsername    = NewError(CodeInvalidUsername, "用户名长度需为3-32个字符")
+	ErrWeakPassword       = NewError(CodeWeakPassword, "密码长度不能少于6位")
+	ErrNotGuest           = NewError(CodeNotGuest, "当前账号不是游客账号")
+)
+
+// AccountManager 账号管理器
+type AccountManager struct {
+	store storage.Store
+}
+
+// NewAccountManager 创建账号管理器实例
+func NewAccountManager(store storage.Store) *AccountManager {
+	return &AccountManager{store: store}
+}
+
+// Register 注册新账号，返回的账号ID即为持久的玩家ID
+func (am *AccountManager) Register(username, password, displayName string) (*models.User, error) {
+	username, hash, err := validateCredentials(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName = strings.TrimSpace(displayName)
+	if displayName == "" {
+		displayName = username
+	}
+
+	user := &models.User{
+		ID:           generateUserID(),
+		Username:     username,
+		DisplayName:  displayName,
+		PasswordHash: hash,
+		Rating:       DefaultRating,
+		CreatedAt:    time.Now().Unix(),
+	}
+
+	if err := am.store.CreateUser(user); err != nil {
+		if err == storage.ErrDuplicate {
+			return nil, ErrUserExists
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// CreateGuest 创建游客账号，游客无需密码即可游戏，之后可升级为正式账号
+func (am *AccountManager) CreateGuest(displayName string) (*models.User, error) {
+	id := generateUserID()
+
+	displayName = strings.TrimSpace(displayName)
+	if displayName == "" {
+		displayName = "游客" + id[len(id)-4:]
+	}
+
+	user := &models.User{
+		ID:          id,
+		Username:    id, // 游客使用玩家ID占位用户名，升级时替换
+		DisplayName: displayName,
+		Guest:       true,
+		Rating:      DefaultRating,
+		CreatedAt:   time.Now().Unix(),
+	}
+
+	if err := am.store.CreateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpgradeGuest 将游客账号升级为正式账号，保留玩家ID及其关联的数据
+func (am *AccountManager) UpgradeGuest(guestID, username, password, displayName string) (*models.User, error) {
+	user, err := am.GetUser(guestID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Guest {
+		return nil, ErrNotGuest
+	}
+
+	username, hash, err := validateCredentials(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Username = username
+	user.PasswordHash = hash
+	user.Guest = false
+	if displayName = strings.TrimSpace(displayName); displayName != "" {
+		user.DisplayName = displayName
+	}
+
+	if err := am.store.UpdateUser(user); err != nil {
+		if err == storage.ErrDuplicate {
+			return nil, ErrUserExists
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// validateCredentials 校验用户名和密码，返回规范化的用户名和密码哈希
+func validateCredentials(username, password string) (string, string, error) {
+	username = strings.TrimSpace(username)
+	if n := utf8.RuneCountInString(username); n < 3 || n > 32 {
+		return "", "", ErrInvalidUsername
+	}
+	if len(password) < 6 {
+		return "", "", ErrWeakPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+	return username, string(hash), nil
+}
+
+// Login 校验用户名和密码
+func (am *AccountManager) Login(username, password string) (*models.User, error) {
+	user, err := am.store.GetUserByUsername(strings.TrimSpace(username))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if user.Guest {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// GetUser 获取账号信息
+func (am *AccountManager) GetUser(userID string) (*models.User, error) {
+	user, err := am.store.GetUserByID(userID)
+	if err == storage.ErrNotFound {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// generateUserID 生成随机的玩家ID
+func generateUserID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "user_" + time.Now().Format("20060102150405.000000")
+	}
+	return "user_" + hex.EncodeToString(b)
+}
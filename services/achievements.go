@@ -0,0 +1,163 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+// achievementContext 评估成就时使用的单个玩家对局信息
+type achievementContext struct {
+	player models.Player
+	record *models.GameRecord
+	stats  *models.PlayerStats // 本局结算后的生涯统计
+	won    bool
+	alive  bool // 是否存活到对局结束
+	roles  map[string]models.Role
+}
+
+// achievementRule 成就规则
+type achievementRule struct {
+	models.Achievement
+	check func(ctx *achievementContext) bool
+}
+
+// achievementRules 所有成就规则
+var achievementRules = []achievementRule{
+	{
+		Achievement: models.Achievement{ID: "first_win", Name: "初尝胜果", Description: "赢得第一场对局"},
+		check: func(ctx *achievementContext) bool {
+			return ctx.won
+		},
+	},
+	{
+		Achievement: models.Achievement{ID: "first_wolf_win", Name: "月下獠牙", Description: "第一次以狼人身份获胜"},
+		check: func(ctx *achievementContext) bool {
+			return ctx.won && isWolf(ctx.player.Role)
+		},
+	},
+	{
+		Achievement: models.Achievement{ID: "perfect_seer", Name: "洞若观火", Description: "作为预言家查验出所有狼人并获胜"},
+		check: func(ctx *achievementContext) bool {
+			if !ctx.won || ctx.player.Role != models.Seer {
+				return false
+			}
+			found := make(map[string]bool)
+			for _, event := range ctx.record.Events {
+				if event.Type == "action" && event.Action == "check" && event.PlayerID == ctx.player.ID &&
+					!event.System && isWolf(ctx.roles[event.TargetID]) {
+					found[event.TargetID] = true
+				}
+			}
+			for id, role := range ctx.roles {
+				if isWolf(role) && !found[id] {
+					return false
+				}
+			}
+			return len(found) > 0
+		},
+	},
+	{
+		Achievement: models.Achievement{ID: "survivor_10", Name: "不倒翁", Description: "累计10次存活到对局结束"},
+		check: func(ctx *achievementContext) bool {
+			return ctx.stats != nil && ctx.stats.SurvivedGames >= 10
+		},
+	},
+	{
+		Achievement: models.Achievement{ID: "last_one_standing", Name: "孤胆英雄", Description: "作为好人存活到最后并获胜"},
+		check: func(ctx *achievementContext) bool {
+			return ctx.won && ctx.alive && !isWolf(ctx.player.Role)
+		},
+	},
+	{
+		Achievement: models.Achievement{ID: "veteran_50", Name: "身经百战", Description: "累计完成50场对局"},
+		check: func(ctx *achievementContext) bool {
+			return ctx.stats != nil && ctx.stats.Games >= 50
+		},
+	},
+}
+
+// AchievementManager 成就管理器
+type AchievementManager struct {
+	store storage.Store
+}
+
+// NewAchievementManager 创建成就管理器实例
+func NewAchievementManager(store storage.Store) *AchievementManager {
+	return &AchievementManager{store: store}
+}
+
+// Catalog 获取所有成就定义
+func (am *AchievementManager) Catalog() []models.Achievement {
+	catalog := make([]models.Achievement, 0, len(achievementRules))
+	for _, rule := range achievementRules {
+		catalog = append(catalog, rule.Achievement)
+	}
+	return catalog
+}
+
+// GetAchievements 获取玩家已解锁的成就
+func (am *AchievementManager) GetAchievements(playerID string) ([]models.PlayerAchievement, error) {
+	return am.store.GetAchievements(playerID)
+}
+
+// Evaluate 根据对局事件日志评估真人玩家新解锁的成就
+func (am *AchievementManager) Evaluate(record *models.GameRecord, stats map[string]*models.PlayerStats) map[string][]models.Achievement {
+	unlocked := make(map[string][]models.Achievement)
+
+	roles := make(map[string]models.Role)
+	for _, player := range record.Players {
+		roles[player.ID] = player.Role
+	}
+
+	dead := make(map[string]bool)
+	for _, event := range record.Events {
+		switch event.Type {
+		case "death":
+			dead[event.PlayerID] = true
+		case "revive":
+			delete(dead, event.PlayerID)
+		}
+	}
+
+	now := time.Now().Unix()
+	for _, player := range record.Players {
+		if player.Type != models.HumanPlayer {
+			continue
+		}
+
+		ctx := &achievementContext{
+			player: player,
+			record: record,
+			stats:  stats[player.ID],
+			won:    isWinner(player, record.Result),
+			alive:  !dead[player.ID],
+			roles:  roles,
+		}
+
+		for _, rule := range achievementRules {
+			if !rule.check(ctx) {
+				continue
+			}
+
+			err := am.store.AddAchievement(&models.PlayerAchievement{
+				PlayerID:      player.ID,
+				AchievementID: rule.ID,
+				GameID:        record.ID,
+				UnlockedAt:    now,
+			})
+			if err == storage.ErrDuplicate {
+				continue
+			}
+			if err != nil {
+				slog.Error("记录玩家成就失败", "player_id", player.ID, "achievement", rule.ID, "error", err)
+				continue
+			}
+			unlocked[player.ID] = append(unlocked[player.ID], rule.Achievement)
+		}
+	}
+
+	return unlocked
+}
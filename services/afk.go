@@ -0,0 +1,104 @@
+package services
+
+import (
+	"github.com/qianlnk/werewolf/models"
+)
+
+// defaultAFKThreshold 连续几次到时未完成必要动作后判定玩家挂机
+const defaultAFKThreshold = 2
+
+// SetAFKPolicy 设置挂机判定：玩家连续threshold次到时未完成必要动作后被标记为挂机，
+// 之后轮到该玩家时直接放弃，不再等待计时；takeover为true时改由AI接管座位。threshold为0表示不判定挂机
+func (rm *RoomManager) SetAFKPolicy(threshold int, takeover bool) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.afkThreshold = threshold
+	rm.afkTakeover = takeover
+}
+
+// afkPolicy 获取挂机判定的次数和是否由AI接管
+func (gc *GameController) afkPolicy() (int, bool) {
+	if gc.game.roomManager == nil {
+		return defaultAFKThreshold, false
+	}
+	gc.game.roomManager.mutex.RLock()
+	defer gc.game.roomManager.mutex.RUnlock()
+
+	return gc.game.roomManager.afkThreshold, gc.game.roomManager.afkTakeover
+}
+
+// MarkActive 玩家有操作（例如发送心跳）时清零其连续未行动次数，挂机的玩家恢复为正常状态
+func (gc *GameController) MarkActive(playerID string) {
+	gc.post(func() {
+		gc.markActive(playerID)
+	})
+}
+
+// markActive 清零玩家的连续未行动次数，挂机的玩家恢复后向房间广播player_back，在对局goroutine中执行
+func (gc *GameController) markActive(playerID string) {
+	if !gc.game.IsStarted {
+		return
+	}
+	delete(gc.game.MissedActions, playerID)
+	if !gc.game.AFK[playerID] {
+		return
+	}
+	delete(gc.game.AFK, playerID)
+
+	gc.logger().Info("挂机玩家恢复操作", "player_id", playerID)
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, map[string]interface{}{
+		"type":      "player_back",
+		"player_id": playerID,
+	})
+}
+
+// recordMissedAction 记录真人玩家一次到时未完成必要动作，达到挂机判定次数时标记挂机并通知房间，
+// 配置了AI接管时在本次超时处理完成后接管座位，在对局goroutine中执行
+func (gc *GameController) recordMissedAction(player models.Player) {
+	threshold, takeover := gc.afkPolicy()
+	if threshold <= 0 || player.Type == models.AIPlayer || gc.game.AFK[player.ID] {
+		return
+	}
+	if gc.game.MissedActions == nil {
+		gc.game.MissedActions = make(map[string]int)
+	}
+	gc.game.MissedActions[player.ID]++
+	if gc.game.MissedActions[player.ID] < threshold {
+		return
+	}
+
+	if gc.game.AFK == nil {
+		gc.game.AFK = make(map[string]bool)
+	}
+	gc.game.AFK[player.ID] = true
+	gc.logger().Info("玩家连续未行动，判定为挂机", "player_id", player.ID, "missed", gc.game.MissedActions[player.ID])
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(map[string]interface{}{
+		"type":      "player_afk",
+		"player_id": player.ID,
+		"missed":    gc.game.MissedActions[player.ID],
+	}, gc.locale(), "message", "system.player_afk", player.Name))
+
+	// 当前阶段仍在超时处理中，接管放到之后执行，避免在结算途中切换阶段
+	if takeover {
+		playerID := player.ID
+		gc.post(func() {
+			gc.takeOverPlayer(playerID, "system.takeover_afk")
+		})
+	}
+}
+
+// skipAFKTurn 挂机玩家轮到行动时直接放弃本阶段的必要动作，不再等待计时，在对局goroutine中执行
+func (gc *GameController) skipAFKTurn(player models.Player) {
+	for _, actionType := range gc.game.pendingActions(player.ID) {
+		if !timeoutDefaultActions[actionType] {
+			continue
+		}
+		action := gc.game.timeoutAction(player, actionType, TimeoutSkip)
+		if err := gc.game.addSystemAction(action); err != nil {
+			gc.logger().Warn("挂机玩家放弃动作失败", "player_id", player.ID, "action", action.Type, "error", err)
+			continue
+		}
+		processActionResult(gc.game, action)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// takeClaim 取出AI本次发言附带的身份声明，由GameController在发言成功后记录到GameState
+func (ai *AIPlayer) takeClaim() *models.RoleClaim {
+	claim := ai.claim
+	ai.claim = nil
+	return claim
+}
+
+// seerClaim AI预言家公开身份时的声明，附带全部真实的查验结果
+func (ai *AIPlayer) seerClaim() *models.RoleClaim {
+	claim := &models.RoleClaim{PlayerID: ai.ID, Role: models.Seer}
+	for _, check := range ai.memory.checks {
+		claim.Checks = append(claim.Checks, models.ClaimedCheck{
+			Round:    check.Round,
+			TargetID: check.TargetID,
+			Wolf:     isWolf(ai.KnownPlayers[check.TargetID]),
+		})
+	}
+	return claim
+}
+
+// fakeSeerClaim 狼人冒充预言家的声明：已经起跳的狼人每天继续编造一条查验结果，
+// 尚未起跳时按性格决定是否起跳，同一狼队只安排一人冒充
+func (ai *AIPlayer) fakeSeerClaim() *models.RoleClaim {
+	own := ai.GameState.claimOf(ai.ID)
+	if own == nil && !ai.shouldFakeClaim() {
+		return nil
+	}
+	if own != nil && own.Role != models.Seer {
+		return nil
+	}
+
+	claim := &models.RoleClaim{PlayerID: ai.ID, Role: models.Seer}
+	if own != nil {
+		claim.Checks = append(claim.Checks, own.Checks...)
+	}
+	if !hasClaimedCheck(claim.Checks, ai.GameState.Round) {
+		if check, ok := ai.fabricateCheck(claim.Checks); ok {
+			claim.Checks = append(claim.Checks, check)
+		}
+	}
+	return claim
+}
+
+// shouldFakeClaim 狼人是否起跳预言家：有好人跳预言家时激进和策略型会对跳，
+// 激进型和随机型也可能主动悍跳
+func (ai *AIPlayer) shouldFakeClaim() bool {
+	realClaim := false
+	for _, claim := range ai.GameState.claimsFor(models.Seer) {
+		if isWolf(ai.KnownPlayers[claim.PlayerID]) {
+			// 同伴已经起跳
+			return false
+		}
+		if ai.isAlive(claim.PlayerID) {
+			realClaim = true
+		}
+	}
+
+	// 历史对局中第一天悍跳胜率更高时更倾向于主动悍跳，反之减少
+	chance := currentAITuning().Wolf.FakeClaimChance[ai.Personality]
+	if preference := ai.learning().preference(StrategyWolfEarlyFakeClaim, StrategyWolfNoEarlyClaim); preference != 1 {
+		chance = min(1, chance*preference)
+		ai.reason("历史对局中第一天悍跳的胜率是不悍跳的%.2f倍，主动悍跳的概率调整为%.2f", preference, chance)
+	}
+	switch ai.Personality {
+	case PersonalityAggressive, PersonalityStrategic:
+		return realClaim || ai.random().Float64() < chance
+	default:
+		return ai.random().Float64() < chance
+	}
+}
+
+// fabricateCheck 编造一条当晚的查验结果：优先给对跳的预言家发查杀，
+// 否则激进型给好人发查杀，其他性格给同伴发金水
+func (ai *AIPlayer) fabricateCheck(existing []models.ClaimedCheck) (models.ClaimedCheck, bool) {
+	checked := make(map[string]bool)
+	for _, check := range existing {
+		checked[check.TargetID] = true
+	}
+	check := models.ClaimedCheck{Round: ai.GameState.Round}
+
+	for _, claim := range ai.GameState.claimsFor(models.Seer) {
+		if claim.PlayerID != ai.ID && !checked[claim.PlayerID] && ai.isAlive(claim.PlayerID) && !isWolf(ai.KnownPlayers[claim.PlayerID]) {
+			check.TargetID, check.Wolf = claim.PlayerID, true
+			return check, true
+		}
+	}
+
+	var good, teammates []string
+	for _, player := range ai.GameState.Players {
+		if !player.Alive || player.ID == ai.ID || checked[player.ID] {
+			continue
+		}
+		if isWolf(ai.KnownPlayers[player.ID]) {
+			teammates = append(teammates, player.ID)
+		} else {
+			good = append(good, player.ID)
+		}
+	}
+
+	switch {
+	case (ai.Personality == PersonalityAggressive || ai.Personality == PersonalityRandom) && len(good) > 0:
+		check.TargetID, check.Wolf = good[ai.random().Intn(len(good))], true
+	case len(teammates) > 0:
+		check.TargetID = teammates[ai.random().Intn(len(teammates))]
+	case len(good) > 0:
+		check.TargetID = good[ai.random().Intn(len(good))]
+	default:
+		return check, false
+	}
+	return check, true
+}
+
+// provenFake 根据AI自己掌握的信息判断某个预言家声明一定是假的：
+// 真预言家知道其他人都是冒充的，查验结果与已知身份矛盾的也一定是假的
+func (ai *AIPlayer) provenFake(playerID string) bool {
+	claim := ai.GameState.claimOf(playerID)
+	if claim == nil || claim.Role != models.Seer || playerID == ai.ID {
+		return false
+	}
+	if ai.Role == models.Seer {
+		return true
+	}
+	for _, check := range claim.Checks {
+		role, known := ai.KnownPlayers[check.TargetID]
+		if known && isWolf(role) != check.Wolf {
+			return true
+		}
+	}
+	return false
+}
+
+// trustedSeer 好人在多个预言家声明中选出更相信的一个，预言家和狼人返回空
+func (ai *AIPlayer) trustedSeer() string {
+	if ai.Role == models.Seer || isWolf(ai.Role) {
+		return ""
+	}
+	return ai.rankSeerClaims(true)
+}
+
+// rankSeerClaims 选出最可信的预言家声明：private为true时排除与自己掌握的信息矛盾的声明，
+// 夜里被狼人击杀的更可信，先起跳的次之，无法区分时返回空
+func (ai *AIPlayer) rankSeerClaims(private bool) string {
+	best, bestScore, tie := "", -1, false
+	for i, claim := range ai.GameState.claimsFor(models.Seer) {
+		if private && ai.provenFake(claim.PlayerID) {
+			continue
+		}
+		score := 0
+		if i == 0 {
+			score++
+		}
+		for _, death := range ai.memory.deaths {
+			if death.PlayerID == claim.PlayerID && death.Phase == PhaseNight {
+				score += 2
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = claim.PlayerID, score, false
+		case score == bestScore:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return best
+}
+
+// claimedWolf 获取某个预言家声明中查杀的、仍然存活的玩家
+func (ai *AIPlayer) claimedWolf(playerID string) string {
+	claim := ai.GameState.claimOf(playerID)
+	if claim == nil {
+		return ""
+	}
+	for i := len(claim.Checks) - 1; i >= 0; i-- {
+		check := claim.Checks[i]
+		if check.Wolf && check.TargetID != ai.ID && ai.isAlive(check.TargetID) {
+			return check.TargetID
+		}
+	}
+	return ""
+}
+
+// describeChecks 将查验结果组织成发言
+func (ai *AIPlayer) describeChecks(checks []models.ClaimedCheck) string {
+	results := make([]string, 0, len(checks))
+	for _, check := range checks {
+		key := "ai.check_good"
+		if check.Wolf {
+			key = "ai.check_wolf"
+		}
+		results = append(results, ai.say(key, check.Round, ai.playerName(check.TargetID)))
+	}
+	return strings.Join(results, ai.say("ai.checks_separator"))
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// ErrNotAIPlayer 查询的玩家不由AI控制
+var ErrNotAIPlayer = NewError(CodeNotAIPlayer, "该玩家不是AI玩家")
+
+// AIDecision AI最近一次决策的结果和推理过程
+type AIDecision struct {
+	Round   int               `json:"round"`
+	Phase   string            `json:"phase"`
+	Action  models.GameAction `json:"action"`
+	Reasons []string          `json:"reasons,omitempty"`
+}
+
+// AIDebugInfo AI玩家当前的推理状态，供管理员调试AI策略
+type AIDebugInfo struct {
+	PlayerID     string                 `json:"player_id"`
+	Role         models.Role            `json:"role"`
+	Personality  models.AIPersonality   `json:"personality"`
+	Suspicion    map[string]float64     `json:"suspicion"`   // 存活的其他玩家是狼人的概率
+	KnownRoles   map[string]models.Role `json:"known_roles"` // 已知的玩家角色
+	LastDecision *AIDecision            `json:"last_decision,omitempty"`
+}
+
+// reason 记录本次决策的一条推理
+func (ai *AIPlayer) reason(format string, args ...interface{}) {
+	ai.trace = append(ai.trace, fmt.Sprintf(format, args...))
+}
+
+// potionState 药的剩余状态
+func potionState(available bool) string {
+	if available {
+		return "可用"
+	}
+	return "已用"
+}
+
+// debugInfo 获取AI当前的推理状态
+func (ai *AIPlayer) debugInfo() *AIDebugInfo {
+	info := &AIDebugInfo{
+		PlayerID:     ai.ID,
+		Role:         ai.Role,
+		Personality:  ai.Personality,
+		Suspicion:    make(map[string]float64),
+		KnownRoles:   make(map[string]models.Role),
+		LastDecision: ai.lastDecision,
+	}
+	for _, player := range ai.GameState.Players {
+		if player.Alive && player.ID != ai.ID {
+			info.Suspicion[player.ID] = ai.suspicion(player.ID)
+		}
+	}
+	for playerID, role := range ai.KnownPlayers {
+		info.KnownRoles[playerID] = role
+	}
+	return info
+}
+
+// AIDebugInfo 获取对局中AI玩家的推理状态，外部机器人返回代其行动的内置AI的状态
+func (gc *GameController) AIDebugInfo(playerID string) (*AIDebugInfo, error) {
+	var info *AIDebugInfo
+	var err error = ErrGameNotStarted
+	// 首次查询时会创建AI实例，因此在对局goroutine中执行
+	gc.do(func() {
+		info, err = gc.aiDebugInfo(playerID)
+	})
+	return info, err
+}
+
+// aiDebugInfo 获取AI玩家的推理状态，在对局goroutine中执行
+func (gc *GameController) aiDebugInfo(playerID string) (*AIDebugInfo, error) {
+	if !gc.game.IsStarted {
+		return nil, ErrGameNotStarted
+	}
+	for _, player := range gc.game.Players {
+		if player.ID != playerID {
+			continue
+		}
+		if player.Type == models.HumanPlayer {
+			return nil, ErrNotAIPlayer
+		}
+		return gc.aiPlayer(player).debugInfo(), nil
+	}
+	return nil, NewError(CodePlayerNotFound, "玩家不存在")
+}
@@ -1,125 +1,299 @@
 package services
 
 import (
-	"fmt"
-	"math/rand"
-	"time"
+	"strings"
 
 	"github.com/qianlnk/werewolf/models"
 )
 
-// AIDialogue AI对话生成器
-type AIDialogue struct {
-	game *GameState
+// generateDiscussion 生成白天发言：先陈述昨晚和上一轮投票的情况，再按角色和性格给出自己的判断
+// 发言中的事实全部来自AI能看到的对局信息：死亡、投票、公开的身份声明和自己的查验结果
+func (ai *AIPlayer) generateDiscussion() string {
+	sentences := []string{ai.describeLastNight()}
+	if recap := ai.describeLastVote(); recap != "" {
+		sentences = append(sentences, recap)
+	}
+	if defense := ai.defendSelf(); defense != "" {
+		sentences = append(sentences, defense)
+	}
+
+	var opinion string
+	switch ai.Role {
+	case models.Werewolf, models.WhiteWolf:
+		opinion = ai.werewolfOpinion()
+	case models.Seer:
+		opinion = ai.seerOpinion()
+	default:
+		opinion = ai.villagerOpinion()
+	}
+	if opinion != "" {
+		sentences = append(sentences, opinion)
+	}
+	if callout := ai.sheriffCallout(); callout != "" {
+		sentences = append(sentences, callout)
+	}
+
+	return joinSentences(ai.locale(), sentences)
 }
 
-// NewAIDialogue 创建AI对话生成器实例
-func NewAIDialogue(game *GameState) *AIDialogue {
-	return &AIDialogue{game: game}
+// describeLastNight 描述昨晚的死亡情况
+func (ai *AIPlayer) describeLastNight() string {
+	deaths := ai.memory.deathsIn(ai.GameState.Round, PhaseNight)
+	if len(deaths) == 0 {
+		return ai.say("ai.peaceful_night")
+	}
+	return ai.say("ai.night_deaths", ai.playerNames(deaths))
 }
 
-// GenerateDialogue 生成AI对话内容
-func (ad *AIDialogue) GenerateDialogue(player models.Player) string {
-	switch ad.game.Phase {
-	case PhaseDay:
-		return ad.generateDayDialogue(player)
-	case PhaseVote:
-		return ad.generateVoteDialogue(player)
-	default:
+// describeLastVote 描述上一轮的投票结果，第一天没有投票时返回空
+func (ai *AIPlayer) describeLastVote() string {
+	round := ai.GameState.Round - 1
+	counts, top := ai.memory.voteTally(round)
+	if top == "" {
 		return ""
 	}
+
+	if exiled := ai.memory.deathsIn(round, PhaseVote); len(exiled) > 0 {
+		return ai.say("ai.last_vote_exiled", ai.playerName(top), counts[top], ai.playerNames(exiled))
+	}
+	return ai.say("ai.last_vote", ai.playerName(top), counts[top])
 }
 
-// generateDayDialogue 生成白天阶段的对话
-func (ad *AIDialogue) generateDayDialogue(player models.Player) string {
-	// 根据角色和性格生成对话
-	switch player.Role {
-	case models.Werewolf, models.WhiteWolf:
-		return ad.generateWerewolfDayDialogue(player)
-	case models.Villager:
-		return ad.generateVillagerDayDialogue(player)
-	case models.Seer:
-		return ad.generateSeerDayDialogue(player)
+// defendSelf 回应上次发言之后投票或点名怀疑自己的玩家，没有人指控时返回空
+func (ai *AIPlayer) defendSelf() string {
+	var accusers []string
+	for _, accuser := range ai.memory.accusersSince(ai.memory.lastSpeech) {
+		if ai.isAlive(accuser) {
+			accusers = append(accusers, accuser)
+		}
+	}
+	if len(accusers) == 0 {
+		return ""
+	}
+
+	names := ai.playerNames(accusers)
+	personality := ai.Personality
+	if personality == PersonalityRandom {
+		personalities := []models.AIPersonality{PersonalityAggressive, PersonalityCautious, PersonalityStrategic}
+		personality = personalities[ai.random().Intn(len(personalities))]
+	}
+	switch personality {
+	case PersonalityAggressive:
+		return ai.say("ai.defend_aggressive", names)
+	case PersonalityStrategic:
+		return ai.say("ai.defend_strategic", names)
 	default:
-		return ad.generateDefaultDayDialogue(player)
+		return ai.say("ai.defend_default", names)
 	}
 }
 
-// generateVoteDialogue 生成投票阶段的对话
-func (ad *AIDialogue) generateVoteDialogue(player models.Player) string {
-	// 分析游戏局势
-	suspects := ad.analyzeSuspects(player)
-	if len(suspects) > 0 {
-		target := suspects[rand.Intn(len(suspects))]
-		return fmt.Sprintf("我认为%s比较可疑，建议大家投票给ta", target.Name)
+// seerOpinion 预言家的发言：根据性格决定何时公开查验结果，有人冒充预言家时立即起跳对质
+func (ai *AIPlayer) seerOpinion() string {
+	if len(ai.memory.checks) == 0 {
+		return ai.say("ai.seer_no_info")
+	}
+
+	var rivals []string
+	for _, claim := range ai.GameState.claimsFor(models.Seer) {
+		if claim.PlayerID != ai.ID {
+			rivals = append(rivals, claim.PlayerID)
+		}
+	}
+
+	wolf := ai.checkedWolf()
+	reveal := len(rivals) > 0 || ai.GameState.claimOf(ai.ID) != nil
+	switch ai.Personality {
+	case PersonalityCautious:
+		// 谨慎型查到狼人才公开身份
+		reveal = reveal || wolf != ""
+	case PersonalityStrategic:
+		// 历史对局中第一天起跳的预言家胜率更高时第一天就起跳
+		early := ai.learning().preference(StrategySeerEarlyClaim, StrategySeerLateClaim) > 1
+		reveal = reveal || wolf != "" || ai.GameState.Round >= 2 || early
+	default:
+		reveal = true
+	}
+	if !reveal {
+		return ai.say("ai.seer_hiding")
 	}
-	return "这局形势不太明朗，大家要谨慎投票"
-}
 
-// generateWerewolfDayDialogue 生成狼人白天对话
-func (ad *AIDialogue) generateWerewolfDayDialogue(player models.Player) string {
-	// 狼人需要伪装和误导
-	responses := []string{
-		"昨晚我好像听到了一些动静，但不确定是什么",
-		"我觉得我们要相信预言家，但也要防止有人冒充",
-		"大家要冷静分析，不要被表象迷惑",
+	ai.claim = ai.seerClaim()
+	opinion := []string{ai.say("ai.claim_seer_checks", ai.describeChecks(ai.claim.Checks))}
+	if len(rivals) > 0 {
+		opinion = append(opinion, ai.say("ai.rivals_fake", ai.playerNames(rivals)))
 	}
-	return responses[rand.Intn(len(responses))]
+	if wolf != "" {
+		opinion = append(opinion, ai.say("ai.follow_vote", ai.playerName(wolf)))
+	} else if len(rivals) > 0 && ai.isAlive(rivals[0]) {
+		opinion = append(opinion, ai.say("ai.rival_is_wolf", ai.playerName(rivals[0])))
+	}
+	return ai.joinOpinion(opinion)
 }
 
-// generateVillagerDayDialogue 生成村民白天对话
-func (ad *AIDialogue) generateVillagerDayDialogue(player models.Player) string {
-	// 村民需要积极找出狼人
-	responses := []string{
-		"大家有没有发现什么可疑的人？",
-		"我们要抓紧时间找出狼人",
-		"昨晚的情况大家怎么看？",
+// checkedWolf 获取查验出的仍然存活的狼人
+func (ai *AIPlayer) checkedWolf() string {
+	for _, check := range ai.memory.checks {
+		if isWolf(ai.KnownPlayers[check.TargetID]) && ai.isAlive(check.TargetID) {
+			return check.TargetID
+		}
 	}
-	return responses[rand.Intn(len(responses))]
+	return ""
 }
 
-// generateSeerDayDialogue 生成预言家白天对话
-func (ad *AIDialogue) generateSeerDayDialogue(player models.Player) string {
-	// 预言家需要引导方向
-	responses := []string{
-		"我有一些重要的信息要分享",
-		"大家要相信我的判断",
-		"我觉得有些人的行为很值得怀疑",
+// werewolfOpinion 狼人的发言：冒充预言家编造查验结果，质疑跳预言家的好人，或者把怀疑引向投过同伴的玩家
+func (ai *AIPlayer) werewolfOpinion() string {
+	if claim := ai.fakeSeerClaim(); claim != nil {
+		ai.claim = claim
+		opinion := []string{ai.say("ai.claim_seer")}
+		if len(claim.Checks) > 0 {
+			opinion[0] = ai.say("ai.claim_seer_checks", ai.describeChecks(claim.Checks))
+		}
+		for _, rival := range ai.GameState.claimsFor(models.Seer) {
+			if rival.PlayerID != ai.ID && ai.isAlive(rival.PlayerID) && !isWolf(ai.KnownPlayers[rival.PlayerID]) {
+				opinion = append(opinion, ai.say("ai.fake_seer", ai.playerName(rival.PlayerID)))
+				break
+			}
+		}
+		if wolf := ai.claimedWolf(ai.ID); wolf != "" {
+			opinion = append(opinion, ai.say("ai.follow_vote", ai.playerName(wolf)))
+		}
+		return ai.joinOpinion(opinion)
+	}
+
+	for _, claim := range ai.GameState.claimsFor(models.Seer) {
+		if !ai.isAlive(claim.PlayerID) || isWolf(ai.KnownPlayers[claim.PlayerID]) {
+			continue
+		}
+		name := ai.playerName(claim.PlayerID)
+		switch ai.Personality {
+		case PersonalityAggressive:
+			return ai.say("ai.wolf_reject_seer", name)
+		case PersonalityStrategic:
+			return ai.say("ai.wolf_question_seer", name)
+		default:
+			return ai.say("ai.wolf_wait_seer", name)
+		}
+	}
+
+	// 上一轮投过同伴的好人最容易被引导成怀疑对象
+	round := ai.GameState.Round - 1
+	for _, vote := range ai.memory.votes {
+		if vote.Round != round || !ai.isAlive(vote.VoterID) || isWolf(ai.KnownPlayers[vote.VoterID]) {
+			continue
+		}
+		if isWolf(ai.KnownPlayers[vote.TargetID]) {
+			return ai.say("ai.wolf_redirect", ai.playerName(vote.VoterID), ai.playerName(vote.TargetID))
+		}
+	}
+
+	// 第一天还没有发言记录可以比较
+	if quiet := ai.quietPlayer(); ai.GameState.Round > 1 && quiet != "" && !isWolf(ai.KnownPlayers[quiet]) {
+		return ai.say("ai.wolf_quiet", ai.playerName(quiet))
 	}
-	return responses[rand.Intn(len(responses))]
+	return ai.say("ai.wolf_default")
 }
 
-// generateDefaultDayDialogue 生成默认白天对话
-func (ad *AIDialogue) generateDefaultDayDialogue(player models.Player) string {
-	responses := []string{
-		"让我们好好分析一下局势",
-		"大家有什么想法吗？",
-		"我们要团结一致找出狼人",
+// villagerOpinion 好人阵营其他角色的发言：权衡预言家的声明并指出可疑玩家
+func (ai *AIPlayer) villagerOpinion() string {
+	var sentences []string
+
+	claims := ai.GameState.claimsFor(models.Seer)
+	var credible []string
+	for _, claim := range claims {
+		if !ai.provenFake(claim.PlayerID) {
+			credible = append(credible, claim.PlayerID)
+			continue
+		}
+		// 查验结果和自己掌握的信息矛盾，说明是冒充的
+		for _, check := range claim.Checks {
+			if check.TargetID == ai.ID && check.Wolf {
+				sentences = append(sentences, ai.say("ai.fake_check_on_me", ai.playerName(claim.PlayerID)))
+				break
+			}
+		}
+		if len(sentences) == 0 {
+			sentences = append(sentences, ai.say("ai.fake_check_mismatch", ai.playerName(claim.PlayerID)))
+		}
+	}
+
+	switch {
+	case len(claims) == 1 && len(credible) == 1 && ai.isAlive(credible[0]):
+		if ai.Personality == PersonalityAggressive {
+			sentences = append(sentences, ai.say("ai.seer_doubt", ai.playerName(credible[0])))
+		} else {
+			sentences = append(sentences, ai.say("ai.seer_trust", ai.playerName(credible[0])))
+		}
+	case len(credible) > 1:
+		if trusted := ai.trustedSeer(); trusted != "" {
+			sentences = append(sentences, ai.say("ai.seers_trust", ai.playerNames(credible), ai.playerName(trusted)))
+		} else {
+			sentences = append(sentences, ai.say("ai.seers_unsure", ai.playerNames(credible)))
+		}
+	}
+
+	if suspect := ai.mostSuspicious(); suspect != "" && !ai.provenFake(suspect) {
+		if !ai.isActive(suspect) {
+			sentences = append(sentences, ai.say("ai.suspect_quiet", ai.playerName(suspect)))
+		} else {
+			sentences = append(sentences, ai.say("ai.suspect", ai.playerName(suspect)))
+		}
 	}
-	return responses[rand.Intn(len(responses))]
+
+	if len(sentences) == 0 {
+		responses := []string{"ai.filler_listen", "ai.filler_hurry", "ai.filler_share"}
+		return ai.say(responses[ai.random().Intn(len(responses))])
+	}
+	return ai.joinOpinion(sentences)
 }
 
-// analyzeSuspects 分析可疑玩家
-func (ad *AIDialogue) analyzeSuspects(player models.Player) []models.Player {
-	suspects := make([]models.Player, 0)
-	for _, p := range ad.game.Players {
-		if !p.Alive || p.ID == player.ID {
+// quietPlayer 获取发言最少的存活玩家
+func (ai *AIPlayer) quietPlayer() string {
+	quiet := ""
+	for _, player := range ai.GameState.Players {
+		if !player.Alive || player.ID == ai.ID || ai.isActive(player.ID) {
 			continue
 		}
+		if quiet == "" || ai.memory.speeches[player.ID] < ai.memory.speeches[quiet] {
+			quiet = player.ID
+		}
+	}
+	return quiet
+}
+
+// isAlive 玩家是否存活
+func (ai *AIPlayer) isAlive(playerID string) bool {
+	for _, player := range ai.GameState.Players {
+		if player.ID == playerID {
+			return player.Alive
+		}
+	}
+	return false
+}
 
-		// 分析玩家行为和发言
-		if isSuspicious(p, ad.game.Actions) {
-			suspects = append(suspects, p)
+// playerName 获取玩家的显示名称
+func (ai *AIPlayer) playerName(playerID string) string {
+	for _, player := range ai.GameState.Players {
+		if player.ID == playerID && player.Name != "" {
+			return player.Name
 		}
 	}
-	return suspects
+	return playerID
 }
 
-// isSuspicious 判断玩家是否可疑
-func isSuspicious(player models.Player, actions []models.GameAction) bool {
-	// 实现可疑行为判断逻辑
-	// 例如：分析投票模式、发言矛盾等
-	// 这里使用随机值作为示例
-	rand.Seed(time.Now().UnixNano())
-	return rand.Float64() < 0.3 // 30%的概率判定为可疑
+// playerNames 获取多名玩家的显示名称，按房间的语言连接
+func (ai *AIPlayer) playerNames(playerIDs []string) string {
+	names := make([]string, 0, len(playerIDs))
+	for _, playerID := range playerIDs {
+		names = append(names, ai.playerName(playerID))
+	}
+	return joinNames(ai.locale(), names)
+}
+
+// joinOpinion 把观点中的多句话连成一句，句末的标点由generateDiscussion统一添加
+func (ai *AIPlayer) joinOpinion(sentences []string) string {
+	joined := joinSentences(ai.locale(), sentences)
+	if ai.locale() == LocaleEN {
+		return strings.TrimSuffix(joined, ".")
+	}
+	return strings.TrimSuffix(joined, "。")
 }
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/reporting"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+// 从历史对局中学习的起跳策略
+const (
+	StrategyWolfEarlyFakeClaim = "wolf_early_fake_claim" // 狼队在第一天悍跳预言家
+	StrategyWolfNoEarlyClaim   = "wolf_no_early_claim"   // 狼队第一天没有悍跳
+	StrategySeerEarlyClaim     = "seer_early_claim"      // 预言家在第一天起跳
+	StrategySeerLateClaim      = "seer_late_claim"       // 预言家第二天之后才起跳或始终没有起跳
+)
+
+const (
+	learningMinGames  = 10 // 两种策略的对局数都不少于该值时才调整AI的倾向
+	learningMinClaims = 3  // 玩家声明预言家的对局数不少于该值时才参考其习惯
+)
+
+// StrategyStats 起跳策略的对局数和胜率
+type StrategyStats struct {
+	Games   int     `json:"games"`
+	Wins    int     `json:"wins"`
+	WinRate float64 `json:"win_rate"`
+}
+
+// PlayerHabits 玩家在历史对局中的起跳习惯
+type PlayerHabits struct {
+	SeerClaims     int `json:"seer_claims"`      // 声明过预言家的对局数
+	FakeSeerClaims int `json:"fake_seer_claims"` // 其中不是预言家的对局数
+}
+
+// AILearning 从历史对局中汇总的策略胜率和玩家习惯，对局开始时载入，整局不变
+type AILearning struct {
+	Games      int                       `json:"games"`
+	Strategies map[string]*StrategyStats `json:"strategies"`
+	Players    map[string]*PlayerHabits  `json:"players"` // 真人玩家和外部机器人，AI的ID每局不同不做统计
+	UpdatedAt  int64                     `json:"updated_at"`
+}
+
+func newAILearning() *AILearning {
+	return &AILearning{
+		Strategies: make(map[string]*StrategyStats),
+		Players:    make(map[string]*PlayerHabits),
+	}
+}
+
+// aiLearning 最近一次汇总的结果，汇总完成后整体替换
+var aiLearning atomic.Pointer[AILearning]
+
+func init() {
+	aiLearning.Store(newAILearning())
+}
+
+// CurrentAILearning 获取最近一次汇总的结果
+func CurrentAILearning() *AILearning {
+	return aiLearning.Load()
+}
+
+// AILearningManager 定期从对局记录中汇总AI策略权重的离线任务
+type AILearningManager struct {
+	store   storage.Store
+	running sync.Mutex // 保证同一时间只有一次汇总
+}
+
+// NewAILearningManager 创建汇总任务实例
+func NewAILearningManager(store storage.Store) *AILearningManager {
+	return &AILearningManager{store: store}
+}
+
+// Start 立即汇总一次，之后按固定间隔重新汇总，interval为0时不启动
+func (lm *AILearningManager) Start(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	run := func() { lm.Run() }
+	go func() {
+		reporting.Call("ai_learning", run)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			reporting.Call("ai_learning", run)
+		}
+	}()
+}
+
+// Run 立即汇总全部对局记录，成功后对之后开始的对局生效
+func (lm *AILearningManager) Run() (*AILearning, error) {
+	lm.running.Lock()
+	defer lm.running.Unlock()
+
+	records, err := lm.store.ListGameRecordsBefore(time.Now().Unix()+1, 0)
+	if err != nil {
+		slog.Error("汇总AI策略失败", "error", err)
+		return nil, err
+	}
+
+	learning := newAILearning()
+	for _, record := range records {
+		learning.add(record)
+	}
+	for _, stats := range learning.Strategies {
+		stats.WinRate = float64(stats.Wins) / float64(stats.Games)
+	}
+	learning.UpdatedAt = time.Now().Unix()
+
+	aiLearning.Store(learning)
+	slog.Info("AI策略汇总完成", "games", learning.Games, "players", len(learning.Players))
+	return learning, nil
+}
+
+// add 汇总一局对局：从发言和房间聊天中找出每名玩家首次声明预言家的回合
+func (l *AILearning) add(record *models.GameRecord) {
+	wolvesWon := record.Result == WerewolfWin || record.Result == WhiteWolfWin
+	if !wolvesWon && record.Result != VillagerWin && record.Result != LoversWin {
+		return
+	}
+
+	claimRounds := make(map[string]int)
+	for _, event := range record.Events {
+		spoken := (event.Type == "action" && event.Action == "discuss") || (event.Type == "chat" && event.Channel == ChannelRoom)
+		if !spoken || parseRoleClaim(event.Content) != models.Seer {
+			continue
+		}
+		if _, claimed := claimRounds[event.PlayerID]; !claimed {
+			claimRounds[event.PlayerID] = event.Round
+		}
+	}
+
+	l.Games++
+	wolfEarlyClaim := false
+	for _, player := range record.Players {
+		round, claimed := claimRounds[player.ID]
+		switch {
+		case isWolf(player.Role):
+			wolfEarlyClaim = wolfEarlyClaim || (claimed && round == 1)
+		case player.Role == models.Seer:
+			strategy := StrategySeerLateClaim
+			if claimed && round == 1 {
+				strategy = StrategySeerEarlyClaim
+			}
+			l.count(strategy, record.Result == VillagerWin)
+		}
+
+		if claimed && player.Type != models.AIPlayer {
+			habits := l.Players[player.ID]
+			if habits == nil {
+				habits = &PlayerHabits{}
+				l.Players[player.ID] = habits
+			}
+			habits.SeerClaims++
+			if player.Role != models.Seer {
+				habits.FakeSeerClaims++
+			}
+		}
+	}
+
+	if wolfEarlyClaim {
+		l.count(StrategyWolfEarlyFakeClaim, wolvesWon)
+	} else {
+		l.count(StrategyWolfNoEarlyClaim, wolvesWon)
+	}
+}
+
+// count 记录一次策略的使用结果
+func (l *AILearning) count(strategy string, won bool) {
+	stats := l.Strategies[strategy]
+	if stats == nil {
+		stats = &StrategyStats{}
+		l.Strategies[strategy] = stats
+	}
+	stats.Games++
+	if won {
+		stats.Wins++
+	}
+}
+
+// preference 策略相对于另一种策略的胜率之比，限制在0.5到2之间，样本不足时返回1
+func (l *AILearning) preference(strategy, alternative string) float64 {
+	chosen, other := l.Strategies[strategy], l.Strategies[alternative]
+	if chosen == nil || other == nil || chosen.Games < learningMinGames || other.Games < learningMinGames {
+		return 1
+	}
+	if other.WinRate == 0 {
+		return 2
+	}
+	return max(0.5, min(2, chosen.WinRate/other.WinRate))
+}
+
+// fakeClaimRate 玩家历史上声明预言家时是假跳的比例，样本不足时返回false
+func (l *AILearning) fakeClaimRate(playerID string) (float64, bool) {
+	habits := l.Players[playerID]
+	if habits == nil || habits.SeerClaims < learningMinClaims {
+		return 0, false
+	}
+	return float64(habits.FakeSeerClaims) / float64(habits.SeerClaims), true
+}
+
+// learning 获取本局载入的历史汇总，从快照恢复的对局使用最近一次汇总
+func (ai *AIPlayer) learning() *AILearning {
+	if ai.GameState.learning != nil {
+		return ai.GameState.learning
+	}
+	return CurrentAILearning()
+}
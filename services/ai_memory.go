@@ -0,0 +1,226 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// aiMemory AI玩家在整局游戏中积累的信息，阶段切换清空GameState.Actions后仍然保留
+// 只记录该AI能够看到的信息：公开的投票、发言和死亡，以及自己的动作，狼人还能看到同伴的击杀
+type aiMemory struct {
+	cursor   int            // 已处理的对局事件数
+	deaths   []aiDeath      // 按时间顺序的死亡记录
+	votes    []aiVote       // 历史投票，每个回合每人只保留最后一票
+	speeches map[string]int // playerID -> 白天发言次数
+	kills    []aiVote       // 狼人同伴的击杀选择，仅狼人记录
+	checks   []aiVote       // 自己的查验记录，仅预言家记录
+	protects []aiVote       // 自己的守护记录，仅守卫记录
+
+	accusations []aiAccusation // 其他玩家对自己的指控
+	lastSpeech  int            // 自己最近一次白天发言的事件序号
+}
+
+// aiAccusation 其他玩家在投票或公开发言中对自己的指控
+type aiAccusation struct {
+	Seq       int
+	Round     int
+	AccuserID string
+	Vote      bool // true为投票，false为发言或聊天中点名怀疑
+}
+
+// aiDeath 玩家死亡记录，Phase为死亡时的阶段：night为夜晚死亡，vote为被投票放逐
+type aiDeath struct {
+	PlayerID string
+	Round    int
+	Phase    string
+}
+
+// aiVote 投票或击杀选择
+type aiVote struct {
+	Round    int
+	VoterID  string
+	TargetID string
+}
+
+func newAIMemory() *aiMemory {
+	return &aiMemory{speeches: make(map[string]int)}
+}
+
+// Observe 读取上次观察之后的对局事件和已知角色，更新AI的记忆
+func (ai *AIPlayer) Observe(game *GameState) {
+	for playerID, role := range game.KnownRoles[ai.ID] {
+		ai.KnownPlayers[playerID] = role
+	}
+
+	// 事件日志在新对局开始时重置，记忆随之重新积累
+	if ai.memory.cursor > len(game.Events) {
+		ai.memory = newAIMemory()
+	}
+	for _, event := range game.Events[ai.memory.cursor:] {
+		ai.remember(event)
+	}
+	ai.memory.cursor = len(game.Events)
+}
+
+// remember 记录单个对局事件
+func (ai *AIPlayer) remember(event models.GameEvent) {
+	switch event.Type {
+	case "death":
+		ai.memory.deaths = append(ai.memory.deaths, aiDeath{PlayerID: event.PlayerID, Round: event.Round, Phase: event.Phase})
+
+	case "revive":
+		kept := ai.memory.deaths[:0]
+		for _, death := range ai.memory.deaths {
+			if death.PlayerID != event.PlayerID {
+				kept = append(kept, death)
+			}
+		}
+		ai.memory.deaths = kept
+
+	case "chat":
+		if event.Channel == ChannelRoom && event.PlayerID != ai.ID && ai.accusedIn(event.Content) {
+			ai.memory.accusations = append(ai.memory.accusations, aiAccusation{Seq: event.Seq, Round: event.Round, AccuserID: event.PlayerID})
+		}
+
+	case "action":
+		switch event.Action {
+		case "vote":
+			ai.memory.votes = rememberVote(ai.memory.votes, aiVote{Round: event.Round, VoterID: event.PlayerID, TargetID: event.TargetID})
+			if event.TargetID == ai.ID && event.PlayerID != ai.ID {
+				ai.memory.accusations = append(ai.memory.accusations, aiAccusation{Seq: event.Seq, Round: event.Round, AccuserID: event.PlayerID, Vote: true})
+			}
+		case "discuss", "campaign":
+			ai.memory.speeches[event.PlayerID]++
+			if event.PlayerID == ai.ID {
+				ai.memory.lastSpeech = event.Seq
+			} else if ai.accusedIn(event.Content) {
+				ai.memory.accusations = append(ai.memory.accusations, aiAccusation{Seq: event.Seq, Round: event.Round, AccuserID: event.PlayerID})
+			}
+		case "check":
+			if event.PlayerID == ai.ID && event.TargetID != "" {
+				ai.memory.checks = append(ai.memory.checks, aiVote{Round: event.Round, VoterID: event.PlayerID, TargetID: event.TargetID})
+			}
+		case "protect":
+			if event.PlayerID == ai.ID {
+				ai.memory.protects = rememberVote(ai.memory.protects, aiVote{Round: event.Round, VoterID: event.PlayerID, TargetID: event.TargetID})
+			}
+		case "kill":
+			if isWolf(ai.Role) {
+				ai.memory.kills = rememberVote(ai.memory.kills, aiVote{Round: event.Round, VoterID: event.PlayerID, TargetID: event.TargetID})
+			}
+		}
+	}
+}
+
+// accusationWords 发言中表示怀疑的词语，与玩家名字同时出现时视为指控该玩家，英文按小写匹配
+var accusationWords = []string{"狼", "可疑", "嫌疑", "有问题", "带节奏", "投他", "跟我投", "查杀",
+	"wolf", "suspicious", "suspect", "vote for", "something is off", "astray"}
+
+// accusedIn 发言是否点名怀疑自己
+func (ai *AIPlayer) accusedIn(content string) bool {
+	if !strings.Contains(content, ai.playerName(ai.ID)) && !strings.Contains(content, ai.ID) {
+		return false
+	}
+	content = strings.ToLower(content)
+	for _, word := range accusationWords {
+		if strings.Contains(content, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// rememberVote 记录投票，同一玩家在同一回合改票时以最后一次为准
+func rememberVote(votes []aiVote, vote aiVote) []aiVote {
+	for i, existing := range votes {
+		if existing.Round == vote.Round && existing.VoterID == vote.VoterID {
+			votes[i] = vote
+			return votes
+		}
+	}
+	return append(votes, vote)
+}
+
+// votesBy 获取玩家的历史投票目标
+func (m *aiMemory) votesBy(playerID string) []string {
+	var targets []string
+	for _, vote := range m.votes {
+		if vote.VoterID == playerID {
+			targets = append(targets, vote.TargetID)
+		}
+	}
+	return targets
+}
+
+// teamTarget 获取狼队某一晚的共识击杀目标，仅狼人记录
+func (m *aiMemory) teamTarget(round int) string {
+	actions := make([]models.GameAction, 0, len(m.kills))
+	for _, kill := range m.kills {
+		if kill.Round == round {
+			actions = append(actions, models.GameAction{Type: "kill", PlayerID: kill.VoterID, TargetID: kill.TargetID})
+		}
+	}
+	return wolfConsensusTarget(actions)
+}
+
+// accusersSince 获取自己上次发言之后指控过自己的玩家，按首次指控的顺序去重
+func (m *aiMemory) accusersSince(seq int) []string {
+	var accusers []string
+	seen := make(map[string]bool)
+	for _, accusation := range m.accusations {
+		if accusation.Seq > seq && !seen[accusation.AccuserID] {
+			seen[accusation.AccuserID] = true
+			accusers = append(accusers, accusation.AccuserID)
+		}
+	}
+	return accusers
+}
+
+// spokenAccusations 统计玩家在发言中指控自己的次数
+func (m *aiMemory) spokenAccusations(playerID string) int {
+	count := 0
+	for _, accusation := range m.accusations {
+		if accusation.AccuserID == playerID && !accusation.Vote {
+			count++
+		}
+	}
+	return count
+}
+
+// protectIn 获取自己某一晚守护的玩家，仅守卫记录
+func (m *aiMemory) protectIn(round int) string {
+	for _, protect := range m.protects {
+		if protect.Round == round {
+			return protect.TargetID
+		}
+	}
+	return ""
+}
+
+// deathsIn 获取某一回合某个阶段死亡的玩家，phase为night时是当晚死亡，为vote时是被放逐
+func (m *aiMemory) deathsIn(round int, phase string) []string {
+	var players []string
+	for _, death := range m.deaths {
+		if death.Phase == phase && death.Round == round {
+			players = append(players, death.PlayerID)
+		}
+	}
+	return players
+}
+
+// voteTally 统计某一回合的投票，返回各玩家的得票数和得票最多的玩家
+func (m *aiMemory) voteTally(round int) (map[string]int, string) {
+	counts := make(map[string]int)
+	top := ""
+	for _, vote := range m.votes {
+		if vote.Round != round {
+			continue
+		}
+		counts[vote.TargetID]++
+		if counts[vote.TargetID] > counts[top] {
+			top = vote.TargetID
+		}
+	}
+	return counts, top
+}
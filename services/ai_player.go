@@ -2,52 +2,135 @@ package services
 
 import (
 	"math/rand"
-	"time"
 
 	"github.com/qianlnk/werewolf/models"
 )
 
 // 性格特征
 const (
-	PersonalityAggressive = "aggressive" // 激进
-	PersonalityCautious   = "cautious"   // 谨慎
-	PersonalityStrategic  = "strategic"  // 策略
-	PersonalityRandom     = "random"     // 随机
+	PersonalityAggressive = models.Aggressive // 激进
+	PersonalityCautious   = models.Cautious   // 谨慎
+	PersonalityStrategic  = models.Strategic  // 策略
+	PersonalityRandom     = models.Random     // 随机
 )
 
-// AIPlayer AI玩家
+// AIPlayer AI玩家，由GameController在整局游戏中保留，通过Observe积累对局信息
 type AIPlayer struct {
 	ID           string
-	Personality  string
+	Personality  models.AIPersonality
 	Role         models.Role
 	GameState    *GameState
 	KnownPlayers map[string]models.Role // 已知的玩家角色信息
+	memory       *aiMemory
+	claim        *models.RoleClaim // 本次发言附带的身份声明
+	sheriffCall  string            // 作为警长在本轮发言中归票的玩家，投票时保持一致
+	trace        []string          // 本次决策的推理过程
+	lastDecision *AIDecision       // 最近一次决策，供管理员调试
 }
 
-// NewAIPlayer 创建AI玩家实例
-func NewAIPlayer(id string, role models.Role, gameState *GameState) *AIPlayer {
-	personalities := []string{
-		PersonalityAggressive,
-		PersonalityCautious,
-		PersonalityStrategic,
-		PersonalityRandom,
+// NewAIPlayer 创建AI玩家实例，personality为空时随机选择性格
+func NewAIPlayer(id string, role models.Role, personality models.AIPersonality, gameState *GameState) *AIPlayer {
+	// 继承玩家已知的角色信息，AI接管真人玩家时不会丢失信息
+	knownPlayers := make(map[string]models.Role)
+	for playerID, knownRole := range gameState.KnownRoles[id] {
+		knownPlayers[playerID] = knownRole
+	}
+
+	if personality == "" {
+		personality = pickAIPersonality(gameState.random(), nil)
 	}
 
-	rand.Seed(time.Now().UnixNano())
 	return &AIPlayer{
 		ID:           id,
-		Personality:  personalities[rand.Intn(len(personalities))],
+		Personality:  personality,
 		Role:         role,
 		GameState:    gameState,
-		KnownPlayers: make(map[string]models.Role),
+		KnownPlayers: knownPlayers,
+		memory:       newAIMemory(),
+	}
+}
+
+// random 获取对局的随机数生成器，同一种子下AI的决策可以复现
+func (ai *AIPlayer) random() *rand.Rand {
+	return ai.GameState.random()
+}
+
+// pickAIPersonality 按权重分布选择AI性格，分布为空时等概率随机选择
+func pickAIPersonality(rng *rand.Rand, weights map[models.AIPersonality]int) models.AIPersonality {
+	total := 0
+	for _, personality := range models.AIPersonalities {
+		total += weights[personality]
 	}
+	if total == 0 {
+		return models.AIPersonalities[rng.Intn(len(models.AIPersonalities))]
+	}
+
+	n := rng.Intn(total)
+	for _, personality := range models.AIPersonalities {
+		if n < weights[personality] {
+			return personality
+		}
+		n -= weights[personality]
+	}
+	return models.AIPersonalities[len(models.AIPersonalities)-1]
 }
 
-// DecideAction 决定下一步行动
+// validateAIPersonalities 校验房间的AI性格分布
+func validateAIPersonalities(weights map[models.AIPersonality]int) error {
+	total := 0
+	for personality, weight := range weights {
+		valid := false
+		for _, known := range models.AIPersonalities {
+			if personality == known {
+				valid = true
+				break
+			}
+		}
+		if !valid || weight < 0 {
+			return ErrInvalidPersonality
+		}
+		total += weight
+	}
+	if len(weights) > 0 && total == 0 {
+		return ErrInvalidPersonality
+	}
+	return nil
+}
+
+// DecideAction 决定下一步行动，并记录本次决策的推理过程
 func (ai *AIPlayer) DecideAction() models.GameAction {
+	ai.trace = nil
+	action := ai.decide()
+	// 本阶段无事可做时保留上一次决策，便于调试时查看
+	if action.Type != "" {
+		ai.lastDecision = &AIDecision{
+			Round:   ai.GameState.Round,
+			Phase:   ai.GameState.Phase,
+			Action:  action,
+			Reasons: ai.trace,
+		}
+	}
+	return action
+}
+
+// decide 按阶段和角色决定行动
+func (ai *AIPlayer) decide() models.GameAction {
+	// 出局的猎人在任何阶段都可以开枪
+	if ai.GameState.PendingShot == ai.ID {
+		return ai.decideHunterShot()
+	}
+	// 出局的警长移交或撕毁警徽
+	if ai.GameState.PendingBadge == ai.ID {
+		return ai.decideBadge()
+	}
+
 	switch ai.GameState.Phase {
 	case PhaseNight:
 		return ai.decideNightAction()
+	case PhaseCampaign:
+		return ai.decideCampaignAction()
+	case PhaseSheriffVote:
+		return ai.decideSheriffVote()
 	case PhaseDay:
 		return ai.decideDayAction()
 	case PhaseVote:
@@ -78,6 +161,16 @@ func (ai *AIPlayer) decideNightAction() models.GameAction {
 	case models.Guard:
 		action.Type = "protect"
 		action.TargetID = ai.selectProtectTarget()
+
+	case models.Cupid:
+		// 丘比特只在第一晚连情侣
+		if ai.GameState.Round == 1 {
+			action.Type = "link"
+			action.TargetID = ai.selectLoverTarget()
+		}
+
+	case models.Hunter, models.Thief, models.Villager:
+		// 猎人在出局时开枪，见decideHunterShot；盗贼和村民夜晚没有行动
 	}
 
 	return action
@@ -85,6 +178,17 @@ func (ai *AIPlayer) decideNightAction() models.GameAction {
 
 // decideDayAction 决定白天行动
 func (ai *AIPlayer) decideDayAction() models.GameAction {
+	// 白狼王可以在白天自爆带走一名玩家
+	if ai.Role == models.WhiteWolf {
+		if target := ai.selectExplodeTarget(); target != "" {
+			return models.GameAction{
+				PlayerID: ai.ID,
+				Type:     "explode",
+				TargetID: target,
+			}
+		}
+	}
+
 	return models.GameAction{
 		PlayerID: ai.ID,
 		Type:     "discuss",
@@ -92,61 +196,75 @@ func (ai *AIPlayer) decideDayAction() models.GameAction {
 	}
 }
 
-// decideVoteAction 决定投票行动
+// decideVoteAction 决定投票行动，警长按发言时的归票投票
 func (ai *AIPlayer) decideVoteAction() models.GameAction {
+	target := ai.selectVoteTarget()
+	if ai.sheriffCall != "" && ai.GameState.Sheriff == ai.ID && ai.isAlive(ai.sheriffCall) {
+		ai.reason("按归票投给%s", ai.sheriffCall)
+		target = ai.sheriffCall
+	}
+	ai.sheriffCall = ""
 	return models.GameAction{
 		PlayerID: ai.ID,
 		Type:     "vote",
-		TargetID: ai.selectVoteTarget(),
+		TargetID: target,
 	}
 }
 
-// selectKillTarget 选择击杀目标
+// selectKillTarget 选择击杀目标，狼队每晚只击杀一人：
+// 同伴已经选择了目标时跟随当前共识，否则由该AI为全队选出目标
 func (ai *AIPlayer) selectKillTarget() string {
-	var potentialTargets []string
+	if target := wolfConsensusTarget(ai.GameState.Actions); target != "" {
+		ai.reason("跟随狼队当前的共识目标%s", target)
+		return target
+	}
 
-	for _, player := range ai.GameState.Players {
-		if !player.Alive || player.Role == models.Werewolf || player.Role == models.WhiteWolf {
-			continue
+	// 公开起跳的预言家威胁最大，无法分辨真假时也先击杀
+	for _, claim := range ai.GameState.claimsFor(models.Seer) {
+		if ai.isAlive(claim.PlayerID) && !isWolf(ai.KnownPlayers[claim.PlayerID]) && !ai.likelyProtected(claim.PlayerID) {
+			ai.reason("%s起跳了预言家，优先击杀", claim.PlayerID)
+			return claim.PlayerID
 		}
+	}
 
-		switch ai.Personality {
-		case PersonalityAggressive:
-			// 优先击杀特殊角色
-			if role, known := ai.KnownPlayers[player.ID]; known &&
-				(role == models.Seer || role == models.Witch) {
+	// 优先击杀已暴露的神职，守卫和女巫会让之后的击杀落空
+	for _, role := range []models.Role{models.Seer, models.Witch, models.Guard} {
+		for _, player := range ai.GameState.Players {
+			if player.Alive && ai.KnownPlayers[player.ID] == role && !ai.likelyProtected(player.ID) {
+				ai.reason("已知%s是%s，优先击杀", player.ID, role)
 				return player.ID
 			}
-
-		case PersonalityCautious:
-			// 优先击杀可能威胁较小的目标
-			if _, known := ai.KnownPlayers[player.ID]; !known {
-				potentialTargets = append(potentialTargets, player.ID)
-			}
-
-		case PersonalityStrategic:
-			// 根据游戏局势选择目标
-			if role, known := ai.KnownPlayers[player.ID]; known && role == models.Villager {
-				potentialTargets = append(potentialTargets, player.ID)
-			}
-
-		case PersonalityRandom:
-			potentialTargets = append(potentialTargets, player.ID)
 		}
 	}
 
-	if len(potentialTargets) > 0 {
-		return potentialTargets[rand.Intn(len(potentialTargets))]
-	}
-
-	// 如果没有找到合适的目标，随机选择一个存活的非狼人玩家
+	// 其余玩家按威胁抽样：在公开信息下越不可疑的好人越可能是神职，越值得击杀
+	// 上一晚击杀落空的目标很可能仍被守卫或女巫保护，尽量避开
+	var candidates, protected []string
 	for _, player := range ai.GameState.Players {
-		if player.Alive && player.Role != models.Werewolf && player.Role != models.WhiteWolf {
-			return player.ID
+		if !player.Alive || isWolf(ai.KnownPlayers[player.ID]) || player.ID == ai.ID || player.ID == ai.lover() {
+			continue
+		}
+		if ai.likelyProtected(player.ID) {
+			protected = append(protected, player.ID)
+			continue
 		}
+		candidates = append(candidates, player.ID)
+	}
+	if len(protected) > 0 {
+		ai.reason("避开上一晚击杀落空的%s", ai.playerNames(protected))
+	}
+	if len(candidates) == 0 {
+		candidates = protected
 	}
 
-	return ""
+	return ai.sampleTarget(candidates, func(playerID string) float64 {
+		return 1 - ai.suspicion(playerID)
+	})
+}
+
+// likelyProtected 狼队上一晚的共识目标没有死亡，说明被守卫守护或被女巫救下
+func (ai *AIPlayer) likelyProtected(playerID string) bool {
+	return ai.memory.teamTarget(ai.GameState.Round-1) == playerID
 }
 
 // selectCheckTarget 选择查验目标
@@ -162,8 +280,10 @@ func (ai *AIPlayer) selectCheckTarget() string {
 		case PersonalityAggressive:
 			// 优先查验可疑的玩家
 			if ai.isSuspicious(player.ID) {
+				ai.reason("%s的怀疑概率为%.2f，优先查验", player.ID, ai.suspicion(player.ID))
 				return player.ID
 			}
+			potentialTargets = append(potentialTargets, player.ID)
 
 		case PersonalityCautious:
 			// 优先查验安静的玩家
@@ -177,72 +297,121 @@ func (ai *AIPlayer) selectCheckTarget() string {
 	}
 
 	if len(potentialTargets) > 0 {
-		return potentialTargets[rand.Intn(len(potentialTargets))]
+		return potentialTargets[ai.random().Intn(len(potentialTargets))]
 	}
 
 	return ""
 }
 
-// decideWitchAction 决定女巫行动
+// decideWitchAction 决定女巫行动，在狼人全部选定击杀目标后调用
+// 解药只能救今晚被击杀的玩家且不能自救，每种药只能用一次
 func (ai *AIPlayer) decideWitchAction() models.GameAction {
 	action := models.GameAction{
 		PlayerID: ai.ID,
 	}
 
-	// 获取昨晚被杀的玩家
+	// 今晚被狼人击杀的玩家
 	killedPlayer := ai.getLastKilledPlayer()
+	canSave := ai.hasSavePotion() && killedPlayer != "" && killedPlayer != ai.ID
+	var poisonTarget string
+	if ai.hasPoison() {
+		poisonTarget = ai.selectPoisonTarget()
+	}
+	ai.reason("今晚被击杀的是%s，解药%s，毒药%s，可毒的目标为%s",
+		killedPlayer, potionState(ai.hasSavePotion()), potionState(ai.hasPoison()), poisonTarget)
 
 	switch ai.Personality {
 	case PersonalityAggressive:
 		// 激进型女巫倾向于使用毒药
-		if ai.hasPoison() && ai.isSuspicious(killedPlayer) {
+		if poisonTarget != "" {
 			action.Type = "poison"
-			action.TargetID = ai.selectPoisonTarget()
-		} else if ai.hasSavePotion() && ai.isImportantPlayer(killedPlayer) {
+			action.TargetID = poisonTarget
+		} else if canSave && ai.isImportantPlayer(killedPlayer) {
 			action.Type = "save"
 			action.TargetID = killedPlayer
 		}
 
 	case PersonalityCautious:
 		// 谨慎型女巫优先考虑救人
-		if ai.hasSavePotion() && ai.isImportantPlayer(killedPlayer) {
+		if canSave && ai.isImportantPlayer(killedPlayer) {
 			action.Type = "save"
 			action.TargetID = killedPlayer
 		}
 
 	case PersonalityStrategic:
 		// 策略型女巫根据局势决定
-		if ai.hasSavePotion() && ai.shouldSavePlayer(killedPlayer) {
+		if canSave && ai.shouldSavePlayer(killedPlayer) {
 			action.Type = "save"
 			action.TargetID = killedPlayer
-		} else if ai.hasPoison() && ai.shouldPoisonPlayer() {
+		} else if poisonTarget != "" && ai.shouldPoisonPlayer() {
 			action.Type = "poison"
-			action.TargetID = ai.selectPoisonTarget()
+			action.TargetID = poisonTarget
 		}
 
 	default:
 		// 随机型女巫随机决定
-		if rand.Float64() < 0.5 && ai.hasSavePotion() && killedPlayer != "" {
+		if ai.random().Float64() < currentAITuning().Witch.RandomSaveChance && canSave {
 			action.Type = "save"
 			action.TargetID = killedPlayer
-		} else if ai.hasPoison() {
+		} else if poisonTarget != "" {
 			action.Type = "poison"
-			action.TargetID = ai.selectPoisonTarget()
+			action.TargetID = poisonTarget
 		}
 	}
 
+	// 两瓶药都不用时选择pass，结束女巫的步骤而不必等到计时结束
+	if action.Type == "" {
+		action.Type = "pass"
+	}
 	return action
 }
 
-// 辅助方法
-func (ai *AIPlayer) getLastKilledPlayer() string {
-	// 获取昨晚被狼人杀害的玩家
-	for _, action := range ai.GameState.Actions {
-		if action.Type == "kill" {
-			return action.TargetID
+// decideHunterShot 猎人出局时决定是否开枪：优先带走确认的狼人和所信任的预言家查杀的玩家，
+// 否则只在足够怀疑时按怀疑概率开枪。谨慎型要求更高，残局时打错人可能直接输掉对局，也要求更高
+func (ai *AIPlayer) decideHunterShot() models.GameAction {
+	action := models.GameAction{
+		PlayerID: ai.ID,
+	}
+
+	threshold := currentAITuning().shotThreshold(ai.Personality, ai.countAlivePlayers())
+
+	var suspects []string
+	for _, player := range ai.GameState.Players {
+		if !player.Alive || player.ID == ai.ID {
+			continue
+		}
+		if isWolf(ai.KnownPlayers[player.ID]) {
+			ai.reason("已知%s是狼人，开枪带走", player.ID)
+			action.Type = "shoot"
+			action.TargetID = player.ID
+			return action
+		}
+		if ai.suspicion(player.ID) >= threshold {
+			suspects = append(suspects, player.ID)
 		}
 	}
-	return ""
+
+	target := ""
+	if seer := ai.trustedSeer(); seer != "" {
+		if target = ai.claimedWolf(seer); target != "" {
+			ai.reason("带走所信任的预言家%s查杀的%s", seer, target)
+		}
+	}
+	if target == "" {
+		ai.reason("怀疑概率不低于%.2f的玩家才考虑开枪", threshold)
+		target = ai.sampleTarget(suspects, ai.suspicion)
+	}
+	if target != "" {
+		action.Type = "shoot"
+		action.TargetID = target
+	}
+	return action
+}
+
+// 辅助方法
+func (ai *AIPlayer) getLastKilledPlayer() string {
+	// 获取今晚狼队的击杀目标
+	return wolfConsensusTarget(ai.GameState.Actions)
 }
 
 func (ai *AIPlayer) isImportantPlayer(playerID string) bool {
@@ -250,7 +419,9 @@ func (ai *AIPlayer) isImportantPlayer(playerID string) bool {
 	if role, known := ai.KnownPlayers[playerID]; known {
 		return role == models.Seer || role == models.Witch || role == models.Guard
 	}
-	return false
+	// 公开声明了神职身份且没有被识破的玩家也值得救
+	claim := ai.GameState.claimOf(playerID)
+	return claim != nil && !ai.provenFake(playerID)
 }
 
 func (ai *AIPlayer) shouldSavePlayer(playerID string) bool {
@@ -259,32 +430,25 @@ func (ai *AIPlayer) shouldSavePlayer(playerID string) bool {
 		return false
 	}
 	// 根据游戏局势判断
-	return ai.GameState.Round <= 3 || ai.countAlivePlayers() <= 6
+	witch := currentAITuning().Witch
+	return ai.GameState.Round <= witch.SaveUntilRound || ai.countAlivePlayers() <= witch.SaveBelowAlive
 }
 
 func (ai *AIPlayer) shouldPoisonPlayer() bool {
 	// 策略性判断是否应该使用毒药
-	return ai.GameState.Round > 2 && ai.countSuspiciousPlayers() > 0
+	return ai.GameState.Round > currentAITuning().Witch.PoisonAfterRound && ai.countSuspiciousPlayers() > 0
 }
 
 func (ai *AIPlayer) selectPoisonTarget() string {
-	// 选择使用毒药的目标
-	var potentialTargets []string
-
+	// 只在超过怀疑阈值的玩家中按怀疑概率抽样，避免毒到好人，今晚已经被击杀的玩家不必再毒
+	killedPlayer := ai.getLastKilledPlayer()
+	var candidates []string
 	for _, player := range ai.GameState.Players {
-		if !player.Alive || player.ID == ai.ID {
-			continue
-		}
-
-		if ai.isSuspicious(player.ID) {
-			potentialTargets = append(potentialTargets, player.ID)
+		if player.Alive && player.ID != ai.ID && player.ID != killedPlayer && ai.isSuspicious(player.ID) {
+			candidates = append(candidates, player.ID)
 		}
 	}
-
-	if len(potentialTargets) > 0 {
-		return potentialTargets[rand.Intn(len(potentialTargets))]
-	}
-	return ""
+	return ai.sampleTarget(candidates, ai.suspicion)
 }
 
 func (ai *AIPlayer) countAlivePlayers() int {
@@ -323,217 +487,100 @@ func (ai *AIPlayer) hasPoison() bool {
 	return false
 }
 
-// selectProtectTarget 选择守护目标
+// selectProtectTarget 选择守护目标，不能连续两晚守护同一名玩家：
+// 优先守护可信的预言家和已知的神职，上一晚守护过的则换人；
+// 否则谨慎型守护自己，随机型随便守护，其他性格守护在公开信息下最不可疑、最像神职的玩家
 func (ai *AIPlayer) selectProtectTarget() string {
-	var potentialTargets []string
-
-	for _, player := range ai.GameState.Players {
-		if !player.Alive || player.ID == ai.ID {
-			continue
-		}
-
-		switch ai.Personality {
-		case PersonalityStrategic:
-			// 优先守护重要角色
-			if role, known := ai.KnownPlayers[player.ID]; known &&
-				(role == models.Seer || role == models.Witch) {
-				return player.ID
-			}
-
-		default:
-			potentialTargets = append(potentialTargets, player.ID)
-		}
+	last := ai.memory.protectIn(ai.GameState.Round - 1)
+	allowed := func(playerID string) bool {
+		return playerID != last && ai.isAlive(playerID)
 	}
-
-	if len(potentialTargets) > 0 {
-		return potentialTargets[rand.Intn(len(potentialTargets))]
+	if last != "" {
+		ai.reason("上一晚守护了%s，今晚不能连续守护", last)
 	}
 
-	return ""
-}
-
-// selectVoteTarget 选择投票目标
-func (ai *AIPlayer) selectVoteTarget() string {
-	var potentialTargets []string
-
-	for _, player := range ai.GameState.Players {
-		if !player.Alive || player.ID == ai.ID {
-			continue
-		}
-
-		switch ai.Personality {
-		case PersonalityAggressive:
-			// 优先投票可疑的玩家
-			if ai.isSuspicious(player.ID) {
-				return player.ID
-			}
-
-		case PersonalityCautious:
-			// 跟随大多数人的投票
-			if ai.isPopularVoteTarget(player.ID) {
+	// 起跳的预言家最容易成为狼人的目标
+	if seer := ai.trustedSeer(); seer != "" && allowed(seer) {
+		ai.reason("守护可信的预言家%s", seer)
+		return seer
+	}
+	for _, role := range []models.Role{models.Seer, models.Witch} {
+		for _, player := range ai.GameState.Players {
+			if ai.KnownPlayers[player.ID] == role && player.ID != ai.ID && allowed(player.ID) {
+				ai.reason("守护已知的%s%s", role, player.ID)
 				return player.ID
 			}
-
-		default:
-			potentialTargets = append(potentialTargets, player.ID)
 		}
 	}
 
-	if len(potentialTargets) > 0 {
-		return potentialTargets[rand.Intn(len(potentialTargets))]
-	}
-
-	return ""
-}
-
-// generateDiscussion 生成讨论内容
-func (ai *AIPlayer) generateDiscussion() string {
-	// 根据角色和性格生成对话内容
-	switch ai.Role {
-	case models.Werewolf, models.WhiteWolf:
-		return ai.generateWerewolfDiscussion()
-	case models.Seer:
-		return ai.generateSeerDiscussion()
-	case models.Witch:
-		return ai.generateWitchDiscussion()
-	case models.Guard:
-		return ai.generateGuardDiscussion()
-	default:
-		return ai.generateVillagerDiscussion()
-	}
-}
-
-// generateWerewolfDiscussion 生成狼人对话
-func (ai *AIPlayer) generateWerewolfDiscussion() string {
-	switch ai.Personality {
-	case PersonalityAggressive:
-		return "我觉得有人在伪装预言家，我们应该投他"
-	case PersonalityCautious:
-		return "大家要冷静分析，不要轻易相信任何人的发言"
-	case PersonalityStrategic:
-		return "我们应该先听听预言家的发言，再做判断"
-	default:
-		return "昨晚的情况大家怎么看？"
-	}
-}
-
-// generateSeerDiscussion 生成预言家对话
-func (ai *AIPlayer) generateSeerDiscussion() string {
-	switch ai.Personality {
-	case PersonalityAggressive:
-		return "我是预言家，昨晚我查验了一个人，发现是狼人"
-	case PersonalityCautious:
-		return "作为预言家，我建议大家要谨慎行动"
-	case PersonalityStrategic:
-		return "我有重要信息要分享，但现在说可能为时过早"
-	default:
-		return "让我们一起分析一下目前的局势"
+	if ai.Personality == PersonalityCautious && allowed(ai.ID) {
+		ai.reason("谨慎型守卫守护自己")
+		return ai.ID
 	}
-}
 
-// generateWitchDiscussion 生成女巫对话
-func (ai *AIPlayer) generateWitchDiscussion() string {
-	switch ai.Personality {
-	case PersonalityAggressive:
-		return "我知道一些重要的信息，但需要大家配合"
-	case PersonalityCautious:
-		return "我们要小心行事，不要轻易相信任何人"
-	case PersonalityStrategic:
-		return "让我们先听听大家的想法，再做决定"
-	default:
-		return "昨晚发生了什么有趣的事情吗？"
+	var candidates []string
+	for _, player := range ai.GameState.Players {
+		if player.ID != ai.ID && allowed(player.ID) {
+			candidates = append(candidates, player.ID)
+		}
 	}
-}
-
-// generateGuardDiscussion 生成守卫对话
-func (ai *AIPlayer) generateGuardDiscussion() string {
-	switch ai.Personality {
-	case PersonalityAggressive:
-		return "我们必须保护好重要的角色"
-	case PersonalityCautious:
-		return "大家要注意安全，狼人可能会有突然袭击"
-	case PersonalityStrategic:
-		return "我觉得我们应该制定一个保护策略"
-	default:
-		return "大家有什么需要帮助的吗？"
+	if len(candidates) == 0 {
+		return ""
 	}
-}
-
-// generateVillagerDiscussion 生成村民对话
-func (ai *AIPlayer) generateVillagerDiscussion() string {
-	switch ai.Personality {
-	case PersonalityAggressive:
-		return "我觉得有人行为很可疑，应该仔细观察"
-	case PersonalityCautious:
-		return "我们要相信预言家，但也要防止有人冒充"
-	case PersonalityStrategic:
-		return "让我们分析一下每个人的发言，找出线索"
-	default:
-		return "大家对昨晚的情况有什么看法？"
+	if ai.Personality == PersonalityRandom {
+		return candidates[ai.random().Intn(len(candidates))]
 	}
+	return ai.sampleTarget(candidates, func(playerID string) float64 {
+		return 1 - ai.suspicion(playerID)
+	})
 }
 
-// 辅助函数
-func (ai *AIPlayer) isSuspicious(playerID string) bool {
-	// 统计玩家的可疑行为
-	suspiciousScore := 0
-
-	// 检查玩家的投票历史
-	for _, action := range ai.GameState.Actions {
-		if action.Type == "vote" && action.PlayerID == playerID {
-			// 如果投票给已知的好人，增加可疑度
-			if role, known := ai.KnownPlayers[action.TargetID]; known && role != models.Werewolf && role != models.WhiteWolf {
-				suspiciousScore++
+// selectVoteTarget 选择投票目标：好人阵营直接投出已确认的狼人，否则按怀疑概率抽样，
+// 谨慎型更倾向于跟随已有的票；狼人投给在公开信息下最可疑的好人
+func (ai *AIPlayer) selectVoteTarget() string {
+	if !isWolf(ai.Role) {
+		for _, player := range ai.GameState.Players {
+			if player.Alive && isWolf(ai.KnownPlayers[player.ID]) {
+				ai.reason("已知%s是狼人，直接投出", player.ID)
+				return player.ID
 			}
 		}
 	}
 
-	// 检查发言活跃度
-	if !ai.isActive(playerID) {
-		suspiciousScore++
-	}
-
-	// 根据预言家的验人结果
-	if ai.Role == models.Seer {
-		if role, known := ai.KnownPlayers[playerID]; known && (role == models.Werewolf || role == models.WhiteWolf) {
-			return true
+	// 冒充预言家的狼人跟着自己编造的查杀投票，保持说法一致
+	if isWolf(ai.Role) {
+		if target := ai.claimedWolf(ai.ID); target != "" {
+			ai.reason("冒充预言家时查杀了%s，投票保持一致", target)
+			return target
 		}
 	}
 
-	return suspiciousScore >= 2
-}
-
-func (ai *AIPlayer) isActive(playerID string) bool {
-	// 统计玩家在白天阶段的发言次数
-	speakCount := 0
-	for _, action := range ai.GameState.Actions {
-		if action.Type == "discuss" && action.PlayerID == playerID {
-			speakCount++
+	var candidates []string
+	for _, player := range ai.GameState.Players {
+		if !player.Alive || player.ID == ai.ID {
+			continue
 		}
-	}
-
-	// 根据游戏轮数判断发言活跃度
-	expectedSpeakCount := ai.GameState.Round * 2 // 每轮期望至少发言两次
-	return speakCount >= expectedSpeakCount
-}
-
-func (ai *AIPlayer) isPopularVoteTarget(playerID string) bool {
-	// 统计当前投票阶段该玩家收到的票数
-	votes := 0
-	for _, action := range ai.GameState.Actions {
-		if action.Type == "vote" && action.TargetID == playerID {
-			votes++
+		// 狼人不投自己的同伴
+		if isWolf(ai.Role) && isWolf(ai.KnownPlayers[player.ID]) {
+			continue
 		}
+		candidates = append(candidates, player.ID)
 	}
 
-	// 计算存活玩家数量
-	aliveCount := 0
-	for _, player := range ai.GameState.Players {
-		if player.Alive {
-			aliveCount++
+	return ai.sampleTarget(candidates, func(playerID string) float64 {
+		weight := ai.suspicion(playerID)
+		if ai.Personality == PersonalityCautious {
+			weight *= float64(1 + ai.currentVotes(playerID))
 		}
-	}
+		return weight
+	})
+}
 
-	// 如果收到超过1/3的票数，认为是热门投票目标
-	return votes >= aliveCount/3
+func (ai *AIPlayer) isActive(playerID string) bool {
+	// 已经历的白天数，每个白天期望至少发言一次
+	expectedSpeakCount := ai.GameState.Round - 1
+	if ai.GameState.Phase != PhaseNight {
+		expectedSpeakCount++
+	}
+	return ai.memory.speeches[playerID] >= expectedSpeakCount
 }
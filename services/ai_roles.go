@@ -0,0 +1,101 @@
+package services
+
+import "github.com/qianlnk/werewolf/models"
+
+// selectLoverTarget 丘比特选择情侣：激进型会把自己连进去，其他性格随机选择
+func (ai *AIPlayer) selectLoverTarget() string {
+	linked := make(map[string]bool)
+	for _, action := range ai.GameState.Actions {
+		if action.PlayerID == ai.ID && action.Type == "link" {
+			linked[action.TargetID] = true
+		}
+	}
+
+	if ai.Personality == PersonalityAggressive && !linked[ai.ID] {
+		return ai.ID
+	}
+
+	var candidates []string
+	for _, player := range ai.GameState.Players {
+		if player.Alive && !linked[player.ID] && (player.ID != ai.ID || ai.Personality == PersonalityRandom) {
+			candidates = append(candidates, player.ID)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[ai.random().Intn(len(candidates))]
+}
+
+// selectExplodeTarget 白狼王决定是否自爆：被起跳的预言家查杀、即将被放逐时自爆带走该预言家，
+// 激进型也会主动带走看起来最可信的预言家。狼队只剩自己时自爆会直接输掉对局，返回空
+func (ai *AIPlayer) selectExplodeTarget() string {
+	teammates := 0
+	for _, player := range ai.GameState.Players {
+		if player.Alive && player.ID != ai.ID && isWolf(ai.KnownPlayers[player.ID]) {
+			teammates++
+		}
+	}
+	if teammates == 0 {
+		return ""
+	}
+
+	for _, claim := range ai.GameState.claimsFor(models.Seer) {
+		if !ai.isAlive(claim.PlayerID) || isWolf(ai.KnownPlayers[claim.PlayerID]) {
+			continue
+		}
+		checked := false
+		for _, check := range claim.Checks {
+			if check.TargetID == ai.ID && check.Wolf {
+				checked = true
+				break
+			}
+		}
+		if !checked {
+			continue
+		}
+
+		ai.reason("被%s查杀，考虑自爆带走他", claim.PlayerID)
+		switch ai.Personality {
+		case PersonalityCautious:
+			// 谨慎型只在预言家没有对跳、查杀难以辩驳时自爆
+			if len(ai.GameState.claimsFor(models.Seer)) == 1 {
+				return claim.PlayerID
+			}
+		case PersonalityRandom:
+			if ai.random().Float64() < currentAITuning().Wolf.RandomExplodeChance {
+				return claim.PlayerID
+			}
+		default:
+			return claim.PlayerID
+		}
+	}
+
+	if ai.Personality == PersonalityAggressive && ai.GameState.Round >= 2 {
+		if seer := ai.rankSeerClaims(false); seer != "" && ai.isAlive(seer) && !isWolf(ai.KnownPlayers[seer]) {
+			ai.reason("激进型白狼王主动自爆带走最可信的预言家%s", seer)
+			return seer
+		}
+	}
+	return ""
+}
+
+// lover 获取AI的情侣，不是情侣时返回空
+func (ai *AIPlayer) lover() string {
+	self := false
+	partner := ""
+	for _, player := range ai.GameState.Players {
+		if !player.IsLover {
+			continue
+		}
+		if player.ID == ai.ID {
+			self = true
+		} else {
+			partner = player.ID
+		}
+	}
+	if !self {
+		return ""
+	}
+	return partner
+}
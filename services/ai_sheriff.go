@@ -0,0 +1,208 @@
+package services
+
+import "github.com/qianlnk/werewolf/models"
+
+// decideCampaignAction 决定是否上警：预言家总是上警争取警徽，打算悍跳的狼人借竞选发言起跳，
+// 其他玩家按性格决定，上警时附带竞选发言。已经做出选择的AI不再重复上警
+func (ai *AIPlayer) decideCampaignAction() models.GameAction {
+	if ai.GameState.hasAction(ai.ID, "campaign") || ai.GameState.hasAction(ai.ID, "decline") {
+		return models.GameAction{}
+	}
+	action := models.GameAction{PlayerID: ai.ID, Type: "decline"}
+
+	var fakeClaim *models.RoleClaim
+	run := false
+	switch {
+	case ai.Role == models.Seer:
+		ai.reason("预言家上警争取警徽，用查验结果带队归票")
+		run = true
+	case isWolf(ai.Role):
+		if fakeClaim = ai.fakeSeerClaim(); fakeClaim != nil {
+			ai.reason("上警悍跳预言家，和真预言家争夺警徽")
+			run = true
+		} else {
+			run = ai.random().Float64() < ai.campaignChance()
+		}
+	default:
+		run = ai.random().Float64() < ai.campaignChance()
+	}
+	if !run {
+		return action
+	}
+
+	action.Type = "campaign"
+	action.Content = ai.generateCampaignSpeech(fakeClaim)
+	return action
+}
+
+// campaignChance 预言家以外的玩家上警的概率，猎人不怕被狼人针对，至少按激进型的概率上警
+func (ai *AIPlayer) campaignChance() float64 {
+	chances := currentAITuning().Sheriff.CampaignChance
+	chance := chances[ai.Personality]
+	if ai.Role == models.Hunter {
+		chance = max(chance, chances[PersonalityAggressive])
+	}
+	ai.reason("%s型上警的概率为%.2f", ai.Personality, chance)
+	return chance
+}
+
+// generateCampaignSpeech 竞选发言：预言家公开身份和查验结果，悍跳的狼人公布编造的查验结果，
+// 其他玩家表明自己是好人并点出可疑的玩家
+func (ai *AIPlayer) generateCampaignSpeech(fakeClaim *models.RoleClaim) string {
+	sentences := []string{ai.describeLastNight()}
+
+	claim := fakeClaim
+	if ai.Role == models.Seer {
+		claim = ai.seerClaim()
+	}
+	if claim == nil {
+		sentences = append(sentences, ai.say("ai.campaign_good"))
+		if suspect := ai.mostSuspicious(); suspect != "" {
+			sentences = append(sentences, ai.say("ai.suspect", ai.playerName(suspect)))
+		}
+		return joinSentences(ai.locale(), sentences)
+	}
+
+	ai.claim = claim
+	if len(claim.Checks) > 0 {
+		sentences = append(sentences, ai.say("ai.claim_seer_checks", ai.describeChecks(claim.Checks)))
+	} else {
+		sentences = append(sentences, ai.say("ai.claim_seer"))
+	}
+	sentences = append(sentences, ai.say("ai.campaign_seer"))
+	for i := len(claim.Checks) - 1; i >= 0; i-- {
+		if check := claim.Checks[i]; check.Wolf && ai.isAlive(check.TargetID) {
+			sentences = append(sentences, ai.say("ai.campaign_seer_wolf", ai.playerName(check.TargetID)))
+			break
+		}
+	}
+	return joinSentences(ai.locale(), sentences)
+}
+
+// decideSheriffVote 警下的玩家投票选出警长，上警的候选人不投票
+func (ai *AIPlayer) decideSheriffVote() models.GameAction {
+	candidates := ai.GameState.sheriffCandidates()
+	for _, candidate := range candidates {
+		if candidate == ai.ID {
+			return models.GameAction{}
+		}
+	}
+	if len(candidates) == 0 {
+		return models.GameAction{}
+	}
+	return models.GameAction{
+		PlayerID: ai.ID,
+		Type:     "elect",
+		TargetID: ai.selectSheriffTarget(candidates),
+	}
+}
+
+// selectSheriffTarget 选择支持的候选人：好人投给所信任的预言家，否则在没有被识破的候选人中投给最不可疑的；
+// 狼人投给上警的同伴，没有同伴上警时避开起跳预言家的候选人，投给在公开信息下最不像神职的好人
+func (ai *AIPlayer) selectSheriffTarget(candidates []string) string {
+	if isWolf(ai.Role) {
+		for _, candidate := range candidates {
+			if isWolf(ai.KnownPlayers[candidate]) {
+				ai.reason("支持上警的同伴%s拿警徽", candidate)
+				return candidate
+			}
+		}
+		var others []string
+		for _, candidate := range candidates {
+			if claim := ai.GameState.claimOf(candidate); claim == nil || claim.Role != models.Seer {
+				others = append(others, candidate)
+			}
+		}
+		if len(others) == 0 {
+			others = candidates
+		}
+		return ai.sampleTarget(others, ai.suspicion)
+	}
+
+	if seer := ai.trustedSeer(); seer != "" {
+		for _, candidate := range candidates {
+			if candidate == seer {
+				ai.reason("把警徽投给所信任的预言家%s", seer)
+				return seer
+			}
+		}
+	}
+	var trusted []string
+	for _, candidate := range candidates {
+		if !isWolf(ai.KnownPlayers[candidate]) && !ai.provenFake(candidate) {
+			trusted = append(trusted, candidate)
+		}
+	}
+	if len(trusted) == 0 {
+		trusted = candidates
+	}
+	return ai.sampleTarget(trusted, func(playerID string) float64 {
+		return 1 - ai.suspicion(playerID)
+	})
+}
+
+// decideBadge 出局的警长决定警徽的去向，没有可以移交的玩家时撕毁警徽
+func (ai *AIPlayer) decideBadge() models.GameAction {
+	action := models.GameAction{PlayerID: ai.ID, Type: "tear_badge"}
+	if target := ai.selectBadgeTarget(); target != "" {
+		action.Type = "pass_badge"
+		action.TargetID = target
+	}
+	return action
+}
+
+// selectBadgeTarget 选择接任警长的玩家：好人交给所信任的预言家或已知的好人，否则交给不可疑的玩家，
+// 存活的玩家都可疑时撕毁警徽；狼人交给存活的同伴，没有同伴时撕毁警徽，不让好人多出半票
+func (ai *AIPlayer) selectBadgeTarget() string {
+	if isWolf(ai.Role) {
+		for _, player := range ai.GameState.Players {
+			if player.Alive && player.ID != ai.ID && isWolf(ai.KnownPlayers[player.ID]) {
+				ai.reason("把警徽移交给同伴%s", player.ID)
+				return player.ID
+			}
+		}
+		ai.reason("没有存活的同伴，撕毁警徽")
+		return ""
+	}
+
+	if seer := ai.trustedSeer(); seer != "" && ai.isAlive(seer) {
+		ai.reason("把警徽移交给所信任的预言家%s", seer)
+		return seer
+	}
+	for _, player := range ai.GameState.Players {
+		if role, known := ai.KnownPlayers[player.ID]; known && player.Alive && player.ID != ai.ID && !isWolf(role) {
+			ai.reason("已知%s是好人，移交警徽", player.ID)
+			return player.ID
+		}
+	}
+
+	var candidates []string
+	for _, player := range ai.GameState.Players {
+		if player.Alive && player.ID != ai.ID && !ai.isSuspicious(player.ID) {
+			candidates = append(candidates, player.ID)
+		}
+	}
+	if len(candidates) == 0 {
+		ai.reason("存活的玩家都很可疑，撕毁警徽")
+		return ""
+	}
+	return ai.sampleTarget(candidates, func(playerID string) float64 {
+		return 1 - ai.suspicion(playerID)
+	})
+}
+
+// sheriffCallout 警长在白天发言的最后归票，用1.5票带领大家投票，投票时保持一致；
+// 狼人警长借归票把票引向好人。不是警长时返回空
+func (ai *AIPlayer) sheriffCallout() string {
+	ai.sheriffCall = ""
+	if ai.GameState.Sheriff != ai.ID {
+		return ""
+	}
+	target := ai.selectVoteTarget()
+	if target == "" {
+		return ""
+	}
+	ai.reason("作为警长归票%s", target)
+	ai.sheriffCall = target
+	return ai.say("ai.sheriff_call", ai.playerName(target))
+}
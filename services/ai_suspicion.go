@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// suspicion AI眼中玩家是狼人的概率。好人阵营结合自己掌握的身份信息，
+// 狼人则估计其他玩家在公开信息下看起来有多可疑，用于跟风投票
+func (ai *AIPlayer) suspicion(playerID string) float64 {
+	// 情侣同生共死，不会怀疑对方
+	if lover := ai.lover(); lover != "" && playerID == lover {
+		return 0
+	}
+
+	private := !isWolf(ai.Role)
+	if private {
+		if playerID == ai.ID {
+			return 0
+		}
+		if role, known := ai.KnownPlayers[playerID]; known {
+			if isWolf(role) {
+				return 1
+			}
+			return 0
+		}
+	}
+	return 1 / (1 + math.Exp(-ai.suspicionLogit(playerID, private)))
+}
+
+// isSuspicious 玩家是否可疑
+func (ai *AIPlayer) isSuspicious(playerID string) bool {
+	return ai.suspicion(playerID) >= currentAITuning().SuspicionThreshold
+}
+
+// suspicionLogit 从狼人占比的先验出发，按投票、身份声明、查验结果和击杀规律累加证据
+// private为false时只使用公开信息
+func (ai *AIPlayer) suspicionLogit(playerID string, private bool) float64 {
+	wolves := 0
+	for _, player := range ai.GameState.Players {
+		if isWolf(player.Role) {
+			wolves++
+		}
+	}
+	tuning := currentAITuning()
+	evidence := tuning.Evidence
+	prior := float64(wolves) / float64(len(ai.GameState.Players)-1)
+	logit := math.Log(prior / (1 - prior))
+
+	trusted := ai.rankSeerClaims(private)
+
+	// 投票记录
+	for _, vote := range ai.memory.votes {
+		if vote.VoterID != playerID {
+			continue
+		}
+		if role, known := ai.KnownPlayers[vote.TargetID]; private && known {
+			if isWolf(role) {
+				logit -= evidence.VoteWolf
+			} else {
+				logit += evidence.VoteGood
+			}
+		}
+		if trusted != "" && vote.TargetID == trusted {
+			logit += evidence.VoteSeer
+		}
+	}
+
+	// 投票给自己已经按投给已知好人计入，这里只计发言中的指控
+	if private {
+		logit += evidence.FalseAccuser * float64(ai.memory.spokenAccusations(playerID))
+	}
+
+	// 身份声明和查验结果
+	if private && ai.provenFake(playerID) {
+		logit += evidence.ProvenFake
+	}
+	// 参考玩家以往对局中的习惯：经常假跳预言家的玩家再跳更可疑，一贯真跳的更可信
+	if claim := ai.GameState.claimOf(playerID); claim != nil && claim.Role == models.Seer {
+		if rate, ok := ai.learning().fakeClaimRate(playerID); ok {
+			logit += evidence.HabitualFakeClaim * (2*rate - 1)
+		}
+	}
+	if trusted != "" {
+		if playerID == trusted {
+			logit -= evidence.TrustedSeer
+		} else if claim := ai.GameState.claimOf(playerID); claim != nil && claim.Role == ai.GameState.claimOf(trusted).Role {
+			logit += evidence.RivalClaim
+		}
+		for _, check := range ai.GameState.claimOf(trusted).Checks {
+			if check.TargetID != playerID {
+				continue
+			}
+			if check.Wolf {
+				logit += evidence.CheckedWolf
+			} else {
+				logit -= evidence.CheckedGood
+			}
+		}
+	}
+
+	// 狼人倾向于在夜里击杀投过自己的玩家
+	for _, death := range ai.memory.deaths {
+		if death.Phase != PhaseNight {
+			continue
+		}
+		for _, vote := range ai.memory.votes {
+			if vote.VoterID == death.PlayerID && vote.TargetID == playerID && vote.Round == death.Round-1 {
+				logit += evidence.SilencedVoter
+			}
+		}
+	}
+
+	if !ai.isActive(playerID) {
+		logit += evidence.Quiet
+	}
+
+	return math.Max(-tuning.MaxSuspicionLogit, math.Min(tuning.MaxSuspicionLogit, logit))
+}
+
+// mostSuspicious 获取最可疑的存活玩家，没有玩家超过阈值时返回空
+func (ai *AIPlayer) mostSuspicious() string {
+	best, bestScore := "", currentAITuning().SuspicionThreshold
+	for _, player := range ai.GameState.Players {
+		if !player.Alive || player.ID == ai.ID {
+			continue
+		}
+		if score := ai.suspicion(player.ID); score >= bestScore {
+			best, bestScore = player.ID, score
+		}
+	}
+	return best
+}
+
+// sampleTarget 按权重随机选择目标。权重按性格取幂：
+// 策略型几乎总是选择权重最高的目标，随机型按原始权重抽样
+func (ai *AIPlayer) sampleTarget(candidates []string, weight func(string) float64) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sharpness := currentAITuning().sharpness(ai.Personality)
+
+	weights := make([]float64, len(candidates))
+	described := make([]string, len(candidates))
+	total := 0.0
+	for i, candidate := range candidates {
+		raw := weight(candidate)
+		described[i] = fmt.Sprintf("%s=%.2f", candidate, raw)
+		weights[i] = math.Pow(raw, sharpness)
+		total += weights[i]
+	}
+	ai.reason("按权重抽样（%s型取%g次幂）：%s", ai.Personality, sharpness, strings.Join(described, "，"))
+	if total <= 0 {
+		return candidates[ai.random().Intn(len(candidates))]
+	}
+
+	n := ai.random().Float64() * total
+	for i, candidate := range candidates {
+		if n < weights[i] {
+			return candidate
+		}
+		n -= weights[i]
+	}
+	return candidates[len(candidates)-1]
+}
+
+// currentVotes 统计当前投票阶段玩家已经收到的票数
+func (ai *AIPlayer) currentVotes(playerID string) int {
+	votes := 0
+	for _, action := range ai.GameState.Actions {
+		if action.Type == "vote" && action.TargetID == playerID {
+			votes++
+		}
+	}
+	return votes
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// ErrInvalidAITuning AI调参不合法
+var ErrInvalidAITuning = NewError(CodeInvalidAITuning, "AI调参不合法：概率和阈值必须在0到1之间，权重不能为负数")
+
+// AITuning AI策略中的可调参数，通过 ai.tuning_file 指定的文件在运行时调整，修改文件后立即对之后的决策生效
+type AITuning struct {
+	SuspicionThreshold float64           `mapstructure:"suspicion_threshold" json:"suspicion_threshold"` // 怀疑概率超过该值即认为玩家可疑
+	MaxSuspicionLogit  float64           `mapstructure:"max_suspicion_logit" json:"max_suspicion_logit"` // 怀疑度对数几率的上下限
+	Evidence           AIEvidenceWeights `mapstructure:"evidence" json:"evidence"`
+	// Sharpness 按权重抽样目标时各性格的取幂指数，越大越倾向于选择权重最高的目标
+	Sharpness map[models.AIPersonality]float64 `mapstructure:"sharpness" json:"sharpness"`
+	Witch     AIWitchTuning                    `mapstructure:"witch" json:"witch"`
+	Hunter    AIHunterTuning                   `mapstructure:"hunter" json:"hunter"`
+	Wolf      AIWolfTuning                     `mapstructure:"wolf" json:"wolf"`
+	Sheriff   AISheriffTuning                  `mapstructure:"sheriff" json:"sheriff"`
+}
+
+// AIEvidenceWeights 各类证据对怀疑度的影响，以对数几率累加，方向由证据本身决定
+type AIEvidenceWeights struct {
+	VoteGood      float64 `mapstructure:"vote_good" json:"vote_good"`           // 投票给已知的好人
+	VoteWolf      float64 `mapstructure:"vote_wolf" json:"vote_wolf"`           // 投票给已知的狼人（减少怀疑）
+	VoteSeer      float64 `mapstructure:"vote_seer" json:"vote_seer"`           // 投票给所信任的预言家
+	ProvenFake    float64 `mapstructure:"proven_fake" json:"proven_fake"`       // 冒充预言家被识破
+	CheckedWolf   float64 `mapstructure:"checked_wolf" json:"checked_wolf"`     // 被所信任的预言家查杀
+	CheckedGood   float64 `mapstructure:"checked_good" json:"checked_good"`     // 被所信任的预言家发金水（减少怀疑）
+	TrustedSeer   float64 `mapstructure:"trusted_seer" json:"trusted_seer"`     // 自己是所信任的预言家（减少怀疑）
+	RivalClaim    float64 `mapstructure:"rival_claim" json:"rival_claim"`       // 与所信任的预言家对跳
+	SilencedVoter float64 `mapstructure:"silenced_voter" json:"silenced_voter"` // 投过他的玩家当晚被狼人击杀
+	Quiet         float64 `mapstructure:"quiet" json:"quiet"`                   // 发言次数少于白天数
+	FalseAccuser  float64 `mapstructure:"false_accuser" json:"false_accuser"`   // 在发言中指控自己，好人AI知道自己是被冤枉的
+	// HabitualFakeClaim 跳预言家的玩家以往对局中的假跳习惯，总是假跳时加上该值，从不假跳时减去该值
+	HabitualFakeClaim float64 `mapstructure:"habitual_fake_claim" json:"habitual_fake_claim"`
+}
+
+// AIWitchTuning 女巫用药的参数
+type AIWitchTuning struct {
+	RandomSaveChance float64 `mapstructure:"random_save_chance" json:"random_save_chance"` // 随机型女巫救人的概率
+	SaveUntilRound   int     `mapstructure:"save_until_round" json:"save_until_round"`     // 策略型女巫在该回合及之前会救神职
+	SaveBelowAlive   int     `mapstructure:"save_below_alive" json:"save_below_alive"`     // 策略型女巫在存活人数不超过该值时会救神职
+	PoisonAfterRound int     `mapstructure:"poison_after_round" json:"poison_after_round"` // 策略型女巫在该回合之后才用毒
+}
+
+// AIHunterTuning 猎人开枪的参数
+type AIHunterTuning struct {
+	// ShotThreshold 各性格开枪要求的最低怀疑概率，未设置的性格使用 suspicion_threshold
+	ShotThreshold    map[models.AIPersonality]float64 `mapstructure:"shot_threshold" json:"shot_threshold"`
+	EndgameAlive     int                              `mapstructure:"endgame_alive" json:"endgame_alive"`         // 存活人数不超过该值时视为残局
+	EndgameThreshold float64                          `mapstructure:"endgame_threshold" json:"endgame_threshold"` // 残局开枪要求的最低怀疑概率
+}
+
+// AIWolfTuning 狼人悍跳和白狼王自爆的参数
+type AIWolfTuning struct {
+	// FakeClaimChance 没有好人跳预言家时各性格主动悍跳的概率
+	FakeClaimChance     map[models.AIPersonality]float64 `mapstructure:"fake_claim_chance" json:"fake_claim_chance"`
+	RandomExplodeChance float64                          `mapstructure:"random_explode_chance" json:"random_explode_chance"` // 随机型白狼王被查杀后自爆的概率
+}
+
+// AISheriffTuning 竞选警长的参数
+type AISheriffTuning struct {
+	// CampaignChance 预言家和悍跳的狼人以外，各性格上警的概率，未设置的性格不上警
+	CampaignChance map[models.AIPersonality]float64 `mapstructure:"campaign_chance" json:"campaign_chance"`
+}
+
+// DefaultAITuning 默认的AI调参
+func DefaultAITuning() AITuning {
+	return AITuning{
+		SuspicionThreshold: 0.6,
+		MaxSuspicionLogit:  4.0,
+		Evidence: AIEvidenceWeights{
+			VoteGood:          0.8,
+			VoteWolf:          0.8,
+			VoteSeer:          0.6,
+			ProvenFake:        3.0,
+			CheckedWolf:       1.5,
+			CheckedGood:       1.0,
+			TrustedSeer:       1.0,
+			RivalClaim:        0.8,
+			SilencedVoter:     0.5,
+			Quiet:             0.3,
+			FalseAccuser:      0.5,
+			HabitualFakeClaim: 1.0,
+		},
+		Sharpness: map[models.AIPersonality]float64{
+			PersonalityAggressive: 2,
+			PersonalityCautious:   3,
+			PersonalityStrategic:  4,
+			PersonalityRandom:     1,
+		},
+		Witch: AIWitchTuning{
+			RandomSaveChance: 0.5,
+			SaveUntilRound:   3,
+			SaveBelowAlive:   6,
+			PoisonAfterRound: 2,
+		},
+		Hunter: AIHunterTuning{
+			ShotThreshold: map[models.AIPersonality]float64{
+				PersonalityAggressive: 0.5,
+				PersonalityCautious:   0.75,
+			},
+			EndgameAlive:     4,
+			EndgameThreshold: 0.75,
+		},
+		Wolf: AIWolfTuning{
+			FakeClaimChance: map[models.AIPersonality]float64{
+				PersonalityAggressive: 0.5,
+				PersonalityRandom:     0.3,
+			},
+			RandomExplodeChance: 0.5,
+		},
+		Sheriff: AISheriffTuning{
+			CampaignChance: map[models.AIPersonality]float64{
+				PersonalityAggressive: 0.8,
+				PersonalityCautious:   0.2,
+				PersonalityStrategic:  0.4,
+				PersonalityRandom:     0.5,
+			},
+		},
+	}
+}
+
+// aiTuning 当前生效的AI调参，重新加载时整体替换，进行中的决策不受影响
+var aiTuning atomic.Pointer[AITuning]
+
+func init() {
+	tuning := DefaultAITuning()
+	aiTuning.Store(&tuning)
+}
+
+// SetAITuning 校验并替换当前的AI调参，不合法时保留原有参数
+func SetAITuning(tuning AITuning) error {
+	if err := tuning.validate(); err != nil {
+		return err
+	}
+	aiTuning.Store(&tuning)
+	slog.Info("AI调参已更新", "suspicion_threshold", tuning.SuspicionThreshold)
+	return nil
+}
+
+// CurrentAITuning 获取当前生效的AI调参
+func CurrentAITuning() AITuning {
+	return *aiTuning.Load()
+}
+
+// currentAITuning 获取当前生效的AI调参，调用方不能修改返回值
+func currentAITuning() *AITuning {
+	return aiTuning.Load()
+}
+
+// validate 校验调参：概率和阈值在0到1之间，权重、指数和回合数不能为负数
+func (t AITuning) validate() error {
+	probabilities := []float64{t.SuspicionThreshold, t.Witch.RandomSaveChance, t.Hunter.EndgameThreshold, t.Wolf.RandomExplodeChance}
+	for _, threshold := range t.Hunter.ShotThreshold {
+		probabilities = append(probabilities, threshold)
+	}
+	for _, chance := range t.Wolf.FakeClaimChance {
+		probabilities = append(probabilities, chance)
+	}
+	for _, chance := range t.Sheriff.CampaignChance {
+		probabilities = append(probabilities, chance)
+	}
+	for _, p := range probabilities {
+		if p < 0 || p > 1 {
+			return ErrInvalidAITuning
+		}
+	}
+
+	e := t.Evidence
+	weights := []float64{t.MaxSuspicionLogit, e.VoteGood, e.VoteWolf, e.VoteSeer, e.ProvenFake, e.CheckedWolf,
+		e.CheckedGood, e.TrustedSeer, e.RivalClaim, e.SilencedVoter, e.Quiet, e.FalseAccuser, e.HabitualFakeClaim}
+	for _, sharpness := range t.Sharpness {
+		weights = append(weights, sharpness)
+	}
+	for _, w := range weights {
+		if w < 0 {
+			return ErrInvalidAITuning
+		}
+	}
+
+	if t.Witch.SaveUntilRound < 0 || t.Witch.SaveBelowAlive < 0 || t.Witch.PoisonAfterRound < 0 || t.Hunter.EndgameAlive < 0 {
+		return ErrInvalidAITuning
+	}
+	return nil
+}
+
+// sharpness 获取性格对应的抽样指数，未设置时取2
+func (t *AITuning) sharpness(personality models.AIPersonality) float64 {
+	if sharpness, ok := t.Sharpness[personality]; ok {
+		return sharpness
+	}
+	return 2
+}
+
+// shotThreshold 获取猎人开枪要求的最低怀疑概率
+func (t *AITuning) shotThreshold(personality models.AIPersonality, alive int) float64 {
+	threshold, ok := t.Hunter.ShotThreshold[personality]
+	if !ok {
+		threshold = t.SuspicionThreshold
+	}
+	if alive <= t.Hunter.EndgameAlive && threshold < t.Hunter.EndgameThreshold {
+		threshold = t.Hunter.EndgameThreshold
+	}
+	return threshold
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+var (
+	ErrInsufficientScope = NewError(CodeInsufficientScope, "API密钥没有该操作的权限")
+	ErrInvalidScope      = NewError(CodeInvalidScope, "无效的API密钥权限")
+	ErrAPIKeyNotFound    = NewError(CodeAPIKeyNotFound, "API密钥不存在")
+)
+
+// apiKeyPrefix API密钥的前缀，用于和JWT令牌区分，完整格式为 wk_<密钥ID>_<随机串>
+const apiKeyPrefix = "wk_"
+
+// apiKeyScopes 可以授予的权限
+var apiKeyScopes = map[string]bool{
+	models.ScopeSpectate: true,
+	models.ScopeBot:      true,
+	models.ScopeAdmin:    true,
+}
+
+// IsAPIKey 判断凭证是否为API密钥
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyPrefix)
+}
+
+// CreateAPIKey 为账号创建API密钥，返回只在创建时可见的完整密钥，expiresIn为0表示永不过期
+// admin权限只能授予管理员账号
+func (am *AuthManager) CreateAPIKey(ownerID, name string, scopes []string, expiresIn time.Duration) (string, *models.APIKey, error) {
+	if len(scopes) == 0 {
+		return "", nil, ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !apiKeyScopes[scope] {
+			return "", nil, ErrInvalidScope
+		}
+		if scope == models.ScopeAdmin && !am.IsAdmin(ownerID) {
+			return "", nil, ErrInvalidScope
+		}
+	}
+
+	now := time.Now()
+	id := generateSessionID()[:16]
+	secret := generateSessionID() + generateSessionID()
+	key := &models.APIKey{
+		ID:         id,
+		OwnerID:    ownerID,
+		Name:       name,
+		Scopes:     scopes,
+		SecretHash: hashAPIKeySecret(secret),
+		CreatedAt:  now.Unix(),
+	}
+	if expiresIn > 0 {
+		key.ExpiresAt = now.Add(expiresIn).Unix()
+	}
+	if err := am.store.SaveAPIKey(key); err != nil {
+		return "", nil, err
+	}
+	return apiKeyPrefix + id + "_" + secret, key, nil
+}
+
+// ParseAPIKey 校验API密钥并返回密钥信息
+func (am *AuthManager) ParseAPIKey(credential string) (*models.APIKey, error) {
+	if !IsAPIKey(credential) {
+		return nil, ErrInvalidToken
+	}
+	id, secret, ok := strings.Cut(credential[len(apiKeyPrefix):], "_")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := am.store.GetAPIKey(id)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKeySecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, ErrInvalidToken
+	}
+	if key.ExpiresAt != 0 && key.ExpiresAt < time.Now().Unix() {
+		return nil, ErrInvalidToken
+	}
+	return key, nil
+}
+
+// ListAPIKeys 列出账号的API密钥，不包含密钥本身
+func (am *AuthManager) ListAPIKeys(ownerID string) ([]*models.APIKey, error) {
+	return am.store.ListAPIKeys(ownerID)
+}
+
+// RevokeAPIKey 撤销账号的API密钥，不能撤销其他账号的密钥
+func (am *AuthManager) RevokeAPIKey(ownerID, id string) error {
+	key, err := am.store.GetAPIKey(id)
+	if err == storage.ErrNotFound || (err == nil && key.OwnerID != ownerID) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return am.store.DeleteAPIKey(id)
+}
+
+// hashAPIKeySecret 计算密钥随机串的哈希，存储中不保存密钥明文
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,56 @@
+package services
+
+import "github.com/qianlnk/werewolf/models"
+
+// Audience 广播对象筛选函数，player为该连接在对局中的座位，旁观者为nil
+type Audience func(playerID string, player *models.Player) bool
+
+var (
+	// AudienceAll 房间内所有连接
+	AudienceAll Audience = func(string, *models.Player) bool { return true }
+
+	// AudienceAlive 存活的玩家
+	AudienceAlive Audience = func(_ string, player *models.Player) bool {
+		return player != nil && player.Alive
+	}
+
+	// AudienceDead 已死亡的玩家
+	AudienceDead Audience = func(_ string, player *models.Player) bool {
+		return player != nil && !player.Alive
+	}
+
+	// AudienceWolves 狼人阵营的玩家（包括已死亡的狼人）
+	AudienceWolves Audience = func(_ string, player *models.Player) bool {
+		return player != nil && isWolf(player.Role)
+	}
+
+	// AudienceSpectators 没有座位的旁观者
+	AudienceSpectators Audience = func(_ string, player *models.Player) bool {
+		return player == nil
+	}
+)
+
+// AudiencePlayers 指定的玩家
+func AudiencePlayers(playerIDs ...string) Audience {
+	targets := make(map[string]bool, len(playerIDs))
+	for _, id := range playerIDs {
+		targets[id] = true
+	}
+	return func(playerID string, _ *models.Player) bool {
+		return targets[playerID]
+	}
+}
+
+// And 同时满足两个条件的广播对象，例如存活的狼人
+func (a Audience) And(other Audience) Audience {
+	return func(playerID string, player *models.Player) bool {
+		return a(playerID, player) && other(playerID, player)
+	}
+}
+
+// Or 满足任一条件的广播对象，例如死亡玩家和旁观者
+func (a Audience) Or(other Audience) Audience {
+	return func(playerID string, player *models.Player) bool {
+		return a(playerID, player) || other(playerID, player)
+	}
+}
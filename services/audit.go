@@ -0,0 +1,45 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+// 动作来源
+const (
+	AuditSourceWS   = "ws"
+	AuditSourceHTTP = "http"
+	AuditSourceGRPC = "grpc"
+	AuditSourceAI   = "ai"
+)
+
+// AuditLogger 游戏动作审计日志，用于争议处理和反作弊审查
+type AuditLogger struct {
+	store storage.Store
+}
+
+// NewAuditLogger 创建审计日志实例
+func NewAuditLogger(store storage.Store) *AuditLogger {
+	return &AuditLogger{store: store}
+}
+
+// Record 记录一次提交的动作，err为空表示动作通过校验
+func (al *AuditLogger) Record(entry models.AuditEntry, err error) {
+	entry.Accepted = err == nil
+	if err != nil {
+		entry.Reason = err.Error()
+	}
+	entry.Timestamp = time.Now().UnixMilli()
+
+	if err := al.store.AppendAuditEntry(&entry); err != nil {
+		slog.Error("写入审计日志失败", "error", err)
+	}
+}
+
+// Query 查询审计日志
+func (al *AuditLogger) Query(query models.AuditQuery) ([]models.AuditEntry, error) {
+	return al.store.ListAuditEntries(query)
+}
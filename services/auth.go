@@ -0,0 +1,168 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+var (
+	ErrInvalidToken  = NewError(CodeInvalidToken, "无效或已过期的令牌")
+	ErrInvalidTicket = NewError(CodeInvalidTicket, "无效或已过期的连接凭证")
+)
+
+// WebSocket连接凭证的有效期和受众，凭证只能用于建立连接，不能代替登录令牌
+const (
+	wsTicketTTL      = time.Minute
+	wsTicketAudience = "ws"
+)
+
+// Claims JWT声明，Subject为玩家ID，ID为会话ID
+type Claims struct {
+	Name  string `json:"name"`            // 玩家显示名称
+	Guest bool   `json:"guest,omitempty"` // 是否为游客
+	jwt.RegisteredClaims
+}
+
+// TicketClaims WebSocket连接凭证声明，Subject为玩家ID
+type TicketClaims struct {
+	RoomID   string `json:"room_id"`
+	ReadOnly bool   `json:"read_only,omitempty"` // 只读连接只能接收消息，不能提交动作和聊天
+	jwt.RegisteredClaims
+}
+
+// AuthManager JWT令牌管理器，每个令牌对应会话存储中的一个会话
+type AuthManager struct {
+	store  storage.Store
+	secret []byte
+	ttl    time.Duration
+	admins map[string]bool
+}
+
+// NewAuthManager 创建令牌管理器实例，未配置密钥时随机生成（重启后旧令牌失效）
+func NewAuthManager(store storage.Store, secret string, ttl time.Duration, admins []string) *AuthManager {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+		slog.Warn("未配置JWT密钥，已随机生成，服务重启后令牌将失效")
+	}
+
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	adminSet := make(map[string]bool, len(admins))
+	for _, id := range admins {
+		adminSet[id] = true
+	}
+
+	return &AuthManager{store: store, secret: key, ttl: ttl, admins: adminSet}
+}
+
+// IsAdmin 判断玩家是否为管理员
+func (am *AuthManager) IsAdmin(playerID string) bool {
+	return am.admins[playerID]
+}
+
+// IssueToken 为账号创建会话并签发令牌
+func (am *AuthManager) IssueToken(user *models.User) (string, error) {
+	now := time.Now()
+	session := &models.Session{
+		ID:        generateSessionID(),
+		UserID:    user.ID,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(am.ttl).Unix(),
+	}
+	if err := am.store.CreateSession(session); err != nil {
+		return "", err
+	}
+
+	claims := Claims{
+		Name:  user.DisplayName,
+		Guest: user.Guest,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        session.ID,
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(am.ttl)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(am.secret)
+}
+
+// RevokeTokens 使账号已签发的所有令牌失效
+func (am *AuthManager) RevokeTokens(userID string) error {
+	return am.store.DeleteUserSessions(userID)
+}
+
+// ParseToken 校验令牌并返回声明
+func (am *AuthManager) ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return am.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	// 会话被撤销（如登出或游客升级）后令牌失效
+	session, err := am.store.GetSession(claims.ID)
+	if err != nil || session.UserID != claims.Subject || session.ExpiresAt < time.Now().Unix() {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// IssueWSTicket 签发加入指定房间的WebSocket连接凭证，返回凭证和过期时间
+// readOnly为true时签发只读凭证，供只有spectate权限的API密钥旁观房间
+func (am *AuthManager) IssueWSTicket(playerID, roomID string, readOnly bool) (string, int64, error) {
+	now := time.Now()
+	expiresAt := now.Add(wsTicketTTL)
+	claims := TicketClaims{
+		RoomID:   roomID,
+		ReadOnly: readOnly,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        generateSessionID(),
+			Subject:   playerID,
+			Audience:  jwt.ClaimStrings{wsTicketAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	ticket, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(am.secret)
+	if err != nil {
+		return "", 0, err
+	}
+	return ticket, expiresAt.Unix(), nil
+}
+
+// ParseWSTicket 校验WebSocket连接凭证并返回声明
+func (am *AuthManager) ParseWSTicket(ticket string) (*TicketClaims, error) {
+	claims := &TicketClaims{}
+	token, err := jwt.ParseWithClaims(ticket, claims, func(token *jwt.Token) (interface{}, error) {
+		return am.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(wsTicketAudience))
+	if err != nil || !token.Valid || claims.Subject == "" || claims.RoomID == "" {
+		return nil, ErrInvalidTicket
+	}
+	return claims, nil
+}
+
+// generateSessionID 生成随机会话ID
+func generateSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
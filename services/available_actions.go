@@ -0,0 +1,144 @@
+package services
+
+import (
+	"github.com/qianlnk/werewolf/models"
+)
+
+// availableActions 玩家在当前阶段可以执行的动作及可选目标，规则与AddAction的校验一致，
+// 没有可执行的动作时返回空列表，调用方需持有锁
+func (gs *GameState) availableActions(playerID string) []models.ActionOption {
+	options := make([]models.ActionOption, 0)
+
+	var player *models.Player
+	for i := range gs.Players {
+		if gs.Players[i].ID == playerID {
+			player = &gs.Players[i]
+			break
+		}
+	}
+	if !gs.IsStarted || player == nil {
+		return options
+	}
+
+	// 出局玩家只有刚出局的猎人可以开枪，刚出局的警长可以移交或撕毁警徽
+	if !player.Alive {
+		others := gs.aliveTargets(func(p models.Player) bool { return p.ID != playerID })
+		if gs.PendingShot == playerID {
+			options = append(options, models.ActionOption{Type: "shoot", Targets: others, Optional: true})
+		}
+		if gs.PendingBadge == playerID {
+			options = append(options,
+				models.ActionOption{Type: "pass_badge", Targets: others, Optional: true},
+				models.ActionOption{Type: "tear_badge", Optional: true})
+		}
+		return options
+	}
+
+	anyone := func(models.Player) bool { return true }
+	switch gs.Phase {
+	case PhaseNight:
+		// 夜晚只有当前步骤的角色可以行动
+		if !gs.inNightStep(player.Role) {
+			break
+		}
+		switch player.Role {
+		case models.Werewolf, models.WhiteWolf:
+			options = append(options, models.ActionOption{
+				Type:    "kill",
+				Targets: gs.aliveTargets(func(p models.Player) bool { return !isWolf(p.Role) }),
+			})
+		case models.Seer:
+			options = append(options, models.ActionOption{Type: "check", Targets: gs.aliveTargets(anyone)})
+		case models.Witch:
+			// 女巫可以不用药，解药只能救今晚被狼人击杀的玩家，选择pass结束本步骤
+			if gs.nightActionDone(*player) {
+				break
+			}
+			canSave, canPoison := gs.witchCanUse(playerID)
+			if canSave {
+				options = append(options, models.ActionOption{Type: "save", Targets: []string{wolfConsensusTarget(gs.Actions)}, Optional: true})
+			}
+			if canPoison {
+				options = append(options, models.ActionOption{Type: "poison", Targets: gs.aliveTargets(anyone), Optional: true})
+			}
+			options = append(options, models.ActionOption{Type: "pass", Optional: true})
+		case models.Guard:
+			last := gs.lastProtect(playerID)
+			options = append(options, models.ActionOption{
+				Type:    "protect",
+				Targets: gs.aliveTargets(func(p models.Player) bool { return p.ID != last }),
+			})
+		case models.Cupid:
+			if gs.Round == 1 && gs.linkCount(playerID) < 2 {
+				linked := make(map[string]bool)
+				for _, action := range gs.Actions {
+					if action.PlayerID == playerID && action.Type == "link" {
+						linked[action.TargetID] = true
+					}
+				}
+				options = append(options, models.ActionOption{
+					Type:     "link",
+					Targets:  gs.aliveTargets(func(p models.Player) bool { return !linked[p.ID] }),
+					Optional: true,
+				})
+			}
+		}
+
+	case PhaseCampaign:
+		options = append(options,
+			models.ActionOption{Type: "campaign", Optional: true},
+			models.ActionOption{Type: "decline", Optional: true})
+
+	case PhaseSheriffVote:
+		if !gs.isSheriffCandidate(playerID) {
+			options = append(options, models.ActionOption{Type: "elect", Targets: gs.sheriffCandidates()})
+		}
+
+	case PhaseDay:
+		options = append(options, models.ActionOption{Type: "discuss", Optional: true})
+		if player.Role == models.WhiteWolf {
+			options = append(options, models.ActionOption{
+				Type:     "explode",
+				Targets:  gs.aliveTargets(func(p models.Player) bool { return p.ID != playerID }),
+				Optional: true,
+			})
+		}
+
+	case PhaseVote:
+		options = append(options, models.ActionOption{Type: "vote", Targets: gs.aliveTargets(anyone)})
+	}
+	return options
+}
+
+// aliveTargets 满足条件的存活玩家ID，按座位顺序排列，调用方需持有锁
+func (gs *GameState) aliveTargets(eligible func(models.Player) bool) []string {
+	targets := make([]string, 0, len(gs.Players))
+	for _, player := range gs.Players {
+		if player.Alive && eligible(player) {
+			targets = append(targets, player.ID)
+		}
+	}
+	return targets
+}
+
+// sendAvailableActions 向玩家推送其在当前阶段可以执行的动作，在对局goroutine中执行
+func (gc *GameController) sendAvailableActions(playerID string) {
+	gc.sink.SendToPlayer(playerID, map[string]interface{}{
+		"type":          "available_actions",
+		"phase":         gc.game.Phase,
+		"night_step":    gc.game.NightStep,
+		"round":         gc.game.Round,
+		"actions":       gc.game.availableActions(playerID),
+		"phase_ends_at": gc.game.PhaseEndsAt,
+		"state_version": gc.game.StateVersion,
+	})
+}
+
+// pushAvailableActions 新阶段开始时向每名真人玩家和外部机器人推送各自可以执行的动作，在对局goroutine中执行
+func (gc *GameController) pushAvailableActions() {
+	for _, player := range gc.game.Players {
+		if player.Type != models.AIPlayer {
+			gc.sendAvailableActions(player.ID)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package services
+
+import "github.com/qianlnk/werewolf/models"
+
+// boardRoles 自定义板子可以使用的角色，也是展开角色列表的顺序。盗贼尚未实现，不能使用
+var boardRoles = []models.Role{
+	models.Werewolf, models.WhiteWolf,
+	models.Seer, models.Witch, models.Hunter, models.Guard, models.Cupid,
+	models.Villager,
+}
+
+// maxBoardWolves 自定义板子最多的狼人数量，包括白狼王
+const maxBoardWolves = 4
+
+// validateBoard 校验自定义板子：只能使用已实现的角色，数量不能为负，狼人1到4个，
+// 狼人以外的特殊角色每种最多一个。阵营平衡由boardMinPlayers要求的最少人数保证，为空表示使用模式对应的板子
+func validateBoard(roles map[models.Role]int) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	known := make(map[models.Role]bool, len(boardRoles))
+	for _, role := range boardRoles {
+		known[role] = true
+	}
+
+	wolves := 0
+	for role, count := range roles {
+		if !known[role] || count < 0 {
+			return ErrInvalidBoard
+		}
+		if role != models.Werewolf && role != models.Villager && count > 1 {
+			return ErrInvalidBoard
+		}
+		if isWolf(role) {
+			wolves += count
+		}
+	}
+	if wolves < 1 || wolves > maxBoardWolves {
+		return ErrInvalidBoard
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// defaultBotActionTimeout 外部机器人每个阶段的默认行动时限
+const defaultBotActionTimeout = 30 * time.Second
+
+// SetBotTimeout 设置外部机器人每个阶段的行动时限，0表示不限制（直到阶段结束）
+func (rm *RoomManager) SetBotTimeout(timeout time.Duration) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.botTimeout = timeout
+}
+
+// botActionTimeout 获取外部机器人的行动时限
+func (gc *GameController) botActionTimeout() time.Duration {
+	if gc.game.roomManager == nil {
+		return defaultBotActionTimeout
+	}
+	gc.game.roomManager.mutex.RLock()
+	defer gc.game.roomManager.mutex.RUnlock()
+
+	return gc.game.roomManager.botTimeout
+}
+
+// armBotTimer 新阶段开始时为尚未行动的外部机器人设置行动时限，在对局goroutine中执行
+func (gc *GameController) armBotTimer() {
+	gc.stopBotTimer()
+
+	timeout := gc.botActionTimeout()
+	if timeout <= 0 || len(gc.stalledBots()) == 0 {
+		return
+	}
+
+	phase, round, step := gc.game.Phase, gc.game.Round, gc.game.NightStep
+	gc.botTimer = deadlines.AfterFunc(timeout, func() {
+		gc.postTimer(func() {
+			gc.handleBotTimeout(phase, round, step)
+		})
+	})
+}
+
+// stopBotTimer 停止机器人行动计时，调用方需持有gc.mutex
+func (gc *GameController) stopBotTimer() {
+	if gc.botTimer != nil {
+		gc.botTimer.Stop()
+		gc.botTimer = nil
+	}
+}
+
+// stalledBots 获取当前阶段还有动作未完成的存活外部机器人，调用方需持有gc.mutex
+func (gc *GameController) stalledBots() []models.Player {
+	var bots []models.Player
+	for _, player := range gc.game.Players {
+		if player.Type == models.BotPlayer && player.Alive && len(gc.game.pendingActions(player.ID)) > 0 {
+			bots = append(bots, player)
+		}
+	}
+	return bots
+}
+
+// handleBotTimeout 行动时限到期后由内置AI代替未行动的机器人完成本阶段的动作，
+// 座位仍归机器人所有，下一阶段机器人可以继续自己行动
+func (gc *GameController) handleBotTimeout(phase string, round int, step string) {
+	// 阶段或夜晚步骤已经切换，计时器已过期
+	if !gc.game.IsStarted || gc.game.Phase != phase || gc.game.Round != round || gc.game.NightStep != step {
+		return
+	}
+	gc.botTimer = nil
+
+	bots := gc.stalledBots()
+	for _, bot := range bots {
+		pending := gc.game.pendingActions(bot.ID)
+		gc.logger().Info("外部机器人超时未行动，由内置AI代为行动", "player_id", bot.ID, "phase", phase, "pending", pending)
+		gc.sink.SendToPlayer(bot.ID, localize(map[string]interface{}{
+			"type":    "bot_timeout",
+			"phase":   phase,
+			"round":   round,
+			"actions": pending,
+		}, gc.locale(), "message", "system.bot_timeout"))
+		if err := gc.applyAIAction(bot); err != nil {
+			gc.logger().Warn("内置AI代替机器人行动失败", "player_id", bot.ID, "error", err)
+		}
+		// 白狼王自爆会直接结束白天
+		if !gc.game.IsStarted || gc.game.Phase != phase || gc.game.Round != round || gc.game.NightStep != step {
+			return
+		}
+	}
+	if len(bots) > 0 {
+		gc.checkPhaseProgress()
+	}
+}
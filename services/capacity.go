@@ -0,0 +1,105 @@
+package services
+
+// 超出单节点容量时返回的错误，客户端收到SERVER_BUSY后稍后重试
+var (
+	ErrTooManyConnections = NewError(CodeServerBusy, "服务器连接数已满，请稍后重试")
+	ErrTooManyRooms       = NewError(CodeServerBusy, "服务器房间数已满，请稍后再创建房间")
+	ErrTooManyGames       = NewError(CodeServerBusy, "服务器同时进行的对局数已满，请稍后再开始游戏")
+)
+
+// Capacity 单节点的容量上限和当前使用量，上限为0表示不限制
+type Capacity struct {
+	Connections    int `json:"connections"`
+	MaxConnections int `json:"max_connections"`
+	Rooms          int `json:"rooms"`
+	MaxRooms       int `json:"max_rooms"`
+	Games          int `json:"games"` // 进行中的对局
+	MaxGames       int `json:"max_games"`
+}
+
+// SetMaxConnections 设置WebSocket连接数上限，达到上限后拒绝新连接，同一连接ID的重连不受影响；0表示不限制
+func (wm *WebSocketManager) SetMaxConnections(max int) {
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	wm.maxConnections = max
+}
+
+// CheckConnectionCapacity 在升级WebSocket连接前检查连接数，已达上限时返回ErrTooManyConnections
+func (wm *WebSocketManager) CheckConnectionCapacity() error {
+	wm.mutex.RLock()
+	max := wm.maxConnections
+	wm.mutex.RUnlock()
+
+	if max > 0 && wm.ConnectionCount() >= max {
+		return ErrTooManyConnections
+	}
+	return nil
+}
+
+// ConnectionCount 当前的WebSocket连接数
+func (wm *WebSocketManager) ConnectionCount() int {
+	return int(wm.connectionCount.Load())
+}
+
+// SetCapacity 设置房间数和同时进行的对局数上限，达到上限后创建房间或开始游戏返回SERVER_BUSY；0表示不限制
+func (rm *RoomManager) SetCapacity(maxRooms, maxGames int) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.maxRooms = maxRooms
+	rm.maxGames = maxGames
+}
+
+// checkRoomCapacityLocked 检查是否还能创建房间，调用方需持有rm.mutex
+func (rm *RoomManager) checkRoomCapacityLocked() error {
+	if rm.maxRooms > 0 && len(rm.rooms) >= rm.maxRooms {
+		return ErrTooManyRooms
+	}
+	return nil
+}
+
+// checkGameCapacity 检查房间是否还能开始对局，房间自己进行中的对局不计入
+func (rm *RoomManager) checkGameCapacity(roomID string) error {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	if rm.maxGames <= 0 {
+		return nil
+	}
+	if rm.playingGamesLocked(roomID) >= rm.maxGames {
+		return ErrTooManyGames
+	}
+	return nil
+}
+
+// playingGamesLocked 统计进行中的对局数，exceptRoomID不计入，调用方需持有rm.mutex
+func (rm *RoomManager) playingGamesLocked(exceptRoomID string) int {
+	count := 0
+	for id, room := range rm.rooms {
+		if id != exceptRoomID && room.GameStarted {
+			count++
+		}
+	}
+	return count
+}
+
+// Capacity 获取当前的容量上限和使用量
+func (rm *RoomManager) Capacity() Capacity {
+	rm.mutex.RLock()
+	capacity := Capacity{
+		Rooms:    len(rm.rooms),
+		MaxRooms: rm.maxRooms,
+		Games:    rm.playingGamesLocked(""),
+		MaxGames: rm.maxGames,
+	}
+	rm.mutex.RUnlock()
+
+	if rm.webSocketMgr != nil {
+		rm.webSocketMgr.mutex.RLock()
+		capacity.MaxConnections = rm.webSocketMgr.maxConnections
+		rm.webSocketMgr.mutex.RUnlock()
+		capacity.Connections = rm.webSocketMgr.ConnectionCount()
+	}
+	return capacity
+}
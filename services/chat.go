@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// 聊天频道，客户端在chat消息的channel字段中指定，未指定时为房间频道
+const (
+	ChannelRoom      = "room"      // 房间公共频道，对局中只有存活玩家在白天可以发言
+	ChannelWolf      = "wolf"      // 狼人频道，只有狼人能看到
+	ChannelDead      = "dead"      // 死亡玩家频道，死亡玩家和旁观者能看到
+	ChannelSpectator = "spectator" // 旁观者频道，只有旁观者能看到
+	ChannelWhisper   = "whisper"   // 两名玩家之间的私聊，需要房间开启私聊
+)
+
+// chatChannels 支持的聊天频道
+var chatChannels = map[string]bool{
+	ChannelRoom:      true,
+	ChannelWolf:      true,
+	ChannelDead:      true,
+	ChannelSpectator: true,
+	ChannelWhisper:   true,
+}
+
+var (
+	ErrChatNotAllowed  = NewError(CodeChatNotAllowed, "当前无法在该频道发言")
+	ErrWhisperDisabled = NewError(CodeWhisperDisabled, "房间未开启私聊")
+	ErrWhisperTarget   = NewError(CodeWhisperTarget, "无法私聊该玩家")
+)
+
+// chatRoomState 判断发言权限需要的房间状态
+type chatRoomState struct {
+	running       bool                      // 对局是否进行中
+	phase         string                    // 当前阶段
+	allowWhispers bool                      // 房间是否开启私聊
+	seats         map[string]*models.Player // 玩家座位，旁观者没有座位
+	members       map[string]bool           // 房间内的所有连接，包括旁观者
+}
+
+// chatAudience 检查玩家能否在频道中发言，返回该消息的接收对象。
+// 对局中出局玩家在公共频道的发言改为发到死亡玩家频道，req.Channel随之修改
+func chatAudience(state *chatRoomState, playerID string, req *ChatRequest) (Audience, error) {
+	seat := state.seats[playerID]
+	alive := seat != nil && seat.Alive
+
+	switch req.Channel {
+	case ChannelRoom:
+		if state.running && seat != nil && !alive {
+			req.Channel = ChannelDead
+			return AudienceDead.Or(AudienceSpectators), nil
+		}
+		// 对局中旁观者不能在公共频道发言，夜晚所有人闭眼
+		if state.running && (!alive || state.phase == PhaseNight) {
+			return nil, ErrChatNotAllowed
+		}
+		return AudienceAll, nil
+
+	case ChannelWolf:
+		if !state.running || !alive || !isWolf(seat.Role) {
+			return nil, ErrChatNotAllowed
+		}
+		return AudienceWolves, nil
+
+	case ChannelDead:
+		if !state.running || seat == nil || seat.Alive {
+			return nil, ErrChatNotAllowed
+		}
+		return AudienceDead.Or(AudienceSpectators), nil
+
+	case ChannelSpectator:
+		if seat != nil {
+			return nil, ErrChatNotAllowed
+		}
+		return AudienceSpectators, nil
+
+	case ChannelWhisper:
+		if !state.allowWhispers {
+			return nil, ErrWhisperDisabled
+		}
+		if req.To == playerID || !state.members[req.To] {
+			return nil, ErrWhisperTarget
+		}
+		// 对局中存活玩家和出局者之间不能私聊，避免泄露场外信息
+		target := state.seats[req.To]
+		if state.running && alive != (target != nil && target.Alive) {
+			return nil, ErrWhisperTarget
+		}
+		return AudiencePlayers(playerID, req.To), nil
+	}
+	return nil, ErrChatNotAllowed
+}
+
+// chatRoomState 获取房间当前的聊天权限状态
+func (wm *WebSocketManager) chatRoomState(roomID string) *chatRoomState {
+	state := &chatRoomState{
+		seats:   wm.roomSeats(roomID),
+		members: make(map[string]bool),
+	}
+	if room, err := wm.roomManager.GetRoom(roomID); err == nil {
+		state.allowWhispers = room.AllowWhispers
+	}
+	if game, exists := wm.roomManager.GetGameController(roomID); exists {
+		state.running = game.IsRunning()
+		state.phase, _ = game.CurrentPhase()
+	}
+
+	for _, playerID := range wm.roomShard(roomID).memberList(roomID) {
+		state.members[playerID] = true
+	}
+	for playerID := range state.seats {
+		state.members[playerID] = true
+	}
+	return state
+}
+
+// handleChat 处理WebSocket连接发来的聊天消息
+func (wm *WebSocketManager) handleChat(c *client, roomID string, req *ChatRequest) {
+	if err := wm.postChat(roomID, c.playerID, req); err != nil {
+		wm.sendError(c, err)
+	}
+}
+
+// PostChat 以玩家身份在房间中发言，供WebSocket之外的前端使用，例如聊天平台的机器人
+func (wm *WebSocketManager) PostChat(roomID, playerID string, req ChatRequest) error {
+	if err := req.validate(roomID); err != nil {
+		return err
+	}
+	return wm.postChat(roomID, playerID, &req)
+}
+
+// postChat 按频道规则处理已校验的聊天消息，只发给频道成员
+func (wm *WebSocketManager) postChat(roomID, playerID string, req *ChatRequest) error {
+	if !wm.isPlayerInRoom(roomID, playerID) {
+		return ErrNotInRoom
+	}
+
+	audience, err := chatAudience(wm.chatRoomState(roomID), playerID, req)
+	if err != nil {
+		return err
+	}
+
+	// 过滤违规内容，多次违规的玩家被禁言
+	filtered, err := wm.roomManager.moderateText(context.Background(), roomID, playerID, AuditSourceWS, req.Channel, req.Message)
+	if err != nil {
+		return err
+	}
+	req.Message = filtered
+
+	// 对局进行中的聊天写入事件日志，便于导出和复盘
+	if game, exists := wm.roomManager.GetGameController(roomID); exists {
+		game.RecordChat(playerID, req.Channel, req.To, req.Message)
+	}
+
+	// 写入房间聊天记录，供之后加入或重连的玩家补发
+	message := wm.recordChat(roomID, audience, ChatBroadcast{
+		Type:     "chat",
+		Channel:  req.Channel,
+		PlayerID: playerID,
+		To:       req.To,
+		Message:  req.Message,
+	})
+	wm.BroadcastToAudience(roomID, audience, message)
+	return nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+const (
+	chatHistorySize     = 200 // 每个房间在内存中保留的最近聊天消息数
+	chatBackfillSize    = 50  // 加入或重连时补发的聊天消息数
+	MaxChatHistoryLimit = 100 // 单次查询聊天记录的最大条数
+)
+
+// chatEntry 房间聊天记录中的一条消息，audience为能看到该消息的对象
+type chatEntry struct {
+	audience Audience
+	message  ChatBroadcast
+}
+
+// chatHistory 房间最近的聊天消息，消息ID从1开始递增
+type chatHistory struct {
+	nextID  int64
+	entries []chatEntry
+}
+
+// add 为消息分配ID和时间并记录，超出容量时丢弃最早的消息
+func (h *chatHistory) add(audience Audience, message ChatBroadcast) ChatBroadcast {
+	h.nextID++
+	message.ID = h.nextID
+	message.Timestamp = time.Now().UnixMilli()
+
+	h.entries = append(h.entries, chatEntry{audience: audience, message: message})
+	if len(h.entries) > chatHistorySize {
+		h.entries = append(h.entries[:0:0], h.entries[len(h.entries)-chatHistorySize:]...)
+	}
+	return message
+}
+
+// ChatHistoryPage 聊天记录查询结果，按时间从早到晚排列，HasMore表示更早的消息仍可继续查询
+type ChatHistoryPage struct {
+	Messages []ChatBroadcast `json:"messages"`
+	HasMore  bool            `json:"has_more"`
+}
+
+// ChatHistoryMessage 加入房间或重连时补发的最近聊天消息
+type ChatHistoryMessage struct {
+	Type   string `json:"type"` // 固定为chat_history
+	RoomID string `json:"room_id"`
+	ChatHistoryPage
+}
+
+// recordChat 将聊天消息写入房间的聊天记录，返回带ID和时间的消息
+func (wm *WebSocketManager) recordChat(roomID string, audience Audience, message ChatBroadcast) ChatBroadcast {
+	shard := wm.roomShard(roomID)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	history, exists := shard.chatHistories[roomID]
+	if !exists {
+		history = &chatHistory{}
+		shard.chatHistories[roomID] = history
+	}
+	return history.add(audience, message)
+}
+
+// ChatHistory 获取玩家能看到的、ID小于before的最近limit条聊天消息，before不大于0时从最新的消息开始
+// 内存中只保留最近的消息，已结束对局的完整聊天记录可以从对局记录中查询
+func (wm *WebSocketManager) ChatHistory(roomID, playerID string, before int64, limit int) ChatHistoryPage {
+	if limit <= 0 || limit > MaxChatHistoryLimit {
+		limit = MaxChatHistoryLimit
+	}
+	seat := wm.roomSeats(roomID)[playerID]
+
+	shard := wm.roomShard(roomID)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	page := ChatHistoryPage{Messages: make([]ChatBroadcast, 0)}
+	history, exists := shard.chatHistories[roomID]
+	if !exists {
+		return page
+	}
+
+	// 从最新的消息向前查找
+	for i := len(history.entries) - 1; i >= 0; i-- {
+		entry := history.entries[i]
+		if before > 0 && entry.message.ID >= before {
+			continue
+		}
+		if entry.audience != nil && !entry.audience(playerID, seat) {
+			continue
+		}
+		if len(page.Messages) == limit {
+			page.HasMore = true
+			break
+		}
+		page.Messages = append(page.Messages, entry.message)
+	}
+
+	// 调整为从早到晚
+	for i, j := 0, len(page.Messages)-1; i < j; i, j = i+1, j-1 {
+		page.Messages[i], page.Messages[j] = page.Messages[j], page.Messages[i]
+	}
+	return page
+}
+
+// sendChatBackfill 向加入或重连的玩家补发房间最近的聊天消息
+func (wm *WebSocketManager) sendChatBackfill(roomID, playerID string) {
+	page := wm.ChatHistory(roomID, playerID, 0, chatBackfillSize)
+	if len(page.Messages) == 0 {
+		return
+	}
+
+	backfill := ChatHistoryMessage{Type: "chat_history", RoomID: roomID, ChatHistoryPage: page}
+	for _, c := range wm.playerClients(playerID) {
+		if err := wm.sendDirect(c, backfill); err != nil {
+			slog.Warn("补发聊天记录失败", "room_id", roomID, "player_id", playerID, "error", err)
+		}
+	}
+}
+
+// GameChatPage 已结束对局的聊天记录查询结果，按时间从早到晚排列
+type GameChatPage struct {
+	Messages []ExportedChat `json:"messages"`
+	HasMore  bool           `json:"has_more"`
+}
+
+// GameChatHistory 获取对局记录中事件序号小于before的最近limit条聊天消息，before不大于0时从最后一条开始
+func GameChatHistory(record *models.GameRecord, before, limit int) GameChatPage {
+	if limit <= 0 || limit > MaxChatHistoryLimit {
+		limit = MaxChatHistoryLimit
+	}
+
+	chats := BuildGameExport(record).Chat
+	end := len(chats)
+	if before > 0 {
+		end = sort.Search(len(chats), func(i int) bool { return chats[i].Seq >= before })
+	}
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+	return GameChatPage{Messages: chats[start:end], HasMore: start > 0}
+}
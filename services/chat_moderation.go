@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// 房间的聊天过滤模式，创建房间时通过chat_filter指定，为空时使用服务器的默认模式
+const (
+	ChatFilterOff   = "off"   // 不过滤
+	ChatFilterMask  = "mask"  // 敏感词替换为星号后照常发送
+	ChatFilterBlock = "block" // 拒绝发送违规内容
+)
+
+// 审计日志中的内容审核事件
+const (
+	AuditActionChatFiltered = "chat_filtered" // 发言命中过滤器，被屏蔽或拒绝
+	AuditActionChatMuted    = "chat_muted"    // 多次违规后被自动禁言
+)
+
+// maxStrikeRecords 违规记录超过该数量时清理过期的记录
+const maxStrikeRecords = 1024
+
+// aiModerationTimeout AI发言在对局goroutine中审核，外部接口响应慢时不等待，直接放行
+const aiModerationTimeout = 500 * time.Millisecond
+
+var (
+	ErrInvalidChatFilter = NewError(CodeInvalidChatFilter, "无效的聊天过滤模式")
+	ErrChatBlocked       = NewError(CodeChatBlocked, "发言包含违规内容，未能发送")
+	ErrMuteNotFound      = NewError(CodeMuteNotFound, "该玩家未被禁言")
+)
+
+// MutedError 玩家因多次违规发言被禁言
+type MutedError struct {
+	Until time.Time
+}
+
+func (e *MutedError) Error() string {
+	return fmt.Sprintf("多次发言违规，已被禁言至 %s", e.Until.Format(time.DateTime))
+}
+
+// MutePolicy 自动禁言规则：Window内违规Threshold次后禁言Duration，Threshold为0表示不自动禁言
+type MutePolicy struct {
+	Threshold int
+	Window    time.Duration
+	Duration  time.Duration
+}
+
+// Mute 禁言记录
+type Mute struct {
+	PlayerID string `json:"player_id"`
+	Until    int64  `json:"until"`
+}
+
+// ChatModerator 聊天内容审核：依次调用各过滤器检查玩家聊天和AI发言，记录违规次数并自动禁言。
+// 过滤器出错时放行，外部审核接口不可用不影响游戏
+type ChatModerator struct {
+	filters     []ContentFilter
+	defaultMode string
+	policy      MutePolicy
+	strikes     map[string][]time.Time // 玩家在违规窗口内的违规时间
+	mutes       map[string]time.Time   // 玩家的禁言截止时间
+	mutex       sync.Mutex
+}
+
+// NewChatModerator 创建聊天审核实例，defaultMode为房间未指定过滤模式时使用的模式，为空时为mask
+func NewChatModerator(defaultMode string, policy MutePolicy, filters ...ContentFilter) (*ChatModerator, error) {
+	if defaultMode == "" {
+		defaultMode = ChatFilterMask
+	}
+	if !validChatFilter(defaultMode) {
+		return nil, ErrInvalidChatFilter
+	}
+	return &ChatModerator{
+		filters:     filters,
+		defaultMode: defaultMode,
+		policy:      policy,
+		strikes:     make(map[string][]time.Time),
+		mutes:       make(map[string]time.Time),
+	}, nil
+}
+
+// validChatFilter 检查房间的聊天过滤模式，空字符串表示使用默认模式
+func validChatFilter(mode string) bool {
+	switch mode {
+	case "", ChatFilterOff, ChatFilterMask, ChatFilterBlock:
+		return true
+	}
+	return false
+}
+
+// mode 房间实际使用的过滤模式
+func (cm *ChatModerator) mode(roomMode string) string {
+	if roomMode == "" {
+		return cm.defaultMode
+	}
+	return roomMode
+}
+
+// check 依次调用各过滤器，合并命中的敏感词和违规类别
+func (cm *ChatModerator) check(ctx context.Context, text string) *FilterResult {
+	merged := &FilterResult{}
+	for _, filter := range cm.filters {
+		result, err := filter.Check(ctx, text)
+		if err != nil {
+			slog.Warn("内容过滤失败，放行该消息", "filter", filter.Name(), "error", err)
+			continue
+		}
+		if result.Flagged {
+			merged.Flagged = true
+			merged.Terms = append(merged.Terms, result.Terms...)
+			merged.Categories = append(merged.Categories, result.Categories...)
+		}
+	}
+	return merged
+}
+
+// mutedUntil 获取玩家的禁言截止时间，未被禁言时返回false
+func (cm *ChatModerator) mutedUntil(playerID string) (time.Time, bool) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	until, exists := cm.mutes[playerID]
+	if !exists {
+		return time.Time{}, false
+	}
+	if !time.Now().Before(until) {
+		delete(cm.mutes, playerID)
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// strike 记录一次违规，达到次数时禁言并清空违规记录，返回禁言截止时间
+func (cm *ChatModerator) strike(playerID string) (time.Time, bool) {
+	if cm.policy.Threshold <= 0 {
+		return time.Time{}, false
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	now := time.Now()
+	recent := cm.strikes[playerID][:0]
+	for _, at := range cm.strikes[playerID] {
+		if now.Sub(at) < cm.policy.Window {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+
+	if len(recent) < cm.policy.Threshold {
+		cm.strikes[playerID] = recent
+		cm.pruneStrikesLocked(now)
+		return time.Time{}, false
+	}
+	delete(cm.strikes, playerID)
+	until := now.Add(cm.policy.Duration)
+	cm.mutes[playerID] = until
+	return until, true
+}
+
+// pruneStrikesLocked 违规记录较多时清理已经移出窗口的记录，调用方需持有锁
+func (cm *ChatModerator) pruneStrikesLocked(now time.Time) {
+	if len(cm.strikes) < maxStrikeRecords {
+		return
+	}
+	for playerID, strikes := range cm.strikes {
+		if now.Sub(strikes[len(strikes)-1]) >= cm.policy.Window {
+			delete(cm.strikes, playerID)
+		}
+	}
+}
+
+// Mutes 列出禁言中的玩家，按截止时间排序
+func (cm *ChatModerator) Mutes() []Mute {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	now := time.Now()
+	mutes := make([]Mute, 0, len(cm.mutes))
+	for playerID, until := range cm.mutes {
+		if !now.Before(until) {
+			delete(cm.mutes, playerID)
+			continue
+		}
+		mutes = append(mutes, Mute{PlayerID: playerID, Until: until.Unix()})
+	}
+	sort.Slice(mutes, func(i, j int) bool { return mutes[i].Until < mutes[j].Until })
+	return mutes
+}
+
+// Unmute 解除玩家的禁言并清空违规记录，玩家未被禁言时返回false
+func (cm *ChatModerator) Unmute(playerID string) bool {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
+	_, muted := cm.mutes[playerID]
+	delete(cm.mutes, playerID)
+	delete(cm.strikes, playerID)
+	return muted
+}
+
+// SetChatModerator 设置聊天内容审核，nil表示不审核
+func (rm *RoomManager) SetChatModerator(cm *ChatModerator) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.chatModerator = cm
+}
+
+// ChatModerator 获取聊天内容审核实例，未设置时为nil
+func (rm *RoomManager) ChatModerator() *ChatModerator {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	return rm.chatModerator
+}
+
+// moderateText 按房间的过滤模式审核玩家聊天或白天发言，返回可以发送的文本。
+// 违规事件写入审计日志，玩家多次违规后自动禁言；AI发言只屏蔽或替换，不会被拒绝或禁言
+func (rm *RoomManager) moderateText(ctx context.Context, roomID, playerID, source, channel, text string) (string, error) {
+	cm := rm.ChatModerator()
+	if cm == nil {
+		return text, nil
+	}
+	isAI := source == AuditSourceAI
+	if !isAI {
+		if until, muted := cm.mutedUntil(playerID); muted {
+			return "", &MutedError{Until: until}
+		}
+	}
+
+	room, err := rm.GetRoom(roomID)
+	if err != nil {
+		return text, nil
+	}
+	mode := cm.mode(room.ChatFilter)
+	if mode == ChatFilterOff {
+		return text, nil
+	}
+
+	result := cm.check(ctx, text)
+	if !result.Flagged {
+		return text, nil
+	}
+
+	entry := models.AuditEntry{
+		RoomID:   roomID,
+		PlayerID: playerID,
+		Source:   source,
+		Action:   AuditActionChatFiltered,
+	}
+	detail := describeFilterResult(channel, result)
+
+	// 外部审核只给出违规类别时无法逐词屏蔽，按拒绝处理
+	blocked := mode == ChatFilterBlock || len(result.Terms) == 0
+	switch {
+	case isAI && len(result.Terms) == 0:
+		// 白天发言是必要动作，AI不能不发言，改用一句过场发言
+		text = Translate(Locale(room.Locale), "ai.moderation_fallback")
+		entry.Reason = "已替换：" + detail
+		rm.Audit().Record(entry, nil)
+	case blocked && !isAI:
+		rm.Audit().Record(entry, fmt.Errorf("%w：%s", ErrChatBlocked, detail))
+	default:
+		text = maskTerms(text, result.Terms)
+		entry.Reason = "已屏蔽：" + detail
+		rm.Audit().Record(entry, nil)
+	}
+
+	if isAI {
+		return text, nil
+	}
+	if until, muted := cm.strike(playerID); muted {
+		rm.Audit().Record(models.AuditEntry{
+			RoomID:   roomID,
+			PlayerID: playerID,
+			Source:   source,
+			Action:   AuditActionChatMuted,
+			Reason:   fmt.Sprintf("%s内违规%d次，禁言至 %s", cm.policy.Window, cm.policy.Threshold, until.Format(time.DateTime)),
+		}, nil)
+		if rm.webSocketMgr != nil {
+			rm.webSocketMgr.SendToPlayer(playerID, map[string]interface{}{
+				"type":    "chat_muted",
+				"room_id": roomID,
+				"until":   until.Unix(),
+			})
+		}
+	}
+	if blocked {
+		return "", ErrChatBlocked
+	}
+	return text, nil
+}
+
+// describeFilterResult 审计日志中记录的命中内容
+func describeFilterResult(channel string, result *FilterResult) string {
+	parts := []string{"频道" + channel}
+	if len(result.Terms) > 0 {
+		parts = append(parts, "敏感词 "+strings.Join(result.Terms, "、"))
+	}
+	if len(result.Categories) > 0 {
+		parts = append(parts, "违规类别 "+strings.Join(result.Categories, "、"))
+	}
+	return strings.Join(parts, "，")
+}
+
+// moderateAIDialogue 审核AI的白天发言，在对局goroutine中执行
+func (gc *GameController) moderateAIDialogue(playerID, text string) string {
+	if gc.game.roomManager == nil {
+		return text
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), aiModerationTimeout)
+	defer cancel()
+
+	text, _ = gc.game.roomManager.moderateText(ctx, gc.game.Room.ID, playerID, AuditSourceAI, ChannelRoom, text)
+	return text
+}
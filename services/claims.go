@@ -0,0 +1,94 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// claimableRoles 发言中可以声明的身份及其中文和英文名称
+var claimableRoles = []struct {
+	role   models.Role
+	name   string
+	nameEN string
+}{
+	{models.Seer, "预言家", "seer"},
+	{models.Witch, "女巫", "witch"},
+	{models.Guard, "守卫", "guard"},
+	{models.Hunter, "猎人", "hunter"},
+}
+
+// parseRoleClaim 从发言中识别"我是预言家"、"I am the Seer"之类的身份声明，没有声明时返回空
+func parseRoleClaim(content string) models.Role {
+	lower := strings.ToLower(content)
+	for _, claimable := range claimableRoles {
+		if strings.Contains(content, "我是"+claimable.name) ||
+			strings.Contains(lower, "i am the "+claimable.nameEN) || strings.Contains(lower, "i'm the "+claimable.nameEN) {
+			return claimable.role
+		}
+	}
+	return ""
+}
+
+// recordSpokenClaim 记录白天发言或房间聊天中的身份声明，调用方需持有锁
+func (gs *GameState) recordSpokenClaim(playerID, content string) {
+	if role := parseRoleClaim(content); role != "" {
+		gs.recordClaim(models.RoleClaim{PlayerID: playerID, Role: role})
+	}
+}
+
+// recordClaim 记录身份声明：同一玩家改口时以最后声明的身份为准，
+// 同一身份的查验结果按夜晚合并，每晚只保留一次
+func (gs *GameState) recordClaim(claim models.RoleClaim) {
+	for i := range gs.Claims {
+		existing := &gs.Claims[i]
+		if existing.PlayerID != claim.PlayerID {
+			continue
+		}
+		if existing.Role != claim.Role {
+			existing.Role = claim.Role
+			existing.Round = gs.Round
+			existing.Checks = nil
+		}
+		for _, check := range claim.Checks {
+			if !hasClaimedCheck(existing.Checks, check.Round) {
+				existing.Checks = append(existing.Checks, check)
+			}
+		}
+		return
+	}
+
+	claim.Round = gs.Round
+	gs.Claims = append(gs.Claims, claim)
+}
+
+// hasClaimedCheck 是否已经公布过某一晚的查验结果
+func hasClaimedCheck(checks []models.ClaimedCheck, round int) bool {
+	for _, check := range checks {
+		if check.Round == round {
+			return true
+		}
+	}
+	return false
+}
+
+// claimOf 获取玩家当前的身份声明
+func (gs *GameState) claimOf(playerID string) *models.RoleClaim {
+	for i := range gs.Claims {
+		if gs.Claims[i].PlayerID == playerID {
+			return &gs.Claims[i]
+		}
+	}
+	return nil
+}
+
+// claimsFor 获取声明某个身份的所有玩家，按首次声明的顺序
+func (gs *GameState) claimsFor(role models.Role) []models.RoleClaim {
+	var claims []models.RoleClaim
+	for _, claim := range gs.Claims {
+		if claim.Role == role {
+			claims = append(claims, claim)
+		}
+	}
+	return claims
+}
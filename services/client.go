@@ -0,0 +1,202 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/qianlnk/werewolf/reporting"
+)
+
+const (
+	clientSendBuffer = 256              // 每个连接默认的发送缓冲区大小
+	writeWait        = 10 * time.Second // 单次写入超时
+	pingPeriod       = 15 * time.Second // 心跳间隔
+	pongWait         = 40 * time.Second // 等待客户端响应的最长时间，需大于心跳间隔
+
+	maxConnectionsPerPlayer = 5 // 每个玩家同时保持的连接数上限
+)
+
+var (
+	ErrClientClosed     = errors.New("连接已关闭")
+	ErrSendBufferFull   = errors.New("发送缓冲区已满")
+	ErrPlayerNotConnect = errors.New("玩家未连接")
+)
+
+// client 单个WebSocket连接，所有写操作都由writePump协程完成
+// gorilla/websocket不允许多个协程同时写同一个连接
+type client struct {
+	playerID     string
+	connectionID string
+	protocol     int    // 协商的协议版本
+	encoding     string // 消息编码，json或protobuf
+	readOnly     bool   // 只读连接，不能提交动作和聊天
+	profile      ClientProfile
+	conn         *websocket.Conn
+	connectedAt  time.Time
+	queue        []outboundMessage // 等待写出的消息
+	queueSize    int               // 发送缓冲区大小
+	queueMutex   sync.Mutex
+	policy       OverflowPolicy // 发送缓冲区已满时的处理策略
+	dropped      int            // 因缓冲区已满丢弃的消息数
+	wake         chan struct{}  // 有新消息时通知写协程
+	done         chan struct{}
+	closed       chan struct{} // 写协程退出、底层连接关闭后关闭
+	closeOnce    sync.Once
+	closeCode    int
+	closeReason  string
+	chunkID      int64        // 最近一条拆分发送的消息ID，只在写协程中使用
+	logger       *slog.Logger // 附带玩家ID和连接ID的日志
+}
+
+// newClient 创建连接并启动写协程
+func newClient(playerID, connectionID string, protocol int, encoding string, readOnly bool, profile ClientProfile, conn *websocket.Conn, queue SendQueueConfig, onClosed func(*client)) *client {
+	c := &client{
+		playerID:     playerID,
+		connectionID: connectionID,
+		protocol:     protocol,
+		encoding:     encoding,
+		readOnly:     readOnly,
+		profile:      profile,
+		conn:         conn,
+		connectedAt:  time.Now(),
+		queueSize:    queue.Size,
+		policy:       queue.Policy,
+		wake:         make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		closed:       make(chan struct{}),
+		logger:       slog.With("player_id", playerID, "connection_id", connectionID),
+	}
+	go c.writePump(onClosed)
+	return c
+}
+
+// enqueue 将JSON消息按连接的编码放入发送缓冲区，不会阻塞，
+// 缓冲区已满时按溢出策略处理，避免慢连接拖住广播
+func (c *client) enqueue(msg []byte) error {
+	return c.enqueuePayload(newPayload(msg))
+}
+
+// enqueuePayload 与enqueue相同，消息的各种编码在发给多个连接时只生成一次
+func (c *client) enqueuePayload(p *payload) error {
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+
+	data, err := p.encoded(c.encoding)
+	if err != nil {
+		return err
+	}
+	out := outboundMessage{data: data}
+	if c.policy == OverflowCoalesce {
+		out.kind = p.coalesceKind()
+	}
+
+	if !c.push(out) {
+		c.logger.Warn("发送缓冲区已满，关闭连接")
+		c.close("发送缓冲区已满")
+		return ErrSendBufferFull
+	}
+	return nil
+}
+
+// close 通知写协程发送关闭帧并关闭连接，可重复调用
+func (c *client) close(reason string) {
+	c.closeWithCode(websocket.CloseNormalClosure, reason)
+}
+
+// closeWithCode 与close相同，使用指定的关闭码，例如服务器重启时使用CloseServiceRestart
+func (c *client) closeWithCode(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// messageType 连接使用的WebSocket帧类型
+func (c *client) messageType() int {
+	if c.encoding == EncodingProtobuf {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// writePump 串行写出缓冲区中的消息并定期发送心跳，退出时关闭底层连接
+func (c *client) writePump(onClosed func(*client)) {
+	// 先注册的defer最后执行，panic时先关闭连接和清理，再捕获上报
+	defer reporting.Recover("ws.write", "player_id", c.playerID, "connection_id", c.connectionID)
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+		close(c.closed)
+		if onClosed != nil {
+			onClosed(c)
+		}
+	}()
+
+	for {
+		select {
+		case <-c.wake:
+			for {
+				msg, ok := c.pop()
+				if !ok {
+					break
+				}
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := c.write(msg); err != nil {
+					c.logger.Info("发送消息失败", "error", err)
+					c.close("")
+					return
+				}
+			}
+
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.logger.Info("心跳发送失败", "error", err)
+				c.close("")
+				return
+			}
+
+		case <-c.done:
+			c.flush()
+			closeMsg := websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+			c.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+			return
+		}
+	}
+}
+
+// flush 在关闭前尽量写出缓冲区中剩余的消息，总耗时不超过一次写入超时
+func (c *client) flush() {
+	c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	for {
+		msg, ok := c.pop()
+		if !ok {
+			return
+		}
+		if err := c.write(msg); err != nil {
+			return
+		}
+	}
+}
+
+// write 写出一条消息，JSON消息超过连接的帧大小上限时拆成多条chunk消息连续写出
+func (c *client) write(msg []byte) error {
+	if c.profile.MaxFrameSize <= 0 || len(msg) <= c.profile.MaxFrameSize || c.messageType() != websocket.TextMessage {
+		return c.conn.WriteMessage(c.messageType(), msg)
+	}
+
+	c.chunkID++
+	for _, frame := range chunkFrames(c.chunkID, msg, c.profile.MaxFrameSize) {
+		if err := c.conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
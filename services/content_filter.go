@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FilterResult 内容过滤的结果
+type FilterResult struct {
+	Flagged bool
+	// Terms 命中的敏感词，屏蔽模式下替换为星号；外部审核接口只给出分类，没有具体的词
+	Terms      []string
+	Categories []string // 外部审核接口判定的违规类别
+}
+
+// ContentFilter 可插拔的内容过滤器，用于聊天和AI发言
+type ContentFilter interface {
+	Name() string
+	Check(ctx context.Context, text string) (*FilterResult, error)
+}
+
+// WordlistFilter 基于敏感词表的过滤器，不区分大小写
+type WordlistFilter struct {
+	words [][]rune
+}
+
+// NewWordlistFilter 创建敏感词过滤器，忽略空词和重复的词
+func NewWordlistFilter(words []string) *WordlistFilter {
+	seen := make(map[string]bool)
+	filter := &WordlistFilter{}
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		filter.words = append(filter.words, []rune(word))
+	}
+	// 长词优先，重叠时按较长的词屏蔽
+	sort.SliceStable(filter.words, func(i, j int) bool { return len(filter.words[i]) > len(filter.words[j]) })
+	return filter
+}
+
+// LoadWordlist 读取敏感词表文件，每行一个词，#开头的行为注释
+func LoadWordlist(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
+
+// Len 敏感词数量
+func (wf *WordlistFilter) Len() int {
+	return len(wf.words)
+}
+
+// Name 过滤器名称
+func (wf *WordlistFilter) Name() string {
+	return "wordlist"
+}
+
+// Check 查找文本中出现的敏感词
+func (wf *WordlistFilter) Check(_ context.Context, text string) (*FilterResult, error) {
+	result := &FilterResult{}
+	lower := lowerRunes(text)
+	for _, word := range wf.words {
+		if indexRunes(lower, word, 0) >= 0 {
+			result.Flagged = true
+			result.Terms = append(result.Terms, string(word))
+		}
+	}
+	return result, nil
+}
+
+// maskTerms 把文本中出现的敏感词逐字替换为星号
+func maskTerms(text string, terms []string) string {
+	runes := []rune(text)
+	lower := lowerRunes(text)
+	for _, term := range terms {
+		word := []rune(term)
+		for i := indexRunes(lower, word, 0); i >= 0; i = indexRunes(lower, word, i+len(word)) {
+			for j := i; j < i+len(word); j++ {
+				runes[j] = '*'
+			}
+		}
+	}
+	return string(runes)
+}
+
+// lowerRunes 逐字转为小写，保持与原文相同的字符位置
+func lowerRunes(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// indexRunes 从from开始查找word在text中的位置，没有时返回-1
+func indexRunes(text, word []rune, from int) int {
+	for i := from; i+len(word) <= len(text); i++ {
+		match := true
+		for j, r := range word {
+			if text[i+j] != r {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// ModerationAPIFilter 调用外部内容审核接口的过滤器，请求体为 {"input": 文本}，
+// 响应格式与OpenAI的moderations接口相同：{"results": [{"flagged": true, "categories": {"harassment": true}}]}
+type ModerationAPIFilter struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewModerationAPIFilter 创建外部审核过滤器，apiKey非空时以Bearer令牌发送，timeout不大于0时为3秒
+func NewModerationAPIFilter(endpoint, apiKey string, timeout time.Duration) *ModerationAPIFilter {
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	return &ModerationAPIFilter{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Name 过滤器名称
+func (mf *ModerationAPIFilter) Name() string {
+	return "api"
+}
+
+// Check 请求外部接口审核文本
+func (mf *ModerationAPIFilter) Check(ctx context.Context, text string) (*FilterResult, error) {
+	body, err := json.Marshal(map[string]string{"input": text})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, mf.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if mf.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+mf.apiKey)
+	}
+
+	resp, err := mf.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求内容审核接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("内容审核接口返回 %s", resp.Status)
+	}
+
+	var response struct {
+		Results []struct {
+			Flagged    bool            `json:"flagged"`
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("解析内容审核接口响应失败: %w", err)
+	}
+
+	result := &FilterResult{}
+	for _, item := range response.Results {
+		if !item.Flagged {
+			continue
+		}
+		result.Flagged = true
+		for category, hit := range item.Categories {
+			if hit {
+				result.Categories = append(result.Categories, category)
+			}
+		}
+	}
+	sort.Strings(result.Categories)
+	return result, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/qianlnk/werewolf/storage"
+)
+
+// ErrorCode 机器可读的错误码，客户端和机器人应根据错误码判断错误类型，错误信息仅用于展示
+type ErrorCode string
+
+// 通用错误码
+const (
+	CodeInvalidRequest ErrorCode = "INVALID_REQUEST" // 请求格式或参数不合法
+	CodeUnauthorized   ErrorCode = "UNAUTHORIZED"    // 未登录或令牌无效
+	CodeForbidden      ErrorCode = "FORBIDDEN"       // 没有权限
+	CodeNotFound       ErrorCode = "NOT_FOUND"       // 记录不存在
+	CodeConflict       ErrorCode = "CONFLICT"        // 记录已存在
+	CodeShuttingDown   ErrorCode = "SHUTTING_DOWN"   // 服务器正在关闭
+	CodeServerBusy     ErrorCode = "SERVER_BUSY"     // 超出服务器容量上限，稍后重试
+	CodeInternal       ErrorCode = "INTERNAL_ERROR"  // 服务端内部错误
+)
+
+// 账号和认证
+const (
+	CodeUserExists         ErrorCode = "USER_EXISTS"
+	CodeUserNotFound       ErrorCode = "USER_NOT_FOUND"
+	CodeInvalidCredentials ErrorCode = "INVALID_CREDENTIALS"
+	CodeInvalidUsername    ErrorCode = "INVALID_USERNAME"
+	CodeWeakPassword       ErrorCode = "WEAK_PASSWORD"
+	CodeNotGuest           ErrorCode = "NOT_GUEST"
+	CodeInvalidToken       ErrorCode = "INVALID_TOKEN"
+	CodeInvalidTicket      ErrorCode = "INVALID_TICKET"
+	CodeInsufficientScope  ErrorCode = "INSUFFICIENT_SCOPE" // API密钥没有该操作需要的权限
+	CodeInvalidScope       ErrorCode = "INVALID_SCOPE"
+	CodeAPIKeyNotFound     ErrorCode = "API_KEY_NOT_FOUND"
+	CodeBanned             ErrorCode = "BANNED"
+	CodeWeChatDisabled     ErrorCode = "WECHAT_DISABLED"     // 未配置微信小程序登录
+	CodeInvalidWeChatCode  ErrorCode = "INVALID_WECHAT_CODE" // 微信登录凭证无效或已使用
+	CodeUnknownProvider    ErrorCode = "UNKNOWN_PROVIDER"    // 不支持或未配置的第三方登录平台
+	CodeInvalidOAuthState  ErrorCode = "INVALID_OAUTH_STATE" // 第三方登录的state无效或已过期
+	CodeOAuthFailed        ErrorCode = "OAUTH_FAILED"        // 第三方平台拒绝授权或接口调用失败
+	CodeIdentityLinked     ErrorCode = "IDENTITY_LINKED"     // 第三方账号已绑定其他玩家账号
+	CodeProviderLinked     ErrorCode = "PROVIDER_LINKED"     // 玩家账号已绑定该平台的其他账号
+	CodeIdentityNotFound   ErrorCode = "IDENTITY_NOT_FOUND"
+	CodeLastLoginMethod    ErrorCode = "LAST_LOGIN_METHOD" // 不能解除唯一的登录方式
+)
+
+// 房间和对局
+const (
+	CodeRoomNotFound       ErrorCode = "ROOM_NOT_FOUND"
+	CodeRoomFull           ErrorCode = "ROOM_FULL"
+	CodeNotInRoom          ErrorCode = "NOT_IN_ROOM"
+	CodePlayerNotFound     ErrorCode = "PLAYER_NOT_FOUND"
+	CodeGameNotFound       ErrorCode = "GAME_NOT_FOUND"
+	CodeGameNotStarted     ErrorCode = "GAME_NOT_STARTED"
+	CodeGameInProgress     ErrorCode = "GAME_IN_PROGRESS"
+	CodeNotEnoughPlayers   ErrorCode = "NOT_ENOUGH_PLAYERS"
+	CodeNotYourTurn        ErrorCode = "NOT_YOUR_TURN"       // 当前阶段或角色不能执行该动作
+	CodeWrongRole          ErrorCode = "WRONG_ROLE"          // 技能与玩家角色不符
+	CodeInvalidAction      ErrorCode = "INVALID_ACTION"      // 未知的动作或技能类型
+	CodeInvalidTarget      ErrorCode = "INVALID_TARGET"      // 目标玩家不存在或不能被选择
+	CodePotionUsed         ErrorCode = "POTION_USED"         // 女巫的药已经用过
+	CodePhaseIncomplete    ErrorCode = "PHASE_NOT_COMPLETE"  // 当前阶段还有未完成的动作
+	CodeInvalidPersonality ErrorCode = "INVALID_PERSONALITY" // AI性格不存在或分布不合法
+	CodeInvalidAIFill      ErrorCode = "INVALID_AI_FILL"     // AI补位人数不合法
+	CodeNotAIPlayer        ErrorCode = "NOT_AI_PLAYER"       // 玩家不由AI控制
+	CodeInvalidAITuning    ErrorCode = "INVALID_AI_TUNING"   // AI调参不合法
+	CodeInvalidMode        ErrorCode = "INVALID_MODE"        // 板子不存在
+	CodeInvalidRoomSize    ErrorCode = "INVALID_ROOM_SIZE"   // 房间人数上限容纳不下板子
+	CodeInvalidBoard       ErrorCode = "INVALID_BOARD"       // 自定义板子的角色数量不合法
+	CodeNotHost            ErrorCode = "NOT_HOST"            // 只有房主可以执行该操作
+	CodePlayerDead         ErrorCode = "PLAYER_DEAD"         // 出局玩家不能执行该动作
+	CodeStateChanged       ErrorCode = "STATE_CHANGED"       // 动作基于的对局状态已经过期
+	CodeNoPhaseSnapshot    ErrorCode = "NO_PHASE_SNAPSHOT"   // 没有可以回滚到的阶段快照
+	CodeInvalidLocale      ErrorCode = "INVALID_LOCALE"      // 不支持的语言
+	CodeJudgeTaken         ErrorCode = "JUDGE_TAKEN"         // 房间已有法官
+	CodeJudgeUnavailable   ErrorCode = "JUDGE_UNAVAILABLE"   // 当前不能担任法官
+	CodeNotJudge           ErrorCode = "NOT_JUDGE"           // 只有本房间的法官可以执行该操作
+)
+
+// 聊天
+const (
+	CodeChatNotAllowed    ErrorCode = "CHAT_NOT_ALLOWED"
+	CodeWhisperDisabled   ErrorCode = "WHISPER_DISABLED"
+	CodeWhisperTarget     ErrorCode = "INVALID_WHISPER_TARGET"
+	CodeChatBlocked       ErrorCode = "CHAT_BLOCKED"        // 发言命中内容过滤
+	CodeChatMuted         ErrorCode = "CHAT_MUTED"          // 多次违规后被自动禁言
+	CodeInvalidChatFilter ErrorCode = "INVALID_CHAT_FILTER" // 房间的聊天过滤模式不合法
+	CodeMuteNotFound      ErrorCode = "MUTE_NOT_FOUND"
+)
+
+// 好友和举报
+const (
+	CodeFriendSelf      ErrorCode = "FRIEND_SELF"
+	CodeFriendExists    ErrorCode = "FRIEND_EXISTS"
+	CodeNotFriends      ErrorCode = "NOT_FRIENDS"
+	CodeFriendOffline   ErrorCode = "FRIEND_OFFLINE"
+	CodeGuestNoFriends  ErrorCode = "GUEST_NO_FRIENDS"
+	CodeReportSelf      ErrorCode = "REPORT_SELF"
+	CodeReportReason    ErrorCode = "REPORT_REASON_REQUIRED"
+	CodeReportNotFound  ErrorCode = "REPORT_NOT_FOUND"
+	CodeReportReviewed  ErrorCode = "REPORT_REVIEWED"
+	CodeInvalidBan      ErrorCode = "INVALID_BAN"
+	CodeBanNotFound     ErrorCode = "BAN_NOT_FOUND"
+	CodeInvalidDecision ErrorCode = "INVALID_DECISION"
+)
+
+// 赛事
+const (
+	CodeTournamentNotFound   ErrorCode = "TOURNAMENT_NOT_FOUND"
+	CodeMatchNotFound        ErrorCode = "MATCH_NOT_FOUND"
+	CodeInvalidTournament    ErrorCode = "INVALID_TOURNAMENT"     // 赛制、每桌人数或轮数不合法
+	CodeTournamentClosed     ErrorCode = "TOURNAMENT_CLOSED"      // 报名已截止
+	CodeTournamentNotRunning ErrorCode = "TOURNAMENT_NOT_RUNNING" // 赛事未开始或已结束
+	CodeNotOrganizer         ErrorCode = "NOT_ORGANIZER"          // 只有赛事的组织者可以执行该操作
+)
+
+// Error 带错误码的错误，Message为展示给玩家的本地化信息
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError 创建带错误码的错误
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// ErrorCodeOf 获取错误的错误码，没有错误码的错误视为内部错误
+func ErrorCodeOf(err error) ErrorCode {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+
+	var validationErr *ValidationError
+	var protocolErr *UnsupportedProtocolError
+	var bannedErr *BannedError
+	var mutedErr *MutedError
+	switch {
+	case errors.As(err, &validationErr), errors.As(err, &protocolErr):
+		return CodeInvalidRequest
+	case errors.As(err, &bannedErr):
+		return CodeBanned
+	case errors.As(err, &mutedErr):
+		return CodeChatMuted
+	case errors.Is(err, storage.ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, storage.ErrDuplicate):
+		return CodeConflict
+	}
+	return CodeInternal
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// roomEventBufferSize 每个房间保留的最近广播事件数，用于客户端补齐丢失的事件
+const roomEventBufferSize = 256
+
+// roomEvent 已广播的房间事件
+type roomEvent struct {
+	seq      int64
+	audience Audience // 广播对象，nil表示房间内所有连接
+	data     []byte   // 带序号的消息内容
+	at       time.Time
+}
+
+// roomEventLog 房间的广播序号和最近事件，序号从1开始单调递增
+type roomEventLog struct {
+	seq    int64
+	events []roomEvent
+	acks   map[string]int64 // playerID -> 最近确认的序号
+	notify chan struct{}    // 有新事件时关闭并替换，用于唤醒等待中的轮询
+}
+
+func newRoomEventLog() *roomEventLog {
+	return &roomEventLog{acks: make(map[string]int64), notify: make(chan struct{})}
+}
+
+// append 为消息分配下一个序号并记录，返回带序号的消息
+func (l *roomEventLog) append(audience Audience, msg []byte) []byte {
+	l.seq++
+	data := withSeq(msg, l.seq)
+
+	l.events = append(l.events, roomEvent{seq: l.seq, audience: audience, data: data, at: time.Now()})
+	if len(l.events) > roomEventBufferSize {
+		l.events = append(l.events[:0:0], l.events[len(l.events)-roomEventBufferSize:]...)
+	}
+
+	close(l.notify)
+	l.notify = make(chan struct{})
+	return data
+}
+
+// since 获取序号大于seq的事件，complete为false表示部分事件已不在缓冲区中
+func (l *roomEventLog) since(seq int64) (events []roomEvent, complete bool) {
+	complete = len(l.events) == 0 || l.events[0].seq <= seq+1 || seq >= l.seq
+	for _, event := range l.events {
+		if event.seq > seq {
+			events = append(events, event)
+		}
+	}
+	return events, complete
+}
+
+// visible 获取序号大于seq且玩家有权收到的事件，seat为玩家的座位，旁观者为nil
+func (l *roomEventLog) visible(playerID string, seat *models.Player, seq int64) *RoomEvents {
+	events, complete := l.since(seq)
+	result := &RoomEvents{Events: make([]RoomEvent, 0, len(events)), LastSeq: l.seq, Complete: complete}
+	for _, event := range events {
+		if event.audience == nil || event.audience(playerID, seat) {
+			result.Events = append(result.Events, RoomEvent{Seq: event.seq, Data: event.data})
+		}
+	}
+	return result
+}
+
+// delayed 获取序号大于seq、发生在cutoff之前且旁观者可以收到的事件，LastSeq为cutoff之前的最后一个序号；
+// 还有cutoff之后的事件时，next为其中最早一个的发生时间
+func (l *roomEventLog) delayed(seq int64, cutoff time.Time) (result *RoomEvents, next time.Time) {
+	events, complete := l.since(seq)
+	result = &RoomEvents{Events: make([]RoomEvent, 0, len(events)), LastSeq: seq, Complete: complete}
+	if !complete && len(l.events) > 0 {
+		result.LastSeq = l.events[0].seq - 1
+	}
+	for _, event := range events {
+		if event.at.After(cutoff) {
+			return result, event.at
+		}
+		if event.audience == nil || event.audience("", nil) {
+			result.Events = append(result.Events, RoomEvent{Seq: event.seq, Data: event.data})
+		}
+		result.LastSeq = event.seq
+	}
+	return result, time.Time{}
+}
+
+// ack 记录玩家已收到的序号，序号只会前进
+func (l *roomEventLog) ack(playerID string, seq int64) {
+	if seq > l.seq {
+		seq = l.seq
+	}
+	if seq > l.acks[playerID] {
+		l.acks[playerID] = seq
+	}
+}
+
+// withSeq 在JSON对象消息中加入seq字段，非对象消息原样返回
+func withSeq(msg []byte, seq int64) []byte {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) < 2 || msg[0] != '{' {
+		return msg
+	}
+
+	data := make([]byte, 0, len(msg)+24)
+	data = append(data, `{"seq":`...)
+	data = strconv.AppendInt(data, seq, 10)
+	if body := bytes.TrimSpace(msg[1:]); len(body) > 0 && body[0] != '}' {
+		data = append(data, ',')
+	}
+	return append(data, msg[1:]...)
+}
+
+// RoomEvent 房间事件，序列化为带seq字段的原始消息
+type RoomEvent struct {
+	Seq  int64
+	Data json.RawMessage
+}
+
+func (e RoomEvent) MarshalJSON() ([]byte, error) {
+	return e.Data, nil
+}
+
+// RoomEvents 房间事件查询结果，Complete为false表示部分事件已过期，客户端需要重新获取完整状态
+type RoomEvents struct {
+	Events   []RoomEvent `json:"events"`
+	LastSeq  int64       `json:"last_seq"`
+	Complete bool        `json:"complete"`
+}
+
+// WaitRoomEvents 获取房间中序号大于since且玩家有权收到的事件，没有新事件时最多等待timeout，
+// 供无法使用WebSocket的客户端长轮询
+func (wm *WebSocketManager) WaitRoomEvents(ctx context.Context, roomID, playerID string, since int64, timeout time.Duration) (*RoomEvents, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		seats := wm.roomSeats(roomID)
+
+		shard := wm.roomShard(roomID)
+		shard.mutex.Lock()
+		eventLog := shard.eventLogLocked(roomID)
+		result := eventLog.visible(playerID, seats[playerID], since)
+		notify := eventLog.notify
+		shard.mutex.Unlock()
+
+		if len(result.Events) > 0 || !result.Complete {
+			return result, nil
+		}
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return result, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// WaitSpectatorEvents 获取房间中序号大于since、旁观者可以收到且已超过delay的事件，没有这样的事件时最多等待timeout，
+// 供直播使用的延迟旁观事件流
+func (wm *WebSocketManager) WaitSpectatorEvents(ctx context.Context, roomID string, since int64, delay, timeout time.Duration) (*RoomEvents, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		shard := wm.roomShard(roomID)
+		shard.mutex.Lock()
+		eventLog := shard.eventLogLocked(roomID)
+		result, next := eventLog.delayed(since, time.Now().Add(-delay))
+		notify := eventLog.notify
+		shard.mutex.Unlock()
+
+		if len(result.Events) > 0 || !result.Complete {
+			return result, nil
+		}
+		// 只有旁观者收不到的事件时也推进序号，避免下次重复检查
+		since = result.LastSeq
+
+		// 已有尚未到时的事件时，到时后再检查，否则等待新事件
+		if next.IsZero() {
+			select {
+			case <-notify:
+				continue
+			case <-timer.C:
+				return result, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		due := time.NewTimer(time.Until(next.Add(delay)))
+		select {
+		case <-due.C:
+		case <-timer.C:
+			due.Stop()
+			return result, nil
+		case <-ctx.Done():
+			due.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// GameExportVersion 导出格式版本，格式不兼容变更时递增
+const GameExportVersion = 1
+
+// ExportedAction 导出的玩家动作
+type ExportedAction struct {
+	Seq       int    `json:"seq"`
+	Round     int    `json:"round"`
+	Phase     string `json:"phase"`
+	Type      string `json:"type"`
+	PlayerID  string `json:"player_id"`
+	TargetID  string `json:"target_id,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// ExportedChat 导出的聊天消息
+type ExportedChat struct {
+	Seq       int    `json:"seq"`
+	Round     int    `json:"round"`
+	Phase     string `json:"phase"`
+	Channel   string `json:"channel,omitempty"`
+	PlayerID  string `json:"player_id"`
+	To        string `json:"to,omitempty"` // 私聊对象
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// ExportedDeath 导出的死亡信息
+type ExportedDeath struct {
+	Round    int               `json:"round"`
+	Phase    string            `json:"phase"`
+	PlayerID string            `json:"player_id"`
+	Cause    models.DeathCause `json:"cause,omitempty"`
+}
+
+// GameExport 完整的机器可读对局记录，供社区存档和分析
+type GameExport struct {
+	Version    int                      `json:"version"`
+	ExportedAt int64                    `json:"exported_at"`
+	ID         string                   `json:"id"`
+	RoomID     string                   `json:"room_id"`
+	Mode       models.GameMode          `json:"mode"`
+	Ranked     bool                     `json:"ranked"`
+	Result     string                   `json:"result"`
+	StartedAt  int64                    `json:"started_at"`
+	EndedAt    int64                    `json:"ended_at"`
+	Duration   int64                    `json:"duration"`
+	Players    []models.Player          `json:"players"` // 包含角色、存活状态和胜负
+	Winners    []string                 `json:"winners"` // 获胜玩家ID
+	Actions    []ExportedAction         `json:"actions"`
+	Votes      map[int][]ExportedAction `json:"votes"` // 按回合分组的投票
+	Chat       []ExportedChat           `json:"chat"`
+	Deaths     []ExportedDeath          `json:"deaths"`
+	Events     []models.GameEvent       `json:"events"` // 原始事件日志
+}
+
+// BuildGameExport 根据对局记录构建导出数据
+func BuildGameExport(record *models.GameRecord) *GameExport {
+	export := &GameExport{
+		Version:    GameExportVersion,
+		ExportedAt: time.Now().Unix(),
+		ID:         record.ID,
+		RoomID:     record.RoomID,
+		Mode:       record.Mode,
+		Ranked:     record.Ranked,
+		Result:     record.Result,
+		StartedAt:  record.StartedAt,
+		EndedAt:    record.EndedAt,
+		Duration:   record.Duration,
+		Players:    record.Players,
+		Winners:    make([]string, 0),
+		Actions:    make([]ExportedAction, 0),
+		Votes:      make(map[int][]ExportedAction),
+		Chat:       make([]ExportedChat, 0),
+		Deaths:     make([]ExportedDeath, 0),
+		Events:     record.Events,
+	}
+
+	for _, player := range record.Players {
+		if isWinner(player, record.Result) {
+			export.Winners = append(export.Winners, player.ID)
+		}
+	}
+
+	for _, event := range record.Events {
+		switch event.Type {
+		case "action":
+			action := ExportedAction{
+				Seq:       event.Seq,
+				Round:     event.Round,
+				Phase:     event.Phase,
+				Type:      event.Action,
+				PlayerID:  event.PlayerID,
+				TargetID:  event.TargetID,
+				Timestamp: event.Timestamp,
+			}
+			export.Actions = append(export.Actions, action)
+			if action.Type == "vote" {
+				export.Votes[action.Round] = append(export.Votes[action.Round], action)
+			}
+		case "chat":
+			export.Chat = append(export.Chat, ExportedChat{
+				Seq:       event.Seq,
+				Round:     event.Round,
+				Phase:     event.Phase,
+				Channel:   event.Channel,
+				PlayerID:  event.PlayerID,
+				To:        event.TargetID,
+				Message:   event.Content,
+				Timestamp: event.Timestamp,
+			})
+		case "death":
+			export.Deaths = append(export.Deaths, ExportedDeath{
+				Round:    event.Round,
+				Phase:    event.Phase,
+				PlayerID: event.PlayerID,
+				Cause:    event.Cause,
+			})
+		}
+	}
+
+	return export
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+var (
+	ErrFriendSelf     = NewError(CodeFriendSelf, "不能添加自己为好友")
+	ErrFriendExists   = NewError(CodeFriendExists, "已经是好友或已发送过好友请求")
+	ErrNotFriends     = NewError(CodeNotFriends, "对方不是你的好友")
+	ErrFriendOffline  = NewError(CodeFriendOffline, "好友当前不在线")
+	ErrNotInRoom      = NewError(CodeNotInRoom, "你不在该房间中")
+	ErrGuestNoFriends = NewError(CodeGuestNoFriends, "游客账号不能添加好友")
+)
+
+// FriendInfo 好友列表中的一项
+type FriendInfo struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	Status      string `json:"status"`            // accepted, incoming（待我确认）, outgoing（待对方确认）
+	Online      bool   `json:"online"`            // 是否有活跃的WebSocket连接
+	RoomID      string `json:"room_id,omitempty"` // 在线时所在的房间
+}
+
+// FriendManager 好友管理器
+type FriendManager struct {
+	store     storage.Store
+	webSocket *WebSocketManager
+}
+
+// NewFriendManager 创建好友管理器实例
+func NewFriendManager(store storage.Store, ws *WebSocketManager) *FriendManager {
+	return &FriendManager{store: store, webSocket: ws}
+}
+
+// AddFriend 向对方发送好友请求，对方已向自己发送过请求时直接成为好友
+func (fm *FriendManager) AddFriend(userID, friendID string) (*models.Friendship, error) {
+	if userID == friendID {
+		return nil, ErrFriendSelf
+	}
+
+	user, err := fm.store.GetUserByID(userID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	friend, err := fm.store.GetUserByID(friendID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.Guest || friend.Guest {
+		return nil, ErrGuestNoFriends
+	}
+
+	friendship, err := fm.store.GetFriendship(userID, friendID)
+	switch {
+	case err == storage.ErrNotFound:
+		friendship = &models.Friendship{
+			RequesterID: userID,
+			AddresseeID: friendID,
+			Status:      models.FriendPending,
+			CreatedAt:   time.Now().Unix(),
+		}
+	case err != nil:
+		return nil, err
+	case friendship.Status == models.FriendPending && friendship.AddresseeID == userID:
+		friendship.Status = models.FriendAccepted
+	default:
+		return nil, ErrFriendExists
+	}
+
+	if err := fm.store.SaveFriendship(friendship); err != nil {
+		return nil, err
+	}
+
+	fm.webSocket.SendToPlayer(friendID, map[string]interface{}{
+		"type":      "friend_" + friendship.Status,
+		"player_id": userID,
+		"name":      user.DisplayName,
+	})
+	return friendship, nil
+}
+
+// RemoveFriend 删除好友或拒绝、撤回好友请求
+func (fm *FriendManager) RemoveFriend(userID, friendID string) error {
+	return fm.store.DeleteFriendship(userID, friendID)
+}
+
+// ListFriends 获取好友列表及其在线状态
+func (fm *FriendManager) ListFriends(userID string) ([]FriendInfo, error) {
+	friendships, err := fm.store.ListFriendships(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	friends := make([]FriendInfo, 0, len(friendships))
+	for _, friendship := range friendships {
+		info := FriendInfo{ID: friendship.AddresseeID, Status: friendship.Status}
+		if friendship.AddresseeID == userID {
+			info.ID = friendship.RequesterID
+		}
+		if friendship.Status == models.FriendPending {
+			info.Status = "outgoing"
+			if friendship.AddresseeID == userID {
+				info.Status = "incoming"
+			}
+		}
+
+		if user, err := fm.store.GetUserByID(info.ID); err == nil {
+			info.DisplayName = user.DisplayName
+		}
+
+		// 只有互为好友才能看到在线状态
+		if friendship.Status == models.FriendAccepted {
+			info.Online = fm.webSocket.IsOnline(info.ID)
+			if info.Online {
+				info.RoomID = fm.webSocket.PlayerRoom(info.ID)
+			}
+		}
+		friends = append(friends, info)
+	}
+	return friends, nil
+}
+
+// InviteToRoom 通过WebSocket邀请在线好友加入自己所在的房间
+func (fm *FriendManager) InviteToRoom(userID, friendID string, room *models.Room) error {
+	friendship, err := fm.store.GetFriendship(userID, friendID)
+	if err == storage.ErrNotFound || (err == nil && friendship.Status != models.FriendAccepted) {
+		return ErrNotFriends
+	}
+	if err != nil {
+		return err
+	}
+
+	inRoom := false
+	var inviterName string
+	for _, player := range room.Players {
+		if player.ID == userID {
+			inRoom = true
+			inviterName = player.Name
+			break
+		}
+	}
+	if !inRoom {
+		return ErrNotInRoom
+	}
+
+	if !fm.webSocket.IsOnline(friendID) {
+		return ErrFriendOffline
+	}
+
+	return fm.webSocket.SendToPlayer(friendID, localize(map[string]interface{}{
+		"type":       "room_invite",
+		"room_id":    room.ID,
+		"room_name":  room.Name,
+		"mode":       room.Mode,
+		"ranked":     room.Ranked,
+		"inviter_id": userID,
+	}, Locale(room.Locale), "message", "system.room_invite", inviterName, room.Name))
+}
@@ -1,155 +1,86 @@
 package services
 
 import (
-	"errors"
-	"log"
-	"math/rand"
-	"sync"
-	"time"
+	"log/slog"
 
 	"github.com/qianlnk/werewolf/models"
 )
 
 // 游戏阶段
 const (
-	PhaseNight = "night" // 夜晚阶段
-	PhaseDay   = "day"   // 白天阶段
-	PhaseVote  = "vote"  // 投票阶段
+	PhaseNight       = "night"        // 夜晚阶段
+	PhaseCampaign    = "campaign"     // 竞选警长，第一晚结束后、第一个白天之前进行，见Room.Sheriff
+	PhaseSheriffVote = "sheriff_vote" // 警下的玩家投票选出警长
+	PhaseDay         = "day"          // 白天阶段
+	PhaseVote        = "vote"         // 投票阶段
 )
 
 var (
-	ErrGameNotStarted = errors.New("游戏尚未开始")
-	ErrGameInProgress = errors.New("游戏正在进行中")
-	ErrInvalidAction  = errors.New("无效的游戏动作")
-	ErrInvalidPhase   = errors.New("当前阶段无法执行该动作")
+	ErrGameNotStarted = NewError(CodeGameNotStarted, "游戏尚未开始")
+	ErrGameInProgress = NewError(CodeGameInProgress, "游戏正在进行中")
+	ErrInvalidAction  = NewError(CodeInvalidAction, "无效的游戏动作")
+	ErrInvalidPhase   = NewError(CodeNotYourTurn, "当前阶段无法执行该动作")
+	ErrGameNotFound   = NewError(CodeGameNotFound, "游戏未找到")
+	ErrPlayerDead     = NewError(CodePlayerDead, "你已出局，不能执行游戏动作")
+	ErrStateChanged   = NewError(CodeStateChanged, "对局状态已变化，请刷新后重试")
 )
 
-// GameManager 游戏管理器
-type GameManager struct {
-	games map[string]*GameState
-	mutex sync.RWMutex
-}
-
-// GameManager 使用 GameState 结构体
-var _ = GameState{} // 确保 GameState 被正确导入
-
-// NewGameManager 创建游戏管理器实例
-func NewGameManager() *GameManager {
-	return &GameManager{
-		games: make(map[string]*GameState),
-	}
-}
-
-// StartGame 开始游戏
-func (gm *GameManager) StartGame(roomID string, players []models.Player) error {
-	gm.mutex.Lock()
-	defer gm.mutex.Unlock()
-
-	if _, exists := gm.games[roomID]; exists {
-		return ErrGameInProgress
-	}
-
-	game := &GameState{
-		RoomID:    roomID,
-		Phase:     "night",
-		Round:     1,
-		Players:   players,
-		Actions:   make([]models.GameAction, 0),
-		TimeLeft:  120, // 每个阶段2分钟
-		IsStarted: true,
+// boardMode 获取对局使用的板子，未知的模式按经典模式处理
+func boardMode(mode models.GameMode) models.GameMode {
+	switch mode {
+	case models.StandardMode, models.ExtendedMode:
+		return mode
+	default:
+		return models.ClassicMode
 	}
-
-	// 分配角色
-	assignRoles(game)
-
-	gm.games[roomID] = game
-	return nil
 }
 
-// GetGameStatus 获取游戏状态
-func (gm *GameManager) GetGameStatus(roomID string) (*models.GameStatus, error) {
-	gm.mutex.RLock()
-	defer gm.mutex.RUnlock()
-
-	game, exists := gm.games[roomID]
-	if !exists || !game.IsStarted {
-		return nil, ErrGameNotStarted
-	}
-
-	return &models.GameStatus{
-		Phase:    game.Phase,
-		Round:    game.Round,
-		Players:  game.Players,
-		Actions:  getAvailableActions(game),
-		TimeLeft: game.TimeLeft,
-	}, nil
-}
-
-// ProcessAction 处理游戏动作
-func (gm *GameManager) ProcessAction(roomID string, action models.GameAction) error {
-	gm.mutex.Lock()
-	defer gm.mutex.Unlock()
-
-	game, exists := gm.games[roomID]
-	if !exists || !game.IsStarted {
-		return ErrGameNotStarted
-	}
-
-	// 验证动作是否有效
-	if !isValidAction(game, action) {
-		return ErrInvalidAction
-	}
-
-	// 记录动作
-	game.Actions = append(game.Actions, action)
-
-	// 使用状态机处理游戏状态转换
-	sm := NewStateMachine(game)
-	if err := sm.TransitionPhase(); err != nil {
-		// 检查是否是游戏结束的错误
-		if err.Error() == WerewolfWin || err.Error() == VillagerWin {
-			// 处理游戏结束逻辑
-			game.IsStarted = false
+// boardMinPlayers 板子的最少人数：神职和狼人都要分配到，且开局时好人多于狼人。
+// 自定义板子的狼人可能更多，要求好人至少是狼人的两倍，避免第一晚过后狼人就追平人数
+func boardMinPlayers(mode models.GameMode, custom map[models.Role]int) int {
+	roles := generateRoles(0, mode, custom)
+	wolves := 0
+	for _, role := range roles {
+		if isWolf(role) {
+			wolves++
 		}
-		return err
 	}
-
-	// 记录动作
-	game.Actions = append(game.Actions, action)
-
-	// 处理动作结果
-	processActionResult(game, action)
-
-	// 检查是否需要进入下一阶段
-	checkAndUpdatePhase(game)
-
-	return nil
+	if len(custom) > 0 {
+		return max(len(roles), 3*wolves)
+	}
+	return max(len(roles), 2*wolves+1)
 }
 
-// 生成角色列表
-func generateRoles(playerCount int, mode models.GameMode) []models.Role {
+// 生成角色列表，custom不为空时按自定义板子的数量生成，见validateBoard
+func generateRoles(playerCount int, mode models.GameMode, custom map[models.Role]int) []models.Role {
 	roles := make([]models.Role, 0)
 
 	// 基础角色分配
-	log.Printf("开始生成角色列表，玩家数量: %d, 游戏模式: %s", playerCount, mode)
-	switch mode {
-	case models.ClassicMode:
+	slog.Debug("开始生成角色列表", "players", playerCount, "mode", mode, "custom", custom)
+	switch {
+	case len(custom) > 0:
+		// 自定义板子：按固定顺序展开，同一种子下的分配结果可以复现
+		for _, role := range boardRoles {
+			for i := 0; i < custom[role]; i++ {
+				roles = append(roles, role)
+			}
+		}
+
+	case mode == models.ClassicMode:
 		// 经典模式：狼人2个，预言家1个，女巫1个，其余为村民
 		roles = append(roles, models.Werewolf, models.Werewolf)
 		roles = append(roles, models.Seer)
 		roles = append(roles, models.Witch)
-		log.Printf("经典模式角色分配：2个狼人，1个预言家，1个女巫")
 
-	case models.StandardMode:
+	case mode == models.StandardMode:
 		// 标准模式：增加猎人和守卫
 		roles = append(roles, models.Werewolf, models.Werewolf)
 		roles = append(roles, models.Seer)
 		roles = append(roles, models.Witch)
 		roles = append(roles, models.Hunter)
 		roles = append(roles, models.Guard)
-		log.Printf("标准模式角色分配：2个狼人，1个预言家，1个女巫，1个猎人，1个守卫")
 
-	case models.ExtendedMode:
+	case mode == models.ExtendedMode:
 		// 扩展模式：增加白狼王和丘比特
 		roles = append(roles, models.Werewolf, models.WhiteWolf)
 		roles = append(roles, models.Seer)
@@ -157,7 +88,6 @@ func generateRoles(playerCount int, mode models.GameMode) []models.Role {
 		roles = append(roles, models.Hunter)
 		roles = append(roles, models.Guard)
 		roles = append(roles, models.Cupid)
-		log.Printf("扩展模式角色分配：1个狼人，1个白狼王，1个预言家，1个女巫，1个猎人，1个守卫，1个丘比特")
 	}
 
 	// 补充村民角色
@@ -165,67 +95,45 @@ func generateRoles(playerCount int, mode models.GameMode) []models.Role {
 	for i := 0; i < villagerCount; i++ {
 		roles = append(roles, models.Villager)
 	}
-	log.Printf("补充村民数量: %d", villagerCount)
+	slog.Debug("补充村民", "villagers", villagerCount)
 
 	return roles
 }
 
 // 分配角色
 func assignRoles(game *GameState) {
-	log.Printf("开始分配角色，房间ID: %s, 玩家数量: %d", game.Room.ID, len(game.Players))
+	logger := slog.With("room_id", game.Room.ID)
+	logger.Debug("开始分配角色", "players", len(game.Players))
 	playerCount := len(game.Players)
-	roles := generateRoles(playerCount, game.Room.Mode)
+	roles := generateRoles(playerCount, game.Room.Mode, game.Room.Roles)
 
 	// 随机打乱角色顺序
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(roles), func(i, j int) {
+	game.random().Shuffle(len(roles), func(i, j int) {
 		roles[i], roles[j] = roles[j], roles[i]
 	})
-	log.Printf("角色顺序已随机打乱")
 
 	// 分配角色给玩家
 	for i := range game.Players {
 		game.Players[i].Role = roles[i]
 		game.Players[i].Alive = true
-		log.Printf("玩家 %s (%s) 被分配角色: %s", game.Players[i].Name, game.Players[i].ID, roles[i])
+		logger.Debug("分配角色", "player_id", game.Players[i].ID, "role", roles[i])
 	}
-	log.Printf("角色分配完成")
+	logger.Info("角色分配完成", "players", playerCount)
 }
 
-// 获取可用动作
-func getAvailableActions(game *GameState) []string {
-	actions := make([]string, 0)
-
-	switch game.Phase {
-	case PhaseNight:
-		// 夜晚阶段的动作
-		for _, player := range game.Players {
-			if !player.Alive {
-				continue
-			}
-
-			switch player.Role {
-			case models.Werewolf, models.WhiteWolf:
-				actions = append(actions, "kill")
-			case models.Seer:
-				actions = append(actions, "check")
-			case models.Witch:
-				actions = append(actions, "save", "poison")
-			case models.Guard:
-				actions = append(actions, "protect")
-			}
-		}
-
-	case PhaseDay:
-		// 白天阶段的动作
-		actions = append(actions, "discuss")
-
-	case PhaseVote:
-		// 投票阶段的动作
-		actions = append(actions, "vote")
-	}
+// untargetedActions 不需要目标玩家的动作：白天发言、女巫不用药、上警和不上警、撕毁警徽
+var untargetedActions = map[string]bool{
+	"discuss":    true,
+	"pass":       true,
+	"campaign":   true,
+	"decline":    true,
+	"tear_badge": true,
+}
 
-	return actions
+// speechActions 附带公开发言的动作：白天发言和竞选发言，发言内容经过过滤并从中识别身份声明
+var speechActions = map[string]bool{
+	"discuss":  true,
+	"campaign": true,
 }
 
 // 验证动作是否有效
@@ -246,21 +154,34 @@ func isValidAction(game *GameState, action models.GameAction) bool {
 	// 根据游戏阶段和角色验证动作
 	switch game.Phase {
 	case PhaseNight:
+		// 夜晚只有当前步骤的角色可以行动
+		if !game.inNightStep(player.Role) {
+			return false
+		}
 		switch action.Type {
 		case "kill":
 			return player.Role == models.Werewolf || player.Role == models.WhiteWolf
 		case "check":
 			return player.Role == models.Seer
-		case "save", "poison":
+		case "save", "poison", "pass":
 			return player.Role == models.Witch
 		case "protect":
 			return player.Role == models.Guard
+		case "link":
+			return player.Role == models.Cupid && game.Round == 1
 		default:
 			return false
 		}
 
+	case PhaseCampaign:
+		return action.Type == "campaign" || action.Type == "decline"
+
+	case PhaseSheriffVote:
+		// 上警的候选人不能投票
+		return action.Type == "elect" && !game.isSheriffCandidate(player.ID)
+
 	case PhaseDay:
-		return action.Type == "discuss"
+		return action.Type == "discuss" || (action.Type == "explode" && player.Role == models.WhiteWolf)
 
 	case PhaseVote:
 		return action.Type == "vote"
@@ -273,57 +194,40 @@ func isValidAction(game *GameState, action models.GameAction) bool {
 // 处理动作结果
 func processActionResult(game *GameState, action models.GameAction) {
 	switch action.Type {
+	case "check":
+		// 预言家的查验结果在记录动作时写入已知角色，见reduce
+
 	case "kill":
-		// 处理狼人杀人
-		for i := range game.Players {
-			if game.Players[i].ID == action.TargetID {
-				game.Players[i].Alive = false
-				break
-			}
-		}
+		// 狼人击杀在夜晚结算时按狼队共识统一处理，见processNightResults
 
 	case "save", "poison":
-		// 女巫救人或毒人
-		for i := range game.Players {
-			if game.Players[i].ID == action.TargetID {
-				if action.Type == "save" {
-					game.Players[i].Alive = true
-				} else {
-					game.Players[i].Alive = false
-				}
-				break
-			}
-		}
+		// 女巫用药在天亮时与狼人击杀一起结算，见processNightResults
 
 	case "vote":
-		// 处理投票结果
-		for i := range game.Players {
-			if game.Players[i].ID == action.TargetID {
-				game.Players[i].Alive = false
-				break
-			}
-		}
-	}
-}
+		// 投票在投票阶段结束时统一计票放逐，见processVoteResults
 
-// 检查和更新游戏阶段
-func checkAndUpdatePhase(game *GameState) {
-	// 检查是否所有玩家都完成了当前阶段的动作
-	switch game.Phase {
-	case PhaseNight:
-		// 夜晚结束，进入白天
-		game.Phase = PhaseDay
+	case "link":
+		// 丘比特连出的情侣在第一晚结束时生效，见processNightResults
 
-	case PhaseDay:
-		// 白天讨论结束，进入投票
-		game.Phase = PhaseVote
+	case "campaign", "decline", "elect":
+		// 上警和警长投票在阶段结束时统一结算，见processCampaign和processSheriffVote
 
-	case PhaseVote:
-		// 投票结束，进入新的夜晚，回合数加1
-		game.Phase = PhaseNight
-		game.Round++
-	}
+	case "pass_badge", "tear_badge":
+		// 出局的警长移交或撕毁警徽
+		game.PendingBadge = ""
+		game.appointSheriff(action.TargetID, SheriffBadge)
 
-	// 重置阶段时间
-	game.TimeLeft = 120 // 2分钟
+	case "shoot":
+		// 猎人开枪立即带走目标
+		game.PendingShot = ""
+		game.kill(action.TargetID, models.DeathHunterShot)
+		game.recordDeaths()
+
+	case "explode":
+		// 白狼王自爆并带走一名玩家，被带走的猎人仍然可以开枪
+		game.kill(action.PlayerID, models.DeathSelfDestruct)
+		game.kill(action.TargetID, models.DeathExploded)
+		game.triggerHunter(action.TargetID)
+		game.recordDeaths()
+	}
 }
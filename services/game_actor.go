@@ -0,0 +1,92 @@
+package services
+
+import (
+	"github.com/qianlnk/werewolf/reporting"
+)
+
+// commandQueueSize 对局命令队列的容量，队列满时提交命令的一方等待
+const commandQueueSize = 64
+
+// run 对局goroutine，按提交顺序逐条执行修改对局的命令，直到控制器关闭。
+// 玩家动作、聊天、AI接管和各类计时器到期都作为命令提交，同一局的修改不会交错执行
+func (gc *GameController) run() {
+	defer close(gc.stopped)
+
+	for {
+		select {
+		case cmd := <-gc.commands:
+			gc.execute(cmd)
+		case <-gc.closed:
+			return
+		}
+	}
+}
+
+// execute 执行一条命令，执行期间持有gc.mutex的写锁，供其他goroutine中的只读查询加读锁；
+// 命令中的panic被捕获并上报，不影响之后的命令
+func (gc *GameController) execute(cmd func()) {
+	gc.mutex.Lock()
+	defer gc.mutex.Unlock()
+	defer reporting.Recover("game.command", "room_id", gc.game.Room.ID, "phase", gc.game.Phase)
+
+	cmd()
+}
+
+// post 将命令放入对局goroutine的队列后立即返回，控制器已关闭时返回false
+func (gc *GameController) post(cmd func()) bool {
+	select {
+	case gc.commands <- cmd:
+		return true
+	case <-gc.closed:
+		return false
+	}
+}
+
+// postTimer 计时器到期时提交命令，在时间轮的goroutine中调用，不能阻塞：
+// 队列已满时改为在新的goroutine中等待，避免一个繁忙的对局拖慢所有对局的计时
+func (gc *GameController) postTimer(cmd func()) {
+	select {
+	case gc.commands <- cmd:
+	case <-gc.closed:
+	default:
+		go gc.post(cmd)
+	}
+}
+
+// do 在对局goroutine中执行命令并等待完成，控制器已关闭时返回false。
+// 命令本身已经在对局goroutine中执行，不能再调用do
+func (gc *GameController) do(cmd func()) bool {
+	done := make(chan struct{})
+	if !gc.post(func() {
+		defer close(done)
+		cmd()
+	}) {
+		return false
+	}
+
+	select {
+	case <-done:
+		return true
+	case <-gc.closed:
+		return false
+	}
+}
+
+// Close 停止对局goroutine和所有计时器，队列中尚未执行的命令被丢弃，返回时对局goroutine已经退出。
+// 已经触发的计时器提交命令失败后直接返回，倒计时推送随控制器关闭退出。不能在对局goroutine中调用
+func (gc *GameController) Close() {
+	gc.closeOnce.Do(func() {
+		close(gc.closed)
+
+		gc.mutex.Lock()
+		if gc.timer != nil {
+			gc.timer.Stop()
+		}
+		gc.stopBotTimer()
+		gc.stopAbsenceTimer()
+		gc.stopCountdown()
+		gc.stopReclaimTimer()
+		gc.mutex.Unlock()
+	})
+	<-gc.stopped
+}
@@ -1,111 +1,187 @@
 package services
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/tracing"
 )
 
 // GameController 游戏流程控制器
 type GameController struct {
 	game         *GameState
 	stateMachine *StateMachine
-	webSocket    *WebSocketManager
-	timer        *time.Timer
-	mutex        sync.RWMutex
+	sink         EventSink            // 对局消息的接收方，通常是WebSocketManager
+	timer        *wheelTimer          // 当前阶段或夜晚步骤的截止时间，各计时器都由共用的时间轮调度，见timer_wheel.go
+	botTimer     *wheelTimer          // 外部机器人的行动时限
+	absenceTimer *wheelTimer          // 断线玩家的宽限期，见armAbsenceTimer
+	reclaimTimer *wheelTimer          // 对局结束后保留期到时释放对局状态，见scheduleReclaim
+	countdown    *wheelTimer          // 当前阶段的下一次倒计时推送
+	aiPlayers    map[string]*AIPlayer // 对局中的AI玩家，整局保留以积累记忆
+	// phaseSnapshots 最近几个阶段开始时的对局状态，最后一个为当前阶段，见RollbackPhase
+	phaseSnapshots [][]byte
+	viewSeq        int64                // 每次推送游戏状态时递增，增量以此标识基于的状态
+	sentViews      map[string]*sentView // 最近一次推送给各玩家和旁观者（空ID）的状态，见stateUpdate
+	lastVote       *VoteTally           // 本局最近一次放逐投票的计票结果，直播叠加层中显示
+	traceCtx       context.Context      // 正在执行的命令所属的调用链，见withSpan
+	commands       chan func()          // 在对局goroutine中执行的命令，见run
+	closed         chan struct{}        // 关闭时对局goroutine退出
+	stopped        chan struct{}        // 对局goroutine退出后关闭
+	closeOnce      sync.Once
+	mutex          sync.RWMutex // 对局goroutine执行命令时持有写锁，其他goroutine中的只读查询持有读锁
 }
 
-// NewGameController 创建游戏控制器实例
-func NewGameController(game *GameState, ws *WebSocketManager) *GameController {
-	return &GameController{
+// NewGameController 创建游戏控制器实例并启动对局goroutine，对局中的所有消息都发送给sink
+func NewGameController(game *GameState, sink EventSink) *GameController {
+	gc := &GameController{
 		game:         game,
 		stateMachine: NewStateMachine(game),
-		webSocket:    ws,
+		sink:         sink,
+		aiPlayers:    make(map[string]*AIPlayer),
+		commands:     make(chan func(), commandQueueSize),
+		closed:       make(chan struct{}),
+		stopped:      make(chan struct{}),
 	}
+	go gc.run()
+	return gc
 }
 
-// StartGame 开始游戏
-func (gc *GameController) StartGame() error {
-	gc.mutex.Lock()
-	defer gc.mutex.Unlock()
+// StartGame 由房主开始游戏
+func (gc *GameController) StartGame(playerID string) error {
+	var err error = ErrGameNotStarted
+	gc.do(func() {
+		err = gc.startGame(playerID)
+	})
+	return err
+}
 
+// startGame 开始游戏，在对局goroutine中执行
+func (gc *GameController) startGame(playerID string) error {
 	// 验证房间ID
 	if gc.game.Room.ID == "" {
-		return errors.New("无效的房间ID")
+		return NewError(CodeInvalidRequest, "无效的房间ID")
+	}
+	if gc.game.IsStarted {
+		return ErrGameInProgress
+	}
+	// 有法官的房间也可以由法官开始
+	if playerID != hostOf(gc.game.Players) && !gc.isJudge(playerID) {
+		return ErrNotHost
+	}
+
+	// 使用房间选择的板子，最少人数不低于板子要求，人数上限要容纳得下板子
+	if boardMode(gc.game.Room.Mode) != gc.game.Room.Mode {
+		return ErrInvalidMode
+	}
+	if err := validateBoard(gc.game.Room.Roles); err != nil {
+		return err
+	}
+	if minPlayers := boardMinPlayers(gc.game.Room.Mode, gc.game.Room.Roles); gc.game.Room.MinPlayers < minPlayers {
+		gc.game.Room.MinPlayers = minPlayers
+	}
+	if gc.game.Room.MaxPlayers > 0 && gc.game.Room.MaxPlayers < gc.game.Room.MinPlayers {
+		return ErrInvalidRoomSize
+	}
+	if gc.game.roomManager != nil {
+		if err := gc.game.roomManager.checkGameCapacity(gc.game.Room.ID); err != nil {
+			return err
+		}
+	}
+
+	// 本局的角色分配、AI性格和AI决策都由同一个随机数种子决定
+	gc.game.seedRandom(gc.gameSeed())
+	gc.game.learning = CurrentAILearning()
+
+	// 用AI补足到房间设置的人数，不少于板子的最少人数，也不超过房间人数上限
+	fillTo := gc.game.Room.AIFill
+	if fillTo > 0 && fillTo < gc.game.Room.MinPlayers {
+		fillTo = gc.game.Room.MinPlayers
+	}
+	if gc.game.Room.MaxPlayers > 0 && fillTo > gc.game.Room.MaxPlayers {
+		fillTo = gc.game.Room.MaxPlayers
 	}
 
 	// 检查是否需要补充AI玩家
-	if len(gc.game.Players) < 6 {
+	if len(gc.game.Players) < fillTo {
 		// 保存现有玩家
 		existingPlayers := make([]models.Player, len(gc.game.Players))
 		copy(existingPlayers, gc.game.Players)
 
 		// 计算需要补充的AI玩家数量
-		aiCount := 6 - len(gc.game.Players)
+		aiCount := fillTo - len(gc.game.Players)
 		// 创建AI玩家
 		for i := 0; i < aiCount; i++ {
 			aiPlayer := models.Player{
-				ID:    generateAIPlayerID(),
-				Name:  generateAIPlayerName(i + 1),
-				Type:  models.AIPlayer,
-				Alive: true,
-				Role:  models.Villager, // 初始设置为村民，后续会在分配角色时被重新设置
+				ID:          generateAIPlayerID(),
+				Name:        generateAIPlayerName(gc.locale(), i+1),
+				Type:        models.AIPlayer,
+				Personality: pickAIPersonality(gc.game.random(), gc.game.Room.AIPersonalities),
+				Alive:       true,
+				Role:        models.Villager, // 初始设置为村民，后续会在分配角色时被重新设置
 			}
 			existingPlayers = append(existingPlayers, aiPlayer)
 		}
 
-		// 更新游戏和房间的玩家列表
+		// 更新游戏和房间的玩家列表，分配角色只修改对局状态中的玩家，房间保存各自的副本。
+		// 连接的goroutine通过PlayersSnapshot读取玩家列表，替换时需要持有对局状态的锁
+		gc.game.mutex.Lock()
 		gc.game.Players = existingPlayers
-		gc.game.Room.Players = existingPlayers
+		gc.game.mutex.Unlock()
+		gc.game.Room.Players = append([]models.Player(nil), existingPlayers...)
 
 		// 更新房间管理器中的房间信息，确保AI玩家信息持久化
-		if gc.game.roomManager != nil {
-			if room, exists := gc.game.roomManager.rooms[gc.game.Room.ID]; exists {
-				room.Players = existingPlayers
+		if rm := gc.game.roomManager; rm != nil {
+			rm.mutex.Lock()
+			if room, exists := rm.rooms[gc.game.Room.ID]; exists {
+				room.Players = append([]models.Player(nil), existingPlayers...)
+				rm.persistRoom(room)
 			}
+			rm.mutex.Unlock()
 		}
 
 		// 广播房间玩家列表更新
-		gc.webSocket.BroadcastToRoom(gc.game.Room.ID, map[string]interface{}{
+		update := map[string]interface{}{
 			"type":    "room_update",
-			"players": gc.game.Room.Players,
-		})
+			"players": publicPlayers(existingPlayers),
+		}
+		if presence, ok := gc.sink.(presenceSource); ok {
+			update["presence"] = presence.RoomPresence(gc.game.Room.ID)
+		}
+		gc.sink.BroadcastToRoom(gc.game.Room.ID, update)
 	}
 
-	// 设置房间游戏模式和最小玩家数
-	gc.game.Room.Mode = models.ClassicMode
-	gc.game.Room.MinPlayers = 6
-
-	// 启动游戏并分配角色
+	// 启动游戏并分配角色，人数仍不足时返回NOT_ENOUGH_PLAYERS
 	if err := gc.game.StartGame(); err != nil {
 		return err
 	}
 
 	// 确保游戏状态已更新
 	gc.game.IsStarted = true
+	gc.aiPlayers = make(map[string]*AIPlayer)
+	gc.lastVote = nil
+	gc.setRoomStarted(true)
 
 	// 向每个玩家单独发送其角色信息
 	for _, player := range gc.game.Players {
-		gc.webSocket.SendToPlayer(player.ID, map[string]interface{}{
-			"type":    "role_assigned",
-			"role":    player.Role,
-			"message": "游戏开始，你的角色是：" + string(player.Role),
-		})
+		gc.sink.SendToPlayer(player.ID, localize(map[string]interface{}{
+			"type": "role_assigned",
+			"role": player.Role,
+		}, gc.locale(), "message", "system.role_assigned", string(player.Role)))
 	}
 
 	// 广播游戏开始消息，但不包含角色信息
-	gc.webSocket.BroadcastToRoom(gc.game.Room.ID, map[string]interface{}{
-		"type":    "game_started",
-		"message": "游戏已开始",
-	})
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(map[string]interface{}{
+		"type": "game_started",
+	}, gc.locale(), "message", "system.game_started"))
 
 	// 启动游戏计时器
 	gc.startPhaseTimer()
+	gc.saveSnapshot()
 
 	// 广播游戏状态
 	gc.broadcastGameState()
@@ -113,6 +189,18 @@ func (gc *GameController) StartGame() error {
 	return nil
 }
 
+// setRoomStarted 对局开始或结束时同步更新房间状态，大厅据此显示进行中的房间，在对局goroutine中执行
+func (gc *GameController) setRoomStarted(started bool) {
+	gc.game.Room.GameStarted = started
+	gc.game.Room.Status = models.RoomFinished
+	if started {
+		gc.game.Room.Status = models.RoomPlaying
+	}
+	if gc.game.roomManager != nil {
+		gc.game.roomManager.setGameStarted(gc.game.Room.ID, started)
+	}
+}
+
 // generateAIPlayerID 生成AI玩家ID
 func generateAIPlayerID() string {
 	now := time.Now()
@@ -121,17 +209,45 @@ func generateAIPlayerID() string {
 }
 
 // generateAIPlayerName 生成AI玩家名称
-func generateAIPlayerName(index int) string {
-	return fmt.Sprintf("AI玩家%d", index)
+func generateAIPlayerName(locale Locale, index int) string {
+	return Translate(locale, "system.ai_player_name", index)
 }
 
-// ProcessAction 处理玩家动作
-func (gc *GameController) ProcessAction(action models.GameAction) error {
-	gc.mutex.Lock()
-	defer gc.mutex.Unlock()
+// ProcessAction 处理玩家动作，source和connectionID用于审计日志，ctx中的调用链延续到动作处理和状态推送
+func (gc *GameController) ProcessAction(ctx context.Context, action models.GameAction, source, connectionID string) error {
+	// 白天发言、竞选发言和聊天使用相同的内容过滤，外部审核接口可能较慢，在进入对局goroutine之前完成
+	if speechActions[action.Type] && gc.game.roomManager != nil {
+		content, err := gc.game.roomManager.moderateText(ctx, action.RoomID, action.PlayerID, source, ChannelRoom, action.Content)
+		if err != nil {
+			return err
+		}
+		action.Content = content
+	}
+
+	var err error = ErrGameNotStarted
+	queued := time.Now()
+	gc.do(func() {
+		gc.withSpan(ctx, "game.process_action", func(span *tracing.Span) {
+			span.SetAttr("player_id", action.PlayerID)
+			span.SetAttr("action", action.Type)
+			span.SetAttr("queue_wait_ms", time.Since(queued).Milliseconds())
+			err = gc.processAction(action, source, connectionID)
+			span.SetError(err)
+		})
+	})
+	return err
+}
 
-	// 验证目标玩家是否存在且有效
-	targetValid := false
+// processAction 处理玩家动作，在对局goroutine中执行
+func (gc *GameController) processAction(action models.GameAction, source, connectionID string) (err error) {
+	// 无论是否通过校验都写入审计日志，阶段和回合以提交时为准
+	phase, round := gc.game.Phase, gc.game.Round
+	defer func() {
+		gc.recordAudit(action, source, connectionID, phase, round, err)
+	}()
+
+	// 验证目标玩家是否存在且有效，白天发言和女巫不用药不需要目标
+	targetValid := untargetedActions[action.Type] && action.TargetID == ""
 	for _, player := range gc.game.Players {
 		if player.ID == action.TargetID {
 			targetValid = true
@@ -140,18 +256,39 @@ func (gc *GameController) ProcessAction(action models.GameAction) error {
 	}
 
 	if !targetValid {
-		return errors.New("无效的目标玩家")
+		return NewError(CodeInvalidTarget, "无效的目标玩家")
 	}
 
 	// 验证并添加动作
 	if err := gc.game.AddAction(action); err != nil {
 		return err
 	}
+	gc.markActive(action.PlayerID)
 
 	// 处理动作结果
 	processActionResult(gc.game, action)
+	if action.Type == "kill" {
+		gc.notifyWolfTeam()
+	}
+	if action.Type == "shoot" {
+		if gc.checkGameEndNow() {
+			return nil
+		}
+		// 被猎人带走的可能是警长
+		gc.wakeSheriff()
+	}
+	if action.Type == "pass_badge" || action.Type == "tear_badge" {
+		gc.announceBadge(action)
+	}
+	if action.Type == "explode" {
+		gc.endDayEarly()
+		return nil
+	}
 
-	// 检查当前阶段是否可以结束
+	// 夜晚当前步骤结束时进入下一个步骤，否则检查当前阶段是否可以结束
+	if gc.advanceNightStep() {
+		return nil
+	}
 	if gc.stateMachine.isPhaseComplete() {
 		if err := gc.endCurrentPhase(); err != nil {
 			return err
@@ -164,38 +301,189 @@ func (gc *GameController) ProcessAction(action models.GameAction) error {
 	return nil
 }
 
-// processAIActions 处理AI玩家的行动
+// processAIActions 处理AI玩家的行动，挂机的玩家直接放弃，夜晚只有当前步骤的玩家行动，在对局goroutine中执行
 func (gc *GameController) processAIActions() {
 	// 确保游戏已经开始
 	if !gc.game.IsStarted {
 		return
 	}
 
-	// 使用互斥锁确保线程安全
-	gc.mutex.Lock()
-	defer gc.mutex.Unlock()
-
+	phase, round, step := gc.game.Phase, gc.game.Round, gc.game.NightStep
 	for _, player := range gc.game.Players {
+		if phase == PhaseNight && !gc.game.inNightStep(player.Role) {
+			continue
+		}
 		if player.Type == models.AIPlayer && player.Alive {
-			// 创建AI玩家实例
-			ai := NewAIPlayer(player.ID, player.Role, gc.game)
-			// 获取AI的行动
-			action := ai.DecideAction()
-			// 处理AI的行动
-			if err := gc.game.AddAction(action); err != nil {
-				// 如果处理动作失败，记录错误并中断处理
-				fmt.Printf("处理AI玩家 %s 的动作时出错: %v\n", player.ID, err)
-				return
+			// 单个AI的动作无效时继续处理其他AI，避免阶段因缺少动作而无法结束
+			if err := gc.applyAIAction(player); err != nil {
+				gc.logger().Warn("AI玩家行动失败", "player_id", player.ID, "error", err)
 			}
-			// 处理动作结果
-			processActionResult(gc.game, action)
+		} else if player.Alive && gc.game.AFK[player.ID] {
+			gc.skipAFKTurn(player)
+		}
+		// 白狼王自爆会直接结束白天，新阶段的AI行动已经在阶段切换时处理
+		if !gc.game.IsStarted || gc.game.Phase != phase || gc.game.Round != round || gc.game.NightStep != step {
+			return
+		}
+	}
+
+	gc.checkPhaseProgress()
+}
+
+// wakeHunter 通知出局的猎人开枪，AI猎人立即决定是否开枪，对局因此结束时返回true，在对局goroutine中执行
+func (gc *GameController) wakeHunter() bool {
+	for _, player := range gc.game.Players {
+		if player.ID != gc.game.PendingShot {
+			continue
+		}
+		if player.Type != models.AIPlayer {
+			gc.sink.SendToPlayer(player.ID, map[string]interface{}{
+				"type":  "hunter_wake",
+				"round": gc.game.Round,
+			})
+			gc.sendAvailableActions(player.ID)
+			return false
+		}
+		if err := gc.applyAIAction(player); err != nil {
+			gc.logger().Warn("AI猎人开枪失败", "player_id", player.ID, "error", err)
+		}
+		// AI猎人决定不开枪时视为放弃，出局的猎人同时是警长时接着移交警徽
+		gc.game.PendingShot = ""
+		return !gc.game.IsStarted
+	}
+	return false
+}
+
+// checkGameEndNow 猎人开枪或白狼王自爆后立即检查对局是否结束，结束时返回true，在对局goroutine中执行
+func (gc *GameController) checkGameEndNow() bool {
+	result := gc.stateMachine.checkGameEnd()
+	if result == GameOngoing {
+		return false
+	}
+	gc.handleGameEnd(result)
+	return true
+}
+
+// gameSeed 获取配置的对局随机数种子
+func (gc *GameController) gameSeed() int64 {
+	if gc.game.roomManager == nil {
+		return 0
+	}
+	gc.game.roomManager.mutex.RLock()
+	defer gc.game.roomManager.mutex.RUnlock()
+
+	return gc.game.roomManager.gameSeed
+}
+
+// aiPlayer 获取玩家对应的AI实例，首次行动或接管时创建，在对局goroutine中执行
+// 从快照恢复的对局会重放事件日志，记忆不会因重启丢失
+func (gc *GameController) aiPlayer(player models.Player) *AIPlayer {
+	ai, exists := gc.aiPlayers[player.ID]
+	if !exists {
+		ai = NewAIPlayer(player.ID, player.Role, player.Personality, gc.game)
+		gc.aiPlayers[player.ID] = ai
+	}
+	ai.Observe(gc.game)
+	return ai
+}
+
+// applyAIAction 让AI决定并执行一个动作
+func (gc *GameController) applyAIAction(player models.Player) error {
+	// 获取AI的行动
+	ai := gc.aiPlayer(player)
+	action := ai.DecideAction()
+	// 当前阶段没有可执行的动作，例如夜晚的村民
+	if action.Type == "" {
+		return nil
+	}
+	if speechActions[action.Type] {
+		action.Content = gc.moderateAIDialogue(player.ID, action.Content)
+	}
+	// 处理AI的行动
+	err := gc.game.AddAction(action)
+	gc.recordAudit(action, AuditSourceAI, "", gc.game.Phase, gc.game.Round, err)
+	if err != nil {
+		return err
+	}
+	// 发言中附带的查验结果，包括狼人编造的结果
+	if claim := ai.takeClaim(); claim != nil {
+		gc.game.recordClaim(*claim)
+	}
+	// 处理动作结果
+	processActionResult(gc.game, action)
+	if action.Type == "kill" {
+		gc.notifyWolfTeam()
+	}
+	switch action.Type {
+	case "shoot":
+		gc.checkGameEndNow()
+	case "explode":
+		gc.endDayEarly()
+	case "pass_badge", "tear_badge":
+		gc.announceBadge(action)
+	}
+	// 丘比特需要连续选择两名情侣
+	if action.Type == "link" && gc.game.linkCount(player.ID) < 2 {
+		return gc.applyAIAction(player)
+	}
+	return nil
+}
+
+// endDayEarly 白狼王自爆后立即结束白天，跳过当天的投票进入黑夜，在对局goroutine中执行
+func (gc *GameController) endDayEarly() {
+	if gc.checkGameEndNow() {
+		return
+	}
+	gc.stateMachine.skipVote()
+	gc.startPhaseTimer()
+	gc.saveSnapshot()
+	gc.broadcastGameState()
+}
+
+// notifyWolfTeam 狼人提交击杀后向存活的狼人推送本晚各目标的票数和当前共识目标，在对局goroutine中执行
+func (gc *GameController) notifyWolfTeam() {
+	votes, _ := wolfKillTally(gc.game.Actions)
+	message := map[string]interface{}{
+		"type":   "wolf_kill",
+		"round":  gc.game.Round,
+		"votes":  votes,
+		"target": wolfConsensusTarget(gc.game.Actions),
+	}
+	for _, player := range gc.game.Players {
+		if player.Alive && player.Type != models.AIPlayer && isWolf(player.Role) {
+			gc.sink.SendToPlayer(player.ID, message)
 		}
 	}
+}
+
+// recordAudit 将提交的动作及其校验结果写入审计日志
+func (gc *GameController) recordAudit(action models.GameAction, source, connectionID, phase string, round int, err error) {
+	if gc.game.roomManager == nil {
+		return
+	}
+	gc.game.roomManager.Audit().Record(models.AuditEntry{
+		RoomID:       gc.game.Room.ID,
+		PlayerID:     action.PlayerID,
+		ConnectionID: connectionID,
+		Source:       source,
+		Action:       action.Type,
+		TargetID:     action.TargetID,
+		Phase:        phase,
+		Round:        round,
+	}, err)
+}
 
-	// 检查当前阶段是否可以结束
+// checkPhaseProgress 检查当前阶段是否可以结束，否则广播最新状态
+func (gc *GameController) checkPhaseProgress() {
+	if !gc.game.IsStarted {
+		return
+	}
+	if gc.advanceNightStep() {
+		return
+	}
 	if gc.stateMachine.isPhaseComplete() {
 		if err := gc.endCurrentPhase(); err != nil {
-			fmt.Printf("结束当前阶段时出错: %v\n", err)
+			gc.logger().Error("结束当前阶段失败", "phase", gc.game.Phase, "round", gc.game.Round, "error", err)
 		}
 	} else {
 		// 即使阶段未结束也要广播最新状态
@@ -203,47 +491,205 @@ func (gc *GameController) processAIActions() {
 	}
 }
 
+// TakeOverPlayer 由AI接管断线未归的真人玩家，保留其角色和已知信息
+func (gc *GameController) TakeOverPlayer(playerID string) bool {
+	takenOver := false
+	gc.do(func() {
+		takenOver = gc.takeOverPlayer(playerID, "system.takeover_disconnected")
+	})
+	return takenOver
+}
+
+// takeOverPlayer 由AI接管玩家，reason为通知房间的接管原因的消息键，在对局goroutine中执行
+func (gc *GameController) takeOverPlayer(playerID, reason string) bool {
+	if !gc.game.IsStarted {
+		return false
+	}
+
+	var player *models.Player
+	for i := range gc.game.Players {
+		if gc.game.Players[i].ID == playerID {
+			player = &gc.game.Players[i]
+			break
+		}
+	}
+	if player == nil || player.Type == models.AIPlayer {
+		return false
+	}
+
+	player.Type = models.AIPlayer
+	for i := range gc.game.Room.Players {
+		if gc.game.Room.Players[i].ID == playerID {
+			gc.game.Room.Players[i].Type = models.AIPlayer
+		}
+	}
+	if gc.game.roomManager != nil {
+		gc.game.roomManager.setPlayerType(gc.game.Room.ID, playerID, models.AIPlayer)
+	}
+	delete(gc.game.MissedActions, playerID)
+	delete(gc.game.AFK, playerID)
+	gc.logger().Info("玩家已由AI接管", "player_id", playerID, "reason", reason)
+
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(map[string]interface{}{
+		"type":      "player_takeover",
+		"player_id": playerID,
+	}, gc.locale(), "message", reason, player.Name))
+
+	// 接管后立即补上该玩家在当前阶段（或夜晚当前步骤）尚未执行的动作
+	if player.Alive && len(gc.game.pendingActions(playerID)) > 0 {
+		if err := gc.applyAIAction(*player); err != nil {
+			gc.logger().Warn("AI接管后执行动作失败", "player_id", playerID, "error", err)
+		}
+	}
+	gc.checkPhaseProgress()
+
+	return true
+}
+
 // startPhaseTimer 启动阶段计时器
 func (gc *GameController) startPhaseTimer() {
 	if gc.timer != nil {
 		gc.timer.Stop()
 	}
 
-	// 处理AI玩家的行动
+	// 保存阶段开始时的状态，结算出错时可以回滚到这里
+	gc.recordPhaseSnapshot()
+
+	// 先念出新阶段的旁白，出局的猎人先开枪，开枪可能直接结束对局，之后出局的警长移交警徽
+	phase, round := gc.game.Phase, gc.game.Round
+	gc.narratePhase()
+	if gc.wakeHunter() {
+		return
+	}
+	gc.wakeSheriff()
+
+	// 夜晚按步骤进行，每个步骤单独计时
+	if phase == PhaseNight {
+		gc.startNightStep()
+		return
+	}
+
+	// 处理AI玩家的行动，AI的行动可能直接结束对局或本阶段，此时下一阶段已经开始计时
 	gc.processAIActions()
+	if !gc.game.IsStarted || gc.game.Phase != phase || gc.game.Round != round {
+		return
+	}
+	gc.pushAvailableActions()
 
-	gc.timer = time.NewTimer(time.Duration(gc.game.TimeLeft) * time.Second)
+	gc.armPhaseTimer()
+	gc.armBotTimer()
+	gc.armAbsenceTimer()
+}
 
-	go func() {
-		<-gc.timer.C
-		gc.handlePhaseTimeout()
-	}()
+// armPhaseTimer 按GameState中的截止时间设置当前阶段的计时器，在对局goroutine中执行
+func (gc *GameController) armPhaseTimer() {
+	if gc.timer != nil {
+		gc.timer.Stop()
+	}
+
+	phase, round, step := gc.game.Phase, gc.game.Round, gc.game.NightStep
+	gc.timer = deadlines.AfterFunc(time.Until(time.UnixMilli(gc.game.PhaseEndsAt)), func() {
+		gc.postTimer(func() {
+			gc.withSpan(context.Background(), "game.phase_timeout", func(span *tracing.Span) {
+				span.SetAttr("phase", phase)
+				span.SetAttr("round", round)
+				gc.handlePhaseTimeout(phase, round, step)
+			})
+		})
+	})
+
+	gc.startCountdown()
+}
+
+// countdownTickInterval 阶段倒计时的推送间隔
+const countdownTickInterval = 5 * time.Second
+
+// startCountdown 定期更新剩余时间并向房间推送倒计时，在对局goroutine中执行
+func (gc *GameController) startCountdown() {
+	gc.stopCountdown()
+
+	var timer *wheelTimer
+	timer = deadlines.AfterFunc(countdownTickInterval, func() {
+		gc.postTimer(func() {
+			// 阶段已切换或倒计时已停止
+			if gc.countdown != timer {
+				return
+			}
+			gc.publishCountdown()
+			gc.startCountdown()
+		})
+	})
+	gc.countdown = timer
 }
 
-// handlePhaseTimeout 处理阶段超时
-func (gc *GameController) handlePhaseTimeout() {
-	gc.mutex.Lock()
-	defer gc.mutex.Unlock()
+// stopCountdown 停止倒计时推送，调用方需持有gc.mutex
+func (gc *GameController) stopCountdown() {
+	if gc.countdown != nil {
+		gc.countdown.Stop()
+		gc.countdown = nil
+	}
+}
 
-	// 强制结束当前阶段
+// publishCountdown 按截止时间推送倒计时，在对局goroutine中执行
+func (gc *GameController) publishCountdown() {
+	msg := map[string]interface{}{
+		"type":          "countdown",
+		"phase":         gc.game.Phase,
+		"night_step":    gc.game.NightStep,
+		"round":         gc.game.Round,
+		"time_left":     gc.game.timeLeft(),
+		"phase_ends_at": gc.game.PhaseEndsAt,
+		"state_version": gc.game.StateVersion,
+	}
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, msg)
+}
+
+// handlePhaseTimeout 处理阶段或夜晚步骤超时，在对局goroutine中执行
+func (gc *GameController) handlePhaseTimeout(phase string, round int, step string) {
+	// 阶段或步骤已经切换，计时器已过期
+	if !gc.game.IsStarted || gc.game.Phase != phase || gc.game.Round != round || gc.game.NightStep != step {
+		return
+	}
+
+	// 到时未行动的玩家按配置执行默认动作，夜晚进入下一个步骤，最后一个步骤超时后和其他阶段一样结束
+	gc.applyTimeoutDefaults()
+	if gc.advanceNightStep() {
+		return
+	}
 	gc.endCurrentPhase()
 }
 
 // endCurrentPhase 结束当前阶段
 func (gc *GameController) endCurrentPhase() error {
-	// 转换游戏阶段
-	if err := gc.stateMachine.TransitionPhase(); err != nil {
-		// 检查是否是游戏结束的错误
-		if err.Error() == VillagerWin || err.Error() == WerewolfWin {
-			gc.handleGameEnd(err.Error())
-			return nil
+	// 夜晚最后一个步骤的角色闭眼
+	if gc.game.Phase == PhaseNight && gc.stateMachine.isPhaseComplete() {
+		gc.narrateNightStepEnd()
+	}
+
+	// 转换游戏阶段，结算出错时回滚到本阶段开始，重新等待玩家行动
+	result, err := gc.stateMachine.TransitionPhase()
+	if err != nil {
+		gc.logger().Error("阶段结算失败", "phase", gc.game.Phase, "round", gc.game.Round, "error", err)
+		if rollbackErr := gc.rollbackPhase("resolution_error"); rollbackErr != nil {
+			return err
 		}
-		return err
+		return nil
+	}
+	if tally := gc.stateMachine.takeVoteTally(); tally != nil {
+		gc.lastVote = tally
+		gc.broadcastVoteTally(tally)
+	}
+	if result != GameOngoing {
+		gc.handleGameEnd(result)
+		return nil
 	}
 
 	// 重置计时器
 	gc.startPhaseTimer()
 
+	// 阶段切换后保存快照
+	gc.saveSnapshot()
+
 	// 广播新阶段信息
 	gc.broadcastGameState()
 
@@ -256,35 +702,215 @@ func (gc *GameController) handleGameEnd(result string) {
 	if gc.timer != nil {
 		gc.timer.Stop()
 	}
+	gc.stopBotTimer()
+	gc.stopAbsenceTimer()
+	gc.stopCountdown()
+	gc.setRoomStarted(false)
+
+	if gc.game.roomManager != nil {
+		gc.game.roomManager.deleteSnapshot(gc.game.Room.ID)
+	}
+
+	// 保存对局记录，更新玩家统计，排位对局同时更新积分
+	summary := &gameSummary{}
+	if gc.game.roomManager != nil {
+		players := make([]models.Player, len(gc.game.Players))
+		copy(players, gc.game.Players)
+		endedAt := time.Now().Unix()
+		summary = gc.game.roomManager.recordGame(&models.GameRecord{
+			ID:        gc.gameID(),
+			RoomID:    gc.game.Room.ID,
+			Mode:      gc.game.Room.Mode,
+			Players:   players,
+			Result:    result,
+			Ranked:    gc.game.Room.Ranked,
+			Events:    append([]models.GameEvent(nil), gc.game.Events...),
+			StartedAt: gc.game.StartedAt,
+			EndedAt:   endedAt,
+			Duration:  endedAt - gc.game.StartedAt,
+		})
+	}
 
 	// 广播游戏结果
-	gc.webSocket.BroadcastToRoom(gc.game.Room.ID, map[string]interface{}{
-		"type":    "game_end",
-		"result":  result,
-		"players": gc.game.Players,
+	if result != "" {
+		gc.narrate(NarrationGameEnd, "narration.result."+result)
+	}
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, map[string]interface{}{
+		"type":           "game_end",
+		"result":         result,
+		"players":        gc.game.Players,
+		"rating_changes": summary.RatingChanges,
+		"stats":          summary.Stats,
+		"achievements":   summary.Achievements,
 	})
+
+	// 单独通知玩家新解锁的成就
+	for playerID, achievements := range summary.Achievements {
+		for _, achievement := range achievements {
+			gc.sink.SendToPlayer(playerID, localize(map[string]interface{}{
+				"type":        "achievement_unlocked",
+				"achievement": achievement,
+			}, gc.locale(), "message", "system.achievement_unlocked", achievement.Name))
+		}
+	}
+
+	gc.sendJudgeState()
+	gc.recordOverlay()
+
+	// 保留期结束后释放对局状态，之后只能从对局记录中查看本局
+	gc.scheduleReclaim()
+}
+
+// gameStateMessage 推送给单个玩家或旁观者的game_state消息
+type gameStateMessage struct {
+	Type    string      `json:"type"`
+	Room    models.Room `json:"room"`
+	ViewSeq int64       `json:"view_seq"` // 本次推送的状态序号，之后的game_state_patch据此应用
+	*models.PlayerGameView
 }
 
-// broadcastGameState 广播游戏状态
+// broadcastGameState 向每名玩家推送各自视角的游戏状态，旁观者收到只包含公开信息的状态，法官收到完整的状态
 func (gc *GameController) broadcastGameState() {
-	log.Printf("[广播游戏状态] 房间ID: %s, 阶段: %s, 回合: %d", gc.game.Room.ID, gc.game.Phase, gc.game.Round)
-	log.Printf("[广播游戏状态] 存活玩家: %d, 剩余时间: %d秒", countAlivePlayers(gc.game.Players), gc.game.TimeLeft)
+	gc.logger().Debug("广播游戏状态", "phase", gc.game.Phase, "round", gc.game.Round,
+		"alive", countAlivePlayers(gc.game.Players), "time_left", gc.game.timeLeft())
 
-	// 构建游戏状态消息
-	gameState := map[string]interface{}{
-		"type":       "game_state",
-		"phase":      gc.game.Phase,
-		"round":      gc.game.Round,
-		"time_left":  gc.game.TimeLeft,
-		"players":    gc.game.Players,
-		"is_started": gc.game.IsStarted,
-		"room":       gc.game.Room,
+	span := gc.startSpan("game.broadcast_state")
+	defer span.Finish()
+
+	// 支持增量的连接只收到与上次推送相比有变化的字段
+	gc.viewSeq++
+	recipients := 0
+	defer func() {
+		span.SetAttr("recipients", recipients)
+	}()
+	for _, player := range gc.game.Players {
+		if player.Type == models.AIPlayer {
+			continue
+		}
+		recipients++
+		view, err := gc.game.playerGameView(player.ID)
+		if err != nil {
+			continue
+		}
+		gc.sendState(player.ID, view)
+	}
+
+	if sink, ok := gc.sink.(audienceSink); ok {
+		view, _ := gc.game.playerGameView("")
+		gc.broadcastSpectatorState(sink, view)
 	}
+	gc.sendJudgeState()
+	gc.recordOverlay()
+}
+
+// RecordChat 将对局进行中的聊天消息写入事件日志，to为私聊对象
+func (gc *GameController) RecordChat(playerID, channel, to, message string) {
+	gc.post(func() {
+		if !gc.game.IsStarted {
+			return
+		}
+		// 出局玩家的发言不算作公开发言
+		if player, err := gc.game.GetPlayerStatus(playerID); err == nil && !player.Alive && channel == ChannelRoom {
+			channel = ChannelDead
+		}
+		gc.markActive(playerID)
+		gc.game.recordEvent(models.GameEvent{Type: "chat", PlayerID: playerID, TargetID: to, Channel: channel, Content: message})
+		if channel == ChannelRoom {
+			gc.game.recordSpokenClaim(playerID, message)
+		}
+	})
+}
+
+// IsRunning 对局是否进行中
+func (gc *GameController) IsRunning() bool {
+	gc.mutex.RLock()
+	defer gc.mutex.RUnlock()
+
+	return gc.game.IsStarted
+}
+
+// logger 附带房间ID和对局ID的日志，对局引擎的日志都通过它输出
+func (gc *GameController) logger() *slog.Logger {
+	return slog.With("room_id", gc.game.Room.ID, "game_id", gc.gameID())
+}
+
+// gameID 本局的对局ID，与保存的对局记录ID一致，对局开始前为空
+func (gc *GameController) gameID() string {
+	if gc.game.StartedAt == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s_%d", gc.game.Room.ID, gc.game.StartedAt)
+}
+
+// CurrentPhase 获取当前阶段和回合
+func (gc *GameController) CurrentPhase() (string, int) {
+	gc.mutex.RLock()
+	defer gc.mutex.RUnlock()
+
+	return gc.game.Phase, gc.game.Round
+}
+
+// BuildSnapshot 构建指定玩家视角的完整游戏快照，用于断线重连后恢复界面
+func (gc *GameController) BuildSnapshot(playerID string) (map[string]interface{}, error) {
+	gc.mutex.RLock()
+	defer gc.mutex.RUnlock()
+
+	gc.game.mutex.RLock()
+	defer gc.game.mutex.RUnlock()
+
+	view, err := gc.game.playerGameView(playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var self models.Player
+	for _, player := range view.Players {
+		if player.ID == playerID {
+			self = player
+		}
+	}
+	snapshot := map[string]interface{}{
+		"type":              "resync",
+		"room":              gc.game.Room,
+		"is_started":        view.IsStarted,
+		"phase":             view.Phase,
+		"night_step":        view.NightStep,
+		"round":             view.Round,
+		"time_left":         view.TimeLeft,
+		"phase_ends_at":     view.PhaseEndsAt,
+		"state_version":     view.StateVersion,
+		"view_seq":          gc.viewSeq,
+		"players":           view.Players,
+		"alive_players":     view.AlivePlayers,
+		"self":              self,
+		"deaths":            view.Deaths,
+		"claims":            view.Claims,
+		"pending_actions":   view.Actions,
+		"available_actions": view.AvailableActions,
+	}
+
+	if view.IsStarted {
+		snapshot["role"] = view.Role
+		snapshot["known_roles"] = view.KnownRoles
+		snapshot["teammates"] = view.Teammates
+		snapshot["checks"] = view.Checks
+		if skills, exists := gc.game.Skills[playerID]; exists {
+			snapshot["skills"] = skills
+		}
+	}
+
+	return snapshot, nil
+}
+
+// BuildStatus 构建指定玩家视角的游戏状态，对局未开始时不包含角色
+func (gc *GameController) BuildStatus(playerID string) (*models.PlayerGameView, error) {
+	gc.mutex.RLock()
+	defer gc.mutex.RUnlock()
 
-	log.Printf("[广播游戏状态] 发送状态消息: %+v", gameState)
+	gc.game.mutex.RLock()
+	defer gc.game.mutex.RUnlock()
 
-	// 直接广播游戏状态，不需要额外的包装
-	gc.webSocket.BroadcastToRoom(gc.game.Room.ID, gameState)
+	return gc.game.playerGameView(playerID)
 }
 
 // countAlivePlayers 统计存活玩家数量
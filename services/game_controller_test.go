@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// newBenchGame 开始一局两名真人玩家、其余由AI补位的对局，并推进到指定阶段，返回对局和两名真人玩家的ID
+func newBenchGame(b *testing.B, players int, phase string) (*GameController, string, string) {
+	b.Helper()
+
+	gc := newAIGame(b, 1, players)
+	first, second := "human_1", "human_2"
+	for _, id := range []string{first, second} {
+		gc.game.Players = append(gc.game.Players, models.Player{ID: id, Name: id, Type: models.HumanPlayer, Alive: true})
+	}
+	if err := gc.StartGame(first); err != nil {
+		b.Fatalf("开始对局失败: %v", err)
+	}
+
+	// 真人玩家不行动，每个阶段到时后推进，夜晚出局的真人玩家由法官复活
+	for i := 0; i < 10; i++ {
+		reached := false
+		gc.do(func() {
+			gc.game.revive(first)
+			gc.game.revive(second)
+			if reached = gc.game.IsStarted && gc.game.Phase == phase; reached {
+				return
+			}
+			gc.game.PhaseEndsAt = 1
+			gc.handlePhaseTimeout(gc.game.Phase, gc.game.Round, gc.game.NightStep)
+		})
+		if reached {
+			return gc, first, second
+		}
+	}
+	b.Fatalf("没有进入%s阶段", phase)
+	return nil, "", ""
+}
+
+// BenchmarkProcessActionDiscuss 白天发言，包括内容过滤、校验、记录事件和推送状态
+func BenchmarkProcessActionDiscuss(b *testing.B) {
+	gc, playerID, _ := newBenchGame(b, 12, PhaseDay)
+	defer gc.Close()
+
+	action := models.GameAction{RoomID: gc.game.Room.ID, PlayerID: playerID, Type: "discuss", Content: "我是预言家，昨晚验了3号是狼人"}
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := gc.ProcessAction(ctx, action, AuditSourceWS, "bench"); err != nil {
+			b.Fatalf("发言失败: %v", err)
+		}
+	}
+}
+
+// BenchmarkProcessActionVote 重复投票，另一名真人玩家不投票，投票阶段不会结束
+func BenchmarkProcessActionVote(b *testing.B) {
+	gc, playerID, other := newBenchGame(b, 12, PhaseVote)
+	defer gc.Close()
+
+	action := models.GameAction{RoomID: gc.game.Room.ID, PlayerID: playerID, Type: "vote", TargetID: other}
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := gc.ProcessAction(ctx, action, AuditSourceWS, "bench"); err != nil {
+			b.Fatalf("投票失败: %v", err)
+		}
+	}
+}
+
+// BenchmarkBroadcastGameState 为每名真人玩家和旁观者生成各自视角的状态并推送
+func BenchmarkBroadcastGameState(b *testing.B) {
+	gc, _, _ := newBenchGame(b, 12, PhaseDay)
+	defer gc.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gc.do(gc.broadcastGameState)
+	}
+}
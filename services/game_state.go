@@ -1,7 +1,8 @@
 package services
 
 import (
-	"errors"
+	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 
@@ -10,41 +11,87 @@ import (
 
 // GameState 游戏状态
 type GameState struct {
-	RoomID      string                  `json:"room_id"`
-	Room        models.Room             `json:"room"`
-	Players     []models.Player         `json:"players"`
-	Phase       string                  `json:"phase"`
-	Round       int                     `json:"round"`
-	Actions     []models.GameAction     `json:"actions"`
-	TimeLeft    int                     `json:"time_left"`
-	IsStarted   bool                    `json:"is_started"`
-	Skills      map[string]*WitchSkills `json:"skills"` // 玩家技能状态
+	RoomID      string                            `json:"room_id"`
+	Room        models.Room                       `json:"room"`
+	Players     []models.Player                   `json:"players"`
+	Phase       string                            `json:"phase"`
+	NightStep   string                            `json:"night_step"` // 夜晚当前进行的步骤，见nightSteps，白天和投票阶段为空
+	Round       int                               `json:"round"`
+	Actions     []models.GameAction               `json:"actions"`
+	PhaseEndsAt int64                             `json:"phase_ends_at"` // 当前阶段截止时间的毫秒时间戳，随快照保存，未开始计时时为0
+	IsStarted   bool                              `json:"is_started"`
+	Skills      map[string]*WitchSkills           `json:"skills"`       // 玩家技能状态
+	StartedAt   int64                             `json:"started_at"`   // 对局开始时间
+	Events      []models.GameEvent                `json:"events"`       // 对局事件日志
+	KnownRoles  map[string]map[string]models.Role `json:"known_roles"`  // 玩家已知的其他玩家角色
+	Claims      []models.RoleClaim                `json:"claims"`       // 玩家公开声明的身份，AI推理和发言共用
+	PendingShot string                            `json:"pending_shot"` // 可以开枪的出局猎人，开枪时限到下一次阶段切换
+	// Sheriff 当前的警长，投票时有1.5票，没有警长时为空
+	Sheriff string `json:"sheriff,omitempty"`
+	// PendingBadge 可以移交或撕毁警徽的出局警长，时限到下一次阶段切换，逾期视为撕毁
+	PendingBadge string `json:"pending_badge,omitempty"`
+	// DeathCauses 已出局玩家的出局原因，猎人被毒死时不能开枪等规则据此判断
+	DeathCauses map[string]models.DeathCause `json:"death_causes,omitempty"`
+	Seed        int64                        `json:"seed"`   // 随机数种子，角色分配和AI决策都由它决定
+	Result      string                       `json:"result"` // 对局结果，对局进行中为空
+	// StateVersion 对局状态版本，开局、阶段切换、玩家出局和对局结束时递增，提交的动作带有过期版本时拒绝
+	StateVersion int64 `json:"state_version"`
+	// VoteWeights 票权与常人不同的玩家，未列出的玩家为1票，例如警长1.5票、翻牌后的白痴0票
+	VoteWeights map[string]float64 `json:"vote_weights,omitempty"`
+	// VoteBonus 本轮投票中玩家额外获得的票数，例如被乌鸦标记的玩家加1票，投票结算后清空
+	VoteBonus map[string]float64 `json:"vote_bonus,omitempty"`
+	// MissedActions 真人玩家连续到时未完成必要动作的次数，玩家有任何操作时清零
+	MissedActions map[string]int `json:"missed_actions,omitempty"`
+	// AFK 被判定为挂机的玩家，轮到其行动时直接放弃
+	AFK         map[string]bool `json:"afk,omitempty"`
+	rng         *rand.Rand
+	learning    *AILearning // 对局开始时载入的历史汇总，AI据此调整起跳倾向和对玩家的判断
 	mutex       sync.RWMutex
 	roomManager *RoomManager
 }
 
 // NewGameState 创建游戏状态实例
 func NewGameState(room models.Room, rm *RoomManager) *GameState {
+	// 对局状态持有玩家列表的副本，分配角色和出局不会修改房间中的玩家
+	players := append([]models.Player(nil), room.Players...)
 	return &GameState{
 		Room:        room,
-		Players:     room.Players,
+		Players:     players,
 		Phase:       PhaseNight,
 		Round:       1,
 		Actions:     make([]models.GameAction, 0),
-		TimeLeft:    120, // 每个阶段默认120秒
 		IsStarted:   false,
 		Skills:      make(map[string]*WitchSkills),
+		KnownRoles:  make(map[string]map[string]models.Role),
 		roomManager: rm,
 	}
 }
 
+// seedRandom 设置对局的随机数种子，seed为0时按当前时间生成
+func (gs *GameState) seedRandom(seed int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	gs.Seed = seed
+	gs.rng = rand.New(rand.NewSource(seed))
+}
+
+// random 获取对局的随机数生成器，调用方需持有锁
+// 从快照恢复的对局按记录的种子重新创建，恢复后的随机序列与原对局不再一致
+func (gs *GameState) random() *rand.Rand {
+	if gs.rng == nil {
+		gs.seedRandom(gs.Seed)
+	}
+	return gs.rng
+}
+
 // StartGame 开始游戏
 func (gs *GameState) StartGame() error {
 	gs.mutex.Lock()
 	defer gs.mutex.Unlock()
 
 	if len(gs.Players) < gs.Room.MinPlayers {
-		return errors.New("玩家人数不足")
+		return NewError(CodeNotEnoughPlayers, "玩家人数不足")
 	}
 
 	// 分配角色
@@ -53,32 +100,83 @@ func (gs *GameState) StartGame() error {
 	// 初始化技能状态
 	gs.initializeSkills()
 
+	// 初始化玩家已知信息
+	gs.initializeKnownRoles()
+
 	// 初始化游戏状态
 	gs.Phase = PhaseNight
 	gs.Round = 1
-	gs.TimeLeft = 120
 	gs.IsStarted = true
+	gs.StartedAt = time.Now().Unix()
 	gs.Actions = make([]models.GameAction, 0)
+	gs.Events = make([]models.GameEvent, 0)
+	gs.Claims = make([]models.RoleClaim, 0)
+	gs.PendingShot = ""
+	gs.Sheriff = ""
+	gs.PendingBadge = ""
+	gs.VoteWeights = nil
+	gs.Result = ""
+	gs.recordEvent(models.GameEvent{Type: "game_start", Content: strconv.FormatInt(gs.Seed, 10)})
+	gs.beginNight()
 
 	return nil
 }
 
+// singleChoiceActions 每个玩家在同一阶段只保留最后一次的动作类型
+var singleChoiceActions = map[string]bool{
+	"kill":    true,
+	"check":   true,
+	"save":    true,
+	"poison":  true,
+	"protect": true,
+	"pass":    true,
+	"vote":    true,
+	"elect":   true,
+}
+
 // AddAction 添加游戏动作
 func (gs *GameState) AddAction(action models.GameAction) error {
 	gs.mutex.Lock()
 	defer gs.mutex.Unlock()
 
+	return gs.addAction(action, false)
+}
+
+// addAction 校验并记录动作，system为true表示动作由系统代为生成，调用方需持有锁
+func (gs *GameState) addAction(action models.GameAction, system bool) error {
 	if !gs.IsStarted {
 		return ErrGameNotStarted
 	}
+	// 版本为0表示不校验：AI和系统代为生成的动作、以及不带版本的旧客户端总是基于当前状态提交
+	if action.StateVersion != 0 && action.StateVersion != gs.StateVersion {
+		return ErrStateChanged
+	}
 
-	// 验证动作是否有效
-	if !isValidAction(gs, action) {
-		return errors.New("无效的动作")
+	// 出局玩家只有猎人可以开枪，警长可以移交警徽
+	if !afterDeathActions[action.Type] {
+		for _, player := range gs.Players {
+			if player.ID == action.PlayerID && !player.Alive {
+				return ErrPlayerDead
+			}
+		}
+	}
+
+	// 验证动作是否有效，出局猎人开枪和警长移交警徽单独校验
+	switch {
+	case action.Type == "shoot":
+		if err := gs.validateShot(action); err != nil {
+			return err
+		}
+	case action.Type == "pass_badge" || action.Type == "tear_badge":
+		if err := gs.validateBadge(action); err != nil {
+			return err
+		}
+	case !isValidAction(gs, action):
+		return NewError(CodeNotYourTurn, "当前无法执行该动作")
 	}
 
 	// 验证目标玩家是否可以被选择
-	if action.TargetID != "" {
+	if action.TargetID != "" && !afterDeathActions[action.Type] {
 		targetValid := false
 		for _, player := range gs.Players {
 			if player.ID == action.TargetID && player.Alive {
@@ -93,26 +191,59 @@ func (gs *GameState) AddAction(action models.GameAction) error {
 					} else {
 						targetValid = true
 					}
+				case PhaseDay:
+					// 白天阶段，只有白狼王自爆需要选择目标
+					targetValid = action.Type == "explode"
 				case PhaseVote:
 					// 投票阶段，所有存活玩家都可以被投票
 					targetValid = true
+				case PhaseSheriffVote:
+					// 警长投票只能投给上警的候选人
+					targetValid = gs.isSheriffCandidate(player.ID)
 				}
 				break
 			}
 		}
 
 		if !targetValid {
-			return errors.New("无效的目标玩家")
+			return NewError(CodeInvalidTarget, "无效的目标玩家")
 		}
 	}
 
-	// 添加时间戳
-	action.Timestamp = time.Now().Unix()
-	gs.Actions = append(gs.Actions, action)
+	var err error
+	switch action.Type {
+	case "save", "poison":
+		err = gs.validateWitchAction(action)
+	case "protect":
+		err = gs.validateProtect(action)
+	case "link":
+		err = gs.validateLink(action)
+	case "explode":
+		if action.TargetID == "" || action.TargetID == action.PlayerID {
+			err = NewError(CodeInvalidTarget, "请选择自爆时带走的玩家")
+		}
+	}
+	if err != nil {
+		return err
+	}
 
+	gs.applyAction(action, system)
 	return nil
 }
 
+// appendAction 记录一个已通过校验的动作，同一阶段只能选择一次的动作以最后一次提交为准，例如玩家在多个设备上先后投票
+func (gs *GameState) appendAction(action models.GameAction) {
+	if singleChoiceActions[action.Type] {
+		for i, existing := range gs.Actions {
+			if existing.PlayerID == action.PlayerID && existing.Type == action.Type {
+				gs.Actions[i] = action
+				return
+			}
+		}
+	}
+	gs.Actions = append(gs.Actions, action)
+}
+
 // GetPlayerStatus 获取玩家状态
 func (gs *GameState) GetPlayerStatus(playerID string) (*models.Player, error) {
 	gs.mutex.RLock()
@@ -124,22 +255,179 @@ func (gs *GameState) GetPlayerStatus(playerID string) (*models.Player, error) {
 		}
 	}
 
-	return nil, errors.New("玩家不存在")
+	return nil, NewError(CodePlayerNotFound, "玩家不存在")
 }
 
-// UpdateTimeLeft 更新剩余时间
-func (gs *GameState) UpdateTimeLeft(seconds int) {
-	gs.mutex.Lock()
-	defer gs.mutex.Unlock()
+// PlayersSnapshot 获取对局中所有玩家的副本
+func (gs *GameState) PlayersSnapshot() []models.Player {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
 
-	gs.TimeLeft = seconds
+	players := make([]models.Player, len(gs.Players))
+	copy(players, gs.Players)
+	return players
 }
 
-// GetAvailableActions 获取玩家可用动作
-func (gs *GameState) GetAvailableActions(playerID string) []string {
+// phaseDuration 每个阶段的时长
+const phaseDuration = 120 * time.Second
+
+// startPhaseClock 从现在开始计算当前阶段的截止时间
+func (gs *GameState) startPhaseClock(d time.Duration) {
+	gs.PhaseEndsAt = time.Now().Add(d).UnixMilli()
+}
+
+// timeLeft 当前阶段剩余秒数，未开始计时时为0
+func (gs *GameState) timeLeft() int {
+	if gs.PhaseEndsAt == 0 {
+		return 0
+	}
+	return max(0, int(time.Until(time.UnixMilli(gs.PhaseEndsAt)).Seconds()))
+}
+
+// phaseExpired 当前阶段是否已到截止时间
+func (gs *GameState) phaseExpired() bool {
+	return gs.PhaseEndsAt > 0 && time.Now().UnixMilli() >= gs.PhaseEndsAt
+}
+
+// GetAvailableActions 获取玩家在当前阶段可以执行的动作及可选目标
+func (gs *GameState) GetAvailableActions(playerID string) []models.ActionOption {
 	gs.mutex.RLock()
 	defer gs.mutex.RUnlock()
 
+	return gs.availableActions(playerID)
+}
+
+// initializeSkills 初始化玩家技能状态
+func (gs *GameState) initializeSkills() {
+	for _, player := range gs.Players {
+		if player.Role == models.Witch {
+			gs.Skills[player.ID] = &WitchSkills{
+				SavePotion:   SkillStatus{Used: false},
+				PoisonPotion: SkillStatus{Used: false},
+			}
+		}
+	}
+}
+
+// stateChangingEvents 会让客户端看到的局面失效的事件，记录时对局状态版本递增
+var stateChangingEvents = map[string]bool{
+	"game_start":   true,
+	"phase_change": true,
+	"night_step":   true,
+	"death":        true,
+	"revive":       true,
+	"game_end":     true,
+}
+
+// recordEvent 记录对局事件，自动补充序号、回合、阶段和时间戳，并在局面变化时递增状态版本
+func (gs *GameState) recordEvent(event models.GameEvent) {
+	if stateChangingEvents[event.Type] {
+		gs.StateVersion++
+	}
+	event.Seq = len(gs.Events) + 1
+	event.Round = gs.Round
+	event.Phase = gs.Phase
+	event.Timestamp = time.Now().UnixMilli()
+	gs.Events = append(gs.Events, event)
+}
+
+// recordDeaths 为新死亡的玩家记录死亡事件，在阶段结算后调用，情侣一方死亡时另一方随之殉情
+func (gs *GameState) recordDeaths() {
+	gs.resolveLovers()
+
+	// 被法官复活的玩家之前的死亡事件不再算数，再次出局时重新记录
+	recorded := make(map[string]bool)
+	for _, event := range gs.Events {
+		switch event.Type {
+		case "death":
+			recorded[event.PlayerID] = true
+		case "revive":
+			delete(recorded, event.PlayerID)
+		}
+	}
+
+	for _, player := range gs.Players {
+		if !player.Alive && !recorded[player.ID] {
+			gs.recordEvent(models.GameEvent{Type: "death", PlayerID: player.ID, Cause: gs.DeathCauses[player.ID]})
+			// 出局的警长在下一次阶段切换前决定警徽的去向
+			if player.ID == gs.Sheriff {
+				gs.setSheriff("")
+				gs.PendingBadge = player.ID
+			}
+		}
+	}
+}
+
+// kill 玩家出局并记录出局原因，已出局的玩家保留最初的原因，返回玩家是否因此出局。
+// 死亡事件在阶段结算后由recordDeaths统一记录，调用方需持有锁
+func (gs *GameState) kill(playerID string, cause models.DeathCause) bool {
+	for i := range gs.Players {
+		if gs.Players[i].ID != playerID || !gs.Players[i].Alive {
+			continue
+		}
+		gs.Players[i].Alive = false
+		if gs.DeathCauses == nil {
+			gs.DeathCauses = make(map[string]models.DeathCause)
+		}
+		gs.DeathCauses[playerID] = cause
+		return true
+	}
+	return false
+}
+
+// revive 已出局的玩家重新回到对局并清除出局原因，返回玩家是否因此复活，
+// 只用于法官的人工裁决，调用方需持有锁
+func (gs *GameState) revive(playerID string) bool {
+	for i := range gs.Players {
+		if gs.Players[i].ID != playerID || gs.Players[i].Alive {
+			continue
+		}
+		gs.Players[i].Alive = true
+		delete(gs.DeathCauses, playerID)
+		return true
+	}
+	return false
+}
+
+// initializeKnownRoles 初始化玩家已知信息，狼人互相知道身份
+func (gs *GameState) initializeKnownRoles() {
+	gs.KnownRoles = make(map[string]map[string]models.Role)
+	for _, player := range gs.Players {
+		gs.KnownRoles[player.ID] = map[string]models.Role{player.ID: player.Role}
+	}
+
+	for _, wolf := range gs.Players {
+		if wolf.Role != models.Werewolf && wolf.Role != models.WhiteWolf {
+			continue
+		}
+		for _, other := range gs.Players {
+			if other.Role == models.Werewolf || other.Role == models.WhiteWolf {
+				gs.KnownRoles[wolf.ID][other.ID] = other.Role
+			}
+		}
+	}
+}
+
+// recordKnownRole 记录玩家获知的其他玩家角色
+func (gs *GameState) recordKnownRole(playerID, targetID string) {
+	if gs.KnownRoles == nil {
+		gs.KnownRoles = make(map[string]map[string]models.Role)
+	}
+
+	for _, target := range gs.Players {
+		if target.ID != targetID {
+			continue
+		}
+		if gs.KnownRoles[playerID] == nil {
+			gs.KnownRoles[playerID] = make(map[string]models.Role)
+		}
+		gs.KnownRoles[playerID][targetID] = target.Role
+		return
+	}
+}
+
+// pendingActions 获取玩家在当前阶段尚未执行的动作
+func (gs *GameState) pendingActions(playerID string) []string {
 	var player *models.Player
 	for i := range gs.Players {
 		if gs.Players[i].ID == playerID {
@@ -148,21 +436,64 @@ func (gs *GameState) GetAvailableActions(playerID string) []string {
 		}
 	}
 
-	if player == nil || !player.Alive {
-		return nil
+	if !gs.IsStarted || player == nil || !player.Alive {
+		return []string{}
 	}
 
-	return getAvailableActions(gs)
-}
+	var required []string
+	switch gs.Phase {
+	case PhaseNight:
+		// 夜晚只有当前步骤的角色需要行动
+		if !gs.inNightStep(player.Role) || gs.nightActionDone(*player) {
+			return []string{}
+		}
+		switch player.Role {
+		case models.Werewolf, models.WhiteWolf:
+			required = []string{"kill"}
+		case models.Seer:
+			required = []string{"check"}
+		case models.Witch:
+			canSave, canPoison := gs.witchCanUse(playerID)
+			if canSave {
+				required = append(required, "save")
+			}
+			if canPoison {
+				required = append(required, "poison")
+			}
+		case models.Guard:
+			required = []string{"protect"}
+		case models.Cupid:
+			if gs.Round == 1 {
+				required = []string{"link"}
+			}
+		}
+	case PhaseCampaign:
+		// 选择不上警的玩家没有必要动作
+		if !gs.hasAction(playerID, "decline") {
+			required = []string{"campaign"}
+		}
+	case PhaseSheriffVote:
+		if !gs.isSheriffCandidate(playerID) {
+			required = []string{"elect"}
+		}
+	case PhaseDay:
+		required = []string{"discuss"}
+	case PhaseVote:
+		required = []string{"vote"}
+	}
 
-// initializeSkills 初始化玩家技能状态
-func (gs *GameState) initializeSkills() {
-	for _, player := range gs.Players {
-		if player.Role == models.Witch {
-			gs.Skills[player.ID] = &WitchSkills{
-				SavePotion:   SkillStatus{Used: false},
-				PoisonPotion: SkillStatus{Used: false},
+	pending := make([]string, 0, len(required))
+	for _, actionType := range required {
+		done := false
+		for _, action := range gs.Actions {
+			if action.PlayerID == playerID && action.Type == actionType {
+				done = true
+				break
 			}
 		}
+		if !done {
+			pending = append(pending, actionType)
+		}
 	}
+	return pending
 }
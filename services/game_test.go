@@ -0,0 +1,320 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/qianlnk/werewolf/logging"
+	"github.com/qianlnk/werewolf/models"
+)
+
+// TestMain 测试中只输出警告以上的日志，对局和连接的常规日志会淹没测试和基准测试的结果
+func TestMain(m *testing.M) {
+	if err := logging.Setup("warn", logging.FormatText); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// discardSink 丢弃所有对局消息的接收方
+type discardSink struct{}
+
+func (discardSink) BroadcastToRoom(roomID string, message interface{}) {}
+
+func (discardSink) SendToPlayer(playerID string, message interface{}) error { return nil }
+
+// newAIGame 创建一局全部由AI补位的对局，seed为0时每局随机生成种子
+func newAIGame(t testing.TB, seed int64, players int) *GameController {
+	t.Helper()
+
+	rm := NewRoomManager(nil)
+	rm.SetGameSeed(seed)
+	room := models.Room{
+		ID:         fmt.Sprintf("room_%s", t.Name()),
+		Name:       "test",
+		Mode:       models.StandardMode,
+		MaxPlayers: players,
+		MinPlayers: boardMinPlayers(models.StandardMode, nil),
+		AIFill:     players,
+		Players:    make([]models.Player, 0),
+	}
+	return NewGameController(NewGameState(room, rm), discardSink{})
+}
+
+// playToEnd 由hostID开始对局并让每个阶段立即到时，直到对局结束，返回对局结果
+func playToEnd(t testing.TB, gc *GameController, hostID string) string {
+	t.Helper()
+
+	if err := gc.StartGame(hostID); err != nil {
+		t.Fatalf("开始对局失败: %v", err)
+	}
+	return finishGame(t, gc)
+}
+
+// finishGame 让进行中对局的每个阶段立即到时，直到对局结束，返回对局结果
+func finishGame(t testing.TB, gc *GameController) string {
+	t.Helper()
+
+	result := ""
+	for i := 0; i < 200; i++ {
+		running := gc.do(func() {
+			if gc.game.IsStarted {
+				gc.game.PhaseEndsAt = 1
+				gc.handlePhaseTimeout(gc.game.Phase, gc.game.Round, gc.game.NightStep)
+			}
+			if !gc.game.IsStarted {
+				result = gc.game.Result
+			}
+		})
+		// 结束的对局已被释放，控制器的goroutine退出后不再修改对局状态
+		if !running {
+			<-gc.stopped
+			return gc.game.Result
+		}
+		if result != "" {
+			return result
+		}
+	}
+	t.Fatal("对局在200个阶段内没有结束")
+	return ""
+}
+
+// gameTranscript 按座位号记录对局的角色、AI性格和所有动作，AI玩家的ID每局不同，不参与比较
+func gameTranscript(gc *GameController) []string {
+	seats := make(map[string]int)
+	var transcript []string
+	for i, player := range gc.game.Players {
+		seats[player.ID] = i + 1
+		transcript = append(transcript, fmt.Sprintf("seat %d: %s %s", i+1, player.Role, player.Personality))
+	}
+	for _, event := range gc.game.Events {
+		if event.Type != "action" && event.Type != "death" {
+			continue
+		}
+		transcript = append(transcript, fmt.Sprintf("round %d %s: %s %d -> %d %q",
+			event.Round, event.Phase, event.Type+"/"+event.Action, seats[event.PlayerID], seats[event.TargetID], event.Content))
+	}
+	return append(transcript, "result: "+gc.game.Result)
+}
+
+func TestSeededGameIsDeterministic(t *testing.T) {
+	const seed = 20240601
+
+	var transcripts [2][]string
+	for i := range transcripts {
+		gc := newAIGame(t, seed, 9)
+		playToEnd(t, gc, "")
+		transcripts[i] = gameTranscript(gc)
+		gc.Close()
+	}
+
+	first, second := transcripts[0], transcripts[1]
+	if len(first) != len(second) {
+		t.Fatalf("相同种子的两局长度不同: %d != %d\n%v\n%v", len(first), len(second), first, second)
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("相同种子的两局在第%d条出现差异:\n%s\n%s", i, first[i], second[i])
+		}
+	}
+}
+
+func TestSeedChangesRoleAssignment(t *testing.T) {
+	roles := func(seed int64) string {
+		gc := newAIGame(t, seed, 9)
+		defer gc.Close()
+		if err := gc.StartGame(""); err != nil {
+			t.Fatalf("开始对局失败: %v", err)
+		}
+		gc.mutex.RLock()
+		defer gc.mutex.RUnlock()
+		return fmt.Sprint(gameTranscript(gc)[:9])
+	}
+
+	if roles(1) != roles(1) {
+		t.Fatal("相同种子的角色分配不同")
+	}
+	for seed := int64(2); seed < 10; seed++ {
+		if roles(seed) != roles(1) {
+			return
+		}
+	}
+	t.Fatal("不同种子的角色分配全部相同")
+}
+
+func TestSheriffElection(t *testing.T) {
+	elected := false
+	for seed := int64(1); seed <= 10; seed++ {
+		gc := newAIGame(t, seed, 9)
+		gc.game.Room.Sheriff = true
+		playToEnd(t, gc, "")
+
+		campaign, results := false, 0
+		for _, event := range gc.game.Events {
+			switch {
+			case event.Type == "phase_change" && event.Content == PhaseCampaign:
+				campaign = true
+			case event.Type == "sheriff" && event.Content == SheriffElected:
+				results++
+				elected = elected || event.PlayerID != ""
+			}
+		}
+		if !campaign || results != 1 {
+			t.Fatalf("种子%d：应当竞选一次警长，实际进入竞选阶段%v，竞选结果%d次", seed, campaign, results)
+		}
+		if gc.game.Sheriff != "" && gc.game.VoteWeights[gc.game.Sheriff] != sheriffVoteWeight {
+			t.Fatalf("种子%d：警长%s的票权为%v", seed, gc.game.Sheriff, gc.game.VoteWeights[gc.game.Sheriff])
+		}
+		gc.Close()
+	}
+	if !elected {
+		t.Fatal("10局中没有选出过警长")
+	}
+}
+
+func TestPlayerGameViewHidesLovers(t *testing.T) {
+	room := models.Room{ID: "room_lovers", Mode: models.StandardMode, Players: []models.Player{
+		{ID: "p1", Role: models.Villager, Alive: true, IsLover: true},
+		{ID: "p2", Role: models.Werewolf, Alive: true, IsLover: true},
+		{ID: "p3", Role: models.Werewolf, Alive: true},
+		{ID: "p4", Role: models.Seer, Alive: false},
+	}}
+	gs := NewGameState(room, nil)
+	gs.IsStarted = true
+	gs.recordKnownRole("p1", "p2")
+	gs.recordKnownRole("p2", "p1")
+	gs.recordKnownRole("p3", "p2")
+
+	lovers := func(viewer string) []string {
+		view, err := gs.playerGameView(viewer)
+		if err != nil {
+			t.Fatalf("生成%q的视角失败: %v", viewer, err)
+		}
+		var ids []string
+		for _, player := range view.Players {
+			if player.IsLover {
+				ids = append(ids, player.ID)
+			}
+		}
+		return ids
+	}
+
+	// 情侣双方互相可见，同伴狼人知道角色但不知道情侣身份，旁观者都不可见
+	for viewer, want := range map[string]string{"p1": "[p1 p2]", "p2": "[p1 p2]", "p3": "[]", "": "[]"} {
+		if got := fmt.Sprint(lovers(viewer)); got != want {
+			t.Errorf("%q看到的情侣为%s，应为%s", viewer, got, want)
+		}
+	}
+
+	// 出局的情侣公开
+	gs.Players[0].Alive = false
+	if got := fmt.Sprint(lovers("p3")); got != "[p1]" {
+		t.Errorf("p3看到的情侣为%s，应为[p1]", got)
+	}
+	if !room.Players[0].Alive {
+		t.Error("对局状态修改了房间中的玩家")
+	}
+}
+
+func TestRebuildMatchesLiveGame(t *testing.T) {
+	gc := newAIGame(t, 7, 9)
+	playToEnd(t, gc, "")
+	defer gc.Close()
+
+	record := &models.GameRecord{RoomID: gc.game.Room.ID, Mode: gc.game.Room.Mode, Players: gc.game.Players, Events: gc.game.Events}
+	rebuilt := RebuildGameState(record, 0)
+
+	if rebuilt.Result != gc.game.Result || rebuilt.StateVersion != gc.game.StateVersion {
+		t.Fatalf("重放结果%q版本%d，对局结果%q版本%d", rebuilt.Result, rebuilt.StateVersion, gc.game.Result, gc.game.StateVersion)
+	}
+	if got, want := fmt.Sprint(rebuilt.KnownRoles), fmt.Sprint(gc.game.KnownRoles); got != want {
+		t.Fatalf("重放的已知角色与对局不同:\n%s\n%s", got, want)
+	}
+	// AI发言附带的查验结果不在事件日志中，只比较声明的身份
+	claimedRoles := func(claims []models.RoleClaim) string {
+		var roles []string
+		for _, claim := range claims {
+			roles = append(roles, claim.PlayerID+":"+string(claim.Role))
+		}
+		return fmt.Sprint(roles)
+	}
+	if got, want := claimedRoles(rebuilt.Claims), claimedRoles(gc.game.Claims); got != want {
+		t.Fatalf("重放的身份声明与对局不同:\n%s\n%s", got, want)
+	}
+	for i, player := range rebuilt.Players {
+		if player.Alive != gc.game.Players[i].Alive {
+			t.Fatalf("重放后%s的存活状态与对局不同", player.ID)
+		}
+	}
+}
+
+func TestConcurrentJoinsReachGame(t *testing.T) {
+	rm := NewRoomManager(nil)
+	room, err := rm.CreateRoom("test", models.StandardMode, 12, false, false, "", "", nil, 0, nil, false, "")
+	if err != nil {
+		t.Fatalf("创建房间失败: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			player := models.Player{ID: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("p%d", i), Type: models.BotPlayer, Alive: true}
+			if err := rm.JoinRoom(room.ID, player); err != nil {
+				t.Errorf("加入房间失败: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	gc, exists := rm.GetGameController(room.ID)
+	if !exists {
+		t.Fatal("房间没有游戏控制器")
+	}
+	defer gc.Close()
+	seated := 0
+	gc.do(func() { seated = len(gc.game.Players) })
+	if seated != 12 {
+		t.Fatalf("对局中有%d名玩家，应为12名", seated)
+	}
+}
+
+func TestStateVersionCheck(t *testing.T) {
+	gc := newAIGame(t, 3, 9)
+	defer gc.Close()
+	if err := gc.StartGame(""); err != nil {
+		t.Fatalf("开始对局失败: %v", err)
+	}
+
+	gc.do(func() {
+		gs := gc.game
+		var action models.GameAction
+		gs.mutex.RLock()
+		for _, player := range gs.Players {
+			if options := gs.availableActions(player.ID); len(options) > 0 {
+				action = models.GameAction{Type: options[0].Type, PlayerID: player.ID, RoomID: gs.Room.ID}
+				if len(options[0].Targets) > 0 {
+					action.TargetID = options[0].Targets[0]
+				}
+				break
+			}
+		}
+		version := gs.StateVersion
+		gs.mutex.RUnlock()
+		if action.PlayerID == "" {
+			t.Fatal("开局后没有可以行动的玩家")
+		}
+
+		action.StateVersion = version + 1
+		if err := gs.AddAction(action); err != ErrStateChanged {
+			t.Fatalf("版本不一致的动作应被拒绝，实际返回%v", err)
+		}
+		action.StateVersion = 0
+		if err := gs.AddAction(action); err != nil {
+			t.Fatalf("不带版本的动作不应校验版本，实际返回%v", err)
+		}
+	})
+}
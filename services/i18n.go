@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Locale 服务端生成文本使用的语言
+type Locale string
+
+// 支持的语言，房间和连接未指定时使用DefaultLocale
+const (
+	LocaleZH      Locale = "zh"
+	LocaleEN      Locale = "en"
+	DefaultLocale        = LocaleZH
+)
+
+var ErrInvalidLocale = NewError(CodeInvalidLocale, "不支持的语言")
+
+// ParseLocale 解析语言标签或Accept-Language头，例如 en-US、zh-CN,zh;q=0.9,en;q=0.8，
+// 按出现顺序返回第一个支持的语言，都不支持时返回空
+func ParseLocale(value string) Locale {
+	for _, tag := range strings.Split(value, ",") {
+		tag, _, _ = strings.Cut(strings.TrimSpace(tag), ";")
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if locale := Locale(base); catalog[locale] != nil {
+			return locale
+		}
+	}
+	return ""
+}
+
+// validLocale 检查房间的语言，空字符串表示使用默认语言
+func validLocale(locale Locale) bool {
+	return locale == "" || catalog[locale] != nil
+}
+
+// orDefault 未指定语言时使用默认语言
+func (l Locale) orDefault() Locale {
+	if l == "" {
+		return DefaultLocale
+	}
+	return l
+}
+
+// Translate 生成消息键在指定语言下的文本，params按顺序填入文本中的格式占位符；
+// 该语言缺少这条消息时使用默认语言，都没有时返回消息键
+func Translate(locale Locale, key string, params ...interface{}) string {
+	format, exists := catalog[locale.orDefault()][key]
+	if !exists {
+		if format, exists = catalog[DefaultLocale][key]; !exists {
+			return key
+		}
+	}
+	if len(params) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, params...)
+}
+
+// localize 在消息的field字段写入按语言生成的文本，同时附带消息键key和参数params，
+// 客户端可以据此按玩家自己的语言重新翻译
+func localize(msg map[string]interface{}, locale Locale, field, key string, params ...interface{}) map[string]interface{} {
+	msg[field] = Translate(locale, key, params...)
+	msg["key"] = key
+	if len(params) > 0 {
+		msg["params"] = params
+	}
+	return msg
+}
+
+// joinNames 按语言的习惯连接多个名称
+func joinNames(locale Locale, names []string) string {
+	if locale.orDefault() == LocaleEN {
+		return strings.Join(names, ", ")
+	}
+	return strings.Join(names, "、")
+}
+
+// joinSentences 按语言的习惯把多个句子连成一段话
+func joinSentences(locale Locale, sentences []string) string {
+	if locale.orDefault() == LocaleEN {
+		return strings.Join(sentences, ". ") + "."
+	}
+	return strings.Join(sentences, "。") + "。"
+}
+
+// LocalizedError 错误在指定语言下的文本：带错误码的错误按错误码翻译，默认语言下和没有错误码的错误保留原有的信息
+func LocalizedError(err error, locale Locale) string {
+	locale = locale.orDefault()
+	if locale == DefaultLocale {
+		return err.Error()
+	}
+
+	var coded *Error
+	var validationErr *ValidationError
+	var bannedErr *BannedError
+	var mutedErr *MutedError
+	switch {
+	case errors.As(err, &coded):
+		return translateError(locale, coded.Code, err)
+	case errors.As(err, &validationErr):
+		return translateError(locale, CodeInvalidRequest, err)
+	case errors.As(err, &bannedErr):
+		if bannedErr.Ban.ExpiresAt == 0 {
+			return Translate(locale, "error.banned_permanent", bannedErr.Ban.Reason)
+		}
+		return Translate(locale, "error.banned_until", time.Unix(bannedErr.Ban.ExpiresAt, 0).Format(time.DateTime), bannedErr.Ban.Reason)
+	case errors.As(err, &mutedErr):
+		return Translate(locale, "error.muted_until", mutedErr.Until.Format(time.DateTime))
+	}
+	return err.Error()
+}
+
+// translateError 按错误码翻译错误信息，该语言没有这个错误码的文本时返回原有的信息
+func translateError(locale Locale, code ErrorCode, err error) string {
+	if text, exists := catalog[locale]["error."+string(code)]; exists {
+		return text
+	}
+	return err.Error()
+}
+
+// locale 获取房间的语言，在对局goroutine中执行
+func (gc *GameController) locale() Locale {
+	return Locale(gc.game.Room.Locale).orDefault()
+}
+
+// locale AI发言使用的语言，与房间相同
+func (ai *AIPlayer) locale() Locale {
+	return Locale(ai.GameState.Room.Locale).orDefault()
+}
+
+// say 生成AI发言中的一句话
+func (ai *AIPlayer) say(key string, params ...interface{}) string {
+	return Translate(ai.locale(), key, params...)
+}
@@ -0,0 +1,369 @@
+package services
+
+import "github.com/qianlnk/werewolf/models"
+
+// catalog 各语言的消息文本，键按用途分组：narration 法官旁白，night 夜晚步骤的台词，
+// system 系统通知，ai AI发言，role 角色名称，story 对局回顾，tournament 赛事，error 错误信息（键为错误码）。
+// 文本中的占位符按fmt的格式填入参数，同一参数出现多次时使用%[1]s这样的显式序号
+var catalog = map[Locale]map[string]string{
+	LocaleZH: {
+		"narration.night_start":            "天黑请闭眼。",
+		"narration.night_start_deaths":     "%s出局。天黑请闭眼。",
+		"narration.day_start":              "天亮了，昨晚是平安夜。请依次发言。",
+		"narration.day_start_deaths":       "天亮了，昨晚%s死亡。请依次发言。",
+		"narration.vote_start":             "发言结束，请开始投票。",
+		"narration.campaign_start":         "天亮了，昨晚是平安夜。现在开始竞选警长，想上警的玩家请发表竞选发言。",
+		"narration.campaign_start_deaths":  "天亮了，昨晚%s死亡。现在开始竞选警长，想上警的玩家请发表竞选发言。",
+		"narration.sheriff_vote_start":     "竞选发言结束，%s在警上，请警下的玩家投票选出警长。",
+		"narration.day_start_sheriff":      "%s当选警长。请依次发言。",
+		"narration.day_start_no_sheriff":   "警徽流失，本局没有警长。请依次发言。",
+		"narration.result." + WerewolfWin:  "游戏结束，狼人阵营获胜。",
+		"narration.result." + VillagerWin:  "游戏结束，好人阵营获胜。",
+		"narration.result." + LoversWin:    "游戏结束，情侣获胜。",
+		"narration.result." + WhiteWolfWin: "游戏结束，白狼王获胜。",
+
+		"night.cupid.open":   "丘比特请睁眼，请选择两名玩家成为情侣。",
+		"night.cupid.close":  "丘比特请闭眼。",
+		"night.wolves.open":  "狼人请睁眼，请选择今晚要击杀的玩家。",
+		"night.wolves.close": "狼人请闭眼。",
+		"night.seer.open":    "预言家请睁眼，请选择要查验的玩家。",
+		"night.seer.close":   "预言家请闭眼。",
+		"night.witch.open":   "女巫请睁眼，今晚有人倒下了，你要使用解药吗？你要使用毒药吗？",
+		"night.witch.close":  "女巫请闭眼。",
+		"night.guard.open":   "守卫请睁眼，请选择今晚要守护的玩家。",
+		"night.guard.close":  "守卫请闭眼。",
+
+		"system.role_assigned":         "游戏开始，你的角色是：%s",
+		"system.game_started":          "游戏已开始",
+		"system.player_absent":         "%s 已断线，本阶段由AI代为行动",
+		"system.player_afk":            "%s 连续未行动，已判定为挂机",
+		"system.bot_timeout":           "超时未行动，本阶段已由内置AI代为行动",
+		"system.takeover_afk":          "%s 长时间未操作，由AI接管",
+		"system.takeover_disconnected": "%s 已断线，由AI接管",
+		"system.achievement_unlocked":  "解锁成就：%s",
+		"system.phase_rollback":        "对局已回滚到本阶段开始，请重新行动",
+		"system.room_invite":           "%s 邀请你加入房间 %s",
+		"system.ai_player_name":        "AI玩家%d",
+		"system.judge_advance":         "法官结束了当前阶段",
+		"system.judge_kill":            "法官判定 %s 出局",
+		"system.judge_revive":          "法官让 %s 重新回到对局",
+		"system.judge_ruling":          "法官裁定：%s",
+		"system.badge_passed":          "警长 %s 将警徽移交给了 %s",
+		"system.badge_torn":            "警长 %s 撕毁了警徽，本局不再有警长",
+		"system.tournament_match":      "%s 第%d轮已开始，你在第%d桌",
+		"system.tournament_finished":   "%s 已结束，冠军是 %s",
+		"tournament.room_name":         "%s 第%d轮 第%d桌",
+
+		"role.werewolf":  "狼人",
+		"role.seer":      "预言家",
+		"role.witch":     "女巫",
+		"role.villager":  "村民",
+		"role.hunter":    "猎人",
+		"role.guard":     "守卫",
+		"role.cupid":     "丘比特",
+		"role.thief":     "盗贼",
+		"role.whitewolf": "白狼王",
+
+		"story.title":          "狼人杀对局回顾：%d名玩家，历时%d分钟",
+		"story.players":        "玩家：%s",
+		"story.player_role":    "%s（%s）",
+		"story.night":          "第%d夜：%s",
+		"story.day":            "第%d天：%s",
+		"story.nothing":        "无事发生。",
+		"story.link":           "丘比特将%s和%s连为情侣",
+		"story.kill":           "狼人袭击了%s",
+		"story.protect":        "守卫守护了%s",
+		"story.save":           "女巫用解药救下了%s",
+		"story.poison":         "女巫向%s撒下了毒药",
+		"story.check":          "预言家查验了%s，结果是%s",
+		"story.check_wolf":     "狼人",
+		"story.check_good":     "好人",
+		"story.shoot":          "猎人%s开枪带走了%s",
+		"story.explode":        "白狼王%s自爆，带走了%s",
+		"story.votes":          "投票：%s",
+		"story.vote_for":       "%s投给%s",
+		"story.abstain":        "%s弃票",
+		"story.vote_separator": "；",
+		"story.death":          "%s出局",
+		"story.death." + string(models.DeathWolfKill): "%s被狼人杀害",
+		"story.death." + string(models.DeathPoison):   "%s被毒死",
+		"story.death." + string(models.DeathVote):     "%s被放逐",
+		"story.death." + string(models.DeathLover):    "%s殉情",
+		"story.death." + string(models.DeathJudge):    "%s被法官判定出局",
+		"story.revive":          "法官让%s重新回到对局",
+		"story.sheriff_elected": "%s当选警长",
+		"story.sheriff_none":    "警长竞选没有结果，警徽流失",
+		"story.badge_passed":    "警徽移交给了%s",
+		"story.badge_torn":      "警长撕毁了警徽",
+		"story.judge_ruling":    "法官裁定：%s",
+		"story.peaceful_night":  "平安夜",
+		"story.winners":         "获胜玩家：%s",
+
+		"ai.peaceful_night":      "昨晚是平安夜",
+		"ai.night_deaths":        "昨晚%s倒牌了",
+		"ai.last_vote":           "上一轮%s得票最多，有%d票",
+		"ai.last_vote_exiled":    "上一轮%s得票最多，有%d票，%s被放逐",
+		"ai.defend_aggressive":   "%[1]s一直针对我，我看%[1]s才是狼",
+		"ai.defend_strategic":    "%[1]s急着把矛头指向我这个好人，这种带节奏的做法很可疑，大家留意%[1]s的投票",
+		"ai.defend_default":      "%s怀疑我，但我真的是好人，希望大家听完我的发言再判断",
+		"ai.seer_no_info":        "我现在还没有什么信息，先听听大家的发言",
+		"ai.seer_hiding":         "我手里有一些信息，现在还不方便说",
+		"ai.claim_seer":          "我是预言家",
+		"ai.claim_seer_checks":   "我是预言家，%s",
+		"ai.check_good":          "第%d晚验了%s，是好人",
+		"ai.check_wolf":          "第%d晚验了%s，是狼人",
+		"ai.checks_separator":    "，",
+		"ai.rivals_fake":         "%s跳预言家是假的，大家不要相信",
+		"ai.follow_vote":         "今天请大家跟我投%s",
+		"ai.rival_is_wolf":       "冒充预言家的%s一定是狼，今天投他",
+		"ai.fake_seer":           "%s是假预言家，大家不要被他骗了",
+		"ai.wolf_reject_seer":    "%s跳预言家，我不认，我觉得他才是狼",
+		"ai.wolf_question_seer":  "%s说自己是预言家，但他的验人结果经不起推敲，大家再想想",
+		"ai.wolf_wait_seer":      "%s跳了预言家，我先听听他后面的发言再站边",
+		"ai.wolf_redirect":       "%s上一轮投了%s，我觉得他在带节奏",
+		"ai.wolf_quiet":          "%s一直没怎么发言，我觉得有问题",
+		"ai.wolf_default":        "我是好人，大家先别急着站边",
+		"ai.fake_check_on_me":    "%s说验了我是狼人，我是好人，他一定是假预言家",
+		"ai.fake_check_mismatch": "%s的验人结果和我知道的对不上，他是假预言家",
+		"ai.seer_doubt":          "%s跳了预言家，我要看他的验人结果能不能对上",
+		"ai.seer_trust":          "%s跳了预言家，我暂时相信他",
+		"ai.seers_trust":         "%s都说自己是预言家，我更相信%s",
+		"ai.seers_unsure":        "%s都说自己是预言家，其中肯定有狼，我再听听",
+		"ai.suspect_quiet":       "%s发言很少，我觉得他嫌疑最大",
+		"ai.suspect":             "综合投票和发言来看，我觉得%s嫌疑最大",
+		"ai.filler_listen":       "目前信息不多，我先听听大家的发言",
+		"ai.filler_hurry":        "我们要抓紧时间找出狼人",
+		"ai.filler_share":        "大家把自己的想法都说一说",
+		"ai.moderation_fallback": "我没什么要补充的，过。",
+		"ai.campaign_seer":       "请把警徽交给我，我会按查验结果带大家归票",
+		"ai.campaign_good":       "我上警，我是好人，拿到警徽我会认真听发言、带大家归票",
+		"ai.campaign_seer_wolf":  "如果我拿到警徽，今天就带大家投%s",
+		"ai.sheriff_call":        "我是警长，今天我归票%s，大家跟我投",
+	},
+
+	LocaleEN: {
+		"narration.night_start":            "Night falls. Everyone, close your eyes.",
+		"narration.night_start_deaths":     "%s is out. Night falls. Everyone, close your eyes.",
+		"narration.day_start":              "Day breaks. It was a peaceful night. Please speak in turn.",
+		"narration.day_start_deaths":       "Day breaks. %s died last night. Please speak in turn.",
+		"narration.vote_start":             "Discussion is over. Please cast your votes.",
+		"narration.campaign_start":         "Day breaks. It was a peaceful night. The sheriff election begins. Players who want to run, please give your campaign speech.",
+		"narration.campaign_start_deaths":  "Day breaks. %s died last night. The sheriff election begins. Players who want to run, please give your campaign speech.",
+		"narration.sheriff_vote_start":     "Campaign speeches are over. %s are running. Everyone else, please vote for the sheriff.",
+		"narration.day_start_sheriff":      "%s is elected sheriff. Please speak in turn.",
+		"narration.day_start_no_sheriff":   "The badge is lost. There is no sheriff this game. Please speak in turn.",
+		"narration.result." + WerewolfWin:  "Game over. The werewolves win.",
+		"narration.result." + VillagerWin:  "Game over. The villagers win.",
+		"narration.result." + LoversWin:    "Game over. The lovers win.",
+		"narration.result." + WhiteWolfWin: "Game over. The White Wolf King wins.",
+
+		"night.cupid.open":   "Cupid, open your eyes and choose two players to fall in love.",
+		"night.cupid.close":  "Cupid, close your eyes.",
+		"night.wolves.open":  "Werewolves, open your eyes and choose a player to kill tonight.",
+		"night.wolves.close": "Werewolves, close your eyes.",
+		"night.seer.open":    "Seer, open your eyes and choose a player to check.",
+		"night.seer.close":   "Seer, close your eyes.",
+		"night.witch.open":   "Witch, open your eyes. Someone fell tonight. Will you use your antidote? Will you use your poison?",
+		"night.witch.close":  "Witch, close your eyes.",
+		"night.guard.open":   "Guard, open your eyes and choose a player to protect tonight.",
+		"night.guard.close":  "Guard, close your eyes.",
+
+		"system.role_assigned":         "The game has started. Your role is: %s",
+		"system.game_started":          "The game has started",
+		"system.player_absent":         "%s disconnected. The AI is acting for them this phase",
+		"system.player_afk":            "%s has missed several turns and is marked as away",
+		"system.bot_timeout":           "You did not act in time. The built-in AI acted for you this phase",
+		"system.takeover_afk":          "%s has been inactive too long. The AI has taken over",
+		"system.takeover_disconnected": "%s disconnected. The AI has taken over",
+		"system.achievement_unlocked":  "Achievement unlocked: %s",
+		"system.phase_rollback":        "The game was rolled back to the start of this phase. Please act again",
+		"system.room_invite":           "%s invited you to join room %s",
+		"system.ai_player_name":        "AI Player %d",
+		"system.judge_advance":         "The judge ended the current phase",
+		"system.judge_kill":            "The judge ruled %s out of the game",
+		"system.judge_revive":          "The judge brought %s back into the game",
+		"system.judge_ruling":          "The judge ruled: %s",
+		"system.badge_passed":          "Sheriff %s passed the badge to %s",
+		"system.badge_torn":            "Sheriff %s tore up the badge. There is no sheriff for the rest of the game",
+		"system.tournament_match":      "%s round %d has started. You are at table %d",
+		"system.tournament_finished":   "%s has finished. The champion is %s",
+		"tournament.room_name":         "%s Round %d Table %d",
+
+		"role.werewolf":  "Werewolf",
+		"role.seer":      "Seer",
+		"role.witch":     "Witch",
+		"role.villager":  "Villager",
+		"role.hunter":    "Hunter",
+		"role.guard":     "Guard",
+		"role.cupid":     "Cupid",
+		"role.thief":     "Thief",
+		"role.whitewolf": "White Wolf King",
+
+		"story.title":          "Werewolf game recap: %d players, %d minutes",
+		"story.players":        "Players: %s",
+		"story.player_role":    "%s (%s)",
+		"story.night":          "Night %d: %s",
+		"story.day":            "Day %d: %s",
+		"story.nothing":        "Nothing happened.",
+		"story.link":           "Cupid made %s and %s lovers",
+		"story.kill":           "The werewolves attacked %s",
+		"story.protect":        "The guard protected %s",
+		"story.save":           "The witch saved %s with the antidote",
+		"story.poison":         "The witch poisoned %s",
+		"story.check":          "The seer checked %s and found %s",
+		"story.check_wolf":     "a werewolf",
+		"story.check_good":     "a good person",
+		"story.shoot":          "Hunter %s shot %s",
+		"story.explode":        "White Wolf King %s self-destructed and took %s down",
+		"story.votes":          "Votes: %s",
+		"story.vote_for":       "%s voted for %s",
+		"story.abstain":        "%s abstained",
+		"story.vote_separator": "; ",
+		"story.death":          "%s died",
+		"story.death." + string(models.DeathWolfKill): "%s was killed by the werewolves",
+		"story.death." + string(models.DeathPoison):   "%s was poisoned",
+		"story.death." + string(models.DeathVote):     "%s was exiled",
+		"story.death." + string(models.DeathLover):    "%s died of heartbreak",
+		"story.death." + string(models.DeathJudge):    "%s was ruled out by the judge",
+		"story.revive":          "The judge brought %s back into the game",
+		"story.sheriff_elected": "%s was elected sheriff",
+		"story.sheriff_none":    "No sheriff was elected and the badge was lost",
+		"story.badge_passed":    "The badge was passed to %s",
+		"story.badge_torn":      "The sheriff tore up the badge",
+		"story.judge_ruling":    "The judge ruled: %s",
+		"story.peaceful_night":  "No one died",
+		"story.winners":         "Winners: %s",
+
+		"ai.peaceful_night":      "Last night was peaceful",
+		"ai.night_deaths":        "%s went down last night",
+		"ai.last_vote":           "Last round %s got the most votes, %d in total",
+		"ai.last_vote_exiled":    "Last round %s got the most votes, %d in total, and %s was exiled",
+		"ai.defend_aggressive":   "%[1]s keeps going after me. I think %[1]s is the wolf",
+		"ai.defend_strategic":    "%[1]s is in a hurry to point at me, a good person. That is suspicious, so watch how %[1]s votes",
+		"ai.defend_default":      "%s suspects me, but I really am on the good side. Please hear me out before deciding",
+		"ai.seer_no_info":        "I don't have much information yet, so I'll listen to everyone first",
+		"ai.seer_hiding":         "I have some information, but it's not the right time to share it",
+		"ai.claim_seer":          "I am the Seer",
+		"ai.claim_seer_checks":   "I am the Seer: %s",
+		"ai.check_good":          "on night %d I checked %s, who is good",
+		"ai.check_wolf":          "on night %d I checked %s, who is a werewolf",
+		"ai.checks_separator":    ", ",
+		"ai.rivals_fake":         "%s is a fake Seer, don't believe them",
+		"ai.follow_vote":         "Please vote for %s with me today",
+		"ai.rival_is_wolf":       "%s is faking the Seer and must be a wolf. Vote for them today",
+		"ai.fake_seer":           "%s is a fake Seer, don't be fooled",
+		"ai.wolf_reject_seer":    "%s claims Seer, but I don't buy it. I think they are the wolf",
+		"ai.wolf_question_seer":  "%s says they are the Seer, but their checks don't hold up. Think it over",
+		"ai.wolf_wait_seer":      "%s claimed Seer. I'll hear more from them before taking a side",
+		"ai.wolf_redirect":       "%s voted for %s last round. I think they are leading us astray",
+		"ai.wolf_quiet":          "%s has barely spoken. Something is off about them",
+		"ai.wolf_default":        "I'm on the good side. Let's not rush to pick sides",
+		"ai.fake_check_on_me":    "%s says they checked me as a werewolf, but I'm good. They must be a fake Seer",
+		"ai.fake_check_mismatch": "%s's checks contradict what I know. They are a fake Seer",
+		"ai.seer_doubt":          "%s claimed Seer. Let's see whether their checks add up",
+		"ai.seer_trust":          "%s claimed Seer. I'll trust them for now",
+		"ai.seers_trust":         "%s all claim to be the Seer. I trust %s more",
+		"ai.seers_unsure":        "%s all claim to be the Seer. One of them must be a wolf, so I'll keep listening",
+		"ai.suspect_quiet":       "%s has said very little. I find them the most suspicious",
+		"ai.suspect":             "Looking at the votes and the discussion, I find %s the most suspicious",
+		"ai.filler_listen":       "There isn't much to go on yet, so I'll listen to everyone first",
+		"ai.filler_hurry":        "We need to find the werewolves quickly",
+		"ai.filler_share":        "Everyone, please share what you think",
+		"ai.moderation_fallback": "Nothing to add from me. Pass.",
+		"ai.campaign_seer":       "Give me the badge and I will lead the vote with my checks",
+		"ai.campaign_good":       "I'm running. I'm on the good side, and with the badge I will listen carefully and lead the vote",
+		"ai.campaign_seer_wolf":  "If I get the badge, I will lead everyone to vote for %s today",
+		"ai.sheriff_call":        "As sheriff, I call the vote on %s today. Please vote with me",
+
+		"error.banned_permanent": "You are permanently banned: %s",
+		"error.banned_until":     "You are banned until %s: %s",
+		"error.muted_until":      "You have been muted until %s for repeated chat violations",
+
+		"error." + string(CodeInvalidRequest): "Invalid request",
+		"error." + string(CodeUnauthorized):   "Not logged in or the token is invalid",
+		"error." + string(CodeForbidden):      "Permission denied",
+		"error." + string(CodeNotFound):       "Not found",
+		"error." + string(CodeConflict):       "Already exists",
+		"error." + string(CodeShuttingDown):   "The server is shutting down",
+		"error." + string(CodeServerBusy):     "The server is at capacity, please try again later",
+		"error." + string(CodeInternal):       "Internal server error",
+
+		"error." + string(CodeUserExists):         "The username is already taken",
+		"error." + string(CodeUserNotFound):       "User not found",
+		"error." + string(CodeInvalidCredentials): "Incorrect username or password",
+		"error." + string(CodeInvalidUsername):    "The username must be 3 to 32 characters long",
+		"error." + string(CodeWeakPassword):       "The password must be at least 6 characters long",
+		"error." + string(CodeNotGuest):           "This account is not a guest account",
+		"error." + string(CodeInvalidToken):       "The token is invalid or has expired",
+		"error." + string(CodeInvalidTicket):      "The connection ticket is invalid or has expired",
+		"error." + string(CodeInsufficientScope):  "The API key does not permit this operation",
+		"error." + string(CodeInvalidScope):       "Invalid API key scope",
+		"error." + string(CodeAPIKeyNotFound):     "API key not found",
+		"error." + string(CodeWeChatDisabled):     "WeChat mini program login is not configured",
+		"error." + string(CodeInvalidWeChatCode):  "The WeChat login code is invalid or has been used",
+		"error." + string(CodeUnknownProvider):    "This login method is not supported or not configured",
+		"error." + string(CodeInvalidOAuthState):  "The login request has expired, please log in again",
+		"error." + string(CodeOAuthFailed):        "Third-party login failed",
+		"error." + string(CodeIdentityLinked):     "This third-party account is already linked to another player",
+		"error." + string(CodeProviderLinked):     "Another account from this provider is already linked, unlink it first",
+		"error." + string(CodeIdentityNotFound):   "No account from this provider is linked",
+		"error." + string(CodeLastLoginMethod):    "This is the only way to log in to the account and cannot be unlinked",
+
+		"error." + string(CodeRoomNotFound):       "Room not found",
+		"error." + string(CodeRoomFull):           "The room is full",
+		"error." + string(CodeNotInRoom):          "You are not in this room",
+		"error." + string(CodePlayerNotFound):     "Player not found",
+		"error." + string(CodeGameNotFound):       "The game has not started or does not exist",
+		"error." + string(CodeGameNotStarted):     "The game has not started yet",
+		"error." + string(CodeGameInProgress):     "The game is in progress",
+		"error." + string(CodeNotEnoughPlayers):   "Not enough players",
+		"error." + string(CodeNotYourTurn):        "You cannot do that right now",
+		"error." + string(CodeWrongRole):          "Your role cannot use this skill",
+		"error." + string(CodeInvalidAction):      "Invalid action",
+		"error." + string(CodeInvalidTarget):      "Invalid target",
+		"error." + string(CodePotionUsed):         "This potion has already been used",
+		"error." + string(CodePhaseIncomplete):    "Not all required actions in this phase are done",
+		"error." + string(CodeInvalidPersonality): "Invalid AI personality distribution",
+		"error." + string(CodeInvalidAIFill):      "The AI fill count cannot be negative or exceed the room size",
+		"error." + string(CodeNotAIPlayer):        "This player is not controlled by the AI",
+		"error." + string(CodeInvalidAITuning):    "Invalid AI tuning: probabilities and thresholds must be between 0 and 1, weights cannot be negative",
+		"error." + string(CodeInvalidMode):        "Invalid game mode",
+		"error." + string(CodeInvalidRoomSize):    "The room size is smaller than this mode requires",
+		"error." + string(CodeInvalidBoard):       "Invalid custom board: at most one of each special role, 1 to 4 werewolves, supported roles only",
+		"error." + string(CodeNotHost):            "Only the host can do this",
+		"error." + string(CodePlayerDead):         "You are out and cannot take game actions",
+		"error." + string(CodeStateChanged):       "The game state has changed, please refresh and try again",
+		"error." + string(CodeNoPhaseSnapshot):    "There is no phase to roll back to",
+		"error." + string(CodeInvalidLocale):      "Unsupported language",
+		"error." + string(CodeJudgeTaken):         "This room already has a judge",
+		"error." + string(CodeJudgeUnavailable):   "A judge can only be seated before the game starts, in an unranked room, by someone not playing in it",
+		"error." + string(CodeNotJudge):           "Only the judge of this room can do this",
+
+		"error." + string(CodeChatNotAllowed):    "You cannot speak in this channel right now",
+		"error." + string(CodeWhisperDisabled):   "Whispers are disabled in this room",
+		"error." + string(CodeWhisperTarget):     "You cannot whisper to this player",
+		"error." + string(CodeChatBlocked):       "Your message contains prohibited content and was not sent",
+		"error." + string(CodeInvalidChatFilter): "Invalid chat filter mode",
+		"error." + string(CodeMuteNotFound):      "This player is not muted",
+
+		"error." + string(CodeFriendSelf):      "You cannot add yourself as a friend",
+		"error." + string(CodeFriendExists):    "Already friends or a request has already been sent",
+		"error." + string(CodeNotFriends):      "This player is not your friend",
+		"error." + string(CodeFriendOffline):   "Your friend is offline",
+		"error." + string(CodeGuestNoFriends):  "Guest accounts cannot add friends",
+		"error." + string(CodeReportSelf):      "You cannot report yourself",
+		"error." + string(CodeReportReason):    "A report reason is required",
+		"error." + string(CodeReportNotFound):  "Report not found",
+		"error." + string(CodeReportReviewed):  "The report has already been reviewed",
+		"error." + string(CodeInvalidBan):      "Invalid ban type or target",
+		"error." + string(CodeBanNotFound):     "Ban not found",
+		"error." + string(CodeInvalidDecision): "Invalid review decision",
+
+		"error." + string(CodeTournamentNotFound):   "Tournament not found",
+		"error." + string(CodeMatchNotFound):        "Match not found",
+		"error." + string(CodeInvalidTournament):    "Invalid tournament: check the format, table size and number of rounds",
+		"error." + string(CodeTournamentClosed):     "Registration for this tournament is closed",
+		"error." + string(CodeTournamentNotRunning): "This tournament is not in progress",
+		"error." + string(CodeNotOrganizer):         "Only the tournament organizer can do this",
+	},
+}
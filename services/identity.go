@@ -0,0 +1,143 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+var (
+	ErrIdentityLinked   = NewError(CodeIdentityLinked, "该第三方账号已绑定其他玩家账号")
+	ErrProviderLinked   = NewError(CodeProviderLinked, "当前账号已绑定该平台的其他账号，请先解除绑定")
+	ErrIdentityNotFound = NewError(CodeIdentityNotFound, "当前账号没有绑定该平台")
+	ErrLastLoginMethod  = NewError(CodeLastLoginMethod, "这是账号唯一的登录方式，不能解除绑定")
+)
+
+// providerNames 第三方平台的显示名称，用于生成新账号的默认名称
+var providerNames = map[string]string{
+	ProviderGitHub: "GitHub",
+	ProviderGoogle: "Google",
+	ProviderWeChat: "微信",
+}
+
+// LoginExternal 用第三方身份登录，身份未绑定账号时创建新账号并绑定，
+// 已绑定时登录到绑定的账号，积分和历史数据与密码登录相同
+func (am *AccountManager) LoginExternal(identity *ExternalIdentity) (*models.User, error) {
+	linked, err := am.store.GetIdentity(identity.Provider, identity.Subject)
+	if err == nil {
+		return am.GetUser(linked.UserID)
+	}
+	if err != storage.ErrNotFound {
+		return nil, err
+	}
+
+	id := generateUserID()
+	displayName := strings.TrimSpace(identity.Name)
+	if displayName == "" {
+		displayName = providerNames[identity.Provider] + "用户" + id[len(id)-4:]
+	}
+	user := &models.User{
+		ID:          id,
+		Username:    id, // 没有密码，使用玩家ID占位用户名
+		DisplayName: displayName,
+		Rating:      DefaultRating,
+		CreatedAt:   time.Now().Unix(),
+	}
+	if err := am.store.CreateUser(user); err != nil {
+		return nil, err
+	}
+
+	if err := am.store.CreateIdentity(newIdentity(user.ID, identity)); err != nil {
+		// 同一身份的并发登录已经创建并绑定了账号，登录到该账号
+		if err == storage.ErrDuplicate {
+			if linked, err := am.store.GetIdentity(identity.Provider, identity.Subject); err == nil {
+				return am.GetUser(linked.UserID)
+			}
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// LinkIdentity 为账号绑定第三方身份，之后可以用该身份登录同一账号；
+// 游客绑定后成为正式账号，可以添加好友
+func (am *AccountManager) LinkIdentity(userID string, identity *ExternalIdentity) error {
+	user, err := am.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if linked, err := am.store.GetIdentity(identity.Provider, identity.Subject); err == nil {
+		if linked.UserID == userID {
+			return nil
+		}
+		return ErrIdentityLinked
+	} else if err != storage.ErrNotFound {
+		return err
+	}
+
+	identities, err := am.store.ListIdentities(userID)
+	if err != nil {
+		return err
+	}
+	for _, linked := range identities {
+		if linked.Provider == identity.Provider {
+			return ErrProviderLinked
+		}
+	}
+
+	if err := am.store.CreateIdentity(newIdentity(userID, identity)); err != nil {
+		if err == storage.ErrDuplicate {
+			return ErrIdentityLinked
+		}
+		return err
+	}
+
+	if user.Guest {
+		user.Guest = false
+		return am.store.UpdateUser(user)
+	}
+	return nil
+}
+
+// UnlinkIdentity 解除账号在指定平台绑定的身份，没有密码的账号至少保留一个绑定
+func (am *AccountManager) UnlinkIdentity(userID, provider string) error {
+	user, err := am.GetUser(userID)
+	if err != nil {
+		return err
+	}
+
+	identities, err := am.store.ListIdentities(userID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == "" && len(identities) == 1 && identities[0].Provider == provider {
+		return ErrLastLoginMethod
+	}
+
+	if err := am.store.DeleteIdentity(userID, provider); err != nil {
+		if err == storage.ErrNotFound {
+			return ErrIdentityNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// ListIdentities 列出账号绑定的第三方身份
+func (am *AccountManager) ListIdentities(userID string) ([]*models.Identity, error) {
+	return am.store.ListIdentities(userID)
+}
+
+// newIdentity 创建绑定记录
+func newIdentity(userID string, identity *ExternalIdentity) *models.Identity {
+	return &models.Identity{
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		UserID:    userID,
+		Name:      identity.Name,
+		CreatedAt: time.Now().Unix(),
+	}
+}
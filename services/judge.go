@@ -0,0 +1,393 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+var (
+	ErrJudgeTaken       = NewError(CodeJudgeTaken, "房间已有法官")
+	ErrJudgeUnavailable = NewError(CodeJudgeUnavailable, "只能在对局开始前由未入座的玩家担任非排位房间的法官")
+	ErrNotJudge         = NewError(CodeNotJudge, "只有本房间的法官可以执行该操作")
+)
+
+// AuditSourceJudge 法官的人工操作
+const AuditSourceJudge = "judge"
+
+// maxJudgeNoteLength 法官备注和裁定的最大长度
+const maxJudgeNoteLength = 500
+
+// JudgeView 法官视角的对局状态：在旁观者视角的基础上公开所有角色，以及夜晚目标、技能、
+// 未公开的出局原因等只有法官能看到的信息
+type JudgeView struct {
+	*models.PlayerGameView
+	JudgeID string `json:"judge_id"`
+	// SubmittedActions 本阶段已提交的动作，包括夜晚各角色选择的目标
+	SubmittedActions []models.GameAction               `json:"submitted_actions"`
+	WolfTarget       string                            `json:"wolf_target,omitempty"` // 狼队当前的共识击杀目标
+	Votes            *VoteTally                        `json:"votes,omitempty"`       // 投票阶段中已投出的票，按票权统计
+	Skills           map[string]WitchSkills            `json:"skills,omitempty"`
+	AllKnownRoles    map[string]map[string]models.Role `json:"all_known_roles,omitempty"` // 每名玩家已知的其他玩家角色
+	DeathCauses      map[string]models.DeathCause      `json:"death_causes,omitempty"`    // 包括夜晚出局的原因
+	PendingShot      string                            `json:"pending_shot,omitempty"`
+	PendingBadge     string                            `json:"pending_badge,omitempty"`
+	VoteWeights      map[string]float64                `json:"vote_weights,omitempty"`
+	VoteBonus        map[string]float64                `json:"vote_bonus,omitempty"`
+	AFK              map[string]bool                   `json:"afk,omitempty"`
+	Notes            []models.GameEvent                `json:"notes,omitempty"` // 法官的备注和公开裁定，按记录顺序排列
+}
+
+// ClaimJudge 玩家担任房间的法官，只能在对局开始前、由未入座的玩家担任，排位房间不设法官
+func (rm *RoomManager) ClaimJudge(roomID, playerID string) error {
+	return rm.setJudge(roomID, func(room *models.Room) error {
+		if room.JudgeID == playerID {
+			return nil
+		}
+		if room.JudgeID != "" {
+			return ErrJudgeTaken
+		}
+		if room.Ranked || room.GameStarted {
+			return ErrJudgeUnavailable
+		}
+		for _, player := range room.Players {
+			if player.ID == playerID {
+				return ErrJudgeUnavailable
+			}
+		}
+		room.JudgeID = playerID
+		return nil
+	})
+}
+
+// ReleaseJudge 法官离开法官席位，对局进行中离开时之后的阶段按计时自动进行
+func (rm *RoomManager) ReleaseJudge(roomID, playerID string) error {
+	return rm.setJudge(roomID, func(room *models.Room) error {
+		if room.JudgeID != playerID {
+			return ErrNotJudge
+		}
+		room.JudgeID = ""
+		return nil
+	})
+}
+
+// setJudge 修改房间的法官席位并保存，再同步到对局控制器中的房间信息
+func (rm *RoomManager) setJudge(roomID string, update func(room *models.Room) error) error {
+	rm.mutex.Lock()
+	room, exists := rm.rooms[roomID]
+	if !exists {
+		rm.mutex.Unlock()
+		return ErrRoomNotFound
+	}
+	if err := update(room); err != nil {
+		rm.mutex.Unlock()
+		return err
+	}
+	rm.persistRoom(room)
+	judgeID := room.JudgeID
+	game := rm.games[roomID]
+	rm.mutex.Unlock()
+
+	// 对局goroutine可能正在等待房间管理器的锁，释放后再提交
+	if game != nil {
+		game.post(func() {
+			game.game.Room.JudgeID = judgeID
+		})
+	}
+	return nil
+}
+
+// isJudge 玩家是否为本局的法官，在对局goroutine中执行
+func (gc *GameController) isJudge(playerID string) bool {
+	return playerID != "" && playerID == gc.game.Room.JudgeID
+}
+
+// JudgeState 获取法官视角的对局状态，与法官席位的变更按提交顺序执行
+func (gc *GameController) JudgeState(judgeID string) (*JudgeView, error) {
+	var view *JudgeView
+	var err error = ErrGameNotFound
+	gc.do(func() {
+		if !gc.isJudge(judgeID) {
+			err = ErrNotJudge
+			return
+		}
+		view, err = gc.game.judgeView(judgeID)
+	})
+	return view, err
+}
+
+// JudgeAdvance 法官立即结束当前阶段或夜晚步骤，与到时结束相同：未行动的玩家执行默认动作
+func (gc *GameController) JudgeAdvance(judgeID string) error {
+	var err error = ErrGameNotFound
+	gc.do(func() {
+		err = gc.judgeAdvance(judgeID)
+		gc.recordJudgeAudit(judgeID, "judge_advance", "", err)
+	})
+	return err
+}
+
+// judgeAdvance 结束当前阶段，在对局goroutine中执行
+func (gc *GameController) judgeAdvance(judgeID string) error {
+	if !gc.isJudge(judgeID) {
+		return ErrNotJudge
+	}
+	if !gc.game.IsStarted {
+		return ErrGameNotStarted
+	}
+
+	gc.logger().Info("法官结束当前阶段", "judge_id", judgeID, "phase", gc.game.Phase, "step", gc.game.NightStep)
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(map[string]interface{}{
+		"type":  "judge_advance",
+		"phase": gc.game.Phase,
+		"round": gc.game.Round,
+	}, gc.locale(), "message", "system.judge_advance"))
+
+	gc.game.PhaseEndsAt = time.Now().UnixMilli()
+	gc.handlePhaseTimeout(gc.game.Phase, gc.game.Round, gc.game.NightStep)
+	return nil
+}
+
+// JudgeKill 法官判定玩家出局，用于处理违规或争议，出局原因为judge，猎人不能因此开枪
+func (gc *GameController) JudgeKill(judgeID, playerID, note string) error {
+	var err error = ErrGameNotFound
+	gc.do(func() {
+		err = gc.judgeKill(judgeID, playerID, note)
+		gc.recordJudgeAudit(judgeID, "judge_kill", playerID, err)
+	})
+	return err
+}
+
+// judgeKill 判定玩家出局，在对局goroutine中执行
+func (gc *GameController) judgeKill(judgeID, playerID, note string) error {
+	player, err := gc.judgeTarget(judgeID, playerID, note)
+	if err != nil {
+		return err
+	}
+	if !player.Alive {
+		return NewError(CodeInvalidTarget, "玩家已出局")
+	}
+
+	gc.logger().Info("法官判定玩家出局", "judge_id", judgeID, "player_id", playerID, "note", note)
+	gc.game.kill(playerID, models.DeathJudge)
+	gc.game.recordDeaths()
+	gc.recordJudgeNote(note)
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(map[string]interface{}{
+		"type":      "judge_kill",
+		"player_id": playerID,
+		"note":      note,
+	}, gc.locale(), "message", "system.judge_kill", player.Name))
+
+	if gc.checkGameEndNow() {
+		return nil
+	}
+	gc.wakeSheriff()
+	gc.saveSnapshot()
+	gc.checkPhaseProgress()
+	return nil
+}
+
+// JudgeRevive 法官让出局的玩家重新回到对局，用于纠正误判，玩家的角色和已知信息保持不变
+func (gc *GameController) JudgeRevive(judgeID, playerID, note string) error {
+	var err error = ErrGameNotFound
+	gc.do(func() {
+		err = gc.judgeRevive(judgeID, playerID, note)
+		gc.recordJudgeAudit(judgeID, "judge_revive", playerID, err)
+	})
+	return err
+}
+
+// judgeRevive 复活玩家，在对局goroutine中执行
+func (gc *GameController) judgeRevive(judgeID, playerID, note string) error {
+	player, err := gc.judgeTarget(judgeID, playerID, note)
+	if err != nil {
+		return err
+	}
+	if player.Alive {
+		return NewError(CodeInvalidTarget, "玩家仍然存活")
+	}
+
+	gc.logger().Info("法官复活玩家", "judge_id", judgeID, "player_id", playerID, "note", note)
+	gc.game.revive(playerID)
+	if gc.game.PendingShot == playerID {
+		gc.game.PendingShot = ""
+	}
+	if gc.game.PendingBadge == playerID {
+		gc.game.PendingBadge = ""
+	}
+	gc.game.recordEvent(models.GameEvent{Type: "revive", PlayerID: playerID})
+	gc.recordJudgeNote(note)
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(map[string]interface{}{
+		"type":      "judge_revive",
+		"player_id": playerID,
+		"note":      note,
+	}, gc.locale(), "message", "system.judge_revive", player.Name))
+
+	if gc.checkGameEndNow() {
+		return nil
+	}
+	gc.saveSnapshot()
+	gc.broadcastGameState()
+	return nil
+}
+
+// judgeTarget 校验法官操作的对象，在对局goroutine中执行
+func (gc *GameController) judgeTarget(judgeID, playerID, note string) (models.Player, error) {
+	if !gc.isJudge(judgeID) {
+		return models.Player{}, ErrNotJudge
+	}
+	if !gc.game.IsStarted {
+		return models.Player{}, ErrGameNotStarted
+	}
+	if len([]rune(note)) > maxJudgeNoteLength {
+		return models.Player{}, NewError(CodeInvalidRequest, "备注过长")
+	}
+	for _, player := range gc.game.Players {
+		if player.ID == playerID {
+			return player, nil
+		}
+	}
+	return models.Player{}, NewError(CodeInvalidTarget, "无效的目标玩家")
+}
+
+// JudgeNote 法官在事件日志中记录备注，public为true时作为裁定向房间公布，否则只有法官可见
+func (gc *GameController) JudgeNote(judgeID, text string, public bool) error {
+	var err error = ErrGameNotFound
+	gc.do(func() {
+		err = gc.judgeNote(judgeID, text, public)
+		action := "judge_note"
+		if public {
+			action = "judge_ruling"
+		}
+		gc.recordJudgeAudit(judgeID, action, "", err)
+	})
+	return err
+}
+
+// judgeNote 记录备注或裁定，在对局goroutine中执行
+func (gc *GameController) judgeNote(judgeID, text string, public bool) error {
+	if !gc.isJudge(judgeID) {
+		return ErrNotJudge
+	}
+	if !gc.game.IsStarted {
+		return ErrGameNotStarted
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return NewError(CodeInvalidRequest, "备注不能为空")
+	}
+	if len([]rune(text)) > maxJudgeNoteLength {
+		return NewError(CodeInvalidRequest, "备注过长")
+	}
+
+	if !public {
+		gc.recordJudgeNote(text)
+		gc.sendJudgeState()
+		return nil
+	}
+	gc.game.recordEvent(models.GameEvent{Type: "judge_ruling", PlayerID: judgeID, Content: text})
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(map[string]interface{}{
+		"type":   "judge_ruling",
+		"ruling": text,
+	}, gc.locale(), "message", "system.judge_ruling", text))
+	gc.sendJudgeState()
+	return nil
+}
+
+// recordJudgeNote 在事件日志中记录只有法官可见的备注，备注为空时不记录
+func (gc *GameController) recordJudgeNote(note string) {
+	if note = strings.TrimSpace(note); note != "" {
+		gc.game.recordEvent(models.GameEvent{Type: "judge_note", PlayerID: gc.game.Room.JudgeID, Content: note})
+	}
+}
+
+// recordJudgeAudit 将法官的操作写入审计日志
+func (gc *GameController) recordJudgeAudit(judgeID, action, targetID string, err error) {
+	if gc.game.roomManager == nil {
+		return
+	}
+	gc.game.roomManager.Audit().Record(models.AuditEntry{
+		RoomID:   gc.game.Room.ID,
+		PlayerID: judgeID,
+		Source:   AuditSourceJudge,
+		Action:   action,
+		TargetID: targetID,
+		Phase:    gc.game.Phase,
+		Round:    gc.game.Round,
+	}, err)
+}
+
+// sendJudgeState 向法官推送法官视角的对局状态，没有法官时不推送，在对局goroutine中执行
+func (gc *GameController) sendJudgeState() {
+	judgeID := gc.game.Room.JudgeID
+	if judgeID == "" {
+		return
+	}
+	view, err := gc.game.judgeView(judgeID)
+	if err != nil {
+		return
+	}
+	gc.sink.SendToPlayer(judgeID, map[string]interface{}{
+		"type":  "judge_state",
+		"state": view,
+	})
+}
+
+// judgeView 在旁观者视角的基础上投影出法官视角，返回的数据与对局状态不共享，调用方需持有锁
+func (gs *GameState) judgeView(judgeID string) (*JudgeView, error) {
+	spectator, err := gs.playerGameView("")
+	if err != nil {
+		return nil, err
+	}
+	copy(spectator.Players, gs.Players)
+
+	view := &JudgeView{
+		PlayerGameView:   spectator,
+		JudgeID:          judgeID,
+		SubmittedActions: append([]models.GameAction{}, gs.Actions...),
+		PendingShot:      gs.PendingShot,
+		PendingBadge:     gs.PendingBadge,
+		AllKnownRoles:    make(map[string]map[string]models.Role, len(gs.KnownRoles)),
+		Skills:           make(map[string]WitchSkills, len(gs.Skills)),
+		DeathCauses:      make(map[string]models.DeathCause, len(gs.DeathCauses)),
+		VoteWeights:      make(map[string]float64, len(gs.VoteWeights)),
+		VoteBonus:        make(map[string]float64, len(gs.VoteBonus)),
+		AFK:              make(map[string]bool, len(gs.AFK)),
+	}
+	if gs.IsStarted && gs.Phase == PhaseNight {
+		view.WolfTarget = wolfConsensusTarget(gs.Actions)
+	}
+	if gs.IsStarted && gs.Phase == PhaseVote {
+		view.Votes = gs.tallyVotes()
+	}
+	for playerID, known := range gs.KnownRoles {
+		roles := make(map[string]models.Role, len(known))
+		for targetID, role := range known {
+			roles[targetID] = role
+		}
+		view.AllKnownRoles[playerID] = roles
+	}
+	for playerID, skills := range gs.Skills {
+		if skills != nil {
+			view.Skills[playerID] = *skills
+		}
+	}
+	for playerID, cause := range gs.DeathCauses {
+		view.DeathCauses[playerID] = cause
+	}
+	for playerID, weight := range gs.VoteWeights {
+		view.VoteWeights[playerID] = weight
+	}
+	for playerID, bonus := range gs.VoteBonus {
+		view.VoteBonus[playerID] = bonus
+	}
+	for playerID, afk := range gs.AFK {
+		view.AFK[playerID] = afk
+	}
+	for _, event := range gs.Events {
+		if event.Type == "judge_note" || event.Type == "judge_ruling" {
+			view.Notes = append(view.Notes, event)
+		}
+	}
+	return view, nil
+}
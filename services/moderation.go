@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+var (
+	ErrReportSelf      = NewError(CodeReportSelf, "不能举报自己")
+	ErrReportReason    = NewError(CodeReportReason, "举报原因不能为空")
+	ErrReportNotFound  = NewError(CodeReportNotFound, "举报不存在")
+	ErrReportReviewed  = NewError(CodeReportReviewed, "举报已处理")
+	ErrInvalidBan      = NewError(CodeInvalidBan, "无效的封禁类型或目标")
+	ErrBanNotFound     = NewError(CodeBanNotFound, "封禁不存在")
+	ErrInvalidDecision = NewError(CodeInvalidDecision, "无效的处理方式")
+)
+
+// BannedError 账号或IP处于封禁中
+type BannedError struct {
+	Ban *models.Ban
+}
+
+func (e *BannedError) Error() string {
+	if e.Ban.ExpiresAt == 0 {
+		return fmt.Sprintf("已被永久封禁：%s", e.Ban.Reason)
+	}
+	return fmt.Sprintf("已被封禁至 %s：%s", time.Unix(e.Ban.ExpiresAt, 0).Format(time.DateTime), e.Ban.Reason)
+}
+
+// ModerationManager 举报和封禁管理器
+type ModerationManager struct {
+	store storage.Store
+}
+
+// NewModerationManager 创建举报和封禁管理器实例
+func NewModerationManager(store storage.Store) *ModerationManager {
+	return &ModerationManager{store: store}
+}
+
+// Report 举报玩家，举报进入待处理队列
+func (mm *ModerationManager) Report(reporterID, reportedID, roomID, reason, details string) (*models.Report, error) {
+	if reporterID == reportedID {
+		return nil, ErrReportSelf
+	}
+	if reason = strings.TrimSpace(reason); reason == "" {
+		return nil, ErrReportReason
+	}
+	if _, err := mm.store.GetUserByID(reportedID); err != nil {
+		if err == storage.ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	report := &models.Report{
+		ID:         generateSessionID(),
+		ReporterID: reporterID,
+		ReportedID: reportedID,
+		RoomID:     roomID,
+		Reason:     reason,
+		Details:    strings.TrimSpace(details),
+		Status:     models.ReportOpen,
+		CreatedAt:  time.Now().Unix(),
+	}
+	if err := mm.store.SaveReport(report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ListReports 分页列出举报，status为空时列出全部
+func (mm *ModerationManager) ListReports(status string, offset, limit int) ([]*models.Report, error) {
+	return mm.store.ListReports(status, offset, limit)
+}
+
+// ReviewReport 处理举报，decision为dismiss时驳回，为ban时封禁被举报账号
+// duration为封禁时长，0表示永久封禁
+func (mm *ModerationManager) ReviewReport(reportID, reviewerID, decision, note string, duration time.Duration) (*models.Report, *models.Ban, error) {
+	report, err := mm.store.GetReport(reportID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, nil, ErrReportNotFound
+		}
+		return nil, nil, err
+	}
+	if report.Status != models.ReportOpen {
+		return nil, nil, ErrReportReviewed
+	}
+
+	var ban *models.Ban
+	switch decision {
+	case "dismiss":
+		report.Status = models.ReportDismissed
+	case "ban":
+		reason := report.Reason
+		if note != "" {
+			reason = note
+		}
+		ban, err = mm.Ban(models.BanAccount, report.ReportedID, reason, reviewerID, duration)
+		if err != nil {
+			return nil, nil, err
+		}
+		report.Status = models.ReportActioned
+	default:
+		return nil, nil, ErrInvalidDecision
+	}
+
+	report.ReviewedBy = reviewerID
+	report.ReviewNote = note
+	report.ReviewedAt = time.Now().Unix()
+	if err := mm.store.SaveReport(report); err != nil {
+		return nil, nil, err
+	}
+	return report, ban, nil
+}
+
+// Ban 封禁账号或IP，duration为0表示永久封禁
+func (mm *ModerationManager) Ban(banType, target, reason, createdBy string, duration time.Duration) (*models.Ban, error) {
+	if (banType != models.BanAccount && banType != models.BanIP) || strings.TrimSpace(target) == "" {
+		return nil, ErrInvalidBan
+	}
+
+	now := time.Now()
+	ban := &models.Ban{
+		ID:        generateSessionID(),
+		Type:      banType,
+		Target:    strings.TrimSpace(target),
+		Reason:    reason,
+		CreatedBy: createdBy,
+		CreatedAt: now.Unix(),
+	}
+	if duration > 0 {
+		ban.ExpiresAt = now.Add(duration).Unix()
+	}
+
+	if err := mm.store.SaveBan(ban); err != nil {
+		return nil, err
+	}
+	return ban, nil
+}
+
+// Unban 解除封禁
+func (mm *ModerationManager) Unban(banID string) error {
+	err := mm.store.DeleteBan(banID)
+	if err == storage.ErrNotFound {
+		return ErrBanNotFound
+	}
+	return err
+}
+
+// ListBans 列出所有封禁
+func (mm *ModerationManager) ListBans() ([]*models.Ban, error) {
+	return mm.store.ListBans()
+}
+
+// CheckBanned 检查账号或IP是否处于封禁中，被封禁时返回*BannedError
+func (mm *ModerationManager) CheckBanned(playerID, ip string) error {
+	bans, err := mm.store.ListBans()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, ban := range bans {
+		if ban.ExpiresAt != 0 && ban.ExpiresAt <= now {
+			continue
+		}
+		if (ban.Type == models.BanAccount && playerID != "" && ban.Target == playerID) ||
+			(ban.Type == models.BanIP && ip != "" && ban.Target == ip) {
+			return &BannedError{Ban: ban}
+		}
+	}
+	return nil
+}
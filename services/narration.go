@@ -0,0 +1,143 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// 法官旁白的步骤，客户端可以按步骤播放对应的配音
+const (
+	NarrationNightStart = "night_start" // 天黑请闭眼
+	NarrationNightStep  = "night_step"  // 夜晚步骤的睁眼和闭眼台词，见nightSteps
+	NarrationCampaign   = "campaign"    // 天亮，公布昨晚的死亡情况并开始竞选警长
+	NarrationSheriff    = "sheriff"     // 竞选发言结束，警下投票选出警长
+	NarrationDayStart   = "day_start"   // 天亮，公布昨晚的死亡情况；竞选之后公布竞选结果
+	NarrationVoteStart  = "vote_start"  // 发言结束，开始投票
+	NarrationGameEnd    = "game_end"    // 公布对局结果
+)
+
+// SetNarrationVoice 设置法官旁白的语音合成地址模板，{text}替换为URL编码后的台词，为空时旁白不附带语音地址
+func (rm *RoomManager) SetNarrationVoice(template string) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.narrationVoice = template
+}
+
+// narrationAudioURL 按配置的模板生成台词的语音地址，未配置时返回空
+func (gc *GameController) narrationAudioURL(text string) string {
+	if gc.game.roomManager == nil {
+		return ""
+	}
+	gc.game.roomManager.mutex.RLock()
+	template := gc.game.roomManager.narrationVoice
+	gc.game.roomManager.mutex.RUnlock()
+
+	if template == "" {
+		return ""
+	}
+	return strings.ReplaceAll(template, "{text}", url.QueryEscape(text))
+}
+
+// narrate 向房间广播一条法官旁白，台词按房间的语言生成，同时附带消息键，在对局goroutine中执行
+func (gc *GameController) narrate(step, key string, params ...interface{}) {
+	msg := localize(map[string]interface{}{
+		"type":  "narration",
+		"step":  step,
+		"round": gc.game.Round,
+		"phase": gc.game.Phase,
+	}, gc.locale(), "text", key, params...)
+	text := msg["text"].(string)
+	if gc.game.NightStep != "" {
+		msg["night_step"] = gc.game.NightStep
+	}
+	if audio := gc.narrationAudioURL(text); audio != "" {
+		msg["audio_url"] = audio
+	}
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, msg)
+}
+
+// narratePhase 新阶段开始时的旁白，在AI行动之前发出，在对局goroutine中执行
+func (gc *GameController) narratePhase() {
+	deaths := gc.game.deathsSincePhaseChange()
+
+	switch gc.game.Phase {
+	case PhaseNight:
+		if len(deaths) > 0 {
+			gc.narrate(NarrationNightStart, "narration.night_start_deaths", gc.game.playerNames(deaths))
+		} else {
+			gc.narrate(NarrationNightStart, "narration.night_start")
+		}
+
+	case PhaseCampaign:
+		if len(deaths) > 0 {
+			gc.narrate(NarrationCampaign, "narration.campaign_start_deaths", gc.game.playerNames(deaths))
+		} else {
+			gc.narrate(NarrationCampaign, "narration.campaign_start")
+		}
+
+	case PhaseSheriffVote:
+		gc.narrate(NarrationSheriff, "narration.sheriff_vote_start", gc.game.playerNames(gc.game.sheriffCandidates()))
+
+	case PhaseDay:
+		// 竞选之后的白天，昨晚的死亡情况已经在竞选开始时公布
+		if sheriff, elected := gc.game.electionSincePhaseChange(); elected {
+			if sheriff != "" {
+				gc.narrate(NarrationDayStart, "narration.day_start_sheriff", gc.game.playerNames([]string{sheriff}))
+			} else {
+				gc.narrate(NarrationDayStart, "narration.day_start_no_sheriff")
+			}
+			break
+		}
+		if len(deaths) > 0 {
+			gc.narrate(NarrationDayStart, "narration.day_start_deaths", gc.game.playerNames(deaths))
+		} else {
+			gc.narrate(NarrationDayStart, "narration.day_start")
+		}
+
+	case PhaseVote:
+		gc.narrate(NarrationVoteStart, "narration.vote_start")
+	}
+}
+
+// deathsSincePhaseChange 获取上一个阶段中出局的玩家，阶段切换事件已经记录
+func (gs *GameState) deathsSincePhaseChange() []string {
+	var deaths []string
+	for i := len(gs.Events) - 2; i >= 0; i-- {
+		event := gs.Events[i]
+		if event.Type == "phase_change" {
+			break
+		}
+		if event.Type == "death" {
+			deaths = append([]string{event.PlayerID}, deaths...)
+		}
+	}
+	return deaths
+}
+
+// hasRole 本局是否有该角色，不论是否存活
+func (gs *GameState) hasRole(role models.Role) bool {
+	for _, player := range gs.Players {
+		if player.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// playerNames 获取多名玩家的显示名称，按房间的语言连接
+func (gs *GameState) playerNames(playerIDs []string) string {
+	names := make([]string, 0, len(playerIDs))
+	for _, playerID := range playerIDs {
+		name := playerID
+		for _, player := range gs.Players {
+			if player.ID == playerID && player.Name != "" {
+				name = player.Name
+			}
+		}
+		names = append(names, name)
+	}
+	return joinNames(Locale(gs.Room.Locale), names)
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"slices"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// 夜晚的步骤，按nightSteps的顺序依次进行
+const (
+	NightStepCupid  = "cupid"  // 丘比特连情侣，只在第一晚
+	NightStepWolves = "wolves" // 狼人选择击杀目标
+	NightStepSeer   = "seer"   // 预言家查验
+	NightStepWitch  = "witch"  // 女巫得知被击杀的玩家后决定是否用药
+	NightStepGuard  = "guard"  // 守卫守护
+)
+
+const (
+	nightStepDuration = 30 * time.Second // 每个夜晚步骤的行动时限
+	// idleNightStepDuration 角色已全部出局的步骤仍然照常进行，只是等待的时间更短，避免跳过步骤泄露身份
+	idleNightStepDuration = 5 * time.Second
+)
+
+// nightStep 夜晚的一个步骤：哪些角色睁眼行动。法官的睁眼和闭眼台词为消息night.<name>.open和night.<name>.close
+type nightStep struct {
+	name      string
+	roles     []models.Role
+	firstOnly bool // 只在第一晚进行
+}
+
+// nightSteps 夜晚按顺序进行的步骤，本局板子中没有的角色对应的步骤被跳过
+var nightSteps = []nightStep{
+	{NightStepCupid, []models.Role{models.Cupid}, true},
+	{NightStepWolves, []models.Role{models.Werewolf, models.WhiteWolf}, false},
+	{NightStepSeer, []models.Role{models.Seer}, false},
+	{NightStepWitch, []models.Role{models.Witch}, false},
+	{NightStepGuard, []models.Role{models.Guard}, false},
+}
+
+// findNightStep 按名称查找夜晚步骤
+func findNightStep(name string) (nightStep, bool) {
+	for _, step := range nightSteps {
+		if step.name == name {
+			return step, true
+		}
+	}
+	return nightStep{}, false
+}
+
+// nextNightStep 获取current之后本局要进行的下一个步骤，current为空时获取第一个步骤，调用方需持有锁
+func (gs *GameState) nextNightStep(current string) (nightStep, bool) {
+	started := current == ""
+	for _, step := range nightSteps {
+		if !started {
+			started = step.name == current
+			continue
+		}
+		if step.firstOnly && gs.Round != 1 {
+			continue
+		}
+		for _, role := range step.roles {
+			if gs.hasRole(role) {
+				return step, true
+			}
+		}
+	}
+	return nightStep{}, false
+}
+
+// beginNight 进入夜晚的第一个步骤，调用方需持有锁
+func (gs *GameState) beginNight() {
+	gs.NightStep = ""
+	step, ok := gs.nextNightStep("")
+	if !ok {
+		gs.startPhaseClock(phaseDuration)
+		return
+	}
+	gs.enterNightStep(step)
+}
+
+// enterNightStep 进入夜晚的一个步骤并开始该步骤的计时，调用方需持有锁
+func (gs *GameState) enterNightStep(step nightStep) {
+	gs.NightStep = step.name
+	gs.recordEvent(models.GameEvent{Type: "night_step", Content: step.name})
+
+	duration := idleNightStepDuration
+	if len(gs.nightStepPlayers()) > 0 {
+		duration = nightStepDuration
+	}
+	gs.startPhaseClock(duration)
+}
+
+// inNightStep 该角色是否在当前的夜晚步骤中行动
+func (gs *GameState) inNightStep(role models.Role) bool {
+	step, ok := findNightStep(gs.NightStep)
+	return ok && slices.Contains(step.roles, role)
+}
+
+// nightStepPlayers 当前夜晚步骤中行动的存活玩家
+func (gs *GameState) nightStepPlayers() []models.Player {
+	var players []models.Player
+	for _, player := range gs.Players {
+		if player.Alive && gs.inNightStep(player.Role) {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+// nightActionDone 玩家是否已完成本晚的行动。女巫可以分别决定两瓶药，选择pass或没有可用的药时完成
+func (gs *GameState) nightActionDone(player models.Player) bool {
+	switch player.Role {
+	case models.Werewolf, models.WhiteWolf:
+		return gs.hasAction(player.ID, "kill")
+	case models.Seer:
+		return gs.hasAction(player.ID, "check")
+	case models.Guard:
+		return gs.hasAction(player.ID, "protect")
+	case models.Cupid:
+		return gs.Round != 1 || gs.linkCount(player.ID) >= 2
+	case models.Witch:
+		canSave, canPoison := gs.witchCanUse(player.ID)
+		return gs.hasAction(player.ID, "pass") || (!canSave && !canPoison)
+	}
+	return true
+}
+
+// witchCanUse 女巫今晚是否还可以使用解药和毒药：药没用过、今晚还没提交，解药还要求今晚有人被狼人击杀且不是女巫自己
+func (gs *GameState) witchCanUse(playerID string) (canSave, canPoison bool) {
+	skills := gs.Skills[playerID]
+	if skills == nil {
+		return false, false
+	}
+	victim := wolfConsensusTarget(gs.Actions)
+	canSave = !skills.SavePotion.Used && !gs.hasAction(playerID, "save") && victim != "" && victim != playerID
+	canPoison = !skills.PoisonPotion.Used && !gs.hasAction(playerID, "poison")
+	return canSave, canPoison
+}
+
+// hasAction 玩家在本阶段是否执行过该类型的动作
+func (gs *GameState) hasAction(playerID, actionType string) bool {
+	for _, action := range gs.Actions {
+		if action.PlayerID == playerID && action.Type == actionType {
+			return true
+		}
+	}
+	return false
+}
+
+// isNightStepComplete 当前夜晚步骤是否结束：到时，或者存活的真人玩家和外部机器人都已完成行动，
+// AI在步骤开始时已经行动
+func (sm *StateMachine) isNightStepComplete() bool {
+	if sm.game.phaseExpired() {
+		return true
+	}
+	for _, player := range sm.game.nightStepPlayers() {
+		if player.Type != models.AIPlayer && !sm.game.nightActionDone(player) {
+			return false
+		}
+	}
+	return true
+}
+
+// startNightStep 开始当前的夜晚步骤：念出睁眼台词，本步骤的AI立即行动，真人女巫收到被击杀的玩家，
+// 然后为其余玩家设置本步骤的计时，在对局goroutine中执行
+func (gc *GameController) startNightStep() {
+	round, name := gc.game.Round, gc.game.NightStep
+	if step, ok := findNightStep(name); ok {
+		gc.narrate(NarrationNightStep, "night."+step.name+".open")
+	}
+
+	if name == NightStepWitch {
+		victim := wolfConsensusTarget(gc.game.Actions)
+		for _, player := range gc.game.nightStepPlayers() {
+			if player.Type != models.AIPlayer {
+				gc.sink.SendToPlayer(player.ID, map[string]interface{}{
+					"type":          "witch_wake",
+					"round":         round,
+					"victim":        victim,
+					"state_version": gc.game.StateVersion,
+				})
+			}
+		}
+	}
+
+	// AI的行动可能直接结束本步骤，此时下一个步骤已经开始计时
+	gc.processAIActions()
+	if !gc.game.IsStarted || gc.game.Phase != PhaseNight || gc.game.Round != round || gc.game.NightStep != name {
+		return
+	}
+	gc.pushAvailableActions()
+
+	gc.armPhaseTimer()
+	gc.armBotTimer()
+	gc.armAbsenceTimer()
+}
+
+// advanceNightStep 当前夜晚步骤结束后进入下一个步骤，进入了新步骤时返回true；
+// 最后一个步骤结束时返回false，由阶段切换结束夜晚，在对局goroutine中执行
+func (gc *GameController) advanceNightStep() bool {
+	if gc.game.Phase != PhaseNight || !gc.stateMachine.isNightStepComplete() {
+		return false
+	}
+	next, ok := gc.game.nextNightStep(gc.game.NightStep)
+	if !ok {
+		return false
+	}
+
+	gc.narrateNightStepEnd()
+	gc.game.enterNightStep(next)
+	gc.startNightStep()
+	if gc.game.IsStarted && gc.game.Phase == PhaseNight && gc.game.NightStep == next.name {
+		gc.broadcastGameState()
+	}
+	return true
+}
+
+// narrateNightStepEnd 念出当前夜晚步骤的闭眼台词，在对局goroutine中执行
+func (gc *GameController) narrateNightStepEnd() {
+	if step, ok := findNightStep(gc.game.NightStep); ok {
+		gc.narrate(NarrationNightStep, "night."+step.name+".close")
+	}
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownProvider   = NewError(CodeUnknownProvider, "不支持或未配置的登录方式")
+	ErrInvalidOAuthState = NewError(CodeInvalidOAuthState, "登录请求已过期，请重新登录")
+	ErrOAuthFailed       = NewError(CodeOAuthFailed, "第三方登录失败")
+)
+
+// 第三方登录平台
+const (
+	ProviderGitHub = "github"
+	ProviderGoogle = "google"
+	ProviderWeChat = "wechat"
+)
+
+// 授权请求state的有效期和受众，state只能用于完成一次第三方登录
+const (
+	OAuthStateTTL      = 10 * time.Minute
+	oauthStateAudience = "oauth"
+)
+
+// oauthTimeout 请求第三方平台接口的超时时间
+const oauthTimeout = 10 * time.Second
+
+// ExternalIdentity 第三方平台返回的用户身份
+type ExternalIdentity struct {
+	Provider string
+	Subject  string // 平台内唯一的用户ID
+	Name     string // 平台上的昵称，可能为空
+}
+
+// OAuthProvider 第三方登录平台，使用OAuth授权码流程
+type OAuthProvider interface {
+	// AuthURL 用户授权页面的地址，授权后带着code和state跳转到redirectURI
+	AuthURL(state, redirectURI string) string
+	// Exchange 用授权码换取用户身份
+	Exchange(ctx context.Context, code, redirectURI string) (*ExternalIdentity, error)
+}
+
+// OAuthManager 已配置的第三方登录平台
+type OAuthManager struct {
+	providers map[string]OAuthProvider
+}
+
+// NewOAuthManager 创建第三方登录管理器实例，平台通过Register添加
+func NewOAuthManager() *OAuthManager {
+	return &OAuthManager{providers: make(map[string]OAuthProvider)}
+}
+
+// Register 添加第三方登录平台
+func (om *OAuthManager) Register(name string, provider OAuthProvider) {
+	om.providers[name] = provider
+}
+
+// Provider 获取第三方登录平台，未配置时返回ErrUnknownProvider
+func (om *OAuthManager) Provider(name string) (OAuthProvider, error) {
+	provider, exists := om.providers[name]
+	if !exists {
+		return nil, ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// Providers 已配置的平台名称，按名称排序
+func (om *OAuthManager) Providers() []string {
+	names := make([]string, 0, len(om.providers))
+	for name := range om.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OAuthStateClaims 授权请求state的声明，ID同时写入浏览器Cookie，回调时两者一致才接受，
+// 防止他人诱导用户完成授权后把身份登录或绑定到攻击者的账号
+type OAuthStateClaims struct {
+	Provider   string `json:"provider"`
+	LinkUserID string `json:"link,omitempty"` // 绑定身份时为当前账号的玩家ID，登录时为空
+	jwt.RegisteredClaims
+}
+
+// IssueOAuthState 签发授权请求的state，返回state和需要写入Cookie的随机值
+func (am *AuthManager) IssueOAuthState(provider, linkUserID string) (string, string, error) {
+	now := time.Now()
+	nonce := generateSessionID()
+	claims := OAuthStateClaims{
+		Provider:   provider,
+		LinkUserID: linkUserID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        nonce,
+			Audience:  jwt.ClaimStrings{oauthStateAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(OAuthStateTTL)),
+		},
+	}
+
+	state, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(am.secret)
+	if err != nil {
+		return "", "", err
+	}
+	return state, nonce, nil
+}
+
+// ParseOAuthState 校验授权回调中的state，nonce为浏览器Cookie中的随机值
+func (am *AuthManager) ParseOAuthState(state, provider, nonce string) (*OAuthStateClaims, error) {
+	claims := &OAuthStateClaims{}
+	token, err := jwt.ParseWithClaims(state, claims, func(token *jwt.Token) (interface{}, error) {
+		return am.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(oauthStateAudience))
+	if err != nil || !token.Valid || claims.Provider != provider || nonce == "" || claims.ID != nonce {
+		return nil, ErrInvalidOAuthState
+	}
+	return claims, nil
+}
+
+// oauth2Provider 标准OAuth 2.0平台：授权码换取访问令牌，再用令牌获取用户信息
+type oauth2Provider struct {
+	name         string
+	clientID     string
+	clientSecret string
+	authURL      string
+	tokenURL     string
+	userURL      string
+	scope        string
+	parseUser    func(data []byte) (subject, name string, err error)
+	client       *http.Client
+}
+
+// NewGitHubProvider 创建GitHub登录
+func NewGitHubProvider(clientID, clientSecret string) OAuthProvider {
+	return &oauth2Provider{
+		name:         ProviderGitHub,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		authURL:      "https://github.com/login/oauth/authorize",
+		tokenURL:     "https://github.com/login/oauth/access_token",
+		userURL:      "https://api.github.com/user",
+		scope:        "read:user",
+		parseUser: func(data []byte) (string, string, error) {
+			var user struct {
+				ID    int64  `json:"id"`
+				Login string `json:"login"`
+				Name  string `json:"name"`
+			}
+			if err := json.Unmarshal(data, &user); err != nil || user.ID == 0 {
+				return "", "", fmt.Errorf("无法解析GitHub用户信息")
+			}
+			name := user.Name
+			if name == "" {
+				name = user.Login
+			}
+			return strconv.FormatInt(user.ID, 10), name, nil
+		},
+		client: &http.Client{Timeout: oauthTimeout},
+	}
+}
+
+// NewGoogleProvider 创建Google登录
+func NewGoogleProvider(clientID, clientSecret string) OAuthProvider {
+	return &oauth2Provider{
+		name:         ProviderGoogle,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		authURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		tokenURL:     "https://oauth2.googleapis.com/token",
+		userURL:      "https://openidconnect.googleapis.com/v1/userinfo",
+		scope:        "openid profile",
+		parseUser: func(data []byte) (string, string, error) {
+			var user struct {
+				Sub  string `json:"sub"`
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(data, &user); err != nil || user.Sub == "" {
+				return "", "", fmt.Errorf("无法解析Google用户信息")
+			}
+			return user.Sub, user.Name, nil
+		},
+		client: &http.Client{Timeout: oauthTimeout},
+	}
+}
+
+// AuthURL 用户授权页面的地址
+func (p *oauth2Provider) AuthURL(state, redirectURI string) string {
+	query := url.Values{
+		"client_id":     {p.clientID},
+		"redirect_uri":  {redirectURI},
+		"response_type": {"code"},
+		"scope":         {p.scope},
+		"state":         {state},
+	}
+	return p.authURL + "?" + query.Encode()
+}
+
+// Exchange 用授权码换取访问令牌并获取用户信息
+func (p *oauth2Provider) Exchange(ctx context.Context, code, redirectURI string) (*ExternalIdentity, error) {
+	form := url.Values{
+		"client_id":     {p.clientID},
+		"client_secret": {p.clientSecret},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"grant_type":    {"authorization_code"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.doJSON(req, &token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("%s授权失败: %s %s", p.name, token.Error, token.ErrorDescription)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, p.userURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取%s用户信息失败: %s", p.name, resp.Status)
+	}
+
+	subject, name, err := p.parseUser(data)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalIdentity{Provider: p.name, Subject: subject, Name: name}, nil
+}
+
+// doJSON 发送请求并解析JSON响应，失败的响应同样解析，由调用方根据错误字段判断
+func (p *oauth2Provider) doJSON(req *http.Request, result any) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("%s返回 %s", p.name, resp.Status)
+	}
+	return nil
+}
+
+// wechatProvider 微信开放平台网站应用的扫码登录，与小程序使用不同的AppID，
+// 两者绑定到同一开放平台账号时返回相同的unionid，对应同一个玩家账号
+type wechatProvider struct {
+	appID  string
+	secret string
+	client *http.Client
+}
+
+// NewWeChatProvider 创建微信扫码登录
+func NewWeChatProvider(appID, secret string) OAuthProvider {
+	return &wechatProvider{appID: appID, secret: secret, client: &http.Client{Timeout: oauthTimeout}}
+}
+
+// AuthURL 微信扫码授权页面的地址
+func (p *wechatProvider) AuthURL(state, redirectURI string) string {
+	query := url.Values{
+		"appid":         {p.appID},
+		"redirect_uri":  {redirectURI},
+		"response_type": {"code"},
+		"scope":         {"snsapi_login"},
+		"state":         {state},
+	}
+	return "https://open.weixin.qq.com/connect/qrconnect?" + query.Encode() + "#wechat_redirect"
+}
+
+// Exchange 用授权码换取openid和unionid，再获取昵称
+func (p *wechatProvider) Exchange(ctx context.Context, code, redirectURI string) (*ExternalIdentity, error) {
+	var token struct {
+		AccessToken string `json:"access_token"`
+		OpenID      string `json:"openid"`
+		UnionID     string `json:"unionid"`
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+	}
+	err := p.get(ctx, "https://api.weixin.qq.com/sns/oauth2/access_token", url.Values{
+		"appid":      {p.appID},
+		"secret":     {p.secret},
+		"code":       {code},
+		"grant_type": {"authorization_code"},
+	}, &token)
+	if err != nil {
+		return nil, err
+	}
+	if token.ErrCode != 0 || token.OpenID == "" {
+		return nil, fmt.Errorf("微信授权失败 %d: %s", token.ErrCode, token.ErrMsg)
+	}
+
+	// 昵称只用于新账号的显示名称，获取失败时不影响登录
+	var user struct {
+		Nickname string `json:"nickname"`
+	}
+	p.get(ctx, "https://api.weixin.qq.com/sns/userinfo", url.Values{
+		"access_token": {token.AccessToken},
+		"openid":       {token.OpenID},
+	}, &user)
+
+	return &ExternalIdentity{Provider: ProviderWeChat, Subject: wechatSubject(token.OpenID, token.UnionID), Name: user.Nickname}, nil
+}
+
+// get 请求微信接口并解析JSON响应
+func (p *wechatProvider) get(ctx context.Context, endpoint string, query url.Values, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("微信接口返回 %s", resp.Status)
+	}
+	return nil
+}
+
+// wechatSubject 微信身份的用户ID，有unionid时使用unionid，小程序和网站登录得到同一个身份
+func wechatSubject(openID, unionID string) string {
+	if unionID != "" {
+		return unionID
+	}
+	return openID
+}
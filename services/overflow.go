@@ -0,0 +1,121 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// OverflowPolicy 连接发送缓冲区已满时的处理策略
+type OverflowPolicy string
+
+const (
+	// OverflowDisconnect 关闭连接，客户端重连后通过快照恢复状态
+	OverflowDisconnect OverflowPolicy = "disconnect"
+	// OverflowDropOldest 丢弃最早的消息，V2客户端可根据序号缺口请求补发
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowCoalesce 同类状态消息只保留最新一条，没有可合并的消息时关闭连接
+	OverflowCoalesce OverflowPolicy = "coalesce"
+)
+
+// ParseOverflowPolicy 解析配置中的缓冲区溢出策略，未配置时关闭连接
+func ParseOverflowPolicy(value string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(value); policy {
+	case "":
+		return OverflowDisconnect, nil
+	case OverflowDisconnect, OverflowDropOldest, OverflowCoalesce:
+		return policy, nil
+	}
+	return "", fmt.Errorf("不支持的发送缓冲区溢出策略 %s", value)
+}
+
+// SendQueueConfig 每个连接的发送缓冲区配置
+type SendQueueConfig struct {
+	Size   int            // 缓冲区可容纳的消息数
+	Policy OverflowPolicy // 缓冲区已满时的处理策略
+}
+
+// coalescableTypes 可以合并的消息类型，这些消息都包含完整状态，新消息可以替代旧消息
+var coalescableTypes = map[string]bool{
+	"game_state":  true,
+	"countdown":   true,
+	"room_update": true,
+}
+
+// coalesceKind 获取消息的合并类型，不可合并的消息返回空字符串
+func coalesceKind(msg []byte) string {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(msg, &header); err != nil || !coalescableTypes[header.Type] {
+		return ""
+	}
+	return header.Type
+}
+
+// outboundMessage 等待写出的消息，kind为合并类型
+type outboundMessage struct {
+	data []byte
+	kind string
+}
+
+// push 将消息加入发送队列，队列已满时按溢出策略处理，返回false表示需要关闭连接
+func (c *client) push(msg outboundMessage) bool {
+	c.queueMutex.Lock()
+	defer c.queueMutex.Unlock()
+
+	if len(c.queue) >= c.queueSize {
+		switch c.policy {
+		case OverflowDropOldest:
+			c.queue = c.queue[1:]
+			c.dropped++
+			c.logger.Warn("发送缓冲区已满，丢弃最早的消息", "dropped", c.dropped)
+		case OverflowCoalesce:
+			if !c.coalesceLocked(msg.kind) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	c.queue = append(c.queue, msg)
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// coalesceLocked 删除队列中与新消息同类的旧消息，返回是否腾出了空间，调用方需持有c.queueMutex
+func (c *client) coalesceLocked(kind string) bool {
+	if kind == "" {
+		return false
+	}
+
+	kept := c.queue[:0]
+	for _, queued := range c.queue {
+		if queued.kind != kind {
+			kept = append(kept, queued)
+		}
+	}
+	removed := len(c.queue) - len(kept)
+	for i := len(kept); i < len(c.queue); i++ {
+		c.queue[i] = outboundMessage{}
+	}
+	c.queue = kept
+	return removed > 0
+}
+
+// pop 取出队列中最早的消息
+func (c *client) pop() ([]byte, bool) {
+	c.queueMutex.Lock()
+	defer c.queueMutex.Unlock()
+
+	if len(c.queue) == 0 {
+		return nil, false
+	}
+	msg := c.queue[0]
+	c.queue[0] = outboundMessage{}
+	c.queue = c.queue[1:]
+	return msg.data, true
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// defaultStreamDelay 直播叠加层和旁观事件流默认的延迟，避免玩家通过直播画面获知投票等进行中的信息
+const defaultStreamDelay = 30 * time.Second
+
+// Overlay 直播画面叠加层（OBS浏览器源等）使用的对局概况，只包含旁观者可见的信息，按直播延迟返回
+type Overlay struct {
+	RoomID      string            `json:"room_id"`
+	RoomName    string            `json:"room_name"`
+	Status      models.RoomStatus `json:"status"`
+	IsStarted   bool              `json:"is_started"`
+	Phase       string            `json:"phase,omitempty"`
+	NightStep   string            `json:"night_step,omitempty"`
+	Round       int               `json:"round,omitempty"`
+	PhaseEndsAt int64             `json:"phase_ends_at,omitempty"` // 当前阶段截止时间的毫秒时间戳
+	// Players 玩家列表，存活玩家的角色不公开，出局玩家的角色公开
+	Players    []models.Player      `json:"players"`
+	AliveCount int                  `json:"alive_count"`
+	Deaths     []models.PlayerDeath `json:"deaths,omitempty"`
+	Votes      *VoteTally           `json:"votes,omitempty"`     // 投票阶段中已投出的票，按票权统计
+	LastVote   *VoteTally           `json:"last_vote,omitempty"` // 最近一次放逐投票的计票结果
+	Result     string               `json:"result,omitempty"`    // 对局结果，对局进行中为空
+	UpdatedAt  int64                `json:"updated_at"`          // 概况对应的时间，毫秒时间戳
+	Delay      int                  `json:"delay"`               // 相对实际对局延迟的秒数
+}
+
+// overlaySnapshot 某一时刻的对局概况
+type overlaySnapshot struct {
+	at      time.Time
+	overlay Overlay
+}
+
+// overlayFeed 各房间最近的对局概况，只保留延迟窗口内的记录和窗口外最新的一条
+type overlayFeed struct {
+	delay     time.Duration
+	snapshots map[string][]overlaySnapshot
+	mutex     sync.Mutex
+}
+
+func newOverlayFeed() *overlayFeed {
+	return &overlayFeed{delay: defaultStreamDelay, snapshots: make(map[string][]overlaySnapshot)}
+}
+
+// record 记录房间当前的对局概况并清理已经用不到的旧记录
+func (f *overlayFeed) record(roomID string, overlay Overlay) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := time.Now()
+	snapshots := append(f.snapshots[roomID], overlaySnapshot{at: now, overlay: overlay})
+	// 保留延迟窗口外最新的一条，它是当前应当显示的内容
+	cutoff := now.Add(-f.delay)
+	drop := 0
+	for drop+1 < len(snapshots) && !snapshots[drop+1].at.After(cutoff) {
+		drop++
+	}
+	f.snapshots[roomID] = append(snapshots[:0:0], snapshots[drop:]...)
+}
+
+// latest 获取延迟后应当显示的对局概况，延迟窗口外还没有记录时返回false
+func (f *overlayFeed) latest(roomID string) (Overlay, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	cutoff := time.Now().Add(-f.delay)
+	snapshots := f.snapshots[roomID]
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if !snapshots[i].at.After(cutoff) {
+			return snapshots[i].overlay, true
+		}
+	}
+	return Overlay{}, false
+}
+
+// SetStreamDelay 设置直播叠加层和旁观事件流的延迟，0表示不延迟
+func (rm *RoomManager) SetStreamDelay(delay time.Duration) {
+	if delay < 0 {
+		delay = 0
+	}
+	rm.overlays.mutex.Lock()
+	defer rm.overlays.mutex.Unlock()
+
+	rm.overlays.delay = delay
+}
+
+// StreamDelay 获取直播叠加层和旁观事件流的延迟
+func (rm *RoomManager) StreamDelay() time.Duration {
+	rm.overlays.mutex.Lock()
+	defer rm.overlays.mutex.Unlock()
+
+	return rm.overlays.delay
+}
+
+// Overlay 获取房间延迟后的对局概况，延迟窗口外还没有对局记录时返回房间的等待状态
+func (rm *RoomManager) Overlay(roomID string) (*Overlay, error) {
+	room, err := rm.GetRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	overlay, exists := rm.overlays.latest(roomID)
+	if !exists {
+		overlay = Overlay{
+			RoomID:    room.ID,
+			RoomName:  room.Name,
+			Status:    models.RoomWaiting,
+			Players:   make([]models.Player, len(room.Players)),
+			UpdatedAt: time.Now().UnixMilli(),
+		}
+		for i, player := range room.Players {
+			player.Role = ""
+			overlay.Players[i] = player
+		}
+	}
+	overlay.Delay = int(rm.StreamDelay() / time.Second)
+	return &overlay, nil
+}
+
+// recordOverlay 记录当前的对局概况，供直播叠加层按延迟获取，在对局goroutine中执行
+func (gc *GameController) recordOverlay() {
+	if gc.game.roomManager == nil {
+		return
+	}
+	view, err := gc.game.playerGameView("")
+	if err != nil {
+		return
+	}
+
+	overlay := Overlay{
+		RoomID:      gc.game.Room.ID,
+		RoomName:    gc.game.Room.Name,
+		Status:      gc.game.Room.Status,
+		IsStarted:   gc.game.IsStarted,
+		Phase:       view.Phase,
+		NightStep:   view.NightStep,
+		Round:       view.Round,
+		PhaseEndsAt: view.PhaseEndsAt,
+		Players:     view.Players,
+		AliveCount:  len(view.AlivePlayers),
+		Deaths:      view.Deaths,
+		LastVote:    gc.lastVote,
+		Result:      gc.game.Result,
+		UpdatedAt:   time.Now().UnixMilli(),
+	}
+	if gc.game.IsStarted && gc.game.Phase == PhaseVote {
+		// 投票尚未结束，不显示按当前票数会被放逐的玩家
+		overlay.Votes = gc.game.tallyVotes()
+		overlay.Votes.Eliminated = ""
+	}
+	gc.game.roomManager.overlays.record(gc.game.Room.ID, overlay)
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"sync"
+)
+
+// payload 一条已序列化的待发送消息，合并类型和protobuf编码在第一次需要时生成，
+// 之后在所有连接间复用，广播给大量连接时每种编码只转换一次
+type payload struct {
+	data []byte // JSON格式
+
+	kindOnce sync.Once
+	kind     string
+
+	protobufOnce sync.Once
+	protobuf     []byte
+	protobufErr  error
+}
+
+// newPayload 包装已序列化的JSON消息
+func newPayload(data []byte) *payload {
+	return &payload{data: data}
+}
+
+// coalesceKind 消息的合并类型，见coalesceKind
+func (p *payload) coalesceKind() string {
+	p.kindOnce.Do(func() {
+		p.kind = coalesceKind(p.data)
+	})
+	return p.kind
+}
+
+// encoded 按连接的编码获取消息内容
+func (p *payload) encoded(encoding string) ([]byte, error) {
+	if encoding != EncodingProtobuf {
+		return p.data, nil
+	}
+	p.protobufOnce.Do(func() {
+		p.protobuf, p.protobufErr = jsonToProtobuf(p.data)
+	})
+	return p.protobuf, p.protobufErr
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// 玩家在线状态
+const (
+	PresenceOnline       = "online"       // 有活跃的连接
+	PresenceReconnecting = "reconnecting" // 连接已断开，仍在重连窗口期内
+	PresenceOffline      = "offline"      // 未连接
+)
+
+// PresenceMessage 玩家在线状态变化时广播给所在房间的消息
+type PresenceMessage struct {
+	Type     string `json:"type"` // 固定为presence
+	PlayerID string `json:"player_id"`
+	Status   string `json:"status"`
+}
+
+// RoomPresence 获取房间内各玩家的在线状态，AI玩家始终在线
+func (wm *WebSocketManager) RoomPresence(roomID string) map[string]string {
+	room, err := wm.roomManager.GetRoom(roomID)
+
+	presence := make(map[string]string)
+	for _, playerID := range wm.roomShard(roomID).memberList(roomID) {
+		presence[playerID] = wm.presence(playerID)
+	}
+	if err == nil {
+		for _, player := range room.Players {
+			if player.Type == models.AIPlayer {
+				presence[player.ID] = PresenceOnline
+			} else if _, exists := presence[player.ID]; !exists {
+				presence[player.ID] = wm.presence(player.ID)
+			}
+		}
+	}
+	return presence
+}
+
+// presence 获取玩家的在线状态
+func (wm *WebSocketManager) presence(playerID string) string {
+	shard := wm.playerShard(playerID)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	return shard.presenceLocked(playerID)
+}
+
+// disconnectedSince 玩家断线且尚未重连时返回断线时间，从未连接过的玩家不算断线
+func (wm *WebSocketManager) disconnectedSince(playerID string) (time.Time, bool) {
+	shard := wm.playerShard(playerID)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	if len(shard.connections[playerID]) > 0 {
+		return time.Time{}, false
+	}
+	since, disconnected := shard.disconnected[playerID]
+	return since, disconnected
+}
+
+// broadcastPresence 向玩家所在的房间广播其在线状态变化
+func (wm *WebSocketManager) broadcastPresence(playerID, status string) {
+	for _, roomID := range wm.playerRooms(playerID) {
+		wm.BroadcastToRoom(roomID, PresenceMessage{Type: "presence", PlayerID: playerID, Status: status})
+	}
+}
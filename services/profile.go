@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+)
+
+// ClientProfile 按客户端平台调整的连接参数，在建立连接时指定
+type ClientProfile struct {
+	Name            string
+	ReadLimit       int64         // 单条上行消息的大小上限
+	MaxFrameSize    int           // 单个下行JSON帧的大小上限，超过时拆成多条chunk消息，0表示不拆分
+	ReconnectWindow time.Duration // 断线后保留座位、等待重连的时间
+	// SilentReconnect 断线和窗口期内的重连不向房间广播在线状态，其他玩家看不到网络切换造成的闪断
+	SilentReconnect bool
+	Locale          Locale // 直接回复该连接的错误信息使用的语言，为空时使用默认语言
+}
+
+// WebProfile 网页和其他客户端使用的默认参数
+var WebProfile = ClientProfile{
+	Name:            "web",
+	ReadLimit:       512 * 1024,
+	ReconnectWindow: playerCleanupDelay,
+}
+
+// 微信小程序连接的默认参数：小程序切到后台几秒后连接即被系统断开，
+// 回到前台时客户端静默重连并从断开前的序号续传，窗口期需要覆盖分享、切换聊天等短暂离开
+const (
+	DefaultMiniProgramFrameSize       = 32 * 1024
+	DefaultMiniProgramReconnectWindow = 2 * time.Minute
+	miniProgramReadLimit              = 16 * 1024 // 小程序只上行动作、聊天和心跳
+	minFrameSize                      = 1024
+)
+
+// MiniProgramProfile 微信小程序连接的参数，maxFrameSize和reconnectWindow不大于0时使用默认值
+func MiniProgramProfile(maxFrameSize int, reconnectWindow time.Duration) ClientProfile {
+	if maxFrameSize <= 0 {
+		maxFrameSize = DefaultMiniProgramFrameSize
+	}
+	if reconnectWindow <= 0 {
+		reconnectWindow = DefaultMiniProgramReconnectWindow
+	}
+	return ClientProfile{
+		Name:            "miniprogram",
+		ReadLimit:       miniProgramReadLimit,
+		MaxFrameSize:    max(maxFrameSize, minFrameSize),
+		ReconnectWindow: reconnectWindow,
+		SilentReconnect: true,
+	}
+}
+
+// ChunkMessage 超过帧大小上限的消息拆成的片段，客户端按ID收齐total个片段后
+// 按index顺序拼接data，得到原消息的JSON文本
+type ChunkMessage struct {
+	Type  string `json:"type"` // 固定为chunk
+	ID    int64  `json:"id"`   // 同一连接内递增
+	Index int    `json:"index"`
+	Total int    `json:"total"`
+	Data  string `json:"data"`
+}
+
+// chunkOverhead ChunkMessage除data外的字段占用的字节数上限
+const chunkOverhead = 96
+
+// chunkFrames 把JSON消息拆成不超过limit字节的chunk消息，按UTF-8字符边界切分，
+// 并计入data在JSON中转义后的长度
+func chunkFrames(id int64, msg []byte, limit int) [][]byte {
+	budget := limit - chunkOverhead
+	var pieces []string
+	start, size := 0, 0
+	for i := 0; i < len(msg); {
+		r, n := utf8.DecodeRune(msg[i:])
+		cost := escapedLen(r, n)
+		if size+cost > budget && i > start {
+			pieces = append(pieces, string(msg[start:i]))
+			start, size = i, 0
+		}
+		size += cost
+		i += n
+	}
+	pieces = append(pieces, string(msg[start:]))
+
+	frames := make([][]byte, 0, len(pieces))
+	for index, piece := range pieces {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(ChunkMessage{Type: "chunk", ID: id, Index: index, Total: len(pieces), Data: piece})
+		frames = append(frames, bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	}
+	return frames
+}
+
+// escapedLen 字符在JSON字符串中转义后的字节数，n为字符在原文中的字节数
+func escapedLen(r rune, n int) int {
+	switch {
+	case r == '"' || r == '\\' || r == '\n' || r == '\r' || r == '\t':
+		return 2
+	case r < 0x20 || r == '\u2028' || r == '\u2029' || (r == utf8.RuneError && n == 1):
+		return 6
+	}
+	return n
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	werewolfpb "github.com/qianlnk/werewolf/proto"
+)
+
+// WebSocket消息编码，客户端在连接时通过encoding查询参数选择
+const (
+	EncodingJSON     = "json"     // 文本帧，JSON格式
+	EncodingProtobuf = "protobuf" // 二进制帧，格式见proto/werewolf.proto
+)
+
+// ParseEncoding 解析客户端选择的消息编码，未选择时使用JSON
+func ParseEncoding(value string) (string, error) {
+	switch value {
+	case "", EncodingJSON:
+		return EncodingJSON, nil
+	case EncodingProtobuf:
+		return EncodingProtobuf, nil
+	}
+	return "", fmt.Errorf("不支持的消息编码 %s", value)
+}
+
+// JSONEnvelope 将服务端的JSON消息转换为二进制消息信封，seq、type、room_id之外的字段放入payload
+func JSONEnvelope(msg []byte) (*werewolfpb.Envelope, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(msg, &fields); err != nil {
+		return nil, err
+	}
+
+	envelope := &werewolfpb.Envelope{}
+	if seq, ok := fields["seq"].(float64); ok {
+		envelope.Seq = int64(seq)
+		delete(fields, "seq")
+	}
+	if msgType, ok := fields["type"].(string); ok {
+		envelope.Type = msgType
+		delete(fields, "type")
+	}
+	if roomID, ok := fields["room_id"].(string); ok {
+		envelope.RoomId = roomID
+		delete(fields, "room_id")
+	}
+
+	payload, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Payload = payload
+	return envelope, nil
+}
+
+// jsonToProtobuf 将服务端的JSON消息编码为protobuf二进制格式
+func jsonToProtobuf(msg []byte) ([]byte, error) {
+	envelope, err := JSONEnvelope(msg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(envelope)
+}
+
+// protobufToJSON 将客户端发送的二进制消息转换为JSON消息信封，payload作为content，忽略未知字段
+func protobufToJSON(data []byte) ([]byte, error) {
+	envelope := &werewolfpb.Envelope{}
+	if err := proto.Unmarshal(data, envelope); err != nil {
+		return nil, err
+	}
+
+	msg := map[string]interface{}{
+		"type":    envelope.Type,
+		"room_id": envelope.RoomId,
+	}
+	if envelope.Payload != nil {
+		msg["content"] = envelope.Payload.AsMap()
+	}
+	return json.Marshal(msg)
+}
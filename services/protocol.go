@@ -0,0 +1,52 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// WebSocket协议版本，客户端在连接时通过protocol查询参数声明
+const (
+	ProtocolV1 = 1 // 初始版本，广播消息不带序号
+	ProtocolV2 = 2 // 广播消息带房间序号，支持ack和replay
+	ProtocolV3 = 3 // 游戏状态在首次推送后以game_state_patch增量推送
+
+	MinProtocolVersion     = ProtocolV1
+	CurrentProtocolVersion = ProtocolV3
+)
+
+// wsRequestMinProtocol 需要更高协议版本的消息类型，未列出的类型所有版本都支持
+var wsRequestMinProtocol = map[string]int{
+	MsgAck:    ProtocolV2,
+	MsgReplay: ProtocolV2,
+}
+
+// UnsupportedProtocolError 客户端声明的协议版本不受支持
+type UnsupportedProtocolError struct {
+	Requested string
+}
+
+func (e *UnsupportedProtocolError) Error() string {
+	return fmt.Sprintf("不支持的协议版本 %s，服务端支持 %d 到 %d", e.Requested, MinProtocolVersion, CurrentProtocolVersion)
+}
+
+// ParseProtocolVersion 解析客户端声明的协议版本，未声明时按初始版本处理以兼容旧客户端
+func ParseProtocolVersion(value string) (int, error) {
+	if value == "" {
+		return ProtocolV1, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version < MinProtocolVersion || version > CurrentProtocolVersion {
+		return 0, &UnsupportedProtocolError{Requested: value}
+	}
+	return version, nil
+}
+
+// HelloMessage 连接建立后发送给客户端的协议协商结果，只发给V2及以上的客户端
+type HelloMessage struct {
+	Type           string `json:"type"`            // 固定为hello
+	Protocol       int    `json:"protocol"`        // 本连接使用的协议版本
+	ServerProtocol int    `json:"server_protocol"` // 服务端支持的最高版本
+	ConnectionID   string `json:"connection_id"`
+}
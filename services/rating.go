@@ -0,0 +1,137 @@
+package services
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+const (
+	DefaultRating = 1500 // 新账号和AI玩家的默认积分
+	ratingK       = 32   // ELO的K系数
+)
+
+// roleDifficulty 角色难度系数，难度越高胜利加分越多、失败扣分越少
+var roleDifficulty = map[models.Role]float64{
+	models.Werewolf:  1.1,
+	models.WhiteWolf: 1.3,
+	models.Seer:      1.3,
+	models.Witch:     1.2,
+	models.Hunter:    1.1,
+	models.Guard:     1.2,
+	models.Cupid:     1.1,
+	models.Thief:     1.1,
+	models.Villager:  1.0,
+}
+
+// RatingManager 积分管理器
+type RatingManager struct {
+	store storage.Store
+}
+
+// NewRatingManager 创建积分管理器实例
+func NewRatingManager(store storage.Store) *RatingManager {
+	return &RatingManager{store: store}
+}
+
+// isWolf 判断角色是否属于狼人阵营
+func isWolf(role models.Role) bool {
+	return role == models.Werewolf || role == models.WhiteWolf
+}
+
+// isWinner 根据对局结果判断玩家是否获胜
+func isWinner(player models.Player, result string) bool {
+	switch result {
+	case WerewolfWin:
+		return isWolf(player.Role)
+	case VillagerWin:
+		return !isWolf(player.Role)
+	case LoversWin:
+		return player.IsLover
+	case WhiteWolfWin:
+		return player.Role == models.WhiteWolf
+	default:
+		return false
+	}
+}
+
+// ApplyGameResult 根据排位对局结果更新真人玩家的积分，返回玩家ID到积分变化的映射
+func (rm *RatingManager) ApplyGameResult(record *models.GameRecord) map[string]int {
+	changes := make(map[string]int)
+	if !record.Ranked {
+		return changes
+	}
+
+	// 读取每位玩家的当前积分，AI和无账号玩家按默认积分计算
+	ratings := make(map[string]int)
+	users := make(map[string]*models.User)
+	for _, player := range record.Players {
+		ratings[player.ID] = DefaultRating
+		if player.Type != models.HumanPlayer {
+			continue
+		}
+		user, err := rm.store.GetUserByID(player.ID)
+		if err != nil {
+			continue
+		}
+		users[player.ID] = user
+		ratings[player.ID] = user.Rating
+	}
+
+	for _, player := range record.Players {
+		user, ok := users[player.ID]
+		if !ok {
+			continue
+		}
+
+		// 对手为胜负结果相反的玩家
+		won := isWinner(player, record.Result)
+		opponentTotal, opponents := 0, 0
+		for _, other := range record.Players {
+			if isWinner(other, record.Result) != won {
+				opponentTotal += ratings[other.ID]
+				opponents++
+			}
+		}
+		if opponents == 0 {
+			continue
+		}
+
+		opponentRating := float64(opponentTotal) / float64(opponents)
+		expected := 1 / (1 + math.Pow(10, (opponentRating-float64(ratings[player.ID]))/400))
+
+		difficulty := roleDifficulty[player.Role]
+		if difficulty == 0 {
+			difficulty = 1
+		}
+
+		var delta float64
+		if won {
+			delta = ratingK * (1 - expected) * difficulty
+		} else {
+			delta = ratingK * (0 - expected) / difficulty
+		}
+
+		change := int(math.Round(delta))
+		user.Rating += change
+		user.RatedGames++
+		if err := rm.store.UpdateUser(user); err != nil {
+			slog.Error("更新玩家积分失败", "player_id", player.ID, "error", err)
+			continue
+		}
+		changes[player.ID] = change
+	}
+
+	return changes
+}
+
+// PlayerRating 获取玩家当前积分，没有账号的玩家返回0
+func (rm *RatingManager) PlayerRating(playerID string) int {
+	user, err := rm.store.GetUserByID(playerID)
+	if err != nil {
+		return 0
+	}
+	return user.Rating
+}
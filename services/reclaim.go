@@ -0,0 +1,117 @@
+package services
+
+import (
+	"time"
+)
+
+// defaultFinishedGameRetention 对局结束后完整状态在内存中保留的默认时长
+const defaultFinishedGameRetention = 10 * time.Minute
+
+// GameMetrics 内存中对局的数量统计
+type GameMetrics struct {
+	Playing   int   `json:"playing"`   // 进行中的对局
+	Finished  int   `json:"finished"`  // 已结束、完整状态仍在保留期内的对局
+	Idle      int   `json:"idle"`      // 等待开始的房间，包括对局状态已释放的房间
+	Reclaimed int64 `json:"reclaimed"` // 服务启动以来已归档并释放内存的对局数
+	Deadlines int   `json:"deadlines"` // 时间轮上等待到期的对局计时器
+}
+
+// SetFinishedGameRetention 设置对局结束后完整状态在内存中保留的时长，保留期内仍可查看结束时的状态，
+// 之后只保留存储中的对局记录；0表示对局结束后立即释放
+func (rm *RoomManager) SetFinishedGameRetention(retention time.Duration) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.finishedRetention = retention
+}
+
+// finishedGameRetention 获取对局结束后的保留时长
+func (gc *GameController) finishedGameRetention() time.Duration {
+	if gc.game.roomManager == nil {
+		return defaultFinishedGameRetention
+	}
+	gc.game.roomManager.mutex.RLock()
+	defer gc.game.roomManager.mutex.RUnlock()
+
+	return gc.game.roomManager.finishedRetention
+}
+
+// scheduleReclaim 对局结束并写入对局记录后，在保留期结束时释放对局状态，在对局goroutine中执行
+func (gc *GameController) scheduleReclaim() {
+	if gc.game.roomManager == nil {
+		return
+	}
+	gc.stopReclaimTimer()
+
+	// 保留期内房主开始了新的一局时不释放
+	startedAt := gc.game.StartedAt
+	gc.reclaimTimer = deadlines.AfterFunc(gc.finishedGameRetention(), func() {
+		gc.postTimer(func() {
+			gc.reclaim(startedAt)
+		})
+	})
+}
+
+// stopReclaimTimer 停止释放对局状态的计时，调用方需持有gc.mutex
+func (gc *GameController) stopReclaimTimer() {
+	if gc.reclaimTimer != nil {
+		gc.reclaimTimer.Stop()
+		gc.reclaimTimer = nil
+	}
+}
+
+// reclaim 用只包含房间信息的新控制器替换已结束的对局，旧控制器的事件日志、动作、阶段快照和AI记忆随之释放，
+// 对局记录在结束时已经写入存储，在对局goroutine中执行
+func (gc *GameController) reclaim(startedAt int64) {
+	gc.reclaimTimer = nil
+	if gc.game.IsStarted || gc.game.StartedAt != startedAt {
+		return
+	}
+	if !gc.game.roomManager.replaceFinishedGame(gc) {
+		return
+	}
+
+	gc.logger().Info("已释放结束对局的内存状态", "started_at", startedAt, "result", gc.game.Result)
+	// Close等待对局goroutine退出，不能在当前命令中直接调用
+	go gc.Close()
+}
+
+// replaceFinishedGame 为房间换上新的空闲控制器，房间已不存在或控制器已被替换时返回false
+func (rm *RoomManager) replaceFinishedGame(gc *GameController) bool {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	roomID := gc.game.Room.ID
+	room, exists := rm.rooms[roomID]
+	if !exists || rm.games[roomID] != gc {
+		return false
+	}
+	rm.games[roomID] = NewGameController(NewGameState(*room, rm), rm.webSocketMgr)
+	rm.reclaimedGames++
+	return true
+}
+
+// GameMetrics 统计内存中进行中、已结束待释放和空闲的对局数量
+func (rm *RoomManager) GameMetrics() GameMetrics {
+	rm.mutex.RLock()
+	metrics := GameMetrics{Reclaimed: rm.reclaimedGames, Deadlines: deadlines.Pending()}
+	games := make([]*GameController, 0, len(rm.games))
+	for _, game := range rm.games {
+		games = append(games, game)
+	}
+	rm.mutex.RUnlock()
+
+	for _, game := range games {
+		game.mutex.RLock()
+		switch {
+		case game.game.IsStarted:
+			metrics.Playing++
+		case game.game.Result != "":
+			metrics.Finished++
+		default:
+			metrics.Idle++
+		}
+		game.mutex.RUnlock()
+	}
+	return metrics
+}
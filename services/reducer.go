@@ -0,0 +1,124 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// RebuildGameState 按顺序重放对局记录的事件日志，重建对局在某个事件之后的状态，seq为0时重放全部事件。
+// 重建只依赖玩家角色和事件日志，不会广播消息或写入存储，可用于回放、核对快照和审计
+func RebuildGameState(record *models.GameRecord, seq int) *GameState {
+	players := make([]models.Player, len(record.Players))
+	copy(players, record.Players)
+	for i := range players {
+		players[i].Alive = true
+		players[i].IsLover = false
+	}
+
+	room := models.Room{ID: record.RoomID, Mode: record.Mode, Players: players}
+	state := NewGameState(room, nil)
+	state.RoomID = record.RoomID
+	state.Claims = make([]models.RoleClaim, 0)
+	state.Events = make([]models.GameEvent, 0, len(record.Events))
+
+	for _, event := range record.Events {
+		if seq > 0 && event.Seq > seq {
+			break
+		}
+		state.reduce(event)
+	}
+	return state
+}
+
+// applyAction 将已通过校验的动作记录为事件，并通过reduce应用到对局状态，
+// 进行中的对局与重放事件日志使用同一套规则，调用方需持有锁
+func (gs *GameState) applyAction(action models.GameAction, system bool) {
+	gs.reduce(models.GameEvent{
+		Seq:       len(gs.Events) + 1,
+		Type:      "action",
+		Round:     gs.Round,
+		Phase:     gs.Phase,
+		Action:    action.Type,
+		PlayerID:  action.PlayerID,
+		TargetID:  action.TargetID,
+		Content:   action.Content,
+		System:    system,
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// reduce 将一条事件应用到对局状态：动作按提交时的规则记录，夜晚和投票的结果在阶段切换时结算，
+// 死亡以事件为准，不重新计算
+func (gs *GameState) reduce(event models.GameEvent) {
+	gs.Round, gs.Phase = event.Round, event.Phase
+	if stateChangingEvents[event.Type] {
+		gs.StateVersion++
+	}
+
+	switch event.Type {
+	case "game_start":
+		gs.IsStarted = true
+		gs.StartedAt = event.Timestamp / 1000
+		gs.Seed, _ = strconv.ParseInt(event.Content, 10, 64)
+		gs.initializeSkills()
+		gs.initializeKnownRoles()
+
+	case "action":
+		gs.appendAction(models.GameAction{
+			Type:      event.Action,
+			PlayerID:  event.PlayerID,
+			TargetID:  event.TargetID,
+			Content:   event.Content,
+			Timestamp: event.Timestamp,
+			RoomID:    gs.RoomID,
+		})
+		switch {
+		case event.Action == "check":
+			gs.recordKnownRole(event.PlayerID, event.TargetID)
+		case speechActions[event.Action]:
+			gs.recordSpokenClaim(event.PlayerID, event.Content)
+		}
+
+	case "chat":
+		if event.Channel == ChannelRoom {
+			gs.recordSpokenClaim(event.PlayerID, event.Content)
+		}
+
+	case "death":
+		gs.kill(event.PlayerID, event.Cause)
+		if event.PlayerID == gs.Sheriff {
+			gs.setSheriff("")
+		}
+
+	case "sheriff":
+		gs.setSheriff(event.PlayerID)
+
+	case "revive":
+		gs.revive(event.PlayerID)
+
+	case "phase_change":
+		// 天亮时结算女巫用药和丘比特连线，进入白天和新的夜晚时清空上一阶段的动作，
+		// 进入投票时保留发言和上警的动作
+		for _, action := range gs.Actions {
+			if action.Type == "save" || action.Type == "poison" {
+				gs.useWitchPotion(action)
+			}
+		}
+		gs.linkLovers()
+		if event.Content != PhaseVote && event.Content != PhaseSheriffVote {
+			gs.Actions = make([]models.GameAction, 0)
+		}
+		gs.NightStep = ""
+
+	case "night_step":
+		gs.NightStep = event.Content
+
+	case "game_end":
+		gs.IsStarted = false
+		gs.Result = event.Content
+	}
+
+	gs.Events = append(gs.Events, event)
+}
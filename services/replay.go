@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/qianlnk/werewolf/models"
+)
+
+// 回放消息类型
+const (
+	ReplayStartMessage = "replay_start"
+	ReplayEventMessage = "replay_event"
+	ReplayEndMessage   = "replay_end"
+)
+
+// ErrReplayAborted 客户端在回放结束前断开连接
+var ErrReplayAborted = errors.New("回放已中断")
+
+// StreamReplay 按事件原始时间间隔通过WebSocket推送对局事件
+// speed 为播放倍速，<=0 时不等待直接推送全部事件
+func StreamReplay(conn *websocket.Conn, record *models.GameRecord, speed float64) error {
+	// 读取客户端消息以感知断开
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := conn.WriteJSON(map[string]interface{}{
+		"type":     ReplayStartMessage,
+		"game_id":  record.ID,
+		"mode":     record.Mode,
+		"players":  record.Players,
+		"total":    len(record.Events),
+		"duration": record.Duration,
+		"speed":    speed,
+	}); err != nil {
+		return err
+	}
+
+	var lastTimestamp int64
+	for i, event := range record.Events {
+		if i > 0 && speed > 0 {
+			gap := time.Duration(float64(event.Timestamp-lastTimestamp)/speed) * time.Millisecond
+			if gap > 0 {
+				select {
+				case <-time.After(gap):
+				case <-done:
+					slog.Info("对局回放中断", "game_id", record.ID, "seq", event.Seq)
+					return ErrReplayAborted
+				}
+			}
+		}
+		lastTimestamp = event.Timestamp
+
+		if err := conn.WriteJSON(map[string]interface{}{
+			"type":  ReplayEventMessage,
+			"event": event,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return conn.WriteJSON(map[string]interface{}{
+		"type":    ReplayEndMessage,
+		"game_id": record.ID,
+		"result":  record.Result,
+	})
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/reporting"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+// retentionBatchSize 每批处理的对局记录数
+const retentionBatchSize = 100
+
+// RetentionPolicy 数据保留策略，时长为0表示不清理该类数据
+type RetentionPolicy struct {
+	Games      time.Duration // 对局记录保留时长
+	Chat       time.Duration // 对局记录中聊天消息的保留时长
+	Audit      time.Duration // 审计日志保留时长
+	ArchiveDir string        // 删除对局记录前导出到该目录，为空时直接删除
+}
+
+// RetentionStats 清理任务的统计
+type RetentionStats struct {
+	RunAt               int64 `json:"run_at"`
+	DurationMs          int64 `json:"duration_ms"`
+	GamesArchived       int64 `json:"games_archived"`
+	GamesDeleted        int64 `json:"games_deleted"`
+	ChatMessagesPurged  int64 `json:"chat_messages_purged"`
+	AuditEntriesDeleted int64 `json:"audit_entries_deleted"`
+	Errors              int64 `json:"errors"`
+}
+
+// add 累加另一次清理的统计
+func (rs *RetentionStats) add(other RetentionStats) {
+	rs.RunAt = other.RunAt
+	rs.DurationMs += other.DurationMs
+	rs.GamesArchived += other.GamesArchived
+	rs.GamesDeleted += other.GamesDeleted
+	rs.ChatMessagesPurged += other.ChatMessagesPurged
+	rs.AuditEntriesDeleted += other.AuditEntriesDeleted
+	rs.Errors += other.Errors
+}
+
+// RetentionManager 数据保留任务，定期归档或删除过期数据
+type RetentionManager struct {
+	store   storage.Store
+	policy  RetentionPolicy
+	runs    int64
+	last    RetentionStats
+	total   RetentionStats
+	running sync.Mutex // 保证同一时间只有一次清理
+	mutex   sync.RWMutex
+}
+
+// NewRetentionManager 创建数据保留任务实例
+func NewRetentionManager(store storage.Store, policy RetentionPolicy) *RetentionManager {
+	return &RetentionManager{store: store, policy: policy}
+}
+
+// Start 按固定间隔执行清理，interval为0时不启动
+func (rm *RetentionManager) Start(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			reporting.Call("retention", func() { rm.Run() })
+		}
+	}()
+}
+
+// Run 立即执行一次清理并返回本次统计
+func (rm *RetentionManager) Run() RetentionStats {
+	rm.running.Lock()
+	defer rm.running.Unlock()
+
+	started := time.Now()
+	stats := RetentionStats{RunAt: started.Unix()}
+
+	if rm.policy.Games > 0 {
+		rm.purgeGames(started.Add(-rm.policy.Games).Unix(), &stats)
+	}
+	if rm.policy.Chat > 0 {
+		rm.purgeChat(started.Add(-rm.policy.Chat).Unix(), &stats)
+	}
+	if rm.policy.Audit > 0 {
+		deleted, err := rm.store.DeleteAuditEntriesBefore(started.Add(-rm.policy.Audit).UnixMilli())
+		if err != nil {
+			slog.Error("清理审计日志失败", "error", err)
+			stats.Errors++
+		}
+		stats.AuditEntriesDeleted = deleted
+	}
+
+	stats.DurationMs = time.Since(started).Milliseconds()
+	slog.Info("数据保留任务完成", "games_archived", stats.GamesArchived, "games_deleted", stats.GamesDeleted,
+		"chat_purged", stats.ChatMessagesPurged, "audit_deleted", stats.AuditEntriesDeleted,
+		"errors", stats.Errors, "duration_ms", stats.DurationMs)
+
+	rm.mutex.Lock()
+	rm.runs++
+	rm.last = stats
+	rm.total.add(stats)
+	rm.mutex.Unlock()
+
+	return stats
+}
+
+// Metrics 获取清理任务的运行次数、最近一次和累计统计
+func (rm *RetentionManager) Metrics() map[string]interface{} {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	return map[string]interface{}{
+		"runs":  rm.runs,
+		"last":  rm.last,
+		"total": rm.total,
+	}
+}
+
+// purgeGames 归档并删除过期的对局记录
+func (rm *RetentionManager) purgeGames(endedBefore int64, stats *RetentionStats) {
+	for {
+		records, err := rm.store.ListGameRecordsBefore(endedBefore, retentionBatchSize)
+		if err != nil {
+			slog.Error("查询过期对局记录失败", "error", err)
+			stats.Errors++
+			return
+		}
+		if len(records) == 0 {
+			return
+		}
+
+		for _, record := range records {
+			if rm.policy.ArchiveDir != "" {
+				if err := rm.archive(record); err != nil {
+					// 归档失败时保留记录，避免数据丢失
+					slog.Error("归档对局失败", "game_id", record.ID, "error", err)
+					stats.Errors++
+					return
+				}
+				stats.GamesArchived++
+			}
+
+			if err := rm.store.DeleteGameRecord(record.ID); err != nil {
+				slog.Error("删除对局失败", "game_id", record.ID, "error", err)
+				stats.Errors++
+				return
+			}
+			stats.GamesDeleted++
+		}
+	}
+}
+
+// purgeChat 清理过期对局记录中的聊天消息
+func (rm *RetentionManager) purgeChat(endedBefore int64, stats *RetentionStats) {
+	records, err := rm.store.ListGameRecordsBefore(endedBefore, 0)
+	if err != nil {
+		slog.Error("查询过期对局记录失败", "error", err)
+		stats.Errors++
+		return
+	}
+
+	for _, record := range records {
+		events := make([]models.GameEvent, 0, len(record.Events))
+		for _, event := range record.Events {
+			if event.Type != "chat" {
+				events = append(events, event)
+			}
+		}
+
+		purged := len(record.Events) - len(events)
+		if purged == 0 {
+			continue
+		}
+		if err := rm.store.UpdateGameRecordEvents(record.ID, events); err != nil {
+			slog.Error("清理对局聊天记录失败", "game_id", record.ID, "error", err)
+			stats.Errors++
+			continue
+		}
+		stats.ChatMessagesPurged += int64(purged)
+	}
+}
+
+// archive 将对局记录以导出格式写入归档目录
+func (rm *RetentionManager) archive(record *models.GameRecord) error {
+	if err := os.MkdirAll(rm.policy.ArchiveDir, 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(BuildGameExport(record))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(rm.policy.ArchiveDir, "game_"+record.ID+".json"), data, 0o644)
+}
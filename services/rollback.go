@@ -0,0 +1,136 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// maxPhaseSnapshots 保留最近几个阶段开始时的快照
+const maxPhaseSnapshots = 4
+
+// ErrNoPhaseSnapshot 没有可以回滚到的阶段快照，例如对局刚从存储恢复
+var ErrNoPhaseSnapshot = NewError(CodeNoPhaseSnapshot, "没有可以回滚的阶段")
+
+// recordPhaseSnapshot 在阶段开始、任何人行动之前保存对局状态，用于回滚，在对局goroutine中执行
+func (gc *GameController) recordPhaseSnapshot() {
+	data, err := gc.snapshot()
+	if err != nil {
+		gc.logger().Error("序列化阶段快照失败", "error", err)
+		return
+	}
+	gc.phaseSnapshots = append(gc.phaseSnapshots, data)
+	if len(gc.phaseSnapshots) > maxPhaseSnapshots {
+		gc.phaseSnapshots = gc.phaseSnapshots[len(gc.phaseSnapshots)-maxPhaseSnapshots:]
+	}
+}
+
+// RollbackPhase 将对局回滚到上一个阶段开始时的状态并重新等待玩家行动，
+// 用于恢复因结算错误而无法继续的对局；对局仍在第一个阶段时重新开始该阶段
+func (gc *GameController) RollbackPhase() error {
+	var err error = ErrGameNotStarted
+	gc.do(func() {
+		if !gc.game.IsStarted {
+			return
+		}
+		if len(gc.phaseSnapshots) > 1 {
+			gc.phaseSnapshots = gc.phaseSnapshots[:len(gc.phaseSnapshots)-1]
+		}
+		err = gc.rollbackPhase("admin")
+	})
+	return err
+}
+
+// rollbackPhase 恢复最近一次阶段快照并重新开始该阶段，在对局goroutine中执行
+func (gc *GameController) rollbackPhase(reason string) error {
+	if len(gc.phaseSnapshots) == 0 {
+		return ErrNoPhaseSnapshot
+	}
+	data := gc.phaseSnapshots[len(gc.phaseSnapshots)-1]
+	gc.phaseSnapshots = gc.phaseSnapshots[:len(gc.phaseSnapshots)-1]
+
+	var snap gameSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("解析阶段快照失败: %w", err)
+	}
+
+	if gc.timer != nil {
+		gc.timer.Stop()
+	}
+	gc.stopBotTimer()
+	gc.stopAbsenceTimer()
+
+	// 回滚不撤销期间发生的AI接管
+	types := make(map[string]models.PlayerType, len(gc.game.Players))
+	for _, player := range gc.game.Players {
+		types[player.ID] = player.Type
+	}
+	version := gc.game.StateVersion
+	gc.game.restore(snap.Game)
+	for i := range gc.game.Players {
+		if playerType, exists := types[gc.game.Players[i].ID]; exists {
+			gc.game.Players[i].Type = playerType
+		}
+	}
+	for i := range gc.game.Room.Players {
+		if playerType, exists := types[gc.game.Room.Players[i].ID]; exists {
+			gc.game.Room.Players[i].Type = playerType
+		}
+	}
+	// 版本号继续递增，回滚前提交的动作都会被拒绝
+	gc.game.StateVersion = version + 1
+	// AI的记忆从回滚后的事件日志重新积累
+	gc.aiPlayers = make(map[string]*AIPlayer)
+
+	if gc.game.Phase == PhaseNight {
+		gc.game.beginNight()
+	} else {
+		gc.game.startPhaseClock(phaseDuration)
+	}
+
+	gc.logger().Warn("对局回滚到阶段开始", "reason", reason, "phase", gc.game.Phase, "round", gc.game.Round)
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(map[string]interface{}{
+		"type":          "phase_rollback",
+		"phase":         gc.game.Phase,
+		"round":         gc.game.Round,
+		"state_version": gc.game.StateVersion,
+	}, gc.locale(), "message", "system.phase_rollback"))
+
+	gc.startPhaseTimer()
+	gc.saveSnapshot()
+	gc.broadcastGameState()
+	return nil
+}
+
+// restore 用快照中的对局状态替换当前状态，保留房间管理器和随机数生成器，调用方需持有gc.mutex
+func (gs *GameState) restore(from *GameState) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	gs.RoomID = from.RoomID
+	gs.Room = from.Room
+	gs.Players = from.Players
+	gs.Phase = from.Phase
+	gs.NightStep = from.NightStep
+	gs.Round = from.Round
+	gs.Actions = from.Actions
+	gs.PhaseEndsAt = from.PhaseEndsAt
+	gs.IsStarted = from.IsStarted
+	gs.Skills = from.Skills
+	gs.StartedAt = from.StartedAt
+	gs.Events = from.Events
+	gs.KnownRoles = from.KnownRoles
+	gs.Claims = from.Claims
+	gs.PendingShot = from.PendingShot
+	gs.Sheriff = from.Sheriff
+	gs.PendingBadge = from.PendingBadge
+	gs.DeathCauses = from.DeathCauses
+	gs.Seed = from.Seed
+	gs.Result = from.Result
+	gs.StateVersion = from.StateVersion
+	gs.VoteWeights = from.VoteWeights
+	gs.VoteBonus = from.VoteBonus
+	gs.MissedActions = from.MissedActions
+	gs.AFK = from.AFK
+}
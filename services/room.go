@@ -1,59 +1,404 @@
 package services
 
 import (
-	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/reporting"
+	"github.com/qianlnk/werewolf/storage"
 )
 
 var (
-	ErrRoomNotFound = errors.New("房间不存在")
-	ErrRoomFull     = errors.New("房间已满")
+	ErrRoomNotFound = NewError(CodeRoomNotFound, "房间不存在")
+	ErrRoomFull     = NewError(CodeRoomFull, "房间已满")
+	ErrShuttingDown = NewError(CodeShuttingDown, "服务器正在关闭，暂不接受新房间")
+
+	ErrInvalidPersonality = NewError(CodeInvalidPersonality, "无效的AI性格分布")
+	ErrInvalidAIFill      = NewError(CodeInvalidAIFill, "AI补位人数不能为负数或超过房间人数上限")
+	ErrInvalidMode        = NewError(CodeInvalidMode, "无效的游戏模式")
+	ErrInvalidRoomSize    = NewError(CodeInvalidRoomSize, "房间人数上限少于该模式的最少人数")
+	ErrInvalidBoard       = NewError(CodeInvalidBoard, "自定义板子不合法：每种神职最多一个，狼人1到4个，且只能使用已支持的角色")
+	ErrNotHost            = NewError(CodeNotHost, "只有房主可以开始游戏")
 )
 
+// DefaultAIFill 创建房间时未指定AI补位人数时的默认值
+const DefaultAIFill = 6
+
 // RoomManager 房间管理器
 type RoomManager struct {
-	rooms        map[string]*models.Room
-	games        map[string]*GameController
-	webSocketMgr *WebSocketManager
-	mutex        sync.RWMutex
+	rooms           map[string]*models.Room
+	games           map[string]*GameController
+	webSocketMgr    *WebSocketManager
+	store           storage.Store
+	ratings         *RatingManager
+	stats           *StatsManager
+	achievements    *AchievementManager
+	audit           *AuditLogger
+	chatModerator   *ChatModerator // 聊天内容审核，nil表示不审核
+	overlays        *overlayFeed   // 直播叠加层按延迟显示的对局概况
+	seasons         *SeasonManager
+	tournaments     *TournamentManager
+	shuttingDown    bool            // 服务器正在关闭，不再创建新房间
+	botTimeout      time.Duration   // 外部机器人每个阶段的行动时限
+	disconnectGrace time.Duration   // 断线玩家的动作由内置AI代为完成前等待的时间
+	gameSeed        int64           // 对局随机数种子，0表示每局随机生成
+	narrationVoice  string          // 法官旁白的语音合成地址模板
+	timeoutDefaults TimeoutDefaults // 阶段到时仍未完成必要动作时的默认处理
+	afkThreshold    int             // 连续几次到时未行动后判定挂机，0表示不判定
+	afkTakeover     bool            // 挂机的玩家由AI接管座位
+	// finishedRetention 对局结束后完整状态在内存中保留的时长，见SetFinishedGameRetention
+	finishedRetention time.Duration
+	reclaimedGames    int64         // 已释放内存的结束对局数
+	maxRooms          int           // 房间数上限，0表示不限制，见SetCapacity
+	maxGames          int           // 同时进行的对局数上限，0表示不限制
+	closed            chan struct{} // 关闭时快照循环退出，见Close
+	closeOnce         sync.Once
+	mutex             sync.RWMutex
 }
 
 // NewRoomManager 创建房间管理器实例
 func NewRoomManager(webSocketMgr *WebSocketManager) *RoomManager {
-	return &RoomManager{
-		rooms:        make(map[string]*models.Room),
-		games:        make(map[string]*GameController),
-		webSocketMgr: webSocketMgr,
+	rm := &RoomManager{
+		rooms:             make(map[string]*models.Room),
+		games:             make(map[string]*GameController),
+		webSocketMgr:      webSocketMgr,
+		botTimeout:        defaultBotActionTimeout,
+		disconnectGrace:   defaultDisconnectGrace,
+		afkThreshold:      defaultAFKThreshold,
+		finishedRetention: defaultFinishedGameRetention,
+		overlays:          newOverlayFeed(),
+		closed:            make(chan struct{}),
+	}
+	rm.SetStore(storage.NewMemoryStore())
+	return rm
+}
+
+// SetStore 设置持久化存储实例
+func (rm *RoomManager) SetStore(store storage.Store) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.store = store
+	rm.ratings = NewRatingManager(store)
+	rm.stats = NewStatsManager(store)
+	rm.achievements = NewAchievementManager(store)
+	rm.audit = NewAuditLogger(store)
+	rm.seasons = NewSeasonManager(store)
+}
+
+// SetGameSeed 设置对局的随机数种子，非0时每局都使用该种子，用于复现对局；0表示每局随机生成
+func (rm *RoomManager) SetGameSeed(seed int64) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.gameSeed = seed
+}
+
+// Stats 获取玩家统计管理器
+func (rm *RoomManager) Stats() *StatsManager {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	return rm.stats
+}
+
+// Achievements 获取成就管理器
+func (rm *RoomManager) Achievements() *AchievementManager {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	return rm.achievements
+}
+
+// Seasons 获取赛季管理器
+func (rm *RoomManager) Seasons() *SeasonManager {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	return rm.seasons
+}
+
+// Audit 获取审计日志
+func (rm *RoomManager) Audit() *AuditLogger {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	return rm.audit
+}
+
+// AuditRejectedAction 记录在进入游戏控制器之前就被拒绝的动作
+func (rm *RoomManager) AuditRejectedAction(action models.GameAction, source, connectionID string, reason error) {
+	entry := models.AuditEntry{
+		RoomID:       action.RoomID,
+		PlayerID:     action.PlayerID,
+		ConnectionID: connectionID,
+		Source:       source,
+		Action:       action.Type,
+		TargetID:     action.TargetID,
+	}
+	if game, exists := rm.GetGameController(action.RoomID); exists {
+		entry.Phase, entry.Round = game.CurrentPhase()
+	}
+	rm.Audit().Record(entry, reason)
+}
+
+// LoadRooms 从持久化存储中恢复房间，服务启动时调用
+func (rm *RoomManager) LoadRooms() error {
+	rooms, err := rm.store.LoadActiveRooms()
+	if err != nil {
+		return err
+	}
+
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	for _, room := range rooms {
+		// 房间默认回到等待开始状态，有快照的对局随后恢复
+		room.GameStarted = false
+		room.Status = models.RoomWaiting
+		room.HostID = hostOf(room.Players)
+		rm.rooms[room.ID] = room
+
+		gameState := NewGameState(*room, rm)
+		rm.games[room.ID] = NewGameController(gameState, rm.webSocketMgr)
+	}
+
+	slog.Info("已从存储中恢复房间", "count", len(rooms))
+
+	return rm.restoreGames()
+}
+
+// restoreGames 从快照恢复进行中的对局，调用方需持有rm.mutex
+func (rm *RoomManager) restoreGames() error {
+	snapshots, err := rm.store.LoadGameSnapshots()
+	if err != nil {
+		return err
+	}
+
+	for roomID, data := range snapshots {
+		room, exists := rm.rooms[roomID]
+		if !exists {
+			rm.deleteSnapshot(roomID)
+			continue
+		}
+
+		gc, err := restoreGameController(data, rm)
+		if err != nil {
+			slog.Error("恢复对局失败", "room_id", roomID, "error", err)
+			continue
+		}
+
+		room.Players = gc.game.Players
+		room.GameStarted = true
+		room.Status = models.RoomPlaying
+		if previous, exists := rm.games[roomID]; exists {
+			previous.Close()
+		}
+		rm.games[roomID] = gc
+
+		// 等待真人玩家和外部机器人重连，超时未归的由AI接管
+		humans := make([]string, 0)
+		for _, player := range gc.game.Players {
+			if player.Type != models.AIPlayer {
+				humans = append(humans, player.ID)
+			}
+		}
+		rm.webSocketMgr.ExpectReconnect(roomID, humans)
+		gc.post(gc.armAbsenceTimer)
+
+		slog.Info("已恢复对局", "room_id", roomID, "round", gc.game.Round, "phase", gc.game.Phase)
+	}
+	return nil
+}
+
+// StartSnapshotLoop 定期保存所有进行中对局的快照
+func (rm *RoomManager) StartSnapshotLoop(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				reporting.Call("snapshot", rm.SaveSnapshots)
+			case <-rm.closed:
+				return
+			}
+		}
+	}()
+}
+
+// SaveSnapshots 保存所有进行中对局的快照
+func (rm *RoomManager) SaveSnapshots() {
+	rm.mutex.RLock()
+	games := make([]*GameController, 0, len(rm.games))
+	for _, game := range rm.games {
+		games = append(games, game)
+	}
+	rm.mutex.RUnlock()
+
+	for _, game := range games {
+		game.SaveSnapshot()
+	}
+}
+
+// StopAccepting 服务器关闭前调用，之后创建房间返回ErrShuttingDown
+func (rm *RoomManager) StopAccepting() {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.shuttingDown = true
+}
+
+// ShuttingDown 服务器是否正在关闭
+func (rm *RoomManager) ShuttingDown() bool {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	return rm.shuttingDown
+}
+
+// Close 停止快照循环并关闭所有对局控制器，返回时各对局的goroutine和计时器都已停止，
+// 服务器关闭时在保存快照之后调用
+func (rm *RoomManager) Close() {
+	rm.closeOnce.Do(func() {
+		close(rm.closed)
+	})
+
+	rm.mutex.RLock()
+	games := make([]*GameController, 0, len(rm.games))
+	for _, game := range rm.games {
+		games = append(games, game)
+	}
+	rm.mutex.RUnlock()
+
+	for _, game := range games {
+		game.Close()
+	}
+}
+
+// saveSnapshot 保存对局快照
+func (rm *RoomManager) saveSnapshot(roomID string, data []byte) {
+	if err := rm.store.SaveGameSnapshot(roomID, data); err != nil {
+		slog.Error("保存对局快照失败", "room_id", roomID, "error", err)
+	}
+}
+
+// deleteSnapshot 删除对局快照
+func (rm *RoomManager) deleteSnapshot(roomID string) {
+	if err := rm.store.DeleteGameSnapshot(roomID); err != nil {
+		slog.Error("删除对局快照失败", "room_id", roomID, "error", err)
+	}
+}
+
+// persistRoom 将房间信息写入持久化存储
+func (rm *RoomManager) persistRoom(room *models.Room) {
+	if err := rm.store.SaveRoom(room); err != nil {
+		slog.Error("保存房间失败", "room_id", room.ID, "error", err)
 	}
 }
 
-// CreateRoom 创建新房间
-func (rm *RoomManager) CreateRoom(name string, mode models.GameMode, maxPlayers int) *models.Room {
+// gameSummary 对局结算信息
+type gameSummary struct {
+	RatingChanges map[string]int                  `json:"rating_changes"` // 排位积分变化
+	Stats         map[string]*models.PlayerStats  `json:"stats"`          // 更新后的玩家统计
+	Achievements  map[string][]models.Achievement `json:"achievements"`   // 新解锁的成就
+}
+
+// recordGame 保存对局记录并更新玩家统计，排位对局同时更新玩家积分和赛季排名
+func (rm *RoomManager) recordGame(record *models.GameRecord) *gameSummary {
+	if err := rm.store.SaveGameRecord(record); err != nil {
+		slog.Error("保存对局记录失败", "room_id", record.RoomID, "game_id", record.ID, "error", err)
+	}
+	summary := &gameSummary{
+		RatingChanges: rm.ratings.ApplyGameResult(record),
+		Stats:         rm.stats.ApplyGameRecord(record),
+	}
+	rm.seasons.ApplyGameResult(record, summary.RatingChanges)
+	summary.Achievements = rm.achievements.Evaluate(record, summary.Stats)
+
+	// 比赛房间的对局计入赛事积分
+	rm.mutex.RLock()
+	tournaments := rm.tournaments
+	rm.mutex.RUnlock()
+	if tournaments != nil {
+		tournaments.ApplyGameRecord(record)
+	}
+	return summary
+}
+
+// CreateRoom 创建新房间，allowWhispers为是否允许玩家之间私聊，chatFilter为聊天过滤模式，locale为房间的语言，aiPersonalities为AI补位时的性格分布，
+// roles为自定义板子的角色数量，为空时使用mode对应的板子，judgeID不为空时该玩家在创建房间的同时担任法官
+func (rm *RoomManager) CreateRoom(name string, mode models.GameMode, maxPlayers int, ranked, allowWhispers bool, chatFilter string, locale Locale, aiPersonalities map[models.AIPersonality]int, aiFill int, roles map[models.Role]int, sheriff bool, judgeID string) (*models.Room, error) {
+	if judgeID != "" && ranked {
+		return nil, ErrJudgeUnavailable
+	}
+	if boardMode(mode) != mode {
+		return nil, ErrInvalidMode
+	}
+	if err := validateBoard(roles); err != nil {
+		return nil, err
+	}
+	minPlayers := boardMinPlayers(mode, roles)
+	if maxPlayers > 0 && maxPlayers < minPlayers {
+		return nil, ErrInvalidRoomSize
+	}
+	if err := validateAIPersonalities(aiPersonalities); err != nil {
+		return nil, err
+	}
+	if !validChatFilter(chatFilter) {
+		return nil, ErrInvalidChatFilter
+	}
+	if !validLocale(locale) {
+		return nil, ErrInvalidLocale
+	}
+	if aiFill < 0 || (maxPlayers > 0 && aiFill > maxPlayers) {
+		return nil, ErrInvalidAIFill
+	}
+
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
 
+	if rm.shuttingDown {
+		return nil, ErrShuttingDown
+	}
+	if err := rm.checkRoomCapacityLocked(); err != nil {
+		return nil, err
+	}
+
 	room := &models.Room{
-		ID:         generateID(),
+		ID:         rm.newRoomIDLocked(),
 		Name:       name,
 		Mode:       mode,
 		MaxPlayers: maxPlayers,
-		MinPlayers: 1, // 修改最小玩家数为1，允许更灵活的配置
+		MinPlayers: minPlayers,
 		Players:    make([]models.Player, 0),
+		Ranked:     ranked,
+		Status:     models.RoomWaiting,
 		CreatedAt:  time.Now().Unix(),
+
+		AllowWhispers:   allowWhispers,
+		ChatFilter:      chatFilter,
+		Locale:          string(locale),
+		AIPersonalities: aiPersonalities,
+		AIFill:          aiFill,
+		Roles:           roles,
+		Sheriff:         sheriff,
+		JudgeID:         judgeID,
 	}
 
 	rm.rooms[room.ID] = room
+	rm.persistRoom(room)
 
 	// 初始化游戏状态和控制器
 	gameState := NewGameState(*room, rm)
 	gameController := NewGameController(gameState, rm.webSocketMgr) // 传入WebSocket管理器实例
 	rm.games[room.ID] = gameController
 
-	return room
+	return room, nil
 }
 
 // GetRoom 获取房间信息
@@ -68,30 +413,92 @@ func (rm *RoomManager) GetRoom(roomID string) (*models.Room, error) {
 	return room, nil
 }
 
-// ListRooms 获取所有房间列表
-func (rm *RoomManager) ListRooms() []*models.Room {
+// RoomInfo 获取房间的公开信息，用于房间查询接口和房间广播：返回房间的副本，玩家列表不含角色和情侣身份，
+// 对局进行中时取自旁观者视角的对局状态，包含存活情况和已出局玩家的角色
+func (rm *RoomManager) RoomInfo(roomID string) (*models.Room, error) {
 	rm.mutex.RLock()
-	defer rm.mutex.RUnlock()
+	room, exists := rm.rooms[roomID]
+	if !exists {
+		rm.mutex.RUnlock()
+		return nil, ErrRoomNotFound
+	}
+	info := publicRoom(room)
+	game := rm.games[roomID]
+	rm.mutex.RUnlock()
+
+	info.Players = gamePlayers(info, game)
+	return info, nil
+}
+
+// publicRoom 房间的副本，玩家列表不含角色和情侣身份，调用方需持有房间管理器的锁
+func publicRoom(room *models.Room) *models.Room {
+	info := *room
+	info.Players = publicPlayers(room.Players)
+	return &info
+}
+
+// gamePlayers 对局进行中时返回旁观者视角的玩家列表，否则返回房间公开的玩家列表。
+// 对局控制器的锁不能在持有房间管理器的锁时获取，调用方需先释放房间管理器的锁
+func gamePlayers(info *models.Room, game *GameController) []models.Player {
+	if !info.GameStarted || game == nil {
+		return info.Players
+	}
+	view, err := game.BuildStatus("")
+	if err != nil {
+		return info.Players
+	}
+	return view.Players
+}
+
+// 房间队列
+const (
+	QueueRanked = "ranked" // 排位
+	QueueCasual = "casual" // 休闲
+)
 
+// ListRooms 获取房间列表，queue和status为空时不按队列或状态筛选，返回的是与RoomInfo相同的公开信息
+func (rm *RoomManager) ListRooms(queue string, status models.RoomStatus) []*models.Room {
+	rm.mutex.RLock()
 	rooms := make([]*models.Room, 0, len(rm.rooms))
+	games := make([]*GameController, 0, len(rm.rooms))
 	for _, room := range rm.rooms {
-		rooms = append(rooms, room)
+		if (queue == QueueRanked && !room.Ranked) || (queue == QueueCasual && room.Ranked) {
+			continue
+		}
+		if status != "" && room.Status != status {
+			continue
+		}
+		rooms = append(rooms, publicRoom(room))
+		games = append(games, rm.games[room.ID])
+	}
+	rm.mutex.RUnlock()
+
+	for i, room := range rooms {
+		room.Players = gamePlayers(room, games[i])
 	}
 	return rooms
 }
 
 // JoinRoom 加入房间
 func (rm *RoomManager) JoinRoom(roomID string, player models.Player) error {
+	game, err := rm.seatPlayer(roomID, player)
+	if err != nil || game == nil {
+		return err
+	}
+
+	// 更新游戏控制器中的玩家信息
+	rm.syncGamePlayers(roomID, game)
+	return nil
+}
+
+// seatPlayer 让玩家在房间中入座，返回需要同步玩家列表的游戏控制器，玩家已在房间中时返回nil
+func (rm *RoomManager) seatPlayer(roomID string, player models.Player) (*GameController, error) {
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
 
 	room, exists := rm.rooms[roomID]
 	if !exists {
-		return ErrRoomNotFound
-	}
-
-	if len(room.Players) >= room.MaxPlayers {
-		return ErrRoomFull
+		return nil, ErrRoomNotFound
 	}
 
 	// 检查玩家是否已在房间中
@@ -99,18 +506,65 @@ func (rm *RoomManager) JoinRoom(roomID string, player models.Player) error {
 		if p.ID == player.ID {
 			// 玩家已在房间中，更新玩家信息
 			p.Name = player.Name
-			return nil
+			return nil, nil
 		}
 	}
 
-	room.Players = append(room.Players, player)
+	// 法官不能同时入座
+	if room.JudgeID == player.ID {
+		return nil, ErrJudgeUnavailable
+	}
 
-	// 更新游戏控制器中的玩家信息
-	if game, exists := rm.games[roomID]; exists {
-		game.game.Players = room.Players
+	// 对局进行中不能入座，只能旁观
+	if room.GameStarted {
+		return nil, ErrGameInProgress
+	}
+	if len(room.Players) >= room.MaxPlayers {
+		return nil, ErrRoomFull
 	}
 
-	return nil
+	// 真人玩家显示其账号积分
+	if player.Type == models.HumanPlayer {
+		player.Rating = rm.ratings.PlayerRating(player.ID)
+	}
+
+	room.Players = append(room.Players, player)
+	room.HostID = hostOf(room.Players)
+	rm.persistRoom(room)
+
+	return rm.games[roomID], nil
+}
+
+// syncGamePlayers 在对局goroutine中把房间的玩家列表复制到尚未开始的对局。
+// 对局goroutine会在执行命令时获取rm.mutex，调用方不能持有rm.mutex；
+// 玩家列表在命令中读取，几个玩家同时入座时不会用较早的列表覆盖较新的
+func (rm *RoomManager) syncGamePlayers(roomID string, game *GameController) {
+	game.do(func() {
+		rm.mutex.RLock()
+		room, exists := rm.rooms[roomID]
+		var players []models.Player
+		if exists {
+			players = append(players, room.Players...)
+		}
+		rm.mutex.RUnlock()
+
+		if !exists || game.game.IsStarted {
+			return
+		}
+		game.game.mutex.Lock()
+		game.game.Players = players
+		game.game.mutex.Unlock()
+	})
+}
+
+// hostOf 房主为最早加入的真人玩家或外部机器人，由AI接管的玩家不再是房主
+func hostOf(players []models.Player) string {
+	for _, player := range players {
+		if player.Type != models.AIPlayer {
+			return player.ID
+		}
+	}
+	return ""
 }
 
 // generateID 生成唯一ID
@@ -120,6 +574,19 @@ func generateID() string {
 	return time.Now().Format("20060102150405")
 }
 
+// newRoomIDLocked 生成未被占用的房间ID，同一秒内创建的房间追加序号，
+// 避免新房间覆盖已有房间及其对局控制器，调用方需持有rm.mutex
+func (rm *RoomManager) newRoomIDLocked() string {
+	base := generateID()
+	id := base
+	for n := 2; ; n++ {
+		if _, exists := rm.rooms[id]; !exists {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
 // GetGameController 获取游戏控制器
 func (rm *RoomManager) GetGameController(roomID string) (*GameController, bool) {
 	rm.mutex.RLock()
@@ -139,12 +606,49 @@ func (rm *RoomManager) GetPlayer(roomID string, playerID string) (*models.Player
 		return nil, ErrRoomNotFound
 	}
 
-	log.Printf("room: %v", room.Players)
 	for _, player := range room.Players {
 		if player.ID == playerID {
 			return &player, nil
 		}
 	}
 
-	return nil, errors.New("玩家不存在")
+	return nil, NewError(CodePlayerNotFound, "玩家不存在")
+}
+
+// setGameStarted 对局开始或结束时更新房间状态
+func (rm *RoomManager) setGameStarted(roomID string, started bool) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	room, exists := rm.rooms[roomID]
+	if !exists {
+		return
+	}
+
+	room.GameStarted = started
+	room.Status = models.RoomFinished
+	if started {
+		room.Status = models.RoomPlaying
+	}
+	rm.persistRoom(room)
+}
+
+// setPlayerType 更新房间中玩家的类型
+func (rm *RoomManager) setPlayerType(roomID, playerID string, playerType models.PlayerType) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	room, exists := rm.rooms[roomID]
+	if !exists {
+		return
+	}
+
+	for i := range room.Players {
+		if room.Players[i].ID == playerID {
+			room.Players[i].Type = playerType
+			room.HostID = hostOf(room.Players)
+			rm.persistRoom(room)
+			return
+		}
+	}
 }
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/reporting"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+// DefaultSeasonLength 默认赛季时长
+const DefaultSeasonLength = 30 * 24 * time.Hour
+
+// SeasonManager 排位赛季管理器，赛季到期后自动轮换
+type SeasonManager struct {
+	store  storage.Store
+	length time.Duration
+	mutex  sync.Mutex
+}
+
+// NewSeasonManager 创建赛季管理器实例
+func NewSeasonManager(store storage.Store) *SeasonManager {
+	return &SeasonManager{store: store, length: DefaultSeasonLength}
+}
+
+// Start 设置赛季时长并定期检查赛季是否到期
+func (sm *SeasonManager) Start(length time.Duration) {
+	sm.mutex.Lock()
+	if length > 0 {
+		sm.length = length
+	}
+	sm.mutex.Unlock()
+
+	if _, err := sm.Current(); err != nil {
+		slog.Error("初始化赛季失败", "error", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			reporting.Call("season", func() {
+				if _, err := sm.Current(); err != nil {
+					slog.Error("检查赛季轮换失败", "error", err)
+				}
+			})
+		}
+	}()
+}
+
+// Current 获取当前赛季，当前赛季已到期时结束它并开启新赛季
+func (sm *SeasonManager) Current() (*models.Season, error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	now := time.Now()
+	season, err := sm.store.GetCurrentSeason()
+	if err != nil && err != storage.ErrNotFound {
+		return nil, err
+	}
+	if err == nil && now.Unix() < season.EndsAt {
+		return season, nil
+	}
+
+	nextID := 1
+	if season != nil {
+		season.EndedAt = now.Unix()
+		if err := sm.store.SaveSeason(season); err != nil {
+			return nil, err
+		}
+		nextID = season.ID + 1
+		slog.Info("赛季已结束", "season", season.Name)
+	}
+
+	next := &models.Season{
+		ID:        nextID,
+		Name:      fmt.Sprintf("S%d", nextID),
+		StartedAt: now.Unix(),
+		EndsAt:    now.Add(sm.length).Unix(),
+	}
+	if err := sm.store.SaveSeason(next); err != nil {
+		return nil, err
+	}
+	slog.Info("赛季已开始", "season", next.Name, "ends_at", time.Unix(next.EndsAt, 0).Format(time.DateTime))
+	return next, nil
+}
+
+// List 列出所有赛季
+func (sm *SeasonManager) List() ([]*models.Season, error) {
+	return sm.store.ListSeasons()
+}
+
+// Standings 分页获取赛季排名
+func (sm *SeasonManager) Standings(seasonID, offset, limit int) ([]*models.SeasonStanding, error) {
+	return sm.store.ListSeasonStandings(seasonID, offset, limit)
+}
+
+// ApplyGameResult 将排位对局的积分变化计入当前赛季排名，休闲对局不影响赛季
+func (sm *SeasonManager) ApplyGameResult(record *models.GameRecord, changes map[string]int) {
+	if !record.Ranked || len(changes) == 0 {
+		return
+	}
+
+	season, err := sm.Current()
+	if err != nil {
+		slog.Error("获取当前赛季失败", "error", err)
+		return
+	}
+
+	now := time.Now().Unix()
+	for _, player := range record.Players {
+		change, rated := changes[player.ID]
+		if !rated {
+			continue
+		}
+
+		standing, err := sm.store.GetSeasonStanding(season.ID, player.ID)
+		if err == storage.ErrNotFound {
+			standing = &models.SeasonStanding{SeasonID: season.ID, PlayerID: player.ID, Rating: DefaultRating}
+		} else if err != nil {
+			slog.Error("读取玩家赛季成绩失败", "player_id", player.ID, "error", err)
+			continue
+		}
+
+		standing.Rating += change
+		standing.Games++
+		if isWinner(player, record.Result) {
+			standing.Wins++
+		}
+		standing.UpdatedAt = now
+		if err := sm.store.SaveSeasonStanding(standing); err != nil {
+			slog.Error("保存玩家赛季成绩失败", "player_id", player.ID, "error", err)
+		}
+	}
+}
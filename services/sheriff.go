@@ -0,0 +1,202 @@
+package services
+
+import "github.com/qianlnk/werewolf/models"
+
+// sheriffVoteWeight 警长在放逐投票中的票权
+const sheriffVoteWeight = 1.5
+
+// sheriff事件的内容，事件的PlayerID为新的警长，为空表示警徽流失
+const (
+	SheriffElected = "elected" // 竞选结果：没有人上警、所有人都上警或平票时没有警长
+	SheriffBadge   = "badge"   // 出局的警长移交或撕毁警徽
+)
+
+// afterDeathActions 出局后仍然可以执行的动作：猎人开枪、警长移交或撕毁警徽
+var afterDeathActions = map[string]bool{
+	"shoot":      true,
+	"pass_badge": true,
+	"tear_badge": true,
+}
+
+// setSheriff 更换警长及其票权，playerID为空表示没有警长，调用方需持有锁
+func (gs *GameState) setSheriff(playerID string) {
+	if gs.Sheriff != "" {
+		delete(gs.VoteWeights, gs.Sheriff)
+	}
+	gs.Sheriff = playerID
+	if playerID == "" {
+		return
+	}
+	if gs.VoteWeights == nil {
+		gs.VoteWeights = make(map[string]float64)
+	}
+	gs.VoteWeights[playerID] = sheriffVoteWeight
+}
+
+// appointSheriff 更换警长并记录sheriff事件，reason为SheriffElected或SheriffBadge，调用方需持有锁
+func (gs *GameState) appointSheriff(playerID, reason string) {
+	gs.setSheriff(playerID)
+	gs.recordEvent(models.GameEvent{Type: "sheriff", PlayerID: playerID, Content: reason})
+}
+
+// sheriffCandidates 上警的存活玩家，按座位顺序排列。同一玩家以最后一次选择为准，上警后选择不上警即为退水，调用方需持有锁
+func (gs *GameState) sheriffCandidates() []string {
+	running := make(map[string]bool)
+	for _, action := range gs.Actions {
+		switch action.Type {
+		case "campaign":
+			running[action.PlayerID] = true
+		case "decline":
+			running[action.PlayerID] = false
+		}
+	}
+	return gs.aliveTargets(func(p models.Player) bool { return running[p.ID] })
+}
+
+// isSheriffCandidate 玩家是否上警，调用方需持有锁
+func (gs *GameState) isSheriffCandidate(playerID string) bool {
+	for _, candidate := range gs.sheriffCandidates() {
+		if candidate == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// sheriffElectionWinner 统计警长投票，每人一票，得票最多的候选人当选；平票或没有人投票时返回空，警徽流失，调用方需持有锁
+func (gs *GameState) sheriffElectionWinner() string {
+	votes := make(map[string]int)
+	for _, action := range gs.Actions {
+		if action.Type == "elect" && action.TargetID != "" {
+			votes[action.TargetID]++
+		}
+	}
+
+	winner, best, tie := "", 0, false
+	for _, candidate := range gs.sheriffCandidates() {
+		switch {
+		case votes[candidate] > best:
+			winner, best, tie = candidate, votes[candidate], false
+		case votes[candidate] == best && best > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return ""
+	}
+	return winner
+}
+
+// validateBadge 校验警徽的去向：只有刚出局的警长可以移交或撕毁警徽，只能移交给存活的其他玩家，调用方需持有锁
+func (gs *GameState) validateBadge(action models.GameAction) error {
+	if gs.PendingBadge == "" || gs.PendingBadge != action.PlayerID {
+		return NewError(CodeNotYourTurn, "当前无法移交警徽")
+	}
+	if action.Type == "tear_badge" {
+		if action.TargetID != "" {
+			return NewError(CodeInvalidTarget, "撕毁警徽不需要选择玩家")
+		}
+		return nil
+	}
+	for _, player := range gs.Players {
+		if player.ID == action.TargetID && player.Alive && player.ID != action.PlayerID {
+			return nil
+		}
+	}
+	return NewError(CodeInvalidTarget, "无效的目标玩家")
+}
+
+// electionSincePhaseChange 上一个阶段结束时的竞选结果，阶段切换事件已经记录；没有竞选时elected为false
+func (gs *GameState) electionSincePhaseChange() (sheriff string, elected bool) {
+	for i := len(gs.Events) - 2; i >= 0; i-- {
+		event := gs.Events[i]
+		if event.Type == "phase_change" {
+			break
+		}
+		if event.Type == "sheriff" && event.Content == SheriffElected {
+			return event.PlayerID, true
+		}
+	}
+	return "", false
+}
+
+// checkCampaignComplete 所有存活玩家都已决定是否上警
+func (sm *StateMachine) checkCampaignComplete() bool {
+	for _, player := range sm.game.Players {
+		if player.Alive && !sm.game.hasAction(player.ID, "campaign") && !sm.game.hasAction(player.ID, "decline") {
+			return false
+		}
+	}
+	return true
+}
+
+// checkSheriffVoteComplete 警下的存活玩家都已投票
+func (sm *StateMachine) checkSheriffVoteComplete() bool {
+	for _, player := range sm.game.Players {
+		if player.Alive && !sm.game.isSheriffCandidate(player.ID) && !sm.game.hasAction(player.ID, "elect") {
+			return false
+		}
+	}
+	return true
+}
+
+// processCampaign 上警结束后决定是否需要投票：只有一人上警时直接当选，没有人上警或所有人都上警时警徽流失，
+// 需要投票时返回true，保留上警的动作用于投票时确定候选人
+func (sm *StateMachine) processCampaign() bool {
+	candidates := sm.game.sheriffCandidates()
+	alive := countAlivePlayers(sm.game.Players)
+	if len(candidates) > 1 && len(candidates) < alive {
+		return true
+	}
+
+	sheriff := ""
+	if len(candidates) == 1 {
+		sheriff = candidates[0]
+	}
+	sm.game.appointSheriff(sheriff, SheriffElected)
+	sm.game.Actions = make([]models.GameAction, 0)
+	return false
+}
+
+// processSheriffVote 结算警长投票并清空竞选阶段的动作
+func (sm *StateMachine) processSheriffVote() {
+	sm.game.appointSheriff(sm.game.sheriffElectionWinner(), SheriffElected)
+	sm.game.Actions = make([]models.GameAction, 0)
+}
+
+// wakeSheriff 通知出局的警长移交警徽，AI警长立即决定移交给谁或撕毁警徽，在对局goroutine中执行
+func (gc *GameController) wakeSheriff() {
+	for _, player := range gc.game.Players {
+		if player.ID != gc.game.PendingBadge {
+			continue
+		}
+		if player.Type != models.AIPlayer {
+			gc.sink.SendToPlayer(player.ID, map[string]interface{}{
+				"type":  "badge_wake",
+				"round": gc.game.Round,
+			})
+			gc.sendAvailableActions(player.ID)
+			return
+		}
+		if err := gc.applyAIAction(player); err != nil {
+			gc.logger().Warn("AI警长移交警徽失败", "player_id", player.ID, "error", err)
+		}
+		return
+	}
+}
+
+// announceBadge 向房间公布警徽的去向，在对局goroutine中执行
+func (gc *GameController) announceBadge(action models.GameAction) {
+	msg := map[string]interface{}{
+		"type":      "sheriff",
+		"player_id": action.TargetID,
+		"from":      action.PlayerID,
+		"reason":    SheriffBadge,
+	}
+	if action.TargetID == "" {
+		gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(msg, gc.locale(), "message", "system.badge_torn", gc.game.playerNames([]string{action.PlayerID})))
+		return
+	}
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, localize(msg, gc.locale(), "message", "system.badge_passed",
+		gc.game.playerNames([]string{action.PlayerID}), gc.game.playerNames([]string{action.TargetID})))
+}
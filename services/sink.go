@@ -0,0 +1,52 @@
+package services
+
+import "time"
+
+// EventSink 对局消息的接收方。GameController只通过它向外发送消息，
+// WebSocketManager是面向客户端的实现，测试时可以换成ChannelSink。
+// 这里只解耦了对局向外推送的消息：引擎仍在services包中，通过RoomManager读取配置、保存房间、
+// 记录对局结果和审计日志，拆成独立的包需要先把这些依赖收敛为存储接口
+type EventSink interface {
+	// BroadcastToRoom 向房间内所有玩家和旁观者发送消息
+	BroadcastToRoom(roomID string, message interface{})
+	// SendToPlayer 向单个玩家发送私有消息
+	SendToPlayer(playerID string, message interface{}) error
+}
+
+// presenceSource 能够提供房间内玩家在线状态的接收方，房间玩家列表更新时附带在线状态
+type presenceSource interface {
+	RoomPresence(roomID string) map[string]string
+}
+
+// audienceSink 能够按条件筛选广播对象的接收方，对局据此向旁观者推送只包含公开信息的状态
+type audienceSink interface {
+	BroadcastToAudience(roomID string, audience Audience, message interface{})
+}
+
+// connectionSource 能够提供玩家连接状态的接收方，对局据此代替断线的玩家行动
+type connectionSource interface {
+	// disconnectedSince 玩家断线且尚未重连时返回断线时间，从未连接过的玩家不算断线
+	disconnectedSince(playerID string) (time.Time, bool)
+}
+
+// EngineEvent 对局引擎发出的一条消息，PlayerID为空表示房间广播
+type EngineEvent struct {
+	RoomID   string
+	PlayerID string
+	Message  interface{}
+}
+
+// ChannelSink 把对局消息依次写入通道的接收方。写入时GameController持有锁，
+// 读取方需要及时消费，并且不能在读取的goroutine中同步调用GameController的方法
+type ChannelSink chan EngineEvent
+
+// BroadcastToRoom 写入一条房间广播
+func (s ChannelSink) BroadcastToRoom(roomID string, message interface{}) {
+	s <- EngineEvent{RoomID: roomID, Message: message}
+}
+
+// SendToPlayer 写入一条私有消息
+func (s ChannelSink) SendToPlayer(playerID string, message interface{}) error {
+	s <- EngineEvent{PlayerID: playerID, Message: message}
+	return nil
+}
@@ -1,10 +1,6 @@
 package services
 
-import (
-	"errors"
-
-	"github.com/qianlnk/werewolf/models"
-)
+import "github.com/qianlnk/werewolf/models"
 
 // SkillManager 技能管理器
 type SkillManager struct {
@@ -33,13 +29,13 @@ func (sm *SkillManager) UseSeerSkill(seerID string, targetID string) (models.Rol
 	// 验证预言家身份
 	seer := sm.findPlayer(seerID)
 	if seer == nil || seer.Role != models.Seer {
-		return "", errors.New("非预言家角色")
+		return "", NewError(CodeWrongRole, "非预言家角色")
 	}
 
 	// 验证目标玩家
 	target := sm.findPlayer(targetID)
 	if target == nil {
-		return "", errors.New("目标玩家不存在")
+		return "", NewError(CodeInvalidTarget, "目标玩家不存在")
 	}
 
 	// 记录查验动作
@@ -57,13 +53,13 @@ func (sm *SkillManager) UseWitchSkill(witchID string, targetID string, skillType
 	// 验证女巫身份
 	witch := sm.findPlayer(witchID)
 	if witch == nil || witch.Role != models.Witch {
-		return errors.New("非女巫角色")
+		return NewError(CodeWrongRole, "非女巫角色")
 	}
 
 	// 验证目标玩家
 	target := sm.findPlayer(targetID)
 	if target == nil {
-		return errors.New("目标玩家不存在")
+		return NewError(CodeInvalidTarget, "目标玩家不存在")
 	}
 
 	// 检查技能是否可用
@@ -71,18 +67,18 @@ func (sm *SkillManager) UseWitchSkill(witchID string, targetID string, skillType
 	switch skillType {
 	case "save":
 		if skills.SavePotion.Used {
-			return errors.New("救人技能已使用")
+			return NewError(CodePotionUsed, "救人技能已使用")
 		}
 		skills.SavePotion.Used = true
 		skills.SavePotion.Target = targetID
 	case "poison":
 		if skills.PoisonPotion.Used {
-			return errors.New("毒药已使用")
+			return NewError(CodePotionUsed, "毒药已使用")
 		}
 		skills.PoisonPotion.Used = true
 		skills.PoisonPotion.Target = targetID
 	default:
-		return errors.New("无效的技能类型")
+		return NewError(CodeInvalidAction, "无效的技能类型")
 	}
 
 	// 记录技能使用
@@ -100,13 +96,13 @@ func (sm *SkillManager) UseHunterSkill(hunterID string, targetID string) error {
 	// 验证猎人身份
 	hunter := sm.findPlayer(hunterID)
 	if hunter == nil || hunter.Role != models.Hunter {
-		return errors.New("非猎人角色")
+		return NewError(CodeWrongRole, "非猎人角色")
 	}
 
 	// 验证目标玩家
 	target := sm.findPlayer(targetID)
 	if target == nil {
-		return errors.New("目标玩家不存在")
+		return NewError(CodeInvalidTarget, "目标玩家不存在")
 	}
 
 	// 记录技能使用
@@ -117,7 +113,7 @@ func (sm *SkillManager) UseHunterSkill(hunterID string, targetID string) error {
 	})
 
 	// 处理猎人技能效果
-	target.Alive = false
+	sm.game.kill(target.ID, models.DeathHunterShot)
 
 	return nil
 }
@@ -127,13 +123,13 @@ func (sm *SkillManager) UseGuardSkill(guardID string, targetID string) error {
 	// 验证守卫身份
 	guard := sm.findPlayer(guardID)
 	if guard == nil || guard.Role != models.Guard {
-		return errors.New("非守卫角色")
+		return NewError(CodeWrongRole, "非守卫角色")
 	}
 
 	// 验证目标玩家
 	target := sm.findPlayer(targetID)
 	if target == nil {
-		return errors.New("目标玩家不存在")
+		return NewError(CodeInvalidTarget, "目标玩家不存在")
 	}
 
 	// 记录技能使用
@@ -158,7 +154,165 @@ func (sm *SkillManager) findPlayer(playerID string) *models.Player {
 
 // 辅助函数：获取女巫技能状态
 func (sm *SkillManager) getWitchSkills(witchID string) *WitchSkills {
-	// 在实际实现中，这里应该从游戏状态中获取或初始化女巫技能状态
-	// 这里简单返回一个新的实例
-	return &WitchSkills{}
+	skills, exists := sm.game.Skills[witchID]
+	if !exists {
+		skills = &WitchSkills{}
+		sm.game.Skills[witchID] = skills
+	}
+	return skills
+}
+
+// validateWitchAction 校验女巫用药：每种药只能用一次，解药只能救今晚被狼人击杀的玩家且不能自救，调用方需持有锁
+func (gs *GameState) validateWitchAction(action models.GameAction) error {
+	skills, exists := gs.Skills[action.PlayerID]
+	if !exists {
+		return NewError(CodeWrongRole, "非女巫角色")
+	}
+	if action.TargetID == "" {
+		return NewError(CodeInvalidTarget, "请选择用药的目标玩家")
+	}
+
+	switch action.Type {
+	case "save":
+		if skills.SavePotion.Used {
+			return NewError(CodePotionUsed, "救人技能已使用")
+		}
+		if action.TargetID == action.PlayerID {
+			return NewError(CodeInvalidTarget, "女巫不能自救")
+		}
+		if action.TargetID != wolfConsensusTarget(gs.Actions) {
+			return NewError(CodeInvalidTarget, "只能救今晚被狼人击杀的玩家")
+		}
+	case "poison":
+		if skills.PoisonPotion.Used {
+			return NewError(CodePotionUsed, "毒药已使用")
+		}
+	}
+	return nil
+}
+
+// useWitchPotion 天亮结算时记录女巫用掉的药，调用方需持有锁
+func (gs *GameState) useWitchPotion(action models.GameAction) {
+	skills, exists := gs.Skills[action.PlayerID]
+	if !exists {
+		return
+	}
+	switch action.Type {
+	case "save":
+		skills.SavePotion = SkillStatus{Used: true, Target: action.TargetID}
+	case "poison":
+		skills.PoisonPotion = SkillStatus{Used: true, Target: action.TargetID}
+	}
+}
+
+// triggerHunter 猎人被狼人击杀或被放逐后获得开枪的机会，被毒死时不能开枪，调用方需持有锁
+func (gs *GameState) triggerHunter(playerID string) {
+	if gs.DeathCauses[playerID] == models.DeathPoison {
+		return
+	}
+	for _, player := range gs.Players {
+		if player.ID == playerID && player.Role == models.Hunter {
+			gs.PendingShot = playerID
+			return
+		}
+	}
+}
+
+// validateShot 校验猎人开枪：只有刚出局的猎人可以开枪，目标必须是存活的其他玩家，调用方需持有锁
+func (gs *GameState) validateShot(action models.GameAction) error {
+	if gs.PendingShot == "" || gs.PendingShot != action.PlayerID {
+		return NewError(CodeNotYourTurn, "当前无法开枪")
+	}
+	for _, player := range gs.Players {
+		if player.ID == action.TargetID && player.Alive && player.ID != action.PlayerID {
+			return nil
+		}
+	}
+	return NewError(CodeInvalidTarget, "无效的目标玩家")
+}
+
+// lastProtect 获取守卫上一晚守护的玩家
+func (gs *GameState) lastProtect(guardID string) string {
+	target := ""
+	for _, event := range gs.Events {
+		if event.Type == "action" && event.Action == "protect" && event.PlayerID == guardID && event.Round == gs.Round-1 {
+			target = event.TargetID
+		}
+	}
+	return target
+}
+
+// validateProtect 校验守卫守护：不能连续两晚守护同一名玩家，调用方需持有锁
+func (gs *GameState) validateProtect(action models.GameAction) error {
+	if action.TargetID != "" && action.TargetID == gs.lastProtect(action.PlayerID) {
+		return NewError(CodeInvalidTarget, "不能连续两晚守护同一名玩家")
+	}
+	return nil
+}
+
+// validateLink 校验丘比特连情侣：第一晚分两次选择两名不同的玩家，调用方需持有锁
+func (gs *GameState) validateLink(action models.GameAction) error {
+	if action.TargetID == "" {
+		return NewError(CodeInvalidTarget, "请选择情侣")
+	}
+	for _, existing := range gs.Actions {
+		if existing.PlayerID == action.PlayerID && existing.Type == "link" && existing.TargetID == action.TargetID {
+			return NewError(CodeInvalidTarget, "已经选择过该玩家")
+		}
+	}
+	if gs.linkCount(action.PlayerID) >= 2 {
+		return NewError(CodeInvalidAction, "已经选择了两名情侣")
+	}
+	return nil
+}
+
+// linkCount 丘比特已经选择的情侣人数
+func (gs *GameState) linkCount(cupidID string) int {
+	count := 0
+	for _, action := range gs.Actions {
+		if action.PlayerID == cupidID && action.Type == "link" {
+			count++
+		}
+	}
+	return count
+}
+
+// linkLovers 第一晚结束时将丘比特选择的两名玩家连成情侣，情侣互相知道对方的身份，调用方需持有锁
+func (gs *GameState) linkLovers() {
+	var lovers []string
+	for _, action := range gs.Actions {
+		if action.Type == "link" {
+			lovers = append(lovers, action.TargetID)
+		}
+	}
+	if len(lovers) != 2 {
+		return
+	}
+
+	for i := range gs.Players {
+		if gs.Players[i].ID == lovers[0] || gs.Players[i].ID == lovers[1] {
+			gs.Players[i].IsLover = true
+		}
+	}
+	gs.recordKnownRole(lovers[0], lovers[1])
+	gs.recordKnownRole(lovers[1], lovers[0])
+}
+
+// resolveLovers 情侣一方死亡时另一方殉情，调用方需持有锁
+func (gs *GameState) resolveLovers() {
+	dead := false
+	for _, player := range gs.Players {
+		if player.IsLover && !player.Alive {
+			dead = true
+			break
+		}
+	}
+	if !dead {
+		return
+	}
+	for _, player := range gs.Players {
+		if player.IsLover {
+			gs.kill(player.ID, models.DeathLover)
+		}
+	}
 }
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// gameSnapshot 进行中对局的快照，用于服务崩溃或重新部署后恢复对局
+type gameSnapshot struct {
+	Game        *GameState `json:"game"`
+	PhaseEndsAt int64      `json:"phase_ends_at,omitempty"` // 旧版快照的阶段截止时间（毫秒），现在随GameState保存
+	SavedAt     int64      `json:"saved_at"`
+}
+
+// snapshot 序列化当前对局状态，调用方需持有gc.mutex
+func (gc *GameController) snapshot() ([]byte, error) {
+	gc.game.mutex.RLock()
+	defer gc.game.mutex.RUnlock()
+
+	return json.Marshal(&gameSnapshot{
+		Game:    gc.game,
+		SavedAt: time.Now().UnixMilli(),
+	})
+}
+
+// saveSnapshot 将进行中的对局写入存储，调用方需持有gc.mutex
+func (gc *GameController) saveSnapshot() {
+	if gc.game.roomManager == nil || !gc.game.IsStarted {
+		return
+	}
+
+	data, err := gc.snapshot()
+	if err != nil {
+		gc.logger().Error("序列化对局快照失败", "error", err)
+		return
+	}
+	gc.game.roomManager.saveSnapshot(gc.game.Room.ID, data)
+}
+
+// SaveSnapshot 保存对局快照
+func (gc *GameController) SaveSnapshot() {
+	gc.mutex.RLock()
+	defer gc.mutex.RUnlock()
+
+	gc.saveSnapshot()
+}
+
+// restoreGameController 从快照恢复对局控制器并继续计时
+func restoreGameController(data []byte, rm *RoomManager) (*GameController, error) {
+	var snap gameSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+
+	game := snap.Game
+	game.roomManager = rm
+	if game.Skills == nil {
+		game.Skills = make(map[string]*WitchSkills)
+	}
+	if game.KnownRoles == nil {
+		game.KnownRoles = make(map[string]map[string]models.Role)
+	}
+
+	if game.PhaseEndsAt == 0 {
+		game.PhaseEndsAt = snap.PhaseEndsAt
+	}
+
+	// 给断线的玩家留出重连时间，再继续当前阶段的计时
+	if time.Until(time.UnixMilli(game.PhaseEndsAt)) < playerCleanupDelay {
+		game.startPhaseClock(playerCleanupDelay)
+	}
+
+	gc := NewGameController(game, rm.webSocketMgr)
+
+	// 在对局goroutine中设置计时器，调用方此时可能持有rm.mutex
+	gc.post(func() {
+		gc.armPhaseTimer()
+		gc.armBotTimer()
+	})
+
+	return gc, nil
+}
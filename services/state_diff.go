@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// stateSink 能够按连接的协议版本推送完整状态或增量的接收方，
+// 协议版本3及以上的连接收到增量，其他连接和房间事件记录中保存完整状态
+type stateSink interface {
+	SendStateToPlayer(playerID string, full, patch interface{}) error
+	BroadcastStateToAudience(roomID string, audience Audience, full, patch interface{})
+}
+
+// sentView 最近一次推送给某个视角的游戏状态，按字段和玩家分别保存序列化结果用于比较
+type sentView struct {
+	seq       int64
+	fields    map[string]json.RawMessage
+	playerIDs []string
+	players   map[string]json.RawMessage
+}
+
+// gameStatePatch game_state的增量，客户端在view_seq等于base_seq的状态上应用：
+// changed中的字段直接替换，removed中的字段删除，players中的玩家按ID替换列表中的对应项。
+// 客户端的view_seq与base_seq不一致时，按消息的seq发送replay补发，补发的是同一序号的完整状态
+type gameStatePatch struct {
+	Type    string                     `json:"type"` // 固定为game_state_patch
+	BaseSeq int64                      `json:"base_seq"`
+	ViewSeq int64                      `json:"view_seq"`
+	Changed map[string]json.RawMessage `json:"changed,omitempty"`
+	Removed []string                   `json:"removed,omitempty"`
+	Players []json.RawMessage          `json:"players,omitempty"`
+}
+
+// newSentView 拆分已序列化的完整状态的各字段，players在玩家列表不变时按玩家比较
+func newSentView(seq int64, message gameStateMessage, data []byte) (*sentView, error) {
+	view := &sentView{seq: seq}
+	if err := json.Unmarshal(data, &view.fields); err != nil {
+		return nil, err
+	}
+	delete(view.fields, "type")
+	delete(view.fields, "view_seq")
+
+	var players []json.RawMessage
+	if err := json.Unmarshal(view.fields["players"], &players); err != nil {
+		return nil, err
+	}
+	view.players = make(map[string]json.RawMessage, len(players))
+	for i, player := range message.Players {
+		view.playerIDs = append(view.playerIDs, player.ID)
+		view.players[player.ID] = players[i]
+	}
+	return view, nil
+}
+
+// diff 计算从previous到当前状态的增量，玩家列表的人数或顺序变化时整体替换players
+func (v *sentView) diff(previous *sentView) *gameStatePatch {
+	patch := &gameStatePatch{Type: "game_state_patch", BaseSeq: previous.seq, ViewSeq: v.seq, Changed: make(map[string]json.RawMessage)}
+	for key, value := range v.fields {
+		if key == "players" {
+			continue
+		}
+		if old, exists := previous.fields[key]; !exists || !bytes.Equal(old, value) {
+			patch.Changed[key] = value
+		}
+	}
+	for key := range previous.fields {
+		if _, exists := v.fields[key]; !exists {
+			patch.Removed = append(patch.Removed, key)
+		}
+	}
+
+	if !equalStrings(v.playerIDs, previous.playerIDs) {
+		patch.Changed["players"] = v.fields["players"]
+		return patch
+	}
+	for _, id := range v.playerIDs {
+		if !bytes.Equal(v.players[id], previous.players[id]) {
+			patch.Players = append(patch.Players, v.players[id])
+		}
+	}
+	return patch
+}
+
+// equalStrings 两个字符串列表的内容和顺序是否相同
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// stateUpdate 生成某个视角的完整状态和相对上次推送的增量，首次推送时增量为nil，
+// viewerID为玩家ID，旁观者为空。完整状态只序列化一次，计算增量和发送都使用返回的encoded，
+// 序列化失败时encoded为nil，在对局goroutine中执行
+func (gc *GameController) stateUpdate(viewerID string, view *models.PlayerGameView) (full gameStateMessage, encoded json.RawMessage, patch *gameStatePatch) {
+	full = gameStateMessage{Type: "game_state", Room: gc.game.Room, PlayerGameView: view, ViewSeq: gc.viewSeq}
+
+	encoded, err := json.Marshal(full)
+	if err == nil {
+		var current *sentView
+		if current, err = newSentView(gc.viewSeq, full, encoded); err == nil {
+			if gc.sentViews == nil {
+				gc.sentViews = make(map[string]*sentView)
+			}
+			if previous := gc.sentViews[viewerID]; previous != nil {
+				patch = current.diff(previous)
+			}
+			gc.sentViews[viewerID] = current
+			return full, encoded, patch
+		}
+	}
+
+	slog.Error("计算游戏状态增量失败", "room_id", gc.game.Room.ID, "error", err)
+	delete(gc.sentViews, viewerID)
+	return full, nil, nil
+}
+
+// sendState 向玩家推送游戏状态，接收方支持增量时同时提供增量，在对局goroutine中执行
+func (gc *GameController) sendState(playerID string, view *models.PlayerGameView) {
+	full, encoded, patch := gc.stateUpdate(playerID, view)
+	if sink, ok := gc.sink.(stateSink); ok && encoded != nil {
+		sink.SendStateToPlayer(playerID, encoded, optionalPatch(patch))
+		return
+	}
+	gc.sink.SendToPlayer(playerID, full)
+}
+
+// broadcastSpectatorState 向旁观者推送只包含公开信息的游戏状态，在对局goroutine中执行
+func (gc *GameController) broadcastSpectatorState(sink audienceSink, view *models.PlayerGameView) {
+	full, encoded, patch := gc.stateUpdate("", view)
+	if stateSink, ok := gc.sink.(stateSink); ok && encoded != nil {
+		stateSink.BroadcastStateToAudience(gc.game.Room.ID, AudienceSpectators, encoded, optionalPatch(patch))
+		return
+	}
+	sink.BroadcastToAudience(gc.game.Room.ID, AudienceSpectators, full)
+}
+
+// optionalPatch 没有增量时返回无类型的nil，接收方据此判断是否发送增量
+func optionalPatch(patch *gameStatePatch) interface{} {
+	if patch == nil {
+		return nil
+	}
+	return patch
+}
@@ -1,8 +1,6 @@
 package services
 
 import (
-	"errors"
-
 	"github.com/qianlnk/werewolf/models"
 )
 
@@ -17,8 +15,8 @@ const (
 
 // StateMachine 游戏状态机
 type StateMachine struct {
-	game   *GameState
-	status string // 游戏状态：ongoing, werewolf_win, villager_win
+	game     *GameState
+	lastVote *VoteTally // 最近一次投票结算的计票结果，由控制器取走后广播
 }
 
 // NewStateMachine 创建状态机实例
@@ -26,22 +24,42 @@ func NewStateMachine(game *GameState) *StateMachine {
 	return &StateMachine{game: game}
 }
 
-// TransitionPhase 转换游戏阶段
-func (sm *StateMachine) TransitionPhase() error {
+// TransitionPhase 转换游戏阶段，返回阶段切换后的对局结果，对局未结束时为GameOngoing
+func (sm *StateMachine) TransitionPhase() (string, error) {
 	if !sm.game.IsStarted {
-		return ErrGameNotStarted
+		return "", ErrGameNotStarted
 	}
 
 	// 检查当前阶段是否所有必要动作都已完成
 	if !sm.isPhaseComplete() {
-		return errors.New("当前阶段尚未完成所有必要动作")
+		return "", NewError(CodePhaseIncomplete, "当前阶段尚未完成所有必要动作")
 	}
 
+	// 上一次出局的猎人没有在本阶段内开枪，视为放弃；出局的警长没有移交警徽，视为撕毁
+	sm.game.PendingShot = ""
+	sm.game.PendingBadge = ""
+
 	// 更新游戏阶段
 	switch sm.game.Phase {
 	case PhaseNight:
 		// 处理夜晚阶段的结果
 		sm.processNightResults()
+		sm.game.recordDeaths()
+		sm.game.Phase = PhaseDay
+		sm.game.NightStep = ""
+		// 开启警长竞选的房间在第一晚结束后先竞选警长
+		if sm.game.Room.Sheriff && sm.game.Round == 1 {
+			sm.game.Phase = PhaseCampaign
+		}
+
+	case PhaseCampaign:
+		sm.game.Phase = PhaseDay
+		if sm.processCampaign() {
+			sm.game.Phase = PhaseSheriffVote
+		}
+
+	case PhaseSheriffVote:
+		sm.processSheriffVote()
 		sm.game.Phase = PhaseDay
 
 	case PhaseDay:
@@ -51,61 +69,63 @@ func (sm *StateMachine) TransitionPhase() error {
 	case PhaseVote:
 		// 处理投票结果
 		sm.processVoteResults()
+		sm.game.recordDeaths()
 		// 进入新的夜晚
 		sm.game.Phase = PhaseNight
 		sm.game.Round++
 	}
 
-	// 重置阶段时间
-	sm.game.TimeLeft = 120
+	sm.game.recordEvent(models.GameEvent{Type: "phase_change", Content: sm.game.Phase})
+
+	// 重置阶段时间，夜晚从第一个步骤开始按步骤计时
+	if sm.game.Phase == PhaseNight {
+		sm.game.beginNight()
+	} else {
+		sm.game.startPhaseClock(phaseDuration)
+	}
 
 	// 检查游戏是否结束
-	return sm.checkGameEnd()
+	return sm.checkGameEnd(), nil
+}
+
+// skipVote 白狼王自爆后跳过当天的投票直接进入黑夜
+func (sm *StateMachine) skipVote() {
+	sm.game.Actions = make([]models.GameAction, 0)
+	sm.game.VoteBonus = nil
+	sm.game.Phase = PhaseNight
+	sm.game.Round++
+	sm.game.recordEvent(models.GameEvent{Type: "phase_change", Content: sm.game.Phase})
+	sm.game.beginNight()
 }
 
 // isPhaseComplete 检查当前阶段是否完成
 func (sm *StateMachine) isPhaseComplete() bool {
+	// 夜晚按步骤进行，最后一个步骤结束时夜晚结束
+	if sm.game.Phase == PhaseNight {
+		_, more := sm.game.nextNightStep(sm.game.NightStep)
+		return !more && sm.isNightStepComplete()
+	}
+
+	// 已到截止时间，尚未完成的动作视为放弃
+	if sm.game.phaseExpired() {
+		return true
+	}
+
 	switch sm.game.Phase {
-	case PhaseNight:
-		return sm.checkNightActionsComplete()
 	case PhaseDay:
-		return sm.game.TimeLeft <= 0
+		// 白天的发言持续到截止时间
+		return false
 	case PhaseVote:
 		return sm.checkVoteComplete()
+	case PhaseCampaign:
+		return sm.checkCampaignComplete()
+	case PhaseSheriffVote:
+		return sm.checkSheriffVoteComplete()
 	default:
 		return false
 	}
 }
 
-// checkNightActionsComplete 检查夜晚行动是否完成
-func (sm *StateMachine) checkNightActionsComplete() bool {
-	// 检查每个活着的特殊角色是否完成了行动
-	for _, player := range sm.game.Players {
-		if !player.Alive {
-			continue
-		}
-
-		switch player.Role {
-		case models.Werewolf, models.WhiteWolf:
-			if !sm.hasActionOfType(player.ID, "kill") {
-				return false
-			}
-		case models.Seer:
-			if !sm.hasActionOfType(player.ID, "check") {
-				return false
-			}
-		case models.Witch:
-			// 女巫可以选择不使用技能
-			continue
-		case models.Guard:
-			if !sm.hasActionOfType(player.ID, "protect") {
-				return false
-			}
-		}
-	}
-	return true
-}
-
 // checkVoteComplete 检查投票是否完成
 func (sm *StateMachine) checkVoteComplete() bool {
 	// 检查是否所有活着的玩家都已投票
@@ -114,7 +134,7 @@ func (sm *StateMachine) checkVoteComplete() bool {
 	for _, player := range sm.game.Players {
 		if player.Alive {
 			aliveCount++
-			if sm.hasActionOfType(player.ID, "vote") {
+			if sm.game.hasAction(player.ID, "vote") {
 				voteCount++
 			}
 		}
@@ -122,71 +142,106 @@ func (sm *StateMachine) checkVoteComplete() bool {
 	return voteCount == aliveCount
 }
 
-// hasActionOfType 检查玩家是否执行了特定类型的动作
-func (sm *StateMachine) hasActionOfType(playerID, actionType string) bool {
-	for _, action := range sm.game.Actions {
-		if action.PlayerID == playerID && action.Type == actionType {
-			return true
-		}
-	}
-	return false
-}
-
 // processNightResults 处理夜晚阶段的结果
 func (sm *StateMachine) processNightResults() {
-	// 处理狼人击杀
+	// 丘比特在第一晚连出情侣
+	sm.game.linkLovers()
+
+	// 狼队每晚只击杀共识目标一人，守卫的守护和女巫的解药只对该玩家生效，毒药单独结算
+	victim := wolfConsensusTarget(sm.game.Actions)
+	var saved, protected, poisoned string
 	for _, action := range sm.game.Actions {
-		if action.Type == "kill" {
-			processActionResult(sm.game, action)
+		switch action.Type {
+		case "protect":
+			protected = action.TargetID
+			continue
+		case "save":
+			if action.TargetID != action.PlayerID {
+				saved = action.TargetID
+			}
+		case "poison":
+			poisoned = action.TargetID
+		default:
+			continue
 		}
+		sm.game.useWitchPotion(action)
+	}
+	// 同一晚既被守护又被救下时仍然死亡（同守同救）
+	if victim != "" && (victim == saved) != (victim == protected) {
+		victim = ""
 	}
 
-	// 处理女巫救人或毒人
-	for _, action := range sm.game.Actions {
-		if action.Type == "save" || action.Type == "poison" {
-			processActionResult(sm.game, action)
-		}
+	// 同时被击杀和毒杀的玩家算作毒杀，猎人不能开枪
+	if poisoned != "" {
+		sm.game.kill(poisoned, models.DeathPoison)
+	}
+	if victim != "" && sm.game.kill(victim, models.DeathWolfKill) {
+		sm.game.triggerHunter(victim)
 	}
 
 	// 清空行动列表
 	sm.game.Actions = make([]models.GameAction, 0)
 }
 
-// processVoteResults 处理投票结果
-func (sm *StateMachine) processVoteResults() {
-	// 统计票数
+// wolfKillTally 统计狼人的击杀选择，返回各目标的票数和按首次被选择排序的目标
+func wolfKillTally(actions []models.GameAction) (map[string]int, []string) {
 	votes := make(map[string]int)
-	for _, action := range sm.game.Actions {
-		if action.Type == "vote" {
-			votes[action.TargetID]++
+	var order []string
+	for _, action := range actions {
+		if action.Type != "kill" || action.TargetID == "" {
+			continue
 		}
-	}
-
-	// 找出票数最多的玩家
-	maxVotes := 0
-	var eliminatedID string
-	for playerID, count := range votes {
-		if count > maxVotes {
-			maxVotes = count
-			eliminatedID = playerID
+		if votes[action.TargetID] == 0 {
+			order = append(order, action.TargetID)
 		}
+		votes[action.TargetID]++
 	}
+	return votes, order
+}
 
-	// 处理投票结果
-	if eliminatedID != "" {
-		action := models.GameAction{
-			Type:     "vote",
-			TargetID: eliminatedID,
+// wolfConsensusTarget 狼队的共识击杀目标：票数最多的玩家，平票时取最先被选择的玩家
+func wolfConsensusTarget(actions []models.GameAction) string {
+	votes, order := wolfKillTally(actions)
+	var target string
+	for _, targetID := range order {
+		if votes[targetID] > votes[target] {
+			target = targetID
 		}
-		processActionResult(sm.game, action)
 	}
+	return target
+}
 
-	// 清空行动列表
+// processVoteResults 处理投票结果：按票权计票并放逐得票最多的玩家，额外票数只在本轮有效
+func (sm *StateMachine) processVoteResults() {
+	tally := sm.game.tallyVotes()
+	sm.game.eliminate(tally)
+	sm.lastVote = tally
+
+	// 清空行动列表和本轮的额外票数
 	sm.game.Actions = make([]models.GameAction, 0)
+	sm.game.VoteBonus = nil
+}
+
+// takeVoteTally 取走最近一次投票的计票结果，没有新的投票结算时返回nil
+func (sm *StateMachine) takeVoteTally() *VoteTally {
+	tally := sm.lastVote
+	sm.lastVote = nil
+	return tally
+}
+
+// checkGameEnd 检查游戏是否结束，结束时记录对局结果和game_end事件，返回对局结果
+func (sm *StateMachine) checkGameEnd() string {
+	result := sm.gameResult()
+	if result != GameOngoing {
+		sm.game.IsStarted = false
+		sm.game.Result = result
+		sm.game.recordEvent(models.GameEvent{Type: "game_end", Content: result})
+	}
+	return result
 }
 
-// checkGameEnd 检查游戏是否结束
-func (sm *StateMachine) checkGameEnd() error {
+// gameResult 按存活玩家判定对局结果
+func (sm *StateMachine) gameResult() string {
 	// 统计各阵营存活人数
 	werewolfCount := 0
 	villagerCount := 0
@@ -227,25 +282,22 @@ func (sm *StateMachine) checkGameEnd() error {
 	// 判定特殊胜利条件
 	// 1. 情侣胜利：只剩下情侣存活
 	if loversAlive == 2 && loversAlive == villagerCount+werewolfCount {
-		sm.status = LoversWin
-		return errors.New("情侣阵营胜利：只剩下情侣存活")
+		return LoversWin
 	}
 
 	// 2. 白狼王觉醒胜利：只剩白狼王一人
 	if whiteWolfCount == 1 && werewolfCount == 1 && villagerCount == 0 {
-		sm.status = WhiteWolfWin
-		return errors.New("白狼王觉醒胜利：白狼王成为最后的胜利者")
+		return WhiteWolfWin
 	}
 
 	// 常规胜利条件判定
 	if werewolfCount == 0 {
-		sm.status = VillagerWin
-		return errors.New("好人阵营胜利：所有狼人都已被清除")
+		// 所有狼人都已被清除
+		return VillagerWin
 	} else if werewolfCount >= villagerCount {
-		sm.status = WerewolfWin
-		return errors.New("狼人阵营胜利：狼人数量已经超过或等于好人数量")
+		// 狼人数量已经超过或等于好人数量
+		return WerewolfWin
 	}
 
-	sm.status = GameOngoing
-	return nil
+	return GameOngoing
 }
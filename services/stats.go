@@ -0,0 +1,150 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+// StatsManager 玩家统计管理器
+type StatsManager struct {
+	store storage.Store
+}
+
+// NewStatsManager 创建统计管理器实例
+func NewStatsManager(store storage.Store) *StatsManager {
+	return &StatsManager{store: store}
+}
+
+// GetStats 获取玩家统计，没有对局记录的玩家返回空统计
+func (sm *StatsManager) GetStats(playerID string) (*models.PlayerStats, error) {
+	stats, err := sm.store.GetPlayerStats(playerID)
+	if err == storage.ErrNotFound {
+		return newPlayerStats(playerID), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stats.Roles == nil {
+		stats.Roles = make(map[models.Role]*models.RoleStats)
+	}
+	return stats, nil
+}
+
+// ApplyGameRecord 根据对局记录更新真人玩家的统计，返回更新后的统计
+func (sm *StatsManager) ApplyGameRecord(record *models.GameRecord) map[string]*models.PlayerStats {
+	updated := make(map[string]*models.PlayerStats)
+
+	// 从事件日志中提取死亡回合和最终回合
+	deathRounds := make(map[string]int)
+	finalRound := 1
+	for _, event := range record.Events {
+		switch event.Type {
+		case "death":
+			deathRounds[event.PlayerID] = event.Round
+		case "revive":
+			delete(deathRounds, event.PlayerID)
+		}
+		if event.Round > finalRound {
+			finalRound = event.Round
+		}
+	}
+
+	roles := make(map[string]models.Role)
+	for _, player := range record.Players {
+		roles[player.ID] = player.Role
+	}
+
+	for _, player := range record.Players {
+		if player.Type != models.HumanPlayer {
+			continue
+		}
+
+		stats, err := sm.GetStats(player.ID)
+		if err != nil {
+			slog.Error("读取玩家统计失败", "player_id", player.ID, "error", err)
+			continue
+		}
+
+		won := isWinner(player, record.Result)
+		stats.Games++
+		roleStats := stats.Roles[player.Role]
+		if roleStats == nil {
+			roleStats = &models.RoleStats{}
+			stats.Roles[player.Role] = roleStats
+		}
+		roleStats.Games++
+		if won {
+			stats.Wins++
+			roleStats.Wins++
+		}
+
+		if round, dead := deathRounds[player.ID]; dead {
+			stats.SurvivedRounds += round
+		} else {
+			stats.SurvivedRounds += finalRound
+			stats.SurvivedGames++
+		}
+
+		// 到时由系统代为执行的动作不计入玩家的统计
+		for _, event := range record.Events {
+			if event.Type != "action" || event.PlayerID != player.ID || event.System {
+				continue
+			}
+			switch event.Action {
+			case "check":
+				stats.SeerChecks++
+				if isWolf(roles[event.TargetID]) {
+					stats.SeerWolvesFound++
+				}
+			case "kill":
+				stats.WolfKills++
+				if round, dead := deathRounds[event.TargetID]; dead && round == event.Round {
+					stats.WolfKillSuccess++
+				}
+			}
+		}
+
+		refreshRates(stats)
+		stats.UpdatedAt = time.Now().Unix()
+		if err := sm.store.SavePlayerStats(stats); err != nil {
+			slog.Error("保存玩家统计失败", "player_id", player.ID, "error", err)
+			continue
+		}
+		updated[player.ID] = stats
+	}
+
+	return updated
+}
+
+// newPlayerStats 创建空的玩家统计
+func newPlayerStats(playerID string) *models.PlayerStats {
+	return &models.PlayerStats{
+		PlayerID: playerID,
+		Roles:    make(map[models.Role]*models.RoleStats),
+	}
+}
+
+// refreshRates 重新计算各项比率
+func refreshRates(stats *models.PlayerStats) {
+	if stats.Roles == nil {
+		stats.Roles = make(map[models.Role]*models.RoleStats)
+	}
+	stats.WinRate = ratio(stats.Wins, stats.Games)
+	stats.AvgSurvivalRounds = ratio(stats.SurvivedRounds, stats.Games)
+	stats.SeerAccuracy = ratio(stats.SeerWolvesFound, stats.SeerChecks)
+	stats.WolfKillRate = ratio(stats.WolfKillSuccess, stats.WolfKills)
+	for _, roleStats := range stats.Roles {
+		roleStats.WinRate = ratio(roleStats.Wins, roleStats.Games)
+	}
+}
+
+// ratio 计算比率，分母为0时返回0
+func ratio(numerator, denominator int) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return float64(numerator) / float64(denominator)
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// storyNightActions 夜晚动作在回顾中的叙述顺序，与夜晚各步骤的顺序一致
+var storyNightActions = []string{"kill", "protect", "save", "poison", "check"}
+
+// storySection 回顾中的一夜或一天（包括当天的投票），按事件日志汇总
+type storySection struct {
+	night    bool
+	round    int
+	links    []models.GameEvent          // 丘比特的连线，按提交顺序
+	last     map[string]models.GameEvent // 每名玩家每种夜晚动作的最后一次提交，键为 玩家ID/动作
+	election []models.GameEvent          // 竞选警长的结果，在白天的投票之前叙述
+	actions  []models.GameEvent          // 猎人开枪和白狼王自爆
+	voters   []string                    // 按首次投票的顺序排列的投票玩家
+	votes    map[string]models.GameEvent // 每名玩家最后一次投票或弃票
+	deaths   []models.GameEvent          // 出局事件、警徽的移交，以及法官的复活和公开裁定，按发生顺序排列
+}
+
+// BuildGameStory 根据对局记录生成可以直接分享的文字回顾，逐夜逐天叙述狼人、神职的行动、
+// 投票和出局情况，最后给出对局结果。记录中所有角色都已公开，回顾不做隐藏
+func BuildGameStory(record *models.GameRecord, locale Locale) string {
+	locale = locale.orDefault()
+	names := make(map[string]string, len(record.Players))
+	for _, player := range record.Players {
+		names[player.ID] = player.Name
+	}
+	name := func(playerID string) string {
+		if name := names[playerID]; name != "" {
+			return name
+		}
+		return playerID
+	}
+
+	var lines []string
+	minutes := (record.Duration + 59) / 60
+	lines = append(lines, Translate(locale, "story.title", len(record.Players), minutes))
+
+	players := make([]string, 0, len(record.Players))
+	for _, player := range record.Players {
+		players = append(players, Translate(locale, "story.player_role", player.Name, roleName(locale, player.Role)))
+	}
+	lines = append(lines, Translate(locale, "story.players", joinNames(locale, players)), "")
+
+	roles := make(map[string]models.Role, len(record.Players))
+	for _, player := range record.Players {
+		roles[player.ID] = player.Role
+	}
+	for _, section := range storySections(record.Events) {
+		lines = append(lines, section.render(locale, name, roles))
+	}
+
+	if record.Result != "" {
+		lines = append(lines, "", Translate(locale, "narration.result."+record.Result))
+		var winners []string
+		for _, player := range record.Players {
+			if isWinner(player, record.Result) {
+				winners = append(winners, player.Name)
+			}
+		}
+		if len(winners) > 0 {
+			lines = append(lines, Translate(locale, "story.winners", joinNames(locale, winners)))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// storyEvents 回顾中叙述的事件类型，法官的私人备注不写入回顾
+var storyEvents = map[string]bool{"action": true, "death": true, "revive": true, "sheriff": true, "judge_ruling": true}
+
+// storySections 按回合和昼夜拆分事件日志，白天和当天的投票合为一段
+func storySections(events []models.GameEvent) []*storySection {
+	var sections []*storySection
+	var current *storySection
+	for _, event := range events {
+		if event.Round == 0 || !storyEvents[event.Type] {
+			continue
+		}
+		night := event.Phase == PhaseNight
+		if current == nil || current.round != event.Round || current.night != night {
+			current = &storySection{
+				night: night,
+				round: event.Round,
+				last:  make(map[string]models.GameEvent),
+				votes: make(map[string]models.GameEvent),
+			}
+			sections = append(sections, current)
+		}
+		current.add(event)
+	}
+	return sections
+}
+
+// add 汇总一条动作、死亡或法官事件，同一阶段只能选择一次的动作以最后一次提交为准
+func (s *storySection) add(event models.GameEvent) {
+	if event.Type == "sheriff" && event.Content == SheriffElected {
+		s.election = append(s.election, event)
+		return
+	}
+	if event.Type != "action" {
+		s.deaths = append(s.deaths, event)
+		return
+	}
+	switch event.Action {
+	case "link":
+		s.links = append(s.links, event)
+	case "kill", "protect", "save", "poison", "check":
+		s.last[event.PlayerID+"/"+event.Action] = event
+	case "shoot", "explode":
+		s.actions = append(s.actions, event)
+	case "vote", "abstain":
+		// 警长投票的弃票不计入放逐投票，竞选的结果由sheriff事件叙述
+		if event.Content == "elect" {
+			return
+		}
+		if _, exists := s.votes[event.PlayerID]; !exists {
+			s.voters = append(s.voters, event.PlayerID)
+		}
+		s.votes[event.PlayerID] = event
+	}
+}
+
+// render 生成这一段的叙述
+func (s *storySection) render(locale Locale, name func(string) string, roles map[string]models.Role) string {
+	var sentences []string
+
+	// 丘比特每次连一名玩家，两次连线合为一句
+	for i := 0; i+1 < len(s.links); i += 2 {
+		sentences = append(sentences, Translate(locale, "story.link", name(s.links[i].TargetID), name(s.links[i+1].TargetID)))
+	}
+	for _, actionType := range storyNightActions {
+		if actionType == "kill" {
+			if target := s.wolfTarget(); target != "" {
+				sentences = append(sentences, Translate(locale, "story.kill", name(target)))
+			}
+			continue
+		}
+		for _, event := range s.nightActions(actionType) {
+			if actionType != "check" {
+				sentences = append(sentences, Translate(locale, "story."+actionType, name(event.TargetID)))
+				continue
+			}
+			result := Translate(locale, "story.check_good")
+			if isWolf(roles[event.TargetID]) {
+				result = Translate(locale, "story.check_wolf")
+			}
+			sentences = append(sentences, Translate(locale, "story.check", name(event.TargetID), result))
+		}
+	}
+
+	for _, event := range s.election {
+		sentences = append(sentences, describeSheriff(locale, name, event))
+	}
+	for _, event := range s.actions {
+		sentences = append(sentences, Translate(locale, "story."+event.Action, name(event.PlayerID), name(event.TargetID)))
+	}
+	if votes := s.describeVotes(locale, name); votes != "" {
+		sentences = append(sentences, votes)
+	}
+
+	// 开枪和自爆已经在动作中叙述
+	deaths := 0
+	for _, event := range s.deaths {
+		switch event.Type {
+		case "revive":
+			sentences = append(sentences, Translate(locale, "story.revive", name(event.PlayerID)))
+			continue
+		case "judge_ruling":
+			sentences = append(sentences, Translate(locale, "story.judge_ruling", event.Content))
+			continue
+		case "sheriff":
+			sentences = append(sentences, describeSheriff(locale, name, event))
+			continue
+		}
+		switch event.Cause {
+		case models.DeathHunterShot, models.DeathSelfDestruct, models.DeathExploded:
+			continue
+		}
+		key := "story.death." + string(event.Cause)
+		if event.Cause == "" {
+			key = "story.death"
+		}
+		sentences = append(sentences, Translate(locale, key, name(event.PlayerID)))
+		deaths++
+	}
+	if s.night && deaths == 0 {
+		sentences = append(sentences, Translate(locale, "story.peaceful_night"))
+	}
+
+	header := "story.day"
+	if s.night {
+		header = "story.night"
+	}
+	if len(sentences) == 0 {
+		return Translate(locale, header, s.round, Translate(locale, "story.nothing"))
+	}
+	return Translate(locale, header, s.round, joinSentences(locale, sentences))
+}
+
+// describeSheriff 叙述警长的产生或警徽的移交
+func describeSheriff(locale Locale, name func(string) string, event models.GameEvent) string {
+	switch {
+	case event.Content == SheriffElected && event.PlayerID != "":
+		return Translate(locale, "story.sheriff_elected", name(event.PlayerID))
+	case event.Content == SheriffElected:
+		return Translate(locale, "story.sheriff_none")
+	case event.PlayerID != "":
+		return Translate(locale, "story.badge_passed", name(event.PlayerID))
+	default:
+		return Translate(locale, "story.badge_torn")
+	}
+}
+
+// wolfTarget 狼队本晚的共识击杀目标，每名狼人以最后一次选择为准
+func (s *storySection) wolfTarget() string {
+	var kills []models.GameAction
+	for _, event := range s.nightActions("kill") {
+		kills = append(kills, models.GameAction{Type: event.Action, PlayerID: event.PlayerID, TargetID: event.TargetID})
+	}
+	return wolfConsensusTarget(kills)
+}
+
+// nightActions 某种夜晚动作各玩家最后一次的提交，按提交顺序排列
+func (s *storySection) nightActions(actionType string) []models.GameEvent {
+	var events []models.GameEvent
+	for _, event := range s.last {
+		if event.Action == actionType && event.TargetID != "" {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events
+}
+
+// describeVotes 按被投票玩家汇总的投票情况，没有投票时返回空
+func (s *storySection) describeVotes(locale Locale, name func(string) string) string {
+	if len(s.voters) == 0 {
+		return ""
+	}
+	var targets []string
+	voters := make(map[string][]string)
+	var abstained []string
+	for _, playerID := range s.voters {
+		event := s.votes[playerID]
+		if event.Action != "vote" || event.TargetID == "" {
+			abstained = append(abstained, name(playerID))
+			continue
+		}
+		if _, exists := voters[event.TargetID]; !exists {
+			targets = append(targets, event.TargetID)
+		}
+		voters[event.TargetID] = append(voters[event.TargetID], name(playerID))
+	}
+
+	var groups []string
+	for _, targetID := range targets {
+		groups = append(groups, Translate(locale, "story.vote_for", joinNames(locale, voters[targetID]), name(targetID)))
+	}
+	if len(abstained) > 0 {
+		groups = append(groups, Translate(locale, "story.abstain", joinNames(locale, abstained)))
+	}
+	return Translate(locale, "story.votes", strings.Join(groups, Translate(locale, "story.vote_separator")))
+}
+
+// roleName 角色在指定语言下的名称，目录中没有时返回角色标识
+func roleName(locale Locale, role models.Role) string {
+	key := "role." + string(role)
+	if name := Translate(locale, key); name != key {
+		return name
+	}
+	return string(role)
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// TimeoutPolicy 阶段到时仍未完成必要动作时的默认处理
+type TimeoutPolicy string
+
+const (
+	// TimeoutSkip 放弃该动作：狼人不击杀、预言家不查验、守卫不守护、投票弃票
+	TimeoutSkip TimeoutPolicy = "skip"
+	// TimeoutRandom 从可选目标中随机选择，不会选中自己（守卫守护除外），狼人已有同伴选定目标时跟随狼队的共识
+	TimeoutRandom TimeoutPolicy = "random"
+)
+
+// ParseTimeoutPolicy 解析配置中的超时默认处理，未配置时放弃
+func ParseTimeoutPolicy(value string) (TimeoutPolicy, error) {
+	switch policy := TimeoutPolicy(value); policy {
+	case "":
+		return TimeoutSkip, nil
+	case TimeoutSkip, TimeoutRandom:
+		return policy, nil
+	}
+	return "", fmt.Errorf("不支持的超时默认处理 %s", value)
+}
+
+// TimeoutDefaults 各类必要动作到时未完成时的默认处理，丘比特连线总是放弃
+type TimeoutDefaults struct {
+	Kill    TimeoutPolicy // 狼人击杀
+	Check   TimeoutPolicy // 预言家查验
+	Protect TimeoutPolicy // 守卫守护
+	Vote    TimeoutPolicy // 放逐投票和警长投票
+}
+
+// policy 获取动作类型对应的默认处理
+func (d TimeoutDefaults) policy(actionType string) TimeoutPolicy {
+	switch actionType {
+	case "kill":
+		return d.Kill
+	case "check":
+		return d.Check
+	case "protect":
+		return d.Protect
+	case "vote", "elect":
+		return d.Vote
+	}
+	return TimeoutSkip
+}
+
+// timeoutDefaultActions 到时会被补上默认动作的必要动作，女巫用药和上警本身可以不做，不在其中
+var timeoutDefaultActions = map[string]bool{
+	"kill":    true,
+	"check":   true,
+	"protect": true,
+	"vote":    true,
+	"elect":   true,
+	"link":    true,
+}
+
+// SetTimeoutDefaults 设置阶段到时仍未完成必要动作时的默认处理
+func (rm *RoomManager) SetTimeoutDefaults(defaults TimeoutDefaults) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.timeoutDefaults = defaults
+}
+
+// timeoutDefaults 获取超时默认处理，未关联房间管理器时全部放弃
+func (gc *GameController) timeoutDefaults() TimeoutDefaults {
+	if gc.game.roomManager == nil {
+		return TimeoutDefaults{}
+	}
+	gc.game.roomManager.mutex.RLock()
+	defer gc.game.roomManager.mutex.RUnlock()
+
+	return gc.game.roomManager.timeoutDefaults
+}
+
+// applyTimeoutDefaults 阶段或夜晚步骤到时后，为还有必要动作未完成的存活玩家补上默认动作，
+// 动作在事件日志中标记为系统生成，之后再照常结束阶段；被补上动作的真人玩家计一次未行动，在对局goroutine中执行
+func (gc *GameController) applyTimeoutDefaults() {
+	if !gc.game.IsStarted || gc.game.Phase == PhaseDay || !gc.game.phaseExpired() {
+		return
+	}
+
+	defaults := gc.timeoutDefaults()
+	for _, player := range gc.game.Players {
+		missed := false
+		for _, actionType := range gc.game.pendingActions(player.ID) {
+			if !timeoutDefaultActions[actionType] {
+				continue
+			}
+			missed = true
+			action := gc.game.timeoutAction(player, actionType, defaults.policy(actionType))
+			if err := gc.game.addSystemAction(action); err != nil {
+				gc.logger().Warn("执行超时默认动作失败", "player_id", player.ID, "action", action.Type, "error", err)
+				continue
+			}
+			processActionResult(gc.game, action)
+			gc.logger().Info("玩家到时未行动，执行默认动作", "player_id", player.ID, "action", action.Type, "target_id", action.TargetID)
+		}
+		if missed {
+			gc.recordMissedAction(player)
+		}
+	}
+}
+
+// timeoutAction 按默认处理生成玩家的动作：随机时从可选目标中选择，没有可选目标或放弃时生成skip动作，
+// 投票和警长投票为abstain，Content为被放弃的动作类型，调用方需持有锁
+func (gs *GameState) timeoutAction(player models.Player, actionType string, policy TimeoutPolicy) models.GameAction {
+	action := models.GameAction{PlayerID: player.ID, RoomID: gs.RoomID}
+	if policy == TimeoutRandom {
+		target := ""
+		if actionType == "kill" {
+			target = wolfConsensusTarget(gs.Actions)
+		}
+		if target == "" {
+			// 守卫可以守护自己，其余动作不随机选中自己，例如不给自己投票
+			var candidates []string
+			for _, option := range gs.availableActions(player.ID) {
+				for _, id := range option.Targets {
+					if option.Type == actionType && (id != player.ID || actionType == "protect") {
+						candidates = append(candidates, id)
+					}
+				}
+			}
+			if len(candidates) > 0 {
+				target = candidates[gs.random().Intn(len(candidates))]
+			}
+		}
+		if target != "" {
+			action.Type, action.TargetID = actionType, target
+			return action
+		}
+	}
+
+	action.Type, action.Content = "skip", actionType
+	if actionType == "vote" || actionType == "elect" {
+		action.Type = "abstain"
+	}
+	return action
+}
+
+// addSystemAction 记录系统代为生成的动作，放弃类动作不经过校验直接记录，调用方不能持有锁
+func (gs *GameState) addSystemAction(action models.GameAction) error {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	if action.Type != "skip" && action.Type != "abstain" {
+		return gs.addAction(action, true)
+	}
+	gs.applyAction(action, true)
+	return nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qianlnk/werewolf/reporting"
+)
+
+// 对局计时使用的时间轮参数：每个刻度100毫秒，一圈约51秒，更长的计时在轮上多转几圈
+const (
+	timerWheelTick  = 100 * time.Millisecond
+	timerWheelSlots = 512
+)
+
+// deadlines 所有对局共用的时间轮，阶段截止、倒计时推送、机器人时限、断线宽限期和结束对局的释放
+// 都由它调度，不再为每个对局的每个计时器各自创建定时器
+var deadlines = newTimerWheel(timerWheelTick, timerWheelSlots)
+
+// timerWheel 哈希时间轮，由一个goroutine按刻度推进并执行到期的回调。
+// 回调在时间轮的goroutine中执行，不能阻塞，对局计时的回调只向对局goroutine提交命令，见GameController.postTimer
+type timerWheel struct {
+	tick    time.Duration
+	slots   []map[*wheelTimer]struct{}
+	current int // 上一次推进到的槽位
+	pending int // 尚未到期的计时器数量
+	started bool
+	mutex   sync.Mutex
+}
+
+// wheelTimer 时间轮上的一个计时器
+type wheelTimer struct {
+	wheel  *timerWheel
+	slot   int
+	rounds int // 还要再转几圈才到期
+	f      func()
+}
+
+// newTimerWheel 创建时间轮，推进的goroutine在第一次添加计时器时启动
+func newTimerWheel(tick time.Duration, slots int) *timerWheel {
+	w := &timerWheel{tick: tick, slots: make([]map[*wheelTimer]struct{}, slots)}
+	for i := range w.slots {
+		w.slots[i] = make(map[*wheelTimer]struct{})
+	}
+	return w
+}
+
+// AfterFunc 在d之后执行f，到期时间按刻度向后取整，不会早于d
+func (w *timerWheel) AfterFunc(d time.Duration, f func()) *wheelTimer {
+	// 下一次推进可能在一个刻度内的任何时刻到来，多等一个刻度保证不会提前到期
+	ticks := 1
+	if d > 0 {
+		ticks = int((d+w.tick-1)/w.tick) + 1
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.started {
+		w.started = true
+		go w.run()
+	}
+	t := &wheelTimer{
+		wheel:  w,
+		slot:   (w.current + ticks) % len(w.slots),
+		rounds: (ticks - 1) / len(w.slots),
+		f:      f,
+	}
+	w.slots[t.slot][t] = struct{}{}
+	w.pending++
+	return t
+}
+
+// Pending 尚未到期的计时器数量
+func (w *timerWheel) Pending() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.pending
+}
+
+// run 按刻度推进时间轮
+func (w *timerWheel) run() {
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.advance()
+	}
+}
+
+// advance 推进一个刻度，执行当前槽位中到期的计时器
+func (w *timerWheel) advance() {
+	w.mutex.Lock()
+	w.current = (w.current + 1) % len(w.slots)
+	var due []func()
+	for t := range w.slots[w.current] {
+		if t.rounds > 0 {
+			t.rounds--
+			continue
+		}
+		delete(w.slots[w.current], t)
+		w.pending--
+		due = append(due, t.f)
+	}
+	w.mutex.Unlock()
+
+	for _, f := range due {
+		fire(f)
+	}
+}
+
+// fire 执行到期的回调，回调中的panic被捕获并上报，不影响时间轮上的其他计时器
+func fire(f func()) {
+	defer reporting.Recover("timer")
+	f()
+}
+
+// Stop 取消计时器，计时器已到期或已取消时返回false
+func (t *wheelTimer) Stop() bool {
+	w := t.wheel
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, exists := w.slots[t.slot][t]; !exists {
+		return false
+	}
+	delete(w.slots[t.slot], t)
+	w.pending--
+	return true
+}
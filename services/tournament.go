@@ -0,0 +1,550 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/storage"
+)
+
+var (
+	ErrTournamentNotFound   = NewError(CodeTournamentNotFound, "赛事不存在")
+	ErrMatchNotFound        = NewError(CodeMatchNotFound, "比赛场次不存在")
+	ErrInvalidTournament    = NewError(CodeInvalidTournament, "赛事设置不合法：请检查赛制、每桌人数和轮数")
+	ErrTournamentClosed     = NewError(CodeTournamentClosed, "赛事报名已截止")
+	ErrTournamentNotRunning = NewError(CodeTournamentNotRunning, "赛事未开始或已结束")
+	ErrNotOrganizer         = NewError(CodeNotOrganizer, "只有赛事的组织者可以执行该操作")
+)
+
+// 赛事积分
+const (
+	TournamentWinPoints      = 3 // 所在阵营获胜
+	TournamentSurvivalPoints = 1 // 对局结束时仍然存活
+)
+
+const (
+	maxTournamentTableSize = 12 // 每桌人数上限
+	maxTournamentRounds    = 10 // 循环赛轮数上限
+	// DefaultTournamentRounds 创建循环赛时未指定轮数的默认值
+	DefaultTournamentRounds = 3
+)
+
+// TournamentManager 赛事管理器：按赛制分桌，为每场比赛自动创建房间，对局结束后计分并安排下一轮
+type TournamentManager struct {
+	store       storage.Store
+	rooms       *RoomManager
+	webSocket   *WebSocketManager
+	tournaments map[string]*models.Tournament // 报名中和进行中的赛事，已结束的赛事只从存储读取
+	rng         *rand.Rand
+	mutex       sync.Mutex
+}
+
+// tournamentNotice 待发送给选手的通知，在释放锁之后发送
+type tournamentNotice struct {
+	playerID string
+	message  map[string]interface{}
+}
+
+// NewTournamentManager 创建赛事管理器实例
+func NewTournamentManager(store storage.Store, rooms *RoomManager, ws *WebSocketManager) *TournamentManager {
+	return &TournamentManager{
+		store:       store,
+		rooms:       rooms,
+		webSocket:   ws,
+		tournaments: make(map[string]*models.Tournament),
+		rng:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Load 从存储恢复报名中和进行中的赛事，在恢复房间之后调用
+func (tm *TournamentManager) Load() error {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	for _, status := range []string{models.TournamentRegistering, models.TournamentRunning} {
+		tournaments, err := tm.store.ListTournaments(status)
+		if err != nil {
+			return err
+		}
+		for _, tournament := range tournaments {
+			tm.tournaments[tournament.ID] = tournament
+		}
+	}
+	return nil
+}
+
+// Create 创建赛事，创建者为组织者，循环赛的轮数为0时使用默认值
+func (tm *TournamentManager) Create(organizerID, name string, format models.TournamentFormat, mode models.GameMode, tableSize, rounds int, locale Locale) (*models.Tournament, error) {
+	if boardMode(mode) != mode {
+		return nil, ErrInvalidMode
+	}
+	if !validLocale(locale) {
+		return nil, ErrInvalidLocale
+	}
+	if tableSize < boardMinPlayers(mode, nil) || tableSize > maxTournamentTableSize {
+		return nil, ErrInvalidTournament
+	}
+	switch format {
+	case models.TournamentRoundRobin:
+		if rounds == 0 {
+			rounds = DefaultTournamentRounds
+		}
+		if rounds < 1 || rounds > maxTournamentRounds {
+			return nil, ErrInvalidTournament
+		}
+	case models.TournamentBracket:
+		// 淘汰赛打到只剩一桌为止
+		rounds = 0
+	default:
+		return nil, ErrInvalidTournament
+	}
+
+	tournament := &models.Tournament{
+		ID:          generateSessionID(),
+		Name:        name,
+		OrganizerID: organizerID,
+		Format:      format,
+		Mode:        mode,
+		TableSize:   tableSize,
+		Rounds:      rounds,
+		Locale:      string(locale),
+		Status:      models.TournamentRegistering,
+		Players:     make([]models.TournamentPlayer, 0),
+		Matches:     make([]models.TournamentMatch, 0),
+		CreatedAt:   time.Now().Unix(),
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if err := tm.store.SaveTournament(tournament); err != nil {
+		return nil, err
+	}
+	tm.tournaments[tournament.ID] = tournament
+	return tournament, nil
+}
+
+// Get 获取赛事，包括所有轮次的分桌和比赛结果
+func (tm *TournamentManager) Get(id string) (*models.Tournament, error) {
+	tournament, err := tm.store.GetTournament(id)
+	if err == storage.ErrNotFound {
+		return nil, ErrTournamentNotFound
+	}
+	return tournament, err
+}
+
+// List 按创建时间倒序列出赛事，status为空时不限制状态
+func (tm *TournamentManager) List(status string) ([]*models.Tournament, error) {
+	return tm.store.ListTournaments(status)
+}
+
+// Standings 获取赛事排名：仍在比赛中的选手在前，其次按淘汰轮次、积分、获胜局数和报名顺序排列
+func (tm *TournamentManager) Standings(id string) ([]models.TournamentStanding, error) {
+	tournament, err := tm.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return tournamentStandings(tournament), nil
+}
+
+// Register 玩家报名赛事，重复报名时直接返回
+func (tm *TournamentManager) Register(id, playerID string) error {
+	name := playerID
+	if user, err := tm.store.GetUserByID(playerID); err == nil {
+		name = user.DisplayName
+		if name == "" {
+			name = user.Username
+		}
+	}
+
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tournament, err := tm.activeLocked(id)
+	if err != nil {
+		return err
+	}
+	if tournament.Status != models.TournamentRegistering {
+		return ErrTournamentClosed
+	}
+	for _, player := range tournament.Players {
+		if player.ID == playerID {
+			return nil
+		}
+	}
+	tournament.Players = append(tournament.Players, models.TournamentPlayer{ID: playerID, Name: name})
+	return tm.store.SaveTournament(tournament)
+}
+
+// Withdraw 玩家在赛事开始前退出报名
+func (tm *TournamentManager) Withdraw(id, playerID string) error {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	tournament, err := tm.activeLocked(id)
+	if err != nil {
+		return err
+	}
+	if tournament.Status != models.TournamentRegistering {
+		return ErrTournamentClosed
+	}
+	for i, player := range tournament.Players {
+		if player.ID == playerID {
+			tournament.Players = append(tournament.Players[:i], tournament.Players[i+1:]...)
+			return tm.store.SaveTournament(tournament)
+		}
+	}
+	return nil
+}
+
+// Start 组织者截止报名并安排第一轮比赛，至少需要两名选手
+func (tm *TournamentManager) Start(id, organizerID string) error {
+	tm.mutex.Lock()
+	tournament, err := tm.activeLocked(id)
+	if err == nil && tournament.OrganizerID != organizerID {
+		err = ErrNotOrganizer
+	} else if err == nil && tournament.Status != models.TournamentRegistering {
+		err = ErrTournamentClosed
+	} else if err == nil && len(tournament.Players) < 2 {
+		err = NewError(CodeNotEnoughPlayers, "报名人数不足")
+	}
+	if err != nil {
+		tm.mutex.Unlock()
+		return err
+	}
+
+	tournament.Status = models.TournamentRunning
+	tournament.StartedAt = time.Now().Unix()
+	notices := tm.scheduleRoundLocked(tournament)
+	err = tm.store.SaveTournament(tournament)
+	tm.mutex.Unlock()
+
+	slog.Info("赛事已开始", "tournament_id", id, "players", len(tournament.Players), "format", tournament.Format)
+	tm.notify(notices)
+	return err
+}
+
+// StartMatch 组织者代房主开始一场比赛，比赛房间创建失败时先重新创建，用于选手迟迟不开始的情况
+func (tm *TournamentManager) StartMatch(id, matchID, organizerID string) error {
+	tm.mutex.Lock()
+	tournament, err := tm.activeLocked(id)
+	if err != nil {
+		tm.mutex.Unlock()
+		return err
+	}
+	if tournament.OrganizerID != organizerID {
+		tm.mutex.Unlock()
+		return ErrNotOrganizer
+	}
+	if tournament.Status != models.TournamentRunning {
+		tm.mutex.Unlock()
+		return ErrTournamentNotRunning
+	}
+	var match *models.TournamentMatch
+	for i := range tournament.Matches {
+		if tournament.Matches[i].ID == matchID {
+			match = &tournament.Matches[i]
+		}
+	}
+	if match == nil || match.Status != models.MatchScheduled {
+		tm.mutex.Unlock()
+		return ErrMatchNotFound
+	}
+
+	var notices []tournamentNotice
+	if match.RoomID == "" {
+		if err := tm.createMatchRoomLocked(tournament, match); err != nil {
+			tm.mutex.Unlock()
+			return err
+		}
+		notices = tm.matchNotices(tournament, *match)
+		if err := tm.store.SaveTournament(tournament); err != nil {
+			slog.Error("保存赛事失败", "tournament_id", id, "error", err)
+		}
+	}
+	roomID := match.RoomID
+	tm.mutex.Unlock()
+	tm.notify(notices)
+
+	// 开始对局需要等待对局goroutine，不能持有赛事的锁：对局结束计分时会反过来获取它
+	game, exists := tm.rooms.GetGameController(roomID)
+	if !exists {
+		return ErrGameNotFound
+	}
+	room, err := tm.rooms.GetRoom(roomID)
+	if err != nil {
+		return err
+	}
+	return game.StartGame(room.HostID)
+}
+
+// ApplyGameRecord 对局结束后为比赛计分，本轮所有比赛都结束时安排下一轮或结束赛事，不是比赛房间的对局直接忽略
+func (tm *TournamentManager) ApplyGameRecord(record *models.GameRecord) {
+	tm.mutex.Lock()
+	var tournament *models.Tournament
+	var match *models.TournamentMatch
+	for _, active := range tm.tournaments {
+		for i := range active.Matches {
+			if active.Matches[i].RoomID == record.RoomID && active.Matches[i].Status == models.MatchScheduled {
+				tournament, match = active, &active.Matches[i]
+			}
+		}
+	}
+	if match == nil {
+		tm.mutex.Unlock()
+		return
+	}
+
+	seated := make(map[string]bool, len(match.PlayerIDs))
+	for _, playerID := range match.PlayerIDs {
+		seated[playerID] = true
+	}
+	match.Status = models.MatchFinished
+	match.GameID = record.ID
+	match.Result = record.Result
+	match.Points = make(map[string]int, len(match.PlayerIDs))
+	for _, player := range record.Players {
+		if !seated[player.ID] {
+			continue
+		}
+		points, won := 0, isWinner(player, record.Result)
+		if won {
+			points += TournamentWinPoints
+		}
+		if player.Alive {
+			points += TournamentSurvivalPoints
+		}
+		match.Points[player.ID] = points
+
+		for i := range tournament.Players {
+			if tournament.Players[i].ID == player.ID {
+				tournament.Players[i].Points += points
+				tournament.Players[i].Games++
+				if won {
+					tournament.Players[i].Wins++
+				}
+			}
+		}
+	}
+	slog.Info("赛事比赛结束", "tournament_id", tournament.ID, "match_id", match.ID, "result", record.Result)
+
+	notices := tm.standingsNotices(tournament)
+	if tm.roundFinishedLocked(tournament) {
+		notices = append(notices, tm.advanceLocked(tournament)...)
+	}
+	if err := tm.store.SaveTournament(tournament); err != nil {
+		slog.Error("保存赛事失败", "tournament_id", tournament.ID, "error", err)
+	}
+	tm.mutex.Unlock()
+	tm.notify(notices)
+}
+
+// activeLocked 获取报名中或进行中的赛事，调用方需持有tm.mutex
+func (tm *TournamentManager) activeLocked(id string) (*models.Tournament, error) {
+	if tournament, exists := tm.tournaments[id]; exists {
+		return tournament, nil
+	}
+	if _, err := tm.store.GetTournament(id); err == nil {
+		return nil, ErrTournamentNotRunning
+	}
+	return nil, ErrTournamentNotFound
+}
+
+// roundFinishedLocked 当前轮次的比赛是否都已结束，调用方需持有tm.mutex
+func (tm *TournamentManager) roundFinishedLocked(tournament *models.Tournament) bool {
+	for _, match := range tournament.Matches {
+		if match.Round == tournament.Round && match.Status != models.MatchFinished {
+			return false
+		}
+	}
+	return true
+}
+
+// advanceLocked 一轮结束后安排下一轮或结束赛事：循环赛打满设定的轮数，
+// 淘汰赛在只剩一桌的决赛后结束，否则按累计成绩保留前一半选手，调用方需持有tm.mutex
+func (tm *TournamentManager) advanceLocked(tournament *models.Tournament) []tournamentNotice {
+	switch tournament.Format {
+	case models.TournamentRoundRobin:
+		if tournament.Round >= tournament.Rounds {
+			return tm.finishLocked(tournament)
+		}
+	case models.TournamentBracket:
+		standings := tournamentStandings(tournament)
+		var remaining []models.TournamentStanding
+		for _, standing := range standings {
+			if standing.EliminatedRound == 0 {
+				remaining = append(remaining, standing)
+			}
+		}
+		if len(remaining) <= tournament.TableSize {
+			return tm.finishLocked(tournament)
+		}
+		advancing := (len(remaining) + 1) / 2
+		for _, standing := range remaining[advancing:] {
+			for i := range tournament.Players {
+				if tournament.Players[i].ID == standing.ID {
+					tournament.Players[i].EliminatedRound = tournament.Round
+				}
+			}
+		}
+	}
+	return tm.scheduleRoundLocked(tournament)
+}
+
+// finishLocked 结束赛事，排名第一的选手为冠军，调用方需持有tm.mutex
+func (tm *TournamentManager) finishLocked(tournament *models.Tournament) []tournamentNotice {
+	standings := tournamentStandings(tournament)
+	tournament.Status = models.TournamentFinished
+	tournament.EndedAt = time.Now().Unix()
+	tournament.ChampionID = standings[0].ID
+	delete(tm.tournaments, tournament.ID)
+	slog.Info("赛事已结束", "tournament_id", tournament.ID, "champion_id", tournament.ChampionID)
+
+	notices := make([]tournamentNotice, 0, len(tournament.Players))
+	for _, player := range tournament.Players {
+		notices = append(notices, tournamentNotice{playerID: player.ID, message: localize(map[string]interface{}{
+			"type":          "tournament_finished",
+			"tournament_id": tournament.ID,
+			"champion_id":   tournament.ChampionID,
+			"standings":     standings,
+		}, Locale(tournament.Locale), "message", "system.tournament_finished", tournament.Name, standings[0].Name)})
+	}
+	return notices
+}
+
+// scheduleRoundLocked 开始下一轮：仍在比赛中的选手随机分桌，各桌人数尽量平均，
+// 每桌创建一个用AI补足人数的房间并让选手入座，调用方需持有tm.mutex
+func (tm *TournamentManager) scheduleRoundLocked(tournament *models.Tournament) []tournamentNotice {
+	tournament.Round++
+
+	var players []string
+	for _, player := range tournament.Players {
+		if player.EliminatedRound == 0 {
+			players = append(players, player.ID)
+		}
+	}
+	tm.rng.Shuffle(len(players), func(i, j int) { players[i], players[j] = players[j], players[i] })
+
+	var notices []tournamentNotice
+	tables := (len(players) + tournament.TableSize - 1) / tournament.TableSize
+	for table := 1; table <= tables; table++ {
+		size := len(players) / (tables - table + 1)
+		match := models.TournamentMatch{
+			ID:        fmt.Sprintf("r%d-t%d", tournament.Round, table),
+			Round:     tournament.Round,
+			Table:     table,
+			PlayerIDs: append([]string(nil), players[:size]...),
+			Status:    models.MatchScheduled,
+		}
+		players = players[size:]
+
+		// 房间创建失败时比赛仍然排入赛程，由组织者开始比赛时重新创建
+		if err := tm.createMatchRoomLocked(tournament, &match); err != nil {
+			slog.Error("创建比赛房间失败", "tournament_id", tournament.ID, "match_id", match.ID, "error", err)
+		} else {
+			notices = append(notices, tm.matchNotices(tournament, match)...)
+		}
+		tournament.Matches = append(tournament.Matches, match)
+	}
+	slog.Info("赛事开始新一轮", "tournament_id", tournament.ID, "round", tournament.Round, "tables", tables)
+	return notices
+}
+
+// createMatchRoomLocked 为比赛创建房间并让选手按分桌顺序入座，第一名选手为房主，调用方需持有tm.mutex
+func (tm *TournamentManager) createMatchRoomLocked(tournament *models.Tournament, match *models.TournamentMatch) error {
+	locale := Locale(tournament.Locale)
+	name := Translate(locale, "tournament.room_name", tournament.Name, match.Round, match.Table)
+	room, err := tm.rooms.CreateRoom(name, tournament.Mode, tournament.TableSize, false, false, "", locale, nil, tournament.TableSize, nil, false, "")
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]string, len(tournament.Players))
+	for _, player := range tournament.Players {
+		names[player.ID] = player.Name
+	}
+	for _, playerID := range match.PlayerIDs {
+		player := models.Player{ID: playerID, Name: names[playerID], Type: models.HumanPlayer}
+		if err := tm.rooms.JoinRoom(room.ID, player); err != nil {
+			slog.Warn("比赛选手入座失败", "room_id", room.ID, "player_id", playerID, "error", err)
+		}
+	}
+	match.RoomID = room.ID
+	return nil
+}
+
+// matchNotices 通知比赛中的选手所在的桌次和房间
+func (tm *TournamentManager) matchNotices(tournament *models.Tournament, match models.TournamentMatch) []tournamentNotice {
+	notices := make([]tournamentNotice, 0, len(match.PlayerIDs))
+	for _, playerID := range match.PlayerIDs {
+		notices = append(notices, tournamentNotice{playerID: playerID, message: localize(map[string]interface{}{
+			"type":          "tournament_match",
+			"tournament_id": tournament.ID,
+			"match":         match,
+		}, Locale(tournament.Locale), "message", "system.tournament_match", tournament.Name, match.Round, match.Table)})
+	}
+	return notices
+}
+
+// standingsNotices 每场比赛结束后向所有选手推送最新排名
+func (tm *TournamentManager) standingsNotices(tournament *models.Tournament) []tournamentNotice {
+	standings := tournamentStandings(tournament)
+	notices := make([]tournamentNotice, 0, len(tournament.Players))
+	for _, player := range tournament.Players {
+		notices = append(notices, tournamentNotice{playerID: player.ID, message: map[string]interface{}{
+			"type":          "tournament_standings",
+			"tournament_id": tournament.ID,
+			"round":         tournament.Round,
+			"standings":     standings,
+		}})
+	}
+	return notices
+}
+
+// notify 向在线的选手发送通知，不在线的选手可以通过接口查询赛程和排名
+func (tm *TournamentManager) notify(notices []tournamentNotice) {
+	for _, notice := range notices {
+		if tm.webSocket.IsOnline(notice.playerID) {
+			tm.webSocket.SendToPlayer(notice.playerID, notice.message)
+		}
+	}
+}
+
+// tournamentStandings 计算赛事排名：仍在比赛中的选手在前，被淘汰的选手按淘汰轮次从晚到早，
+// 同一档内按积分、获胜局数和报名顺序排列
+func tournamentStandings(tournament *models.Tournament) []models.TournamentStanding {
+	standings := make([]models.TournamentStanding, len(tournament.Players))
+	for i, player := range tournament.Players {
+		standings[i] = models.TournamentStanding{TournamentPlayer: player}
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if (a.EliminatedRound == 0) != (b.EliminatedRound == 0) {
+			return a.EliminatedRound == 0
+		}
+		if a.EliminatedRound != b.EliminatedRound {
+			return a.EliminatedRound > b.EliminatedRound
+		}
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		return a.Wins > b.Wins
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
+
+// SetTournaments 设置赛事管理器，比赛房间的对局结束后由它计分
+func (rm *RoomManager) SetTournaments(tm *TournamentManager) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+
+	rm.tournaments = tm
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+
+	"github.com/qianlnk/werewolf/tracing"
+)
+
+// withSpan 在片段中执行对局命令，命令中创建的片段（例如推送游戏状态）都归入该片段，在对局goroutine中执行
+func (gc *GameController) withSpan(ctx context.Context, name string, fn func(span *tracing.Span)) {
+	ctx, span := tracing.Start(ctx, name)
+	span.SetAttr("room_id", gc.game.Room.ID)
+	previous := gc.traceCtx
+	gc.traceCtx = ctx
+	defer func() {
+		gc.traceCtx = previous
+		span.Finish()
+	}()
+
+	fn(span)
+}
+
+// startSpan 以当前命令的片段为父片段创建片段，当前命令没有片段时开始新的调用链，在对局goroutine中执行
+func (gc *GameController) startSpan(name string) *tracing.Span {
+	ctx := gc.traceCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, span := tracing.Start(ctx, name)
+	span.SetAttr("room_id", gc.game.Room.ID)
+	return span
+}
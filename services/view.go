@@ -0,0 +1,130 @@
+package services
+
+import "github.com/qianlnk/werewolf/models"
+
+// playerGameView 投影出指定玩家视角的对局信息，REST状态查询、game_state推送和重连快照共用这一套规则：
+// 其他存活玩家的角色只在已知时返回，情侣身份只有情侣双方知道，已出局玩家的角色和情侣身份公开。
+// playerID为空时生成旁观者视角，
+// 只包含公开信息。调用方需持有锁
+func (gs *GameState) playerGameView(playerID string) (*models.PlayerGameView, error) {
+	var self *models.Player
+	if playerID != "" {
+		for i := range gs.Players {
+			if gs.Players[i].ID == playerID {
+				self = &gs.Players[i]
+				break
+			}
+		}
+		if self == nil {
+			return nil, NewError(CodePlayerNotFound, "玩家不存在")
+		}
+	}
+
+	view := &models.PlayerGameView{
+		PlayerID:     playerID,
+		IsStarted:    gs.IsStarted,
+		Phase:        gs.Phase,
+		NightStep:    gs.NightStep,
+		Round:        gs.Round,
+		Players:      make([]models.Player, len(gs.Players)),
+		AlivePlayers: make([]string, 0),
+		Deaths:       gs.publicDeaths(),
+		TimeLeft:     gs.timeLeft(),
+		PhaseEndsAt:  gs.PhaseEndsAt,
+		Claims:       gs.Claims,
+		Sheriff:      gs.Sheriff,
+		StateVersion: gs.StateVersion,
+	}
+	if gs.IsStarted && (gs.Phase == PhaseCampaign || gs.Phase == PhaseSheriffVote) {
+		view.Candidates = gs.sheriffCandidates()
+	}
+
+	// 隐藏其他玩家的角色和情侣身份，只保留已知信息和已死亡玩家的信息
+	known := gs.KnownRoles[playerID]
+	selfLover := self != nil && self.IsLover
+	for i, player := range gs.Players {
+		if player.ID != playerID && player.Alive {
+			if _, ok := known[player.ID]; !ok {
+				player.Role = ""
+			}
+			if !selfLover {
+				player.IsLover = false
+			}
+		}
+		view.Players[i] = player
+		if player.Alive {
+			view.AlivePlayers = append(view.AlivePlayers, player.ID)
+		}
+	}
+
+	if self == nil {
+		return view, nil
+	}
+	view.Actions = gs.pendingActions(playerID)
+	view.AvailableActions = gs.availableActions(playerID)
+	if gs.IsStarted {
+		view.Role = self.Role
+		view.KnownRoles = known
+		view.Checks = gs.seerChecks(playerID)
+		if isWolf(self.Role) {
+			for _, player := range gs.Players {
+				if player.ID != playerID && isWolf(player.Role) {
+					view.Teammates = append(view.Teammates, player.ID)
+				}
+			}
+		}
+	}
+	return view, nil
+}
+
+// publicPlayers 玩家列表的副本，不含角色和情侣身份，用于房间信息和对局外的广播
+func publicPlayers(players []models.Player) []models.Player {
+	public := make([]models.Player, len(players))
+	for i, player := range players {
+		player.Role = ""
+		player.IsLover = false
+		public[i] = player
+	}
+	return public
+}
+
+// seerChecks 玩家自己的查验记录，按查验顺序排列，调用方需持有锁
+func (gs *GameState) seerChecks(playerID string) []models.SeerCheck {
+	roles := make(map[string]models.Role, len(gs.Players))
+	for _, player := range gs.Players {
+		roles[player.ID] = player.Role
+	}
+
+	var checks []models.SeerCheck
+	for _, event := range gs.Events {
+		if event.Type == "action" && event.Action == "check" && event.PlayerID == playerID {
+			checks = append(checks, models.SeerCheck{Round: event.Round, TargetID: event.TargetID, Role: roles[event.TargetID]})
+		}
+	}
+	return checks
+}
+
+// publicDeaths 已公开的出局记录，按出局顺序排列，夜晚出局的原因不公开，被复活玩家的记录不再保留，调用方需持有锁
+func (gs *GameState) publicDeaths() []models.PlayerDeath {
+	var deaths []models.PlayerDeath
+	for _, event := range gs.Events {
+		switch event.Type {
+		case "death":
+			death := models.PlayerDeath{PlayerID: event.PlayerID, Round: event.Round, Phase: event.Phase}
+			if event.Phase != PhaseNight {
+				death.Cause = event.Cause
+			}
+			deaths = append(deaths, death)
+		case "revive":
+			// 被法官复活的玩家不再显示之前的出局记录
+			kept := deaths[:0]
+			for _, death := range deaths {
+				if death.PlayerID != event.PlayerID {
+					kept = append(kept, death)
+				}
+			}
+			deaths = kept
+		}
+	}
+	return deaths
+}
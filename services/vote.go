@@ -0,0 +1,84 @@
+package services
+
+import "github.com/qianlnk/werewolf/models"
+
+// VoteTally 一次放逐投票的计票结果，投票阶段结束时向房间广播
+type VoteTally struct {
+	Round      int                `json:"round"`
+	Votes      map[string]string  `json:"votes"`           // 投票玩家 -> 被投票玩家
+	Weights    map[string]float64 `json:"weights"`         // 投票玩家的票权
+	Bonus      map[string]float64 `json:"bonus,omitempty"` // 被投票玩家额外获得的票数
+	Totals     map[string]float64 `json:"totals"`          // 被投票玩家的总票数，包含额外票数
+	Eliminated string             `json:"eliminated,omitempty"`
+}
+
+// voteWeight 玩家的票权，未设置时为1。警长、翻牌的白痴等改变票权的角色通过VoteWeights设置
+func (gs *GameState) voteWeight(playerID string) float64 {
+	if weight, exists := gs.VoteWeights[playerID]; exists {
+		return weight
+	}
+	return 1
+}
+
+// tallyVotes 按票权统计本轮投票并加上额外票数，总票数最多的玩家被放逐，平票时取最先得票的玩家，
+// 同一种子下结果可以复现；没有玩家得票时不放逐任何人
+func (gs *GameState) tallyVotes() *VoteTally {
+	tally := &VoteTally{
+		Round:   gs.Round,
+		Votes:   make(map[string]string),
+		Weights: make(map[string]float64),
+		Totals:  make(map[string]float64),
+	}
+
+	var order []string
+	count := func(targetID string, votes float64) {
+		if _, exists := tally.Totals[targetID]; !exists {
+			order = append(order, targetID)
+		}
+		tally.Totals[targetID] += votes
+	}
+	for _, action := range gs.Actions {
+		if action.Type != "vote" || action.TargetID == "" {
+			continue
+		}
+		weight := gs.voteWeight(action.PlayerID)
+		tally.Votes[action.PlayerID] = action.TargetID
+		tally.Weights[action.PlayerID] = weight
+		count(action.TargetID, weight)
+	}
+	if len(gs.VoteBonus) > 0 {
+		tally.Bonus = make(map[string]float64, len(gs.VoteBonus))
+		for _, player := range gs.Players {
+			if bonus, exists := gs.VoteBonus[player.ID]; exists && player.Alive {
+				tally.Bonus[player.ID] = bonus
+				count(player.ID, bonus)
+			}
+		}
+	}
+
+	var maxVotes float64
+	for _, playerID := range order {
+		if tally.Totals[playerID] > maxVotes {
+			maxVotes = tally.Totals[playerID]
+			tally.Eliminated = playerID
+		}
+	}
+	return tally
+}
+
+// broadcastVoteTally 向房间广播上一轮投票的计票结果，在对局goroutine中执行
+func (gc *GameController) broadcastVoteTally(tally *VoteTally) {
+	gc.sink.BroadcastToRoom(gc.game.Room.ID, map[string]interface{}{
+		"type":  "vote_result",
+		"tally": tally,
+	})
+}
+
+// eliminate 放逐计票结果中的玩家，出局的猎人可以开枪
+func (gs *GameState) eliminate(tally *VoteTally) {
+	if tally.Eliminated == "" {
+		return
+	}
+	gs.kill(tally.Eliminated, models.DeathVote)
+	gs.triggerHunter(tally.Eliminated)
+}
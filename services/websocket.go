@@ -1,54 +1,156 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
+	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/qianlnk/werewolf/models"
+	"github.com/qianlnk/werewolf/reporting"
+	"github.com/qianlnk/werewolf/tracing"
 )
 
+// errInternal 处理消息时发生panic，回复客户端的错误，连接保持可用
+var errInternal = NewError(CodeInternal, "服务器内部错误")
+
 // WebSocketManager WebSocket连接管理器
+// 连接按玩家ID、房间数据按房间ID分片加锁，一个房间的大量聊天和广播不会阻塞其他房间，见ws_shard.go
 type WebSocketManager struct {
-	connections   map[string]*websocket.Conn // playerID -> connection
-	connectionIDs map[string]string          // playerID -> connectionID
-	rooms         map[string][]string        // roomID -> []playerID
-	mutex         sync.RWMutex
-	roomManager   *RoomManager
+	playerShards [wsShardCount]*playerShard // 按玩家ID分片的连接和断线状态
+	roomShards   [wsShardCount]*roomShard   // 按房间ID分片的成员、广播事件和聊天记录
+	sendQueue    SendQueueConfig            // 新连接的发送缓冲区配置
+	shuttingDown bool                       // 服务器正在关闭，不再接受新连接
+	// maxConnections 连接数上限，0表示不限制，见SetMaxConnections
+	maxConnections  int
+	connectionCount atomic.Int64 // 当前的连接数
+	mutex           sync.RWMutex // 保护sendQueue、shuttingDown和maxConnections，可以在持有分片锁时获取
+	roomManager     *RoomManager
 }
 
 // NewWebSocketManager 创建WebSocket管理器实例
 func NewWebSocketManager(rm *RoomManager) *WebSocketManager {
-	return &WebSocketManager{
-		connections:   make(map[string]*websocket.Conn),
-		connectionIDs: make(map[string]string),
-		rooms:         make(map[string][]string),
-		roomManager:   rm,
+	wm := &WebSocketManager{
+		sendQueue:   SendQueueConfig{Size: clientSendBuffer, Policy: OverflowDisconnect},
+		roomManager: rm,
+	}
+	for i := range wm.playerShards {
+		wm.playerShards[i] = newPlayerShard()
+		wm.roomShards[i] = newRoomShard()
 	}
+	return wm
 }
 
-// RegisterConnection 注册新的WebSocket连接
-func (wm *WebSocketManager) RegisterConnection(playerID string, conn *websocket.Conn, connectionID string) {
+// SetSendQueue 设置连接的发送缓冲区大小和溢出策略，只影响之后建立的连接，size不大于0时使用默认大小
+func (wm *WebSocketManager) SetSendQueue(size int, policy OverflowPolicy) {
 	wm.mutex.Lock()
 	defer wm.mutex.Unlock()
 
-	// 检查并清理该玩家的所有旧连接
-	if oldConn, exists := wm.connections[playerID]; exists {
-		// 直接关闭旧连接
-		oldConn.Close()
-		delete(wm.connections, playerID)
-		delete(wm.connectionIDs, playerID)
+	if size <= 0 {
+		size = clientSendBuffer
+	}
+	wm.sendQueue = SendQueueConfig{Size: size, Policy: policy}
+}
+
+// RegisterConnection 注册新的WebSocket连接
+// protocol为协商后的协议版本，见ParseProtocolVersion；encoding为消息编码，见ParseEncoding；
+// profile为客户端平台的连接参数，见ClientProfile
+func (wm *WebSocketManager) RegisterConnection(playerID string, conn *websocket.Conn, connectionID string, protocol int, encoding string, readOnly bool, profile ClientProfile) {
+	shard := wm.playerShard(playerID)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	wm.mutex.RLock()
+	shuttingDown, sendQueue, maxConnections := wm.shuttingDown, wm.sendQueue, wm.maxConnections
+	wm.mutex.RUnlock()
+
+	// 服务器正在关闭时直接拒绝，客户端收到关闭码后稍后重连
+	if shuttingDown {
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "服务器正在重启")
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		conn.Close()
+		return
+	}
+
+	// 连接数已达上限时拒绝新连接，同一连接ID的重连替换旧连接，不增加连接数
+	if _, replacing := shard.connections[playerID][connectionID]; !replacing && maxConnections > 0 && wm.ConnectionCount() >= maxConnections {
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, ErrTooManyConnections.Message)
+		conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		conn.Close()
+		return
+	}
+
+	// 在重连窗口期内重新连接的玩家需要同步游戏快照，窗口期和是否静默重连取决于断开的连接
+	shard.stopCleanupLocked(playerID)
+	silent := false
+	if disconnectedAt, exists := shard.disconnected[playerID]; exists {
+		previous := shard.reconnectProfileLocked(playerID)
+		if time.Since(disconnectedAt) <= previous.ReconnectWindow {
+			shard.pendingResync[playerID] = true
+			silent = previous.SilentReconnect
+		}
+		delete(shard.disconnected, playerID)
+		delete(shard.reconnectProfiles, playerID)
 	}
 
-	// 保存新连接和连接ID
-	wm.connections[playerID] = conn
-	wm.connectionIDs[playerID] = connectionID
+	// 玩家可以在多个设备或标签页上同时连接，已有连接的玩家新开的连接同样需要同步快照
+	clients := shard.connections[playerID]
+	if clients == nil {
+		clients = make(map[string]*client)
+		shard.connections[playerID] = clients
+	}
+	if len(clients) > 0 {
+		shard.pendingResync[playerID] = true
+	}
+
+	// 同一连接ID（如页面刷新）的旧连接尚未断开时直接替换，旧连接的写协程退出时不会影响新连接
+	if old, exists := clients[connectionID]; exists {
+		old.close("连接已被替换")
+		delete(clients, connectionID)
+		wm.connectionCount.Add(-1)
+	}
+
+	// 超出连接数上限时关闭最早建立的连接
+	if len(clients) >= maxConnectionsPerPlayer {
+		var oldest *client
+		for _, other := range clients {
+			if oldest == nil || other.connectedAt.Before(oldest.connectedAt) {
+				oldest = other
+			}
+		}
+		oldest.close("连接数超过上限")
+		delete(clients, oldest.connectionID)
+		wm.connectionCount.Add(-1)
+	}
+
+	// 保存新连接，写协程负责该连接的所有写操作
+	c := newClient(playerID, connectionID, protocol, encoding, readOnly, profile, conn, sendQueue, wm.removeClient)
+	clients[connectionID] = c
+	wm.connectionCount.Add(1)
+
+	// 告知新版本客户端协商结果，旧版本客户端不认识该消息
+	if protocol >= ProtocolV2 {
+		hello, _ := json.Marshal(HelloMessage{
+			Type:           "hello",
+			Protocol:       protocol,
+			ServerProtocol: CurrentProtocolVersion,
+			ConnectionID:   connectionID,
+		})
+		c.enqueue(hello)
+	}
 
 	// 启动消息处理协程
-	go wm.handleMessages(playerID, conn)
+	go wm.handleMessages(c)
+
+	// 通知所在房间该玩家已上线，首次加入房间的玩家会随room_update一起通知；静默重连时断线也没有通知过
+	if !silent {
+		reporting.Go("ws.presence", func() { wm.broadcastPresence(playerID, PresenceOnline) })
+	}
 }
 
 // Message WebSocket消息结构
@@ -60,268 +162,512 @@ type Message struct {
 
 // JoinRoom 将玩家加入房间的WebSocket广播组
 func (wm *WebSocketManager) JoinRoom(roomID, playerID string) {
-	wm.mutex.Lock()
-	defer wm.mutex.Unlock()
-
-	// 检查房间是否存在
-	if _, exists := wm.rooms[roomID]; !exists {
-		wm.rooms[roomID] = make([]string, 0)
+	// 重连的玩家需要同步完整的游戏快照和最近的聊天记录
+	shard := wm.playerShard(playerID)
+	shard.mutex.Lock()
+	resync := shard.pendingResync[playerID]
+	delete(shard.pendingResync, playerID)
+	shard.mutex.Unlock()
+	if resync {
+		reporting.Go("ws.resync", func() {
+			wm.sendResyncSnapshot(roomID, playerID)
+			wm.sendChatBackfill(roomID, playerID)
+		})
 	}
 
-	// 检查玩家是否已在房间中
-	for _, pid := range wm.rooms[roomID] {
-		if pid == playerID {
-			// 玩家已在房间中，直接返回
-			return
-		}
+	// 玩家已在房间中时直接返回
+	if !wm.addMember(roomID, playerID) {
+		return
 	}
 
-	// 玩家不在房间中，添加到房间
-	wm.rooms[roomID] = append(wm.rooms[roomID], playerID)
+	// 中途加入的玩家补发最近的聊天记录
+	if !resync {
+		reporting.Go("ws.chat_backfill", func() { wm.sendChatBackfill(roomID, playerID) })
+	}
 
 	// 广播房间成员更新消息
-	go func() {
-		room, err := wm.roomManager.GetRoom(roomID)
+	reporting.Go("ws.room_update", func() {
+		room, err := wm.roomManager.RoomInfo(roomID)
 		if err == nil {
 			wm.BroadcastToRoom(roomID, map[string]interface{}{
-				"type":    "room_update",
-				"players": room.Players,
+				"type":     "room_update",
+				"players":  room.Players,
+				"presence": wm.RoomPresence(roomID),
 			})
 		}
-	}()
+	})
+}
+
+// ResumeRoom 与JoinRoom相同，但重连的玩家已在房间中时补发since之后的房间事件代替完整快照，
+// 客户端从断开前收到的最后一个序号续传；事件记录已不完整时仍发送快照
+func (wm *WebSocketManager) ResumeRoom(roomID, playerID string, since int64) {
+	if !wm.isPlayerInRoom(roomID, playerID) {
+		wm.JoinRoom(roomID, playerID)
+		return
+	}
+
+	shard := wm.playerShard(playerID)
+	shard.mutex.Lock()
+	resync := shard.pendingResync[playerID]
+	delete(shard.pendingResync, playerID)
+	shard.mutex.Unlock()
+	if !resync {
+		return
+	}
+
+	reporting.Go("ws.resume", func() {
+		resp := wm.replayResponse(roomID, playerID, &since)
+		if !resp.Complete {
+			wm.sendResyncSnapshot(roomID, playerID)
+			wm.sendChatBackfill(roomID, playerID)
+			return
+		}
+		for _, c := range wm.playerClients(playerID) {
+			if err := wm.sendDirect(c, resp); err != nil {
+				c.logger.Info("补发房间事件失败", "room_id", roomID, "error", err)
+			}
+		}
+	})
 }
 
 // BroadcastToRoom 向房间内所有玩家广播消息
 func (wm *WebSocketManager) BroadcastToRoom(roomID string, message interface{}) {
-	log.Printf("[WebSocket广播] 开始向房间 %s 广播消息, %v", roomID, message)
+	wm.broadcast(roomID, nil, message, nil)
+}
+
+// BroadcastToAudience 向房间内符合条件的连接广播消息，例如只发给存活玩家或狼人
+func (wm *WebSocketManager) BroadcastToAudience(roomID string, audience Audience, message interface{}) {
+	wm.broadcast(roomID, audience, message, nil)
+}
+
+// BroadcastStateToAudience 向房间内符合条件的连接广播游戏状态，协议版本3及以上的连接收到增量，
+// 房间事件中记录完整状态，补发时客户端收到同一序号的完整状态
+func (wm *WebSocketManager) BroadcastStateToAudience(roomID string, audience Audience, full, patch interface{}) {
+	wm.broadcast(roomID, audience, full, patch)
+}
 
+// broadcast 向房间内的连接广播消息，audience为nil时发给所有连接，patch不为nil时发给支持增量的连接
+func (wm *WebSocketManager) broadcast(roomID string, audience Audience, message, patch interface{}) {
 	// 序列化消息
 	msgBytes, err := json.Marshal(message)
 	if err != nil {
-		log.Printf("[WebSocket广播] 消息序列化失败: %v", err)
+		slog.Error("广播消息序列化失败", "room_id", roomID, "error", err)
 		return
 	}
+	var patchBytes []byte
+	if patch != nil {
+		if patchBytes, err = json.Marshal(patch); err != nil {
+			slog.Error("广播消息序列化失败", "room_id", roomID, "error", err)
+			return
+		}
+	}
 
-	// 获取房间内的所有玩家ID
-	wm.mutex.RLock()
-	playerIDs, exists := wm.rooms[roomID]
-	if !exists {
-		wm.mutex.RUnlock()
-		log.Printf("[WebSocket广播] 房间 %s 不存在", roomID)
-		return
+	// 筛选广播对象需要玩家的座位信息，在持有锁之前获取
+	var seats map[string]*models.Player
+	if audience != nil {
+		seats = wm.roomSeats(roomID)
 	}
 
-	// 获取玩家的连接
-	connections := make([]*websocket.Conn, 0)
-	for _, playerID := range playerIDs {
-		if conn, ok := wm.connections[playerID]; ok {
-			connections = append(connections, conn)
+	// 获取房间内的所有玩家ID，分配序号和放入发送缓冲区都在房间分片的锁内完成，保证各连接按序号顺序收到消息
+	shard := wm.roomShard(roomID)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	// 为消息分配房间内的序号，客户端发现序号不连续时可以请求补发，
+	// 房间内暂时没有WebSocket连接时也要记录，供轮询的客户端获取
+	eventLog := shard.eventLogLocked(roomID)
+	seqBytes := eventLog.append(audience, msgBytes)
+	plain, sequenced := newPayload(msgBytes), newPayload(seqBytes)
+	var patched *payload
+	if patchBytes != nil {
+		patched = newPayload(withSeq(patchBytes, eventLog.seq))
+	}
+
+	// 获取玩家的所有连接
+	clients := make([]*client, 0)
+	for _, playerID := range shard.members[roomID] {
+		if audience != nil && !audience(playerID, seats[playerID]) {
+			continue
 		}
+		clients = append(clients, wm.playerClients(playerID)...)
 	}
-	wm.mutex.RUnlock()
 
-	log.Printf("[WebSocket广播] 房间 %s 中有 %d 个活跃连接", roomID, len(connections))
+	slog.Debug("广播消息", "room_id", roomID, "seq", eventLog.seq, "connections", len(clients))
 
-	// 向每个连接发送消息
-	for _, conn := range connections {
-		if err := conn.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
-			log.Printf("[WebSocket广播] 向连接发送消息失败: %v", err)
-			continue
+	// 放入每个连接的发送缓冲区，由各自的写协程发送
+	// 同一种消息的编码转换在所有连接间复用
+	for _, c := range clients {
+		data := sequenced
+		if c.protocol < ProtocolV2 {
+			data = plain
+		} else if c.protocol >= ProtocolV3 && patched != nil {
+			data = patched
+		}
+		if err := c.enqueuePayload(data); err != nil {
+			c.logger.Info("广播消息发送失败", "room_id", roomID, "error", err)
 		}
 	}
+}
 
-	log.Printf("[WebSocket广播] 消息广播完成")
+// roomSeats 获取房间内玩家的座位信息，游戏开始后以对局状态为准
+func (wm *WebSocketManager) roomSeats(roomID string) map[string]*models.Player {
+	var players []models.Player
+	if game, exists := wm.roomManager.GetGameController(roomID); exists {
+		players = game.game.PlayersSnapshot()
+	} else if room, err := wm.roomManager.GetRoom(roomID); err == nil {
+		players = room.Players
+	}
+
+	seats := make(map[string]*models.Player, len(players))
+	for i := range players {
+		seats[players[i].ID] = &players[i]
+	}
+	return seats
 }
 
-// SendToPlayer 向指定玩家发送消息
+// SendToPlayer 向指定玩家发送私有消息
+// 玩家在房间中时消息同时记入房间事件，只有该玩家能补发或轮询到
 func (wm *WebSocketManager) SendToPlayer(playerID string, message interface{}) error {
-	wm.mutex.RLock()
-	defer wm.mutex.RUnlock()
+	return wm.sendToPlayer(playerID, message, nil)
+}
 
-	conn, exists := wm.connections[playerID]
-	if !exists {
-		return errors.New("玩家未连接")
-	}
+// SendStateToPlayer 向玩家推送游戏状态，协议版本3及以上的连接收到增量，房间事件中记录完整状态
+func (wm *WebSocketManager) SendStateToPlayer(playerID string, full, patch interface{}) error {
+	return wm.sendToPlayer(playerID, full, patch)
+}
 
-	msg := Message{
+// sendToPlayer 向玩家发送私有消息，patch不为nil时发给支持增量的连接
+func (wm *WebSocketManager) sendToPlayer(playerID string, message, patch interface{}) error {
+	msgBytes, err := json.Marshal(Message{
 		Type:    "private",
 		Content: message,
+	})
+	if err != nil {
+		return err
+	}
+	var patchBytes []byte
+	if patch != nil {
+		if patchBytes, err = json.Marshal(Message{Type: "private", Content: patch}); err != nil {
+			return err
+		}
 	}
 
-	// 使用重试机制发送消息
-	maxRetries := 3
-	for i := 0; i < maxRetries; i++ {
-		if err := wm.checkConnection(conn); err != nil {
-			if i == maxRetries-1 {
-				go wm.RemoveConnection(playerID)
-				return errors.New("连接已断开")
-			}
-			time.Sleep(time.Second * time.Duration(i+1))
-			continue
+	// 记入房间事件和放入发送缓冲区都在房间分片的锁内完成，与房间广播保持序号顺序
+	data, patchData := msgBytes, patchBytes
+	if roomID := wm.PlayerRoom(playerID); roomID != "" {
+		shard := wm.roomShard(roomID)
+		shard.mutex.Lock()
+		defer shard.mutex.Unlock()
+		eventLog := shard.eventLogLocked(roomID)
+		data = eventLog.append(AudiencePlayers(playerID), msgBytes)
+		if patchBytes != nil {
+			patchData = withSeq(patchBytes, eventLog.seq)
 		}
+	}
 
-		// 设置写入超时
-		conn.SetWriteDeadline(time.Now().Add(time.Second * 5))
-		err := conn.WriteJSON(msg)
-		conn.SetWriteDeadline(time.Time{})
+	// 发给玩家的所有连接，任一连接发送成功即视为成功
+	clients := wm.playerClients(playerID)
+	if len(clients) == 0 {
+		return ErrPlayerNotConnect
+	}
 
-		if err == nil {
-			return nil // 发送成功
-		}
+	plain, sequenced := newPayload(msgBytes), newPayload(data)
+	var patched *payload
+	if patchData != nil {
+		patched = newPayload(patchData)
+	}
 
-		if i == maxRetries-1 {
-			go wm.RemoveConnection(playerID)
-			return err
+	var sendErr error
+	sent := false
+	for _, c := range clients {
+		payload := sequenced
+		if c.protocol < ProtocolV2 {
+			payload = plain
+		} else if c.protocol >= ProtocolV3 && patched != nil {
+			payload = patched
 		}
-
-		log.Printf("发送消息到玩家 %s 失败 (尝试 %d/%d): %v", playerID, i+1, maxRetries, err)
-		time.Sleep(time.Second * time.Duration(i+1))
+		if err := c.enqueuePayload(payload); err != nil {
+			sendErr = err
+			continue
+		}
+		sent = true
 	}
+	if !sent {
+		return sendErr
+	}
+	return nil
+}
 
-	return errors.New("发送消息失败，已达到最大重试次数")
+// sendDirect 直接向指定连接发送消息，不记入房间事件，用于心跳、错误和补发等连接层面的响应
+func (wm *WebSocketManager) sendDirect(c *client, message interface{}) error {
+	msgBytes, err := json.Marshal(Message{
+		Type:    "private",
+		Content: message,
+	})
+	if err != nil {
+		return err
+	}
+	return c.enqueue(msgBytes)
 }
 
-// startPingHandler 启动心跳检测
-func (wm *WebSocketManager) startPingHandler(playerID string, conn *websocket.Conn) {
-	ticker := time.NewTicker(time.Second * 15) // 减少心跳间隔以更快检测连接问题
-	defer ticker.Stop()
+// playerClients 获取玩家当前的所有连接
+func (wm *WebSocketManager) playerClients(playerID string) []*client {
+	shard := wm.playerShard(playerID)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
 
-	maxFailures := 3
-	failures := 0
-	backoffLimit := 30 * time.Second
+	return shard.clientsLocked(playerID)
+}
 
-	for range ticker.C {
-		// 先检查连接状态
-		if conn == nil || (conn != nil && conn.WriteMessage == nil) {
-			log.Printf("玩家 %s 的连接已失效", playerID)
-			wm.RemoveConnection(playerID)
-			return
-		}
+// 添加延迟清理的时间常量
+const playerCleanupDelay = 30 * time.Second
 
-		// 设置写入超时
-		if err := conn.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
-			log.Printf("设置写入超时失败: %v", err)
-			wm.RemoveConnection(playerID)
-			return
-		}
+// RemoveConnection 移除玩家的所有WebSocket连接
+func (wm *WebSocketManager) RemoveConnection(playerID string) {
+	for _, c := range wm.playerClients(playerID) {
+		// 写协程会先发送关闭帧再关闭底层连接
+		c.close("连接关闭")
+		wm.removeClient(c)
+	}
+}
 
-		// 发送ping消息
-		if err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(time.Second)); err != nil {
-			failures++
-			log.Printf("心跳检测失败 (%d/%d): %v", failures, maxFailures, err)
+// shutdownReconnectAfter 服务器关闭时建议客户端等待多少秒后重连
+const shutdownReconnectAfter = 5
 
-			if failures >= maxFailures {
-				log.Printf("玩家 %s 的连接已断开（心跳检测失败达到上限）", playerID)
-				wm.RemoveConnection(playerID)
-				return
-			}
+// ShutdownMessage 服务器关闭前发送给所有连接的通知
+type ShutdownMessage struct {
+	Type           string `json:"type"` // 固定为server_shutdown
+	Message        string `json:"message"`
+	ReconnectAfter int    `json:"reconnect_after"` // 建议客户端等待的秒数
+}
 
-			// 使用指数退避算法进行重试
-			backoff := time.Duration(1<<uint(failures-1)) * time.Second
-			if backoff > backoffLimit {
-				backoff = backoffLimit
+// Shutdown 停止接受新连接，通知所有连接服务器即将关闭，
+// 等待各连接写完缓冲区中的消息并发送关闭帧，ctx结束时不再等待
+func (wm *WebSocketManager) Shutdown(ctx context.Context) error {
+	wm.mutex.Lock()
+	wm.shuttingDown = true
+	wm.mutex.Unlock()
+
+	// 重连窗口期不再处理座位，玩家在服务器重启后通过快照恢复座位
+	clients := make([]*client, 0)
+	for _, shard := range wm.playerShards {
+		shard.mutex.Lock()
+		for playerID := range shard.cleanups {
+			shard.stopCleanupLocked(playerID)
+		}
+		for _, playerClients := range shard.connections {
+			for _, c := range playerClients {
+				clients = append(clients, c)
 			}
-			time.Sleep(backoff)
-			continue
 		}
-
-		// 重置失败计数和写入超时
-		failures = 0
-		conn.SetWriteDeadline(time.Time{})
+		shard.mutex.Unlock()
 	}
-}
 
-// checkConnection 检查连接状态
-func (wm *WebSocketManager) checkConnection(conn *websocket.Conn) error {
-	if conn == nil {
-		return errors.New("连接为空")
+	slog.Info("服务器关闭，正在断开WebSocket连接", "connections", len(clients))
+
+	notice := ShutdownMessage{
+		Type:           "server_shutdown",
+		Message:        "服务器正在重启，请稍后重新连接",
+		ReconnectAfter: shutdownReconnectAfter,
+	}
+	noticeBytes, _ := json.Marshal(Message{Type: "private", Content: notice})
+	noticePayload := newPayload(noticeBytes)
+	for _, c := range clients {
+		if err := c.enqueuePayload(noticePayload); err != nil {
+			c.logger.Info("发送关闭通知失败", "error", err)
+		}
+		c.closeWithCode(websocket.CloseServiceRestart, "服务器正在重启")
 	}
 
-	// 发送ping消息检查连接状态
-	if err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(time.Second)); err != nil {
-		return err
+	for _, c := range clients {
+		select {
+		case <-c.closed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 	return nil
 }
 
-// 添加延迟清理的时间常量
-const playerCleanupDelay = 30 * time.Second
+// removeClient 清理已关闭的连接，连接已被同一玩家的新连接替换时不做处理
+func (wm *WebSocketManager) removeClient(c *client) {
+	shard := wm.playerShard(c.playerID)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
 
-// RemoveConnection 移除WebSocket连接
-func (wm *WebSocketManager) RemoveConnection(playerID string) {
-	wm.mutex.Lock()
-	defer wm.mutex.Unlock()
+	clients := shard.connections[c.playerID]
+	if clients[c.connectionID] != c {
+		return
+	}
+
+	// 从连接映射中删除，玩家仍有其他连接时不视为断线
+	delete(clients, c.connectionID)
+	wm.connectionCount.Add(-1)
+	if len(clients) > 0 {
+		c.logger.Info("已清理连接", "remaining", len(clients))
+		return
+	}
+	delete(shard.connections, c.playerID)
+
+	// 服务器关闭时断开的连接不视为断线，玩家在服务器重启后通过快照恢复座位
+	wm.mutex.RLock()
+	shuttingDown := wm.shuttingDown
+	wm.mutex.RUnlock()
+	if shuttingDown {
+		return
+	}
+	shard.disconnected[c.playerID] = time.Now()
+	shard.reconnectProfiles[c.playerID] = c.profile
 
-	// 获取连接
-	conn, exists := wm.connections[playerID]
+	// 设置一个重连窗口期，避免页面刷新时立即清理房间和玩家信息
+	wm.startReconnectWindowLocked(shard, c.playerID, c.profile.ReconnectWindow)
+	if !c.profile.SilentReconnect {
+		reporting.Go("ws.presence", func() { wm.broadcastPresence(c.playerID, PresenceReconnecting) })
+	}
+	reporting.Go("ws.disconnect", func() { wm.notifyDisconnect(c.playerID) })
+
+	c.logger.Info("玩家已断线，等待重连窗口期")
+}
+
+// sendResyncSnapshot 向重连的玩家发送其视角的完整游戏快照
+func (wm *WebSocketManager) sendResyncSnapshot(roomID, playerID string) {
+	game, exists := wm.roomManager.GetGameController(roomID)
 	if !exists {
 		return
 	}
 
-	// 先检查连接状态
-	if err := wm.checkConnection(conn); err == nil {
-		// 连接仍然有效，尝试发送关闭消息
-		closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "连接关闭")
-		_ = conn.SetWriteDeadline(time.Now().Add(100 * time.Millisecond))
-		err := conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(100*time.Millisecond))
-		if err != nil && !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-			log.Printf("发送关闭消息失败: %v", err)
+	snapshot, err := game.BuildSnapshot(playerID)
+	if err != nil {
+		slog.Error("构建重连快照失败", "room_id", roomID, "player_id", playerID, "error", err)
+		return
+	}
+
+	for _, c := range wm.playerClients(playerID) {
+		if err := wm.sendDirect(c, snapshot); err != nil {
+			c.logger.Info("发送重连快照失败", "room_id", roomID, "error", err)
+			return
 		}
 	}
+	slog.Info("已向重连玩家发送游戏快照", "room_id", roomID, "player_id", playerID)
+}
 
-	// 从连接映射中删除
-	delete(wm.connections, playerID)
-	delete(wm.connectionIDs, playerID)
+// notifyDisconnect 通知玩家所在房间的对局有玩家断线，对局在宽限期后代为完成其动作
+func (wm *WebSocketManager) notifyDisconnect(playerID string) {
+	roomID := wm.PlayerRoom(playerID)
+	if roomID == "" || wm.roomManager == nil {
+		return
+	}
+	if game, exists := wm.roomManager.GetGameController(roomID); exists {
+		game.CheckAbsentPlayers()
+	}
+}
 
-	// 确保连接被关闭
-	conn.Close()
+// startReconnectWindowLocked 开始玩家的重连窗口期，窗口期结束时仍未重连才处理座位；
+// 玩家重连或服务器关闭时计时器被取消，不会留下等待中的goroutine。调用方需持有分片的写锁
+func (wm *WebSocketManager) startReconnectWindowLocked(shard *playerShard, playerID string, window time.Duration) {
+	shard.stopCleanupLocked(playerID)
 
-	// 设置一个重连窗口期，避免页面刷新时立即清理房间和玩家信息
-	go func() {
-		// 等待30秒，给玩家重连的机会
-		time.Sleep(30 * time.Second)
+	// 窗口期很短时回调可能在赋值之前开始执行，回调在取得分片锁之后才读取timer
+	var timer *time.Timer
+	timer = time.AfterFunc(window, func() {
+		wm.expireReconnectWindow(playerID, &timer)
+	})
+	shard.cleanups[playerID] = timer
+}
 
-		wm.mutex.Lock()
-		defer wm.mutex.Unlock()
+// expireReconnectWindow 重连窗口期结束后处理未重连玩家的座位，计时器已被取消或替换时不做处理
+func (wm *WebSocketManager) expireReconnectWindow(playerID string, timer **time.Timer) {
+	defer reporting.Recover("ws.reconnect_window", "player_id", playerID)
+	shard := wm.playerShard(playerID)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
 
-		// 检查玩家是否已经重新连接
-		if _, reconnected := wm.connections[playerID]; reconnected {
-			return
+	if shard.cleanups[playerID] != *timer {
+		return
+	}
+	delete(shard.cleanups, playerID)
+
+	// 检查玩家是否已经重新连接
+	if len(shard.connections[playerID]) > 0 {
+		return
+	}
+	delete(shard.disconnected, playerID)
+	delete(shard.reconnectProfiles, playerID)
+
+	// 如果玩家没有重连，则处理其所在房间的座位
+	for _, roomID := range shard.rooms[playerID] {
+		roomID := roomID
+		reporting.Go("ws.abandoned_seat", func() {
+			wm.BroadcastToRoom(roomID, PresenceMessage{Type: "presence", PlayerID: playerID, Status: PresenceOffline})
+			wm.handleAbandonedSeat(roomID, playerID)
+		})
+	}
+
+	slog.Info("玩家未在重连窗口期内重连，已清理房间资源", "player_id", playerID)
+}
+
+// ExpectReconnect 将恢复对局中的玩家标记为断线，等待其在重连窗口期内重连
+func (wm *WebSocketManager) ExpectReconnect(roomID string, playerIDs []string) {
+	now := time.Now()
+	for _, playerID := range playerIDs {
+		shard := wm.playerShard(playerID)
+		shard.mutex.Lock()
+		connected := len(shard.connections[playerID]) > 0
+		if !connected {
+			shard.disconnected[playerID] = now
+			wm.startReconnectWindowLocked(shard, playerID, playerCleanupDelay)
 		}
+		shard.mutex.Unlock()
 
-		// 如果玩家没有重连，则清理房间信息
-		for roomID, players := range wm.rooms {
-			for i, pid := range players {
-				if pid == playerID {
-					// 广播玩家离开消息
-					go wm.broadcastPlayerLeft(roomID, playerID)
-					// 从房间中移除玩家
-					wm.rooms[roomID] = append(players[:i], players[i+1:]...)
-					break
-				}
-			}
+		if !connected {
+			wm.addMember(roomID, playerID)
+		}
+	}
+}
 
-			// 如果房间为空，清理房间
-			if len(wm.rooms[roomID]) == 0 {
-				delete(wm.rooms, roomID)
-			}
+// handleAbandonedSeat 处理未重连玩家的座位：游戏进行中由AI接管，否则移出房间
+func (wm *WebSocketManager) handleAbandonedSeat(roomID, playerID string) {
+	if game, exists := wm.roomManager.GetGameController(roomID); exists && game.TakeOverPlayer(playerID) {
+		return
+	}
+
+	shard := wm.roomShard(roomID)
+	shard.mutex.Lock()
+	players := shard.members[roomID]
+	for i, pid := range players {
+		if pid == playerID {
+			// 从房间中移除玩家
+			shard.members[roomID] = append(players[:i], players[i+1:]...)
+			break
 		}
+	}
 
-		log.Printf("玩家 %s 未在重连窗口期内重连，已清理房间资源", playerID)
-	}()
+	// 如果房间为空，清理房间
+	if len(shard.members[roomID]) == 0 {
+		delete(shard.members, roomID)
+		delete(shard.eventLogs, roomID)
+		delete(shard.chatHistories, roomID)
+	}
+	shard.mutex.Unlock()
 
-	log.Printf("已清理玩家 %s 的连接资源，等待重连窗口期", playerID)
+	playerShard := wm.playerShard(playerID)
+	playerShard.mutex.Lock()
+	playerShard.removeRoomLocked(playerID, roomID)
+	playerShard.mutex.Unlock()
+
+	// 广播玩家离开消息
+	wm.broadcastPlayerLeft(roomID, playerID)
 }
 
 // broadcastPlayerLeft 广播玩家离开消息
 func (wm *WebSocketManager) broadcastPlayerLeft(roomID, playerID string) {
-	room, err := wm.roomManager.GetRoom(roomID)
+	room, err := wm.roomManager.RoomInfo(roomID)
 	if err != nil {
-		log.Printf("获取房间信息失败: %v", err)
+		slog.Warn("获取房间信息失败", "room_id", roomID, "error", err)
 		return
 	}
 
-	// 更新房间玩家列表
+	// 更新房间玩家列表，RoomInfo返回的是副本
 	for i, player := range room.Players {
 		if player.ID == playerID {
 			room.Players = append(room.Players[:i], room.Players[i+1:]...)
@@ -337,173 +683,260 @@ func (wm *WebSocketManager) broadcastPlayerLeft(roomID, playerID string) {
 	})
 }
 
-// isPlayerInRoom 检查玩家是否在指定房间中
-func (wm *WebSocketManager) isPlayerInRoom(roomID, playerID string) bool {
-	wm.mutex.RLock()
-	defer wm.mutex.RUnlock()
+// IsOnline 检查玩家当前是否有活跃的连接
+func (wm *WebSocketManager) IsOnline(playerID string) bool {
+	shard := wm.playerShard(playerID)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
 
-	players, exists := wm.rooms[roomID]
-	if !exists {
-		return false
-	}
+	return len(shard.connections[playerID]) > 0
+}
 
-	for _, pid := range players {
-		if pid == playerID {
-			return true
-		}
+// PlayerRoom 获取玩家当前所在的房间，在多个房间中时返回最早加入的房间，不在任何房间时返回空
+func (wm *WebSocketManager) PlayerRoom(playerID string) string {
+	shard := wm.playerShard(playerID)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	if rooms := shard.rooms[playerID]; len(rooms) > 0 {
+		return rooms[0]
 	}
-	return false
+	return ""
+}
+
+// isPlayerInRoom 检查玩家是否在指定房间中
+func (wm *WebSocketManager) isPlayerInRoom(roomID, playerID string) bool {
+	return wm.roomShard(roomID).hasMember(roomID, playerID)
 }
 
 // handleMessages 处理接收到的WebSocket消息
-func (wm *WebSocketManager) handleMessages(playerID string, conn *websocket.Conn) {
+func (wm *WebSocketManager) handleMessages(c *client) {
+	playerID := c.playerID
+	conn := c.conn
+
+	// 读循环本身出现panic时关闭并清理这个连接，不影响其他连接
+	defer func() {
+		if value := recover(); value != nil {
+			reporting.Report(reporting.PanicEvent(value, "ws.read", "player_id", playerID, "connection_id", c.connectionID))
+			c.close("")
+			wm.removeClient(c)
+		}
+	}()
+
 	// 设置连接参数
-	conn.SetReadLimit(512 * 1024) // 设置最大消息大小为512KB
+	conn.SetReadLimit(c.profile.ReadLimit)
+
+	// 客户端在pongWait内没有任何消息或心跳响应时视为断线，读操作会超时返回
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
 
 	for {
 		// 读取消息
-		_, p, err := conn.ReadMessage()
+		frameType, p, err := conn.ReadMessage()
 		if err != nil {
 			// 检查是否是正常的连接关闭
+			var netErr net.Error
 			if websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
-				log.Printf("连接正常关闭: %v", err)
-				break // 直接退出消息处理循环，不调用RemoveConnection
+				c.logger.Info("连接正常关闭")
+			} else if errors.As(err, &netErr) && netErr.Timeout() {
+				c.logger.Info("未响应心跳，关闭连接", "timeout", pongWait)
+			} else {
+				c.logger.Info("读取消息失败", "error", err)
 			}
-			// 处理意外的连接关闭
-			log.Printf("读取消息失败: %v", err)
-			wm.RemoveConnection(playerID)
+			// 读失败后连接不可再用，通知写协程退出并清理连接
+			c.close("")
+			wm.removeClient(c)
 			break
 		}
+		conn.SetReadDeadline(time.Now().Add(pongWait))
 
-		// 解析消息
-		var msg Message
-		if err := json.Unmarshal(p, &msg); err != nil {
-			log.Printf("解析消息失败: %v", err)
-			continue
+		// 二进制帧为protobuf编码的消息，先转换为JSON消息信封
+		if frameType == websocket.BinaryMessage {
+			if p, err = protobufToJSON(p); err != nil {
+				c.logger.Info("二进制消息解析失败", "error", err)
+				wm.sendError(c, &ValidationError{Message: "消息格式错误: " + err.Error()})
+				continue
+			}
 		}
 
-		// 根据消息类型处理不同的业务逻辑
-		switch msg.Type {
-		case "game_action":
-			// 打印完整的action消息内容
-			log.Printf("收到game_action消息: RoomID=%s, PlayerID=%s, Content=%+v", msg.RoomID, playerID, msg.Content)
-
-			// 验证房间ID
-			if msg.RoomID == "" {
-				wm.SendToPlayer(playerID, map[string]interface{}{
-					"type":    "error",
-					"message": "缺少房间ID",
-				})
-				continue
+		// 解析并校验消息
+		msg, req, err := decodeRequest(p, c.protocol)
+		if err != nil {
+			c.logger.Info("消息校验失败", "error", err)
+			if action, ok := req.(*GameActionRequest); ok {
+				wm.rejectAction(c, action.gameAction(msg.RoomID, playerID), err)
+			} else {
+				wm.sendError(c, err)
 			}
+			continue
+		}
 
-			// 验证动作内容
-			if action, ok := msg.Content.(map[string]interface{}); ok {
-				// 检查必要字段是否存在且不为空
-				actionType, typeOk := action["type"].(string)
-
-				if !typeOk || actionType == "" {
-					wm.SendToPlayer(playerID, map[string]interface{}{
-						"type":    "error",
-						"message": "无效的动作类型",
-					})
-					continue
-				}
-
-				// 对于开始游戏动作，直接处理
-				if actionType == "start_game" {
-					// 验证玩家是否在房间中
-					if !wm.isPlayerInRoom(msg.RoomID, playerID) {
-						wm.SendToPlayer(playerID, map[string]interface{}{
-							"type":    "error",
-							"message": "玩家不在房间中",
-						})
-						continue
-					}
-
-					// 获取游戏控制器并开始游戏
-					if game, exists := wm.roomManager.GetGameController(msg.RoomID); exists {
-						if err := game.StartGame(); err != nil {
-							wm.SendToPlayer(playerID, map[string]interface{}{
-								"type":    "error",
-								"message": err.Error(),
-							})
-						}
-					} else {
-						wm.SendToPlayer(playerID, map[string]interface{}{
-							"type":    "error",
-							"message": "游戏未初始化",
-						})
-					}
-					continue
-				}
-
-				// 其他游戏动作需要验证目标玩家
-				targetID, targetOk := action["target"].(string)
-				if !targetOk || targetID == "" {
-					wm.SendToPlayer(playerID, map[string]interface{}{
-						"type":    "error",
-						"message": "无效的目标玩家",
-					})
-					continue
-				}
-
-				// 验证玩家是否在房间中
-				if !wm.isPlayerInRoom(msg.RoomID, playerID) {
-					wm.SendToPlayer(playerID, map[string]interface{}{
-						"type":    "error",
-						"message": "玩家不在房间中",
-					})
-					continue
-				}
-
-				// 验证目标玩家是否在房间中
-				if !wm.isPlayerInRoom(msg.RoomID, targetID) {
-					wm.SendToPlayer(playerID, map[string]interface{}{
-						"type":    "error",
-						"message": "目标玩家不在房间中",
-					})
-					continue
-				}
-
-				// 将动作转发给游戏控制器
-				gameAction := models.GameAction{
-					RoomID:   msg.RoomID,
-					PlayerID: playerID,
-					Type:     actionType,
-					TargetID: targetID,
-				}
-
-				// 获取游戏控制器并处理动作
-				if game, exists := wm.roomManager.GetGameController(msg.RoomID); exists {
-					if err := game.ProcessAction(gameAction); err != nil {
-						// 发送错误消息给玩家
-						wm.SendToPlayer(playerID, map[string]interface{}{
-							"type":    "error",
-							"message": err.Error(),
-						})
-					}
-				} else {
-					wm.SendToPlayer(playerID, map[string]interface{}{
-						"type":    "error",
-						"message": "游戏未开始或不存在",
-					})
-				}
+		// 只读连接只能发送心跳、确认和补发请求
+		if c.readOnly {
+			if action, ok := req.(*GameActionRequest); ok {
+				wm.rejectAction(c, action.gameAction(msg.RoomID, playerID), ErrInsufficientScope)
+				continue
 			}
-		case "chat":
-			// 处理聊天消息
-			if chat, ok := msg.Content.(map[string]interface{}); ok {
-				// 广播聊天消息给房间内所有玩家
-				wm.BroadcastToRoom(msg.RoomID, map[string]interface{}{
-					"type":      "chat",
-					"player_id": playerID,
-					"message":   chat["message"],
-				})
+			if _, ok := req.(*ChatRequest); ok {
+				wm.sendError(c, ErrInsufficientScope)
+				continue
 			}
-		default:
-			log.Printf("未知的消息类型: %s", msg.Type)
 		}
+
+		// 心跳和确认之外的消息各自记录一个片段，客户端可以通过traceparent字段延续自己的调用链
+		ctx, span := context.Background(), (*tracing.Span)(nil)
+		if msg.Type != MsgPing && msg.Type != MsgAck {
+			ctx, span = tracing.StartRemote(ctx, "ws."+msg.Type, msg.Traceparent)
+			span.SetAttr("player_id", playerID)
+			span.SetAttr("room_id", msg.RoomID)
+			span.SetAttr("connection_id", c.connectionID)
+		}
+
+		wm.dispatch(ctx, c, msg, req)
+		span.Finish()
+	}
+}
+
+// dispatch 根据消息类型处理不同的业务逻辑
+// 处理中的panic被捕获并上报，客户端收到内部错误，连接继续读取之后的消息
+func (wm *WebSocketManager) dispatch(ctx context.Context, c *client, msg *inboundMessage, req wsRequest) {
+	defer func() {
+		if value := recover(); value != nil {
+			reporting.Report(reporting.PanicEvent(value, "ws.message",
+				"type", msg.Type, "player_id", c.playerID, "room_id", msg.RoomID, "connection_id", c.connectionID))
+			tracing.FromContext(ctx).SetError(errInternal)
+			wm.sendError(c, errInternal)
+		}
+	}()
+
+	switch req := req.(type) {
+	case *GameActionRequest:
+		wm.handleGameAction(ctx, c, msg.RoomID, req)
+	case *ChatRequest:
+		wm.handleChat(c, msg.RoomID, req)
+	case *PingRequest:
+		wm.sendDirect(c, PongResponse{Type: "pong"})
+		if game, exists := wm.roomManager.GetGameController(msg.RoomID); exists {
+			game.MarkActive(c.playerID)
+		}
+	case *AckRequest:
+		wm.ackEvents(msg.RoomID, c.playerID, req.Seq)
+	case *ReplayRequest:
+		wm.replayEvents(c, msg.RoomID, req)
+	}
+}
+
+// handleGameAction 处理玩家的游戏动作
+// 玩家的多个连接提交的动作按到达顺序处理，同类动作以最后一次为准
+func (wm *WebSocketManager) handleGameAction(ctx context.Context, c *client, roomID string, req *GameActionRequest) {
+	playerID := c.playerID
+	c.logger.Debug("收到游戏动作", "room_id", roomID, "action", req.Type, "target", req.Target)
+	gameAction := req.gameAction(roomID, playerID)
+
+	// 对于开始游戏动作，直接处理
+	if req.Type == "start_game" {
+		// 验证玩家是否在房间中
+		if !wm.isPlayerInRoom(roomID, playerID) {
+			wm.sendError(c, ErrNotInRoom)
+			return
+		}
+
+		// 获取游戏控制器并开始游戏
+		game, exists := wm.roomManager.GetGameController(roomID)
+		if !exists {
+			wm.sendError(c, NewError(CodeGameNotFound, "游戏未初始化"))
+			return
+		}
+		if err := game.StartGame(playerID); err != nil {
+			wm.sendError(c, err)
+		}
+		return
+	}
+
+	// 验证玩家是否在房间中
+	if !wm.isPlayerInRoom(roomID, playerID) {
+		wm.rejectAction(c, gameAction, ErrNotInRoom)
+		return
+	}
+
+	// 出局玩家只有猎人可以开枪、警长可以移交警徽
+	if seat := wm.roomSeats(roomID)[playerID]; seat != nil && !seat.Alive && !afterDeathActions[req.Type] {
+		wm.rejectAction(c, gameAction, ErrPlayerDead)
+		return
+	}
+
+	// 验证目标玩家是否在房间中，不需要目标的动作不带目标
+	if !(untargetedActions[req.Type] && req.Target == "") && !wm.isPlayerInRoom(roomID, req.Target) {
+		wm.rejectAction(c, gameAction, NewError(CodeInvalidTarget, "目标玩家不在房间中"))
+		return
+	}
+
+	// 获取游戏控制器并处理动作
+	game, exists := wm.roomManager.GetGameController(roomID)
+	if !exists {
+		wm.rejectAction(c, gameAction, NewError(CodeGameNotFound, "游戏未开始或不存在"))
+		return
+	}
+	if err := game.ProcessAction(ctx, gameAction, AuditSourceWS, c.connectionID); err != nil {
+		wm.sendError(c, err)
+	}
+}
+
+// ackEvents 记录玩家已收到的房间事件序号
+func (wm *WebSocketManager) ackEvents(roomID, playerID string, seq int64) {
+	shard := wm.roomShard(roomID)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	if eventLog, exists := shard.eventLogs[roomID]; exists {
+		eventLog.ack(playerID, seq)
+	}
+}
+
+// replayEvents 向玩家补发指定序号之后的房间事件，只补发该玩家有权收到的事件
+func (wm *WebSocketManager) replayEvents(c *client, roomID string, req *ReplayRequest) {
+	playerID := c.playerID
+	if !wm.isPlayerInRoom(roomID, playerID) {
+		wm.sendError(c, ErrNotInRoom)
+		return
+	}
+	wm.sendDirect(c, wm.replayResponse(roomID, playerID, req.Since))
+}
+
+// replayResponse 玩家有权收到的、指定序号之后的房间事件，since为nil时从玩家确认过的序号开始
+func (wm *WebSocketManager) replayResponse(roomID, playerID string, since *int64) ReplayResponse {
+	seats := wm.roomSeats(roomID)
+
+	shard := wm.roomShard(roomID)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	resp := ReplayResponse{Type: "replay", RoomID: roomID, Events: make([]RoomEvent, 0), Complete: true}
+	if eventLog, exists := shard.eventLogs[roomID]; exists {
+		from := eventLog.acks[playerID]
+		if since != nil {
+			from = *since
+		}
+
+		result := eventLog.visible(playerID, seats[playerID], from)
+		resp.Events = result.Events
+		resp.LastSeq = result.LastSeq
+		resp.Complete = result.Complete
 	}
+	return resp
+}
+
+// sendError 向发起请求的连接发送错误消息
+func (wm *WebSocketManager) sendError(c *client, err error) {
+	wm.sendDirect(c, newErrorResponse(err, c.profile.Locale))
+}
+
+// rejectAction 向玩家返回动作被拒绝的原因，并写入审计日志
+func (wm *WebSocketManager) rejectAction(c *client, action models.GameAction, reason error) {
+	wm.roomManager.AuditRejectedAction(action, AuditSourceWS, c.connectionID, reason)
+	wm.sendError(c, reason)
 }
 
 // SetRoomManager 设置房间管理器实例
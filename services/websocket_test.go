@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/goleak"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// testReconnectWindow 测试中断线玩家的重连窗口期
+const testReconnectWindow = 50 * time.Millisecond
+
+// newTestServer 创建房间管理器、WebSocket管理器和接受连接的测试服务器，
+// 连接的玩家、房间和连接ID来自查询参数
+func newTestServer(t testing.TB) (*RoomManager, *WebSocketManager, *httptest.Server) {
+	t.Helper()
+
+	wm := NewWebSocketManager(nil)
+	rm := NewRoomManager(wm)
+	wm.SetRoomManager(rm)
+
+	profile := WebProfile
+	profile.ReconnectWindow = testReconnectWindow
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		query := r.URL.Query()
+		wm.RegisterConnection(query.Get("player"), conn, query.Get("connection_id"), CurrentProtocolVersion, EncodingJSON, false, profile)
+		wm.JoinRoom(query.Get("room"), query.Get("player"))
+	}))
+	return rm, wm, server
+}
+
+// dialPlayer 以玩家身份连接测试服务器
+func dialPlayer(t testing.TB, server *httptest.Server, roomID, playerID, connectionID string) *websocket.Conn {
+	t.Helper()
+
+	url := fmt.Sprintf("ws%s?room=%s&player=%s&connection_id=%s", strings.TrimPrefix(server.URL, "http"), roomID, playerID, connectionID)
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	return conn
+}
+
+// waitFor 等待条件成立，超时后测试失败
+func waitFor(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGamesAndConnectionsDoNotLeakGoroutines(t *testing.T) {
+	// 时间轮的goroutine在第一次使用时启动，之后一直运行
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent(),
+		goleak.IgnoreAnyFunction("github.com/qianlnk/werewolf/services.(*timerWheel).run"))
+
+	rm, wm, server := newTestServer(t)
+	defer server.Close()
+	rm.SetGameSeed(1)
+	rm.SetFinishedGameRetention(0)
+
+	for i := 0; i < 3; i++ {
+		room, err := rm.CreateRoom(fmt.Sprintf("leak %d", i), models.StandardMode, 9, false, false, "", "", nil, 9, nil, false, "")
+		if err != nil {
+			t.Fatalf("创建房间失败: %v", err)
+		}
+		host := models.Player{ID: fmt.Sprintf("host_%d", i), Name: "host", Type: models.HumanPlayer, Alive: true}
+		if err := rm.JoinRoom(room.ID, host); err != nil {
+			t.Fatalf("加入房间失败: %v", err)
+		}
+
+		// 在重连窗口期内重连，之前的窗口期被取消
+		conn := dialPlayer(t, server, room.ID, host.ID, "tab")
+		waitFor(t, "连接建立", func() bool { return wm.IsOnline(host.ID) })
+		conn.Close()
+		waitFor(t, "连接断开", func() bool { return !wm.IsOnline(host.ID) })
+		conn = dialPlayer(t, server, room.ID, host.ID, "tab")
+		waitFor(t, "重新连接", func() bool { return wm.IsOnline(host.ID) })
+
+		game, _ := rm.GetGameController(room.ID)
+		if err := game.StartGame(host.ID); err != nil {
+			t.Fatalf("开始对局失败: %v", err)
+		}
+
+		// 对局中断线不归，窗口期结束后由AI接管
+		conn.Close()
+		waitFor(t, "AI接管", func() bool {
+			game.mutex.RLock()
+			defer game.mutex.RUnlock()
+			return game.game.Players[0].Type == models.AIPlayer
+		})
+
+		finishGame(t, game)
+
+		// 保留期为0，结束的对局随即被释放，旧控制器的goroutine退出
+		waitFor(t, "释放结束的对局", func() bool {
+			current, _ := rm.GetGameController(room.ID)
+			return current != game
+		})
+		<-game.stopped
+	}
+
+	rm.Close()
+}
+
+// BenchmarkBroadcastToRoom 向房间内的所有连接广播一条消息
+func BenchmarkBroadcastToRoom(b *testing.B) {
+	for _, members := range []int{12, 100, 1000} {
+		b.Run(fmt.Sprintf("members=%d", members), func(b *testing.B) {
+			rm, wm, server := newTestServer(b)
+			defer server.Close()
+			// 发送缓冲区容纳得下所有广播以及玩家加入时的上线和房间更新通知，客户端读取跟不上时也不会断开连接
+			wm.SetSendQueue(b.N+4*members, OverflowDisconnect)
+
+			room, err := rm.CreateRoom("bench", models.StandardMode, 0, false, false, "", "", nil, 0, nil, false, "")
+			if err != nil {
+				b.Fatalf("创建房间失败: %v", err)
+			}
+			roomID := room.ID
+			conns := make([]*websocket.Conn, members)
+			for i := range conns {
+				playerID := fmt.Sprintf("player_%d", i)
+				conns[i] = dialPlayer(b, server, roomID, playerID, "bench")
+				go func(conn *websocket.Conn) {
+					for {
+						if _, _, err := conn.NextReader(); err != nil {
+							return
+						}
+					}
+				}(conns[i])
+			}
+			waitFor(b, "所有玩家加入房间", func() bool { return len(wm.roomShard(roomID).memberList(roomID)) == members })
+
+			message := map[string]interface{}{"type": "chat", "player_id": "player_0", "message": "我是预言家，昨晚验了3号"}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				wm.BroadcastToRoom(roomID, message)
+			}
+			b.StopTimer()
+
+			for _, conn := range conns {
+				conn.Close()
+			}
+		})
+	}
+}
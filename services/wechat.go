@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrWeChatDisabled    = NewError(CodeWeChatDisabled, "未配置微信小程序登录")
+	ErrInvalidWeChatCode = NewError(CodeInvalidWeChatCode, "无效或已使用的微信登录凭证")
+)
+
+// code2SessionURL 微信小程序登录凭证校验接口
+const code2SessionURL = "https://api.weixin.qq.com/sns/jscode2session"
+
+// 微信接口返回的登录凭证错误：无效、已使用
+var invalidWeChatCodes = map[int]bool{40029: true, 40163: true}
+
+// WeChatAuth 微信小程序登录，用wx.login获得的code向微信换取用户的openid和unionid，
+// 得到的身份与网站扫码登录相同，见wechatSubject。session_key只用于解密小程序的用户数据，服务端不做保存
+type WeChatAuth struct {
+	appID    string
+	secret   string
+	endpoint string
+	client   *http.Client
+}
+
+// NewWeChatAuth 创建微信小程序登录实例，appID或secret为空时不可用
+func NewWeChatAuth(appID, secret string) *WeChatAuth {
+	return &WeChatAuth{
+		appID:    appID,
+		secret:   secret,
+		endpoint: code2SessionURL,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Enabled 是否配置了小程序的AppID和AppSecret
+func (wa *WeChatAuth) Enabled() bool {
+	return wa.appID != "" && wa.secret != ""
+}
+
+// Code2Session 校验登录凭证，返回用户的微信身份
+func (wa *WeChatAuth) Code2Session(ctx context.Context, code string) (*ExternalIdentity, error) {
+	if !wa.Enabled() {
+		return nil, ErrWeChatDisabled
+	}
+
+	query := url.Values{
+		"appid":      {wa.appID},
+		"secret":     {wa.secret},
+		"js_code":    {code},
+		"grant_type": {"authorization_code"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wa.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := wa.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求微信登录接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OpenID  string `json:"openid"`
+		UnionID string `json:"unionid"`
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("微信登录接口返回 %s", resp.Status)
+	}
+	if invalidWeChatCodes[result.ErrCode] {
+		return nil, ErrInvalidWeChatCode
+	}
+	if result.ErrCode != 0 || result.OpenID == "" {
+		return nil, fmt.Errorf("微信登录接口返回错误 %d: %s", result.ErrCode, result.ErrMsg)
+	}
+	return &ExternalIdentity{Provider: ProviderWeChat, Subject: wechatSubject(result.OpenID, result.UnionID)}, nil
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// 客户端发送的WebSocket消息类型
+const (
+	MsgGameAction = "game_action"
+	MsgChat       = "chat"
+	MsgPing       = "ping"
+	MsgAck        = "ack"
+	MsgReplay     = "replay"
+)
+
+// maxChatLength 单条聊天消息的最大字数
+const maxChatLength = 500
+
+// inboundMessage 客户端发送的消息信封，content按type解析为对应的请求结构
+type inboundMessage struct {
+	Type    string          `json:"type"`
+	RoomID  string          `json:"room_id"`
+	Content json.RawMessage `json:"content"`
+	// Traceparent 可选，W3C格式的客户端调用链，服务端处理该消息的片段作为其子片段
+	Traceparent string `json:"traceparent,omitempty"`
+}
+
+// wsRequest 客户端请求的内容，validate校验各字段是否合法
+type wsRequest interface {
+	validate(roomID string) error
+}
+
+// GameActionRequest game_action消息的内容
+type GameActionRequest struct {
+	Type    string `json:"type"`              // 动作类型，start_game表示开始游戏
+	Target  string `json:"target"`            // 目标玩家ID，发言和女巫不用药时为空
+	Content string `json:"content,omitempty"` // 动作附带的内容，例如发言
+	// StateVersion 客户端看到的对局状态版本，取自game_state等消息，为0时不校验
+	StateVersion int64 `json:"state_version,omitempty"`
+}
+
+func (r *GameActionRequest) validate(roomID string) error {
+	if roomID == "" {
+		return &ValidationError{Field: "room_id", Message: "缺少房间ID"}
+	}
+	if r.Type == "" {
+		return &ValidationError{Field: "content.type", Message: "无效的动作类型"}
+	}
+	if r.Type != "start_game" && !untargetedActions[r.Type] && r.Target == "" {
+		return &ValidationError{Field: "content.target", Message: "无效的目标玩家"}
+	}
+	return nil
+}
+
+// gameAction 转换为游戏动作
+func (r *GameActionRequest) gameAction(roomID, playerID string) models.GameAction {
+	return models.GameAction{
+		RoomID:       roomID,
+		PlayerID:     playerID,
+		Type:         r.Type,
+		TargetID:     r.Target,
+		Content:      r.Content,
+		StateVersion: r.StateVersion,
+	}
+}
+
+// ChatRequest chat消息的内容
+type ChatRequest struct {
+	Channel string `json:"channel,omitempty"` // 聊天频道，默认为房间频道
+	To      string `json:"to,omitempty"`      // 私聊对象，仅whisper频道
+	Message string `json:"message"`
+}
+
+func (r *ChatRequest) validate(roomID string) error {
+	if roomID == "" {
+		return &ValidationError{Field: "room_id", Message: "缺少房间ID"}
+	}
+	if r.Channel == "" {
+		r.Channel = ChannelRoom
+	}
+	if !chatChannels[r.Channel] {
+		return &ValidationError{Field: "content.channel", Message: "未知的聊天频道: " + r.Channel}
+	}
+	if r.Channel == ChannelWhisper && r.To == "" {
+		return &ValidationError{Field: "content.to", Message: "缺少私聊对象"}
+	}
+	if r.Channel != ChannelWhisper {
+		r.To = ""
+	}
+	r.Message = strings.TrimSpace(r.Message)
+	if r.Message == "" {
+		return &ValidationError{Field: "content.message", Message: "聊天内容不能为空"}
+	}
+	if utf8.RuneCountInString(r.Message) > maxChatLength {
+		return &ValidationError{Field: "content.message", Message: fmt.Sprintf("聊天内容不能超过%d字", maxChatLength)}
+	}
+	return nil
+}
+
+// PingRequest 客户端心跳，没有内容
+type PingRequest struct{}
+
+func (r *PingRequest) validate(string) error { return nil }
+
+// AckRequest 客户端确认已收到的房间事件序号
+type AckRequest struct {
+	Seq int64 `json:"seq"`
+}
+
+func (r *AckRequest) validate(roomID string) error {
+	if roomID == "" {
+		return &ValidationError{Field: "room_id", Message: "缺少房间ID"}
+	}
+	if r.Seq < 0 {
+		return &ValidationError{Field: "content.seq", Message: "无效的序号"}
+	}
+	return nil
+}
+
+// ReplayRequest 客户端请求补发序号since之后的房间事件，since省略时从最近确认的序号开始
+type ReplayRequest struct {
+	Since *int64 `json:"since"`
+}
+
+func (r *ReplayRequest) validate(roomID string) error {
+	if roomID == "" {
+		return &ValidationError{Field: "room_id", Message: "缺少房间ID"}
+	}
+	if r.Since != nil && *r.Since < 0 {
+		return &ValidationError{Field: "content.since", Message: "无效的序号"}
+	}
+	return nil
+}
+
+// wsRequestTypes 各消息类型对应的请求结构，新增消息类型时在此注册
+var wsRequestTypes = map[string]func() wsRequest{
+	MsgGameAction: func() wsRequest { return &GameActionRequest{} },
+	MsgChat:       func() wsRequest { return &ChatRequest{} },
+	MsgPing:       func() wsRequest { return &PingRequest{} },
+	MsgAck:        func() wsRequest { return &AckRequest{} },
+	MsgReplay:     func() wsRequest { return &ReplayRequest{} },
+}
+
+// ValidationError 消息字段校验失败，Field为出错字段的路径
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// decodeRequest 解析消息信封并按类型解析和校验消息内容，protocol为连接的协议版本
+func decodeRequest(data []byte, protocol int) (*inboundMessage, wsRequest, error) {
+	var msg inboundMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, nil, &ValidationError{Message: "消息格式错误: " + err.Error()}
+	}
+
+	newRequest, exists := wsRequestTypes[msg.Type]
+	if !exists {
+		return &msg, nil, &ValidationError{Field: "type", Message: "未知的消息类型: " + msg.Type}
+	}
+	if minProtocol := wsRequestMinProtocol[msg.Type]; protocol < minProtocol {
+		return &msg, nil, &ValidationError{
+			Field:   "type",
+			Message: fmt.Sprintf("消息类型 %s 需要协议版本 %d", msg.Type, minProtocol),
+		}
+	}
+
+	req := newRequest()
+	if len(msg.Content) > 0 && string(msg.Content) != "null" {
+		if err := json.Unmarshal(msg.Content, req); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				return &msg, nil, &ValidationError{
+					Field:   "content." + typeErr.Field,
+					Message: fmt.Sprintf("字段类型错误，应为%s", typeErr.Type),
+				}
+			}
+			return &msg, nil, &ValidationError{Field: "content", Message: "消息内容格式错误"}
+		}
+	}
+
+	if err := req.validate(msg.RoomID); err != nil {
+		return &msg, req, err
+	}
+	return &msg, req, nil
+}
+
+// ErrorResponse 发送给客户端的错误消息
+type ErrorResponse struct {
+	Type    string    `json:"type"` // 固定为error
+	Code    ErrorCode `json:"code"` // 机器可读的错误码
+	Message string    `json:"message"`
+	Field   string    `json:"field,omitempty"` // 校验失败的字段
+}
+
+// newErrorResponse 根据错误构建错误消息，错误信息使用连接的语言，校验错误会带上出错的字段
+func newErrorResponse(err error, locale Locale) ErrorResponse {
+	resp := ErrorResponse{Type: "error", Code: ErrorCodeOf(err), Message: LocalizedError(err, locale)}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		resp.Field = validationErr.Field
+	}
+	return resp
+}
+
+// ChatBroadcast 发给频道成员的聊天消息
+type ChatBroadcast struct {
+	Type      string `json:"type"` // 固定为chat
+	ID        int64  `json:"id"`   // 房间内的聊天消息ID，用于向前翻页
+	Channel   string `json:"channel"`
+	PlayerID  string `json:"player_id"`
+	To        string `json:"to,omitempty"` // 私聊对象
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"` // 毫秒时间戳
+}
+
+// PongResponse 客户端心跳的响应
+type PongResponse struct {
+	Type string `json:"type"` // 固定为pong
+}
+
+// ReplayResponse 补发的房间事件，Complete为false表示部分事件已过期，客户端需要重新获取完整状态
+type ReplayResponse struct {
+	Type     string      `json:"type"` // 固定为replay
+	RoomID   string      `json:"room_id"`
+	Events   []RoomEvent `json:"events"`
+	LastSeq  int64       `json:"last_seq"`
+	Complete bool        `json:"complete"`
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// wsShardCount 连接和房间数据各自的分片数量，不同分片的玩家和房间互不争用锁
+const wsShardCount = 32
+
+// playerShard 按玩家ID分片的连接状态
+type playerShard struct {
+	connections   map[string]map[string]*client // playerID -> connectionID -> connection
+	disconnected  map[string]time.Time          // playerID -> 断线时间
+	pendingResync map[string]bool               // playerID -> 是否需要在加入房间后同步快照
+	// reconnectProfiles 断线玩家最后一个连接的客户端参数，决定重连窗口期和是否静默重连
+	reconnectProfiles map[string]ClientProfile
+	rooms             map[string][]string    // playerID -> 所在的房间，按加入顺序排列
+	cleanups          map[string]*time.Timer // playerID -> 重连窗口期结束时处理座位的计时器
+	mutex             sync.RWMutex
+}
+
+// roomShard 按房间ID分片的房间数据
+// 锁顺序为先房间分片再玩家分片，持有玩家分片的锁时不能再获取房间分片的锁
+type roomShard struct {
+	members       map[string][]string      // roomID -> []playerID
+	eventLogs     map[string]*roomEventLog // roomID -> 广播序号和最近事件
+	chatHistories map[string]*chatHistory  // roomID -> 最近的聊天消息
+	mutex         sync.RWMutex
+}
+
+// newPlayerShard 创建玩家分片
+func newPlayerShard() *playerShard {
+	return &playerShard{
+		connections:       make(map[string]map[string]*client),
+		disconnected:      make(map[string]time.Time),
+		pendingResync:     make(map[string]bool),
+		reconnectProfiles: make(map[string]ClientProfile),
+		rooms:             make(map[string][]string),
+		cleanups:          make(map[string]*time.Timer),
+	}
+}
+
+// reconnectProfileLocked 断线玩家最后一个连接的客户端参数，服务器重启后恢复的玩家使用默认参数，调用方需持有分片的锁
+func (s *playerShard) reconnectProfileLocked(playerID string) ClientProfile {
+	if profile, exists := s.reconnectProfiles[playerID]; exists {
+		return profile
+	}
+	return WebProfile
+}
+
+// newRoomShard 创建房间分片
+func newRoomShard() *roomShard {
+	return &roomShard{
+		members:       make(map[string][]string),
+		eventLogs:     make(map[string]*roomEventLog),
+		chatHistories: make(map[string]*chatHistory),
+	}
+}
+
+// shardIndex 计算ID所在的分片
+func shardIndex(id string) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % wsShardCount)
+}
+
+// playerShard 获取玩家所在的分片
+func (wm *WebSocketManager) playerShard(playerID string) *playerShard {
+	return wm.playerShards[shardIndex(playerID)]
+}
+
+// roomShard 获取房间所在的分片
+func (wm *WebSocketManager) roomShard(roomID string) *roomShard {
+	return wm.roomShards[shardIndex(roomID)]
+}
+
+// eventLogLocked 获取房间的广播事件记录，不存在时创建，调用方需持有分片的写锁
+func (s *roomShard) eventLogLocked(roomID string) *roomEventLog {
+	eventLog, exists := s.eventLogs[roomID]
+	if !exists {
+		eventLog = newRoomEventLog()
+		s.eventLogs[roomID] = eventLog
+	}
+	return eventLog
+}
+
+// addMemberLocked 将玩家加入房间成员，已在房间中时返回false，调用方需持有分片的写锁
+func (s *roomShard) addMemberLocked(roomID, playerID string) bool {
+	for _, pid := range s.members[roomID] {
+		if pid == playerID {
+			return false
+		}
+	}
+	s.members[roomID] = append(s.members[roomID], playerID)
+	return true
+}
+
+// hasMember 检查玩家是否在房间成员中
+func (s *roomShard) hasMember(roomID, playerID string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, pid := range s.members[roomID] {
+		if pid == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// memberList 获取房间成员的副本
+func (s *roomShard) memberList(roomID string) []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]string(nil), s.members[roomID]...)
+}
+
+// clientsLocked 获取玩家当前的所有连接，调用方需持有分片的锁
+func (s *playerShard) clientsLocked(playerID string) []*client {
+	clients := make([]*client, 0, len(s.connections[playerID]))
+	for _, c := range s.connections[playerID] {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// stopCleanupLocked 取消玩家尚未结束的重连窗口期，调用方需持有分片的写锁
+func (s *playerShard) stopCleanupLocked(playerID string) {
+	if timer, exists := s.cleanups[playerID]; exists {
+		timer.Stop()
+		delete(s.cleanups, playerID)
+	}
+}
+
+// presenceLocked 获取玩家的在线状态，调用方需持有分片的锁
+func (s *playerShard) presenceLocked(playerID string) string {
+	if len(s.connections[playerID]) > 0 {
+		return PresenceOnline
+	}
+	if _, disconnected := s.disconnected[playerID]; disconnected {
+		return PresenceReconnecting
+	}
+	return PresenceOffline
+}
+
+// addRoomLocked 记录玩家加入的房间，调用方需持有分片的写锁
+func (s *playerShard) addRoomLocked(playerID, roomID string) {
+	for _, id := range s.rooms[playerID] {
+		if id == roomID {
+			return
+		}
+	}
+	s.rooms[playerID] = append(s.rooms[playerID], roomID)
+}
+
+// removeRoomLocked 移除玩家加入的房间，调用方需持有分片的写锁
+func (s *playerShard) removeRoomLocked(playerID, roomID string) {
+	rooms := s.rooms[playerID]
+	for i, id := range rooms {
+		if id == roomID {
+			rooms = append(rooms[:i:i], rooms[i+1:]...)
+			break
+		}
+	}
+	if len(rooms) == 0 {
+		delete(s.rooms, playerID)
+		return
+	}
+	s.rooms[playerID] = rooms
+}
+
+// addMember 将玩家加入房间的成员和玩家的房间索引，玩家原本不在房间中时返回true
+func (wm *WebSocketManager) addMember(roomID, playerID string) bool {
+	rs := wm.roomShard(roomID)
+	rs.mutex.Lock()
+	added := rs.addMemberLocked(roomID, playerID)
+	rs.mutex.Unlock()
+
+	ps := wm.playerShard(playerID)
+	ps.mutex.Lock()
+	ps.addRoomLocked(playerID, roomID)
+	ps.mutex.Unlock()
+	return added
+}
+
+// playerRooms 获取玩家所在的所有房间
+func (wm *WebSocketManager) playerRooms(playerID string) []string {
+	ps := wm.playerShard(playerID)
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	return append([]string(nil), ps.rooms[playerID]...)
+}
@@ -0,0 +1,818 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// MemoryStore 内存存储，进程重启后数据丢失，用于开发和测试
+type MemoryStore struct {
+	rooms        map[string]models.Room
+	records      map[string]models.GameRecord
+	users        map[string]models.User                   // userID -> user
+	sessions     map[string]models.Session                // sessionID -> session
+	stats        map[string][]byte                        // playerID -> 统计JSON
+	achievements map[string][]models.PlayerAchievement    // playerID -> 已解锁成就
+	snapshots    map[string][]byte                        // roomID -> 对局快照
+	audit        []models.AuditEntry                      // 审计日志，只追加
+	auditSeq     int64                                    // 审计日志自增ID
+	seasons      map[int]models.Season                    // seasonID -> 赛季
+	standings    map[int]map[string]models.SeasonStanding // seasonID -> playerID -> 赛季成绩
+	friendships  []models.Friendship
+	reports      []models.Report
+	bans         map[string]models.Ban    // banID -> 封禁
+	apiKeys      map[string]models.APIKey // keyID -> API密钥
+	identities   []models.Identity
+	tournaments  map[string][]byte // tournamentID -> 赛事JSON
+	mutex        sync.RWMutex
+}
+
+// NewMemoryStore 创建内存存储实例
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		rooms:        make(map[string]models.Room),
+		records:      make(map[string]models.GameRecord),
+		users:        make(map[string]models.User),
+		sessions:     make(map[string]models.Session),
+		stats:        make(map[string][]byte),
+		achievements: make(map[string][]models.PlayerAchievement),
+		snapshots:    make(map[string][]byte),
+		seasons:      make(map[int]models.Season),
+		standings:    make(map[int]map[string]models.SeasonStanding),
+		bans:         make(map[string]models.Ban),
+		apiKeys:      make(map[string]models.APIKey),
+		tournaments:  make(map[string][]byte),
+	}
+}
+
+// SaveRoom 保存房间
+func (ms *MemoryStore) SaveRoom(room *models.Room) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	saved := *room
+	saved.Players = append([]models.Player(nil), room.Players...)
+	ms.rooms[room.ID] = saved
+	return nil
+}
+
+// DeleteRoom 删除房间
+func (ms *MemoryStore) DeleteRoom(roomID string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	delete(ms.rooms, roomID)
+	return nil
+}
+
+// LoadActiveRooms 加载所有房间
+func (ms *MemoryStore) LoadActiveRooms() ([]*models.Room, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	rooms := make([]*models.Room, 0, len(ms.rooms))
+	for _, room := range ms.rooms {
+		loaded := room
+		loaded.Players = append([]models.Player(nil), room.Players...)
+		rooms = append(rooms, &loaded)
+	}
+	return rooms, nil
+}
+
+// SaveGameRecord 保存对局记录
+func (ms *MemoryStore) SaveGameRecord(record *models.GameRecord) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	saved := *record
+	saved.Players = append([]models.Player(nil), record.Players...)
+	saved.Events = append([]models.GameEvent(nil), record.Events...)
+	ms.records[record.ID] = saved
+	return nil
+}
+
+// ListGameRecords 分页列出对局记录
+func (ms *MemoryStore) ListGameRecords(offset, limit int) ([]*models.GameRecord, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	records := make([]*models.GameRecord, 0, len(ms.records))
+	for _, record := range ms.records {
+		summary := record
+		summary.Events = nil
+		records = append(records, &summary)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].EndedAt > records[j].EndedAt
+	})
+
+	if offset >= len(records) {
+		return []*models.GameRecord{}, nil
+	}
+	records = records[offset:]
+	if limit > 0 && limit < len(records) {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// GetGameRecord 获取对局记录
+func (ms *MemoryStore) GetGameRecord(id string) (*models.GameRecord, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	record, exists := ms.records[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &record, nil
+}
+
+// ListGameRecordsBefore 列出早于指定时间结束的对局记录
+func (ms *MemoryStore) ListGameRecordsBefore(endedBefore int64, limit int) ([]*models.GameRecord, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	records := make([]*models.GameRecord, 0)
+	for _, record := range ms.records {
+		if record.EndedAt < endedBefore {
+			found := record
+			found.Events = append([]models.GameEvent(nil), record.Events...)
+			records = append(records, &found)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].EndedAt < records[j].EndedAt
+	})
+
+	if limit > 0 && limit < len(records) {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// UpdateGameRecordEvents 替换对局记录的事件日志
+func (ms *MemoryStore) UpdateGameRecordEvents(id string, events []models.GameEvent) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	record, exists := ms.records[id]
+	if !exists {
+		return ErrNotFound
+	}
+	record.Events = append([]models.GameEvent(nil), events...)
+	ms.records[id] = record
+	return nil
+}
+
+// DeleteGameRecord 删除对局记录
+func (ms *MemoryStore) DeleteGameRecord(id string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	delete(ms.records, id)
+	return nil
+}
+
+// CreateUser 创建账号
+func (ms *MemoryStore) CreateUser(user *models.User) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, existing := range ms.users {
+		if existing.Username == user.Username || existing.ID == user.ID {
+			return ErrDuplicate
+		}
+	}
+	ms.users[user.ID] = *user
+	return nil
+}
+
+// GetUserByID 根据玩家ID获取账号
+func (ms *MemoryStore) GetUserByID(id string) (*models.User, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	user, exists := ms.users[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+// GetUserByUsername 根据用户名获取账号
+func (ms *MemoryStore) GetUserByUsername(username string) (*models.User, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	for _, user := range ms.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// UpdateUser 更新账号信息
+func (ms *MemoryStore) UpdateUser(user *models.User) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if _, exists := ms.users[user.ID]; !exists {
+		return ErrNotFound
+	}
+	for _, existing := range ms.users {
+		if existing.ID != user.ID && existing.Username == user.Username {
+			return ErrDuplicate
+		}
+	}
+	ms.users[user.ID] = *user
+	return nil
+}
+
+// CreateSession 保存会话
+func (ms *MemoryStore) CreateSession(session *models.Session) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.sessions[session.ID] = *session
+	return nil
+}
+
+// GetSession 获取会话
+func (ms *MemoryStore) GetSession(id string) (*models.Session, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	session, exists := ms.sessions[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+// DeleteUserSessions 删除账号的所有会话
+func (ms *MemoryStore) DeleteUserSessions(userID string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for id, session := range ms.sessions {
+		if session.UserID == userID {
+			delete(ms.sessions, id)
+		}
+	}
+	return nil
+}
+
+// GetPlayerStats 获取玩家统计
+func (ms *MemoryStore) GetPlayerStats(playerID string) (*models.PlayerStats, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	data, exists := ms.stats[playerID]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	// 以JSON保存副本，避免调用方修改共享的map
+	var stats models.PlayerStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// SavePlayerStats 保存玩家统计
+func (ms *MemoryStore) SavePlayerStats(stats *models.PlayerStats) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.stats[stats.PlayerID] = data
+	return nil
+}
+
+// GetAchievements 获取玩家已解锁的成就
+func (ms *MemoryStore) GetAchievements(playerID string) ([]models.PlayerAchievement, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	return append([]models.PlayerAchievement{}, ms.achievements[playerID]...), nil
+}
+
+// AddAchievement 记录玩家解锁的成就
+func (ms *MemoryStore) AddAchievement(achievement *models.PlayerAchievement) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, existing := range ms.achievements[achievement.PlayerID] {
+		if existing.AchievementID == achievement.AchievementID {
+			return ErrDuplicate
+		}
+	}
+	ms.achievements[achievement.PlayerID] = append(ms.achievements[achievement.PlayerID], *achievement)
+	return nil
+}
+
+// SaveGameSnapshot 保存对局快照
+func (ms *MemoryStore) SaveGameSnapshot(roomID string, data []byte) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.snapshots[roomID] = append([]byte(nil), data...)
+	return nil
+}
+
+// LoadGameSnapshots 加载所有对局快照
+func (ms *MemoryStore) LoadGameSnapshots() (map[string][]byte, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	snapshots := make(map[string][]byte, len(ms.snapshots))
+	for roomID, data := range ms.snapshots {
+		snapshots[roomID] = append([]byte(nil), data...)
+	}
+	return snapshots, nil
+}
+
+// DeleteGameSnapshot 删除对局快照
+func (ms *MemoryStore) DeleteGameSnapshot(roomID string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	delete(ms.snapshots, roomID)
+	return nil
+}
+
+// AppendAuditEntry 追加审计日志条目
+func (ms *MemoryStore) AppendAuditEntry(entry *models.AuditEntry) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.auditSeq++
+	entry.ID = ms.auditSeq
+	ms.audit = append(ms.audit, *entry)
+	return nil
+}
+
+// DeleteAuditEntriesBefore 删除早于指定时间的审计日志
+func (ms *MemoryStore) DeleteAuditEntriesBefore(timestamp int64) (int64, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	kept := make([]models.AuditEntry, 0, len(ms.audit))
+	for _, entry := range ms.audit {
+		if entry.Timestamp >= timestamp {
+			kept = append(kept, entry)
+		}
+	}
+	deleted := int64(len(ms.audit) - len(kept))
+	ms.audit = kept
+	return deleted, nil
+}
+
+// ListAuditEntries 查询审计日志
+func (ms *MemoryStore) ListAuditEntries(query models.AuditQuery) ([]models.AuditEntry, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	entries := make([]models.AuditEntry, 0)
+	skipped := 0
+	for _, entry := range ms.audit {
+		if (query.RoomID != "" && entry.RoomID != query.RoomID) ||
+			(query.PlayerID != "" && entry.PlayerID != query.PlayerID) ||
+			(query.Since > 0 && entry.Timestamp < query.Since) ||
+			(query.Until > 0 && entry.Timestamp >= query.Until) {
+			continue
+		}
+		if skipped < query.Offset {
+			skipped++
+			continue
+		}
+		entries = append(entries, entry)
+		if query.Limit > 0 && len(entries) >= query.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// GetCurrentSeason 获取进行中的赛季
+func (ms *MemoryStore) GetCurrentSeason() (*models.Season, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	var current *models.Season
+	for _, season := range ms.seasons {
+		if season.EndedAt == 0 && (current == nil || season.ID > current.ID) {
+			found := season
+			current = &found
+		}
+	}
+	if current == nil {
+		return nil, ErrNotFound
+	}
+	return current, nil
+}
+
+// SaveSeason 保存赛季
+func (ms *MemoryStore) SaveSeason(season *models.Season) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.seasons[season.ID] = *season
+	return nil
+}
+
+// ListSeasons 列出所有赛季
+func (ms *MemoryStore) ListSeasons() ([]*models.Season, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	seasons := make([]*models.Season, 0, len(ms.seasons))
+	for _, season := range ms.seasons {
+		found := season
+		seasons = append(seasons, &found)
+	}
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].ID > seasons[j].ID
+	})
+	return seasons, nil
+}
+
+// GetSeasonStanding 获取玩家的赛季成绩
+func (ms *MemoryStore) GetSeasonStanding(seasonID int, playerID string) (*models.SeasonStanding, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	standing, exists := ms.standings[seasonID][playerID]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &standing, nil
+}
+
+// SaveSeasonStanding 保存玩家的赛季成绩
+func (ms *MemoryStore) SaveSeasonStanding(standing *models.SeasonStanding) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if ms.standings[standing.SeasonID] == nil {
+		ms.standings[standing.SeasonID] = make(map[string]models.SeasonStanding)
+	}
+	ms.standings[standing.SeasonID][standing.PlayerID] = *standing
+	return nil
+}
+
+// ListSeasonStandings 分页列出赛季排名
+func (ms *MemoryStore) ListSeasonStandings(seasonID, offset, limit int) ([]*models.SeasonStanding, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	standings := make([]*models.SeasonStanding, 0, len(ms.standings[seasonID]))
+	for _, standing := range ms.standings[seasonID] {
+		found := standing
+		standings = append(standings, &found)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		if standings[i].Rating != standings[j].Rating {
+			return standings[i].Rating > standings[j].Rating
+		}
+		return standings[i].PlayerID < standings[j].PlayerID
+	})
+
+	if offset >= len(standings) {
+		return []*models.SeasonStanding{}, nil
+	}
+	standings = standings[offset:]
+	if limit > 0 && limit < len(standings) {
+		standings = standings[:limit]
+	}
+	return standings, nil
+}
+
+// findFriendship 查找两个账号之间的好友关系下标，调用方需持有ms.mutex
+func (ms *MemoryStore) findFriendship(userID, otherID string) int {
+	for i, f := range ms.friendships {
+		if (f.RequesterID == userID && f.AddresseeID == otherID) ||
+			(f.RequesterID == otherID && f.AddresseeID == userID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetFriendship 获取好友关系
+func (ms *MemoryStore) GetFriendship(userID, otherID string) (*models.Friendship, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	i := ms.findFriendship(userID, otherID)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	friendship := ms.friendships[i]
+	return &friendship, nil
+}
+
+// SaveFriendship 保存好友关系
+func (ms *MemoryStore) SaveFriendship(friendship *models.Friendship) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if i := ms.findFriendship(friendship.RequesterID, friendship.AddresseeID); i >= 0 {
+		ms.friendships[i] = *friendship
+		return nil
+	}
+	ms.friendships = append(ms.friendships, *friendship)
+	return nil
+}
+
+// DeleteFriendship 删除好友关系
+func (ms *MemoryStore) DeleteFriendship(userID, otherID string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if i := ms.findFriendship(userID, otherID); i >= 0 {
+		ms.friendships = append(ms.friendships[:i], ms.friendships[i+1:]...)
+	}
+	return nil
+}
+
+// ListFriendships 列出账号参与的所有好友关系
+func (ms *MemoryStore) ListFriendships(userID string) ([]*models.Friendship, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	friendships := make([]*models.Friendship, 0)
+	for _, f := range ms.friendships {
+		if f.RequesterID == userID || f.AddresseeID == userID {
+			found := f
+			friendships = append(friendships, &found)
+		}
+	}
+	return friendships, nil
+}
+
+// SaveReport 保存举报
+func (ms *MemoryStore) SaveReport(report *models.Report) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for i := range ms.reports {
+		if ms.reports[i].ID == report.ID {
+			ms.reports[i] = *report
+			return nil
+		}
+	}
+	ms.reports = append(ms.reports, *report)
+	return nil
+}
+
+// GetReport 获取举报
+func (ms *MemoryStore) GetReport(id string) (*models.Report, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	for _, report := range ms.reports {
+		if report.ID == id {
+			return &report, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListReports 分页列出举报
+func (ms *MemoryStore) ListReports(status string, offset, limit int) ([]*models.Report, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	reports := make([]*models.Report, 0)
+	for _, report := range ms.reports {
+		if status != "" && report.Status != status {
+			continue
+		}
+		found := report
+		reports = append(reports, &found)
+	}
+
+	if offset >= len(reports) {
+		return []*models.Report{}, nil
+	}
+	reports = reports[offset:]
+	if limit > 0 && limit < len(reports) {
+		reports = reports[:limit]
+	}
+	return reports, nil
+}
+
+// SaveBan 保存封禁
+func (ms *MemoryStore) SaveBan(ban *models.Ban) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.bans[ban.ID] = *ban
+	return nil
+}
+
+// DeleteBan 解除封禁
+func (ms *MemoryStore) DeleteBan(id string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if _, exists := ms.bans[id]; !exists {
+		return ErrNotFound
+	}
+	delete(ms.bans, id)
+	return nil
+}
+
+// ListBans 列出所有封禁
+func (ms *MemoryStore) ListBans() ([]*models.Ban, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	bans := make([]*models.Ban, 0, len(ms.bans))
+	for _, ban := range ms.bans {
+		found := ban
+		bans = append(bans, &found)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].CreatedAt < bans[j].CreatedAt
+	})
+	return bans, nil
+}
+
+// SaveAPIKey 保存API密钥
+func (ms *MemoryStore) SaveAPIKey(key *models.APIKey) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	saved := *key
+	saved.Scopes = append([]string(nil), key.Scopes...)
+	ms.apiKeys[key.ID] = saved
+	return nil
+}
+
+// GetAPIKey 获取API密钥
+func (ms *MemoryStore) GetAPIKey(id string) (*models.APIKey, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	key, exists := ms.apiKeys[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return &key, nil
+}
+
+// ListAPIKeys 列出账号的API密钥
+func (ms *MemoryStore) ListAPIKeys(ownerID string) ([]*models.APIKey, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	keys := make([]*models.APIKey, 0)
+	for _, key := range ms.apiKeys {
+		if key.OwnerID == ownerID {
+			found := key
+			keys = append(keys, &found)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt < keys[j].CreatedAt
+	})
+	return keys, nil
+}
+
+// DeleteAPIKey 删除API密钥
+func (ms *MemoryStore) DeleteAPIKey(id string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if _, exists := ms.apiKeys[id]; !exists {
+		return ErrNotFound
+	}
+	delete(ms.apiKeys, id)
+	return nil
+}
+
+// CreateIdentity 绑定第三方登录身份
+func (ms *MemoryStore) CreateIdentity(identity *models.Identity) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for _, existing := range ms.identities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+			return ErrDuplicate
+		}
+	}
+	ms.identities = append(ms.identities, *identity)
+	return nil
+}
+
+// GetIdentity 获取第三方登录身份
+func (ms *MemoryStore) GetIdentity(provider, subject string) (*models.Identity, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	for _, identity := range ms.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			found := identity
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ListIdentities 列出账号绑定的第三方登录身份
+func (ms *MemoryStore) ListIdentities(userID string) ([]*models.Identity, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	identities := make([]*models.Identity, 0)
+	for _, identity := range ms.identities {
+		if identity.UserID == userID {
+			found := identity
+			identities = append(identities, &found)
+		}
+	}
+	return identities, nil
+}
+
+// DeleteIdentity 解除账号在指定平台绑定的身份
+func (ms *MemoryStore) DeleteIdentity(userID, provider string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	for i, identity := range ms.identities {
+		if identity.UserID == userID && identity.Provider == provider {
+			ms.identities = append(ms.identities[:i], ms.identities[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// SaveTournament 保存赛事
+func (ms *MemoryStore) SaveTournament(tournament *models.Tournament) error {
+	data, err := json.Marshal(tournament)
+	if err != nil {
+		return err
+	}
+
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.tournaments[tournament.ID] = data
+	return nil
+}
+
+// GetTournament 获取赛事
+func (ms *MemoryStore) GetTournament(id string) (*models.Tournament, error) {
+	ms.mutex.RLock()
+	data, exists := ms.tournaments[id]
+	ms.mutex.RUnlock()
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	var tournament models.Tournament
+	if err := json.Unmarshal(data, &tournament); err != nil {
+		return nil, err
+	}
+	return &tournament, nil
+}
+
+// ListTournaments 按创建时间倒序列出赛事
+func (ms *MemoryStore) ListTournaments(status string) ([]*models.Tournament, error) {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	tournaments := make([]*models.Tournament, 0)
+	for _, data := range ms.tournaments {
+		var tournament models.Tournament
+		if err := json.Unmarshal(data, &tournament); err != nil {
+			return nil, err
+		}
+		if status != "" && tournament.Status != status {
+			continue
+		}
+		tournaments = append(tournaments, &tournament)
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		return tournaments[i].CreatedAt > tournaments[j].CreatedAt
+	})
+	return tournaments, nil
+}
+
+// Close 关闭存储
+func (ms *MemoryStore) Close() error {
+	return nil
+}
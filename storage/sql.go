@@ -0,0 +1,1205 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"  // Postgres驱动
+	_ "modernc.org/sqlite" // SQLite驱动
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+// 建表语句，同时兼容Postgres和SQLite
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS rooms (
+		id               TEXT PRIMARY KEY,
+		name             TEXT NOT NULL,
+		mode             TEXT NOT NULL,
+		max_players      INTEGER NOT NULL,
+		min_players      INTEGER NOT NULL,
+		game_started     BOOLEAN NOT NULL DEFAULT FALSE,
+		ranked           BOOLEAN NOT NULL DEFAULT FALSE,
+		allow_whispers   BOOLEAN NOT NULL DEFAULT FALSE,
+		chat_filter      TEXT NOT NULL DEFAULT '',
+		locale           TEXT NOT NULL DEFAULT '',
+		judge_id         TEXT NOT NULL DEFAULT '',
+		ai_personalities TEXT NOT NULL DEFAULT '',
+		ai_fill          INTEGER NOT NULL DEFAULT 6,
+		roles            TEXT NOT NULL DEFAULT '',
+		created_at       BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS players (
+		room_id     TEXT NOT NULL,
+		id          TEXT NOT NULL,
+		seat        INTEGER NOT NULL,
+		name        TEXT NOT NULL,
+		type        TEXT NOT NULL,
+		role        TEXT NOT NULL,
+		personality TEXT NOT NULL,
+		alive       BOOLEAN NOT NULL,
+		is_lover    BOOLEAN NOT NULL,
+		rating      INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (room_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS game_records (
+		id         TEXT PRIMARY KEY,
+		room_id    TEXT NOT NULL,
+		mode       TEXT NOT NULL,
+		players    TEXT NOT NULL,
+		result     TEXT NOT NULL,
+		ranked     BOOLEAN NOT NULL DEFAULT FALSE,
+		events     TEXT NOT NULL,
+		started_at BIGINT NOT NULL,
+		ended_at   BIGINT NOT NULL,
+		duration   BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS users (
+		id            TEXT PRIMARY KEY,
+		username      TEXT NOT NULL UNIQUE,
+		display_name  TEXT NOT NULL,
+		password_hash TEXT NOT NULL,
+		guest         BOOLEAN NOT NULL DEFAULT FALSE,
+		rating        INTEGER NOT NULL DEFAULT 1500,
+		rated_games   INTEGER NOT NULL DEFAULT 0,
+		created_at    BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS sessions (
+		id         TEXT PRIMARY KEY,
+		user_id    TEXT NOT NULL,
+		created_at BIGINT NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS player_stats (
+		player_id  TEXT PRIMARY KEY,
+		data       TEXT NOT NULL,
+		updated_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS player_achievements (
+		player_id      TEXT NOT NULL,
+		achievement_id TEXT NOT NULL,
+		game_id        TEXT NOT NULL,
+		unlocked_at    BIGINT NOT NULL,
+		PRIMARY KEY (player_id, achievement_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS game_snapshots (
+		room_id    TEXT PRIMARY KEY,
+		data       TEXT NOT NULL,
+		updated_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id            INTEGER PRIMARY KEY AUTOINCREMENT,
+		room_id       TEXT NOT NULL,
+		player_id     TEXT NOT NULL,
+		connection_id TEXT NOT NULL,
+		source        TEXT NOT NULL,
+		action        TEXT NOT NULL,
+		target_id     TEXT NOT NULL,
+		phase         TEXT NOT NULL,
+		round         INTEGER NOT NULL,
+		accepted      BOOLEAN NOT NULL,
+		reason        TEXT NOT NULL,
+		timestamp     BIGINT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_audit_log_room ON audit_log (room_id, timestamp)`,
+	`CREATE INDEX IF NOT EXISTS idx_audit_log_player ON audit_log (player_id, timestamp)`,
+	`CREATE TABLE IF NOT EXISTS seasons (
+		id         INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		started_at BIGINT NOT NULL,
+		ends_at    BIGINT NOT NULL,
+		ended_at   BIGINT NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS season_standings (
+		season_id  INTEGER NOT NULL,
+		player_id  TEXT NOT NULL,
+		rating     INTEGER NOT NULL,
+		games      INTEGER NOT NULL,
+		wins       INTEGER NOT NULL,
+		updated_at BIGINT NOT NULL,
+		PRIMARY KEY (season_id, player_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS friendships (
+		requester_id TEXT NOT NULL,
+		addressee_id TEXT NOT NULL,
+		status       TEXT NOT NULL,
+		created_at   BIGINT NOT NULL,
+		PRIMARY KEY (requester_id, addressee_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_friendships_addressee ON friendships (addressee_id)`,
+	`CREATE TABLE IF NOT EXISTS reports (
+		id          TEXT PRIMARY KEY,
+		reporter_id TEXT NOT NULL,
+		reported_id TEXT NOT NULL,
+		room_id     TEXT NOT NULL,
+		reason      TEXT NOT NULL,
+		details     TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		reviewed_by TEXT NOT NULL,
+		review_note TEXT NOT NULL,
+		created_at  BIGINT NOT NULL,
+		reviewed_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS bans (
+		id         TEXT PRIMARY KEY,
+		type       TEXT NOT NULL,
+		target     TEXT NOT NULL,
+		reason     TEXT NOT NULL,
+		created_by TEXT NOT NULL,
+		created_at BIGINT NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id          TEXT PRIMARY KEY,
+		owner_id    TEXT NOT NULL,
+		name        TEXT NOT NULL,
+		scopes      TEXT NOT NULL,
+		secret_hash TEXT NOT NULL,
+		created_at  BIGINT NOT NULL,
+		expires_at  BIGINT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_api_keys_owner ON api_keys (owner_id)`,
+	`CREATE TABLE IF NOT EXISTS identities (
+		provider   TEXT NOT NULL,
+		subject    TEXT NOT NULL,
+		user_id    TEXT NOT NULL,
+		name       TEXT NOT NULL,
+		created_at BIGINT NOT NULL,
+		PRIMARY KEY (provider, subject)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_identities_user ON identities (user_id)`,
+	`CREATE TABLE IF NOT EXISTS tournaments (
+		id         TEXT PRIMARY KEY,
+		status     TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		created_at BIGINT NOT NULL
+	)`,
+}
+
+// SQLStore 基于database/sql的存储，支持Postgres和SQLite
+type SQLStore struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewSQLStore 创建SQL存储实例并初始化表结构
+func NewSQLStore(driver, dsn string) (*SQLStore, error) {
+	if dsn == "" {
+		return nil, fmt.Errorf("存储类型 %s 缺少连接串", driver)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite不支持并发写入，限制为单连接
+	if driver == "sqlite" {
+		db.SetMaxOpenConns(1)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	for _, stmt := range schema {
+		// Postgres没有AUTOINCREMENT，使用BIGSERIAL自增主键
+		if driver == "postgres" {
+			stmt = strings.ReplaceAll(stmt, "INTEGER PRIMARY KEY AUTOINCREMENT", "BIGSERIAL PRIMARY KEY")
+		}
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("初始化表结构失败: %w", err)
+		}
+	}
+
+	return &SQLStore{db: db, driver: driver}, nil
+}
+
+// rebind 将 ? 占位符转换为驱动对应的格式
+func (ss *SQLStore) rebind(query string) string {
+	if ss.driver != "postgres" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+// SaveRoom 保存房间及其玩家信息
+func (ss *SQLStore) SaveRoom(room *models.Room) error {
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	personalities := ""
+	if len(room.AIPersonalities) > 0 {
+		data, err := json.Marshal(room.AIPersonalities)
+		if err != nil {
+			return err
+		}
+		personalities = string(data)
+	}
+	roles := ""
+	if len(room.Roles) > 0 {
+		data, err := json.Marshal(room.Roles)
+		if err != nil {
+			return err
+		}
+		roles = string(data)
+	}
+
+	_, err = tx.Exec(ss.rebind(`INSERT INTO rooms (id, name, mode, max_players, min_players, game_started, ranked, allow_whispers, chat_filter, locale, judge_id, ai_personalities, ai_fill, roles, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, mode = excluded.mode,
+			max_players = excluded.max_players, min_players = excluded.min_players,
+			game_started = excluded.game_started, ranked = excluded.ranked, allow_whispers = excluded.allow_whispers,
+			chat_filter = excluded.chat_filter, locale = excluded.locale, judge_id = excluded.judge_id, ai_personalities = excluded.ai_personalities, ai_fill = excluded.ai_fill, roles = excluded.roles`),
+		room.ID, room.Name, string(room.Mode), room.MaxPlayers, room.MinPlayers, room.GameStarted, room.Ranked, room.AllowWhispers,
+		room.ChatFilter, room.Locale, room.JudgeID, personalities, room.AIFill, roles, room.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	// 玩家列表整体覆盖
+	if _, err := tx.Exec(ss.rebind(`DELETE FROM players WHERE room_id = ?`), room.ID); err != nil {
+		return err
+	}
+	for seat, player := range room.Players {
+		_, err := tx.Exec(ss.rebind(`INSERT INTO players (room_id, id, seat, name, type, role, personality, alive, is_lover, rating)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			room.ID, player.ID, seat, player.Name, string(player.Type), string(player.Role),
+			string(player.Personality), player.Alive, player.IsLover, player.Rating)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteRoom 删除房间及其玩家信息
+func (ss *SQLStore) DeleteRoom(roomID string) error {
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(ss.rebind(`DELETE FROM players WHERE room_id = ?`), roomID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ss.rebind(`DELETE FROM rooms WHERE id = ?`), roomID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// LoadActiveRooms 加载所有房间及其玩家信息
+func (ss *SQLStore) LoadActiveRooms() ([]*models.Room, error) {
+	rows, err := ss.db.Query(`SELECT id, name, mode, max_players, min_players, game_started, ranked, allow_whispers, chat_filter, locale, judge_id, ai_personalities, ai_fill, roles, created_at
+		FROM rooms ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rooms := make([]*models.Room, 0)
+	byID := make(map[string]*models.Room)
+	for rows.Next() {
+		room := &models.Room{Players: make([]models.Player, 0)}
+		var mode, personalities, roles string
+		if err := rows.Scan(&room.ID, &room.Name, &mode, &room.MaxPlayers, &room.MinPlayers,
+			&room.GameStarted, &room.Ranked, &room.AllowWhispers, &room.ChatFilter, &room.Locale, &room.JudgeID, &personalities, &room.AIFill, &roles, &room.CreatedAt); err != nil {
+			return nil, err
+		}
+		room.Mode = models.GameMode(mode)
+		if personalities != "" {
+			if err := json.Unmarshal([]byte(personalities), &room.AIPersonalities); err != nil {
+				return nil, err
+			}
+		}
+		if roles != "" {
+			if err := json.Unmarshal([]byte(roles), &room.Roles); err != nil {
+				return nil, err
+			}
+		}
+		rooms = append(rooms, room)
+		byID[room.ID] = room
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	playerRows, err := ss.db.Query(`SELECT room_id, id, name, type, role, personality, alive, is_lover, rating
+		FROM players ORDER BY room_id, seat`)
+	if err != nil {
+		return nil, err
+	}
+	defer playerRows.Close()
+
+	for playerRows.Next() {
+		var roomID, playerType, role, personality string
+		var player models.Player
+		if err := playerRows.Scan(&roomID, &player.ID, &player.Name, &playerType, &role, &personality,
+			&player.Alive, &player.IsLover, &player.Rating); err != nil {
+			return nil, err
+		}
+		player.Type = models.PlayerType(playerType)
+		player.Role = models.Role(role)
+		player.Personality = models.AIPersonality(personality)
+
+		if room, ok := byID[roomID]; ok {
+			room.Players = append(room.Players, player)
+		}
+	}
+	return rooms, playerRows.Err()
+}
+
+// SaveGameRecord 保存对局记录
+func (ss *SQLStore) SaveGameRecord(record *models.GameRecord) error {
+	players, err := json.Marshal(record.Players)
+	if err != nil {
+		return err
+	}
+	events, err := json.Marshal(record.Events)
+	if err != nil {
+		return err
+	}
+
+	_, err = ss.db.Exec(ss.rebind(`INSERT INTO game_records (id, room_id, mode, players, result, ranked, events, started_at, ended_at, duration)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		record.ID, record.RoomID, string(record.Mode), string(players), record.Result, record.Ranked, string(events),
+		record.StartedAt, record.EndedAt, record.Duration)
+	return err
+}
+
+// ListGameRecords 分页列出对局记录
+func (ss *SQLStore) ListGameRecords(offset, limit int) ([]*models.GameRecord, error) {
+	if limit <= 0 {
+		limit = 1<<31 - 1
+	}
+
+	rows, err := ss.db.Query(ss.rebind(`SELECT id, room_id, mode, players, result, ranked, started_at, ended_at, duration
+		FROM game_records ORDER BY ended_at DESC LIMIT ? OFFSET ?`), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*models.GameRecord, 0)
+	for rows.Next() {
+		var record models.GameRecord
+		var mode, players string
+		if err := rows.Scan(&record.ID, &record.RoomID, &mode, &players, &record.Result, &record.Ranked,
+			&record.StartedAt, &record.EndedAt, &record.Duration); err != nil {
+			return nil, err
+		}
+		record.Mode = models.GameMode(mode)
+		if err := json.Unmarshal([]byte(players), &record.Players); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	return records, rows.Err()
+}
+
+// GetGameRecord 获取对局记录
+func (ss *SQLStore) GetGameRecord(id string) (*models.GameRecord, error) {
+	var record models.GameRecord
+	var mode, players, events string
+	err := ss.db.QueryRow(ss.rebind(`SELECT id, room_id, mode, players, result, ranked, events, started_at, ended_at, duration
+		FROM game_records WHERE id = ?`), id).Scan(&record.ID, &record.RoomID, &mode, &players, &record.Result,
+		&record.Ranked, &events, &record.StartedAt, &record.EndedAt, &record.Duration)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record.Mode = models.GameMode(mode)
+	if err := json.Unmarshal([]byte(players), &record.Players); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &record.Events); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ListGameRecordsBefore 列出早于指定时间结束的对局记录
+func (ss *SQLStore) ListGameRecordsBefore(endedBefore int64, limit int) ([]*models.GameRecord, error) {
+	if limit <= 0 {
+		limit = 1<<31 - 1
+	}
+
+	rows, err := ss.db.Query(ss.rebind(`SELECT id, room_id, mode, players, result, ranked, events, started_at, ended_at, duration
+		FROM game_records WHERE ended_at < ? ORDER BY ended_at LIMIT ?`), endedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]*models.GameRecord, 0)
+	for rows.Next() {
+		var record models.GameRecord
+		var mode, players, events string
+		if err := rows.Scan(&record.ID, &record.RoomID, &mode, &players, &record.Result, &record.Ranked, &events,
+			&record.StartedAt, &record.EndedAt, &record.Duration); err != nil {
+			return nil, err
+		}
+		record.Mode = models.GameMode(mode)
+		if err := json.Unmarshal([]byte(players), &record.Players); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(events), &record.Events); err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	return records, rows.Err()
+}
+
+// UpdateGameRecordEvents 替换对局记录的事件日志
+func (ss *SQLStore) UpdateGameRecordEvents(id string, events []models.GameEvent) error {
+	data, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	result, err := ss.db.Exec(ss.rebind(`UPDATE game_records SET events = ? WHERE id = ?`), string(data), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteGameRecord 删除对局记录
+func (ss *SQLStore) DeleteGameRecord(id string) error {
+	_, err := ss.db.Exec(ss.rebind(`DELETE FROM game_records WHERE id = ?`), id)
+	return err
+}
+
+// CreateUser 创建账号
+func (ss *SQLStore) CreateUser(user *models.User) error {
+	// 先检查用户名，避免依赖不同驱动的唯一约束错误类型
+	if _, err := ss.GetUserByUsername(user.Username); err == nil {
+		return ErrDuplicate
+	} else if err != ErrNotFound {
+		return err
+	}
+
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO users (id, username, display_name, password_hash, guest, rating, rated_games, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		user.ID, user.Username, user.DisplayName, user.PasswordHash, user.Guest, user.Rating, user.RatedGames, user.CreatedAt)
+	return err
+}
+
+// UpdateUser 更新账号信息
+func (ss *SQLStore) UpdateUser(user *models.User) error {
+	if existing, err := ss.GetUserByUsername(user.Username); err == nil && existing.ID != user.ID {
+		return ErrDuplicate
+	} else if err != nil && err != ErrNotFound {
+		return err
+	}
+
+	result, err := ss.db.Exec(ss.rebind(`UPDATE users SET username = ?, display_name = ?, password_hash = ?, guest = ?,
+		rating = ?, rated_games = ? WHERE id = ?`),
+		user.Username, user.DisplayName, user.PasswordHash, user.Guest, user.Rating, user.RatedGames, user.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateSession 保存会话
+func (ss *SQLStore) CreateSession(session *models.Session) error {
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO sessions (id, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)`),
+		session.ID, session.UserID, session.CreatedAt, session.ExpiresAt)
+	return err
+}
+
+// GetSession 获取会话
+func (ss *SQLStore) GetSession(id string) (*models.Session, error) {
+	var session models.Session
+	err := ss.db.QueryRow(ss.rebind(`SELECT id, user_id, created_at, expires_at FROM sessions WHERE id = ?`), id).
+		Scan(&session.ID, &session.UserID, &session.CreatedAt, &session.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// DeleteUserSessions 删除账号的所有会话
+func (ss *SQLStore) DeleteUserSessions(userID string) error {
+	_, err := ss.db.Exec(ss.rebind(`DELETE FROM sessions WHERE user_id = ?`), userID)
+	return err
+}
+
+// GetUserByID 根据玩家ID获取账号
+func (ss *SQLStore) GetUserByID(id string) (*models.User, error) {
+	return ss.getUser(`SELECT id, username, display_name, password_hash, guest, rating, rated_games, created_at FROM users WHERE id = ?`, id)
+}
+
+// GetUserByUsername 根据用户名获取账号
+func (ss *SQLStore) GetUserByUsername(username string) (*models.User, error) {
+	return ss.getUser(`SELECT id, username, display_name, password_hash, guest, rating, rated_games, created_at FROM users WHERE username = ?`, username)
+}
+
+// getUser 查询单个账号
+func (ss *SQLStore) getUser(query string, arg string) (*models.User, error) {
+	var user models.User
+	err := ss.db.QueryRow(ss.rebind(query), arg).Scan(&user.ID, &user.Username, &user.DisplayName,
+		&user.PasswordHash, &user.Guest, &user.Rating, &user.RatedGames, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetPlayerStats 获取玩家统计
+func (ss *SQLStore) GetPlayerStats(playerID string) (*models.PlayerStats, error) {
+	var data string
+	err := ss.db.QueryRow(ss.rebind(`SELECT data FROM player_stats WHERE player_id = ?`), playerID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stats models.PlayerStats
+	if err := json.Unmarshal([]byte(data), &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// SavePlayerStats 保存玩家统计
+func (ss *SQLStore) SavePlayerStats(stats *models.PlayerStats) error {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+
+	_, err = ss.db.Exec(ss.rebind(`INSERT INTO player_stats (player_id, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (player_id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`),
+		stats.PlayerID, string(data), stats.UpdatedAt)
+	return err
+}
+
+// GetAchievements 获取玩家已解锁的成就
+func (ss *SQLStore) GetAchievements(playerID string) ([]models.PlayerAchievement, error) {
+	rows, err := ss.db.Query(ss.rebind(`SELECT player_id, achievement_id, game_id, unlocked_at
+		FROM player_achievements WHERE player_id = ? ORDER BY unlocked_at`), playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	achievements := make([]models.PlayerAchievement, 0)
+	for rows.Next() {
+		var achievement models.PlayerAchievement
+		if err := rows.Scan(&achievement.PlayerID, &achievement.AchievementID, &achievement.GameID,
+			&achievement.UnlockedAt); err != nil {
+			return nil, err
+		}
+		achievements = append(achievements, achievement)
+	}
+	return achievements, rows.Err()
+}
+
+// AddAchievement 记录玩家解锁的成就
+func (ss *SQLStore) AddAchievement(achievement *models.PlayerAchievement) error {
+	var count int
+	err := ss.db.QueryRow(ss.rebind(`SELECT COUNT(*) FROM player_achievements WHERE player_id = ? AND achievement_id = ?`),
+		achievement.PlayerID, achievement.AchievementID).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicate
+	}
+
+	_, err = ss.db.Exec(ss.rebind(`INSERT INTO player_achievements (player_id, achievement_id, game_id, unlocked_at)
+		VALUES (?, ?, ?, ?)`),
+		achievement.PlayerID, achievement.AchievementID, achievement.GameID, achievement.UnlockedAt)
+	return err
+}
+
+// SaveGameSnapshot 保存对局快照
+func (ss *SQLStore) SaveGameSnapshot(roomID string, data []byte) error {
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO game_snapshots (room_id, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (room_id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`),
+		roomID, string(data), time.Now().Unix())
+	return err
+}
+
+// LoadGameSnapshots 加载所有对局快照
+func (ss *SQLStore) LoadGameSnapshots() (map[string][]byte, error) {
+	rows, err := ss.db.Query(`SELECT room_id, data FROM game_snapshots`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make(map[string][]byte)
+	for rows.Next() {
+		var roomID, data string
+		if err := rows.Scan(&roomID, &data); err != nil {
+			return nil, err
+		}
+		snapshots[roomID] = []byte(data)
+	}
+	return snapshots, rows.Err()
+}
+
+// DeleteGameSnapshot 删除对局快照
+func (ss *SQLStore) DeleteGameSnapshot(roomID string) error {
+	_, err := ss.db.Exec(ss.rebind(`DELETE FROM game_snapshots WHERE room_id = ?`), roomID)
+	return err
+}
+
+// AppendAuditEntry 追加审计日志条目
+func (ss *SQLStore) AppendAuditEntry(entry *models.AuditEntry) error {
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO audit_log (room_id, player_id, connection_id, source, action, target_id,
+		phase, round, accepted, reason, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		entry.RoomID, entry.PlayerID, entry.ConnectionID, entry.Source, entry.Action, entry.TargetID,
+		entry.Phase, entry.Round, entry.Accepted, entry.Reason, entry.Timestamp)
+	return err
+}
+
+// DeleteAuditEntriesBefore 删除早于指定时间的审计日志
+func (ss *SQLStore) DeleteAuditEntriesBefore(timestamp int64) (int64, error) {
+	result, err := ss.db.Exec(ss.rebind(`DELETE FROM audit_log WHERE timestamp < ?`), timestamp)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ListAuditEntries 查询审计日志
+func (ss *SQLStore) ListAuditEntries(query models.AuditQuery) ([]models.AuditEntry, error) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	if query.RoomID != "" {
+		conditions = append(conditions, "room_id = ?")
+		args = append(args, query.RoomID)
+	}
+	if query.PlayerID != "" {
+		conditions = append(conditions, "player_id = ?")
+		args = append(args, query.PlayerID)
+	}
+	if query.Since > 0 {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, query.Since)
+	}
+	if query.Until > 0 {
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, query.Until)
+	}
+
+	stmt := `SELECT id, room_id, player_id, connection_id, source, action, target_id, phase, round, accepted, reason, timestamp
+		FROM audit_log`
+	if len(conditions) > 0 {
+		stmt += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 1<<31 - 1
+	}
+	stmt += " ORDER BY timestamp, id LIMIT ? OFFSET ?"
+	args = append(args, limit, query.Offset)
+
+	rows, err := ss.db.Query(ss.rebind(stmt), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err := rows.Scan(&entry.ID, &entry.RoomID, &entry.PlayerID, &entry.ConnectionID, &entry.Source,
+			&entry.Action, &entry.TargetID, &entry.Phase, &entry.Round, &entry.Accepted, &entry.Reason,
+			&entry.Timestamp); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetCurrentSeason 获取进行中的赛季
+func (ss *SQLStore) GetCurrentSeason() (*models.Season, error) {
+	var season models.Season
+	err := ss.db.QueryRow(`SELECT id, name, started_at, ends_at, ended_at FROM seasons
+		WHERE ended_at = 0 ORDER BY id DESC LIMIT 1`).
+		Scan(&season.ID, &season.Name, &season.StartedAt, &season.EndsAt, &season.EndedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+// SaveSeason 保存赛季
+func (ss *SQLStore) SaveSeason(season *models.Season) error {
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO seasons (id, name, started_at, ends_at, ended_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, started_at = excluded.started_at,
+			ends_at = excluded.ends_at, ended_at = excluded.ended_at`),
+		season.ID, season.Name, season.StartedAt, season.EndsAt, season.EndedAt)
+	return err
+}
+
+// ListSeasons 列出所有赛季
+func (ss *SQLStore) ListSeasons() ([]*models.Season, error) {
+	rows, err := ss.db.Query(`SELECT id, name, started_at, ends_at, ended_at FROM seasons ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seasons := make([]*models.Season, 0)
+	for rows.Next() {
+		var season models.Season
+		if err := rows.Scan(&season.ID, &season.Name, &season.StartedAt, &season.EndsAt, &season.EndedAt); err != nil {
+			return nil, err
+		}
+		seasons = append(seasons, &season)
+	}
+	return seasons, rows.Err()
+}
+
+// GetSeasonStanding 获取玩家的赛季成绩
+func (ss *SQLStore) GetSeasonStanding(seasonID int, playerID string) (*models.SeasonStanding, error) {
+	var standing models.SeasonStanding
+	err := ss.db.QueryRow(ss.rebind(`SELECT season_id, player_id, rating, games, wins, updated_at
+		FROM season_standings WHERE season_id = ? AND player_id = ?`), seasonID, playerID).
+		Scan(&standing.SeasonID, &standing.PlayerID, &standing.Rating, &standing.Games, &standing.Wins, &standing.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &standing, nil
+}
+
+// SaveSeasonStanding 保存玩家的赛季成绩
+func (ss *SQLStore) SaveSeasonStanding(standing *models.SeasonStanding) error {
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO season_standings (season_id, player_id, rating, games, wins, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (season_id, player_id) DO UPDATE SET rating = excluded.rating, games = excluded.games,
+			wins = excluded.wins, updated_at = excluded.updated_at`),
+		standing.SeasonID, standing.PlayerID, standing.Rating, standing.Games, standing.Wins, standing.UpdatedAt)
+	return err
+}
+
+// ListSeasonStandings 分页列出赛季排名
+func (ss *SQLStore) ListSeasonStandings(seasonID, offset, limit int) ([]*models.SeasonStanding, error) {
+	if limit <= 0 {
+		limit = 1<<31 - 1
+	}
+
+	rows, err := ss.db.Query(ss.rebind(`SELECT season_id, player_id, rating, games, wins, updated_at
+		FROM season_standings WHERE season_id = ? ORDER BY rating DESC, player_id LIMIT ? OFFSET ?`),
+		seasonID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	standings := make([]*models.SeasonStanding, 0)
+	for rows.Next() {
+		var standing models.SeasonStanding
+		if err := rows.Scan(&standing.SeasonID, &standing.PlayerID, &standing.Rating, &standing.Games,
+			&standing.Wins, &standing.UpdatedAt); err != nil {
+			return nil, err
+		}
+		standings = append(standings, &standing)
+	}
+	return standings, rows.Err()
+}
+
+// GetFriendship 获取好友关系
+func (ss *SQLStore) GetFriendship(userID, otherID string) (*models.Friendship, error) {
+	var friendship models.Friendship
+	err := ss.db.QueryRow(ss.rebind(`SELECT requester_id, addressee_id, status, created_at FROM friendships
+		WHERE (requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)`),
+		userID, otherID, otherID, userID).
+		Scan(&friendship.RequesterID, &friendship.AddresseeID, &friendship.Status, &friendship.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &friendship, nil
+}
+
+// SaveFriendship 保存好友关系
+func (ss *SQLStore) SaveFriendship(friendship *models.Friendship) error {
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO friendships (requester_id, addressee_id, status, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (requester_id, addressee_id) DO UPDATE SET status = excluded.status`),
+		friendship.RequesterID, friendship.AddresseeID, friendship.Status, friendship.CreatedAt)
+	return err
+}
+
+// DeleteFriendship 删除好友关系
+func (ss *SQLStore) DeleteFriendship(userID, otherID string) error {
+	_, err := ss.db.Exec(ss.rebind(`DELETE FROM friendships
+		WHERE (requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)`),
+		userID, otherID, otherID, userID)
+	return err
+}
+
+// ListFriendships 列出账号参与的所有好友关系
+func (ss *SQLStore) ListFriendships(userID string) ([]*models.Friendship, error) {
+	rows, err := ss.db.Query(ss.rebind(`SELECT requester_id, addressee_id, status, created_at FROM friendships
+		WHERE requester_id = ? OR addressee_id = ? ORDER BY created_at`), userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	friendships := make([]*models.Friendship, 0)
+	for rows.Next() {
+		var friendship models.Friendship
+		if err := rows.Scan(&friendship.RequesterID, &friendship.AddresseeID, &friendship.Status,
+			&friendship.CreatedAt); err != nil {
+			return nil, err
+		}
+		friendships = append(friendships, &friendship)
+	}
+	return friendships, rows.Err()
+}
+
+// SaveReport 保存举报
+func (ss *SQLStore) SaveReport(report *models.Report) error {
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO reports (id, reporter_id, reported_id, room_id, reason, details, status,
+		reviewed_by, review_note, created_at, reviewed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET status = excluded.status, reviewed_by = excluded.reviewed_by,
+			review_note = excluded.review_note, reviewed_at = excluded.reviewed_at`),
+		report.ID, report.ReporterID, report.ReportedID, report.RoomID, report.Reason, report.Details, report.Status,
+		report.ReviewedBy, report.ReviewNote, report.CreatedAt, report.ReviewedAt)
+	return err
+}
+
+// GetReport 获取举报
+func (ss *SQLStore) GetReport(id string) (*models.Report, error) {
+	var report models.Report
+	err := ss.db.QueryRow(ss.rebind(`SELECT id, reporter_id, reported_id, room_id, reason, details, status,
+		reviewed_by, review_note, created_at, reviewed_at FROM reports WHERE id = ?`), id).
+		Scan(&report.ID, &report.ReporterID, &report.ReportedID, &report.RoomID, &report.Reason, &report.Details,
+			&report.Status, &report.ReviewedBy, &report.ReviewNote, &report.CreatedAt, &report.ReviewedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ListReports 分页列出举报
+func (ss *SQLStore) ListReports(status string, offset, limit int) ([]*models.Report, error) {
+	if limit <= 0 {
+		limit = 1<<31 - 1
+	}
+
+	query := `SELECT id, reporter_id, reported_id, room_id, reason, details, status,
+		reviewed_by, review_note, created_at, reviewed_at FROM reports`
+	args := make([]interface{}, 0)
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := ss.db.Query(ss.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := make([]*models.Report, 0)
+	for rows.Next() {
+		var report models.Report
+		if err := rows.Scan(&report.ID, &report.ReporterID, &report.ReportedID, &report.RoomID, &report.Reason,
+			&report.Details, &report.Status, &report.ReviewedBy, &report.ReviewNote, &report.CreatedAt,
+			&report.ReviewedAt); err != nil {
+			return nil, err
+		}
+		reports = append(reports, &report)
+	}
+	return reports, rows.Err()
+}
+
+// SaveBan 保存封禁
+func (ss *SQLStore) SaveBan(ban *models.Ban) error {
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO bans (id, type, target, reason, created_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		ban.ID, ban.Type, ban.Target, ban.Reason, ban.CreatedBy, ban.CreatedAt, ban.ExpiresAt)
+	return err
+}
+
+// DeleteBan 解除封禁
+func (ss *SQLStore) DeleteBan(id string) error {
+	result, err := ss.db.Exec(ss.rebind(`DELETE FROM bans WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListBans 列出所有封禁
+func (ss *SQLStore) ListBans() ([]*models.Ban, error) {
+	rows, err := ss.db.Query(`SELECT id, type, target, reason, created_by, created_at, expires_at FROM bans ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bans := make([]*models.Ban, 0)
+	for rows.Next() {
+		var ban models.Ban
+		if err := rows.Scan(&ban.ID, &ban.Type, &ban.Target, &ban.Reason, &ban.CreatedBy, &ban.CreatedAt,
+			&ban.ExpiresAt); err != nil {
+			return nil, err
+		}
+		bans = append(bans, &ban)
+	}
+	return bans, rows.Err()
+}
+
+// SaveAPIKey 保存API密钥，权限以逗号分隔存储
+func (ss *SQLStore) SaveAPIKey(key *models.APIKey) error {
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO api_keys (id, owner_id, name, scopes, secret_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		key.ID, key.OwnerID, key.Name, strings.Join(key.Scopes, ","), key.SecretHash, key.CreatedAt, key.ExpiresAt)
+	return err
+}
+
+// GetAPIKey 获取API密钥
+func (ss *SQLStore) GetAPIKey(id string) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	err := ss.db.QueryRow(ss.rebind(`SELECT id, owner_id, name, scopes, secret_hash, created_at, expires_at
+		FROM api_keys WHERE id = ?`), id).
+		Scan(&key.ID, &key.OwnerID, &key.Name, &scopes, &key.SecretHash, &key.CreatedAt, &key.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	key.Scopes = splitScopes(scopes)
+	return &key, nil
+}
+
+// ListAPIKeys 列出账号的API密钥
+func (ss *SQLStore) ListAPIKeys(ownerID string) ([]*models.APIKey, error) {
+	rows, err := ss.db.Query(ss.rebind(`SELECT id, owner_id, name, scopes, secret_hash, created_at, expires_at
+		FROM api_keys WHERE owner_id = ? ORDER BY created_at`), ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		var key models.APIKey
+		var scopes string
+		if err := rows.Scan(&key.ID, &key.OwnerID, &key.Name, &scopes, &key.SecretHash, &key.CreatedAt,
+			&key.ExpiresAt); err != nil {
+			return nil, err
+		}
+		key.Scopes = splitScopes(scopes)
+		keys = append(keys, &key)
+	}
+	return keys, rows.Err()
+}
+
+// DeleteAPIKey 删除API密钥
+func (ss *SQLStore) DeleteAPIKey(id string) error {
+	result, err := ss.db.Exec(ss.rebind(`DELETE FROM api_keys WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CreateIdentity 绑定第三方登录身份
+func (ss *SQLStore) CreateIdentity(identity *models.Identity) error {
+	// 先检查是否已绑定，避免依赖不同驱动的唯一约束错误类型
+	if _, err := ss.GetIdentity(identity.Provider, identity.Subject); err == nil {
+		return ErrDuplicate
+	} else if err != ErrNotFound {
+		return err
+	}
+
+	_, err := ss.db.Exec(ss.rebind(`INSERT INTO identities (provider, subject, user_id, name, created_at)
+		VALUES (?, ?, ?, ?, ?)`),
+		identity.Provider, identity.Subject, identity.UserID, identity.Name, identity.CreatedAt)
+	return err
+}
+
+// GetIdentity 获取第三方登录身份
+func (ss *SQLStore) GetIdentity(provider, subject string) (*models.Identity, error) {
+	var identity models.Identity
+	err := ss.db.QueryRow(ss.rebind(`SELECT provider, subject, user_id, name, created_at
+		FROM identities WHERE provider = ? AND subject = ?`), provider, subject).
+		Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Name, &identity.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// ListIdentities 列出账号绑定的第三方登录身份
+func (ss *SQLStore) ListIdentities(userID string) ([]*models.Identity, error) {
+	rows, err := ss.db.Query(ss.rebind(`SELECT provider, subject, user_id, name, created_at
+		FROM identities WHERE user_id = ? ORDER BY created_at`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := make([]*models.Identity, 0)
+	for rows.Next() {
+		var identity models.Identity
+		if err := rows.Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Name, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, &identity)
+	}
+	return identities, rows.Err()
+}
+
+// DeleteIdentity 解除账号在指定平台绑定的身份
+func (ss *SQLStore) DeleteIdentity(userID, provider string) error {
+	result, err := ss.db.Exec(ss.rebind(`DELETE FROM identities WHERE user_id = ? AND provider = ?`), userID, provider)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// splitScopes 解析逗号分隔的权限列表
+func splitScopes(scopes string) []string {
+	if scopes == "" {
+		return []string{}
+	}
+	return strings.Split(scopes, ",")
+}
+
+// SaveTournament 保存赛事，分桌、比赛和积分整体以JSON保存
+func (ss *SQLStore) SaveTournament(tournament *models.Tournament) error {
+	data, err := json.Marshal(tournament)
+	if err != nil {
+		return err
+	}
+
+	_, err = ss.db.Exec(ss.rebind(`INSERT INTO tournaments (id, status, data, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET status = excluded.status, data = excluded.data`),
+		tournament.ID, tournament.Status, string(data), tournament.CreatedAt)
+	return err
+}
+
+// GetTournament 获取赛事
+func (ss *SQLStore) GetTournament(id string) (*models.Tournament, error) {
+	var data string
+	err := ss.db.QueryRow(ss.rebind(`SELECT data FROM tournaments WHERE id = ?`), id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tournament models.Tournament
+	if err := json.Unmarshal([]byte(data), &tournament); err != nil {
+		return nil, err
+	}
+	return &tournament, nil
+}
+
+// ListTournaments 按创建时间倒序列出赛事
+func (ss *SQLStore) ListTournaments(status string) ([]*models.Tournament, error) {
+	query := `SELECT data FROM tournaments`
+	args := make([]interface{}, 0)
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY created_at DESC`
+
+	rows, err := ss.db.Query(ss.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tournaments := make([]*models.Tournament, 0)
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var tournament models.Tournament
+		if err := json.Unmarshal([]byte(data), &tournament); err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, &tournament)
+	}
+	return tournaments, rows.Err()
+}
+
+// Close 关闭数据库连接
+func (ss *SQLStore) Close() error {
+	return ss.db.Close()
+}
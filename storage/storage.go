@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/qianlnk/werewolf/models"
+)
+
+var (
+	ErrNotFound  = errors.New("记录不存在")
+	ErrDuplicate = errors.New("记录已存在")
+)
+
+// Store 持久化存储接口
+type Store interface {
+	// SaveRoom 保存房间及其玩家信息，已存在则覆盖
+	SaveRoom(room *models.Room) error
+	// DeleteRoom 删除房间及其玩家信息
+	DeleteRoom(roomID string) error
+	// LoadActiveRooms 加载所有未关闭的房间
+	LoadActiveRooms() ([]*models.Room, error)
+	// SaveGameRecord 保存对局记录
+	SaveGameRecord(record *models.GameRecord) error
+	// ListGameRecords 按结束时间倒序分页列出对局记录，不包含事件日志
+	ListGameRecords(offset, limit int) ([]*models.GameRecord, error)
+	// GetGameRecord 获取包含完整事件日志的对局记录
+	GetGameRecord(id string) (*models.GameRecord, error)
+	// ListGameRecordsBefore 按结束时间顺序列出早于指定时间结束的对局记录，包含完整事件日志
+	ListGameRecordsBefore(endedBefore int64, limit int) ([]*models.GameRecord, error)
+	// UpdateGameRecordEvents 替换对局记录的事件日志
+	UpdateGameRecordEvents(id string, events []models.GameEvent) error
+	// DeleteGameRecord 删除对局记录
+	DeleteGameRecord(id string) error
+	// CreateUser 创建账号，用户名已存在时返回ErrDuplicate
+	CreateUser(user *models.User) error
+	// GetUserByID 根据玩家ID获取账号
+	GetUserByID(id string) (*models.User, error)
+	// GetUserByUsername 根据用户名获取账号
+	GetUserByUsername(username string) (*models.User, error)
+	// UpdateUser 更新账号信息，用户名与其他账号冲突时返回ErrDuplicate
+	UpdateUser(user *models.User) error
+	// CreateSession 保存会话
+	CreateSession(session *models.Session) error
+	// GetSession 获取会话
+	GetSession(id string) (*models.Session, error)
+	// DeleteUserSessions 删除账号的所有会话
+	DeleteUserSessions(userID string) error
+	// GetPlayerStats 获取玩家统计，没有记录时返回ErrNotFound
+	GetPlayerStats(playerID string) (*models.PlayerStats, error)
+	// SavePlayerStats 保存玩家统计
+	SavePlayerStats(stats *models.PlayerStats) error
+	// GetAchievements 获取玩家已解锁的成就
+	GetAchievements(playerID string) ([]models.PlayerAchievement, error)
+	// AddAchievement 记录玩家解锁的成就，已解锁时返回ErrDuplicate
+	AddAchievement(achievement *models.PlayerAchievement) error
+	// SaveGameSnapshot 保存进行中对局的快照，已存在则覆盖
+	SaveGameSnapshot(roomID string, data []byte) error
+	// LoadGameSnapshots 加载所有对局快照，返回房间ID到快照数据的映射
+	LoadGameSnapshots() (map[string][]byte, error)
+	// DeleteGameSnapshot 删除对局快照
+	DeleteGameSnapshot(roomID string) error
+	// AppendAuditEntry 追加审计日志条目，条目写入后不可修改
+	AppendAuditEntry(entry *models.AuditEntry) error
+	// ListAuditEntries 按时间顺序查询审计日志
+	ListAuditEntries(query models.AuditQuery) ([]models.AuditEntry, error)
+	// DeleteAuditEntriesBefore 删除早于指定毫秒时间戳的审计日志，仅供数据保留任务使用，返回删除条数
+	DeleteAuditEntriesBefore(timestamp int64) (int64, error)
+	// GetCurrentSeason 获取进行中的赛季，没有时返回ErrNotFound
+	GetCurrentSeason() (*models.Season, error)
+	// SaveSeason 保存赛季，已存在则覆盖
+	SaveSeason(season *models.Season) error
+	// ListSeasons 按ID倒序列出所有赛季
+	ListSeasons() ([]*models.Season, error)
+	// GetSeasonStanding 获取玩家的赛季成绩，没有记录时返回ErrNotFound
+	GetSeasonStanding(seasonID int, playerID string) (*models.SeasonStanding, error)
+	// SaveSeasonStanding 保存玩家的赛季成绩
+	SaveSeasonStanding(standing *models.SeasonStanding) error
+	// ListSeasonStandings 按赛季积分倒序分页列出赛季排名
+	ListSeasonStandings(seasonID, offset, limit int) ([]*models.SeasonStanding, error)
+	// GetFriendship 获取两个账号之间的好友关系（不区分方向），没有时返回ErrNotFound
+	GetFriendship(userID, otherID string) (*models.Friendship, error)
+	// SaveFriendship 保存好友关系，已存在则覆盖
+	SaveFriendship(friendship *models.Friendship) error
+	// DeleteFriendship 删除两个账号之间的好友关系
+	DeleteFriendship(userID, otherID string) error
+	// ListFriendships 列出账号参与的所有好友关系
+	ListFriendships(userID string) ([]*models.Friendship, error)
+	// SaveReport 保存举报，已存在则覆盖
+	SaveReport(report *models.Report) error
+	// GetReport 获取举报
+	GetReport(id string) (*models.Report, error)
+	// ListReports 按创建时间顺序分页列出举报，status为空时不限制状态
+	ListReports(status string, offset, limit int) ([]*models.Report, error)
+	// SaveBan 保存封禁
+	SaveBan(ban *models.Ban) error
+	// DeleteBan 解除封禁
+	DeleteBan(id string) error
+	// ListBans 列出所有封禁
+	ListBans() ([]*models.Ban, error)
+	// SaveAPIKey 保存API密钥
+	SaveAPIKey(key *models.APIKey) error
+	// GetAPIKey 获取API密钥，没有时返回ErrNotFound
+	GetAPIKey(id string) (*models.APIKey, error)
+	// ListAPIKeys 按创建时间顺序列出账号的API密钥
+	ListAPIKeys(ownerID string) ([]*models.APIKey, error)
+	// DeleteAPIKey 删除API密钥
+	DeleteAPIKey(id string) error
+	// CreateIdentity 绑定第三方登录身份，身份已绑定账号时返回ErrDuplicate
+	CreateIdentity(identity *models.Identity) error
+	// GetIdentity 获取第三方登录身份，没有时返回ErrNotFound
+	GetIdentity(provider, subject string) (*models.Identity, error)
+	// ListIdentities 按绑定时间顺序列出账号绑定的第三方登录身份
+	ListIdentities(userID string) ([]*models.Identity, error)
+	// DeleteIdentity 解除账号在指定平台绑定的身份
+	DeleteIdentity(userID, provider string) error
+	// SaveTournament 保存赛事，已存在则覆盖
+	SaveTournament(tournament *models.Tournament) error
+	// GetTournament 获取赛事，没有时返回ErrNotFound
+	GetTournament(id string) (*models.Tournament, error)
+	// ListTournaments 按创建时间倒序列出赛事，status为空时不限制状态
+	ListTournaments(status string) ([]*models.Tournament, error)
+	// Close 关闭存储
+	Close() error
+}
+
+// New 根据驱动类型创建存储实例
+func New(driver, dsn string) (Store, error) {
+	switch driver {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "sqlite", "postgres":
+		return NewSQLStore(driver, dsn)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", driver)
+	}
+}
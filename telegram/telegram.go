@@ -0,0 +1,179 @@
+// Package telegram 通过Telegram机器人进行对局：群组对应一个房间，玩家在群里用命令加入和开局，
+// 投票使用消息下方的按钮，身份和夜晚行动通过与机器人的私聊完成，规则和对局流程由bridge包和引擎处理。
+// 玩家需要先私聊机器人发送 /start 才能收到私信；转发白天发言需要在BotFather中关闭机器人的隐私模式
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/qianlnk/werewolf/bridge"
+	"github.com/qianlnk/werewolf/services"
+)
+
+// Telegram接口参数
+const (
+	defaultAPIURL  = "https://api.telegram.org"
+	pollTimeout    = 30 // getUpdates长轮询的秒数
+	requestTimeout = 10 * time.Second
+	maxMessageLen  = 4096 // 单条消息的字符数上限
+	maxRetries     = 3    // 遇到限流时的最大重试次数
+	buttonsPerRow  = 2    // 每行按钮数
+)
+
+// Bot Telegram机器人，通过getUpdates长轮询接收消息，通过Bot API发送消息
+type Bot struct {
+	token  string
+	apiURL string
+	bridge *bridge.Bridge
+	client *http.Client
+
+	mutex    sync.Mutex
+	username string // 机器人的用户名，用于识别 /join@机器人 形式的命令
+
+	ctx    context.Context // 关闭机器人时取消
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New 创建Telegram机器人，apiURL为空时使用官方地址，调用Start后开始接收消息
+func New(token, apiURL string, rooms *services.RoomManager, sockets *services.WebSocketManager) *Bot {
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Bot{
+		token:  token,
+		apiURL: apiURL,
+		client: &http.Client{Timeout: requestTimeout + pollTimeout*time.Second},
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	b.bridge = bridge.New(b, rooms, sockets, "/")
+	return b
+}
+
+// Start 在后台接收消息
+func (b *Bot) Start() {
+	go b.run()
+}
+
+// Close 停止接收消息和推送房间事件
+func (b *Bot) Close() {
+	b.cancel()
+	b.bridge.Close()
+	<-b.done
+}
+
+// Name 平台名
+func (b *Bot) Name() string {
+	return "telegram"
+}
+
+// inlineButton 消息下方的按钮，点击后机器人收到携带CallbackData的回调
+type inlineButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// SendGroup 向群组发送消息，选项渲染为按钮
+func (b *Bot) SendGroup(chatID string, msg bridge.Message) error {
+	return b.sendMessage(chatID, msg)
+}
+
+// SendDirect 通过与用户的私聊发送消息，私聊的会话ID与用户ID相同
+func (b *Bot) SendDirect(userID string, msg bridge.Message) error {
+	return b.sendMessage(userID, msg)
+}
+
+// sendMessage 发送消息，超过长度上限时拆成多条，按钮附在最后一条
+func (b *Bot) sendMessage(chatID string, msg bridge.Message) error {
+	chunks := bridge.SplitText(msg.Text, maxMessageLen)
+	for i, chunk := range chunks {
+		body := map[string]any{"chat_id": chatID, "text": chunk}
+		if i == len(chunks)-1 && len(msg.Choices) > 0 {
+			body["reply_markup"] = map[string]any{"inline_keyboard": keyboard(msg.Choices)}
+		}
+		if err := b.call("sendMessage", body, nil); err != nil {
+			slog.Warn("发送Telegram消息失败", "chat_id", chatID, "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// keyboard 把选项排成按钮，点击按钮相当于发送选项的命令
+func keyboard(choices []bridge.Choice) [][]inlineButton {
+	var rows [][]inlineButton
+	for i, choice := range choices {
+		if i%buttonsPerRow == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], inlineButton{Text: choice.Label, CallbackData: choice.Command})
+	}
+	return rows
+}
+
+// apiResponse Bot API的返回
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+	Parameters  *struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// call 调用Bot API的方法，遇到限流时按返回的等待时间重试
+func (b *Bot) call(method string, body, result any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/bot%s/%s", b.apiURL, b.token, method)
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(b.ctx, http.MethodPost, url, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := b.client.Do(req)
+		if err != nil {
+			return err
+		}
+		payload, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		var response apiResponse
+		if err := json.Unmarshal(payload, &response); err != nil {
+			return fmt.Errorf("Telegram返回 %s", resp.Status)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && response.Parameters != nil && attempt < maxRetries {
+			select {
+			case <-time.After(time.Duration(response.Parameters.RetryAfter) * time.Second):
+			case <-b.ctx.Done():
+				return b.ctx.Err()
+			}
+			continue
+		}
+		if !response.OK {
+			return fmt.Errorf("Telegram返回 %s: %s", resp.Status, response.Description)
+		}
+		if result != nil {
+			return json.Unmarshal(response.Result, result)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,166 @@
+package telegram
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qianlnk/werewolf/bridge"
+	"github.com/qianlnk/werewolf/reporting"
+)
+
+// maxBackoff 请求失败后重试的最长等待时间
+const maxBackoff = time.Minute
+
+// update getUpdates返回的一条更新，只处理消息和按钮回调
+type update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *message       `json:"message"`
+	CallbackQuery *callbackQuery `json:"callback_query"`
+}
+
+type message struct {
+	Chat struct {
+		ID   int64  `json:"id"`
+		Type string `json:"type"` // private、group、supergroup、channel
+	} `json:"chat"`
+	From *user  `json:"from"`
+	Text string `json:"text"`
+}
+
+type user struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+}
+
+type callbackQuery struct {
+	ID      string   `json:"id"`
+	From    user     `json:"from"`
+	Message *message `json:"message"`
+	Data    string   `json:"data"`
+}
+
+// run 长轮询接收更新并按顺序处理，请求失败时按指数退避重试
+func (b *Bot) run() {
+	defer close(b.done)
+
+	var offset int64
+	backoff := time.Second
+	for {
+		if b.username == "" {
+			var me user
+			if err := b.call("getMe", map[string]any{}, &me); err == nil {
+				b.mutex.Lock()
+				b.username = me.Username
+				b.mutex.Unlock()
+				slog.Info("Telegram机器人已连接", "username", me.Username)
+			}
+		}
+
+		var updates []update
+		err := b.call("getUpdates", map[string]any{
+			"offset":          offset,
+			"timeout":         pollTimeout,
+			"allowed_updates": []string{"message", "callback_query"},
+		}, &updates)
+		if b.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Warn("接收Telegram消息失败，稍后重试", "error", err, "retry_in", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-b.ctx.Done():
+				return
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+		backoff = time.Second
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			reporting.Call("telegram.update", func() { b.handleUpdate(u) })
+		}
+	}
+}
+
+// handleUpdate 把消息和按钮回调交给bridge处理
+func (b *Bot) handleUpdate(u update) {
+	if q := u.CallbackQuery; q != nil {
+		// 先应答回调，客户端停止按钮上的加载状态
+		b.call("answerCallbackQuery", map[string]any{"callback_query_id": q.ID}, nil)
+		if q.Message == nil || q.From.IsBot {
+			return
+		}
+		if q.Message.Chat.Type == "private" {
+			b.bridge.HandleDirect(bridgeUser(q.From), q.Data)
+		} else {
+			b.bridge.HandleGroupCommand(chatID(q.Message), bridgeUser(q.From), strings.Fields(q.Data))
+		}
+		return
+	}
+
+	msg := u.Message
+	if msg == nil || msg.From == nil || msg.From.IsBot || msg.Text == "" {
+		return
+	}
+	from := bridgeUser(*msg.From)
+	text := strings.TrimSpace(msg.Text)
+
+	if msg.Chat.Type == "private" {
+		command, args, isCommand := b.parseCommand(text)
+		switch {
+		case isCommand && (command == "start" || command == "help"):
+			b.SendDirect(from.ID, bridge.Message{Text: "已可以接收对局私信。请在群里发送 /join 加入房间，夜晚行动时点击私信中的按钮即可"})
+		case isCommand:
+			b.bridge.HandleDirect(from, strings.Join(append([]string{command}, args...), " "))
+		default:
+			b.bridge.HandleDirect(from, text)
+		}
+		return
+	}
+
+	if command, args, isCommand := b.parseCommand(text); isCommand {
+		b.bridge.HandleGroupCommand(chatID(msg), from, append([]string{command}, args...))
+		return
+	}
+	b.bridge.HandleGroupText(chatID(msg), from, text)
+}
+
+// parseCommand 解析 /命令 参数 形式的消息，发给其他机器人的命令（/join@其他机器人）不处理
+func (b *Bot) parseCommand(text string) (command string, args []string, ok bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", nil, false
+	}
+	fields := strings.Fields(text[1:])
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	command, target, addressed := strings.Cut(fields[0], "@")
+	b.mutex.Lock()
+	username := b.username
+	b.mutex.Unlock()
+	if addressed && username != "" && !strings.EqualFold(target, username) {
+		return "", nil, false
+	}
+	return strings.ToLower(command), fields[1:], true
+}
+
+// bridgeUser 转换为bridge中的用户，名字优先使用全名
+func bridgeUser(u user) bridge.User {
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" {
+		name = u.Username
+	}
+	return bridge.User{ID: strconv.FormatInt(u.ID, 10), Name: name}
+}
+
+// chatID 消息所在会话的ID
+func chatID(msg *message) string {
+	return strconv.FormatInt(msg.Chat.ID, 10)
+}
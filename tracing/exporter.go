@@ -0,0 +1,214 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 导出参数：队列满时丢弃新片段，不阻塞请求和对局
+const (
+	exportQueueSize = 4096
+	exportBatchSize = 256
+	exportInterval  = 5 * time.Second
+	exportTimeout   = 10 * time.Second
+)
+
+// exporter 按OTLP/HTTP的JSON格式批量发送片段
+type exporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+	spans       chan *Span
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func newExporter(endpoint, serviceName string) *exporter {
+	if serviceName == "" {
+		serviceName = "werewolf"
+	}
+	e := &exporter{
+		url:         strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: exportTimeout},
+		spans:       make(chan *Span, exportQueueSize),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// add 放入导出队列，队列已满时丢弃
+func (e *exporter) add(s *Span) {
+	select {
+	case e.spans <- s:
+	default:
+	}
+}
+
+// run 定期或攒够一批时发送
+func (e *exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exportBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			slog.Warn("导出调用链失败", "spans", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case s := <-e.spans:
+			batch = append(batch, s)
+			if len(batch) >= exportBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.stop:
+			for {
+				select {
+				case s := <-e.spans:
+					batch = append(batch, s)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// shutdown 发送队列中剩余的片段，ctx结束时不再等待
+func (e *exporter) shutdown(ctx context.Context) error {
+	close(e.stop)
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send 发送一批片段
+func (e *exporter) send(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("采集端返回 %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP JSON格式的请求体，字段名与opentelemetry-proto的JSON映射一致
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"` // 0未设置，2错误
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+// otlpSpanKindInternal 片段类型，服务内部的片段统一为INTERNAL
+const otlpSpanKindInternal = 1
+
+// request 构建一批片段的请求体
+func (e *exporter) request(spans []*Span) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			Name:              s.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		}
+		if s.ParentID.IsValid() {
+			span.ParentSpanID = s.ParentID.String()
+		}
+		for key, value := range s.Attrs {
+			span.Attributes = append(span.Attributes, otlpAttribute(key, value))
+		}
+		if s.Err != "" {
+			span.Status = otlpStatus{Code: 2, Message: s.Err}
+		}
+		out = append(out, span)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{otlpAttribute("service.name", e.serviceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/qianlnk/werewolf"}, Spans: out}},
+	}}}
+}
+
+// otlpAttribute 按值的类型转换为OTLP的属性
+func otlpAttribute(key string, value any) otlpKeyValue {
+	switch v := value.(type) {
+	case bool:
+		return otlpKeyValue{Key: key, Value: map[string]any{"boolValue": v}}
+	case int:
+		return otlpKeyValue{Key: key, Value: map[string]any{"intValue": strconv.Itoa(v)}}
+	case int64:
+		return otlpKeyValue{Key: key, Value: map[string]any{"intValue": strconv.FormatInt(v, 10)}}
+	case float64:
+		return otlpKeyValue{Key: key, Value: map[string]any{"doubleValue": v}}
+	case string:
+		return otlpKeyValue{Key: key, Value: map[string]any{"stringValue": v}}
+	}
+	return otlpKeyValue{Key: key, Value: map[string]any{"stringValue": fmt.Sprint(value)}}
+}
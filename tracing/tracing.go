@@ -0,0 +1,242 @@
+// Package tracing 为HTTP请求、WebSocket消息和对局引擎记录调用链
+// 调用链和片段ID与W3C Trace Context兼容，客户端可以通过traceparent请求头或消息字段传入上游的调用链，
+// 配置了采集地址时结束的片段按OTLP/HTTP的JSON格式批量发送到OpenTelemetry Collector，
+// 未配置时只生成ID用于日志关联，并记录超过慢调用阈值的片段
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID 调用链ID
+type TraceID [16]byte
+
+// SpanID 片段ID
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// IsValid ID不全为0
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// IsValid ID不全为0
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// Span 调用链中的一个片段，由Start创建，调用End结束。同一片段只应在一个goroutine中修改
+type Span struct {
+	TraceID  TraceID
+	SpanID   SpanID
+	ParentID SpanID
+	Name     string
+	Start    time.Time
+	End      time.Time
+	Attrs    map[string]any
+	Err      string // 片段失败时的错误信息
+	sampled  bool
+	ended    bool
+}
+
+type spanKey struct{}
+
+// FromContext 获取上下文中当前的片段，没有时返回nil
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithSpan 将片段放入上下文，之后在该上下文中创建的片段都是它的子片段
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// Start 创建片段，上下文中有片段时作为其子片段，否则开始一条新的调用链
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	span := &Span{Name: name, Start: time.Now(), SpanID: newSpanID()}
+	if parent := FromContext(ctx); parent != nil {
+		span.TraceID, span.ParentID, span.sampled = parent.TraceID, parent.SpanID, parent.sampled
+	} else {
+		span.TraceID = newTraceID()
+		span.sampled = currentTracer().sample()
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// StartRemote 以上游传入的traceparent为父片段创建片段，traceparent无效时开始一条新的调用链
+func StartRemote(ctx context.Context, name, traceparent string) (context.Context, *Span) {
+	traceID, parentID, sampled, ok := ParseTraceparent(traceparent)
+	if !ok {
+		return Start(ctx, name)
+	}
+	span := &Span{Name: name, Start: time.Now(), SpanID: newSpanID(), TraceID: traceID, ParentID: parentID, sampled: sampled}
+	return ContextWithSpan(ctx, span), span
+}
+
+// SetAttr 设置片段的属性，例如房间ID、玩家ID和动作类型
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	if s.Attrs == nil {
+		s.Attrs = make(map[string]any)
+	}
+	s.Attrs[key] = value
+}
+
+// SetError 记录片段失败的原因，err为nil时不做处理
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.Err = err.Error()
+}
+
+// Finish 结束片段，已采样的片段交给导出器，超过慢调用阈值的片段记录日志；重复调用只生效一次
+func (s *Span) Finish() {
+	if s == nil || s.ended {
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	currentTracer().finish(s)
+}
+
+// Duration 片段的耗时，未结束时为到目前为止的耗时
+func (s *Span) Duration() time.Duration {
+	if s.End.IsZero() {
+		return time.Since(s.Start)
+	}
+	return s.End.Sub(s.Start)
+}
+
+// Traceparent 按W3C Trace Context格式输出，用于响应头和传给下游
+func (s *Span) Traceparent() string {
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", s.TraceID, s.SpanID, flags)
+}
+
+// LogAttrs 附加到日志的调用链属性，片段为nil时返回nil
+func (s *Span) LogAttrs() []any {
+	if s == nil {
+		return nil
+	}
+	return []any{"trace_id", s.TraceID.String(), "span_id", s.SpanID.String()}
+}
+
+// ParseTraceparent 解析W3C traceparent，格式为 00-<32位调用链ID>-<16位父片段ID>-<标志>
+func ParseTraceparent(value string) (traceID TraceID, parentID SpanID, sampled bool, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, parentID, false, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || !traceID.IsValid() {
+		return traceID, parentID, false, false
+	}
+	if _, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil || !parentID.IsValid() {
+		return traceID, parentID, false, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return traceID, parentID, false, false
+	}
+	return traceID, parentID, flags[0]&1 == 1, true
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
+
+// Config 调用链配置
+type Config struct {
+	Endpoint      string        // OTLP/HTTP采集地址，例如 http://localhost:4318，为空时不导出
+	ServiceName   string        // 上报的服务名
+	SampleRatio   float64       // 新调用链的采样比例，0到1；上游传入的调用链沿用上游的采样决定
+	SlowThreshold time.Duration // 超过该耗时的片段记录警告日志，0表示不记录
+}
+
+// tracer 全局的采样、慢调用日志和导出配置
+type tracer struct {
+	config   Config
+	exporter *exporter
+}
+
+var (
+	globalTracer = &tracer{config: Config{SampleRatio: 1}}
+	tracerMutex  sync.RWMutex
+)
+
+func currentTracer() *tracer {
+	tracerMutex.RLock()
+	defer tracerMutex.RUnlock()
+
+	return globalTracer
+}
+
+// Setup 按配置启动调用链导出，返回的函数在服务关闭时调用，发送尚未导出的片段
+func Setup(config Config) func(context.Context) error {
+	t := &tracer{config: config}
+	if config.Endpoint != "" {
+		t.exporter = newExporter(config.Endpoint, config.ServiceName)
+	}
+
+	tracerMutex.Lock()
+	globalTracer = t
+	tracerMutex.Unlock()
+
+	if t.exporter == nil {
+		return func(context.Context) error { return nil }
+	}
+	slog.Info("调用链导出已启用", "endpoint", config.Endpoint, "sample_ratio", config.SampleRatio)
+	return t.exporter.shutdown
+}
+
+// sample 按采样比例决定新调用链是否导出
+func (t *tracer) sample() bool {
+	switch {
+	case t.config.SampleRatio >= 1:
+		return true
+	case t.config.SampleRatio <= 0:
+		return false
+	}
+	var b [1]byte
+	rand.Read(b[:])
+	return float64(b[0])/256 < t.config.SampleRatio
+}
+
+// finish 处理结束的片段
+func (t *tracer) finish(s *Span) {
+	if t.config.SlowThreshold > 0 && s.Duration() >= t.config.SlowThreshold {
+		attrs := append(s.LogAttrs(), "span", s.Name, "duration_ms", s.Duration().Milliseconds())
+		for key, value := range s.Attrs {
+			attrs = append(attrs, key, value)
+		}
+		slog.Warn("慢调用", attrs...)
+	}
+	if s.sampled && t.exporter != nil {
+		t.exporter.add(s)
+	}
+}